@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"backend/structs"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// GEOMETRÍA DE BLOQUES
+// El tamaño de bloque se elige al formatear (mkfs -blocksize) y queda guardado
+// en S_block_s. Cuántas entradas, bytes de datos o apuntadores caben en cada
+// tipo de bloque se deriva de ese valor, nunca del tamaño de las estructuras
+// compiladas. Las particiones formateadas sin -blocksize conservan la
+// distribución original de 96 bytes: 4 entradas de carpeta, 64 bytes de datos,
+// 8 apuntadores y 88 bytes de nombre largo por bloque.
+
+const legacyBlockSize = int64(96)
+
+// blockSizes - Tamaños de bloque que acepta mkfs -blocksize
+var blockSizes = []int64{64, 128, 256, 512, 1024}
+
+// folderEntrySize - Bytes de una entrada de carpeta (structs.BContent)
+var folderEntrySize = int64(binary.Size(structs.BContent{}))
+
+// blockGeometry - Capacidad de cada tipo de bloque para un tamaño de bloque
+type blockGeometry struct {
+	Size      int64 // Bytes que ocupa cada bloque en la partición (S_block_s)
+	Entries   int   // Entradas de un bloque de carpeta
+	Data      int   // Bytes de datos de un bloque de archivo
+	Pointers  int   // Apuntadores de un bloque de apuntadores
+	NameChunk int   // Bytes de nombre de un bloque de nombre largo (el resto es BNext)
+}
+
+// geometryFor - Geometría de una partición según su S_block_s
+func geometryFor(blockSize int64) blockGeometry {
+	if blockSize == legacyBlockSize {
+		return blockGeometry{Size: blockSize, Entries: 4, Data: 64, Pointers: 8, NameChunk: 88}
+	}
+	return blockGeometry{
+		Size:      blockSize,
+		Entries:   int(blockSize / folderEntrySize),
+		Data:      int(blockSize),
+		Pointers:  int(blockSize / 8),
+		NameChunk: int(blockSize - 8),
+	}
+}
+
+// validBlockSize - Verificar que S_block_s es uno de los tamaños que crea mkfs
+func validBlockSize(blockSize int64) bool {
+	if blockSize == legacyBlockSize {
+		return true
+	}
+	for _, size := range blockSizes {
+		if size == blockSize {
+			return true
+		}
+	}
+	return false
+}
+
+// geometry - Geometría de bloques del sistema de archivos
+func (fs *FileSystem) geometry() blockGeometry {
+	return geometryFor(fs.SB.S_block_s)
+}
+
+// newFolderBlock - Bloque de carpeta con todas las entradas libres
+func (g blockGeometry) newFolderBlock() structs.BloqueCarpeta {
+	folderBlock := structs.BloqueCarpeta{BContent: make([]structs.BContent, g.Entries)}
+	for i := range folderBlock.BContent {
+		folderBlock.BContent[i].BInodo = -1
+	}
+	return folderBlock
+}
+
+// newFileBlock - Bloque de archivo vacío
+func (g blockGeometry) newFileBlock() structs.BloqueArchivo {
+	return structs.BloqueArchivo{BContent: make([]byte, g.Data)}
+}
+
+// newPointerBlock - Bloque de apuntadores vacío (todos en -1)
+func (g blockGeometry) newPointerBlock() structs.BloqueApuntador {
+	pointerBlock := structs.BloqueApuntador{BPointers: make([]int64, g.Pointers)}
+	for i := range pointerBlock.BPointers {
+		pointerBlock.BPointers[i] = -1
+	}
+	return pointerBlock
+}
+
+// newNameBlock - Bloque de nombre largo vacío y sin siguiente
+func (g blockGeometry) newNameBlock() structs.BloqueNombre {
+	return structs.BloqueNombre{BName: make([]byte, g.NameChunk), BNext: -1}
+}
+
+// encode - Bytes de un bloque tal como se guarda en la partición
+func (g blockGeometry) encode(block interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	switch b := block.(type) {
+	case *structs.BloqueCarpeta:
+		if len(b.BContent) != g.Entries {
+			return nil, fmt.Errorf("el bloque de carpeta tiene %d entradas y la partición usa %d", len(b.BContent), g.Entries)
+		}
+		binary.Write(&buffer, binary.LittleEndian, b.BContent)
+	case *structs.BloqueArchivo:
+		if len(b.BContent) != g.Data {
+			return nil, fmt.Errorf("el bloque de archivo tiene %d bytes y la partición usa %d", len(b.BContent), g.Data)
+		}
+		buffer.Write(b.BContent)
+	case *structs.BloqueApuntador:
+		if len(b.BPointers) != g.Pointers {
+			return nil, fmt.Errorf("el bloque de apuntadores tiene %d apuntadores y la partición usa %d", len(b.BPointers), g.Pointers)
+		}
+		binary.Write(&buffer, binary.LittleEndian, b.BPointers)
+	case *structs.BloqueNombre:
+		if len(b.BName) != g.NameChunk {
+			return nil, fmt.Errorf("el bloque de nombre tiene %d bytes y la partición usa %d", len(b.BName), g.NameChunk)
+		}
+		buffer.Write(b.BName)
+		binary.Write(&buffer, binary.LittleEndian, b.BNext)
+	default:
+		return nil, fmt.Errorf("tipo de bloque no soportado: %T", block)
+	}
+	return buffer.Bytes(), nil
+}
+
+// decode - Interpretar los bytes de un bloque (al menos Size) como el tipo indicado
+func (g blockGeometry) decode(raw []byte, block interface{}) error {
+	if int64(len(raw)) < g.Size {
+		return fmt.Errorf("el bloque tiene %d bytes y la partición usa %d", len(raw), g.Size)
+	}
+	reader := bytes.NewReader(raw)
+	switch b := block.(type) {
+	case *structs.BloqueCarpeta:
+		b.BContent = make([]structs.BContent, g.Entries)
+		return binary.Read(reader, binary.LittleEndian, b.BContent)
+	case *structs.BloqueArchivo:
+		b.BContent = append([]byte(nil), raw[:g.Data]...)
+	case *structs.BloqueApuntador:
+		b.BPointers = make([]int64, g.Pointers)
+		return binary.Read(reader, binary.LittleEndian, b.BPointers)
+	case *structs.BloqueNombre:
+		b.BName = append([]byte(nil), raw[:g.NameChunk]...)
+		b.BNext = int64(binary.LittleEndian.Uint64(raw[g.NameChunk:]))
+	default:
+		return fmt.Errorf("tipo de bloque no soportado: %T", block)
+	}
+	return nil
+}
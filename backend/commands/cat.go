@@ -2,40 +2,47 @@ package commands
 
 import (
 	"backend/structs"
-	"encoding/binary"
+	"bytes"
+	"encoding/base64"
 	"fmt"
 	"os"
-	"strings"
 )
 
-func ExecuteCat(files map[string]string) {
+func ExecuteCat(session *Session, files map[string]string) (*CommandResult, error) {
+	res := NewCommandResult("cat")
+
 	// Verificar sesión activa
-	if !RequireActiveSession() {
-		return
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
 	}
 
 	// Validar que se proporcione al menos un archivo
 	if len(files) == 0 {
-		fmt.Println("Error: debe especificar al menos un archivo con -file1=...")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "debe especificar al menos un archivo con -file1=..")
 	}
 
-	// Obtener sesión actual
-	session := GetCurrentSession()
+	// Validar la sesión recibida
 	if session == nil {
-		fmt.Println("Error: No se pudo obtener la sesión actual.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no se pudo obtener la sesión actual")
 	}
 
 	// Buscar la partición montada de la sesión
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: No se encontró la partición montada con ID '%s'.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
 	}
 
-	fmt.Println("Contenido de los archivos:")
-	fmt.Println("========================================")
+	// La lectura comparte el disco con otros lectores; la fecha de acceso se
+	// registra al final, con el disco en exclusiva
+	unlock := rlockDisk(mounted.Path)
+
+	contents := make(map[string]string)
+	var accessed []int64
+	encodings := make(map[string]string)
+	fileErrors := make(map[string]string)
+
+	res.Println("Contenido de los archivos:")
+	res.Println("========================================")
 
 	// Procesar archivos en orden (file1, file2, file3, ...)
 	for i := 1; i <= len(files); i++ {
@@ -47,80 +54,96 @@ func ExecuteCat(files map[string]string) {
 		}
 
 		if filePath == "" {
-			fmt.Printf("Error: el parámetro -%s está vacío.\n", fileKey)
+			res.Printf("Error: el parámetro -%s está vacío.\n", fileKey)
+			fileErrors[fileKey] = "parámetro vacío"
 			continue
 		}
 
 		// Mostrar separador entre archivos (excepto el primero)
 		if i > 1 {
-			fmt.Println("----------------------------------------")
+			res.Println("----------------------------------------")
 		}
 
-		fmt.Printf("Archivo: %s\n", filePath)
+		res.Printf("Archivo: %s\n", filePath)
 
 		// Leer el archivo del sistema de archivos EXT2
-		content, err := readFileFromEXT2WithPermissions(mounted, filePath, session.User)
+		content, inodeIndex, err := readFileFromEXT2WithPermissions(mounted, filePath, session.User)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
+			res.Printf("Error: %v\n", err)
+			fileErrors[filePath] = err.Error()
+			continue
+		}
+		accessed = append(accessed, inodeIndex)
+
+		res.AddPath(filePath)
+
+		// Los archivos binarios se muestran en hexadecimal y viajan en base64
+		if !IsTextContent(content) {
+			contents[filePath] = base64.StdEncoding.EncodeToString(content)
+			encodings[filePath] = "base64"
+			res.Printf("⚠️ Contenido binario (%d bytes), se muestra en hexadecimal:\n", len(content))
+			res.Printf("%s", formatAsHex(content))
 			continue
 		}
+		contents[filePath] = string(content)
 
 		// Mostrar contenido
-		fmt.Print(content)
-		if !strings.HasSuffix(content, "\n") {
-			fmt.Println() // Agregar salto de línea si no existe
+		res.Printf("%s", content)
+		if !bytes.HasSuffix(content, []byte("\n")) {
+			res.Println() // Agregar salto de línea si no existe
 		}
 	}
 
-	fmt.Println("========================================")
-}
+	res.Println("========================================")
+	unlock()
 
-// Leer archivo del sistema EXT2 con verificación de permisos
-func readFileFromEXT2WithPermissions(mounted *MountedPartition, filePath string, currentUser string) (string, error) {
-	// Abrir el archivo del disco
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
-	if err != nil {
-		return "", fmt.Errorf("error al abrir el disco: %v", err)
+	if err := recordAccess(mounted, accessed); err != nil {
+		res.Printf("⚠️ No se pudo actualizar la fecha de acceso: %v\n", err)
+		res.Set("atimeError", err.Error())
 	}
-	defer file.Close()
 
-	// Obtener partición y superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
-	if err != nil {
-		return "", err
+	res.Set("contents", contents)
+	if len(encodings) > 0 {
+		res.Set("encodings", encodings)
 	}
+	if len(fileErrors) > 0 {
+		res.Set("errors", fileErrors)
+	}
+
+	return res, nil
+}
 
-	// ← CAMBIO: Usar navegación por rutas completas
-	inodeIndex, err := findFileAtPath(file, superblock, filePath)
+// Leer archivo del sistema EXT2 con verificación de permisos. También devuelve
+// el inodo leído, para actualizar su fecha de acceso.
+func readFileFromEXT2WithPermissions(mounted *MountedPartition, filePath string, currentUser string) ([]byte, int64, error) {
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
 	if err != nil {
-		return "", fmt.Errorf("archivo '%s' no encontrado", filePath)
+		return nil, -1, err
 	}
+	defer fs.Close()
 
-	// Leer el inodo del archivo
-	inodePosition := superblock.S_inode_start + (inodeIndex * superblock.S_inode_s)
-	file.Seek(inodePosition, 0)
-	var fileInode structs.Inodos
-	if err := binary.Read(file, binary.LittleEndian, &fileInode); err != nil {
-		return "", fmt.Errorf("error al leer el inodo del archivo: %v", err)
+	inodeIndex, fileInode, err := fs.Lookup(filePath)
+	if err != nil {
+		return nil, -1, wrapCommandError(ErrCodeNotFound, err, "archivo '%s' no encontrado", filePath)
 	}
 
 	// Verificar que es un archivo (no directorio)
 	if fileInode.I_type != '1' {
-		return "", fmt.Errorf("'%s' no es un archivo", filePath)
+		return nil, -1, fmt.Errorf("'%s' no es un archivo", filePath)
 	}
 
 	// Verificar permisos de lectura
-	if !hasReadPermission(&fileInode, currentUser) {
-		return "", fmt.Errorf("sin permisos de lectura para el archivo '%s'", filePath)
+	if !hasReadPermission(fileInode, currentUser) {
+		return nil, -1, newCommandError(ErrCodePermissionDenied, "sin permisos de lectura para el archivo '%s'", filePath)
 	}
 
 	// Leer el contenido del archivo usando función multi-bloque
-	content, err := readFileContentMultiBlock(file, superblock, &fileInode)
+	content, err := fs.ReadFile(fileInode)
 	if err != nil {
-		return "", fmt.Errorf("error al leer el contenido del archivo: %v", err)
+		return nil, -1, fmt.Errorf("error al leer el contenido del archivo: %v", err)
 	}
 
-	return content, nil
+	return content, inodeIndex, nil
 }
 
 // Verificar permisos de lectura
@@ -142,42 +165,8 @@ func hasReadPermission(inode *structs.Inodos, currentUser string) bool {
 	ownerPerms := permissions[0]
 
 	// Verificar si el propietario tiene permiso de lectura
-	// En octal: 4 = lectura, 6 = lectura+escritura, 7 = lectura+escritura+ejecución
-	if ownerPerms >= '4' {
-		return true
-	}
-
-	return false
-}
-
-func findFileAtPath(file *os.File, superblock *structs.SuperBloque, filePath string) (int64, error) {
-	// Parsear la ruta del archivo
-	parsedPath := parsePath(filePath)
-	if parsedPath == nil {
-		return -1, fmt.Errorf("ruta inválida: %s", filePath)
-	}
-
-	// Si no es absoluta, buscar en directorio raíz (compatibilidad)
-	if !parsedPath.IsAbsolute {
-		return findFileInRootDirectory(file, superblock, filePath)
-	}
-
-	// Navegar hasta el directorio padre
-	currentInodeIndex := int64(0) // Empezar desde el directorio raíz
-
-	for _, dirName := range parsedPath.Directories {
-		nextInode, err := findInodeInDirectory(file, superblock, currentInodeIndex, dirName)
-		if err != nil {
-			return -1, fmt.Errorf("directorio '%s' no encontrado", dirName)
-		}
-		currentInodeIndex = nextInode
-	}
-
-	// Buscar el archivo en el directorio final
-	fileInode, err := findInodeInDirectory(file, superblock, currentInodeIndex, parsedPath.FileName)
-	if err != nil {
-		return -1, fmt.Errorf("archivo '%s' no encontrado", parsedPath.FileName)
-	}
-
-	return fileInode, nil
+	// En octal: 4 = lectura, 6 = lectura+escritura, 7 = lectura+escritura+ejecución.
+	// mkfs y chmod guardan dígitos ASCII ('6') y los inodos nuevos el valor (6):
+	// el bit de lectura coincide en ambos casos.
+	return ownerPerms&4 != 0
 }
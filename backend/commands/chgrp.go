@@ -1,106 +1,82 @@
 package commands
 
 import (
-	"backend/structs"
-	"encoding/binary"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 )
 
-func ExecuteChgrp(username, newGroupName string) {
+func ExecuteChgrp(session *Session, username, newGroupName string) (*CommandResult, error) {
+	res := NewCommandResult("chgrp")
+
 	// Verificar sesión activa
-	if !RequireActiveSession() {
-		return
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
 	}
 
 	// Validar parámetros obligatorios
 	if username == "" {
-		fmt.Println("Error: el parámetro -user es obligatorio para chgrp.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -user es obligatorio para chgrp")
 	}
 	if newGroupName == "" {
-		fmt.Println("Error: el parámetro -grp es obligatorio para chgrp.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -grp es obligatorio para chgrp")
 	}
 
-	// Obtener sesión actual
-	session := GetCurrentSession()
+	// Validar la sesión recibida
 	if session == nil {
-		fmt.Println("Error: No se pudo obtener la sesión actual.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no se pudo obtener la sesión actual")
 	}
 
 	// Verificar que solo root puede cambiar grupos
 	if !session.IsRoot {
-		fmt.Printf("Error: Solo el usuario 'root' puede cambiar grupos. Usuario actual: '%s'\n", session.User)
-		return
+		return res, newCommandError(ErrCodePermissionDenied, "solo el usuario 'root' puede cambiar grupos. Usuario actual: '%s'", session.User)
 	}
 
 	// Buscar la partición montada de la sesión
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: No se encontró la partición montada con ID '%s'.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
 	}
 
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
+	}
+	defer tx.rollback()
+
 	// Cambiar el grupo del usuario en el archivo users.txt
-	err := changeUserGroupInUsersFile(mounted, username, newGroupName)
+	err = changeUserGroupInUsersFile(mounted, username, newGroupName)
 	if err != nil {
-		fmt.Printf("Error al cambiar el grupo del usuario '%s': %v\n", username, err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al cambiar el grupo del usuario '%s'", username)
 	}
 
-	fmt.Printf("Grupo del usuario '%s' cambiado exitosamente a '%s'.\n", username, newGroupName)
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	res.AddPath("/users.txt")
+	res.Set("user", username)
+	res.Set("group", newGroupName)
+	res.Printf("Grupo del usuario '%s' cambiado exitosamente a '%s'.\n", username, newGroupName)
+
+	return res, nil
 }
 
 // Cambiar grupo del usuario en el archivo users.txt
 func changeUserGroupInUsersFile(mounted *MountedPartition, username, newGroupName string) error {
 	// Abrir el archivo del disco
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+	file, err := openMountedDisk(mounted, os.O_RDWR)
 	if err != nil {
 		return fmt.Errorf("error al abrir el disco: %v", err)
 	}
 	defer file.Close()
 
-	// Leer el MBR para obtener información de la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		return fmt.Errorf("error al leer el MBR: %v", err)
-	}
-
-	// Encontrar la partición específica
-	var partition *structs.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_status != '0' {
-			partitionNameBytes := p.Part_name[:]
-			nullIndex := len(partitionNameBytes)
-			for j, b := range partitionNameBytes {
-				if b == 0 {
-					nullIndex = j
-					break
-				}
-			}
-			partitionName := strings.TrimSpace(string(partitionNameBytes[:nullIndex]))
-
-			if strings.EqualFold(partitionName, mounted.Name) || partitionName == mounted.Name {
-				partitionCopy := p
-				partition = &partitionCopy
-				break
-			}
-		}
-	}
-
-	if partition == nil {
-		return fmt.Errorf("no se pudo encontrar la partición '%s'", mounted.Name)
-	}
-
-	// Leer el superbloque
-	file.Seek(partition.Part_start, 0)
-	var superblock structs.SuperBloque
-	if err := binary.Read(file, binary.LittleEndian, &superblock); err != nil {
-		return fmt.Errorf("error al leer el superbloque: %v", err)
+	// Verificar que la partición (del MBR, lógica o GPT) tenga un sistema de archivos
+	if _, err := mountedFileSystem(file, mounted); err != nil {
+		return err
 	}
 
 	// Leer el contenido actual del archivo users.txt
@@ -110,12 +86,11 @@ func changeUserGroupInUsersFile(mounted *MountedPartition, username, newGroupNam
 	}
 
 	// Validar que el usuario existe y el grupo destino existe
-	newGroupGID, err := validateUserAndGroup(currentContent, username, newGroupName)
-	if err != nil {
+	if _, err := validateUserAndGroup(currentContent, username, newGroupName); err != nil {
 		return err
 	}
 
-	newContent, changed, err := changeUserGroup(currentContent, username, newGroupName, newGroupGID)
+	newContent, changed, err := changeUserGroup(currentContent, username, newGroupName)
 	if err != nil {
 		return err
 	}
@@ -176,19 +151,19 @@ func validateUserAndGroup(content, username, newGroupName string) (string, error
 
 	// Verificar que el usuario existe
 	if !userExists {
-		return "", fmt.Errorf("el usuario '%s' no existe", username)
+		return "", newCommandError(ErrCodeNotFound, "el usuario '%s' no existe", username)
 	}
 
 	// Verificar que el grupo destino existe
 	if !groupExists {
-		return "", fmt.Errorf("el grupo '%s' no existe o está eliminado", newGroupName)
+		return "", newCommandError(ErrCodeNotFound, "el grupo '%s' no existe o está eliminado", newGroupName)
 	}
 
 	// Retornar el GID del grupo destino
 	return newGroupGID, nil
 }
 
-func changeUserGroup(content, username, newGroupName, newGroupGID string) (string, bool, error) {
+func changeUserGroup(content, username, newGroupName string) (string, bool, error) {
 	lines := strings.Split(content, "\n")
 	var newLines []string
 	changed := false
@@ -224,11 +199,11 @@ func changeUserGroup(content, username, newGroupName, newGroupGID string) (strin
 					return "", false, fmt.Errorf("el usuario '%s' ya pertenece al grupo '%s'", username, newGroupName)
 				}
 
-				newLine := fmt.Sprintf("%s,U,%s,%s,%s", newGroupGID, newGroupName, user, password)
+				// El usuario conserva su UID; solo cambia de grupo
+				newLine := fmt.Sprintf("%s,U,%s,%s,%s", uidStr, newGroupName, user, password)
 				newLines = append(newLines, newLine)
 				changed = true
 
-				fmt.Printf("Usuario actualizado: %s -> %s\n", originalLine, newLine)
 			} else {
 				newLines = append(newLines, originalLine)
 			}
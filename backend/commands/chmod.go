@@ -1,47 +1,39 @@
 package commands
 
 import (
-	"backend/structs"
-	"encoding/binary"
-	"fmt"
 	"os"
-	"strconv"
 	"strings"
 )
 
 // ExecuteChmod - Cambiar los permisos de un archivo o carpeta
-func ExecuteChmod(path string, recursive bool, ugo string) {
+func ExecuteChmod(session *Session, path string, recursive bool, ugo string) (*CommandResult, error) {
+	res := NewCommandResult("chmod")
+
 	// Validar parámetros obligatorios
 	if path == "" {
-		fmt.Println("Error: el parámetro -path es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio")
 	}
 
 	if ugo == "" {
-		fmt.Println("Error: el parámetro -ugo es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -ugo es obligatorio")
 	}
 
 	// Validar formato de permisos (3 dígitos del 0-7)
 	if len(ugo) != 3 {
-		fmt.Println("Error: el parámetro -ugo debe tener exactamente 3 dígitos (ejemplo: 764).")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -ugo debe tener exactamente 3 dígitos (ejemplo: 764)")
 	}
 
 	// Validar que cada dígito esté en el rango 0-7
 	for i, char := range ugo {
 		digit := int(char - '0')
 		if digit < 0 || digit > 7 {
-			fmt.Printf("Error: el dígito %d del parámetro -ugo debe estar entre 0 y 7 (valor recibido: %c).\n", i+1, char)
-			return
+			return res, newCommandError(ErrCodeInvalidArgument, "el dígito %d del parámetro -ugo debe estar entre 0 y 7 (valor recibido: %c)", i+1, char)
 		}
 	}
 
 	// Validar que hay una sesión activa
-	session := GetCurrentSession()
 	if session == nil {
-		fmt.Println("Error: no hay ninguna sesión activa. Use el comando 'login' primero.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no hay ninguna sesión activa. Use el comando 'login' primero")
 	}
 
 	// Normalizar la ruta
@@ -53,58 +45,33 @@ func ExecuteChmod(path string, recursive bool, ugo string) {
 	// Abrir el disco montado
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: la partición '%s' no está montada.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "la partición '%s' no está montada", session.PartitionID)
 	}
 
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
 	if err != nil {
-		fmt.Printf("Error al abrir el disco: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
 	}
-	defer file.Close()
+	defer tx.rollback()
 
-	// Obtener superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
+	fs, err := openFileSystem(mounted, os.O_RDWR)
 	if err != nil {
-		fmt.Printf("Error al obtener superbloque: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el sistema de archivos")
 	}
+	defer fs.Close()
 
 	// Determinar el inodo del archivo/carpeta
-	var targetInodeNum int64
-
-	if path == "/" {
-		targetInodeNum = 0
-	} else {
-		parsedPath := parsePath(path)
-		if parsedPath == nil {
-			fmt.Printf("Error: ruta inválida '%s'.\n", path)
-			return
-		}
-
-		targetInodeNum, err = findItemByPath(file, superblock, parsedPath)
-		if err != nil {
-			fmt.Printf("Error: no se encontró la ruta '%s': %v\n", path, err)
-			return
-		}
-	}
-
-	// Leer el inodo del archivo/carpeta
-	var targetInode structs.Inodos
-	targetInodePos := superblock.S_inode_start + (targetInodeNum * superblock.S_inode_s)
-	file.Seek(targetInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &targetInode); err != nil {
-		fmt.Printf("Error al leer el inodo: %v\n", err)
-		return
+	targetInodeNum, targetInode, err := fs.Lookup(path)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeNotFound, err, "no se encontró la ruta '%s'", path)
 	}
 
 	// Verificar permisos: solo root o el propietario pueden cambiar permisos
 	if !session.IsRoot {
-		currentUID, err := getUserIDByName(file, superblock, session.User)
-		if err != nil || currentUID != targetInode.I_uid {
-			fmt.Printf("Error: solo el propietario o root pueden cambiar los permisos de '%s'.\n", path)
-			return
+		userInfo, err := fs.UserInfo(session.User)
+		if err != nil || userInfo.UID != targetInode.I_uid {
+			return res, newCommandError(ErrCodePermissionDenied, "solo el propietario o root pueden cambiar los permisos de '%s'", path)
 		}
 	}
 
@@ -112,54 +79,60 @@ func ExecuteChmod(path string, recursive bool, ugo string) {
 	changedCount := 0
 	if recursive && targetInode.I_type == '0' {
 		// Cambiar recursivamente
-		fmt.Printf("🔐 Cambiando permisos de '%s' recursivamente a %s...\n", path, ugo)
+		res.Printf("🔐 Cambiando permisos de '%s' recursivamente a %s...\n", path, ugo)
 		currentUser := session.User
 		if session.IsRoot {
 			currentUser = "root"
 		}
-		chmodRecursive(file, superblock, targetInodeNum, ugo, currentUser, &changedCount)
+		chmodRecursive(fs, targetInodeNum, ugo, currentUser, &changedCount)
 	} else {
 		// Cambiar solo el archivo/carpeta especificado
-		fmt.Printf("🔐 Cambiando permisos de '%s' a %s...\n", path, ugo)
+		res.Printf("🔐 Cambiando permisos de '%s' a %s...\n", path, ugo)
 
 		// Actualizar permisos
 		copy(targetInode.I_perm[:], ugo)
+		touchChange(targetInode)
 
 		// Escribir el inodo actualizado
-		file.Seek(targetInodePos, 0)
-		if err := binary.Write(file, binary.LittleEndian, &targetInode); err != nil {
-			fmt.Printf("Error al actualizar el inodo: %v\n", err)
-			return
+		if err := fs.WriteInode(targetInodeNum, targetInode); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el inodo")
 		}
 		changedCount = 1
 	}
 
 	// Actualizar el superbloque
-	file.Seek(superblock.S_inode_start-int64(binary.Size(structs.SuperBloque{})), 0)
-	if err := binary.Write(file, binary.LittleEndian, superblock); err != nil {
-		fmt.Printf("Error al actualizar el superbloque: %v\n", err)
-		return
+	if err := fs.Flush(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
+	}
+
+	res.AddPath(path)
+	res.Set("inode", targetInodeNum)
+	res.Set("permissions", ugo)
+	res.Set("changed", changedCount)
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
 	}
 
-	fmt.Printf("✅ Permisos cambiados exitosamente.\n")
-	fmt.Printf("   📄 Archivos/Carpetas modificados: %d\n", changedCount)
+	res.Printf("✅ Permisos cambiados exitosamente.\n")
+	res.Printf("   📄 Archivos/Carpetas modificados: %d\n", changedCount)
+
+	return res, nil
 }
 
 // chmodRecursive - Cambiar permisos recursivamente
-func chmodRecursive(file *os.File, superblock *structs.SuperBloque, dirInodeNum int64, permissions string, currentUser string, changedCount *int) {
+func chmodRecursive(fs *FileSystem, dirInodeNum int64, permissions string, currentUser string, changedCount *int) {
 	// Leer el inodo del directorio
-	var dirInode structs.Inodos
-	dirInodePos := superblock.S_inode_start + (dirInodeNum * superblock.S_inode_s)
-	file.Seek(dirInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &dirInode); err != nil {
+	dirInode, err := fs.ReadInode(dirInodeNum)
+	if err != nil {
 		return
 	}
 
 	// Verificar si el archivo/carpeta pertenece al usuario actual (si no es root)
 	shouldChange := currentUser == "root"
 	if !shouldChange {
-		currentUID, err := getUserIDByName(file, superblock, currentUser)
-		if err == nil && currentUID == dirInode.I_uid {
+		userInfo, err := fs.UserInfo(currentUser)
+		if err == nil && userInfo.UID == dirInode.I_uid {
 			shouldChange = true
 		}
 	}
@@ -167,8 +140,8 @@ func chmodRecursive(file *os.File, superblock *structs.SuperBloque, dirInodeNum
 	// Cambiar los permisos del directorio actual si corresponde
 	if shouldChange {
 		copy(dirInode.I_perm[:], permissions)
-		file.Seek(dirInodePos, 0)
-		if err := binary.Write(file, binary.LittleEndian, &dirInode); err != nil {
+		touchChange(dirInode)
+		if err := fs.WriteInode(dirInodeNum, dirInode); err != nil {
 			return
 		}
 		*changedCount++
@@ -179,116 +152,40 @@ func chmodRecursive(file *os.File, superblock *structs.SuperBloque, dirInodeNum
 		return
 	}
 
-	// Recorrer los bloques del directorio
-	for i := 0; i < 15 && dirInode.I_block[i] != -1; i++ {
-		blockPos := superblock.S_block_start + (dirInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPos, 0)
-
-		var folderBlock structs.BloqueCarpeta
-		if err := binary.Read(file, binary.LittleEndian, &folderBlock); err != nil {
-			continue
-		}
-
-		// Recorrer cada entrada
-		for j := 0; j < 4; j++ {
-			entryName := strings.TrimRight(string(folderBlock.BContent[j].BName[:]), "\x00")
-			entryName = strings.TrimSpace(entryName)
-
-			// Saltar entradas vacías, . y ..
-			if entryName == "" || entryName == "." || entryName == ".." {
-				continue
-			}
-
-			entryInodeNum := folderBlock.BContent[j].BInodo
-			if entryInodeNum == -1 {
-				continue
-			}
-
-			// Leer el inodo de la entrada
-			var entryInode structs.Inodos
-			entryInodePos := superblock.S_inode_start + (entryInodeNum * superblock.S_inode_s)
-			file.Seek(entryInodePos, 0)
-			if err := binary.Read(file, binary.LittleEndian, &entryInode); err != nil {
-				continue
-			}
-
-			// Si es un directorio, procesar recursivamente
-			if entryInode.I_type == '0' {
-				chmodRecursive(file, superblock, entryInodeNum, permissions, currentUser, changedCount)
-			} else {
-				// Si es un archivo, verificar propiedad y cambiar permisos
-				shouldChangeFile := currentUser == "root"
-				if !shouldChangeFile {
-					currentUID, err := getUserIDByName(file, superblock, currentUser)
-					if err == nil && currentUID == entryInode.I_uid {
-						shouldChangeFile = true
-					}
-				}
-
-				if shouldChangeFile {
-					copy(entryInode.I_perm[:], permissions)
-					file.Seek(entryInodePos, 0)
-					if err := binary.Write(file, binary.LittleEndian, &entryInode); err != nil {
-						continue
-					}
-					*changedCount++
-				}
-			}
-		}
-	}
-}
-
-// getUserIDByName - Obtener el UID de un usuario por su nombre
-func getUserIDByName(file *os.File, superblock *structs.SuperBloque, username string) (int64, error) {
-	// Buscar en el archivo users.txt (inodo 1)
-	usersInodeNum := int64(1)
-
-	var usersInode structs.Inodos
-	usersInodePos := superblock.S_inode_start + (usersInodeNum * superblock.S_inode_s)
-	file.Seek(usersInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &usersInode); err != nil {
-		return -1, fmt.Errorf("no se pudo leer el archivo de usuarios")
+	// Recorrer las entradas del directorio
+	entries, err := fs.ReadDir(dirInodeNum)
+	if err != nil {
+		return
 	}
-
-	// Leer el contenido del archivo users.txt
-	var content strings.Builder
-	for i := 0; i < 15 && usersInode.I_block[i] != -1; i++ {
-		blockPos := superblock.S_block_start + (usersInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPos, 0)
-
-		var fileBlock structs.BloqueArchivo
-		if err := binary.Read(file, binary.LittleEndian, &fileBlock); err != nil {
+	for _, entry := range entries {
+		// Leer el inodo de la entrada
+		entryInode, err := fs.ReadInode(entry.Inode)
+		if err != nil {
 			continue
 		}
 
-		blockContent := string(fileBlock.BContent[:])
-		blockContent = strings.TrimRight(blockContent, "\x00")
-		content.WriteString(blockContent)
-	}
-
-	// Parsear el contenido
-	lines := strings.Split(content.String(), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+		// Si es un directorio, procesar recursivamente
+		if entryInode.I_type == '0' {
+			chmodRecursive(fs, entry.Inode, permissions, currentUser, changedCount)
 			continue
 		}
 
-		// Formato: UID,tipo,grupo,usuario,contraseña
-		parts := strings.Split(line, ",")
-		if len(parts) >= 4 {
-			uid := strings.TrimSpace(parts[0])
-			user := strings.TrimSpace(parts[3])
+		// Si es un archivo, verificar propiedad y cambiar permisos
+		shouldChangeFile := currentUser == "root"
+		if !shouldChangeFile {
+			userInfo, err := fs.UserInfo(currentUser)
+			if err == nil && userInfo.UID == entryInode.I_uid {
+				shouldChangeFile = true
+			}
+		}
 
-			if user == username {
-				uidNum, err := strconv.ParseInt(uid, 10, 64)
-				if err != nil {
-					return -1, fmt.Errorf("UID inválido para el usuario %s", username)
-				}
-				return uidNum, nil
+		if shouldChangeFile {
+			copy(entryInode.I_perm[:], permissions)
+			touchChange(entryInode)
+			if err := fs.WriteInode(entry.Inode, entryInode); err != nil {
+				continue
 			}
+			*changedCount++
 		}
 	}
-
-	return -1, fmt.Errorf("usuario no encontrado")
 }
@@ -1,32 +1,27 @@
 package commands
 
 import (
-	"backend/structs"
-	"encoding/binary"
 	"fmt"
 	"os"
-	"strconv"
 	"strings"
 )
 
 // ExecuteChown - Cambiar el propietario de un archivo o carpeta
-func ExecuteChown(path string, recursive bool, usuario string) {
+func ExecuteChown(session *Session, path string, recursive bool, usuario string) (*CommandResult, error) {
+	res := NewCommandResult("chown")
+
 	// Validar parámetros obligatorios
 	if path == "" {
-		fmt.Println("Error: el parámetro -path es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio")
 	}
 
 	if usuario == "" {
-		fmt.Println("Error: el parámetro -usuario es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -usuario es obligatorio")
 	}
 
 	// Validar que hay una sesión activa
-	session := GetCurrentSession()
 	if session == nil {
-		fmt.Println("Error: no hay ninguna sesión activa. Use el comando 'login' primero.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no hay ninguna sesión activa. Use el comando 'login' primero")
 	}
 
 	// Normalizar la ruta
@@ -38,57 +33,33 @@ func ExecuteChown(path string, recursive bool, usuario string) {
 	// Abrir el disco montado
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: la partición '%s' no está montada.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "la partición '%s' no está montada", session.PartitionID)
 	}
 
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
 	if err != nil {
-		fmt.Printf("Error al abrir el disco: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
 	}
-	defer file.Close()
+	defer tx.rollback()
 
-	// Obtener superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
+	fs, err := openFileSystem(mounted, os.O_RDWR)
 	if err != nil {
-		fmt.Printf("Error al obtener superbloque: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el sistema de archivos")
 	}
+	defer fs.Close()
 
 	// Verificar que el usuario objetivo existe
-	targetUID, err := getUserID(file, superblock, usuario)
+	targetUser, err := fs.UserInfo(usuario)
 	if err != nil {
-		fmt.Printf("Error: el usuario '%s' no existe.\n", usuario)
-		return
+		return res, newCommandError(ErrCodeNotFound, "el usuario '%s' no existe", usuario)
 	}
+	targetUID := targetUser.UID
 
 	// Determinar el inodo del archivo/carpeta
-	var targetInodeNum int64
-
-	if path == "/" {
-		targetInodeNum = 0
-	} else {
-		parsedPath := parsePath(path)
-		if parsedPath == nil {
-			fmt.Printf("Error: ruta inválida '%s'.\n", path)
-			return
-		}
-
-		targetInodeNum, err = findItemByPath(file, superblock, parsedPath)
-		if err != nil {
-			fmt.Printf("Error: no se encontró la ruta '%s': %v\n", path, err)
-			return
-		}
-	}
-
-	// Leer el inodo del archivo/carpeta
-	var targetInode structs.Inodos
-	targetInodePos := superblock.S_inode_start + (targetInodeNum * superblock.S_inode_s)
-	file.Seek(targetInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &targetInode); err != nil {
-		fmt.Printf("Error al leer el inodo: %v\n", err)
-		return
+	targetInodeNum, targetInode, err := fs.Lookup(path)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeNotFound, err, "no se encontró la ruta '%s'", path)
 	}
 
 	// Verificar permisos: solo root o el propietario pueden cambiar el dueño
@@ -96,8 +67,7 @@ func ExecuteChown(path string, recursive bool, usuario string) {
 		// El usuario actual debe ser el propietario
 		currentUID := fmt.Sprintf("%d", targetInode.I_uid)
 		if session.User != currentUID {
-			fmt.Printf("Error: solo el propietario o root pueden cambiar el dueño de '%s'.\n", path)
-			return
+			return res, newCommandError(ErrCodePermissionDenied, "solo el propietario o root pueden cambiar el dueño de '%s'", path)
 		}
 	}
 
@@ -105,47 +75,54 @@ func ExecuteChown(path string, recursive bool, usuario string) {
 	changedCount := 0
 	if recursive && targetInode.I_type == '0' {
 		// Cambiar recursivamente
-		fmt.Printf("📝 Cambiando propietario de '%s' recursivamente a '%s'...\n", path, usuario)
-		chownRecursive(file, superblock, targetInodeNum, targetUID, &changedCount)
+		res.Printf("📝 Cambiando propietario de '%s' recursivamente a '%s'...\n", path, usuario)
+		chownRecursive(fs, targetInodeNum, targetUID, &changedCount)
 	} else {
 		// Cambiar solo el archivo/carpeta especificado
-		fmt.Printf("📝 Cambiando propietario de '%s' a '%s'...\n", path, usuario)
+		res.Printf("📝 Cambiando propietario de '%s' a '%s'...\n", path, usuario)
 		targetInode.I_uid = targetUID
+		touchChange(targetInode)
 
 		// Escribir el inodo actualizado
-		file.Seek(targetInodePos, 0)
-		if err := binary.Write(file, binary.LittleEndian, &targetInode); err != nil {
-			fmt.Printf("Error al actualizar el inodo: %v\n", err)
-			return
+		if err := fs.WriteInode(targetInodeNum, targetInode); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el inodo")
 		}
 		changedCount = 1
 	}
 
 	// Actualizar el superbloque
-	file.Seek(superblock.S_inode_start-int64(binary.Size(structs.SuperBloque{})), 0)
-	if err := binary.Write(file, binary.LittleEndian, superblock); err != nil {
-		fmt.Printf("Error al actualizar el superbloque: %v\n", err)
-		return
+	if err := fs.Flush(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
+	}
+
+	res.AddPath(path)
+	res.Set("inode", targetInodeNum)
+	res.Set("owner", usuario)
+	res.Set("uid", targetUID)
+	res.Set("changed", changedCount)
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
 	}
 
-	fmt.Printf("✅ Propietario cambiado exitosamente.\n")
-	fmt.Printf("   📄 Archivos/Carpetas modificados: %d\n", changedCount)
+	res.Printf("✅ Propietario cambiado exitosamente.\n")
+	res.Printf("   📄 Archivos/Carpetas modificados: %d\n", changedCount)
+
+	return res, nil
 }
 
 // chownRecursive - Cambiar propietario recursivamente
-func chownRecursive(file *os.File, superblock *structs.SuperBloque, dirInodeNum int64, newUID int64, changedCount *int) {
+func chownRecursive(fs *FileSystem, dirInodeNum int64, newUID int64, changedCount *int) {
 	// Leer el inodo del directorio
-	var dirInode structs.Inodos
-	dirInodePos := superblock.S_inode_start + (dirInodeNum * superblock.S_inode_s)
-	file.Seek(dirInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &dirInode); err != nil {
+	dirInode, err := fs.ReadInode(dirInodeNum)
+	if err != nil {
 		return
 	}
 
 	// Cambiar el propietario del directorio actual
 	dirInode.I_uid = newUID
-	file.Seek(dirInodePos, 0)
-	if err := binary.Write(file, binary.LittleEndian, &dirInode); err != nil {
+	touchChange(dirInode)
+	if err := fs.WriteInode(dirInodeNum, dirInode); err != nil {
 		return
 	}
 	*changedCount++
@@ -155,106 +132,30 @@ func chownRecursive(file *os.File, superblock *structs.SuperBloque, dirInodeNum
 		return
 	}
 
-	// Recorrer los bloques del directorio
-	for i := 0; i < 15 && dirInode.I_block[i] != -1; i++ {
-		blockPos := superblock.S_block_start + (dirInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPos, 0)
-
-		var folderBlock structs.BloqueCarpeta
-		if err := binary.Read(file, binary.LittleEndian, &folderBlock); err != nil {
-			continue
-		}
-
-		// Recorrer cada entrada
-		for j := 0; j < 4; j++ {
-			entryName := strings.TrimRight(string(folderBlock.BContent[j].BName[:]), "\x00")
-			entryName = strings.TrimSpace(entryName)
-
-			// Saltar entradas vacías, . y ..
-			if entryName == "" || entryName == "." || entryName == ".." {
-				continue
-			}
-
-			entryInodeNum := folderBlock.BContent[j].BInodo
-			if entryInodeNum == -1 {
-				continue
-			}
-
-			// Leer el inodo de la entrada
-			var entryInode structs.Inodos
-			entryInodePos := superblock.S_inode_start + (entryInodeNum * superblock.S_inode_s)
-			file.Seek(entryInodePos, 0)
-			if err := binary.Read(file, binary.LittleEndian, &entryInode); err != nil {
-				continue
-			}
-
-			// Si es un directorio, procesar recursivamente
-			if entryInode.I_type == '0' {
-				chownRecursive(file, superblock, entryInodeNum, newUID, changedCount)
-			} else {
-				// Si es un archivo, cambiar el propietario
-				entryInode.I_uid = newUID
-				file.Seek(entryInodePos, 0)
-				if err := binary.Write(file, binary.LittleEndian, &entryInode); err != nil {
-					continue
-				}
-				*changedCount++
-			}
-		}
-	}
-}
-
-// getUserID - Obtener el UID de un usuario por su nombre
-func getUserID(file *os.File, superblock *structs.SuperBloque, username string) (int64, error) {
-	// Buscar en el archivo users.txt (inodo 1)
-	usersInodeNum := int64(1)
-
-	var usersInode structs.Inodos
-	usersInodePos := superblock.S_inode_start + (usersInodeNum * superblock.S_inode_s)
-	file.Seek(usersInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &usersInode); err != nil {
-		return -1, fmt.Errorf("no se pudo leer el archivo de usuarios")
+	// Recorrer las entradas del directorio
+	entries, err := fs.ReadDir(dirInodeNum)
+	if err != nil {
+		return
 	}
-
-	// Leer el contenido del archivo users.txt
-	var content strings.Builder
-	for i := 0; i < 15 && usersInode.I_block[i] != -1; i++ {
-		blockPos := superblock.S_block_start + (usersInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPos, 0)
-
-		var fileBlock structs.BloqueArchivo
-		if err := binary.Read(file, binary.LittleEndian, &fileBlock); err != nil {
+	for _, entry := range entries {
+		// Leer el inodo de la entrada
+		entryInode, err := fs.ReadInode(entry.Inode)
+		if err != nil {
 			continue
 		}
 
-		blockContent := string(fileBlock.BContent[:])
-		blockContent = strings.TrimRight(blockContent, "\x00")
-		content.WriteString(blockContent)
-	}
-
-	// Parsear el contenido
-	lines := strings.Split(content.String(), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
+		// Si es un directorio, procesar recursivamente
+		if entryInode.I_type == '0' {
+			chownRecursive(fs, entry.Inode, newUID, changedCount)
 			continue
 		}
 
-		// Formato: UID,tipo,grupo,usuario,contraseña
-		parts := strings.Split(line, ",")
-		if len(parts) >= 4 {
-			uid := strings.TrimSpace(parts[0])
-			user := strings.TrimSpace(parts[3])
-
-			if user == username {
-				uidNum, err := strconv.ParseInt(uid, 10, 64)
-				if err != nil {
-					return -1, fmt.Errorf("UID inválido para el usuario %s", username)
-				}
-				return uidNum, nil
-			}
+		// Si es un archivo, cambiar el propietario
+		entryInode.I_uid = newUID
+		touchChange(entryInode)
+		if err := fs.WriteInode(entry.Inode, entryInode); err != nil {
+			continue
 		}
+		*changedCount++
 	}
-
-	return -1, fmt.Errorf("usuario no encontrado")
 }
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Pruebas de concurrencia: varias goroutines ejecutan comandos sobre el mismo
+// disco como lo haría el servidor HTTP con peticiones simultáneas. Se corren
+// con el detector de carreras: go test -race ./commands/
+
+// TestConcurrentMkfileMkdir - Crear carpetas y archivos en paralelo sin repetir inodos
+func TestConcurrentMkfileMkdir(t *testing.T) {
+	img := newCrashImage(t)
+
+	const workers = 8
+	const filesPerWorker = 4
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	inodes := make(map[int64]string)
+	errs := make(chan error, workers*(filesPerWorker+1)+workers)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			dir := fmt.Sprintf("/par/g%d", w)
+			if _, err := ExecuteMkdir(img.session, dir, true); err != nil {
+				errs <- fmt.Errorf("mkdir %s: %v", dir, err)
+				return
+			}
+			for f := 0; f < filesPerWorker; f++ {
+				path := fmt.Sprintf("%s/f%d.txt", dir, f)
+				res, err := ExecuteMkfile(img.session, path, false, 100+w*10+f, "")
+				if err != nil {
+					errs <- fmt.Errorf("mkfile %s: %v", path, err)
+					continue
+				}
+				inode, _ := res.Data["inode"].(int64)
+				mu.Lock()
+				if other, dup := inodes[inode]; dup {
+					errs <- fmt.Errorf("inodo %d asignado a %s y a %s", inode, other, path)
+				}
+				inodes[inode] = path
+				mu.Unlock()
+			}
+		}(w)
+	}
+
+	// Lectores simultáneos: no deben fallar ni ver estados a medias
+	for r := 0; r < workers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ExecuteCat(img.session, map[string]string{"file1": "/docs/a.txt"}); err != nil {
+				errs <- fmt.Errorf("cat: %v", err)
+			}
+			if _, err := GetFilesList(img.session, img.mounted, "/docs"); err != nil {
+				errs <- fmt.Errorf("listar /docs: %v", err)
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if len(inodes) != workers*filesPerWorker {
+		t.Fatalf("se esperaban %d archivos, se crearon %d", workers*filesPerWorker, len(inodes))
+	}
+	img.checkClean(t, "creación en paralelo")
+
+	// Cada archivo conserva su propio contenido
+	for w := 0; w < workers; w++ {
+		for f := 0; f < filesPerWorker; f++ {
+			path := fmt.Sprintf("/par/g%d/f%d.txt", w, f)
+			res, err := ReadFileByPath(img.mounted, path)
+			if err != nil {
+				t.Fatalf("leer %s: %v", path, err)
+			}
+			content, _ := res.Data["content"].([]byte)
+			if len(content) != 100+w*10+f {
+				t.Errorf("%s: se esperaban %d bytes, se leyeron %d", path, 100+w*10+f, len(content))
+			}
+		}
+	}
+}
+
+// TestConcurrentMounts - Montar y consultar particiones de varios discos a la vez
+func TestConcurrentMounts(t *testing.T) {
+	dir := t.TempDir()
+
+	const disks = 4
+	paths := make([]string, disks)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("disco%d.mia", i))
+		if _, err := ExecuteMkdisk(1, "m", "ff", paths[i], "mbr", 0); err != nil {
+			t.Fatalf("mkdisk: %v", err)
+		}
+		for _, name := range []string{"p1", "p2"} {
+			if _, err := ExecuteFdisk(200, "k", paths[i], "p", "wf", name, "", 0); err != nil {
+				t.Fatalf("fdisk %s: %v", name, err)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	ids := make(map[string]bool)
+	errs := make(chan error, disks*2)
+
+	for _, path := range paths {
+		for _, name := range []string{"p1", "p2"} {
+			wg.Add(1)
+			go func(path, name string) {
+				defer wg.Done()
+				res, err := ExecuteMount(path, name)
+				if err != nil {
+					errs <- fmt.Errorf("mount %s/%s: %v", path, name, err)
+					return
+				}
+				id, _ := res.Data["id"].(string)
+				mu.Lock()
+				if ids[id] {
+					errs <- fmt.Errorf("ID %s repetido", id)
+				}
+				ids[id] = true
+				mu.Unlock()
+
+				// Consultas mientras otras goroutines montan
+				if GetMountedPartition(id) == nil {
+					errs <- fmt.Errorf("la partición %s no aparece montada", id)
+				}
+				GetMountedPartitionsOnly()
+			}(path, name)
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for id := range ids {
+		if _, err := ExecuteUnmount(id); err != nil {
+			t.Errorf("unmount %s: %v", id, err)
+		}
+	}
+	for _, path := range paths {
+		RemoveDiskFromRegistry(path)
+		os.Remove(path)
+	}
+}
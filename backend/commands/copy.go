@@ -2,7 +2,6 @@ package commands
 
 import (
 	"backend/structs"
-	"encoding/binary"
 	"fmt"
 	"os"
 	"strings"
@@ -10,23 +9,21 @@ import (
 )
 
 // ExecuteCopy - Copiar archivo o carpeta con su contenido a otro destino
-func ExecuteCopy(path string, destino string) {
+func ExecuteCopy(session *Session, path string, destino string) (*CommandResult, error) {
+	res := NewCommandResult("copy")
+
 	// Validar parámetros obligatorios
 	if path == "" {
-		fmt.Println("Error: el parámetro -path es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio")
 	}
 
 	if destino == "" {
-		fmt.Println("Error: el parámetro -destino es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -destino es obligatorio")
 	}
 
 	// Validar que hay una sesión activa
-	session := GetCurrentSession()
 	if session == nil {
-		fmt.Println("Error: no hay ninguna sesión activa. Use el comando 'login' primero.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no hay ninguna sesión activa. Use el comando 'login' primero")
 	}
 
 	// Normalizar rutas
@@ -42,170 +39,164 @@ func ExecuteCopy(path string, destino string) {
 
 	// Validar que destino no sea igual a origen
 	if path == destino {
-		fmt.Println("Error: el origen y el destino no pueden ser iguales.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el origen y el destino no pueden ser iguales")
 	}
 
 	// Validar que no se intente copiar una ruta dentro de sí misma
 	if strings.HasPrefix(destino, path+"/") {
-		fmt.Println("Error: no se puede copiar una carpeta dentro de sí misma.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "no se puede copiar una carpeta dentro de sí misma")
 	}
 
 	// Abrir el disco montado
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: la partición '%s' no está montada.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "la partición '%s' no está montada", session.PartitionID)
 	}
 
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
 	if err != nil {
-		fmt.Printf("Error al abrir el disco: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
 	}
-	defer file.Close()
+	defer tx.rollback()
 
-	// Obtener superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
+	fs, err := openFileSystem(mounted, os.O_RDWR)
 	if err != nil {
-		fmt.Printf("Error al obtener superbloque: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el sistema de archivos")
 	}
+	defer fs.Close()
 
 	// Parsear la ruta de origen
 	parsedPath := parsePath(path)
 	if parsedPath == nil {
-		fmt.Printf("Error: ruta de origen inválida '%s'.\n", path)
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "ruta de origen inválida '%s'", path)
 	}
 
 	// Buscar el archivo/carpeta de origen
-	sourceInodeNum, err := findItemByPath(file, superblock, parsedPath)
+	sourceInodeNum, sourceInode, err := fs.Lookup(path)
 	if err != nil {
-		fmt.Printf("Error: no se encontró '%s': %v\n", path, err)
-		return
-	}
-
-	// Leer el inodo de origen
-	var sourceInode structs.Inodos
-	sourceInodePos := superblock.S_inode_start + (sourceInodeNum * superblock.S_inode_s)
-	file.Seek(sourceInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &sourceInode); err != nil {
-		fmt.Printf("Error al leer el inodo de origen: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeNotFound, err, "no se encontró '%s'", path)
 	}
 
 	// Validar permisos de lectura sobre el origen
-	if !checkReadPermissionOnInode(&sourceInode, session.User, session.Group) {
-		fmt.Printf("Error: no tiene permisos de lectura sobre '%s'.\n", path)
-		return
-	}
-
-	// Parsear la ruta de destino
-	parsedDestino := parsePath(destino)
-	if parsedDestino == nil {
-		fmt.Printf("Error: ruta de destino inválida '%s'.\n", destino)
-		return
+	if !checkReadPermissionOnInode(sourceInode, session.User, session.Group) {
+		return res, newCommandError(ErrCodePermissionDenied, "no tiene permisos de lectura sobre '%s'", path)
 	}
 
 	// Buscar el directorio de destino
-	destInodeNum, err := findItemByPath(file, superblock, parsedDestino)
+	destInodeNum, destInode, err := fs.Lookup(destino)
 	if err != nil {
-		fmt.Printf("Error: no se encontró el directorio de destino '%s': %v\n", destino, err)
-		return
-	}
-
-	// Leer el inodo de destino
-	var destInode structs.Inodos
-	destInodePos := superblock.S_inode_start + (destInodeNum * superblock.S_inode_s)
-	file.Seek(destInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &destInode); err != nil {
-		fmt.Printf("Error al leer el inodo de destino: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeNotFound, err, "no se encontró el directorio de destino '%s'", destino)
 	}
 
 	// Validar que el destino sea un directorio
 	if destInode.I_type != '0' {
-		fmt.Printf("Error: el destino '%s' no es un directorio.\n", destino)
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el destino '%s' no es un directorio", destino)
 	}
 
 	// Validar permisos de escritura sobre el destino
-	if !checkWritePermissionOnInode(&destInode, session.User, session.Group) {
-		fmt.Printf("Error: no tiene permisos de escritura sobre el directorio de destino '%s'.\n", destino)
-		return
+	if !checkWritePermissionOnInode(destInode, session.User, session.Group) {
+		return res, newCommandError(ErrCodePermissionDenied, "no tiene permisos de escritura sobre el directorio de destino '%s'", destino)
 	}
 
 	// Verificar que no exista ya un archivo/carpeta con el mismo nombre en el destino
-	existingInode, _ := findInodeInDirectory(file, superblock, destInodeNum, parsedPath.FileName)
-	if existingInode != -1 {
-		fmt.Printf("Error: ya existe '%s' en el directorio de destino.\n", parsedPath.FileName)
-		return
+	if _, err := fs.LookupIn(destInodeNum, parsedPath.FileName); err == nil {
+		return res, newCommandError(ErrCodeAlreadyExists, "ya existe '%s' en el directorio de destino", parsedPath.FileName)
+	}
+
+	// El nombre debe caber en una entrada de la partición
+	if err := fs.checkName(parsedPath.FileName); err != nil {
+		return res, err
+	}
+
+	// Las copias de un usuario le pertenecen y cuentan para su cuota; las de
+	// root conservan el dueño del origen
+	var owner *UserInfo
+	if !session.IsRoot {
+		if owner, err = fs.UserInfo(session.User); err != nil {
+			owner = nil
+		}
+	}
+	if owner != nil {
+		if err := fs.enforceQuota(session, owner.UID, owner.GID); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al leer las cuotas")
+		}
+		blocks, inodes, err := copySize(fs, sourceInodeNum, sourceInode, session.User, session.Group)
+		if err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al calcular el tamaño de '%s'", path)
+		}
+		if err := fs.quota.check(blocks, inodes); err != nil {
+			return res, err
+		}
 	}
 
 	// Realizar la copia
-	fmt.Printf("📋 Copiando '%s' a '%s'...\n", path, destino)
+	res.Printf("📋 Copiando '%s' a '%s'...\n", path, destino)
 
 	copiedCount := 0
 	skippedCount := 0
+	var newInodeNum int64
 
 	if sourceInode.I_type == '0' { // Es un directorio
-		newDirInodeNum, err := copyDirectoryRecursive(file, superblock, sourceInodeNum, destInodeNum, parsedPath.FileName, session.User, session.Group, &copiedCount, &skippedCount)
+		newDirInodeNum, err := copyDirectoryRecursive(res, fs, sourceInodeNum, destInodeNum, parsedPath.FileName, session.User, session.Group, owner, &copiedCount, &skippedCount)
 		if err != nil {
-			fmt.Printf("Error al copiar el directorio: %v\n", err)
-			return
+			return res, wrapCommandError(ErrCodeIO, err, "error al copiar el directorio")
 		}
+		newInodeNum = newDirInodeNum
 
 		// Agregar la entrada del nuevo directorio al directorio de destino
-		if err := addEntryToDirectory(file, superblock, destInodeNum, parsedPath.FileName, newDirInodeNum); err != nil {
-			fmt.Printf("Error al agregar la entrada al directorio de destino: %v\n", err)
-			return
+		if err := fs.AddEntry(destInodeNum, parsedPath.FileName, newDirInodeNum); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al agregar la entrada al directorio de destino")
 		}
 
 	} else { // Es un archivo
-		newFileInodeNum, err := copyFile(file, superblock, &sourceInode)
+		newFileInodeNum, err := copyFile(fs, sourceInodeNum, sourceInode, owner)
 		if err != nil {
-			fmt.Printf("Error al copiar el archivo: %v\n", err)
-			return
+			return res, wrapCommandError(ErrCodeIO, err, "error al copiar el archivo")
 		}
 		copiedCount++
+		newInodeNum = newFileInodeNum
 
 		// Agregar la entrada del archivo al directorio de destino
-		if err := addEntryToDirectory(file, superblock, destInodeNum, parsedPath.FileName, newFileInodeNum); err != nil {
-			fmt.Printf("Error al agregar la entrada al directorio de destino: %v\n", err)
-			return
+		if err := fs.AddEntry(destInodeNum, parsedPath.FileName, newFileInodeNum); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al agregar la entrada al directorio de destino")
 		}
 	}
 
 	// Actualizar el superbloque
-	file.Seek(superblock.S_inode_start-int64(binary.Size(structs.SuperBloque{})), 0)
-	if err := binary.Write(file, binary.LittleEndian, superblock); err != nil {
-		fmt.Printf("Error al actualizar el superbloque: %v\n", err)
-		return
+	if err := fs.Flush(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
 	}
 
-	fmt.Printf("✅ Copia completada exitosamente.\n")
-	fmt.Printf("   📄 Archivos/Carpetas copiados: %d\n", copiedCount)
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	res.AddPath(path)
+	res.AddPath(strings.TrimSuffix(destino, "/") + "/" + parsedPath.FileName)
+	res.Set("inode", newInodeNum)
+	res.Set("copied", copiedCount)
+	res.Set("skipped", skippedCount)
+	res.Printf("✅ Copia completada exitosamente.\n")
+	res.Printf("   📄 Archivos/Carpetas copiados: %d\n", copiedCount)
 	if skippedCount > 0 {
-		fmt.Printf("   ⚠️  Archivos/Carpetas omitidos por falta de permisos: %d\n", skippedCount)
+		res.Printf("   ⚠️  Archivos/Carpetas omitidos por falta de permisos: %d\n", skippedCount)
 	}
+
+	return res, nil
 }
 
-// copyFile - Copiar un archivo (crear nuevo inodo y copiar bloques)
-func copyFile(file *os.File, superblock *structs.SuperBloque, sourceInode *structs.Inodos) (int64, error) {
-	// Buscar un inodo libre
-	newInodeNum, err := findFreeInode(file, superblock)
+// copyFile - Copiar un archivo (crear nuevo inodo y copiar bloques). La copia
+// conserva I_mtime del origen; en el origen cuenta como una lectura (I_atime).
+// Con owner la copia pasa a ser suya; con nil conserva el dueño del origen.
+func copyFile(fs *FileSystem, sourceInodeNum int64, sourceInode *structs.Inodos, owner *UserInfo) (int64, error) {
+	// Reservar un inodo libre
+	newInodeNum, err := fs.AllocInode()
 	if err != nil {
 		return -1, err
 	}
 
-	// Marcar el inodo como usado
-	if err := markInodeAsUsed(file, superblock, newInodeNum); err != nil {
-		return -1, err
-	}
-	superblock.S_free_inodes_count--
-
 	// Crear el nuevo inodo (copia de los metadatos)
 	var newInode structs.Inodos
 	newInode.I_uid = sourceInode.I_uid
@@ -216,50 +207,46 @@ func copyFile(file *os.File, superblock *structs.SuperBloque, sourceInode *struc
 	newInode.I_mtime = sourceInode.I_mtime
 	newInode.I_type = sourceInode.I_type
 	newInode.I_perm = sourceInode.I_perm
+	newInode.I_links = 1
+	if owner != nil {
+		newInode.I_uid = owner.UID
+		newInode.I_gid = owner.GID
+	}
 
 	// Inicializar los bloques
 	for i := 0; i < 15; i++ {
 		newInode.I_block[i] = -1
 	}
 
-	// Copiar los bloques de datos
-	for i := 0; i < 15 && sourceInode.I_block[i] != -1; i++ {
-		// Buscar un bloque libre
-		newBlockNum, err := findFreeBlock(file, superblock)
-		if err != nil {
-			return -1, err
-		}
+	// Copiar los bloques de datos (directos e indirectos)
+	sourceBlocks, err := fs.DataBlocks(sourceInode)
+	if err != nil {
+		return -1, err
+	}
 
-		// Marcar el bloque como usado
-		if err := markBlockAsUsed(file, superblock, newBlockNum); err != nil {
+	for i, sourceBlockNum := range sourceBlocks {
+		// Asignar el bloque equivalente en el nuevo inodo
+		newBlockNum, err := fs.BlockFor(&newInode, int64(i))
+		if err != nil {
 			return -1, err
 		}
-		superblock.S_free_blocks_count--
-
-		// Leer el bloque de origen
-		sourceBlockPos := superblock.S_block_start + (sourceInode.I_block[i] * superblock.S_block_s)
-		file.Seek(sourceBlockPos, 0)
 
 		var fileBlock structs.BloqueArchivo
-		if err := binary.Read(file, binary.LittleEndian, &fileBlock); err != nil {
+		if err := fs.ReadBlock(sourceBlockNum, &fileBlock); err != nil {
 			return -1, err
 		}
-
-		// Escribir el bloque en la nueva posición
-		newBlockPos := superblock.S_block_start + (newBlockNum * superblock.S_block_s)
-		file.Seek(newBlockPos, 0)
-		if err := binary.Write(file, binary.LittleEndian, &fileBlock); err != nil {
+		if err := fs.WriteBlock(newBlockNum, &fileBlock); err != nil {
 			return -1, err
 		}
-
-		// Asignar el bloque al nuevo inodo
-		newInode.I_block[i] = newBlockNum
 	}
 
 	// Escribir el nuevo inodo
-	newInodePos := superblock.S_inode_start + (newInodeNum * superblock.S_inode_s)
-	file.Seek(newInodePos, 0)
-	if err := binary.Write(file, binary.LittleEndian, &newInode); err != nil {
+	if err := fs.WriteInode(newInodeNum, &newInode); err != nil {
+		return -1, err
+	}
+
+	touchAccess(sourceInode)
+	if err := fs.WriteInode(sourceInodeNum, sourceInode); err != nil {
 		return -1, err
 	}
 
@@ -267,26 +254,18 @@ func copyFile(file *os.File, superblock *structs.SuperBloque, sourceInode *struc
 }
 
 // copyDirectoryRecursive - Copiar un directorio recursivamente
-func copyDirectoryRecursive(file *os.File, superblock *structs.SuperBloque, sourceInodeNum int64, destParentInodeNum int64, _ string, username string, groupname string, copiedCount *int, skippedCount *int) (int64, error) {
+func copyDirectoryRecursive(res *CommandResult, fs *FileSystem, sourceInodeNum int64, destParentInodeNum int64, _ string, username string, groupname string, owner *UserInfo, copiedCount *int, skippedCount *int) (int64, error) {
 	// Leer el inodo de origen
-	var sourceInode structs.Inodos
-	sourceInodePos := superblock.S_inode_start + (sourceInodeNum * superblock.S_inode_s)
-	file.Seek(sourceInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &sourceInode); err != nil {
-		return -1, err
-	}
-
-	// Buscar un inodo libre para el nuevo directorio
-	newDirInodeNum, err := findFreeInode(file, superblock)
+	sourceInode, err := fs.ReadInode(sourceInodeNum)
 	if err != nil {
 		return -1, err
 	}
 
-	// Marcar el inodo como usado
-	if err := markInodeAsUsed(file, superblock, newDirInodeNum); err != nil {
+	// Reservar un inodo libre para el nuevo directorio
+	newDirInodeNum, err := fs.AllocInode()
+	if err != nil {
 		return -1, err
 	}
-	superblock.S_free_inodes_count--
 
 	// Crear el nuevo inodo del directorio
 	var newDirInode structs.Inodos
@@ -298,6 +277,11 @@ func copyDirectoryRecursive(file *os.File, superblock *structs.SuperBloque, sour
 	newDirInode.I_mtime = time.Now().Unix()
 	newDirInode.I_type = '0' // Directorio
 	newDirInode.I_perm = sourceInode.I_perm
+	newDirInode.I_links = 1
+	if owner != nil {
+		newDirInode.I_uid = owner.UID
+		newDirInode.I_gid = owner.GID
+	}
 
 	// Inicializar bloques
 	for i := 0; i < 15; i++ {
@@ -305,124 +289,117 @@ func copyDirectoryRecursive(file *os.File, superblock *structs.SuperBloque, sour
 	}
 
 	// Crear el bloque inicial del directorio con . y ..
-	newBlockNum, err := findFreeBlock(file, superblock)
+	newBlockNum, err := fs.AllocBlock()
 	if err != nil {
 		return -1, err
 	}
-
-	if err := markBlockAsUsed(file, superblock, newBlockNum); err != nil {
-		return -1, err
-	}
-	superblock.S_free_blocks_count--
-
 	newDirInode.I_block[0] = newBlockNum
 
-	// Crear el bloque de carpeta con . y ..
-	var folderBlock structs.BloqueCarpeta
-	for i := 0; i < 4; i++ {
-		folderBlock.BContent[i].BInodo = -1
-	}
-
-	// Entrada .
-	copy(folderBlock.BContent[0].BName[:], ".")
+	folderBlock := fs.newFolderBlock()
+	setEntryName(&folderBlock.BContent[0], ".")
 	folderBlock.BContent[0].BInodo = newDirInodeNum
-
-	// Entrada ..
-	copy(folderBlock.BContent[1].BName[:], "..")
+	setEntryName(&folderBlock.BContent[1], "..")
 	folderBlock.BContent[1].BInodo = destParentInodeNum
 
-	// Escribir el bloque
-	blockPos := superblock.S_block_start + (newBlockNum * superblock.S_block_s)
-	file.Seek(blockPos, 0)
-	if err := binary.Write(file, binary.LittleEndian, &folderBlock); err != nil {
+	if err := fs.WriteBlock(newBlockNum, &folderBlock); err != nil {
 		return -1, err
 	}
 
 	// Escribir el nuevo inodo del directorio
-	newDirInodePos := superblock.S_inode_start + (newDirInodeNum * superblock.S_inode_s)
-	file.Seek(newDirInodePos, 0)
-	if err := binary.Write(file, binary.LittleEndian, &newDirInode); err != nil {
+	if err := fs.WriteInode(newDirInodeNum, &newDirInode); err != nil {
 		return -1, err
 	}
 
 	*copiedCount++
 
 	// Copiar el contenido del directorio
-	for i := 0; i < 15 && sourceInode.I_block[i] != -1; i++ {
-		blockPos := superblock.S_block_start + (sourceInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPos, 0)
-
-		var sourceFolderBlock structs.BloqueCarpeta
-		if err := binary.Read(file, binary.LittleEndian, &sourceFolderBlock); err != nil {
+	entries, err := fs.ReadDir(sourceInodeNum)
+	if err != nil {
+		return -1, err
+	}
+	for _, entry := range entries {
+		// Leer el inodo de la entrada
+		entryInode, err := fs.ReadInode(entry.Inode)
+		if err != nil {
 			continue
 		}
 
-		// Recorrer cada entrada
-		for j := 0; j < 4; j++ {
-			entryName := strings.TrimRight(string(sourceFolderBlock.BContent[j].BName[:]), "\x00")
-			entryName = strings.TrimSpace(entryName)
+		// Verificar permisos de lectura
+		if !checkReadPermissionOnInode(entryInode, username, groupname) {
+			res.Printf("   ⚠️  Omitido '%s' (sin permisos de lectura)\n", entry.Name)
+			*skippedCount++
+			continue
+		}
 
-			// Saltar . y ..
-			if entryName == "" || entryName == "." || entryName == ".." {
-				continue
+		// Copiar según el tipo
+		var newEntryInodeNum int64
+		if entryInode.I_type == '0' { // Directorio
+			newEntryInodeNum, err = copyDirectoryRecursive(res, fs, entry.Inode, newDirInodeNum, entry.Name, username, groupname, owner, copiedCount, skippedCount)
+			if ErrorCode(err) == ErrCodeQuotaExceeded {
+				return -1, err // Sin cuota no tiene sentido seguir: se deshace toda la copia
 			}
-
-			entryInodeNum := sourceFolderBlock.BContent[j].BInodo
-			if entryInodeNum == -1 {
+			if err != nil {
+				res.Printf("   ⚠️  Error al copiar directorio '%s': %v\n", entry.Name, err)
+				*skippedCount++
 				continue
 			}
-
-			// Leer el inodo de la entrada
-			var entryInode structs.Inodos
-			entryInodePos := superblock.S_inode_start + (entryInodeNum * superblock.S_inode_s)
-			file.Seek(entryInodePos, 0)
-			if err := binary.Read(file, binary.LittleEndian, &entryInode); err != nil {
-				continue
+		} else { // Archivo
+			newEntryInodeNum, err = copyFile(fs, entry.Inode, entryInode, owner)
+			if ErrorCode(err) == ErrCodeQuotaExceeded {
+				return -1, err
 			}
-
-			// Verificar permisos de lectura
-			if !checkReadPermissionOnInode(&entryInode, username, groupname) {
-				fmt.Printf("   ⚠️  Omitido '%s' (sin permisos de lectura)\n", entryName)
+			if err != nil {
+				res.Printf("   ⚠️  Error al copiar archivo '%s': %v\n", entry.Name, err)
 				*skippedCount++
 				continue
 			}
+			*copiedCount++
+		}
 
-			// Copiar según el tipo
-			if entryInode.I_type == '0' { // Directorio
-				newSubDirInodeNum, err := copyDirectoryRecursive(file, superblock, entryInodeNum, newDirInodeNum, entryName, username, groupname, copiedCount, skippedCount)
-				if err != nil {
-					fmt.Printf("   ⚠️  Error al copiar directorio '%s': %v\n", entryName, err)
-					*skippedCount++
-					continue
-				}
-
-				// Agregar entrada al nuevo directorio
-				if err := addEntryToDirectory(file, superblock, newDirInodeNum, entryName, newSubDirInodeNum); err != nil {
-					fmt.Printf("   ⚠️  Error al agregar entrada '%s': %v\n", entryName, err)
-					continue
-				}
-
-			} else { // Archivo
-				newFileInodeNum, err := copyFile(file, superblock, &entryInode)
-				if err != nil {
-					fmt.Printf("   ⚠️  Error al copiar archivo '%s': %v\n", entryName, err)
-					*skippedCount++
-					continue
-				}
-				*copiedCount++
-
-				// Agregar entrada al nuevo directorio
-				if err := addEntryToDirectory(file, superblock, newDirInodeNum, entryName, newFileInodeNum); err != nil {
-					fmt.Printf("   ⚠️  Error al agregar entrada '%s': %v\n", entryName, err)
-					continue
-				}
-			}
+		// Agregar entrada al nuevo directorio
+		if err := fs.AddEntry(newDirInodeNum, entry.Name, newEntryInodeNum); ErrorCode(err) == ErrCodeQuotaExceeded {
+			return -1, err
+		} else if err != nil {
+			res.Printf("   ⚠️  Error al agregar entrada '%s': %v\n", entry.Name, err)
+			continue
 		}
 	}
 
 	return newDirInodeNum, nil
 }
 
+// copySize - Bloques e inodos que ocupará la copia (sin lo que se omite por
+// permisos). Sirve para verificar la cuota antes de copiar: en EXT2 una copia
+// a medias no se deshace.
+func copySize(fs *FileSystem, inodeNum int64, inode *structs.Inodos, username string, groupname string) (int64, int64, error) {
+	dataBlocks, pointerBlocks, err := fs.InodeBlocks(inode)
+	if err != nil {
+		return 0, 0, err
+	}
+	blocks, inodes := int64(len(dataBlocks)+len(pointerBlocks)), int64(1)
+	if inode.I_type != '0' {
+		return blocks, inodes, nil
+	}
+
+	entries, err := fs.ReadDir(inodeNum)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, entry := range entries {
+		entryInode, err := fs.ReadInode(entry.Inode)
+		if err != nil || !checkReadPermissionOnInode(entryInode, username, groupname) {
+			continue // copyDirectoryRecursive también lo omite
+		}
+		entryBlocks, entryInodes, err := copySize(fs, entry.Inode, entryInode, username, groupname)
+		if err != nil {
+			return 0, 0, err
+		}
+		blocks += entryBlocks
+		inodes += entryInodes
+	}
+	return blocks, inodes, nil
+}
+
 // checkReadPermissionOnInode - Verificar permisos de lectura
 func checkReadPermissionOnInode(inode *structs.Inodos, username string, groupname string) bool {
 	// Root siempre tiene permisos
@@ -458,25 +435,3 @@ func checkReadPermissionOnInode(inode *structs.Inodos, username string, groupnam
 	// Otros - bit de lectura (4)
 	return (othersPerms & 4) != 0
 }
-
-// findItemByPath - Buscar un item por su ruta completa
-func findItemByPath(file *os.File, superblock *structs.SuperBloque, parsedPath *ParsedPath) (int64, error) {
-	currentInodeNum := int64(0) // Raíz
-
-	// Navegar por los directorios
-	for _, dirName := range parsedPath.Directories {
-		nextInode, err := findInodeInDirectory(file, superblock, currentInodeNum, dirName)
-		if err != nil {
-			return -1, err
-		}
-		currentInodeNum = nextInode
-	}
-
-	// Buscar el archivo/carpeta final
-	targetInodeNum, err := findInodeInDirectory(file, superblock, currentInodeNum, parsedPath.FileName)
-	if err != nil {
-		return -1, err
-	}
-
-	return targetInodeNum, nil
-}
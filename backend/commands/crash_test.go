@@ -0,0 +1,241 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Pruebas de consistencia ante cortes: cada comando se ejecuta contra una
+// imagen EXT3 cortando la E/S después de cada escritura posible, y luego se
+// verifica la partición con fsck tras reproducir el journal (como al montar).
+// En EXT2 no hay journal: tras el corte se repara con fsck -repair y una
+// segunda revisión debe salir limpia.
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "extreamfs-test")
+	if err != nil {
+		panic(err)
+	}
+	// No tocar el registro ni la tabla de montajes del sistema
+	registryFilePath = filepath.Join(dir, "registry.json")
+	mountTableFilePath = filepath.Join(dir, "mount_table.json")
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// crashImage - Imagen formateada con una sesión root y una copia de su estado inicial
+type crashImage struct {
+	dir      string
+	diskPath string
+	baseline []byte
+	id       string
+	session  *Session
+	mounted  *MountedPartition
+}
+
+// newCrashImage - Crear un disco con una partición EXT3 y el árbol inicial de las pruebas
+func newCrashImage(t *testing.T) *crashImage {
+	t.Helper()
+	return newCrashImageFS(t, "3fs")
+}
+
+// newCrashImageFS - Crear la imagen de prueba con el sistema de archivos indicado
+func newCrashImageFS(t *testing.T, fs string) *crashImage {
+	t.Helper()
+	dir := t.TempDir()
+	img := &crashImage{dir: dir, diskPath: filepath.Join(dir, "crash.mia")}
+
+	if _, err := ExecuteMkdisk(2, "m", "ff", img.diskPath, "mbr", 0); err != nil {
+		t.Fatalf("mkdisk: %v", err)
+	}
+	if _, err := ExecuteFdisk(1, "m", img.diskPath, "p", "wf", "part1", "", 0); err != nil {
+		t.Fatalf("fdisk: %v", err)
+	}
+	res, err := ExecuteMount(img.diskPath, "part1")
+	if err != nil {
+		t.Fatalf("mount: %v", err)
+	}
+	img.id, _ = res.Data["id"].(string)
+	t.Cleanup(func() {
+		ExecuteUnmount(img.id)
+		RemoveDiskFromRegistry(img.diskPath)
+	})
+
+	if _, err := ExecuteMkfs(img.id, "full", fs, false, 0, 0, 0); err != nil {
+		t.Fatalf("mkfs: %v", err)
+	}
+	img.session, _, err = ExecuteLogin(nil, "root", "123", img.id)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	img.mounted = GetMountedPartition(img.id)
+
+	// Árbol inicial sobre el que trabajan los comandos
+	if _, err := ExecuteMkdir(img.session, "/docs/sub", true); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err := ExecuteMkdir(img.session, "/otro", false); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err := ExecuteMkfile(img.session, "/docs/a.txt", false, 1500, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	if _, err := ExecuteMkfile(img.session, "/docs/sub/b.txt", false, 200, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	img.checkClean(t, "estado inicial")
+
+	img.baseline, err = os.ReadFile(img.diskPath)
+	if err != nil {
+		t.Fatalf("leer imagen: %v", err)
+	}
+	return img
+}
+
+// restore - Volver la imagen al estado inicial
+func (img *crashImage) restore(t *testing.T) {
+	t.Helper()
+	if err := os.WriteFile(img.diskPath, img.baseline, 0644); err != nil {
+		t.Fatalf("restaurar imagen: %v", err)
+	}
+}
+
+// checkClean - Reproducir el journal pendiente y verificar la partición con fsck
+func (img *crashImage) checkClean(t *testing.T, label string) {
+	t.Helper()
+	if _, err := recoverMountedJournal(img.mounted); err != nil {
+		t.Fatalf("%s: replay del journal: %v", label, err)
+	}
+	res, err := ExecuteFsck(img.id, false)
+	if err != nil {
+		t.Fatalf("%s: fsck: %v", label, err)
+	}
+	if clean, _ := res.Data["clean"].(bool); !clean {
+		t.Fatalf("%s: partición inconsistente: %v", label, res.Data["problems"])
+	}
+}
+
+// checkRepaired - Reparar la partición con fsck y verificar que quede limpia
+func (img *crashImage) checkRepaired(t *testing.T, label string) {
+	t.Helper()
+	if _, err := ExecuteFsck(img.id, true); err != nil {
+		t.Fatalf("%s: fsck -repair: %v", label, err)
+	}
+	res, err := ExecuteFsck(img.id, false)
+	if err != nil {
+		t.Fatalf("%s: fsck: %v", label, err)
+	}
+	if clean, _ := res.Data["clean"].(bool); !clean {
+		t.Fatalf("%s: quedan problemas tras reparar: %v", label, res.Data["problems"])
+	}
+}
+
+// crashCase - Comando a interrumpir
+type crashCase struct {
+	name string
+	run  func(img *crashImage) error
+}
+
+func crashCases(t *testing.T) []crashCase {
+	contentPath := filepath.Join(t.TempDir(), "contenido.txt")
+	content := make([]byte, 3000)
+	for i := range content {
+		content[i] = byte('a' + i%26)
+	}
+	if err := os.WriteFile(contentPath, content, 0644); err != nil {
+		t.Fatalf("contenido: %v", err)
+	}
+
+	return []crashCase{
+		{"mkfile", func(img *crashImage) error {
+			_, err := ExecuteMkfile(img.session, "/docs/nuevo.txt", false, 2500, "")
+			return err
+		}},
+		{"mkdir", func(img *crashImage) error {
+			_, err := ExecuteMkdir(img.session, "/docs/x/y/z", true)
+			return err
+		}},
+		{"remove", func(img *crashImage) error {
+			_, err := ExecuteRemove(img.session, "/docs")
+			return err
+		}},
+		{"move", func(img *crashImage) error {
+			_, err := ExecuteMove(img.session, "/docs/sub", "/otro")
+			return err
+		}},
+		{"edit", func(img *crashImage) error {
+			_, err := ExecuteEdit(img.session, "/docs/a.txt", contentPath)
+			return err
+		}},
+	}
+}
+
+func TestCommandsSurviveWriteCuts(t *testing.T) {
+	img := newCrashImage(t)
+
+	for _, tc := range crashCases(t) {
+		t.Run(tc.name, func(t *testing.T) {
+			// Ejecución completa para contar las escrituras. Solo cuentan las
+			// que llegan a la imagen: lo que la transacción deja en su
+			// registro en memoria no pasa por checkDiskWrite, y la tabla de
+			// montajes y el registro de discos no usan diskFile.
+			img.restore(t)
+			clearDiskFault()
+			if err := tc.run(img); err != nil {
+				t.Fatalf("ejecución sin fallas: %v", err)
+			}
+			total := diskWriteCount()
+			t.Logf("%d escrituras por ejecución", total)
+			img.checkClean(t, "sin fallas")
+
+			modes := []struct {
+				name string
+				mode diskFaultMode
+			}{
+				{"stop", diskFaultStop},
+				{"fail", diskFaultFail},
+			}
+			for _, m := range modes {
+				for n := int64(0); n <= total; n++ {
+					img.restore(t)
+					injectDiskFault(m.mode, n)
+					err := tc.run(img)
+					clearDiskFault()
+
+					if m.mode == diskFaultFail && n < total && err == nil {
+						t.Fatalf("%s tras %d escrituras: se esperaba un error", m.name, n)
+					}
+					img.checkClean(t, fmt.Sprintf("%s tras %d escrituras", m.name, n))
+				}
+			}
+		})
+	}
+}
+
+func TestExt2RepairsAfterWriteCuts(t *testing.T) {
+	img := newCrashImageFS(t, "2fs")
+
+	for _, tc := range crashCases(t) {
+		t.Run(tc.name, func(t *testing.T) {
+			img.restore(t)
+			clearDiskFault()
+			if err := tc.run(img); err != nil {
+				t.Fatalf("ejecución sin fallas: %v", err)
+			}
+			total := diskWriteCount()
+			img.checkClean(t, "sin fallas")
+
+			for n := int64(0); n < total; n++ {
+				img.restore(t)
+				injectDiskFault(diskFaultStop, n)
+				tc.run(img)
+				clearDiskFault()
+				img.checkRepaired(t, fmt.Sprintf("corte tras %d escrituras", n))
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ type DiskInfo struct {
     Size       int             `json:"size"`
     Unit       string          `json:"unit"`
     Fit        string          `json:"fit"`
+    Table      string          `json:"table"` // "MBR" o "GPT"
     Partitions []PartitionInfo `json:"partitions"`
 }
 
@@ -34,7 +35,9 @@ func GetAllDisks() []DiskInfo {
 
 // readDiskInfoOptimized - Leer información de un disco de forma optimizada
 func readDiskInfoOptimized(diskPath string) *DiskInfo {
-    file, err := os.Open(diskPath)
+    defer rlockDisk(diskPath)()
+
+    file, err := openDisk(diskPath, os.O_RDONLY)
     if err != nil {
         return nil
     }
@@ -83,12 +86,23 @@ func readDiskInfoOptimized(diskPath string) *DiskInfo {
         Size:       size,
         Unit:       unit,
         Fit:        fit,
+        Table:      "MBR",
         Partitions: []PartitionInfo{},
     }
 
+    // En discos GPT las particiones están en la tabla GPT, no en el MBR protector
+    partitions := mbr.Mbr_partitions[:]
+    if isGPTDisk(&mbr) {
+        diskInfo.Table = "GPT"
+        table, err := readGPT(file, &mbr)
+        if err != nil {
+            return diskInfo
+        }
+        partitions = table.partitions()
+    }
+
     // Leer particiones primarias y extendidas
-    for i := 0; i < 4; i++ {
-        partition := mbr.Mbr_partitions[i]
+    for _, partition := range partitions {
 
         // Si la partición está vacía, continuar
         if partition.Part_status == 0 || partition.Part_s == 0 {
@@ -109,7 +123,7 @@ func readDiskInfoOptimized(diskPath string) *DiskInfo {
         // Verificar si está montada
         isMounted := false
         partID := ""
-        for _, mounted := range mountedPartitions {
+        for _, mounted := range GetMountedPartitions() {
             if mounted.Path == diskPath && mounted.Name == partName {
                 isMounted = true
                 partID = mounted.ID
@@ -145,7 +159,7 @@ func readDiskInfoOptimized(diskPath string) *DiskInfo {
 }
 
 // readLogicalPartitionsOptimized - Leer particiones lógicas de forma optimizada
-func readLogicalPartitionsOptimized(file *os.File, extendedStart int64, diskPath string) []PartitionInfo {
+func readLogicalPartitionsOptimized(file *diskFile, extendedStart int64, diskPath string) []PartitionInfo {
     var logicalPartitions []PartitionInfo
 
     currentEBR := extendedStart
@@ -175,7 +189,7 @@ func readLogicalPartitionsOptimized(file *os.File, extendedStart int64, diskPath
             // Verificar si está montada
             isMounted := false
             partID := ""
-            for _, mounted := range mountedPartitions {
+            for _, mounted := range GetMountedPartitions() {
                 if mounted.Path == diskPath && mounted.Name == partName {
                     isMounted = true
                     partID = mounted.ID
@@ -262,6 +276,7 @@ func GetDisksBasicInfo() []map[string]interface{} {
             "size":       disk.Size,
             "unit":       disk.Unit,
             "fit":        disk.Fit,
+            "table":      disk.Table,
             "partitions": partitions,
         }
     }
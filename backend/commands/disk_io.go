@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// CAPA DE E/S DE DISCOS
+// Todos los accesos a los archivos .mia de este paquete pasan por diskFile, de
+// modo que las escrituras se pueden contar e interrumpir en las pruebas. Dentro
+// de una transacción EXT3 el diskFile lleva además el registro de escrituras
+// de la transacción (walOverlay): las lecturas ven lo escrito y las escrituras
+// quedan en memoria hasta confirmar. Solo cuentan las que llegan al disco.
+
+// diskFaultMode - Qué pasa con las escrituras después del límite configurado
+type diskFaultMode int
+
+const (
+	diskFaultNone diskFaultMode = iota
+	diskFaultFail               // Las escrituras devuelven errDiskFault
+	diskFaultStop               // Las escrituras se descartan en silencio (corte de energía)
+)
+
+// errDiskFault - Error que devuelven las escrituras con una falla inyectada
+var errDiskFault = errors.New("falla de escritura inyectada")
+
+// Estado de la inyección de fallas (solo para pruebas)
+var diskFaults struct {
+	sync.Mutex
+	mode   diskFaultMode
+	limit  int64 // Escrituras que se permiten antes de fallar
+	writes int64 // Escrituras realizadas desde el último reinicio
+}
+
+// injectDiskFault - Dejar pasar `after` escrituras y aplicar el modo a las siguientes
+func injectDiskFault(mode diskFaultMode, after int64) {
+	diskFaults.Lock()
+	defer diskFaults.Unlock()
+	diskFaults.mode = mode
+	diskFaults.limit = after
+	diskFaults.writes = 0
+}
+
+// clearDiskFault - Quitar la falla inyectada y reiniciar el contador
+func clearDiskFault() {
+	injectDiskFault(diskFaultNone, 0)
+}
+
+// diskWriteCount - Escrituras realizadas desde el último reinicio
+func diskWriteCount() int64 {
+	diskFaults.Lock()
+	defer diskFaults.Unlock()
+	return diskFaults.writes
+}
+
+// checkDiskWrite - Registrar una escritura. Devuelve si debe llegar al disco
+// o el error con el que debe fallar.
+func checkDiskWrite() (bool, error) {
+	diskFaults.Lock()
+	defer diskFaults.Unlock()
+
+	diskFaults.writes++
+	if diskFaults.mode == diskFaultNone || diskFaults.writes <= diskFaults.limit {
+		return true, nil
+	}
+	if diskFaults.mode == diskFaultFail {
+		return false, errDiskFault
+	}
+	return false, nil
+}
+
+// diskFile - Archivo de disco abierto
+type diskFile struct {
+	file    *os.File
+	overlay *walOverlay // Escrituras de la transacción abierta (nil fuera de una)
+}
+
+// openDisk - Abrir un archivo de disco
+func openDisk(path string, flag int) (*diskFile, error) {
+	file, err := os.OpenFile(path, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &diskFile{file: file}, nil
+}
+
+func (d *diskFile) Name() string {
+	return d.file.Name()
+}
+
+func (d *diskFile) Read(p []byte) (int, error) {
+	if d.overlay == nil {
+		return d.file.Read(p)
+	}
+	position, err := d.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	n, err := d.ReadAt(p, position)
+	d.file.Seek(position+int64(n), io.SeekStart)
+	return n, err
+}
+
+func (d *diskFile) ReadAt(p []byte, offset int64) (int, error) {
+	n, err := d.file.ReadAt(p, offset)
+	if d.overlay != nil {
+		d.overlay.read(p[:n], offset)
+	}
+	return n, err
+}
+
+func (d *diskFile) Seek(offset int64, whence int) (int64, error) {
+	return d.file.Seek(offset, whence)
+}
+
+func (d *diskFile) Stat() (os.FileInfo, error) {
+	return d.file.Stat()
+}
+
+func (d *diskFile) Close() error {
+	return d.file.Close()
+}
+
+// Write - Escribir en la posición actual. Con una falla de tipo "stop" la
+// escritura se descarta pero la posición avanza igual.
+func (d *diskFile) Write(p []byte) (int, error) {
+	if d.overlay != nil {
+		position, err := d.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		n, err := d.WriteAt(p, position)
+		d.file.Seek(position+int64(n), io.SeekStart)
+		return n, err
+	}
+
+	write, err := checkDiskWrite()
+	if err != nil {
+		return 0, err
+	}
+	if !write {
+		d.file.Seek(int64(len(p)), 1)
+		return len(p), nil
+	}
+	return d.file.Write(p)
+}
+
+// WriteAt - Escribir en una posición. Dentro de una transacción la escritura
+// pasa por su registro, que decide qué llega al disco ahora.
+func (d *diskFile) WriteAt(p []byte, offset int64) (int, error) {
+	if d.overlay != nil {
+		if err := d.overlay.write(d, p, offset); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return d.writeDiskAt(p, offset)
+}
+
+// writeDiskAt - Escribir directo en el archivo del disco
+func (d *diskFile) writeDiskAt(p []byte, offset int64) (int, error) {
+	write, err := checkDiskWrite()
+	if err != nil {
+		return 0, err
+	}
+	if !write {
+		return len(p), nil
+	}
+	return d.file.WriteAt(p, offset)
+}
+
+func (d *diskFile) Truncate(size int64) error {
+	write, err := checkDiskWrite()
+	if err != nil || !write {
+		return err
+	}
+	return d.file.Truncate(size)
+}
+
+func (d *diskFile) Sync() error {
+	diskFaults.Lock()
+	stopped := diskFaults.mode != diskFaultNone && diskFaults.writes > diskFaults.limit
+	diskFaults.Unlock()
+	if stopped {
+		return nil
+	}
+	return d.file.Sync()
+}
+
+// markFileData - Avisar a la transacción abierta que un bloque se escribió
+// con datos de un archivo (ver walOverlay.changes)
+func (d *diskFile) markFileData(block int64) {
+	if d.overlay != nil {
+		d.overlay.markFileData(block)
+	}
+}
+
+// freedInTransaction - Verificar si la transacción abierta liberó un bloque
+// que estaba en uso al empezar
+func (d *diskFile) freedInTransaction(block int64) bool {
+	return d.overlay != nil && d.overlay.isFreed(block)
+}
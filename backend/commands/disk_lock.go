@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// Bloqueo de lectura/escritura por disco. El servidor HTTP atiende cada
+// petición en su propia goroutine: los comandos que modifican una partición
+// toman el bloqueo exclusivo y los que solo leen el compartido. El bloqueo es
+// del disco completo porque sus particiones comparten el MBR y los EBRs.
+//
+// Orden para evitar interbloqueos: primero el disco y después mountsMutex.
+// Los bloqueos no son reentrantes, así que se toman solo en la entrada de
+// cada comando (o en beginTransaction) y nunca en las funciones auxiliares.
+var (
+	diskLocksMutex sync.Mutex
+	diskLocks      = make(map[string]*sync.RWMutex)
+)
+
+// diskLockFor - Obtener el bloqueo de un disco por su ruta
+func diskLockFor(path string) *sync.RWMutex {
+	key := filepath.Clean(path)
+	if abs, err := filepath.Abs(key); err == nil {
+		key = abs
+	}
+
+	diskLocksMutex.Lock()
+	defer diskLocksMutex.Unlock()
+
+	lock, ok := diskLocks[key]
+	if !ok {
+		lock = &sync.RWMutex{}
+		diskLocks[key] = lock
+	}
+	return lock
+}
+
+// lockDisk - Tomar el bloqueo exclusivo del disco. Devuelve la función que lo libera.
+func lockDisk(path string) func() {
+	lock := diskLockFor(path)
+	lock.Lock()
+	return lock.Unlock
+}
+
+// rlockDisk - Tomar el bloqueo compartido del disco. Devuelve la función que lo libera.
+func rlockDisk(path string) func() {
+	lock := diskLockFor(path)
+	lock.RLock()
+	return lock.RUnlock
+}
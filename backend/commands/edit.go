@@ -2,31 +2,27 @@ package commands
 
 import (
 	"backend/structs"
-	"encoding/binary"
 	"fmt"
 	"os"
 	"strings"
-	"time"
 )
 
 // ExecuteEdit - Editar el contenido de un archivo existente
-func ExecuteEdit(path string, contenido string) {
+func ExecuteEdit(session *Session, path string, contenido string) (*CommandResult, error) {
+	res := NewCommandResult("edit")
+
 	// Validar parámetros obligatorios
 	if path == "" {
-		fmt.Println("Error: el parámetro -path es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio")
 	}
 
 	if contenido == "" {
-		fmt.Println("Error: el parámetro -contenido es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -contenido es obligatorio")
 	}
 
 	// Validar que hay una sesión activa
-	session := GetCurrentSession()
 	if session == nil {
-		fmt.Println("Error: no hay ninguna sesión activa. Use el comando 'login' primero.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no hay ninguna sesión activa. Use el comando 'login' primero")
 	}
 
 	// Normalizar la ruta del archivo destino
@@ -38,128 +34,102 @@ func ExecuteEdit(path string, contenido string) {
 	// Leer el contenido del archivo fuente del sistema operativo
 	contentData, err := os.ReadFile(contenido)
 	if err != nil {
-		fmt.Printf("Error al leer el archivo de contenido '%s': %v\n", contenido, err)
-		return
-	}
-
-	// Validar que el contenido no exceda el límite
-	if len(contentData) > 64*1024 { // 64 KB máximo (considerando bloques)
-		fmt.Printf("Error: el contenido es demasiado grande (máximo 64KB)\n")
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al leer el archivo de contenido '%s'", contenido)
 	}
 
 	// Abrir el disco montado
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: la partición '%s' no está montada.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "la partición '%s' no está montada", session.PartitionID)
 	}
 
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
 	if err != nil {
-		fmt.Printf("Error al abrir el disco: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
 	}
-	defer file.Close()
+	defer tx.rollback()
 
-	// Obtener superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
+	fs, err := openFileSystem(mounted, os.O_RDWR)
 	if err != nil {
-		fmt.Printf("Error al obtener superbloque: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el sistema de archivos")
 	}
+	defer fs.Close()
 
-	// Parsear la ruta
-	parsedPath := parsePath(path)
-	if parsedPath == nil {
-		fmt.Printf("Error: ruta inválida '%s'.\n", path)
-		return
+	// Validar que el contenido quepa en los bloques directos e indirectos de un inodo
+	if maxFileSize := fs.maxFileSize(); int64(len(contentData)) > maxFileSize {
+		return res, newCommandError(ErrCodeNoSpace, "el contenido es demasiado grande (máximo %d bytes)", maxFileSize)
 	}
 
 	// Buscar el archivo navegando por los directorios
-	currentInodeNum := int64(0) // Inodo raíz
-
-	// Navegar por los directorios
-	for _, dirName := range parsedPath.Directories {
-		nextInode, err := findInodeInDirectory(file, superblock, currentInodeNum, dirName)
-		if err != nil {
-			fmt.Printf("Error: no se encontró el directorio '%s': %v\n", dirName, err)
-			return
-		}
-		currentInodeNum = nextInode
-	}
-
-	// Buscar el archivo en el directorio actual
-	fileInodeNum, err := findInodeInDirectory(file, superblock, currentInodeNum, parsedPath.FileName)
+	fileInodeNum, fileInode, err := fs.Lookup(path)
 	if err != nil {
-		fmt.Printf("Error: no se encontró el archivo '%s': %v\n", parsedPath.FileName, err)
-		return
-	}
-
-	// Leer el inodo del archivo
-	var fileInode structs.Inodos
-	fileInodePos := superblock.S_inode_start + (fileInodeNum * superblock.S_inode_s)
-	file.Seek(fileInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &fileInode); err != nil {
-		fmt.Printf("Error al leer el inodo del archivo: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeNotFound, err, "no se encontró el archivo '%s'", path)
 	}
 
 	// Verificar que es un archivo, no un directorio
 	if fileInode.I_type == '0' {
-		fmt.Printf("Error: '%s' es un directorio, no un archivo.\n", path)
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "'%s' es un directorio, no un archivo", path)
 	}
 
 	// Validar permisos de lectura y escritura
-	if !checkReadWritePermissionOnInode(&fileInode, session.User, session.Group) {
-		fmt.Printf("Error: no tiene permisos de lectura y escritura sobre '%s'.\n", path)
-		return
+	if !checkReadWritePermissionOnInode(fileInode, session.User, session.Group) {
+		return res, newCommandError(ErrCodePermissionDenied, "no tiene permisos de lectura y escritura sobre '%s'", path)
 	}
 
-	// Liberar los bloques actuales del archivo
-	if err := freeFileBlocks(file, superblock, &fileInode); err != nil {
-		fmt.Printf("Error al liberar bloques del archivo: %v\n", err)
-		return
+	// El contenido nuevo cuenta para la cuota del dueño del archivo; se
+	// verifica antes de liberar los bloques actuales
+	if err := fs.enforceQuota(session, fileInode.I_uid, fileInode.I_gid); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al leer las cuotas")
 	}
-
-	// Escribir el nuevo contenido
-	bytesWritten, err := writeFileContentEdit(file, superblock, &fileInode, contentData)
+	dataBlocks, pointerBlocks, err := fs.InodeBlocks(fileInode)
 	if err != nil {
-		fmt.Printf("Error al escribir el contenido: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al leer los bloques del archivo")
+	}
+	currentBlocks := int64(len(dataBlocks) + len(pointerBlocks))
+	if err := fs.quota.check(fs.blocksForSize(int64(len(contentData)))-currentBlocks, 0); err != nil {
+		return res, err
 	}
 
-	// Actualizar el inodo del archivo
-	fileInode.I_s = int64(bytesWritten)
-	fileInode.I_mtime = time.Now().Unix()
+	// Liberar los bloques actuales del archivo (incluidos los de apuntadores)
+	if err := fs.FreeInodeBlocks(fileInode); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al liberar bloques del archivo")
+	}
 
-	// Guardar el inodo actualizado
-	file.Seek(fileInodePos, 0)
-	if err := binary.Write(file, binary.LittleEndian, &fileInode); err != nil {
-		fmt.Printf("Error al actualizar el inodo: %v\n", err)
-		return
+	// Escribir el nuevo contenido y guardar el inodo (WriteFile actualiza I_mtime e I_ctime)
+	if err := fs.WriteFile(fileInodeNum, fileInode, contentData); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al escribir el contenido")
 	}
+	bytesWritten := len(contentData)
 
 	// Actualizar el superbloque
-	file.Seek(superblock.S_inode_start-int64(binary.Size(structs.SuperBloque{})), 0)
-	if err := binary.Write(file, binary.LittleEndian, superblock); err != nil {
-		fmt.Printf("Error al actualizar el superbloque: %v\n", err)
-		return
+	if err := fs.Flush(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
 	}
 
 	err = WriteJournal(
+		res,
 		mounted,
 		"edit",
 		path,
 		string(contentData), // Convertir []byte a string
 	)
 	if err != nil {
-		fmt.Printf("⚠️ No se pudo escribir al journal: %v\n", err)
+		res.Printf("⚠️ No se pudo escribir al journal: %v\n", err)
+	}
+
+	res.AddPath(path)
+	res.Set("inode", fileInodeNum)
+	res.Set("size", bytesWritten)
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
 	}
 
-	fmt.Printf("✅ Archivo '%s' editado exitosamente.\n", path)
-	fmt.Printf("   📝 %d bytes escritos.\n", bytesWritten)
+	res.Printf("✅ Archivo '%s' editado exitosamente.\n", path)
+	res.Printf("   📝 %d bytes escritos.\n", bytesWritten)
+
+	return res, nil
 }
 
 // checkReadWritePermissionOnInode - Verificar permisos de lectura Y escritura
@@ -200,73 +170,3 @@ func checkReadWritePermissionOnInode(inode *structs.Inodos, username string, gro
 	// Otros - necesita permisos de lectura Y escritura
 	return (othersPerms&4) != 0 && (othersPerms&2) != 0
 }
-
-// freeFileBlocks - Liberar todos los bloques de un archivo
-func freeFileBlocks(file *os.File, superblock *structs.SuperBloque, inode *structs.Inodos) error {
-	// Recorrer todos los bloques del archivo
-	for i := 0; i < 15 && inode.I_block[i] != -1; i++ {
-		// Marcar el bloque como libre
-		if err := markBlockAsFree(file, superblock, inode.I_block[i]); err != nil {
-			return err
-		}
-		superblock.S_free_blocks_count++
-
-		// Limpiar la referencia del bloque
-		inode.I_block[i] = -1
-	}
-
-	return nil
-}
-
-// writeFileContentEdit - Escribir contenido en un archivo (usa las funciones existentes)
-func writeFileContentEdit(file *os.File, superblock *structs.SuperBloque, inode *structs.Inodos, content []byte) (int, error) {
-	totalWritten := 0
-	offset := 0
-	blockIndex := 0
-
-	for offset < len(content) && blockIndex < 15 {
-		// Calcular cuánto escribir en este bloque
-		remaining := len(content) - offset
-		toWrite := remaining
-		if toWrite > 64 {
-			toWrite = 64 // Tamaño máximo de un bloque de archivo
-		}
-
-		// Buscar un bloque libre
-		freeBlockNum, err := findFreeBlock(file, superblock)
-		if err != nil {
-			return totalWritten, fmt.Errorf("no hay bloques disponibles: %v", err)
-		}
-
-		// Marcar el bloque como usado
-		if err := markBlockAsUsed(file, superblock, freeBlockNum); err != nil {
-			return totalWritten, err
-		}
-		superblock.S_free_blocks_count--
-
-		// Asignar el bloque al inodo
-		inode.I_block[blockIndex] = freeBlockNum
-
-		// Crear y escribir el bloque de archivo
-		var fileBlock structs.BloqueArchivo
-		copy(fileBlock.BContent[:], content[offset:offset+toWrite])
-
-		// Escribir el bloque en el disco
-		blockPos := superblock.S_block_start + (freeBlockNum * superblock.S_block_s)
-		file.Seek(blockPos, 0)
-		if err := binary.Write(file, binary.LittleEndian, &fileBlock); err != nil {
-			return totalWritten, err
-		}
-
-		totalWritten += toWrite
-		offset += toWrite
-		blockIndex++
-	}
-
-	// Si hay más contenido que bloques disponibles
-	if offset < len(content) {
-		return totalWritten, fmt.Errorf("contenido demasiado grande, solo se escribieron %d bytes", totalWritten)
-	}
-
-	return totalWritten, nil
-}
@@ -0,0 +1,539 @@
+package commands
+
+import (
+	"archive/tar"
+	"backend/structs"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EXPORTAR UN SUBÁRBOL DE LA PARTICIÓN
+// Recorre el árbol EXT2/EXT3 desde un inodo y escribe carpetas y archivos con
+// su contenido en una carpeta del host o en un archivo tar. Los permisos
+// (I_perm), el dueño (I_uid/I_gid) y la fecha de modificación (I_mtime) se
+// conservan en la carpeta del host hasta donde se puede y completos en el tar.
+// Los enlaces simbólicos se exportan como enlaces con la misma ruta destino; en
+// la carpeta del host se omiten los que apuntarían fuera de ella y no se sigue
+// ningún enlace que ya exista en el host.
+
+// exportSink - Destino de una exportación: carpeta del host o archivo tar.
+// Los nombres son relativos a la raíz de la exportación ("" es la raíz).
+type exportSink interface {
+	Dir(name string, inode *structs.Inodos) error
+	File(name string, inode *structs.Inodos, fs *FileSystem) (int64, error)
+	Symlink(name string, inode *structs.Inodos, target string) error
+	Close() error
+}
+
+// exporter - Estado de una exportación en curso
+type exporter struct {
+	res     *CommandResult
+	fs      *FileSystem
+	session *Session
+	sink    exportSink
+	visited map[int64]bool
+
+	folders int
+	files   int
+	links   int
+	bytes   int64
+	skipped []map[string]string
+}
+
+func ExecuteExport(session *Session, srcPath string, dest string, format string) (*CommandResult, error) {
+	res := NewCommandResult("export")
+
+	// Verificar sesión activa
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
+	}
+
+	// Validar parámetros obligatorios
+	if srcPath == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio para export")
+	}
+	if dest == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -dest es obligatorio para export")
+	}
+	format = strings.ToLower(format)
+	if format == "" {
+		format = "dir"
+	}
+	if format != "dir" && format != "tar" {
+		return res, newCommandError(ErrCodeInvalidArgument, "formato inválido '%s' (use dir o tar)", format)
+	}
+
+	// Buscar la partición montada de la sesión
+	mounted := GetMountedPartition(session.PartitionID)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
+	}
+
+	err := runExport(res, session, mounted, srcPath, func(string) (exportSink, error) {
+		if format == "dir" {
+			return &hostDirSink{root: dest}, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return nil, fmt.Errorf("error al crear la carpeta del archivo tar: %v", err)
+		}
+		file, err := os.Create(dest)
+		if err != nil {
+			return nil, fmt.Errorf("error al crear el archivo tar: %v", err)
+		}
+		return newTarSink(file, file), nil
+	})
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al exportar '%s'", srcPath)
+	}
+
+	res.Printf("✅ '%s' exportado a '%s' (%s): %d carpetas, %d archivos y %d enlaces (%d bytes).\n",
+		srcPath, dest, format, res.Data["folders"], res.Data["files"], res.Data["links"], res.Data["bytes"])
+	res.AddPath(srcPath)
+	res.Set("dest", dest)
+	res.Set("format", format)
+
+	return res, nil
+}
+
+// ExportTar - Escribir en formato tar un subárbol de la partición. start recibe
+// el nombre sugerido del archivo antes del primer byte (para las cabeceras
+// HTTP) y devuelve el destino; si falla la búsqueda o los permisos no se llama.
+func ExportTar(session *Session, id string, srcPath string, start func(name string) io.Writer) (*CommandResult, error) {
+	res := NewCommandResult("export")
+
+	mounted, err := streamPartition(session, id)
+	if err != nil {
+		return res, err
+	}
+
+	err = runExport(res, session, mounted, srcPath, func(base string) (exportSink, error) {
+		if base == "" {
+			base = mounted.ID
+		}
+		return newTarSink(start(base+".tar"), nil), nil
+	})
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al exportar '%s'", srcPath)
+	}
+	res.AddPath(srcPath)
+	res.Set("format", "tar")
+	return res, nil
+}
+
+// runExport - Recorrer el subárbol de srcPath con la partición bloqueada para
+// lectura. open recibe el nombre base de srcPath ("" para la raíz).
+func runExport(res *CommandResult, session *Session, mounted *MountedPartition, srcPath string, open func(base string) (exportSink, error)) error {
+	parsedPath := parsePath(srcPath)
+	if parsedPath == nil || !parsedPath.IsAbsolute {
+		return newCommandError(ErrCodeInvalidArgument, "la ruta debe ser absoluta: %s", srcPath)
+	}
+
+	defer rlockDisk(mounted.Path)()
+
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
+	if err != nil {
+		return fmt.Errorf("error al abrir la partición: %v", err)
+	}
+	defer fs.Close()
+
+	inodeIndex, inode, err := fs.Lookup(srcPath)
+	if err != nil {
+		return newCommandError(ErrCodeNotFound, "la ruta '%s' no existe", srcPath)
+	}
+	if inode.I_type == '1' && !hasReadPermission(inode, session.User) {
+		return newCommandError(ErrCodePermissionDenied, "sin permisos de lectura para el archivo '%s'", srcPath)
+	}
+
+	sink, err := open(parsedPath.FileName)
+	if err != nil {
+		return err
+	}
+
+	e := &exporter{
+		res:     res,
+		fs:      fs,
+		session: session,
+		sink:    sink,
+		visited: make(map[int64]bool),
+		skipped: []map[string]string{},
+	}
+
+	// En el tar todo queda dentro de una carpeta con el nombre de srcPath;
+	// en la carpeta del host, -dest ocupa el lugar de srcPath.
+	rootName := ""
+	if ts, ok := sink.(*tarSink); ok {
+		rootName = parsedPath.FileName
+		ts.users, ts.groups = ownerNames(fs)
+	}
+
+	walkErr := e.walk(inodeIndex, inode, rootName, parsedPath.FullPath)
+	if err := sink.Close(); err != nil && walkErr == nil {
+		walkErr = err
+	}
+	if walkErr != nil {
+		return walkErr
+	}
+
+	res.Set("folders", e.folders)
+	res.Set("files", e.files)
+	res.Set("links", e.links)
+	res.Set("bytes", e.bytes)
+	res.Set("skipped", e.skipped)
+	return nil
+}
+
+// walk - Exportar un inodo y, si es carpeta, todo su contenido
+func (e *exporter) walk(inodeIndex int64, inode *structs.Inodos, name string, fullPath string) error {
+	if inode.I_type == '1' {
+		if !hasReadPermission(inode, e.session.User) {
+			e.skip(fullPath, "sin permisos de lectura")
+			return nil
+		}
+		size, err := e.sink.File(name, inode, e.fs)
+		if err != nil {
+			return fmt.Errorf("error al exportar el archivo '%s': %v", fullPath, err)
+		}
+		e.files++
+		e.bytes += size
+		return nil
+	}
+
+	if inode.I_type == '2' {
+		target, err := e.fs.ReadLink(inode)
+		if err != nil {
+			e.skip(fullPath, err.Error())
+			return nil
+		}
+		if err := e.sink.Symlink(name, inode, target); errors.Is(err, errLinkOutside) {
+			e.skip(fullPath, fmt.Sprintf("%v ('%s')", err, target))
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error al exportar el enlace '%s': %v", fullPath, err)
+		}
+		e.links++
+		return nil
+	}
+
+	// Una carpeta se recorre una sola vez aunque aparezca en más de un lugar
+	if e.visited[inodeIndex] {
+		e.skip(fullPath, "carpeta ya exportada")
+		return nil
+	}
+	e.visited[inodeIndex] = true
+
+	if err := e.sink.Dir(name, inode); err != nil {
+		return fmt.Errorf("error al exportar la carpeta '%s': %v", fullPath, err)
+	}
+	e.folders++
+
+	entries, err := e.fs.ReadDir(inodeIndex)
+	if err != nil {
+		return fmt.Errorf("error al leer la carpeta '%s': %v", fullPath, err)
+	}
+	for _, entry := range entries {
+		child, err := e.fs.ReadInode(entry.Inode)
+		if err != nil {
+			e.skip(path.Join(fullPath, entry.Name), err.Error())
+			continue
+		}
+		if err := e.walk(entry.Inode, child, path.Join(name, entry.Name), path.Join(fullPath, entry.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip - Registrar un elemento de la partición que no se exportó
+func (e *exporter) skip(fullPath string, reason string) {
+	e.res.Printf("⚠️ Omitido '%s': %s\n", fullPath, reason)
+	e.skipped = append(e.skipped, map[string]string{
+		"path":   fullPath,
+		"reason": reason,
+	})
+}
+
+// inodeMode - Permisos rwx del inodo como modo Unix. I_perm guarda dígitos
+// ASCII ('6') o valores (6); los tres bits bajos son los mismos en ambos casos.
+func inodeMode(inode *structs.Inodos) int64 {
+	return int64(inode.I_perm[0]&7)<<6 | int64(inode.I_perm[1]&7)<<3 | int64(inode.I_perm[2]&7)
+}
+
+// hostDirSink - Exportación a una carpeta del host
+type hostDirSink struct {
+	root string
+	dirs []hostDirTimes
+}
+
+// hostDirTimes - Carpeta del host cuya fecha se ajusta al final (escribir su
+// contenido la cambia)
+type hostDirTimes struct {
+	path  string
+	mode  os.FileMode
+	mtime time.Time
+}
+
+// errLinkOutside - Enlace cuyo destino saldría de la carpeta de la exportación
+var errLinkOutside = errors.New("el destino del enlace sale de la carpeta de destino")
+
+// path - Ruta en el host de name, que debe quedar dentro de la raíz
+func (s *hostDirSink) path(name string) (string, error) {
+	hostPath := filepath.Join(s.root, filepath.FromSlash(name))
+	if !withinDir(s.root, hostPath) {
+		return "", fmt.Errorf("'%s' queda fuera de la carpeta de destino", name)
+	}
+	return hostPath, nil
+}
+
+// mkdirs - Crear las carpetas desde la raíz hasta dir sin seguir enlaces del
+// host: uno que haya quedado en el camino podría apuntar fuera de la raíz
+func (s *hostDirSink) mkdirs(dir string) error {
+	if err := os.MkdirAll(s.root, 0755); err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(s.root, dir)
+	if err != nil || rel == "." {
+		return err
+	}
+	current := s.root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			if err := os.Mkdir(current, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("'%s' es un enlace simbólico del host", current)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("'%s' existe y no es una carpeta", current)
+		}
+	}
+	return nil
+}
+
+// notSymlink - Rechazar un enlace del host en el lugar donde se va a escribir
+func notSymlink(hostPath string) error {
+	info, err := os.Lstat(hostPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("'%s' es un enlace simbólico del host", hostPath)
+	}
+	return nil
+}
+
+// withinDir - Si target (limpia) es dir o está debajo de ella
+func withinDir(dir string, target string) bool {
+	rel, err := filepath.Rel(dir, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (s *hostDirSink) Dir(name string, inode *structs.Inodos) error {
+	dirPath, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if err := s.mkdirs(dirPath); err != nil {
+		return err
+	}
+	s.dirs = append(s.dirs, hostDirTimes{
+		path:  dirPath,
+		mode:  os.FileMode(inodeMode(inode)),
+		mtime: time.Unix(inode.I_mtime, 0),
+	})
+	return nil
+}
+
+func (s *hostDirSink) File(name string, inode *structs.Inodos, fs *FileSystem) (int64, error) {
+	filePath, err := s.path(name)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.mkdirs(filepath.Dir(filePath)); err != nil {
+		return 0, err
+	}
+	if err := notSymlink(filePath); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|openNoFollow, 0644)
+	if err != nil {
+		return 0, err
+	}
+	size, err := fs.ReadFileTo(file, inode)
+	if err == nil {
+		// Sobre el descriptor: la ruta ya no se vuelve a resolver
+		err = file.Chmod(os.FileMode(inodeMode(inode)))
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return size, err
+	}
+
+	mtime := time.Unix(inode.I_mtime, 0)
+	return size, os.Chtimes(filePath, mtime, mtime)
+}
+
+// Symlink - Los destinos absolutos o que salen de la raíz se rechazan con
+// errLinkOutside. Un ".." solo se admite al inicio: después de otro componente
+// podría pasar por un enlace y resolverse en otra carpeta.
+func (s *hostDirSink) Symlink(name string, inode *structs.Inodos, target string) error {
+	linkPath, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	hostTarget := filepath.FromSlash(target)
+	if filepath.IsAbs(hostTarget) || strings.HasPrefix(target, "/") {
+		return errLinkOutside
+	}
+	rest := strings.Split(target, "/")
+	for len(rest) > 0 && rest[0] == ".." {
+		rest = rest[1:]
+	}
+	for _, part := range rest {
+		if part == ".." {
+			return errLinkOutside
+		}
+	}
+	if !withinDir(s.root, filepath.Join(filepath.Dir(linkPath), hostTarget)) {
+		return errLinkOutside
+	}
+
+	if err := s.mkdirs(filepath.Dir(linkPath)); err != nil {
+		return err
+	}
+	// Reemplazar lo que haya quedado de una exportación anterior (si es un
+	// enlace, se borra el enlace y no su destino)
+	if err := os.Remove(linkPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Symlink(target, linkPath)
+}
+
+// Close - Aplicar permisos y fechas a las carpetas, de las más profundas a la raíz.
+// El dueño no se cambia: en el host requeriría privilegios de superusuario.
+func (s *hostDirSink) Close() error {
+	for i := len(s.dirs) - 1; i >= 0; i-- {
+		dir := s.dirs[i]
+		if err := os.Chtimes(dir.path, dir.mtime, dir.mtime); err != nil {
+			return err
+		}
+		// Se conserva el acceso del dueño del host para poder volver a exportar encima
+		if err := os.Chmod(dir.path, dir.mode|0700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tarSink - Exportación a un archivo tar
+type tarSink struct {
+	tw     *tar.Writer
+	closer io.Closer
+	users  map[int64]string
+	groups map[int64]string
+}
+
+// newTarSink - Escritor tar sobre w; closer (opcional) se cierra al terminar
+func newTarSink(w io.Writer, closer io.Closer) *tarSink {
+	return &tarSink{tw: tar.NewWriter(w), closer: closer}
+}
+
+// header - Cabecera tar con los permisos, el dueño y la fecha del inodo
+func (s *tarSink) header(name string, inode *structs.Inodos) *tar.Header {
+	return &tar.Header{
+		Name:    name,
+		Mode:    inodeMode(inode),
+		Uid:     int(inode.I_uid),
+		Gid:     int(inode.I_gid),
+		Uname:   s.users[inode.I_uid],
+		Gname:   s.groups[inode.I_gid],
+		ModTime: time.Unix(inode.I_mtime, 0),
+		Format:  tar.FormatPAX,
+	}
+}
+
+func (s *tarSink) Dir(name string, inode *structs.Inodos) error {
+	// La raíz de la partición no tiene nombre propio dentro del tar
+	if name == "" {
+		return nil
+	}
+	header := s.header(name+"/", inode)
+	header.Typeflag = tar.TypeDir
+	return s.tw.WriteHeader(header)
+}
+
+func (s *tarSink) File(name string, inode *structs.Inodos, fs *FileSystem) (int64, error) {
+	header := s.header(name, inode)
+	header.Typeflag = tar.TypeReg
+	header.Size = inode.I_s
+	if err := s.tw.WriteHeader(header); err != nil {
+		return 0, err
+	}
+	return fs.ReadFileTo(s.tw, inode)
+}
+
+func (s *tarSink) Symlink(name string, inode *structs.Inodos, target string) error {
+	header := s.header(name, inode)
+	header.Typeflag = tar.TypeSymlink
+	header.Linkname = target
+	return s.tw.WriteHeader(header)
+}
+
+func (s *tarSink) Close() error {
+	err := s.tw.Close()
+	if s.closer != nil {
+		if closeErr := s.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// ownerNames - Nombres de usuarios y grupos de users.txt por UID y GID.
+// mkfs crea la raíz y users.txt con UID/GID 0, que corresponden a root.
+func ownerNames(fs *FileSystem) (map[int64]string, map[int64]string) {
+	users := map[int64]string{0: "root"}
+	groups := map[int64]string{0: "root"}
+	content, err := fs.ReadUsers()
+	if err != nil {
+		return users, groups
+	}
+	for _, line := range strings.Split(content, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ",")
+		if len(parts) < 3 {
+			continue
+		}
+		id, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		switch strings.TrimSpace(parts[1]) {
+		case "G":
+			groups[id] = strings.TrimSpace(parts[2])
+		case "U":
+			if len(parts) >= 4 {
+				users[id] = strings.TrimSpace(parts[3])
+			}
+		}
+	}
+	return users, groups
+}
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package commands
+
+// openNoFollow - Sin O_NOFOLLOW; hostDirSink revisa con Lstat antes de abrir
+const openNoFollow = 0
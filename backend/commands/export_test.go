@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Pruebas de la exportación a una carpeta del host: no debe escribir fuera de
+// -dest ni a través de enlaces simbólicos que ya existan en el host.
+
+func TestExportDirDoesNotFollowHostSymlinks(t *testing.T) {
+	img := newCrashImage(t)
+	outside := t.TempDir()
+
+	// Enlaces del host dejados en el destino antes de exportar
+	dest := filepath.Join(t.TempDir(), "export")
+	if err := os.MkdirAll(filepath.Join(dest, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "a.txt"), filepath.Join(dest, "docs", "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dest, "otro")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExecuteMkfile(img.session, "/otro/c.txt", false, 10, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+
+	if _, err := ExecuteExport(img.session, "/", dest, "dir"); err == nil {
+		t.Fatal("export siguió enlaces del host sin error")
+	}
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("export escribió fuera del destino: %v", entries)
+	}
+}
+
+func TestExportDirSkipsLinksLeavingDest(t *testing.T) {
+	img := newCrashImage(t)
+
+	links := map[string]string{
+		"/docs/abs":    "/etc/passwd",
+		"/docs/up":     "../../fuera",
+		"/docs/vuelta": "sub/../../..",
+		"/docs/ok":     "sub/b.txt",
+		"/docs/raiz":   "../otro",
+	}
+	for link, target := range links {
+		if _, err := ExecuteLn(img.session, target, link, true); err != nil {
+			t.Fatalf("ln %s: %v", link, err)
+		}
+	}
+
+	dest := filepath.Join(t.TempDir(), "export")
+	res, err := ExecuteExport(img.session, "/", dest, "dir")
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	if skipped, _ := res.Data["skipped"].([]map[string]string); len(skipped) != 3 {
+		t.Fatalf("se esperaban 3 enlaces omitidos: %v", res.Data["skipped"])
+	}
+	for _, name := range []string{"abs", "up", "vuelta"} {
+		if _, err := os.Lstat(filepath.Join(dest, "docs", name)); !os.IsNotExist(err) {
+			t.Fatalf("se exportó el enlace '%s'", name)
+		}
+	}
+	for _, name := range []string{"ok", "raiz"} {
+		target, err := os.Readlink(filepath.Join(dest, "docs", name))
+		if err != nil || target != links["/docs/"+name] {
+			t.Fatalf("enlace '%s': %q %v", name, target, err)
+		}
+	}
+}
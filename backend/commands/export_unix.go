@@ -0,0 +1,8 @@
+//go:build unix
+
+package commands
+
+import "syscall"
+
+// openNoFollow - Abrir sin seguir un enlace simbólico en el último componente
+const openNoFollow = syscall.O_NOFOLLOW
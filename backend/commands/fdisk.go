@@ -9,11 +9,12 @@ import (
 	"strings"
 )
 
-func ExecuteFdisk(size int64, unit string, path string, tipo string, fit string, name string, delete string, add int64) {
+func ExecuteFdisk(size int64, unit string, path string, tipo string, fit string, name string, delete string, add int64) (*CommandResult, error) {
+    res := NewCommandResult("fdisk")
+
     // Validar path obligatorio
     if path == "" {
-        fmt.Printf("Error: El parámetro -path es obligatorio.\n")
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio")
     }
 
     if !strings.HasSuffix(strings.ToLower(path), ".mia") {
@@ -22,43 +23,50 @@ func ExecuteFdisk(size int64, unit string, path string, tipo string, fit string,
 
     // Verificar que el archivo existe
     if _, err := os.Stat(path); os.IsNotExist(err) {
-        fmt.Printf("Error: El archivo '%s' no existe.\n", path)
-        return
+        return res, newCommandError(ErrCodeNotFound, "el archivo '%s' no existe", path)
     }
 
+    // Se modifica la tabla de particiones: disco en exclusiva
+    defer lockDisk(path)()
+
     // CASO 1: DELETE - Eliminar partición
     if delete != "" {
         if name == "" {
-            fmt.Printf("Error: El parámetro -name es obligatorio para eliminar una partición.\n")
-            return
+            return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -name es obligatorio para eliminar una partición")
         }
-        executeDelete(path, name, delete)
-        return
+        if err := executeDelete(res, path, name, delete); err != nil {
+            return res, err
+        }
+        res.AddPath(path)
+        res.Set("name", name)
+        return res, nil
     }
 
     // CASO 2: ADD - Agregar/quitar espacio a partición existente
     if add != 0 {
         if name == "" {
-            fmt.Printf("Error: El parámetro -name es obligatorio para modificar el tamaño de una partición.\n")
-            return
+            return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -name es obligatorio para modificar el tamaño de una partición")
         }
         if unit == "" {
             unit = "K" // Por defecto Kilobytes
         }
-        executeAdd(path, name, add, unit)
-        return
+        if err := executeAdd(res, path, name, add, unit); err != nil {
+            return res, err
+        }
+        res.AddPath(path)
+        res.Set("name", name)
+        res.Set("add", convertSize(add, unit))
+        return res, nil
     }
 
     // CASO 3: CREATE - Crear nueva partición
     // Validar parámetros obligatorios SOLO para CREATE
     if size <= 0 {
-        fmt.Printf("Error: El parámetro -size es obligatorio y debe ser positivo para crear una partición.\n")
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -size es obligatorio y debe ser positivo para crear una partición")
     }
 
     if name == "" {
-        fmt.Printf("Error: El parámetro -name es obligatorio.\n")
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -name es obligatorio")
     }
 
     // Manejar parámetros opcionales con valores por defecto
@@ -77,56 +85,62 @@ func ExecuteFdisk(size int64, unit string, path string, tipo string, fit string,
     // Validar unidadesb
     unit = strings.ToUpper(unit)
     if unit != "B" && unit != "K" && unit != "M" {
-        fmt.Printf("Error: Unidad '%s' no válida. Use 'B', 'K' o 'M'.\n", unit)
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "unidad '%s' no válida. Use 'B', 'K' o 'M'", unit)
     }
 
     // Validar tipos
     tipo = strings.ToUpper(tipo)
     if tipo != "P" && tipo != "E" && tipo != "L" {
-        fmt.Printf("Error: Tipo de partición '%s' no válido. Use 'P', 'E' o 'L'.\n", tipo)
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "tipo de partición '%s' no válido. Use 'P', 'E' o 'L'", tipo)
     }
 
     // Validar fit
     fit = strings.ToUpper(fit)
     if fit != "BF" && fit != "FF" && fit != "WF" {
-        fmt.Printf("Error: Ajuste '%s' no válido. Use 'BF', 'FF' o 'WF'.\n", fit)
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "ajuste '%s' no válido. Use 'BF', 'FF' o 'WF'", fit)
     }
 
-    file, err := os.OpenFile(path, os.O_RDWR, 0644)
+    file, err := openDisk(path, os.O_RDWR)
     if err != nil {
-        fmt.Printf("Error al abrir el archivo: %v\n", err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al abrir el archivo")
     }
     defer file.Close()
 
     var mbr structs.MBR
     if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-        fmt.Printf("Error al leer el MBR: %v\n", err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al leer el MBR")
     }
 
     // Convertir tamaño según la unidad
     sizeInBytes := convertSize(size, unit)
 
+    // Los discos GPT tienen su propia tabla (ver fdisk_gpt.go)
+    if isGPTDisk(&mbr) {
+        startPosition, err := createGPTPartition(res, file, &mbr, sizeInBytes, tipo, fit, name)
+        if err != nil {
+            return res, err
+        }
+        res.AddPath(path)
+        res.Set("name", name)
+        res.Set("type", tipo)
+        res.Set("size", sizeInBytes)
+        res.Set("start", startPosition)
+        return res, nil
+    }
+
     // Validar nombre duplicado
     if err := validatePartitionName(name, &mbr); err != nil {
-        fmt.Printf("Error: %v\n", err)
-        return
+        return res, commandErrorFrom(ErrCodeInvalidArgument, err)
     }
 
     // Validar restricciones de particiones
     if err := validatePartitionConstraints(tipo, &mbr); err != nil {
-        fmt.Printf("Error: %v\n", err)
-        return
+        return res, commandErrorFrom(ErrCodeInvalidArgument, err)
     }
 
     // Validar espacio disponible
     if err := validateAvailableSpace(sizeInBytes, &mbr, tipo); err != nil {
-        fmt.Printf("Error: %v\n", err)
-        return
+        return res, commandErrorFrom(ErrCodeNoSpace, err)
     }
 
     // Buscar un slot libre en las particiones (solo para P y E)
@@ -137,19 +151,22 @@ func ExecuteFdisk(size int64, unit string, path string, tipo string, fit string,
     case "P", "E":
         partitionIndex = findFreePartitionSlot(&mbr)
         if partitionIndex == -1 {
-            fmt.Printf("Error: No hay slots disponibles para particiones primarias/extendidas.\n")
-            return
+            return res, newCommandError(ErrCodeNoSpace, "no hay slots disponibles para particiones primarias/extendidas")
         }
         startPosition = calculateStartPosition(&mbr, fit, sizeInBytes)
     case "L":
         // Para particiones lógicas, usar la partición extendida
-        _, err = handleLogicalPartition(&mbr, sizeInBytes, fit, name, file)
+        logicalStart, err := handleLogicalPartition(&mbr, sizeInBytes, fit, name, file)
         if err != nil {
-            fmt.Printf("Error: %v\n", err)
-            return
+            return res, commandErrorFrom(ErrCodeIO, err)
         }
-        fmt.Printf("Partición lógica '%s' creada exitosamente en '%s'.\n", name, path)
-        return
+        res.AddPath(path)
+        res.Set("name", name)
+        res.Set("type", "L")
+        res.Set("start", logicalStart)
+        res.Set("size", sizeInBytes)
+        res.Printf("Partición lógica '%s' creada exitosamente en '%s'.\n", name, path)
+        return res, nil
     }
 
     // Crear la nueva partición
@@ -171,8 +188,7 @@ func ExecuteFdisk(size int64, unit string, path string, tipo string, fit string,
     // Escribir el MBR actualizado al inicio del archivo
     file.Seek(0, 0)
     if err := binary.Write(file, binary.LittleEndian, &mbr); err != nil {
-        fmt.Printf("Error al escribir el MBR: %v\n", err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al escribir el MBR")
     }
 
     var tipoNombre string
@@ -185,37 +201,43 @@ func ExecuteFdisk(size int64, unit string, path string, tipo string, fit string,
         tipoNombre = "Lógica"
     }
 
-    fmt.Printf("Partición '%s' de tipo '%s' creada exitosamente en '%s'.\n", name, tipoNombre, path)
-    fmt.Printf("Tamaño: %d bytes, Ajuste: %s, Posición: %d\n", sizeInBytes, fit, startPosition)
+    res.AddPath(path)
+    res.Set("name", name)
+    res.Set("type", tipo)
+    res.Set("size", sizeInBytes)
+    res.Set("start", startPosition)
+    res.Printf("Partición '%s' de tipo '%s' creada exitosamente en '%s'.\n", name, tipoNombre, path)
+    res.Printf("Tamaño: %d bytes, Ajuste: %s, Posición: %d\n", sizeInBytes, fit, startPosition)
 
     if err := file.Sync(); err != nil {
-        fmt.Printf("Error al sincronizar el archivo: %v\n", err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al sincronizar el archivo")
     }
+
+    return res, nil
 }
 
 // executeDelete - Eliminar una partición
-func executeDelete(path string, name string, deleteType string) {
+func executeDelete(res *CommandResult, path string, name string, deleteType string) error {
     // Validar tipo de eliminación
     deleteType = strings.ToLower(deleteType)
     if deleteType != "fast" && deleteType != "full" {
-        fmt.Printf("Error: Tipo de eliminación '%s' no válido. Use 'fast' o 'full'.\n", deleteType)
-        return
+        return newCommandError(ErrCodeInvalidArgument, "tipo de eliminación '%s' no válido. Use 'fast' o 'full'", deleteType)
     }
 
     // Abrir archivo
-    file, err := os.OpenFile(path, os.O_RDWR, 0644)
+    file, err := openDisk(path, os.O_RDWR)
     if err != nil {
-        fmt.Printf("Error al abrir el archivo: %v\n", err)
-        return
+        return wrapCommandError(ErrCodeIO, err, "error al abrir el archivo")
     }
     defer file.Close()
 
     // Leer MBR
     var mbr structs.MBR
     if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-        fmt.Printf("Error al leer el MBR: %v\n", err)
-        return
+        return wrapCommandError(ErrCodeIO, err, "error al leer el MBR")
+    }
+    if isGPTDisk(&mbr) {
+        return deleteGPTPartition(res, file, &mbr, name, deleteType)
     }
 
     // PRIMERO: Buscar en particiones primarias/extendidas
@@ -237,7 +259,7 @@ func executeDelete(path string, name string, deleteType string) {
 
     // Si no se encontró, buscar en particiones lógicas
     if partitionIndex == -1 {
-        fmt.Printf("🔍 Buscando en particiones lógicas...\n")
+        res.Printf("🔍 Buscando en particiones lógicas...\n")
         
         // Encontrar la partición extendida
         var extendedPartition *structs.Partition
@@ -251,27 +273,26 @@ func executeDelete(path string, name string, deleteType string) {
 
         if extendedPartition != nil {
             // Buscar en la cadena de EBRs
-            if err := deleteLogicalPartitionByName(file, extendedPartition, name, deleteType); err != nil {
-                fmt.Printf("Error: %v\n", err)
+            if err := deleteLogicalPartitionByName(res, file, extendedPartition, name, deleteType); err != nil {
+                return commandErrorFrom(ErrCodeNotFound, err)
             }
-            return
+            return nil
         }
 
-        fmt.Printf("Error: No se encontró la partición '%s'.\n", name)
-        return
+        return newCommandError(ErrCodeNotFound, "no se encontró la partición '%s'", name)
     }
 
     // Si es extendida, eliminar particiones lógicas
     if partition.Part_type == 'E' || partition.Part_type == 'e' {
-        fmt.Printf("🗑️  Eliminando particiones lógicas dentro de la partición extendida...\n")
-        if err := deleteLogicalPartitions(file, partition, deleteType); err != nil {
-            fmt.Printf("⚠️  Advertencia al eliminar particiones lógicas: %v\n", err)
+        res.Printf("🗑️  Eliminando particiones lógicas dentro de la partición extendida...\n")
+        if err := deleteLogicalPartitions(res, file, partition, deleteType); err != nil {
+            res.Printf("⚠️  Advertencia al eliminar particiones lógicas: %v\n", err)
         }
     }
 
     // Tipo de eliminación
     if deleteType == "full" {
-        fmt.Printf("🔄 Eliminación completa: rellenando con \\0...\n")
+        res.Printf("🔄 Eliminación completa: rellenando con \\0...\n")
         fillWithZeros(file, partition.Part_start, partition.Part_s)
     }
 
@@ -286,21 +307,22 @@ func executeDelete(path string, name string, deleteType string) {
     }
 
     // Reorganizar particiones
-    fmt.Printf("🔧 Reorganizando particiones...\n")
-    defragmentPartitions(&mbr, file)
+    res.Printf("🔧 Reorganizando particiones...\n")
+    defragmentPartitions(res, &mbr, file)
 
     // Escribir MBR actualizado
     file.Seek(0, 0)
     if err := binary.Write(file, binary.LittleEndian, &mbr); err != nil {
-        fmt.Printf("Error al escribir el MBR: %v\n", err)
-        return
+        return wrapCommandError(ErrCodeIO, err, "error al escribir el MBR")
     }
 
-    fmt.Printf("✅ Partición '%s' eliminada exitosamente (%s).\n", name, deleteType)
+    res.Printf("✅ Partición '%s' eliminada exitosamente (%s).\n", name, deleteType)
+
+    return nil
 }
 
 // Eliminar partición lógica por nombre
-func deleteLogicalPartitionByName(file *os.File, extendedPartition *structs.Partition, name string, deleteType string) error {
+func deleteLogicalPartitionByName(res *CommandResult, file *diskFile, extendedPartition *structs.Partition, name string, deleteType string) error {
     currentEBRPos := extendedPartition.Part_start
     var prevEBRPos int64 = -1
 
@@ -316,11 +338,11 @@ func deleteLogicalPartitionByName(file *os.File, extendedPartition *structs.Part
 
         // Si encontramos la partición
         if ebrName == name {
-            fmt.Printf("✅ Partición lógica '%s' encontrada\n", name)
+            res.Printf("✅ Partición lógica '%s' encontrada\n", name)
 
             // Eliminar contenido si es FULL
             if deleteType == "full" {
-                fmt.Printf("🔄 Eliminación completa: rellenando con \\0...\n")
+                res.Printf("🔄 Eliminación completa: rellenando con \\0...\n")
                 fillWithZeros(file, ebr.PartStart, ebr.PartS)
             }
 
@@ -341,7 +363,7 @@ func deleteLogicalPartitionByName(file *os.File, extendedPartition *structs.Part
             emptyEBR.PartNext = -1
             binary.Write(file, binary.LittleEndian, &emptyEBR)
 
-            fmt.Printf("✅ Partición lógica '%s' eliminada exitosamente (%s).\n", name, deleteType)
+            res.Printf("✅ Partición lógica '%s' eliminada exitosamente (%s).\n", name, deleteType)
             return nil
         }
 
@@ -349,12 +371,12 @@ func deleteLogicalPartitionByName(file *os.File, extendedPartition *structs.Part
         currentEBRPos = ebr.PartNext
     }
 
-    return fmt.Errorf("no se encontró la partición lógica '%s'", name)
+    return newCommandError(ErrCodeNotFound, "no se encontró la partición lógica '%s'", name)
 }
 
 /// defragmentPartitions - Reorganizar particiones para consolidar espacio libre
-func defragmentPartitions(mbr *structs.MBR, file *os.File) {
-    fmt.Printf("🔧 Desfragmentando particiones...\n")
+func defragmentPartitions(res *CommandResult, mbr *structs.MBR, file *diskFile) {
+    res.Printf("🔧 Desfragmentando particiones...\n")
 
     // Paso 1: Ordenar particiones por posición de inicio
     type PartitionInfo struct {
@@ -378,7 +400,7 @@ func defragmentPartitions(mbr *structs.MBR, file *os.File) {
 
     // Si no hay particiones activas o solo hay una, no hay nada que hacer
     if len(activePartitions) <= 1 {
-        fmt.Printf("   ℹ️  No es necesario desfragmentar (solo %d partición activa)\n", len(activePartitions))
+        res.Printf("   ℹ️  No es necesario desfragmentar (solo %d partición activa)\n", len(activePartitions))
         return
     }
 
@@ -404,7 +426,7 @@ func defragmentPartitions(mbr *structs.MBR, file *os.File) {
         // Si la partición necesita moverse
         if oldStart != newStart {
             partName := strings.TrimRight(string(activePartitions[i].Part.Part_name[:]), "\x00")
-            fmt.Printf("   📦 Moviendo partición '%s' de posición %d a %d\n",
+            res.Printf("   📦 Moviendo partición '%s' de posición %d a %d\n",
                 partName,
                 oldStart,
                 newStart)
@@ -413,14 +435,14 @@ func defragmentPartitions(mbr *structs.MBR, file *os.File) {
             partitionData := make([]byte, activePartitions[i].Size)
             file.Seek(oldStart, 0)
             if _, err := file.Read(partitionData); err != nil {
-                fmt.Printf("   ⚠️  Error al leer partición: %v\n", err)
+                res.Printf("   ⚠️  Error al leer partición: %v\n", err)
                 continue
             }
 
             // Escribir en la nueva posición
             file.Seek(newStart, 0)
             if _, err := file.Write(partitionData); err != nil {
-                fmt.Printf("   ⚠️  Error al escribir partición: %v\n", err)
+                res.Printf("   ⚠️  Error al escribir partición: %v\n", err)
                 continue
             }
 
@@ -464,19 +486,19 @@ func defragmentPartitions(mbr *structs.MBR, file *os.File) {
     // Escribir el MBR actualizado
     file.Seek(0, 0)
     if err := binary.Write(file, binary.LittleEndian, mbr); err != nil {
-        fmt.Printf("   ⚠️  Error al escribir MBR: %v\n", err)
+        res.Printf("   ⚠️  Error al escribir MBR: %v\n", err)
         return
     }
 
     if moved {
-        fmt.Printf("   ✅ Particiones reorganizadas exitosamente\n")
+        res.Printf("   ✅ Particiones reorganizadas exitosamente\n")
     } else {
-        fmt.Printf("   ℹ️  Las particiones ya están en posiciones óptimas\n")
+        res.Printf("   ℹ️  Las particiones ya están en posiciones óptimas\n")
     }
 }
 
 // deleteLogicalPartitions - Eliminar todas las particiones lógicas dentro de una extendida
-func deleteLogicalPartitions(file *os.File, extendedPartition *structs.Partition, deleteType string) error {
+func deleteLogicalPartitions(res *CommandResult, file *diskFile, extendedPartition *structs.Partition, deleteType string) error {
 	// Leer el primer EBR
 	currentEBRPos := extendedPartition.Part_start
 
@@ -490,7 +512,7 @@ func deleteLogicalPartitions(file *os.File, extendedPartition *structs.Partition
 		// Si hay una partición lógica válida
 		if ebr.PartS > 0 {
 			logicalName := strings.TrimSpace(string(ebr.PartName[:]))
-			fmt.Printf("   🗑️  Eliminando partición lógica '%s'...\n", logicalName)
+			res.Printf("   🗑️  Eliminando partición lógica '%s'...\n", logicalName)
 
 			if deleteType == "full" {
 				fillWithZeros(file, ebr.PartStart, ebr.PartS)
@@ -505,7 +527,7 @@ func deleteLogicalPartitions(file *os.File, extendedPartition *structs.Partition
 }
 
 // fillWithZeros - Rellenar un rango del archivo con \0
-func fillWithZeros(file *os.File, start int64, size int64) {
+func fillWithZeros(file *diskFile, start int64, size int64) {
 	file.Seek(start, 0)
 
 	// Escribir en bloques de 4KB para mejor rendimiento
@@ -524,30 +546,30 @@ func fillWithZeros(file *os.File, start int64, size int64) {
 }
 
 // executeAdd - Agregar o quitar espacio de una partición
-func executeAdd(path string, name string, add int64, unit string) {
+func executeAdd(res *CommandResult, path string, name string, add int64, unit string) error {
     // Validar unidad
     unit = strings.ToUpper(unit)
     if unit != "B" && unit != "K" && unit != "M" {
-        fmt.Printf("Error: Unidad '%s' no válida. Use 'B', 'K' o 'M'.\n", unit)
-        return
+        return newCommandError(ErrCodeInvalidArgument, "unidad '%s' no válida. Use 'B', 'K' o 'M'", unit)
     }
 
     // Convertir tamaño según la unidad
     addBytes := convertSize(add, unit)
 
     // Abrir archivo
-    file, err := os.OpenFile(path, os.O_RDWR, 0644)
+    file, err := openDisk(path, os.O_RDWR)
     if err != nil {
-        fmt.Printf("Error al abrir el archivo: %v\n", err)
-        return
+        return wrapCommandError(ErrCodeIO, err, "error al abrir el archivo")
     }
     defer file.Close()
 
     // Leer MBR
     var mbr structs.MBR
     if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-        fmt.Printf("Error al leer el MBR: %v\n", err)
-        return
+        return wrapCommandError(ErrCodeIO, err, "error al leer el MBR")
+    }
+    if isGPTDisk(&mbr) {
+        return resizeGPTPartition(res, file, &mbr, path, name, addBytes)
     }
 
     // Buscar la partición
@@ -568,8 +590,7 @@ func executeAdd(path string, name string, add int64, unit string) {
     }
 
     if partitionIndex == -1 {
-        fmt.Printf("Error: No se encontró la partición '%s'.\n", name)
-        return
+        return newCommandError(ErrCodeNotFound, "no se encontró la partición '%s'", name)
     }
 
     // Calcular nuevo tamaño
@@ -577,9 +598,8 @@ func executeAdd(path string, name string, add int64, unit string) {
 
     // Validar que el nuevo tamaño no sea negativo
     if newSize < 0 {
-        fmt.Printf("Error: El nuevo tamaño de la partición sería negativo o cero. Tamaño actual: %d bytes, Cambio: %d bytes.\n",
+        return newCommandError(ErrCodeInvalidArgument, "el nuevo tamaño de la partición sería negativo o cero. Tamaño actual: %d bytes, Cambio: %d bytes",
             partition.Part_s, addBytes)
-        return
     }
 
     // Guardar tamaño anterior
@@ -588,6 +608,11 @@ func executeAdd(path string, name string, add int64, unit string) {
 
     // Si se está REDUCIENDO el tamaño (add negativo)
     if addBytes < 0 {
+        // Reducir antes el sistema de archivos: si sus datos no caben, la partición no cambia
+        if err := resizePartitionFileSystem(res, file, path, name, oldStart, newSize); err != nil {
+            return err
+        }
+
         // Limpiar desde el nuevo final hasta el final anterior
         fillWithZeros(file, oldStart+newSize, -addBytes)
 
@@ -595,14 +620,13 @@ func executeAdd(path string, name string, add int64, unit string) {
         mbr.Mbr_partitions[partitionIndex].Part_s = newSize
 
         // ✅ Reorganizar particiones para consolidar espacio libre
-        fmt.Printf("🔧 Reorganizando particiones para consolidar espacio libre...\n")
-        defragmentPartitions(&mbr, file)
+        res.Printf("🔧 Reorganizando particiones para consolidar espacio libre...\n")
+        defragmentPartitions(res, &mbr, file)
 
         // Releer el MBR después de desfragmentar
         file.Seek(0, 0)
         if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-            fmt.Printf("Error al releer el MBR: %v\n", err)
-            return
+            return wrapCommandError(ErrCodeIO, err, "error al releer el MBR")
         }
 
         // Buscar de nuevo la partición después de desfragmentar
@@ -628,27 +652,24 @@ func executeAdd(path string, name string, add int64, unit string) {
             totalAvailable := calculateTotalAvailableSpace(&mbr)
             
             if addBytes > totalAvailable {
-                fmt.Printf("Error: No hay suficiente espacio disponible en el disco.\n")
-                fmt.Printf("   Espacio disponible total: %d bytes\n", totalAvailable)
-                fmt.Printf("   Espacio solicitado: %d bytes\n", addBytes)
-                return
+                return newCommandError(ErrCodeNoSpace, "no hay suficiente espacio disponible en el disco. Espacio disponible total: %d bytes, Espacio solicitado: %d bytes",
+                    totalAvailable, addBytes)
             }
             
             // Si hay espacio en el disco pero no después de la partición,
             // necesitamos reorganizar
-            fmt.Printf("🔧 Reorganizando particiones para crear espacio contiguo...\n")
+            res.Printf("🔧 Reorganizando particiones para crear espacio contiguo...\n")
             
             // Actualizar tamaño temporalmente
             mbr.Mbr_partitions[partitionIndex].Part_s = newSize
             
             // Desfragmentar (esto moverá las particiones para crear espacio)
-            defragmentPartitions(&mbr, file)
+            defragmentPartitions(res, &mbr, file)
             
             // Releer el MBR
             file.Seek(0, 0)
             if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-                fmt.Printf("Error al releer el MBR: %v\n", err)
-                return
+                return wrapCommandError(ErrCodeIO, err, "error al releer el MBR")
             }
             
             // Buscar de nuevo la partición
@@ -669,24 +690,34 @@ func executeAdd(path string, name string, add int64, unit string) {
             // Escribir el MBR
             file.Seek(0, 0)
             if err := binary.Write(file, binary.LittleEndian, &mbr); err != nil {
-                fmt.Printf("Error al escribir el MBR: %v\n", err)
-                return
+                return wrapCommandError(ErrCodeIO, err, "error al escribir el MBR")
             }
         }
     }
 
     // Mostrar resultado
     if addBytes > 0 {
-        fmt.Printf("✅ Se agregaron %d bytes a la partición '%s'.\n", addBytes, name)
+        res.Printf("✅ Se agregaron %d bytes a la partición '%s'.\n", addBytes, name)
     } else {
-        fmt.Printf("✅ Se quitaron %d bytes de la partición '%s'.\n", -addBytes, name)
+        res.Printf("✅ Se quitaron %d bytes de la partición '%s'.\n", -addBytes, name)
     }
-    fmt.Printf("   Tamaño anterior: %d bytes\n", oldSize)
-    fmt.Printf("   Tamaño nuevo: %d bytes\n", mbr.Mbr_partitions[partitionIndex].Part_s)
+    res.Printf("   Tamaño anterior: %d bytes\n", oldSize)
+    res.Printf("   Tamaño nuevo: %d bytes\n", mbr.Mbr_partitions[partitionIndex].Part_s)
 
     // ✅ Calcular y mostrar espacio disponible después
     availableAfter := calculateAvailableSpaceAfter(&mbr.Mbr_partitions[partitionIndex], &mbr)
-    fmt.Printf("   💾 Espacio disponible después: %d bytes (%d MB)\n", availableAfter, availableAfter/(1024*1024))
+    res.Printf("   💾 Espacio disponible después: %d bytes (%d MB)\n", availableAfter, availableAfter/(1024*1024))
+
+    // Extender el sistema de archivos al espacio agregado
+    resized := mbr.Mbr_partitions[partitionIndex]
+    if addBytes > 0 {
+        if err := resizePartitionFileSystem(res, file, path, name, resized.Part_start, resized.Part_s); err != nil {
+            res.Printf("⚠️ No se pudo extender el sistema de archivos: %v\n", err)
+        }
+    }
+    updateMountedPartition(path, name, resized.Part_start, resized.Part_s)
+
+    return nil
 }
 
 // ✅ NUEVA FUNCIÓN: Calcular espacio total disponible en el disco
@@ -727,7 +758,7 @@ func validatePartitionName(name string, mbr *structs.MBR) error {
 		if partition.Part_status != '0' {
 			partitionName := strings.TrimSpace(string(partition.Part_name[:]))
 			if partitionName == name {
-				return fmt.Errorf("ya existe una partición con el nombre '%s'", name)
+				return newCommandError(ErrCodeAlreadyExists, "ya existe una partición con el nombre '%s'", name)
 			}
 		}
 	}
@@ -753,18 +784,18 @@ func validatePartitionConstraints(tipo string, mbr *structs.MBR) error {
 	// Restricción: máximo 4 particiones primarias + extendidas
 	if tipo == "P" || tipo == "E" {
 		if primaryCount+extendedCount >= 4 {
-			return fmt.Errorf("no se pueden crear más particiones. Máximo 4 particiones primarias/extendidas")
+			return newCommandError(ErrCodeNoSpace, "no se pueden crear más particiones. Máximo 4 particiones primarias/extendidas")
 		}
 	}
 
 	// Restricción: solo una partición extendida por disco
 	if tipo == "E" && extendedCount >= 1 {
-		return fmt.Errorf("ya existe una partición extendida en el disco")
+		return newCommandError(ErrCodeAlreadyExists, "ya existe una partición extendida en el disco")
 	}
 
 	// Restricción: no se puede crear partición lógica sin extendida
 	if tipo == "L" && extendedCount == 0 {
-		return fmt.Errorf("no se puede crear una partición lógica sin una partición extendida")
+		return newCommandError(ErrCodeInvalidArgument, "no se puede crear una partición lógica sin una partición extendida")
 	}
 
 	return nil
@@ -787,7 +818,7 @@ func validateAvailableSpace(sizeInBytes int64, mbr *structs.MBR, tipo string) er
 	availableSpace := mbr.Mbr_tamano - usedSpace
 
 	if sizeInBytes > availableSpace {
-		return fmt.Errorf("no hay espacio suficiente en el disco. Disponible: %d bytes, Requerido: %d bytes",
+		return newCommandError(ErrCodeNoSpace, "no hay espacio suficiente en el disco. Disponible: %d bytes, Requerido: %d bytes",
 			availableSpace, sizeInBytes)
 	}
 
@@ -806,7 +837,7 @@ func validateLogicalPartitionSpace(sizeInBytes int64, mbr *structs.MBR) error {
     }
 
     if extendedPartition == nil {
-        return fmt.Errorf("no se encontró partición extendida")
+        return newCommandError(ErrCodeNotFound, "no se encontró partición extendida")
     }
 
     // Abrir el archivo del disco
@@ -821,7 +852,7 @@ func validateLogicalPartitionSpace(sizeInBytes int64, mbr *structs.MBR) error {
     requiredSpace := sizeInBytes + ebrSize
 
     if requiredSpace > extendedPartition.Part_s {
-        return fmt.Errorf("no hay espacio suficiente en la partición extendida. Disponible: %d bytes, Requerido: %d bytes",
+        return newCommandError(ErrCodeNoSpace, "no hay espacio suficiente en la partición extendida. Disponible: %d bytes, Requerido: %d bytes",
             extendedPartition.Part_s, requiredSpace)
     }
 
@@ -829,7 +860,7 @@ func validateLogicalPartitionSpace(sizeInBytes int64, mbr *structs.MBR) error {
 }
 
 // Calcular espacio usado por particiones lógicas
-func calculateLogicalPartitionsUsedSpace(file *os.File, extendedPartition *structs.Partition) (int64, error) {
+func calculateLogicalPartitionsUsedSpace(file *diskFile, extendedPartition *structs.Partition) (int64, error) {
     usedSpace := int64(0)
     const ebrSize = int64(1024) // Tamaño de cada EBR
 
@@ -863,7 +894,7 @@ func calculateLogicalPartitionsUsedSpace(file *os.File, extendedPartition *struc
 }
 
 // Validar nombre duplicado en particiones lógicas
-func validateLogicalPartitionName(file *os.File, extendedPartition *structs.Partition, name string) error {
+func validateLogicalPartitionName(file *diskFile, extendedPartition *structs.Partition, name string) error {
     currentEBRPos := extendedPartition.Part_start
 
     for currentEBRPos != -1 {
@@ -878,7 +909,7 @@ func validateLogicalPartitionName(file *os.File, extendedPartition *structs.Part
             ebrName = strings.TrimRight(ebrName, "\x00")
 
             if ebrName == name {
-                return fmt.Errorf("ya existe una partición lógica con el nombre '%s'", name)
+                return newCommandError(ErrCodeAlreadyExists, "ya existe una partición lógica con el nombre '%s'", name)
             }
         }
 
@@ -983,7 +1014,6 @@ type FreeSpace struct {
 
 // Obtener todos los espacios libres en el disco
 func getFreeSpaces(mbr *structs.MBR) []FreeSpace {
-	var freeSpaces []FreeSpace
 	var usedRanges []FreeSpace
 
 	// Agregar el rango del MBR como usado
@@ -999,6 +1029,13 @@ func getFreeSpaces(mbr *structs.MBR) []FreeSpace {
 		}
 	}
 
+	return freeSpacesIn(usedRanges, mbr.Mbr_tamano)
+}
+
+// freeSpacesIn - Espacios libres entre los rangos usados, hasta el final del disco
+func freeSpacesIn(usedRanges []FreeSpace, diskEnd int64) []FreeSpace {
+	var freeSpaces []FreeSpace
+
 	// Ordenar los rangos usados por posición de inicio
 	for i := 0; i < len(usedRanges)-1; i++ {
 		for j := i + 1; j < len(usedRanges); j++ {
@@ -1028,10 +1065,10 @@ func getFreeSpaces(mbr *structs.MBR) []FreeSpace {
 	}
 
 	// Agregar espacio libre al final del disco si existe
-	if currentPos < mbr.Mbr_tamano {
+	if currentPos < diskEnd {
 		freeSpaces = append(freeSpaces, FreeSpace{
 			Start: currentPos,
-			Size:  mbr.Mbr_tamano - currentPos,
+			Size:  diskEnd - currentPos,
 		})
 	}
 
@@ -1059,7 +1096,7 @@ func calculateFirstFit(mbr *structs.MBR, sizeNeeded int64) int64 {
 }
 
 // Manejar particiones lógicas con validación completa
-func handleLogicalPartition(mbr *structs.MBR, sizeInBytes int64, fit string, name string, file *os.File) (int64, error) {
+func handleLogicalPartition(mbr *structs.MBR, sizeInBytes int64, fit string, name string, file *diskFile) (int64, error) {
     // Encontrar la partición extendida
     var extendedPartition *structs.Partition
     for i := 0; i < 4; i++ {
@@ -1070,7 +1107,7 @@ func handleLogicalPartition(mbr *structs.MBR, sizeInBytes int64, fit string, nam
     }
 
     if extendedPartition == nil {
-        return 0, fmt.Errorf("no se encontró partición extendida")
+        return 0, newCommandError(ErrCodeNotFound, "no se encontró partición extendida")
     }
 
     // Validar nombre duplicado en particiones lógicas
@@ -1092,7 +1129,7 @@ func handleLogicalPartition(mbr *structs.MBR, sizeInBytes int64, fit string, nam
     // Validar que hay espacio suficiente (tamaño solicitado + EBR)
     requiredSpace := sizeInBytes + ebrSize
     if requiredSpace > availableSpace {
-        return 0, fmt.Errorf("no hay espacio suficiente en la partición extendida. Disponible: %d bytes, Requerido: %d bytes",
+        return 0, newCommandError(ErrCodeNoSpace, "no hay espacio suficiente en la partición extendida. Disponible: %d bytes, Requerido: %d bytes",
             availableSpace, requiredSpace)
     }
 
@@ -1151,7 +1188,7 @@ func handleLogicalPartition(mbr *structs.MBR, sizeInBytes int64, fit string, nam
 
     // Validar que la nueva posición está dentro de la partición extendida
     if newEBRPos+requiredSpace > extendedPartition.Part_start+extendedPartition.Part_s {
-        return 0, fmt.Errorf("no hay espacio contiguo suficiente para la partición lógica")
+        return 0, newCommandError(ErrCodeNoSpace, "no hay espacio contiguo suficiente para la partición lógica")
     }
 
     // Crear nuevo EBR
@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"backend/structs"
+)
+
+// FDISK EN DISCOS GPT
+// Crear, eliminar y redimensionar particiones en la tabla GPT. A diferencia
+// del MBR no se reorganizan las demás particiones: cada una se queda donde
+// está y el ajuste elige solo entre huecos donde la nueva cabe completa.
+
+// chooseFreeSpace - Inicio del espacio libre que corresponde al ajuste (FF, BF o WF)
+func chooseFreeSpace(freeSpaces []FreeSpace, fit string, sizeNeeded int64) (int64, bool) {
+	chosen := -1
+	for i, space := range freeSpaces {
+		if space.Size < sizeNeeded {
+			continue
+		}
+		switch {
+		case chosen == -1:
+			chosen = i
+		case fit == "BF" && space.Size < freeSpaces[chosen].Size:
+			chosen = i
+		case fit == "WF" && space.Size > freeSpaces[chosen].Size:
+			chosen = i
+		}
+		if fit == "FF" {
+			break
+		}
+	}
+
+	if chosen == -1 {
+		return 0, false
+	}
+	return freeSpaces[chosen].Start, true
+}
+
+// createGPTPartition - Crear una partición primaria en la primera entrada libre
+func createGPTPartition(res *CommandResult, file *diskFile, mbr *structs.MBR, sizeInBytes int64, tipo string, fit string, name string) (int64, error) {
+	if tipo != "P" {
+		return 0, newCommandError(ErrCodeUnsupported, "los discos GPT no usan particiones extendidas ni lógicas; todas las particiones son primarias")
+	}
+
+	table, err := readGPT(file, mbr)
+	if err != nil {
+		return 0, wrapCommandError(ErrCodeIO, err, "error al leer la tabla GPT")
+	}
+
+	if table.findPartition(name) != -1 {
+		return 0, newCommandError(ErrCodeAlreadyExists, "ya existe una partición con el nombre '%s'", name)
+	}
+
+	index := table.freeEntry()
+	if index == -1 {
+		return 0, newCommandError(ErrCodeNoSpace, "la tabla GPT no tiene entradas libres (%d en total)", len(table.Entries))
+	}
+
+	start, ok := chooseFreeSpace(table.freeSpaces(), fit, sizeInBytes)
+	if !ok {
+		return 0, newCommandError(ErrCodeNoSpace, "no hay un espacio libre contiguo de %d bytes en el disco", sizeInBytes)
+	}
+
+	entry := &table.Entries[index]
+	*entry = structs.GPTEntry{Gpt_type_guid: gptLinuxDataType, Gpt_unique_guid: newGUID()}
+	entry.Gpt_partition = structs.NewPartition('1', 'P', fit[0], start, sizeInBytes, [16]byte{})
+	copy(entry.Gpt_partition.Part_name[:], []byte(name))
+
+	if err := table.write(file); err != nil {
+		return 0, wrapCommandError(ErrCodeIO, err, "error al escribir la tabla GPT")
+	}
+
+	res.Printf("Partición '%s' de tipo 'Primaria' creada exitosamente en la entrada GPT %d.\n", name, index+1)
+	res.Printf("Tamaño: %d bytes, Ajuste: %s, Posición: %d\n", sizeInBytes, fit, start)
+	return start, nil
+}
+
+// deleteGPTPartition - Liberar la entrada GPT de una partición
+func deleteGPTPartition(res *CommandResult, file *diskFile, mbr *structs.MBR, name string, deleteType string) error {
+	table, err := readGPT(file, mbr)
+	if err != nil {
+		return wrapCommandError(ErrCodeIO, err, "error al leer la tabla GPT")
+	}
+
+	index := table.findPartition(name)
+	if index == -1 {
+		return newCommandError(ErrCodeNotFound, "no se encontró la partición '%s'", name)
+	}
+
+	if deleteType == "full" {
+		partition := table.Entries[index].Gpt_partition
+		res.Printf("🔄 Eliminación completa: rellenando con \\0...\n")
+		fillWithZeros(file, partition.Part_start, partition.Part_s)
+	}
+
+	table.clearEntry(index)
+	if err := table.write(file); err != nil {
+		return wrapCommandError(ErrCodeIO, err, "error al escribir la tabla GPT")
+	}
+
+	res.Printf("✅ Partición '%s' eliminada exitosamente (%s).\n", name, deleteType)
+	return nil
+}
+
+// resizeGPTPartition - Agregar o quitar espacio al final de una partición GPT
+func resizeGPTPartition(res *CommandResult, file *diskFile, mbr *structs.MBR, path string, name string, addBytes int64) error {
+	table, err := readGPT(file, mbr)
+	if err != nil {
+		return wrapCommandError(ErrCodeIO, err, "error al leer la tabla GPT")
+	}
+
+	index := table.findPartition(name)
+	if index == -1 {
+		return newCommandError(ErrCodeNotFound, "no se encontró la partición '%s'", name)
+	}
+	partition := &table.Entries[index].Gpt_partition
+
+	oldSize := partition.Part_s
+	newSize := oldSize + addBytes
+	if newSize <= 0 {
+		return newCommandError(ErrCodeInvalidArgument, "el nuevo tamaño de la partición sería negativo o cero. Tamaño actual: %d bytes, Cambio: %d bytes",
+			oldSize, addBytes)
+	}
+
+	// Espacio libre inmediatamente después de la partición
+	end := partition.Part_start + partition.Part_s
+	availableAfter := int64(0)
+	for _, space := range table.freeSpaces() {
+		if space.Start == end {
+			availableAfter = space.Size
+		}
+	}
+
+	if addBytes > availableAfter {
+		return newCommandError(ErrCodeNoSpace, "no hay espacio libre contiguo después de la partición '%s'. Disponible: %d bytes, Solicitado: %d bytes",
+			name, availableAfter, addBytes)
+	}
+
+	// Al reducir, primero el sistema de archivos: si sus datos no caben, la partición no cambia
+	if addBytes < 0 {
+		if err := resizePartitionFileSystem(res, file, path, name, partition.Part_start, newSize); err != nil {
+			return err
+		}
+		fillWithZeros(file, partition.Part_start+newSize, -addBytes)
+	}
+	partition.Part_s = newSize
+
+	if err := table.write(file); err != nil {
+		return wrapCommandError(ErrCodeIO, err, "error al escribir la tabla GPT")
+	}
+
+	if addBytes > 0 {
+		res.Printf("✅ Se agregaron %d bytes a la partición '%s'.\n", addBytes, name)
+	} else {
+		res.Printf("✅ Se quitaron %d bytes de la partición '%s'.\n", -addBytes, name)
+	}
+	res.Printf("   Tamaño anterior: %d bytes\n", oldSize)
+	res.Printf("   Tamaño nuevo: %d bytes\n", newSize)
+	res.Printf("   💾 Espacio disponible después: %d bytes (%d MB)\n", availableAfter-addBytes, (availableAfter-addBytes)/(1024*1024))
+
+	// Extender el sistema de archivos al espacio agregado
+	if addBytes > 0 {
+		if err := resizePartitionFileSystem(res, file, path, name, partition.Part_start, newSize); err != nil {
+			res.Printf("⚠️ No se pudo extender el sistema de archivos: %v\n", err)
+		}
+	}
+	updateMountedPartition(path, name, partition.Part_start, newSize)
+	return nil
+}
@@ -5,8 +5,10 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"path"
 	"strings"
 	"time"
 	"unicode"
@@ -21,20 +23,22 @@ type FileNode struct {
 	Permissions string `json:"permissions"`
 	Owner       string `json:"owner"`
 	Group       string `json:"group"`
+	Modified    int64  `json:"modified"`         // I_mtime (segundos Unix): cambio del contenido
+	Accessed    int64  `json:"accessed"`         // I_atime: último acceso al contenido
+	Changed     int64  `json:"changed"`          // I_ctime: cambio del contenido o de los metadatos
+	Target      string `json:"target,omitempty"` // Destino si la entrada es un enlace simbólico
 }
 
-// GetFilesList obtiene la lista de archivos de un directorio
-func GetFilesList(mounted *MountedPartition, dirPath string) ([]FileNode, error) {
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("error al abrir el disco: %v", err)
-	}
-	defer file.Close()
+// GetFilesList obtiene la lista de archivos de un directorio; la sesión debe
+// tener permiso de lectura sobre él
+func GetFilesList(session *Session, mounted *MountedPartition, dirPath string) ([]FileNode, error) {
+	defer rlockDisk(mounted.Path)()
 
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
 	if err != nil {
 		return nil, err
 	}
+	defer fs.Close()
 
 	// Normalizar la ruta
 	dirPath = strings.TrimSpace(dirPath)
@@ -42,116 +46,110 @@ func GetFilesList(mounted *MountedPartition, dirPath string) ([]FileNode, error)
 		dirPath = "/"
 	}
 
-	var currentInodeNum int64 = 0 // Empezar desde la raíz
-
-	// Si no es la raíz, navegar hasta el directorio
-	if dirPath != "/" {
-		parsedPath := parsePath(dirPath + "/dummy") // Agregar dummy para que parsee correctamente
-		if parsedPath == nil {
-			return nil, fmt.Errorf("ruta inválida: %s", dirPath)
-		}
-
-		fmt.Printf("   Navegando por directorios: %v\n", parsedPath.Directories)
-
-		// Navegar por cada directorio
-		for i, dirName := range parsedPath.Directories {
-			fmt.Printf("   [%d] Buscando directorio: '%s' en inodo %d\n", i, dirName, currentInodeNum)
-
-			nextInode, err := findInodeInDirectory(file, superblock, currentInodeNum, dirName)
-			if err != nil {
-				fmt.Printf("   ❌ ERROR: no se encontró '%s': %v\n", dirName, err)
-				return nil, fmt.Errorf("no se encontró el directorio '%s': %v", dirName, err)
-			}
-
-			fmt.Printf("   ✅ Encontrado '%s' en inodo %d\n", dirName, nextInode)
-			currentInodeNum = nextInode
-		}
-	}
-
-	fmt.Printf("   📂 Leyendo contenido del inodo %d\n", currentInodeNum)
-
-	// Leer el inodo del directorio
-	var dirInode structs.Inodos
-	inodePos := superblock.S_inode_start + (currentInodeNum * superblock.S_inode_s)
-	file.Seek(inodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &dirInode); err != nil {
-		return nil, fmt.Errorf("error al leer inodo del directorio: %v", err)
+	dirInodeNum, dirInode, err := fs.Lookup(dirPath)
+	if err != nil {
+		return nil, err
 	}
 
 	// Verificar que es un directorio
 	if dirInode.I_type != '0' {
 		return nil, fmt.Errorf("la ruta no es un directorio")
 	}
+	if !hasReadPermission(dirInode, session.User) {
+		return nil, newCommandError(ErrCodePermissionDenied, "sin permisos de lectura para la carpeta '%s'", dirPath)
+	}
 
-	var files []FileNode
+	entries, err := fs.ReadDir(dirInodeNum)
+	if err != nil {
+		return nil, err
+	}
 
-	// Leer todos los bloques del directorio
-	for i := 0; i < 15 && dirInode.I_block[i] != -1; i++ {
-		blockPos := superblock.S_block_start + (dirInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPos, 0)
+	var files []FileNode
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		// FILTRAR: Evitar duplicados
+		if seen[entry.Name] {
+			continue
+		}
+		seen[entry.Name] = true
 
-		var dirBlock structs.BloqueCarpeta
-		if err := binary.Read(file, binary.LittleEndian, &dirBlock); err != nil {
+		entryInode, err := fs.ReadInode(entry.Inode)
+		if err != nil {
 			continue
 		}
 
-		// Leer cada entrada del bloque
-		for j := 0; j < 4; j++ {
-			if dirBlock.BContent[j].BInodo == -1 {
-				continue
-			}
+		fileNode := newFileNode(entry.Name, entryInode)
 
-			// CORRECCIÓN: Extraer nombre correctamente
-			entryName := strings.TrimRight(string(dirBlock.BContent[j].BName[:]), "\x00")
-			entryName = strings.TrimSpace(entryName)
+		// Un enlace simbólico se muestra con los datos de su destino
+		if entryInode.I_type == '2' {
+			target, _ := fs.ReadLink(entryInode)
+			if resolved, err := fs.followLink(dirInodeNum, entry.Inode, 0); err == nil {
+				if resolvedInode, err := fs.ReadInode(resolved); err == nil {
+					fileNode = newFileNode(entry.Name, resolvedInode)
+				}
+			}
+			fileNode.Target = target
+		}
 
-			fmt.Printf("   📄 Entrada encontrada: '%s' (inodo %d)\n", entryName, dirBlock.BContent[j].BInodo)
+		files = append(files, fileNode)
+	}
 
-			// FILTRAR: Saltar entradas "." y ".."
-			if entryName == "." || entryName == ".." {
-				fmt.Printf("   ⏭️  Saltando entrada especial: '%s'\n", entryName)
-				continue
-			}
+	return files, nil
+}
 
-			// FILTRAR: Evitar duplicados
-			isDuplicate := false
-			for _, existingFile := range files {
-				if existingFile.Name == entryName {
-					isDuplicate = true
-					fmt.Printf("   ⚠️  Entrada duplicada ignorada: '%s'\n", entryName)
-					break
-				}
-			}
-			if isDuplicate {
-				continue
-			}
+// StatPath obtiene la información de un archivo o directorio sin listar su
+// contenido. Como al listar, la sesión debe poder leer la carpeta que lo
+// contiene (la raíz, a sí misma).
+func StatPath(session *Session, mounted *MountedPartition, filePath string) (FileNode, error) {
+	return statPath(mounted, filePath, session)
+}
 
-			// Leer el inodo de la entrada
-			entryInodePos := superblock.S_inode_start + (dirBlock.BContent[j].BInodo * superblock.S_inode_s)
-			file.Seek(entryInodePos, 0)
+// statPath - StatPath; sin sesión no revisa permisos (lo usan los comandos
+// que después validan los suyos)
+func statPath(mounted *MountedPartition, filePath string, session *Session) (FileNode, error) {
+	defer rlockDisk(mounted.Path)()
 
-			var entryInode structs.Inodos
-			if err := binary.Read(file, binary.LittleEndian, &entryInode); err != nil {
-				continue
-			}
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
+	if err != nil {
+		return FileNode{}, err
+	}
+	defer fs.Close()
 
-			// Crear FileNode
-			fileNode := FileNode{
-				Name:        entryName,
-				Type:        getFileTypeFromInode(entryInode.I_type),
-				Size:        entryInode.I_s,
-				Permissions: getPermissionsStringFromBytes(entryInode.I_perm),
-				Owner:       fmt.Sprintf("user%d", entryInode.I_uid),
-				Group:       fmt.Sprintf("group%d", entryInode.I_gid),
-			}
+	_, inode, err := fs.Lookup(filePath)
+	if err != nil {
+		return FileNode{}, newCommandError(ErrCodeNotFound, "'%s' no existe", filePath)
+	}
 
-			files = append(files, fileNode)
-			fmt.Printf("   ✅ Agregado: %s (tipo: %s)\n", fileNode.Name, fileNode.Type)
+	if session != nil {
+		_, parent, err := fs.Lookup(path.Dir(path.Clean("/" + filePath)))
+		if err != nil {
+			return FileNode{}, newCommandError(ErrCodeNotFound, "'%s' no existe", filePath)
+		}
+		if !hasReadPermission(parent, session.User) {
+			return FileNode{}, newCommandError(ErrCodePermissionDenied, "sin permisos de lectura para la carpeta de '%s'", filePath)
 		}
 	}
 
-	fmt.Printf("   📊 Total de archivos válidos: %d\n", len(files))
-	return files, nil
+	name := path.Base(filePath)
+	if filePath == "/" {
+		name = "/"
+	}
+	return newFileNode(name, inode), nil
+}
+
+// newFileNode - FileNode con los datos de un inodo
+func newFileNode(name string, inode *structs.Inodos) FileNode {
+	return FileNode{
+		Name:        name,
+		Type:        getFileTypeFromInode(inode.I_type),
+		Size:        inode.I_s,
+		Permissions: getPermissionsStringFromBytes(inode.I_perm),
+		Owner:       fmt.Sprintf("user%d", inode.I_uid),
+		Group:       fmt.Sprintf("group%d", inode.I_gid),
+		Modified:    inode.I_mtime,
+		Accessed:    inode.I_atime,
+		Changed:     inode.I_ctime,
+	}
 }
 
 // getFileTypeFromInode convierte el tipo de inodo a string
@@ -189,380 +187,388 @@ func getPermissionsStringFromBytes(perms [3]byte) string {
 }
 
 // Leer el contenido completo de cualquier archivo (multi-bloque)
-func ReadFileContent(mounted *MountedPartition, fileName string) (string, error) {
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
-	if err != nil {
-		return "", fmt.Errorf("error al abrir el disco: %v", err)
-	}
-	defer file.Close()
-
-	// Obtener partición y superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
+func ReadFileContent(mounted *MountedPartition, fileName string) ([]byte, error) {
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer fs.Close()
 
 	// Buscar el archivo en el directorio raíz
-	inodeIndex, err := findFileInRootDirectory(file, superblock, fileName)
+	inodeIndex, err := fs.LookupIn(0, fileName)
 	if err != nil {
-		return "", fmt.Errorf("archivo '%s' no encontrado: %v", fileName, err)
+		return nil, fmt.Errorf("archivo '%s' no encontrado: %v", fileName, err)
 	}
 
-	// Leer el inodo del archivo
-	inodePosition := superblock.S_inode_start + (inodeIndex * superblock.S_inode_s)
-	file.Seek(inodePosition, 0)
-	var fileInode structs.Inodos
-	if err := binary.Read(file, binary.LittleEndian, &fileInode); err != nil {
-		return "", fmt.Errorf("error al leer el inodo de '%s': %v", fileName, err)
+	fileInode, err := fs.ReadInode(inodeIndex)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el inodo de '%s': %v", fileName, err)
 	}
 
 	// Leer el contenido completo (multi-bloque)
-	content, err := readFileContentMultiBlock(file, superblock, &fileInode)
+	content, err := fs.ReadFile(fileInode)
 	if err != nil {
-		return "", fmt.Errorf("error al leer el contenido de '%s': %v", fileName, err)
+		return nil, fmt.Errorf("error al leer el contenido de '%s': %v", fileName, err)
 	}
 
 	return content, nil
 }
 
 // Escribir contenido completo a cualquier archivo (multi-bloque)
-func WriteFileContent(mounted *MountedPartition, fileName, newContent string) error {
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
-	if err != nil {
-		return fmt.Errorf("error al abrir el disco: %v", err)
-	}
-	defer file.Close()
-
-	// Obtener partición y superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
+func WriteFileContent(mounted *MountedPartition, fileName string, newContent []byte) error {
+	fs, err := openFileSystem(mounted, os.O_RDWR)
 	if err != nil {
 		return err
 	}
+	defer fs.Close()
 
 	// Buscar el archivo en el directorio raíz
-	inodeIndex, err := findFileInRootDirectory(file, superblock, fileName)
+	inodeIndex, err := fs.LookupIn(0, fileName)
 	if err != nil {
 		return fmt.Errorf("archivo '%s' no encontrado: %v", fileName, err)
 	}
 
-	// Leer el inodo del archivo
-	inodePosition := superblock.S_inode_start + (inodeIndex * superblock.S_inode_s)
-	file.Seek(inodePosition, 0)
-	var fileInode structs.Inodos
-	if err := binary.Read(file, binary.LittleEndian, &fileInode); err != nil {
+	fileInode, err := fs.ReadInode(inodeIndex)
+	if err != nil {
 		return fmt.Errorf("error al leer el inodo de '%s': %v", fileName, err)
 	}
 
 	// Escribir contenido multi-bloque
-	err = writeFileContentMultiBlock(file, superblock, &fileInode, newContent, inodePosition)
-	if err != nil {
+	if err := fs.WriteFile(inodeIndex, fileInode, newContent); err != nil {
 		return fmt.Errorf("error al escribir '%s': %v", fileName, err)
 	}
 
-	return nil
+	return fs.Flush()
 }
 
 func ReadUsersFileContent(mounted *MountedPartition) (string, error) {
-	return ReadFileContent(mounted, "users.txt")
+	content, err := ReadFileContent(mounted, "users.txt")
+	return string(content), err
 }
 
 func WriteUsersFileContent(mounted *MountedPartition, newContent string) error {
-	return WriteFileContent(mounted, "users.txt", newContent)
-}
-
-// Obtener partición y superbloque
-func getPartitionAndSuperblock(file *os.File, mounted *MountedPartition) (*structs.Partition, *structs.SuperBloque, error) {
-	// Primero: si el objeto mounted ya tiene el offset de inicio, intentar usarlo directamente.
-	if mounted.Start > 0 {
-		file.Seek(mounted.Start, 0)
-		var sb structs.SuperBloque
-		if err := binary.Read(file, binary.LittleEndian, &sb); err == nil {
-			// Validar que parezca un superbloque válido
-			if sb.S_inodes_count > 0 && sb.S_inode_s > 0 && sb.S_block_s > 0 {
-				// Construir una partición basada en la información de mounted
-				var p structs.Partition
-				p.Part_start = mounted.Start
-				p.Part_s = mounted.Size
-				// Rellenar nombre (copiar hasta 16 bytes)
-				var nameBytes [16]byte
-				copy(nameBytes[:], []byte(mounted.Name))
-				p.Part_name = nameBytes
-				return &p, &sb, nil
-			}
-		}
-		// Si falló, continuamos intentando buscar por nombre en el MBR
-	}
+	return WriteFileContent(mounted, "users.txt", []byte(newContent))
+}
 
-	// Leer el MBR
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		return nil, nil, fmt.Errorf("error al leer el MBR: %v", err)
+// ReadFile - Leer el contenido de un archivo multi-bloque (directos e indirectos).
+// Se leen exactamente I_s bytes, sin recortar NUL, para no alterar archivos binarios.
+func (fs *FileSystem) ReadFile(fileInode *structs.Inodos) ([]byte, error) {
+	var content bytes.Buffer
+	if fileInode.I_s > 0 {
+		content.Grow(int(fileInode.I_s))
 	}
-
-	// Encontrar la partición específica por nombre (por compatibilidad)
-	var partition *structs.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_status != '0' {
-			partitionNameBytes := p.Part_name[:]
-			nullIndex := len(partitionNameBytes)
-			for j, b := range partitionNameBytes {
-				if b == 0 {
-					nullIndex = j
-					break
-				}
-			}
-			partitionName := strings.TrimSpace(string(partitionNameBytes[:nullIndex]))
-
-			if strings.EqualFold(partitionName, mounted.Name) || partitionName == mounted.Name {
-				partitionCopy := p
-				partition = &partitionCopy
-				break
-			}
-		}
+	if _, err := fs.ReadFileTo(&content, fileInode); err != nil {
+		return nil, err
 	}
+	return content.Bytes(), nil
+}
 
-	if partition == nil {
-		return nil, nil, fmt.Errorf("no se pudo encontrar la partición '%s'", mounted.Name)
+// IsTextContent - Indicar si el contenido se puede mostrar como texto: UTF-8
+// válido, sin NUL y con pocos caracteres de control
+func IsTextContent(content []byte) bool {
+	if len(content) == 0 {
+		return true
+	}
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) != -1 {
+		return false
 	}
 
-	// Leer el superbloque
-	file.Seek(partition.Part_start, 0)
-	var superblock structs.SuperBloque
-	if err := binary.Read(file, binary.LittleEndian, &superblock); err != nil {
-		return nil, nil, fmt.Errorf("error al leer el superbloque: %v", err)
+	// Contar caracteres no imprimibles
+	nonPrintable := 0
+	for _, char := range content {
+		if char < 32 && char != 9 && char != 10 && char != 13 { // Excluir tab, LF, CR
+			nonPrintable++
+		}
 	}
 
-	return partition, &superblock, nil
+	// Si más del 20% son caracteres no imprimibles, considerarlo binario
+	threshold := len(content) / 5
+	return nonPrintable <= threshold
 }
 
-// Buscar archivo en el directorio raíz
-func findFileInRootDirectory(file *os.File, superblock *structs.SuperBloque, fileName string) (int64, error) {
-	file.Seek(superblock.S_block_start, 0)
+// WriteFile - Reemplazar el contenido de un archivo multi-bloque y guardar su inodo
+func (fs *FileSystem) WriteFile(inodeIndex int64, fileInode *structs.Inodos, contentBytes []byte) error {
+	blockSize := fs.geometry().Data
+	blocksNeeded := (len(contentBytes) + blockSize - 1) / blockSize
 
-	var rootBlock structs.BloqueCarpeta
-	if err := binary.Read(file, binary.LittleEndian, &rootBlock); err != nil {
-		return -1, fmt.Errorf("error al leer el directorio raíz: %v", err)
+	if int64(blocksNeeded) > fs.maxBlocksPerInode() {
+		return fmt.Errorf("el archivo es demasiado grande: necesita %d bloques, máximo %d", blocksNeeded, fs.maxBlocksPerInode())
 	}
 
-	for i := 0; i < 4; i++ {
-		if rootBlock.BContent[i].BInodo != -1 {
-			entryName := string(rootBlock.BContent[i].BName[:])
-			entryName = strings.Trim(entryName, "\x00")
-
-			if entryName == fileName {
-				return rootBlock.BContent[i].BInodo, nil
-			}
-		}
+	// Si el archivo se reduce, liberar todos sus bloques y volver a asignarlos
+	currentBlocks, err := fs.DataBlocks(fileInode)
+	if err != nil {
+		return err
 	}
-
-	return -1, fmt.Errorf("archivo no encontrado")
-}
-
-// Leer contenido de archivo multi-bloque
-func readFileContentMultiBlock(file *os.File, superblock *structs.SuperBloque, fileInode *structs.Inodos) (string, error) {
-	var content strings.Builder
-
-	for i := 0; i < 15; i++ {
-		if fileInode.I_block[i] == -1 {
-			break
+	if len(currentBlocks) > blocksNeeded {
+		if err := fs.FreeInodeBlocks(fileInode); err != nil {
+			return err
 		}
+	}
 
-		blockPosition := superblock.S_block_start + (fileInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPosition, 0)
-
-		var fileBlock structs.BloqueArchivo
-		if err := binary.Read(file, binary.LittleEndian, &fileBlock); err != nil {
-			return "", fmt.Errorf("error al leer el bloque %d: %v", i, err)
-		}
+	// Actualizar el tamaño del archivo
+	fileInode.I_s = int64(len(contentBytes))
 
-		remainingBytes := fileInode.I_s - int64(content.Len())
-		if remainingBytes <= 0 {
-			break
+	// Escribir contenido en múltiples bloques, asignando los que falten
+	for blockIndex := 0; blockIndex < blocksNeeded; blockIndex++ {
+		blockNum, err := fs.BlockFor(fileInode, int64(blockIndex))
+		if err != nil {
+			return fmt.Errorf("no se pudo asignar bloque %d: %w", blockIndex, err)
 		}
 
-		var blockContent string
-		if remainingBytes >= int64(len(fileBlock.BContent)) {
-			blockContent = string(fileBlock.BContent[:])
-		} else {
-			blockContent = string(fileBlock.BContent[:remainingBytes])
+		startByte := blockIndex * blockSize
+		endByte := startByte + blockSize
+		if endByte > len(contentBytes) {
+			endByte = len(contentBytes)
 		}
 
-		blockContent = strings.TrimRight(blockContent, "\x00")
-		content.WriteString(blockContent)
+		fileBlock := fs.geometry().newFileBlock()
+		copy(fileBlock.BContent, contentBytes[startByte:endByte])
 
-		if int64(content.Len()) >= fileInode.I_s {
-			break
+		if err := fs.WriteBlock(blockNum, &fileBlock); err != nil {
+			return fmt.Errorf("error al escribir el bloque %d: %v", blockIndex, err)
 		}
 	}
 
-	result := content.String()
-	if int64(len(result)) > fileInode.I_s {
-		result = result[:fileInode.I_s]
+	// Escribir el inodo actualizado
+	touchModify(fileInode)
+	if err := fs.WriteInode(inodeIndex, fileInode); err != nil {
+		return fmt.Errorf("error al escribir el inodo actualizado: %v", err)
 	}
 
-	return result, nil
+	return nil
 }
 
-// Escribir contenido de archivo multi-bloque
-func writeFileContentMultiBlock(file *os.File, superblock *structs.SuperBloque, fileInode *structs.Inodos, newContent string, inodePosition int64) error {
-	blockSize := len(structs.BloqueArchivo{}.BContent)
-	contentBytes := []byte(newContent)
-	blocksNeeded := (len(contentBytes) + blockSize - 1) / blockSize
-
-	if blocksNeeded > 15 {
-		return fmt.Errorf("el archivo es demasiado grande: necesita %d bloques, máximo 15", blocksNeeded)
+// WriteFileFrom - Escribir en un archivo sin bloques lo que se lea de r, un
+// bloque a la vez, y guardar su inodo. Devuelve los bytes escritos. Si falla,
+// libera los bloques que alcanzó a asignar y no toca el inodo en el disco.
+func (fs *FileSystem) WriteFileFrom(inodeIndex int64, fileInode *structs.Inodos, r io.Reader) (int64, error) {
+	written, err := fs.writeBlocksFrom(fileInode, r)
+	if err != nil {
+		if freeErr := fs.FreeInodeBlocks(fileInode); freeErr != nil {
+			return written, fmt.Errorf("%v (además, error al liberar los bloques asignados: %v)", err, freeErr)
+		}
+		return written, err
 	}
 
-	// Actualizar el tamaño del archivo
-	fileInode.I_s = int64(len(newContent))
-
-	// Contar bloques actuales
-	currentBlocks := 0
-	for i := 0; i < 15; i++ {
-		if fileInode.I_block[i] != -1 {
-			currentBlocks++
-		} else {
-			break
-		}
+	fileInode.I_s = written
+	touchModify(fileInode)
+	if err := fs.WriteInode(inodeIndex, fileInode); err != nil {
+		return written, fmt.Errorf("error al escribir el inodo actualizado: %v", err)
 	}
+	return written, nil
+}
 
-	// Asignar bloques adicionales si es necesario
-	if blocksNeeded > currentBlocks {
-		for i := currentBlocks; i < blocksNeeded; i++ {
-			newBlockIndex, err := findFreeBlock(file, superblock)
+// writeBlocksFrom - Copiar r a los bloques de un inodo sin bloques, asignándolos
+func (fs *FileSystem) writeBlocksFrom(fileInode *structs.Inodos, r io.Reader) (int64, error) {
+	var written int64
+	for blockIndex := int64(0); ; blockIndex++ {
+		fileBlock := fs.geometry().newFileBlock()
+		n, readErr := io.ReadFull(r, fileBlock.BContent)
+		if n > 0 {
+			if blockIndex >= fs.maxBlocksPerInode() {
+				return written, newCommandError(ErrCodeNoSpace, "el archivo supera el máximo de %d bytes por inodo", fs.maxFileSize())
+			}
+			blockNum, err := fs.BlockFor(fileInode, blockIndex)
 			if err != nil {
-				return fmt.Errorf("no se pudo asignar bloque %d: %v", i, err)
+				return written, wrapCommandError(ErrCodeNoSpace, err, "no se pudo asignar el bloque %d", blockIndex)
 			}
-			fileInode.I_block[i] = newBlockIndex
-
-			if err := markBlockAsUsed(file, superblock, newBlockIndex); err != nil {
-				return fmt.Errorf("error al marcar bloque como usado: %v", err)
+			if err := fs.WriteBlock(blockNum, &fileBlock); err != nil {
+				return written, fmt.Errorf("error al escribir el bloque %d: %v", blockIndex, err)
 			}
-
+			written += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return written, fmt.Errorf("error al leer el contenido: %v", readErr)
 		}
 	}
+	return written, nil
+}
 
-	// Escribir el inodo actualizado
-	file.Seek(inodePosition, 0)
-	if err := binary.Write(file, binary.LittleEndian, fileInode); err != nil {
-		return fmt.Errorf("error al escribir el inodo actualizado: %v", err)
+// ReadFileTo - Copiar a w el contenido de un archivo, un bloque a la vez
+func (fs *FileSystem) ReadFileTo(w io.Writer, fileInode *structs.Inodos) (int64, error) {
+	dataBlocks, err := fs.DataBlocks(fileInode)
+	if err != nil {
+		return 0, err
 	}
 
-	// Escribir contenido en múltiples bloques
-	for blockIndex := 0; blockIndex < blocksNeeded; blockIndex++ {
-		if fileInode.I_block[blockIndex] == -1 {
-			return fmt.Errorf("bloque %d no está asignado", blockIndex)
-		}
-
-		startByte := blockIndex * blockSize
-		endByte := startByte + blockSize
-		if endByte > len(contentBytes) {
-			endByte = len(contentBytes)
+	var written int64
+	for i, blockIndex := range dataBlocks {
+		remaining := fileInode.I_s - written
+		if remaining <= 0 {
+			break
 		}
 
-		blockContent := contentBytes[startByte:endByte]
-
-		blockPosition := superblock.S_block_start + (fileInode.I_block[blockIndex] * superblock.S_block_s)
-		file.Seek(blockPosition, 0)
-
 		var fileBlock structs.BloqueArchivo
-		for i := range fileBlock.BContent {
-			fileBlock.BContent[i] = 0
+		if err := fs.ReadBlock(blockIndex, &fileBlock); err != nil {
+			return written, fmt.Errorf("error al leer el bloque %d: %v", i, err)
 		}
 
-		copy(fileBlock.BContent[:], blockContent)
-
-		if err := binary.Write(file, binary.LittleEndian, &fileBlock); err != nil {
-			return fmt.Errorf("error al escribir el bloque %d: %v", blockIndex, err)
+		chunk := fileBlock.BContent
+		if remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+		n, err := w.Write(chunk)
+		written += int64(n)
+		if err != nil {
+			return written, err
 		}
-
 	}
 
-	return nil
+	return written, nil
 }
 
-// Buscar bloque libre
-func findFreeBlock(file *os.File, superblock *structs.SuperBloque) (int64, error) {
-	file.Seek(superblock.S_bm_block_start, 0)
-	bitmap := make([]byte, superblock.S_blocks_count)
-	if _, err := file.Read(bitmap); err != nil {
-		return -1, fmt.Errorf("error al leer bitmap de bloques: %v", err)
+// findFree - Primer índice libre de un bitmap. Los índices para los que avoid
+// devuelve true se usan solo si no queda otro libre.
+func (fs *FileSystem) findFree(bitmapStart, count int64, avoid func(int64) bool) (int64, bool, error) {
+	bitmap := make([]byte, count)
+	if _, err := fs.file.ReadAt(bitmap, bitmapStart); err != nil {
+		return -1, false, err
 	}
 
-	for i := int64(0); i < superblock.S_blocks_count; i++ {
-		if bitmap[i] == 0 {
-			return i, nil
+	fallback := int64(-1)
+	for i := int64(0); i < count; i++ {
+		if bitmap[i] != 0 {
+			continue
+		}
+		if avoid != nil && avoid(i) {
+			if fallback == -1 {
+				fallback = i
+			}
+			continue
 		}
+		return i, true, nil
 	}
 
-	return -1, fmt.Errorf("no hay bloques libres disponibles")
+	return fallback, fallback != -1, nil
 }
 
-// Marcar bloque como usado
-func markBlockAsUsed(file *os.File, superblock *structs.SuperBloque, blockIndex int64) error {
-	bitmapPosition := superblock.S_bm_block_start + blockIndex
-	file.Seek(bitmapPosition, 0)
-
-	if _, err := file.Write([]byte{1}); err != nil {
-		return fmt.Errorf("error al marcar bloque como usado: %v", err)
+// setBitmapByte - Escribir un byte de bitmap. Devuelve false si ya tenía ese estado,
+// para que los contadores del superbloque solo cambien una vez.
+func (fs *FileSystem) setBitmapByte(position int64, value byte) (bool, error) {
+	current := make([]byte, 1)
+	if _, err := fs.file.ReadAt(current, position); err != nil {
+		return false, err
+	}
+	if (current[0] != 0) == (value != 0) {
+		return false, nil
 	}
 
-	return nil
+	if _, err := fs.file.WriteAt([]byte{value}, position); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// Buscar inodo libre
-func findFreeInode(file *os.File, superblock *structs.SuperBloque) (int64, error) {
-	file.Seek(superblock.S_bm_inode_start, 0)
-	bitmap := make([]byte, superblock.S_inodes_count)
-	if _, err := file.Read(bitmap); err != nil {
-		return -1, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+// AllocBlock - Buscar un bloque libre y marcarlo como usado (descuenta S_free_blocks_count)
+func (fs *FileSystem) AllocBlock() (int64, error) {
+	// Como en ext3, los bloques que liberó la transacción abierta se reutilizan
+	// al final: así los datos nuevos van directo al disco y no al journal
+	blockIndex, found, err := fs.findFree(fs.SB.S_bm_block_start, fs.SB.S_blocks_count, fs.file.freedInTransaction)
+	if err != nil {
+		return -1, fmt.Errorf("error al leer bitmap de bloques: %v", err)
+	}
+	if !found {
+		return -1, newCommandError(ErrCodeNoSpace, "no hay bloques libres disponibles")
+	}
+	if err := fs.quota.charge(1, 0); err != nil {
+		return -1, err
 	}
 
-	for i := int64(0); i < superblock.S_inodes_count; i++ {
-		if bitmap[i] == 0 {
-			return i, nil
-		}
+	if err := fs.MarkBlock(blockIndex, true); err != nil {
+		return -1, err
 	}
+	return blockIndex, nil
+}
 
-	return -1, fmt.Errorf("no hay inodos libres disponibles")
+// FreeBlock - Marcar un bloque como libre (suma a S_free_blocks_count)
+func (fs *FileSystem) FreeBlock(blockIndex int64) error {
+	fs.quota.release(1, 0)
+	return fs.MarkBlock(blockIndex, false)
 }
 
-// Marcar inodo como usado
-func markInodeAsUsed(file *os.File, superblock *structs.SuperBloque, inodeIndex int64) error {
-	bitmapPosition := superblock.S_bm_inode_start + inodeIndex
-	file.Seek(bitmapPosition, 0)
+// MarkBlock - Marcar un bloque como usado o libre ajustando el contador del superbloque
+func (fs *FileSystem) MarkBlock(blockIndex int64, used bool) error {
+	state := "libre"
+	if used {
+		state = "usado"
+	}
+	if blockIndex < 0 || blockIndex >= fs.SB.S_blocks_count {
+		return fmt.Errorf("error al marcar bloque como %s: bloque %d fuera de rango", state, blockIndex)
+	}
 
-	if _, err := file.Write([]byte{1}); err != nil {
-		return fmt.Errorf("error al marcar inodo como usado: %v", err)
+	changed, err := fs.setBitmapByte(fs.SB.S_bm_block_start+blockIndex, bitmapValue(used))
+	if err != nil {
+		return fmt.Errorf("error al marcar bloque como %s: %v", state, err)
+	}
+	if changed && used {
+		fs.SB.S_free_blocks_count--
+	} else if changed {
+		fs.SB.S_free_blocks_count++
 	}
 
 	return nil
 }
 
-// Marcar inodo como libre
-func markInodeAsFree(file *os.File, superblock *structs.SuperBloque, inodeIndex int64) error {
-	bitmapPosition := superblock.S_bm_inode_start + inodeIndex
-	file.Seek(bitmapPosition, 0)
+// AllocInode - Buscar un inodo libre y marcarlo como usado (descuenta S_free_inodes_count)
+func (fs *FileSystem) AllocInode() (int64, error) {
+	inodeIndex, found, err := fs.findFree(fs.SB.S_bm_inode_start, fs.SB.S_inodes_count, nil)
+	if err != nil {
+		return -1, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+	if !found {
+		return -1, newCommandError(ErrCodeNoSpace, "no hay inodos libres disponibles")
+	}
+	if err := fs.quota.charge(0, 1); err != nil {
+		return -1, err
+	}
 
-	if _, err := file.Write([]byte{0}); err != nil {
-		return fmt.Errorf("error al marcar inodo como libre: %v", err)
+	if err := fs.MarkInode(inodeIndex, true); err != nil {
+		return -1, err
 	}
+	return inodeIndex, nil
+}
 
-	return nil
+// FreeInode - Marcar un inodo como libre (suma a S_free_inodes_count)
+func (fs *FileSystem) FreeInode(inodeIndex int64) error {
+	fs.quota.release(0, 1)
+	return fs.MarkInode(inodeIndex, false)
 }
 
-// Marcar bloque como libre
-func markBlockAsFree(file *os.File, superblock *structs.SuperBloque, blockIndex int64) error {
-	bitmapPosition := superblock.S_bm_block_start + blockIndex
-	file.Seek(bitmapPosition, 0)
+// MarkInode - Marcar un inodo como usado o libre ajustando el contador del superbloque
+func (fs *FileSystem) MarkInode(inodeIndex int64, used bool) error {
+	state := "libre"
+	if used {
+		state = "usado"
+	}
+	if inodeIndex < 0 || inodeIndex >= fs.SB.S_inodes_count {
+		return fmt.Errorf("error al marcar inodo como %s: inodo %d fuera de rango", state, inodeIndex)
+	}
 
-	if _, err := file.Write([]byte{0}); err != nil {
-		return fmt.Errorf("error al marcar bloque como libre: %v", err)
+	changed, err := fs.setBitmapByte(fs.SB.S_bm_inode_start+inodeIndex, bitmapValue(used))
+	if err != nil {
+		return fmt.Errorf("error al marcar inodo como %s: %v", state, err)
+	}
+	if changed && used {
+		fs.SB.S_free_inodes_count--
+	} else if changed {
+		fs.SB.S_free_inodes_count++
 	}
 
 	return nil
 }
 
+// bitmapValue - Byte del bitmap para un estado
+func bitmapValue(used bool) byte {
+	if used {
+		return 1
+	}
+	return 0
+}
+
 // Estructura para parsear rutas
 type ParsedPath struct {
 	IsAbsolute  bool
@@ -615,13 +621,10 @@ func parsePath(path string) *ParsedPath {
 	}
 }
 
-// Buscar inodo en un directorio (función global)
-func findInodeInDirectory(file *os.File, superblock *structs.SuperBloque, dirInodeIndex int64, itemName string) (int64, error) {
-	// Leer el inodo del directorio
-	inodePosition := superblock.S_inode_start + (dirInodeIndex * superblock.S_inode_s)
-	file.Seek(inodePosition, 0)
-	var dirInode structs.Inodos
-	if err := binary.Read(file, binary.LittleEndian, &dirInode); err != nil {
+// LookupIn - Buscar una entrada por nombre dentro de un directorio
+func (fs *FileSystem) LookupIn(dirInodeIndex int64, itemName string) (int64, error) {
+	dirInode, err := fs.ReadInode(dirInodeIndex)
+	if err != nil {
 		return -1, fmt.Errorf("error al leer inodo del directorio: %v", err)
 	}
 
@@ -630,43 +633,23 @@ func findInodeInDirectory(file *os.File, superblock *structs.SuperBloque, dirIno
 		return -1, fmt.Errorf("no es un directorio")
 	}
 
-	// Buscar en todos los bloques del directorio
-	for i := 0; i < 15; i++ {
-		if dirInode.I_block[i] == -1 {
-			break
-		}
-
-		blockPosition := superblock.S_block_start + (dirInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPosition, 0)
+	// Buscar en todos los bloques del directorio (directos e indirectos)
+	dirBlocks, err := fs.DataBlocks(dirInode)
+	if err != nil {
+		return -1, err
+	}
 
-		var dirBlock structs.BloqueCarpeta
-		if err := binary.Read(file, binary.LittleEndian, &dirBlock); err != nil {
+	for _, blockNum := range dirBlocks {
+		dirBlock, err := fs.ReadFolderBlock(blockNum)
+		if err != nil {
 			return -1, fmt.Errorf("error al leer bloque del directorio: %v", err)
 		}
 
 		// Buscar en las entradas del bloque
-		for j := 0; j < 4; j++ {
-			if dirBlock.BContent[j].BInodo != -1 {
-				entryName := string(dirBlock.BContent[j].BName[:])
-				entryName = strings.Trim(entryName, "\x00")
-
-				// Comparación exacta incluyendo espacios
-				if entryName == itemName {
-					return dirBlock.BContent[j].BInodo, nil
-				}
-
-				// Comparación tolerante: comparar versiones truncadas a 12 caracteres
-				e := entryName
-				c := itemName
-				if len(e) > 12 {
-					e = e[:12]
-				}
-				if len(c) > 12 {
-					c = c[:12]
-				}
-				if e == c {
-					return dirBlock.BContent[j].BInodo, nil
-				}
+		for j := range dirBlock.BContent {
+			entry := &dirBlock.BContent[j]
+			if entry.BInodo != -1 && fs.nameMatches(entry, itemName) {
+				return entry.BInodo, nil
 			}
 		}
 	}
@@ -676,75 +659,60 @@ func findInodeInDirectory(file *os.File, superblock *structs.SuperBloque, dirIno
 
 // GetMountedPartitions devuelve las particiones montadas (función auxiliar)
 func GetMountedPartitions() []MountedPartition {
-	// Intentar cargar desde disco si está vacío
-	if len(mountedPartitions) == 0 {
-		// Llamar a la función que carga montajes (definida en mount.go)
-		// la función loadMountsFromFile es package-local en mount.go
-		// así que la llamamos indirectamente pidiendo que GetMountedPartition realice la carga
-		// Intentamos un acceso no intrusivo
-		_ = GetMountedPartition("")
-	}
-	return mountedPartitions
+	// Copia de la tabla para recorrerla sin tener tomado mountsMutex
+	mountsMutex.RLock()
+	defer mountsMutex.RUnlock()
+	return append([]MountedPartition(nil), mountedPartitions...)
 }
 
-// ReadFileByPath lee el contenido de un archivo dado su path completo
-func ReadFileByPath(mounted *MountedPartition, filePath string) (string, error) {
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
-	if err != nil {
-		return "", fmt.Errorf("error al abrir el disco: %v", err)
-	}
-	defer file.Close()
+// ReadFileByPath lee el contenido de un archivo dado su path completo. El
+// contenido va en res.Data["content"] ([]byte).
+func ReadFileByPath(mounted *MountedPartition, filePath string) (*CommandResult, error) {
+	res := NewCommandResult("read")
 
-	// Obtener partición y superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
+	content, inodeIndex, err := readFileByPath(mounted, filePath)
 	if err != nil {
-		return "", err
+		return res, err
 	}
+	res.AddPath(filePath)
+	res.Set("content", content)
 
-	// Parsear el path
-	parsed := parsePath(filePath)
-	if parsed == nil {
-		return "", fmt.Errorf("ruta inválida")
+	// La fecha de acceso se registra después de liberar el bloqueo compartido
+	if err := recordAccess(mounted, []int64{inodeIndex}); err != nil {
+		res.Printf("⚠️ No se pudo actualizar la fecha de acceso de '%s': %v\n", filePath, err)
+		res.Set("atimeError", err.Error())
 	}
+	return res, nil
+}
 
-	// Navegar por los directorios hasta llegar al archivo
-	currentInodeIndex := int64(0) // Inodo raíz
-
-	// Navegar por cada directorio en el path
-	for _, dirName := range parsed.Directories {
-		nextInode, err := findInodeInDirectory(file, superblock, currentInodeIndex, dirName)
-		if err != nil {
-			return "", fmt.Errorf("no se encontró el directorio '%s': %v", dirName, err)
-		}
-		currentInodeIndex = nextInode
-	}
+// readFileByPath - Lectura de ReadFileByPath con el disco compartido. Devuelve
+// también el inodo, para actualizar su fecha de acceso.
+func readFileByPath(mounted *MountedPartition, filePath string) ([]byte, int64, error) {
+	defer rlockDisk(mounted.Path)()
 
-	// Ahora buscar el archivo en el directorio actual
-	fileInodeIndex, err := findInodeInDirectory(file, superblock, currentInodeIndex, parsed.FileName)
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
 	if err != nil {
-		return "", fmt.Errorf("archivo '%s' no encontrado: %v", parsed.FileName, err)
+		return nil, -1, err
 	}
+	defer fs.Close()
 
-	// Leer el inodo del archivo
-	inodePosition := superblock.S_inode_start + (fileInodeIndex * superblock.S_inode_s)
-	file.Seek(inodePosition, 0)
-	var fileInode structs.Inodos
-	if err := binary.Read(file, binary.LittleEndian, &fileInode); err != nil {
-		return "", fmt.Errorf("error al leer el inodo del archivo: %v", err)
+	inodeIndex, fileInode, err := fs.Lookup(filePath)
+	if err != nil {
+		return nil, -1, err
 	}
 
 	// Verificar que sea un archivo y no una carpeta
 	if fileInode.I_type == '0' {
-		return "", fmt.Errorf("'%s' es una carpeta, no un archivo", parsed.FileName)
+		return nil, -1, fmt.Errorf("'%s' es una carpeta, no un archivo", filePath)
 	}
 
 	// Leer el contenido del archivo
-	content, err := readFileContentMultiBlock(file, superblock, &fileInode)
+	content, err := fs.ReadFile(fileInode)
 	if err != nil {
-		return "", fmt.Errorf("error al leer el contenido del archivo: %v", err)
+		return nil, -1, fmt.Errorf("error al leer el contenido del archivo: %v", err)
 	}
 
-	return content, nil
+	return content, inodeIndex, nil
 }
 
 // JournalEntry representa una entrada del journaling para el frontend
@@ -759,17 +727,14 @@ type JournalEntry struct {
 
 // GetJournaling obtiene todas las entradas del journaling basado en structs.Journal
 func GetJournaling(mounted *MountedPartition) ([]JournalEntry, error) {
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("error al abrir el disco: %v", err)
-	}
-	defer file.Close()
+	defer rlockDisk(mounted.Path)()
 
-	// Obtener partición y superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
 	if err != nil {
 		return nil, err
 	}
+	defer fs.Close()
+	file, superblock := fs.file, fs.SB
 
 	fmt.Println("========== DEBUG JOURNALING ==========")
 	fmt.Printf("Partición: %s\n", mounted.Name)
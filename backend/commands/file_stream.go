@@ -0,0 +1,226 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// TRANSFERENCIA DE ARCHIVOS (PUT/GET /file)
+// El contenido viaja como bytes entre la petición HTTP y los bloques del
+// archivo, sin armarlo completo en memoria ni pasar por un archivo del host.
+
+// streamPartition - Partición sobre la que trabaja una transferencia: la
+// indicada (si la sesión tiene acceso) o la de la sesión
+func streamPartition(session *Session, id string) (*MountedPartition, error) {
+	if err := RequireActiveSession(session); err != nil {
+		return nil, err
+	}
+	if id == "" {
+		id = session.PartitionID
+	}
+	if err := ValidatePartitionAccess(session, id); err != nil {
+		return nil, commandErrorFrom(ErrCodePermissionDenied, err)
+	}
+
+	mounted := GetMountedPartition(id)
+	if mounted == nil {
+		return nil, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", id)
+	}
+	return mounted, nil
+}
+
+// UploadFile - Crear o reemplazar un archivo con los bytes que se lean de r.
+// Con recursive se crean las carpetas padre que falten. length es el tamaño
+// anunciado (Content-Length) o -1 si no se conoce; si no cabe en un inodo se
+// rechaza antes de tocar la partición.
+func UploadFile(session *Session, id string, path string, recursive bool, length int64, r io.Reader) (*CommandResult, error) {
+	res := NewCommandResult("upload")
+
+	parsedPath := parsePath(path)
+	if parsedPath == nil || parsedPath.FileName == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "ruta inválida: '%s'", path)
+	}
+
+	mounted, err := streamPartition(session, id)
+	if err != nil {
+		return res, err
+	}
+
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
+	}
+	defer tx.rollback()
+
+	inodeIndex, size, err := uploadToFileSystem(res, mounted, parsedPath, recursive, session, length, r)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al subir el archivo '%s'", path)
+	}
+
+	if err := WriteJournal(res, mounted, "upload", path, fmt.Sprintf("%d bytes", size)); err != nil {
+		res.Printf("⚠️ No se pudo escribir al journal: %v\n", err)
+	}
+
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	res.Printf("✅ Archivo '%s' subido exitosamente con %d bytes.\n", path, size)
+	res.AddPath(path)
+	res.Set("inode", inodeIndex)
+	res.Set("size", size)
+
+	return res, nil
+}
+
+// uploadToFileSystem - Copiar el contenido a bloques nuevos y pasar a ellos el
+// inodo del archivo (nuevo o existente) solo si la copia termina: en EXT2 no hay
+// transacción que deshaga una subida a medias
+func uploadToFileSystem(res *CommandResult, mounted *MountedPartition, parsedPath *ParsedPath, recursive bool, session *Session, length int64, r io.Reader) (int64, int64, error) {
+	fs, err := openFileSystem(mounted, os.O_RDWR)
+	if err != nil {
+		return -1, 0, err
+	}
+	defer fs.Close()
+
+	if maxFileSize := fs.maxFileSize(); length > maxFileSize {
+		return -1, 0, newCommandError(ErrCodeNoSpace, "el contenido es demasiado grande (máximo %d bytes)", maxFileSize)
+	}
+
+	// Lo que se crea cuenta para la cuota del usuario
+	if err := fs.enforceSessionQuota(session); err != nil {
+		return -1, 0, fmt.Errorf("error al leer las cuotas: %v", err)
+	}
+
+	parentInode, err := ensureParentDirectories(res, fs, parsedPath, recursive, "r", session)
+	if err != nil {
+		return -1, 0, err
+	}
+
+	inodeIndex, err := fs.LookupIn(parentInode, parsedPath.FileName)
+	if err == nil {
+		// Reemplazar un archivo existente: se conserva su inodo (dueño y permisos)
+		fileInode, err := fs.ReadInode(inodeIndex)
+		if err != nil {
+			return -1, 0, err
+		}
+		if fileInode.I_type != '1' {
+			return -1, 0, newCommandError(ErrCodeInvalidArgument, "'%s' es una carpeta", parsedPath.FullPath)
+		}
+		canWrite, err := fs.CanWrite(inodeIndex, session)
+		if err != nil {
+			return -1, 0, fmt.Errorf("error al verificar permisos: %v", err)
+		}
+		if !canWrite {
+			return -1, 0, newCommandError(ErrCodePermissionDenied, "sin permisos de escritura sobre '%s'", parsedPath.FullPath)
+		}
+		// El contenido nuevo cuenta para la cuota del dueño del archivo
+		if err := fs.enforceQuota(session, fileInode.I_uid, fileInode.I_gid); err != nil {
+			return -1, 0, fmt.Errorf("error al leer las cuotas: %v", err)
+		}
+
+		// Los bloques viejos se liberan cuando el inodo ya apunta a los nuevos
+		staged := *fileInode
+		for i := range staged.I_block {
+			staged.I_block[i] = -1
+		}
+		size, err := fs.WriteFileFrom(inodeIndex, &staged, r)
+		if err != nil {
+			fs.Flush()
+			return -1, 0, err
+		}
+		if err := fs.FreeInodeBlocks(fileInode); err != nil {
+			return -1, 0, err
+		}
+		if err := fs.Flush(); err != nil {
+			return -1, 0, err
+		}
+		return inodeIndex, size, nil
+	}
+
+	canWrite, err := fs.CanWrite(parentInode, session)
+	if err != nil {
+		return -1, 0, fmt.Errorf("error al verificar permisos: %v", err)
+	}
+	if !canWrite {
+		return -1, 0, newCommandError(ErrCodePermissionDenied, "sin permisos de escritura en el directorio padre")
+	}
+
+	// Archivo nuevo: la entrada se agrega cuando el contenido ya está escrito
+	if inodeIndex, err = fs.AllocInode(); err != nil {
+		return -1, 0, wrapCommandError(ErrCodeNoSpace, err, "no hay inodos libres")
+	}
+	fileInode := fs.newInode('1', session)
+	size, err := fs.WriteFileFrom(inodeIndex, &fileInode, r)
+	if err == nil {
+		if err = fs.AddEntry(parentInode, parsedPath.FileName, inodeIndex); err != nil {
+			err = fmt.Errorf("error al agregar entrada al directorio padre: %w", err)
+			fs.FreeInodeBlocks(&fileInode)
+		}
+	}
+	if err != nil {
+		fs.FreeInode(inodeIndex)
+		fs.Flush()
+		return -1, 0, err
+	}
+
+	if err := fs.Flush(); err != nil {
+		return -1, 0, err
+	}
+	return inodeIndex, size, nil
+}
+
+// DownloadFile - Copiar a un destino el contenido de un archivo. start recibe el
+// tamaño antes del primer byte (para las cabeceras HTTP) y devuelve el destino;
+// si falla la búsqueda o los permisos no se llama. El resultado lleva los
+// bytes copiados (size).
+func DownloadFile(session *Session, id string, path string, start func(size int64) io.Writer) (*CommandResult, error) {
+	res := NewCommandResult("download")
+
+	mounted, err := streamPartition(session, id)
+	if err != nil {
+		return res, err
+	}
+
+	size, inodeIndex, err := downloadFile(mounted, session, path, start)
+	res.Set("size", size)
+	if err != nil {
+		return res, err
+	}
+	res.AddPath(path)
+
+	// La fecha de acceso se registra después de liberar el bloqueo compartido
+	if err := recordAccess(mounted, []int64{inodeIndex}); err != nil {
+		res.Printf("⚠️ No se pudo actualizar la fecha de acceso de '%s': %v\n", path, err)
+		res.Set("atimeError", err.Error())
+	}
+	return res, nil
+}
+
+// downloadFile - Lectura de DownloadFile con el disco compartido. Devuelve
+// también el inodo, para actualizar su fecha de acceso.
+func downloadFile(mounted *MountedPartition, session *Session, path string, start func(size int64) io.Writer) (int64, int64, error) {
+	defer rlockDisk(mounted.Path)()
+
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
+	if err != nil {
+		return 0, -1, wrapCommandError(ErrCodeIO, err, "error al abrir la partición")
+	}
+	defer fs.Close()
+
+	inodeIndex, fileInode, err := fs.Lookup(path)
+	if err != nil {
+		return 0, -1, newCommandError(ErrCodeNotFound, "archivo '%s' no encontrado", path)
+	}
+	if fileInode.I_type != '1' {
+		return 0, -1, newCommandError(ErrCodeInvalidArgument, "'%s' no es un archivo", path)
+	}
+	if !hasReadPermission(fileInode, session.User) {
+		return 0, -1, newCommandError(ErrCodePermissionDenied, "sin permisos de lectura para el archivo '%s'", path)
+	}
+
+	size, err := fs.ReadFileTo(start(fileInode.I_s), fileInode)
+	return size, inodeIndex, err
+}
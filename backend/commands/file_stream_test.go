@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// Pruebas de PUT/GET /file: el contenido subido se descarga igual, y una
+// subida que falla (demasiado grande o cortada) deja el archivo anterior y los
+// contadores como estaban, también en EXT2, donde no hay transacción.
+
+// brokenReader - Entrega lo que tenga r y luego falla, como una conexión que se corta
+type brokenReader struct {
+	r io.Reader
+}
+
+func (b *brokenReader) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("conexión interrumpida")
+	}
+	return n, err
+}
+
+// download - Contenido de un archivo descargado con DownloadFile
+func download(t *testing.T, img *crashImage, path string) []byte {
+	t.Helper()
+	var content bytes.Buffer
+	res, err := DownloadFile(img.session, img.id, path, func(size int64) io.Writer { return &content })
+	if err != nil {
+		t.Fatalf("descargar %s: %v", path, err)
+	}
+	if res.Data["size"] != int64(content.Len()) {
+		t.Fatalf("la descarga informa %v bytes y entregó %d", res.Data["size"], content.Len())
+	}
+	return content.Bytes()
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	img := newCrashImageFS(t, "2fs")
+	content := mkfileContent(5000)
+
+	// Reemplazar un archivo y crear otro con carpetas padre nuevas
+	for _, path := range []string{"/docs/a.txt", "/nuevo/dir/c.txt"} {
+		res, err := UploadFile(img.session, img.id, path, true, -1, bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("subir %s: %v", path, err)
+		}
+		if res.Data["size"] != int64(len(content)) {
+			t.Fatalf("se subieron %v bytes, se esperaban %d", res.Data["size"], len(content))
+		}
+		if got := download(t, img, path); !bytes.Equal(got, content) {
+			t.Fatalf("el contenido descargado de %s (%d bytes) no coincide", path, len(got))
+		}
+	}
+	img.checkClean(t, "tras subir")
+
+	// Un archivo más chico libera los bloques que sobran
+	before := freeBlocksOf(t, img)
+	if _, err := UploadFile(img.session, img.id, "/docs/a.txt", false, 10, bytes.NewReader(content[:10])); err != nil {
+		t.Fatalf("subir: %v", err)
+	}
+	if after := freeBlocksOf(t, img); after <= before {
+		t.Fatalf("al achicar el archivo quedaron %d bloques libres, había %d", after, before)
+	}
+	checkContent(t, img, "/docs/a.txt", content[:10])
+	img.checkClean(t, "tras achicar")
+}
+
+func TestFailedUploadKeepsFile(t *testing.T) {
+	for _, fsType := range []string{"2fs", "3fs"} {
+		t.Run(fsType, func(t *testing.T) {
+			img := newCrashImageFS(t, fsType)
+			fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+			if err != nil {
+				t.Fatal(err)
+			}
+			maxFileSize := fs.maxFileSize()
+			fs.Close()
+			big := mkfileContent(int(maxFileSize) + 1000)
+
+			cases := []struct {
+				name   string
+				length int64
+				reader func() io.Reader
+				code   string
+			}{
+				{"tamaño anunciado", int64(len(big)), func() io.Reader { return bytes.NewReader(big) }, ErrCodeNoSpace},
+				{"tamaño desconocido", -1, func() io.Reader { return bytes.NewReader(big) }, ErrCodeNoSpace},
+				{"conexión cortada", -1, func() io.Reader { return &brokenReader{bytes.NewReader(big[:3000])} }, ErrCodeIO},
+			}
+			for _, tc := range cases {
+				for _, path := range []string{"/docs/a.txt", "/docs/nuevo.txt"} {
+					before := superblockOf(t, img)
+					_, err := UploadFile(img.session, img.id, path, false, tc.length, tc.reader())
+					if ErrorCode(err) != tc.code {
+						t.Fatalf("%s en %s: se esperaba %s: %v", tc.name, path, tc.code, err)
+					}
+					after := superblockOf(t, img)
+					if after.S_free_blocks_count != before.S_free_blocks_count || after.S_free_inodes_count != before.S_free_inodes_count {
+						t.Fatalf("%s en %s: libres %d→%d bloques, %d→%d inodos", tc.name, path,
+							before.S_free_blocks_count, after.S_free_blocks_count, before.S_free_inodes_count, after.S_free_inodes_count)
+					}
+					checkContent(t, img, "/docs/a.txt", mkfileContent(1500))
+					if _, err := statPath(img.mounted, "/docs/nuevo.txt", nil); err == nil {
+						t.Fatalf("%s: quedó /docs/nuevo.txt", tc.name)
+					}
+					img.checkClean(t, tc.name+" en "+path)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,742 @@
+package commands
+
+import (
+	"backend/structs"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DISCO, PARTICIÓN Y SISTEMA DE ARCHIVOS
+// Disk lee el MBR (y la tabla GPT si el MBR es protector) una sola vez y ubica
+// particiones (primarias, extendidas y lógicas). FileSystem conoce la distribución EXT2/EXT3 de una partición:
+// superbloque, bitmaps, inodos y bloques. Los comandos trabajan sobre un
+// FileSystem abierto en vez de calcular posiciones a mano, de modo que todos
+// los pasos de una operación comparten el mismo handle.
+
+// Disk - Disco .mia abierto junto con su MBR
+type Disk struct {
+	Path string
+	MBR  structs.MBR
+	GPT  *gptTable // Tabla GPT (nil en discos MBR)
+	file *diskFile
+}
+
+// OpenDisk - Abrir un disco y leer su MBR
+func OpenDisk(path string, flag int) (*Disk, error) {
+	file, err := openDisk(path, flag)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir el disco: %v", err)
+	}
+
+	disk, err := newDisk(file, path)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return disk, nil
+}
+
+// newDisk - Leer el MBR (y la tabla GPT) de un disco ya abierto
+func newDisk(file *diskFile, path string) (*Disk, error) {
+	disk := &Disk{Path: path, file: file}
+	if err := disk.ReadStruct(0, &disk.MBR); err != nil {
+		return nil, fmt.Errorf("error al leer el MBR: %v", err)
+	}
+	if isGPTDisk(&disk.MBR) {
+		table, err := readGPT(file, &disk.MBR)
+		if err != nil {
+			return nil, err
+		}
+		disk.GPT = table
+	}
+	return disk, nil
+}
+
+// Close - Cerrar el disco
+func (d *Disk) Close() error {
+	return d.file.Close()
+}
+
+// ReadStruct - Leer una estructura en una posición absoluta del disco
+func (d *Disk) ReadStruct(position int64, data interface{}) error {
+	d.file.Seek(position, 0)
+	return binary.Read(d.file, binary.LittleEndian, data)
+}
+
+// WriteStruct - Escribir una estructura en una posición absoluta del disco
+func (d *Disk) WriteStruct(position int64, data interface{}) error {
+	d.file.Seek(position, 0)
+	return binary.Write(d.file, binary.LittleEndian, data)
+}
+
+// WriteMBR - Guardar el MBR en memoria al inicio del disco
+func (d *Disk) WriteMBR() error {
+	if err := d.WriteStruct(0, &d.MBR); err != nil {
+		return fmt.Errorf("error al escribir el MBR: %v", err)
+	}
+	return nil
+}
+
+// WriteTable - Guardar la tabla de particiones en memoria (MBR o GPT)
+func (d *Disk) WriteTable() error {
+	if d.GPT != nil {
+		return d.GPT.write(d.file)
+	}
+	return d.WriteMBR()
+}
+
+// PrimaryPartitions - Entradas de la tabla del disco: los 4 slots del MBR o
+// las entradas GPT (índice = Partition.Index). Se pueden modificar y guardar
+// con WriteTable.
+func (d *Disk) PrimaryPartitions() []*structs.Partition {
+	var partitions []*structs.Partition
+	if d.GPT != nil {
+		for i := range d.GPT.Entries {
+			partitions = append(partitions, &d.GPT.Entries[i].Gpt_partition)
+		}
+		return partitions
+	}
+	for i := range d.MBR.Mbr_partitions {
+		partitions = append(partitions, &d.MBR.Mbr_partitions[i])
+	}
+	return partitions
+}
+
+// tableRanges - Zonas del disco que ocupa la tabla de particiones
+func (d *Disk) tableRanges() []FreeSpace {
+	if d.GPT != nil {
+		return d.GPT.tableRanges()
+	}
+	return []FreeSpace{{Start: 0, Size: int64(binary.Size(structs.MBR{}))}}
+}
+
+// partitionName - Nombre de una partición sin los bytes nulos del final
+func partitionName(name []byte) string {
+	end := len(name)
+	for i, b := range name {
+		if b == 0 {
+			end = i
+			break
+		}
+	}
+	return strings.TrimSpace(string(name[:end]))
+}
+
+// Partition - Partición dentro de un disco (primaria, extendida o lógica)
+type Partition struct {
+	Disk  *Disk
+	Name  string
+	Type  byte // 'P', 'E' o 'L'
+	Start int64
+	Size  int64
+	Index int // Posición en la tabla del MBR o GPT, -1 para lógicas
+}
+
+// FindPartition - Buscar una partición por nombre, primero en el MBR y luego
+// en la cadena de EBRs de la extendida (en discos GPT, en sus entradas)
+func (d *Disk) FindPartition(name string) (*Partition, error) {
+	if d.GPT != nil {
+		index := d.GPT.findPartition(name)
+		if index == -1 {
+			return nil, fmt.Errorf("no se pudo encontrar la partición '%s'", name)
+		}
+		p := &d.GPT.Entries[index].Gpt_partition
+		return &Partition{Disk: d, Name: name, Type: 'P', Start: p.Part_start, Size: p.Part_s, Index: index}, nil
+	}
+
+	var extended *structs.Partition
+	for i := range d.MBR.Mbr_partitions {
+		p := &d.MBR.Mbr_partitions[i]
+		if p.Part_status == '0' || p.Part_s <= 0 {
+			continue
+		}
+		partType := p.Part_type
+		if partType >= 'a' && partType <= 'z' {
+			partType -= 'a' - 'A'
+		}
+		if partType == 'E' {
+			extended = p
+		}
+		if strings.EqualFold(partitionName(p.Part_name[:]), name) {
+			return &Partition{Disk: d, Name: name, Type: partType, Start: p.Part_start, Size: p.Part_s, Index: i}, nil
+		}
+	}
+
+	if extended != nil {
+		for _, ebr := range d.LogicalPartitions(extended) {
+			if strings.EqualFold(partitionName(ebr.PartName[:]), name) {
+				return &Partition{Disk: d, Name: name, Type: 'L', Start: ebr.PartStart, Size: ebr.PartS, Index: -1}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no se pudo encontrar la partición '%s'", name)
+}
+
+// LogicalPartitions - Recorrer la cadena de EBRs de una partición extendida
+func (d *Disk) LogicalPartitions(extended *structs.Partition) []structs.EBR {
+	var ebrs []structs.EBR
+	position := extended.Part_start
+	for position != -1 && len(ebrs) <= 32 {
+		var ebr structs.EBR
+		if err := d.ReadStruct(position, &ebr); err != nil || ebr.PartS == 0 {
+			break
+		}
+		ebrs = append(ebrs, ebr)
+		position = ebr.PartNext
+	}
+	return ebrs
+}
+
+// ReadSuperblock - Leer el superbloque al inicio de la partición
+func (p *Partition) ReadSuperblock() (*structs.SuperBloque, error) {
+	var superblock structs.SuperBloque
+	if err := p.Disk.ReadStruct(p.Start, &superblock); err != nil {
+		return nil, fmt.Errorf("error al leer el superbloque: %v", err)
+	}
+	if superblock.S_inodes_count <= 0 || superblock.S_inode_s <= 0 || !validBlockSize(superblock.S_block_s) {
+		return nil, fmt.Errorf("la partición '%s' no tiene un sistema de archivos", p.Name)
+	}
+	return &superblock, nil
+}
+
+// FileSystem - Abrir el sistema de archivos de la partición
+func (p *Partition) FileSystem() (*FileSystem, error) {
+	superblock, err := p.ReadSuperblock()
+	if err != nil {
+		return nil, err
+	}
+	return &FileSystem{Partition: p, SB: superblock, file: p.Disk.file}, nil
+}
+
+// FileSystem - Sistema de archivos EXT2/EXT3 de una partición
+type FileSystem struct {
+	Partition *Partition
+	SB        *structs.SuperBloque
+	file      *diskFile
+	quota     *quotaTracker // Cuotas activas del comando (ver enforceQuota)
+}
+
+// openFileSystem - Abrir el sistema de archivos de una partición montada.
+// Dentro de una transacción trabaja sobre su copia (ver openMountedDisk).
+func openFileSystem(mounted *MountedPartition, flag int) (*FileSystem, error) {
+	file, err := openMountedDisk(mounted, flag)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir el disco: %v", err)
+	}
+
+	fs, err := mountedFileSystem(file, mounted)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return fs, nil
+}
+
+// mountedFileSystem - Ubicar una partición montada en un disco ya abierto.
+// Usa el inicio guardado al montar y, si no sirve, busca la partición por nombre.
+func mountedFileSystem(file *diskFile, mounted *MountedPartition) (*FileSystem, error) {
+	disk, err := newDisk(file, mounted.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mounted.Start > 0 {
+		partition := &Partition{Disk: disk, Name: mounted.Name, Type: 'P', Start: mounted.Start, Size: mounted.Size, Index: -1}
+		if fs, err := partition.FileSystem(); err == nil {
+			return fs, nil
+		}
+	}
+
+	partition, err := disk.FindPartition(mounted.Name)
+	if err != nil {
+		return nil, err
+	}
+	return partition.FileSystem()
+}
+
+// Close - Cerrar el disco del sistema de archivos
+func (fs *FileSystem) Close() error {
+	return fs.file.Close()
+}
+
+// Flush - Escribir el superbloque (contadores libres) al inicio de la partición
+func (fs *FileSystem) Flush() error {
+	if err := fs.Partition.Disk.WriteStruct(fs.Partition.Start, fs.SB); err != nil {
+		return fmt.Errorf("error al actualizar el superbloque: %v", err)
+	}
+	return nil
+}
+
+// inodePosition - Posición absoluta de un inodo
+func (fs *FileSystem) inodePosition(inodeIndex int64) int64 {
+	return fs.SB.S_inode_start + inodeIndex*fs.SB.S_inode_s
+}
+
+// blockPosition - Posición absoluta de un bloque
+func (fs *FileSystem) blockPosition(blockIndex int64) int64 {
+	return fs.SB.S_block_start + blockIndex*fs.SB.S_block_s
+}
+
+// inodeStructSize - Bytes de structs.Inodos. Las particiones formateadas antes
+// de I_links guardan inodos más cortos (S_inode_s menor).
+var inodeStructSize = int64(binary.Size(structs.Inodos{}))
+
+// decodeInode - Interpretar los bytes de un inodo; si son menos que la
+// estructura actual, los campos que faltan quedan en cero
+func decodeInode(raw []byte) (structs.Inodos, error) {
+	var inode structs.Inodos
+	if int64(len(raw)) < inodeStructSize {
+		padded := make([]byte, inodeStructSize)
+		copy(padded, raw)
+		raw = padded
+	}
+	err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &inode)
+	return inode, err
+}
+
+// inodeSize - Bytes que ocupa cada inodo en la partición
+func (fs *FileSystem) inodeSize() int64 {
+	if fs.SB.S_inode_s < inodeStructSize {
+		return fs.SB.S_inode_s
+	}
+	return inodeStructSize
+}
+
+// ReadInode - Leer un inodo
+func (fs *FileSystem) ReadInode(inodeIndex int64) (*structs.Inodos, error) {
+	if inodeIndex < 0 || inodeIndex >= fs.SB.S_inodes_count {
+		return nil, fmt.Errorf("inodo %d fuera de rango", inodeIndex)
+	}
+	raw := make([]byte, fs.inodeSize())
+	if _, err := fs.file.ReadAt(raw, fs.inodePosition(inodeIndex)); err != nil {
+		return nil, fmt.Errorf("error al leer el inodo %d: %v", inodeIndex, err)
+	}
+	inode, err := decodeInode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el inodo %d: %v", inodeIndex, err)
+	}
+	return &inode, nil
+}
+
+// WriteInode - Escribir un inodo (sin I_links en particiones antiguas)
+func (fs *FileSystem) WriteInode(inodeIndex int64, inode *structs.Inodos) error {
+	if inodeIndex < 0 || inodeIndex >= fs.SB.S_inodes_count {
+		return fmt.Errorf("inodo %d fuera de rango", inodeIndex)
+	}
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.LittleEndian, inode); err != nil {
+		return fmt.Errorf("error al escribir el inodo %d: %v", inodeIndex, err)
+	}
+	if _, err := fs.file.WriteAt(buffer.Bytes()[:fs.inodeSize()], fs.inodePosition(inodeIndex)); err != nil {
+		return fmt.Errorf("error al escribir el inodo %d: %v", inodeIndex, err)
+	}
+	return nil
+}
+
+// ReadBlock - Leer un bloque como la estructura indicada (carpeta, archivo,
+// apuntadores o nombre largo) según la geometría de la partición
+func (fs *FileSystem) ReadBlock(blockIndex int64, block interface{}) error {
+	if blockIndex < 0 || blockIndex >= fs.SB.S_blocks_count {
+		return fmt.Errorf("bloque %d fuera de rango", blockIndex)
+	}
+	raw := make([]byte, fs.SB.S_block_s)
+	if _, err := fs.file.ReadAt(raw, fs.blockPosition(blockIndex)); err != nil {
+		return fmt.Errorf("error al leer el bloque %d: %v", blockIndex, err)
+	}
+	if err := fs.geometry().decode(raw, block); err != nil {
+		return fmt.Errorf("error al leer el bloque %d: %v", blockIndex, err)
+	}
+	return nil
+}
+
+// WriteBlock - Escribir un bloque
+func (fs *FileSystem) WriteBlock(blockIndex int64, block interface{}) error {
+	if blockIndex < 0 || blockIndex >= fs.SB.S_blocks_count {
+		return fmt.Errorf("bloque %d fuera de rango", blockIndex)
+	}
+	raw, err := fs.geometry().encode(block)
+	if err != nil {
+		return fmt.Errorf("error al escribir el bloque %d: %v", blockIndex, err)
+	}
+	if _, data := block.(*structs.BloqueArchivo); data {
+		fs.file.markFileData(blockIndex)
+	}
+	if _, err := fs.file.WriteAt(raw, fs.blockPosition(blockIndex)); err != nil {
+		return fmt.Errorf("error al escribir el bloque %d: %v", blockIndex, err)
+	}
+	return nil
+}
+
+// ReadFolderBlock - Leer un bloque de carpeta
+func (fs *FileSystem) ReadFolderBlock(blockIndex int64) (*structs.BloqueCarpeta, error) {
+	var folderBlock structs.BloqueCarpeta
+	if err := fs.ReadBlock(blockIndex, &folderBlock); err != nil {
+		return nil, err
+	}
+	return &folderBlock, nil
+}
+
+// newFolderBlock - Bloque de carpeta con todas las entradas libres
+func (fs *FileSystem) newFolderBlock() structs.BloqueCarpeta {
+	return fs.geometry().newFolderBlock()
+}
+
+// entryName - Nombre de una entrada de carpeta
+func entryName(entry *structs.BContent) string {
+	return strings.TrimSpace(strings.TrimRight(string(entry.BName[:]), "\x00"))
+}
+
+// setEntryName - Guardar el nombre de una entrada (truncado a 12 bytes)
+func setEntryName(entry *structs.BContent, name string) {
+	entry.BName = [12]byte{}
+	copy(entry.BName[:], name)
+}
+
+// DirEntry - Entrada de un directorio
+type DirEntry struct {
+	Name  string
+	Inode int64
+}
+
+// ReadDir - Listar las entradas de un directorio, sin "." ni ".."
+func (fs *FileSystem) ReadDir(dirInodeIndex int64) ([]DirEntry, error) {
+	dirInode, err := fs.ReadInode(dirInodeIndex)
+	if err != nil {
+		return nil, err
+	}
+	if dirInode.I_type != '0' {
+		return nil, fmt.Errorf("no es un directorio")
+	}
+
+	dirBlocks, err := fs.DataBlocks(dirInode)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []DirEntry
+	for _, blockIndex := range dirBlocks {
+		folderBlock, err := fs.ReadFolderBlock(blockIndex)
+		if err != nil {
+			return nil, err
+		}
+		for j := range folderBlock.BContent {
+			entry := &folderBlock.BContent[j]
+			name := fs.EntryName(entry)
+			if entry.BInodo == -1 || name == "" || name == "." || name == ".." {
+				continue
+			}
+			entries = append(entries, DirEntry{Name: name, Inode: entry.BInodo})
+		}
+	}
+	return entries, nil
+}
+
+// Lookup - Resolver una ruta absoluta desde la raíz y devolver su inodo.
+// Sigue los enlaces simbólicos, también el del último componente.
+func (fs *FileSystem) Lookup(path string) (int64, *structs.Inodos, error) {
+	parsed := parsePath(path)
+	if parsed == nil {
+		return -1, nil, fmt.Errorf("ruta inválida '%s'", path)
+	}
+
+	components := parsed.Directories
+	if parsed.FileName != "" {
+		components = append(components, parsed.FileName)
+	}
+
+	current, err := fs.lookupFrom(0, components, true, 0)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	inode, err := fs.ReadInode(current)
+	if err != nil {
+		return -1, nil, err
+	}
+	return current, inode, nil
+}
+
+// LookupParent - Resolver la carpeta que contiene a la ruta y devolver el
+// nombre final (sin seguirlo si es un enlace simbólico)
+func (fs *FileSystem) LookupParent(path string) (int64, string, error) {
+	parsed := parsePath(path)
+	if parsed == nil || parsed.FileName == "" {
+		return -1, "", fmt.Errorf("ruta inválida '%s'", path)
+	}
+
+	current, err := fs.lookupFrom(0, parsed.Directories, true, 0)
+	if err != nil {
+		return -1, "", err
+	}
+	return current, parsed.FileName, nil
+}
+
+// ReadUsers - Leer el contenido de users.txt (inodo 1)
+func (fs *FileSystem) ReadUsers() (string, error) {
+	usersInode, err := fs.ReadInode(1)
+	if err != nil {
+		return "", fmt.Errorf("error al leer el inodo de users.txt: %v", err)
+	}
+	content, err := fs.ReadFile(usersInode)
+	if err != nil {
+		return "", fmt.Errorf("error al leer el contenido de users.txt: %v", err)
+	}
+	return string(content), nil
+}
+
+// newInode - Inodo vacío del tipo indicado, con dueño según la sesión
+func (fs *FileSystem) newInode(inodeType byte, session *Session) structs.Inodos {
+	var inode structs.Inodos
+	inode.I_uid = 1 // Por defecto, root
+	inode.I_gid = 1 // Por defecto, root group
+	inode.I_type = inodeType
+	inode.I_perm = [3]byte{6, 6, 4} // 664 por defecto
+	inode.I_links = 1
+
+	currentTime := time.Now().Unix()
+	inode.I_atime = currentTime // Tiempo de acceso
+	inode.I_ctime = currentTime // Tiempo de cambio del inodo
+	inode.I_mtime = currentTime // Tiempo de modificación
+
+	// Configurar usuario y grupo según la sesión
+	if session != nil && !session.IsRoot {
+		if userInfo, err := fs.UserInfo(session.User); err == nil {
+			inode.I_uid = userInfo.UID
+			inode.I_gid = userInfo.GID
+		}
+	}
+
+	for i := range inode.I_block {
+		inode.I_block[i] = -1
+	}
+	return inode
+}
+
+// CreateDirectory - Crear una carpeta con sus entradas "." y ".." dentro de otra
+func (fs *FileSystem) CreateDirectory(parentInodeIndex int64, dirName string, session *Session) (int64, error) {
+	// Validar longitud del nombre
+	if err := fs.checkName(dirName); err != nil {
+		return -1, err
+	}
+
+	newInodeIndex, err := fs.AllocInode()
+	if err != nil {
+		return -1, fmt.Errorf("no se pudo asignar un inodo: %w", err)
+	}
+	newBlockIndex, err := fs.AllocBlock()
+	if err != nil {
+		return -1, fmt.Errorf("no se pudo asignar un bloque: %w", err)
+	}
+
+	newDirInode := fs.newInode('0', session)
+	newDirInode.I_s = fs.SB.S_block_s
+	newDirInode.I_block[0] = newBlockIndex
+	if err := fs.WriteInode(newInodeIndex, &newDirInode); err != nil {
+		return -1, fmt.Errorf("error al escribir inodo del directorio: %v", err)
+	}
+
+	// Bloque del directorio con las entradas "." y ".."
+	dirBlock := fs.newFolderBlock()
+	setEntryName(&dirBlock.BContent[0], ".")
+	dirBlock.BContent[0].BInodo = newInodeIndex
+	setEntryName(&dirBlock.BContent[1], "..")
+	dirBlock.BContent[1].BInodo = parentInodeIndex
+	if err := fs.WriteBlock(newBlockIndex, &dirBlock); err != nil {
+		return -1, fmt.Errorf("error al escribir bloque del directorio: %v", err)
+	}
+
+	if err := fs.AddEntry(parentInodeIndex, dirName, newInodeIndex); err != nil {
+		return -1, fmt.Errorf("error al agregar entrada al directorio padre: %w", err)
+	}
+
+	return newInodeIndex, nil
+}
+
+// CreateFile - Crear un archivo con su contenido dentro de una carpeta
+func (fs *FileSystem) CreateFile(parentInodeIndex int64, fileName string, content []byte, session *Session) (int64, error) {
+	// Validar longitud del nombre
+	if err := fs.checkName(fileName); err != nil {
+		return -1, err
+	}
+
+	// El tamaño se valida antes de asignar nada
+	if maxFileSize := fs.maxFileSize(); int64(len(content)) > maxFileSize {
+		return -1, newCommandError(ErrCodeNoSpace, "el contenido es demasiado grande (máximo %d bytes)", maxFileSize)
+	}
+
+	newInodeIndex, err := fs.AllocInode()
+	if err != nil {
+		return -1, fmt.Errorf("no se pudo asignar un inodo: %w", err)
+	}
+
+	// Si falla la escritura (p. ej. sin bloques libres) se devuelve lo asignado:
+	// en EXT2 no hay transacción que lo deshaga
+	newFileInode := fs.newInode('1', session)
+	err = fs.WriteFile(newInodeIndex, &newFileInode, content)
+	if err != nil {
+		err = fmt.Errorf("error al escribir contenido del archivo: %w", err)
+	} else if err = fs.AddEntry(parentInodeIndex, fileName, newInodeIndex); err != nil {
+		err = fmt.Errorf("error al agregar entrada al directorio padre: %w", err)
+	}
+	if err != nil {
+		fs.FreeInodeBlocks(&newFileInode)
+		fs.FreeInode(newInodeIndex)
+		return -1, err
+	}
+
+	return newInodeIndex, nil
+}
+
+// RemoveEntry - Quitar de una carpeta la entrada que apunta al inodo indicado
+// y actualizar su tiempo de modificación
+func (fs *FileSystem) RemoveEntry(dirInodeIndex int64, itemInodeIndex int64) error {
+	removed, err := fs.removeEntryWhere(dirInodeIndex, func(entry *structs.BContent) bool {
+		return entry.BInodo == itemInodeIndex
+	})
+	if err != nil {
+		return err
+	}
+	if removed == -1 {
+		return fmt.Errorf("no se encontró la entrada del inodo %d en el directorio", itemInodeIndex)
+	}
+	return nil
+}
+
+// RenameEntry - Cambiar el nombre de la entrada de una carpeta que apunta al
+// inodo indicado con el nombre actual oldName
+func (fs *FileSystem) RenameEntry(dirInodeIndex int64, itemInodeIndex int64, oldName string, newName string) error {
+	if err := fs.checkName(newName); err != nil {
+		return err
+	}
+
+	dirInode, err := fs.ReadInode(dirInodeIndex)
+	if err != nil {
+		return err
+	}
+
+	dirBlocks, err := fs.DataBlocks(dirInode)
+	if err != nil {
+		return err
+	}
+
+	for _, blockIndex := range dirBlocks {
+		folderBlock, err := fs.ReadFolderBlock(blockIndex)
+		if err != nil {
+			return err
+		}
+
+		for j := range folderBlock.BContent {
+			entry := &folderBlock.BContent[j]
+			if entry.BInodo != itemInodeIndex || !fs.nameMatches(entry, oldName) {
+				continue
+			}
+
+			if err := fs.setName(entry, newName); err != nil {
+				return err
+			}
+			if err := fs.WriteBlock(blockIndex, folderBlock); err != nil {
+				return err
+			}
+
+			// Actualizar los tiempos de modificación y cambio de la carpeta
+			touchModify(dirInode)
+			return fs.WriteInode(dirInodeIndex, dirInode)
+		}
+	}
+
+	return fmt.Errorf("no se encontró la entrada '%s' en el directorio", oldName)
+}
+
+// ReleaseInode - Liberar los bloques de un inodo y marcarlo como libre
+func (fs *FileSystem) ReleaseInode(inodeIndex int64) error {
+	inode, err := fs.ReadInode(inodeIndex)
+	if err != nil {
+		return err
+	}
+
+	// Los nombres largos de las entradas de una carpeta tienen sus propios bloques
+	if inode.I_type == '0' {
+		if err := fs.releaseEntryNames(inode); err != nil {
+			return err
+		}
+	}
+
+	// Liberar todos los bloques (directos e indirectos)
+	if err := fs.FreeInodeBlocks(inode); err != nil {
+		return err
+	}
+	if err := fs.WriteInode(inodeIndex, inode); err != nil {
+		return err
+	}
+
+	if err := fs.FreeInode(inodeIndex); err != nil {
+		return fmt.Errorf("error al liberar inodo: %v", err)
+	}
+	return nil
+}
+
+// CanWrite - Verificar si la sesión puede escribir en una carpeta (bit 2 de I_perm)
+func (fs *FileSystem) CanWrite(dirInodeIndex int64, session *Session) (bool, error) {
+	dirInode, err := fs.ReadInode(dirInodeIndex)
+	if err != nil {
+		return false, fmt.Errorf("error al leer inodo del directorio: %v", err)
+	}
+
+	// Si es root, siempre tiene permisos
+	if session.IsRoot {
+		return true, nil
+	}
+
+	// Obtener información del usuario actual
+	userInfo, err := fs.UserInfo(session.User)
+	if err != nil {
+		return false, fmt.Errorf("error al obtener información del usuario: %v", err)
+	}
+
+	// Determinar categoría del usuario (User, Group, Other)
+	permissionIndex := 2
+	if userInfo.UID == dirInode.I_uid {
+		permissionIndex = 0
+	} else if userInfo.GID == dirInode.I_gid {
+		permissionIndex = 1
+	}
+
+	return dirInode.I_perm[permissionIndex]&2 != 0, nil
+}
+
+// ensureParentDirectories - Recorrer las carpetas padre de una ruta y devolver
+// la última. Si create es true, crea las que falten; si no, indica el
+// parámetro (flag) que permite crearlas; las creadas se informan en res.
+func ensureParentDirectories(res *CommandResult, fs *FileSystem, parsedPath *ParsedPath, create bool, flag string, session *Session) (int64, error) {
+	currentInodeIndex := int64(0) // Empezar desde el directorio raíz
+
+	for _, dirName := range parsedPath.Directories {
+		nextInode, err := fs.LookupIn(currentInodeIndex, dirName)
+		if err == nil {
+			// Una carpeta de la ruta puede ser un enlace simbólico
+			currentInodeIndex, err = fs.followLink(currentInodeIndex, nextInode, 0)
+			if err != nil {
+				return -1, err
+			}
+			continue
+		}
+
+		// El directorio no existe
+		if !create {
+			return -1, newCommandError(ErrCodeNotFound, "el directorio padre '%s' no existe. Use %s para crear directorios padre", dirName, flag)
+		}
+
+		newDirInode, err := fs.CreateDirectory(currentInodeIndex, dirName, session)
+		if err != nil {
+			return -1, fmt.Errorf("error al crear directorio padre '%s': %w", dirName, err)
+		}
+		res.Printf("📁 Directorio padre '%s' creado\n", dirName)
+		currentInodeIndex = newDirInode
+	}
+
+	return currentInodeIndex, nil
+}
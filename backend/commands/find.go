@@ -1,32 +1,27 @@
 package commands
 
 import (
-	"backend/structs"
-	"encoding/binary"
-	"fmt"
 	"os"
 	"regexp"
 	"strings"
 )
 
 // ExecuteFind - Buscar archivos o carpetas por patrón de nombre
-func ExecuteFind(path string, name string) {
+func ExecuteFind(session *Session, path string, name string) (*CommandResult, error) {
+	res := NewCommandResult("find")
+
 	// Validar parámetros obligatorios
 	if path == "" {
-		fmt.Println("Error: el parámetro -path es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio")
 	}
 
 	if name == "" {
-		fmt.Println("Error: el parámetro -name es obligatorio.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -name es obligatorio")
 	}
 
 	// Validar que hay una sesión activa
-	session := GetCurrentSession()
 	if session == nil {
-		fmt.Println("Error: no hay ninguna sesión activa. Use el comando 'login' primero.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no hay ninguna sesión activa. Use el comando 'login' primero")
 	}
 
 	// Normalizar la ruta
@@ -44,159 +39,112 @@ func ExecuteFind(path string, name string) {
 	// Abrir el disco montado
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: la partición '%s' no está montada.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "la partición '%s' no está montada", session.PartitionID)
 	}
+	defer rlockDisk(mounted.Path)()
 
-	file, err := os.OpenFile(mounted.Path, os.O_RDONLY, 0644)
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
 	if err != nil {
-		fmt.Printf("Error al abrir el disco: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	// Obtener superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
-	if err != nil {
-		fmt.Printf("Error al obtener superbloque: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el sistema de archivos")
 	}
+	defer fs.Close()
 
 	// Determinar el inodo de inicio
-	var startInodeNum int64
-
-	if path == "/" {
-		startInodeNum = 0
-	} else {
-		parsedPath := parsePath(path)
-		if parsedPath == nil {
-			fmt.Printf("Error: ruta inválida '%s'.\n", path)
-			return
-		}
-
-		startInodeNum, err = findItemByPath(file, superblock, parsedPath)
-		if err != nil {
-			fmt.Printf("Error: no se encontró la ruta '%s': %v\n", path, err)
-			return
-		}
-	}
-
-	// Leer el inodo de inicio
-	var startInode structs.Inodos
-	startInodePos := superblock.S_inode_start + (startInodeNum * superblock.S_inode_s)
-	file.Seek(startInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &startInode); err != nil {
-		fmt.Printf("Error al leer el inodo de inicio: %v\n", err)
-		return
+	startInodeNum, startInode, err := fs.Lookup(path)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeNotFound, err, "no se encontró la ruta '%s'", path)
 	}
 
 	// Validar que la ruta de inicio sea un directorio
 	if startInode.I_type != '0' {
-		fmt.Printf("Error: la ruta '%s' no es un directorio.\n", path)
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "la ruta '%s' no es un directorio", path)
 	}
 
 	// Convertir el patrón a expresión regular
 	pattern := convertPatternToRegex(name)
 	regex, err := regexp.Compile(pattern)
 	if err != nil {
-		fmt.Printf("Error al compilar el patrón de búsqueda: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al compilar el patrón de búsqueda")
 	}
 
 	// Realizar la búsqueda
-	fmt.Printf("🔍 Buscando archivos/carpetas que coincidan con '%s' en '%s':\n\n", name, path)
+	res.Printf("🔍 Buscando archivos/carpetas que coincidan con '%s' en '%s':\n\n", name, path)
 
 	results := make([]FindResult, 0)
-	searchRecursive(file, superblock, startInodeNum, path, regex, session.User, session.Group, &results)
+	searchRecursive(fs, startInodeNum, path, regex, session.User, session.Group, &results)
 
 	// Mostrar resultados
 	if len(results) == 0 {
-		fmt.Println("No se encontraron archivos o carpetas que coincidan con el patrón.")
-		return
+		res.Println("No se encontraron archivos o carpetas que coincidan con el patrón.")
+		return res, nil
 	}
 
 	// Imprimir árbol de resultados
-	printFindTree(results, path)
-	fmt.Printf("\n✅ Se encontraron %d resultados.\n", len(results))
+	printFindTree(res, results, path)
+	res.Printf("\n✅ Se encontraron %d resultados.\n", len(results))
+
+	for _, result := range results {
+		res.AddPath(result.Path)
+	}
+	res.Set("matches", results)
+
+	return res, nil
 }
 
 // FindResult - Estructura para almacenar resultados de búsqueda
 type FindResult struct {
-	Path       string
-	Name       string
-	IsDir      bool
-	Level      int
-	ParentPath string
+	Path       string `json:"path"`
+	Name       string `json:"name"`
+	IsDir      bool   `json:"isDir"`
+	Level      int    `json:"level"`
+	ParentPath string `json:"parentPath"`
 }
 
 // searchRecursive - Buscar recursivamente en directorios
-func searchRecursive(file *os.File, superblock *structs.SuperBloque, dirInodeNum int64, currentPath string, pattern *regexp.Regexp, username string, groupname string, results *[]FindResult) {
-	var dirInode structs.Inodos
-	dirInodePos := superblock.S_inode_start + (dirInodeNum * superblock.S_inode_s)
-	file.Seek(dirInodePos, 0)
-	if err := binary.Read(file, binary.LittleEndian, &dirInode); err != nil {
+func searchRecursive(fs *FileSystem, dirInodeNum int64, currentPath string, pattern *regexp.Regexp, username string, groupname string, results *[]FindResult) {
+	dirInode, err := fs.ReadInode(dirInodeNum)
+	if err != nil {
 		return
 	}
 
-	if !checkReadPermissionOnInode(&dirInode, username, groupname) {
+	if !checkReadPermissionOnInode(dirInode, username, groupname) {
 		return
 	}
 
-	for i := 0; i < 15 && dirInode.I_block[i] != -1; i++ {
-		blockPos := superblock.S_block_start + (dirInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPos, 0)
-
-		var folderBlock structs.BloqueCarpeta
-		if err := binary.Read(file, binary.LittleEndian, &folderBlock); err != nil {
+	entries, err := fs.ReadDir(dirInodeNum)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		entryInode, err := fs.ReadInode(entry.Inode)
+		if err != nil {
 			continue
 		}
 
-		for j := 0; j < 4; j++ {
-			entryName := strings.TrimRight(string(folderBlock.BContent[j].BName[:]), "\x00")
-			entryName = strings.TrimSpace(entryName)
-
-			if entryName == "" || entryName == "." || entryName == ".." {
-				continue
-			}
-
-			entryInodeNum := folderBlock.BContent[j].BInodo
-			if entryInodeNum == -1 {
-				continue
-			}
-
-			var entryInode structs.Inodos
-			entryInodePos := superblock.S_inode_start + (entryInodeNum * superblock.S_inode_s)
-			file.Seek(entryInodePos, 0)
-			if err := binary.Read(file, binary.LittleEndian, &entryInode); err != nil {
-				continue
-			}
-
-			if !checkReadPermissionOnInode(&entryInode, username, groupname) {
-				continue
-			}
+		if !checkReadPermissionOnInode(entryInode, username, groupname) {
+			continue
+		}
 
-			var fullPath string
-			if currentPath == "/" {
-				fullPath = "/" + entryName
-			} else {
-				fullPath = currentPath + "/" + entryName
-			}
+		var fullPath string
+		if currentPath == "/" {
+			fullPath = "/" + entry.Name
+		} else {
+			fullPath = currentPath + "/" + entry.Name
+		}
 
-			if pattern.MatchString(entryName) {
-				result := FindResult{
-					Path:       fullPath,
-					Name:       entryName,
-					IsDir:      entryInode.I_type == '0',
-					Level:      countSlashes(fullPath),
-					ParentPath: currentPath,
-				}
-				*results = append(*results, result)
+		if pattern.MatchString(entry.Name) {
+			result := FindResult{
+				Path:       fullPath,
+				Name:       entry.Name,
+				IsDir:      entryInode.I_type == '0',
+				Level:      countSlashes(fullPath),
+				ParentPath: currentPath,
 			}
+			*results = append(*results, result)
+		}
 
-			if entryInode.I_type == '0' {
-				searchRecursive(file, superblock, entryInodeNum, fullPath, pattern, username, groupname, results)
-			}
+		if entryInode.I_type == '0' {
+			searchRecursive(fs, entry.Inode, fullPath, pattern, username, groupname, results)
 		}
 	}
 }
@@ -219,12 +167,12 @@ func countSlashes(path string) int {
 }
 
 // printFindTree - Imprimir los resultados en forma de árbol
-func printFindTree(results []FindResult, basePath string) {
+func printFindTree(res *CommandResult, results []FindResult, basePath string) {
 	if len(results) == 0 {
 		return
 	}
 
-	fmt.Println(basePath)
+	res.Println(basePath)
 
 	// Organizar resultados por ruta completa
 	allPaths := make(map[string]FindResult)
@@ -244,7 +192,7 @@ func printFindTree(results []FindResult, basePath string) {
 	}
 
 	// Imprimir recursivamente
-	printTreeLevelFinal(basePath, resultsByParent, 0)
+	printTreeLevelFinal(res, basePath, resultsByParent, 0)
 }
 
 // addIntermediateDirs - Agregar directorios intermedios
@@ -296,7 +244,7 @@ func addIntermediateDirs(path string, _ string, allPaths map[string]FindResult)
 }
 
 // printTreeLevelFinal - Imprimir un nivel del árbol
-func printTreeLevelFinal(currentPath string, resultsByParent map[string][]FindResult, level int) {
+func printTreeLevelFinal(res *CommandResult, currentPath string, resultsByParent map[string][]FindResult, level int) {
 	items, exists := resultsByParent[currentPath]
 
 	if !exists || len(items) == 0 {
@@ -310,10 +258,10 @@ func printTreeLevelFinal(currentPath string, resultsByParent map[string][]FindRe
 			itemType = " (carpeta)"
 		}
 
-		fmt.Printf("%s|_ %s%s\n", indent, item.Name, itemType)
+		res.Printf("%s|_ %s%s\n", indent, item.Name, itemType)
 
 		if item.IsDir {
-			printTreeLevelFinal(item.Path, resultsByParent, level+1)
+			printTreeLevelFinal(res, item.Path, resultsByParent, level+1)
 		}
 	}
 }
@@ -0,0 +1,733 @@
+package commands
+
+import (
+	"backend/structs"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Tipos de problemas que detecta fsck
+const (
+	fsckFreeInodesCount = "free_inodes_count" // S_free_inodes_count no coincide con el bitmap
+	fsckFreeBlocksCount = "free_blocks_count" // S_free_blocks_count no coincide con el bitmap
+	fsckInodeNotMarked  = "inode_not_marked"  // Inodo alcanzable marcado como libre
+	fsckBlockNotMarked  = "block_not_marked"  // Bloque alcanzable marcado como libre
+	fsckOrphanInode     = "orphan_inode"      // Inodo marcado como usado pero inalcanzable
+	fsckLeakedBlock     = "leaked_block"      // Bloque marcado como usado pero inalcanzable
+	fsckDuplicateBlock  = "duplicate_block"   // Bloque referenciado más de una vez
+	fsckBadPointer      = "bad_pointer"       // Apuntador fuera de rango
+	fsckDanglingEntry   = "dangling_entry"    // Entrada de carpeta hacia un inodo inválido
+	fsckBadDot          = "bad_dot"           // Entrada '.' ausente o incorrecta
+	fsckBadDotDot       = "bad_dotdot"        // Entrada '..' ausente o incorrecta
+	fsckBadName         = "bad_name"          // Nombre largo con bloques inválidos o compartidos
+	fsckLinkCount       = "link_count"        // I_links no coincide con las entradas que apuntan al inodo
+)
+
+// FsckProblem - Inconsistencia encontrada por fsck
+type FsckProblem struct {
+	Kind     string `json:"kind"`
+	Inode    int64  `json:"inode"` // -1 si no aplica
+	Block    int64  `json:"block"` // -1 si no aplica
+	Path     string `json:"path,omitempty"`
+	Detail   string `json:"detail"`
+	Repaired bool   `json:"repaired"`
+}
+
+// blockRef - Ubicación de un apuntador a bloque: una ranura de I_block del inodo
+// (pointerBlock == -1) o una posición dentro de un bloque de apuntadores
+type blockRef struct {
+	inode        int64
+	slot         int
+	pointerBlock int64
+	position     int
+}
+
+// fsckChecker - Estado del recorrido de fsck
+type fsckChecker struct {
+	fs         *FileSystem
+	superblock *structs.SuperBloque
+	repair     bool
+
+	inodeBitmap []byte
+	blockBitmap []byte
+
+	reachableInodes map[int64]bool
+	entryCount      map[int64]int64 // inodo -> entradas de carpeta que lo apuntan
+	blockOwners     map[int64]int64 // bloque -> primer inodo que lo usa
+	duplicates      []blockRef
+	problems        []FsckProblem
+}
+
+// ExecuteFsck - Verificar (y opcionalmente reparar) la consistencia de una partición
+func ExecuteFsck(id string, repair bool) (*CommandResult, error) {
+	res := NewCommandResult("fsck")
+
+	if id == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio para fsck")
+	}
+
+	mounted := GetMountedPartition(id)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró ninguna partición montada con ID '%s'", id)
+	}
+
+	// Una transacción pendiente del journal se resuelve antes de revisar, como
+	// al montar: si no, fsck vería las tablas a medio aplicar y la reparación
+	// escribiría encima de lo que el replay debía dejar
+	pending, err := journalPending(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al revisar el journal")
+	}
+	if pending {
+		unlock := lockDisk(mounted.Path)
+		recovery, err := recoverMountedJournal(mounted)
+		unlock()
+		if err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al rehacer el journal")
+		}
+		if message := describeJournalRecovery(recovery); message != "" {
+			res.Println(message)
+		}
+		res.Set("journalReplayed", recovery.Replayed)
+		res.Set("journalDiscarded", recovery.Discarded)
+	}
+
+	// La verificación comparte el disco con otros lectores; la reparación lo toma en exclusiva
+	flags, lock := os.O_RDONLY, rlockDisk
+	if repair {
+		flags, lock = os.O_RDWR, lockDisk
+	}
+	defer lock(mounted.Path)()
+	file, err := openDisk(mounted.Path, flags)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el disco")
+	}
+	defer file.Close()
+
+	fs, err := mountedFileSystem(file, mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al leer el superbloque")
+	}
+	superblock := fs.SB
+	if !validMagic(superblock) {
+		return res, newCommandError(ErrCodeUnsupported, "la partición '%s' no tiene un sistema de archivos EXT2/EXT3 válido", id)
+	}
+
+	checker := &fsckChecker{
+		fs:              fs,
+		superblock:      superblock,
+		repair:          repair,
+		reachableInodes: make(map[int64]bool),
+		entryCount:      make(map[int64]int64),
+		blockOwners:     make(map[int64]int64),
+	}
+
+	if err := checker.loadBitmaps(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al leer los bitmaps")
+	}
+
+	res.Printf("🔍 Verificando sistema de archivos EXT%d de la partición '%s'...\n", superblock.S_file_system_type, id)
+
+	// PASO 1: Recorrer el árbol desde el inodo raíz
+	if err := checker.walkTree(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al recorrer el árbol de directorios")
+	}
+
+	// PASO 2: Bloques referenciados más de una vez
+	if err := checker.checkDuplicates(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al reparar bloques duplicados")
+	}
+
+	// PASO 3: Contador de enlaces duros contra las entradas encontradas
+	if err := checker.checkLinkCounts(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al reparar contadores de enlaces")
+	}
+
+	// PASO 4: Bitmaps contra inodos y bloques alcanzables
+	checker.checkBitmaps()
+
+	// PASO 5: Contadores del superbloque contra los bitmaps
+	checker.checkFreeCounts()
+
+	if repair {
+		if err := checker.writeBitmaps(); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al escribir los bitmaps")
+		}
+		if err := fs.Flush(); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
+		}
+	}
+
+	repaired := 0
+	for _, problem := range checker.problems {
+		icon := "⚠️ "
+		if problem.Repaired {
+			icon = "🔧"
+			repaired++
+		}
+		res.Printf("%s [%s] %s\n", icon, problem.Kind, problem.Detail)
+	}
+
+	if len(checker.problems) == 0 {
+		res.Println("✅ El sistema de archivos es consistente.")
+	} else if repair {
+		res.Printf("🔧 Problemas encontrados: %d, reparados: %d\n", len(checker.problems), repaired)
+	} else {
+		res.Printf("❗ Problemas encontrados: %d. Use -repair para corregirlos.\n", len(checker.problems))
+	}
+	res.Printf("   📊 Inodos alcanzables: %d de %d\n", len(checker.reachableInodes), superblock.S_inodes_count)
+	res.Printf("   📊 Bloques alcanzables: %d de %d\n", len(checker.blockOwners), superblock.S_blocks_count)
+
+	res.Set("id", id)
+	res.Set("clean", len(checker.problems) == 0)
+	res.Set("repair", repair)
+	res.Set("problems", checker.problems)
+	res.Set("repaired", repaired)
+	res.Set("reachableInodes", len(checker.reachableInodes))
+	res.Set("reachableBlocks", len(checker.blockOwners))
+	res.Set("freeInodes", superblock.S_free_inodes_count)
+	res.Set("freeBlocks", superblock.S_free_blocks_count)
+
+	return res, nil
+}
+
+// addProblem - Registrar un problema
+func (c *fsckChecker) addProblem(kind string, inode, block int64, path string, repaired bool, format string, a ...interface{}) {
+	c.problems = append(c.problems, FsckProblem{
+		Kind:     kind,
+		Inode:    inode,
+		Block:    block,
+		Path:     path,
+		Detail:   fmt.Sprintf(format, a...),
+		Repaired: repaired,
+	})
+}
+
+// loadBitmaps - Leer ambos bitmaps (un byte por inodo/bloque, 0 libre y 1 usado)
+func (c *fsckChecker) loadBitmaps() error {
+	c.inodeBitmap = make([]byte, c.superblock.S_inodes_count)
+	if _, err := c.fs.file.ReadAt(c.inodeBitmap, c.superblock.S_bm_inode_start); err != nil {
+		return fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+
+	c.blockBitmap = make([]byte, c.superblock.S_blocks_count)
+	if _, err := c.fs.file.ReadAt(c.blockBitmap, c.superblock.S_bm_block_start); err != nil {
+		return fmt.Errorf("error al leer bitmap de bloques: %v", err)
+	}
+
+	return nil
+}
+
+// writeBitmaps - Escribir los bitmaps corregidos
+func (c *fsckChecker) writeBitmaps() error {
+	if _, err := c.fs.file.WriteAt(c.inodeBitmap, c.superblock.S_bm_inode_start); err != nil {
+		return fmt.Errorf("error al escribir bitmap de inodos: %v", err)
+	}
+	if _, err := c.fs.file.WriteAt(c.blockBitmap, c.superblock.S_bm_block_start); err != nil {
+		return fmt.Errorf("error al escribir bitmap de bloques: %v", err)
+	}
+	return nil
+}
+
+// isValidInodeType - Verificar que el tipo del inodo sea carpeta ('0'),
+// archivo ('1') o enlace simbólico ('2')
+func isValidInodeType(inode *structs.Inodos) bool {
+	return inode.I_type == '0' || inode.I_type == '1' || inode.I_type == '2'
+}
+
+// walkTree - Recorrer en anchura todos los directorios desde la raíz (inodo 0)
+func (c *fsckChecker) walkTree() error {
+	type pending struct {
+		inode  int64
+		parent int64
+		path   string
+	}
+
+	queue := []pending{{inode: 0, parent: 0, path: "/"}}
+	c.reachableInodes[0] = true
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		inode, err := c.fs.ReadInode(current.inode)
+		if err != nil {
+			return err
+		}
+
+		dataBlocks, err := c.collectBlocks(current.inode, inode, current.path)
+		if err != nil {
+			return err
+		}
+
+		if inode.I_type != '0' {
+			continue
+		}
+
+		// Revisar las entradas de cada bloque de carpeta
+		for blockNumber, blockIndex := range dataBlocks {
+			var folderBlock structs.BloqueCarpeta
+			if err := c.fs.ReadBlock(blockIndex, &folderBlock); err != nil {
+				return err
+			}
+			modified := false
+
+			// '.' y '..' ocupan las dos primeras entradas del primer bloque
+			if blockNumber == 0 {
+				if c.checkLink(&folderBlock.BContent[0], ".", current.inode, fsckBadDot, current.path) {
+					modified = true
+				}
+				if c.checkLink(&folderBlock.BContent[1], "..", current.parent, fsckBadDotDot, current.path) {
+					modified = true
+				}
+			}
+
+			for j := range folderBlock.BContent {
+				if blockNumber == 0 && j < 2 {
+					continue
+				}
+
+				entry := &folderBlock.BContent[j]
+				if entry.BInodo == -1 {
+					continue
+				}
+
+				name := c.fs.EntryName(entry)
+				childPath := strings.TrimSuffix(current.path, "/") + "/" + name
+
+				if name == "." || name == ".." {
+					c.addProblem(fsckDanglingEntry, current.inode, blockIndex, current.path, c.repair,
+						"entrada '%s' duplicada en '%s' (bloque %d)", name, current.path, blockIndex)
+					if c.repair {
+						clearFolderEntry(entry)
+						modified = true
+					}
+					continue
+				}
+
+				// La entrada debe apuntar a un inodo existente con un tipo válido
+				valid := entry.BInodo >= 0 && entry.BInodo < c.superblock.S_inodes_count
+				var child *structs.Inodos
+				if valid {
+					child, err = c.fs.ReadInode(entry.BInodo)
+					if err != nil {
+						return err
+					}
+					valid = isValidInodeType(child)
+				}
+				if !valid {
+					c.addProblem(fsckDanglingEntry, entry.BInodo, blockIndex, childPath, c.repair,
+						"la entrada '%s' apunta al inodo inválido %d", childPath, entry.BInodo)
+					if c.repair {
+						clearFolderEntry(entry)
+						modified = true
+					}
+					continue
+				}
+
+				// Un directorio solo puede tener una entrada: al reparar se quita la
+				// que se encontró después, como la que deja un move interrumpido
+				visited := c.reachableInodes[entry.BInodo]
+				if visited && child.I_type == '0' {
+					c.addProblem(fsckBadDotDot, entry.BInodo, blockIndex, childPath, c.repair,
+						"el directorio '%s' (inodo %d) está enlazado desde más de una carpeta", childPath, entry.BInodo)
+					if c.repair {
+						clearFolderEntry(entry)
+						modified = true
+						continue
+					}
+				}
+
+				// Bloques del nombre largo de la entrada
+				if c.checkName(entry, current.inode, blockIndex, childPath) {
+					modified = true
+				}
+
+				c.entryCount[entry.BInodo]++
+
+				// Un inodo ya visitado no se recorre otra vez (evita ciclos)
+				if visited {
+					continue
+				}
+
+				c.reachableInodes[entry.BInodo] = true
+				queue = append(queue, pending{inode: entry.BInodo, parent: current.inode, path: childPath})
+			}
+
+			if modified {
+				if err := c.fs.WriteBlock(blockIndex, &folderBlock); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkLink - Verificar una entrada '.' o '..'. Devuelve true si la corrigió.
+func (c *fsckChecker) checkLink(entry *structs.BContent, name string, expected int64, kind string, path string) bool {
+	current := strings.TrimRight(string(entry.BName[:]), "\x00")
+	if current == name && entry.BInodo == expected {
+		return false
+	}
+
+	c.addProblem(kind, expected, -1, path, c.repair,
+		"'%s' en '%s' apunta al inodo %d (nombre '%s'), se esperaba %d", name, path, entry.BInodo, current, expected)
+	if !c.repair {
+		return false
+	}
+
+	entry.BName = [12]byte{}
+	copy(entry.BName[:], name)
+	entry.BLong = 0
+	entry.BInodo = expected
+	return true
+}
+
+// checkName - Registrar como usados los bloques del nombre largo de una
+// entrada. Una cadena dañada o que comparte bloques se reporta y, al reparar,
+// la entrada se queda con los 12 bytes de BName. Devuelve true si la modificó.
+func (c *fsckChecker) checkName(entry *structs.BContent, dirInode, folderBlock int64, path string) bool {
+	if entry.BLong == 0 || !hasLongNames(c.superblock) {
+		return false
+	}
+
+	blocks, _, err := c.fs.nameChain(entry)
+	if err == nil {
+		for _, blockIndex := range blocks {
+			if owner, used := c.blockOwners[blockIndex]; used {
+				err = fmt.Errorf("el bloque %d también lo usa el inodo %d", blockIndex, owner)
+				break
+			}
+		}
+	}
+	if err == nil {
+		for _, blockIndex := range blocks {
+			c.blockOwners[blockIndex] = dirInode
+		}
+		return false
+	}
+
+	c.addProblem(fsckBadName, dirInode, folderBlock, path, c.repair,
+		"el nombre largo de '%s' está dañado: %v", path, err)
+	if !c.repair {
+		return false
+	}
+	entry.BLong = 0
+	return true
+}
+
+// clearFolderEntry - Vaciar una entrada de carpeta
+func clearFolderEntry(entry *structs.BContent) {
+	entry.BName = [12]byte{}
+	entry.BLong = 0
+	entry.BInodo = -1
+}
+
+// collectBlocks - Registrar los bloques de un inodo (datos y apuntadores) y
+// devolver sus bloques de datos en orden lógico. Los apuntadores fuera de rango
+// se reportan y, al reparar, se descartan.
+func (c *fsckChecker) collectBlocks(inodeIndex int64, inode *structs.Inodos, path string) ([]int64, error) {
+	var dataBlocks []int64
+	inodeModified := false
+
+	for slot := 0; slot < len(inode.I_block); slot++ {
+		blockIndex := inode.I_block[slot]
+		if blockIndex == -1 {
+			continue
+		}
+
+		if blockIndex < 0 || blockIndex >= c.superblock.S_blocks_count {
+			c.addProblem(fsckBadPointer, inodeIndex, blockIndex, path, c.repair,
+				"el inodo %d ('%s') apunta al bloque inexistente %d (I_block[%d])", inodeIndex, path, blockIndex, slot)
+			if c.repair {
+				inode.I_block[slot] = -1
+				inodeModified = true
+			}
+			continue
+		}
+
+		ref := blockRef{inode: inodeIndex, slot: slot, pointerBlock: -1}
+		if !c.claimBlock(blockIndex, inodeIndex, ref) {
+			continue
+		}
+
+		if slot < directBlocksCount {
+			dataBlocks = append(dataBlocks, blockIndex)
+			continue
+		}
+
+		level := slot - directBlocksCount + 1
+		if err := c.collectIndirect(inodeIndex, blockIndex, level, path, &dataBlocks); err != nil {
+			return nil, err
+		}
+	}
+
+	if inodeModified {
+		if err := c.fs.WriteInode(inodeIndex, inode); err != nil {
+			return nil, err
+		}
+	}
+
+	return dataBlocks, nil
+}
+
+// collectIndirect - Recorrer un bloque de apuntadores del nivel indicado
+func (c *fsckChecker) collectIndirect(inodeIndex, pointerIndex int64, level int, path string, dataBlocks *[]int64) error {
+	pointerBlock, err := c.fs.ReadPointerBlock(pointerIndex)
+	if err != nil {
+		return err
+	}
+	modified := false
+
+	for position, blockIndex := range pointerBlock.BPointers {
+		if blockIndex == -1 {
+			continue
+		}
+
+		if blockIndex < 0 || blockIndex >= c.superblock.S_blocks_count {
+			c.addProblem(fsckBadPointer, inodeIndex, blockIndex, path, c.repair,
+				"el bloque de apuntadores %d del inodo %d apunta al bloque inexistente %d", pointerIndex, inodeIndex, blockIndex)
+			if c.repair {
+				pointerBlock.BPointers[position] = -1
+				modified = true
+			}
+			continue
+		}
+
+		ref := blockRef{inode: inodeIndex, pointerBlock: pointerIndex, position: position}
+		if !c.claimBlock(blockIndex, inodeIndex, ref) {
+			continue
+		}
+
+		if level == 1 {
+			*dataBlocks = append(*dataBlocks, blockIndex)
+			continue
+		}
+
+		if err := c.collectIndirect(inodeIndex, blockIndex, level-1, path, dataBlocks); err != nil {
+			return err
+		}
+	}
+
+	if modified {
+		return c.fs.WritePointerBlock(pointerIndex, &pointerBlock)
+	}
+	return nil
+}
+
+// claimBlock - Marcar un bloque como usado por el inodo. Si otro inodo ya lo
+// usa se registra como duplicado y se devuelve false para no recorrerlo de nuevo.
+func (c *fsckChecker) claimBlock(blockIndex, inodeIndex int64, ref blockRef) bool {
+	if owner, used := c.blockOwners[blockIndex]; used {
+		c.duplicates = append(c.duplicates, ref)
+		c.addProblem(fsckDuplicateBlock, inodeIndex, blockIndex, "", false,
+			"el bloque %d lo usan los inodos %d y %d", blockIndex, owner, inodeIndex)
+		return false
+	}
+	c.blockOwners[blockIndex] = inodeIndex
+	return true
+}
+
+// checkLinkCounts - Comparar I_links de cada archivo o enlace con las entradas
+// que lo apuntan. Al reparar se ajusta I_links para que rm no libere bloques
+// que otra entrada sigue usando.
+func (c *fsckChecker) checkLinkCounts() error {
+	if !c.fs.supportsHardLinks() {
+		return nil
+	}
+
+	inodes := make([]int64, 0, len(c.entryCount))
+	for inodeIndex := range c.entryCount {
+		inodes = append(inodes, inodeIndex)
+	}
+	sort.Slice(inodes, func(i, j int) bool { return inodes[i] < inodes[j] })
+
+	for _, inodeIndex := range inodes {
+		inode, err := c.fs.ReadInode(inodeIndex)
+		if err != nil {
+			return err
+		}
+		entries := c.entryCount[inodeIndex]
+		if inode.I_type == '0' || linkCount(inode) == entries {
+			continue
+		}
+
+		c.addProblem(fsckLinkCount, inodeIndex, -1, "", c.repair,
+			"el inodo %d tiene I_links = %d pero lo apuntan %d entradas", inodeIndex, linkCount(inode), entries)
+		if c.repair {
+			inode.I_links = entries
+			if err := c.fs.WriteInode(inodeIndex, inode); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkDuplicates - Al reparar, dar a cada referencia duplicada una copia propia
+// del bloque en un bloque que no use nadie más
+func (c *fsckChecker) checkDuplicates() error {
+	if !c.repair || len(c.duplicates) == 0 {
+		return nil
+	}
+
+	nextFree := int64(0)
+	problemIndex := 0
+	for _, ref := range c.duplicates {
+		// Buscar el problema correspondiente para marcarlo como reparado
+		for problemIndex < len(c.problems) && c.problems[problemIndex].Kind != fsckDuplicateBlock {
+			problemIndex++
+		}
+
+		// Bloque destino: ni alcanzable ni marcado en el bitmap
+		for nextFree < c.superblock.S_blocks_count && (c.blockBitmap[nextFree] != 0 || c.hasOwner(nextFree)) {
+			nextFree++
+		}
+		if nextFree >= c.superblock.S_blocks_count {
+			return newCommandError(ErrCodeNoSpace, "no hay bloques libres para separar los bloques duplicados")
+		}
+
+		source, err := c.resolveRef(ref)
+		if err != nil {
+			return err
+		}
+
+		buffer := make([]byte, c.superblock.S_block_s)
+		if _, err := c.fs.file.ReadAt(buffer, c.superblock.S_block_start+source*c.superblock.S_block_s); err != nil {
+			return fmt.Errorf("error al leer el bloque %d: %v", source, err)
+		}
+		if _, err := c.fs.file.WriteAt(buffer, c.superblock.S_block_start+nextFree*c.superblock.S_block_s); err != nil {
+			return fmt.Errorf("error al escribir el bloque %d: %v", nextFree, err)
+		}
+		if err := c.updateRef(ref, nextFree); err != nil {
+			return err
+		}
+
+		c.blockOwners[nextFree] = ref.inode
+		if problemIndex < len(c.problems) {
+			c.problems[problemIndex].Repaired = true
+			c.problems[problemIndex].Detail += fmt.Sprintf(" (copiado al bloque %d)", nextFree)
+			problemIndex++
+		}
+	}
+
+	return nil
+}
+
+// hasOwner - Verificar si un bloque es alcanzable
+func (c *fsckChecker) hasOwner(blockIndex int64) bool {
+	_, used := c.blockOwners[blockIndex]
+	return used
+}
+
+// resolveRef - Obtener el bloque al que apunta una referencia
+func (c *fsckChecker) resolveRef(ref blockRef) (int64, error) {
+	if ref.pointerBlock == -1 {
+		inode, err := c.fs.ReadInode(ref.inode)
+		if err != nil {
+			return -1, err
+		}
+		return inode.I_block[ref.slot], nil
+	}
+
+	pointerBlock, err := c.fs.ReadPointerBlock(ref.pointerBlock)
+	if err != nil {
+		return -1, err
+	}
+	return pointerBlock.BPointers[ref.position], nil
+}
+
+// updateRef - Hacer que una referencia apunte a otro bloque
+func (c *fsckChecker) updateRef(ref blockRef, blockIndex int64) error {
+	if ref.pointerBlock == -1 {
+		inode, err := c.fs.ReadInode(ref.inode)
+		if err != nil {
+			return err
+		}
+		inode.I_block[ref.slot] = blockIndex
+		return c.fs.WriteInode(ref.inode, inode)
+	}
+
+	pointerBlock, err := c.fs.ReadPointerBlock(ref.pointerBlock)
+	if err != nil {
+		return err
+	}
+	pointerBlock.BPointers[ref.position] = blockIndex
+	return c.fs.WritePointerBlock(ref.pointerBlock, &pointerBlock)
+}
+
+// checkBitmaps - Comparar los bitmaps con los inodos y bloques alcanzables
+func (c *fsckChecker) checkBitmaps() {
+	for i := int64(0); i < c.superblock.S_inodes_count; i++ {
+		used := c.inodeBitmap[i] != 0
+		reachable := c.reachableInodes[i]
+
+		switch {
+		case reachable && !used:
+			c.addProblem(fsckInodeNotMarked, i, -1, "", c.repair, "el inodo %d está en uso pero el bitmap lo marca libre", i)
+			if c.repair {
+				c.inodeBitmap[i] = 1
+			}
+		case used && !reachable:
+			c.addProblem(fsckOrphanInode, i, -1, "", c.repair, "el inodo %d está marcado como usado pero ninguna carpeta lo referencia", i)
+			if c.repair {
+				c.inodeBitmap[i] = 0
+			}
+		}
+	}
+
+	for i := int64(0); i < c.superblock.S_blocks_count; i++ {
+		used := c.blockBitmap[i] != 0
+		reachable := c.hasOwner(i)
+
+		switch {
+		case reachable && !used:
+			c.addProblem(fsckBlockNotMarked, -1, i, "", c.repair, "el bloque %d está en uso pero el bitmap lo marca libre", i)
+			if c.repair {
+				c.blockBitmap[i] = 1
+			}
+		case used && !reachable:
+			c.addProblem(fsckLeakedBlock, -1, i, "", c.repair, "el bloque %d está marcado como usado pero ningún inodo lo referencia", i)
+			if c.repair {
+				c.blockBitmap[i] = 0
+			}
+		}
+	}
+}
+
+// checkFreeCounts - Comparar los contadores del superbloque con los bitmaps
+func (c *fsckChecker) checkFreeCounts() {
+	freeInodes := int64(0)
+	for _, b := range c.inodeBitmap {
+		if b == 0 {
+			freeInodes++
+		}
+	}
+
+	freeBlocks := int64(0)
+	for _, b := range c.blockBitmap {
+		if b == 0 {
+			freeBlocks++
+		}
+	}
+
+	if c.superblock.S_free_inodes_count != freeInodes {
+		c.addProblem(fsckFreeInodesCount, -1, -1, "", c.repair,
+			"S_free_inodes_count es %d pero el bitmap indica %d inodos libres", c.superblock.S_free_inodes_count, freeInodes)
+		if c.repair {
+			c.superblock.S_free_inodes_count = freeInodes
+		}
+	}
+
+	if c.superblock.S_free_blocks_count != freeBlocks {
+		c.addProblem(fsckFreeBlocksCount, -1, -1, "", c.repair,
+			"S_free_blocks_count es %d pero el bitmap indica %d bloques libres", c.superblock.S_free_blocks_count, freeBlocks)
+		if c.repair {
+			c.superblock.S_free_blocks_count = freeBlocks
+		}
+	}
+}
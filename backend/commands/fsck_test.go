@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"os"
+	"testing"
+)
+
+// Pruebas de fsck: una partición con bitmaps, contadores, apuntadores y
+// entradas dañados queda limpia tras fsck -repair, y una transacción
+// pendiente del journal se rehace antes de revisar.
+
+// fsckKinds - Tipos de problema que reporta un resultado de fsck
+func fsckKinds(res *CommandResult) map[string]bool {
+	kinds := make(map[string]bool)
+	problems, _ := res.Data["problems"].([]FsckProblem)
+	for _, problem := range problems {
+		kinds[problem.Kind] = true
+	}
+	return kinds
+}
+
+// firstFree - Primera posición libre (0) de un bitmap en el disco
+func firstFree(t *testing.T, fs *FileSystem, start, count int64) int64 {
+	t.Helper()
+	bitmap := make([]byte, count)
+	if _, err := fs.file.ReadAt(bitmap, start); err != nil {
+		t.Fatal(err)
+	}
+	for i, used := range bitmap {
+		if used == 0 {
+			return int64(i)
+		}
+	}
+	t.Fatal("el bitmap no tiene posiciones libres")
+	return -1
+}
+
+// corruptFileSystem - Dañar bitmaps, contadores, apuntadores y entradas de la imagen
+func corruptFileSystem(t *testing.T, img *crashImage) {
+	t.Helper()
+	fs, err := openFileSystem(img.mounted, os.O_RDWR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	sb := fs.SB
+	write := func(p []byte, offset int64) {
+		if _, err := fs.file.WriteAt(p, offset); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Bitmaps: un inodo y un bloque en uso marcados libres, y al revés
+	docs, docsInode, err := fs.Lookup("/docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	freeInode := firstFree(t, fs, sb.S_bm_inode_start, sb.S_inodes_count)
+	freeBlock := firstFree(t, fs, sb.S_bm_block_start, sb.S_blocks_count)
+	write([]byte{0}, sb.S_bm_inode_start+docs)
+	write([]byte{0}, sb.S_bm_block_start+docsInode.I_block[0])
+	write([]byte{1}, sb.S_bm_inode_start+freeInode)
+	write([]byte{1}, sb.S_bm_block_start+freeBlock)
+
+	// Apuntador fuera de rango en un archivo
+	fileIndex, fileInode, err := fs.Lookup("/docs/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileInode.I_block[directBlocksCount-1] = sb.S_blocks_count + 100
+	if err := fs.WriteInode(fileIndex, fileInode); err != nil {
+		t.Fatal(err)
+	}
+
+	// '..' de /docs/sub apuntando a la raíz y su entrada b.txt hacia un inodo
+	// inexistente (el archivo sigue alcanzable por /enlace)
+	_, subInode, err := fs.Lookup("/docs/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	subBlock, err := fs.ReadFolderBlock(subInode.I_block[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	subBlock.BContent[1].BInodo = 0
+	subBlock.BContent[2].BInodo = sb.S_inodes_count + 7
+	if err := fs.WriteBlock(subInode.I_block[0], subBlock); err != nil {
+		t.Fatal(err)
+	}
+
+	// Contador de enlaces que no corresponde a las entradas
+	linked, linkedInode, err := fs.Lookup("/enlace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkedInode.I_links = 5
+	if err := fs.WriteInode(linked, linkedInode); err != nil {
+		t.Fatal(err)
+	}
+
+	// Contadores libres del superbloque
+	sb.S_free_blocks_count += 5
+	sb.S_free_inodes_count -= 3
+	if err := fs.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFsckRepairsCorruption(t *testing.T) {
+	for _, fsType := range []string{"2fs", "3fs"} {
+		t.Run(fsType, func(t *testing.T) {
+			img := newCrashImageFS(t, fsType)
+			if _, err := ExecuteLn(img.session, "/docs/sub/b.txt", "/enlace", false); err != nil {
+				t.Fatalf("ln: %v", err)
+			}
+			corruptFileSystem(t, img)
+
+			res, err := ExecuteFsck(img.id, false)
+			if err != nil {
+				t.Fatalf("fsck: %v", err)
+			}
+			kinds := fsckKinds(res)
+			for _, kind := range []string{fsckInodeNotMarked, fsckBlockNotMarked, fsckOrphanInode, fsckLeakedBlock,
+				fsckBadPointer, fsckBadDotDot, fsckDanglingEntry, fsckLinkCount, fsckFreeBlocksCount, fsckFreeInodesCount} {
+				if !kinds[kind] {
+					t.Errorf("fsck no reportó %s: %v", kind, res.Data["problems"])
+				}
+			}
+
+			if _, err := ExecuteFsck(img.id, true); err != nil {
+				t.Fatalf("fsck -repair: %v", err)
+			}
+			img.checkClean(t, "tras reparar")
+
+			// La partición reparada sigue funcionando
+			if _, err := ExecuteMkfile(img.session, "/docs/nuevo.txt", false, 2000, ""); err != nil {
+				t.Fatalf("mkfile: %v", err)
+			}
+			checkContent(t, img, "/enlace", mkfileContent(200))
+			img.checkClean(t, "tras escribir")
+		})
+	}
+}
+
+func TestFsckReplaysPendingJournal(t *testing.T) {
+	for _, repair := range []bool{false, true} {
+		name := "verificar"
+		if repair {
+			name = "reparar"
+		}
+		t.Run(name, func(t *testing.T) {
+			img := newCrashImage(t)
+
+			// En el disco un bloque libre figura como usado; el journal tiene
+			// confirmada, sin aplicar, la escritura que lo corrige
+			file, err := openDisk(img.mounted.Path, os.O_RDWR)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fs, err := mountedFileSystem(file, img.mounted)
+			if err != nil {
+				file.Close()
+				t.Fatal(err)
+			}
+			offset := fs.SB.S_bm_block_start + firstFree(t, fs, fs.SB.S_bm_block_start, fs.SB.S_blocks_count)
+			layout, _ := walLayoutFor(fs.Partition.Start, fs.SB)
+			records, err := encodeWalRecords([]walChange{{offset: offset, data: []byte{0}}})
+			if err == nil {
+				_, err = file.WriteAt([]byte{1}, offset)
+			}
+			if err == nil {
+				_, err = commitWalRecords(file, layout, records, 1)
+			}
+			file.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := ExecuteFsck(img.id, repair)
+			if err != nil {
+				t.Fatalf("fsck: %v", err)
+			}
+			if replayed, _ := res.Data["journalReplayed"].(bool); !replayed {
+				t.Fatal("fsck no rehízo la transacción pendiente")
+			}
+			if clean, _ := res.Data["clean"].(bool); !clean {
+				t.Fatalf("fsck revisó antes de rehacer el journal: %v", res.Data["problems"])
+			}
+			if pending, err := journalPending(img.mounted); err != nil || pending {
+				t.Fatalf("el journal sigue pendiente: %v %v", pending, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"backend/structs"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// TABLA DE PARTICIONES GPT
+// Un disco creado con mkdisk -table=gpt conserva el MBR al inicio, pero como
+// MBR protector: su única partición (tipo 'G') cubre todo el disco, así que el
+// código que solo entiende MBR lo ve lleno. En el sector 1 va la cabecera GPT
+// y a continuación el arreglo de entradas; al final del disco, en orden
+// inverso, la copia de respaldo de ambos. Cada cabecera lleva el CRC32 de sí
+// misma y del arreglo de entradas. Si la copia principal está dañada se usa la
+// de respaldo, y la siguiente escritura vuelve a guardar las dos. En GPT todas
+// las particiones son primarias: no hay extendida ni EBRs.
+
+const (
+	gptSignature      = "EFI PART"
+	gptRevision       = 0x00010000 // Versión 1.0
+	gptSectorSize     = int64(512)
+	gptDefaultEntries = 128
+	gptMaxEntries     = 1024
+	gptProtectiveType = 'G' // Part_type de la partición del MBR protector
+)
+
+// gptLinuxDataType - GUID de tipo "Linux filesystem data"
+// (0FC63DAF-8483-4772-8E79-3D69D8477DE4) en el orden de bytes de GPT
+var gptLinuxDataType = [16]byte{0xAF, 0x3D, 0xC6, 0x0F, 0x83, 0x84, 0x72, 0x47, 0x8E, 0x79, 0x3D, 0x69, 0xD8, 0x47, 0x7D, 0xE4}
+
+// gptTable - Tabla GPT de un disco: cabecera principal y arreglo de entradas
+type gptTable struct {
+	Header     structs.GPTHeader
+	Entries    []structs.GPTEntry
+	FromBackup bool // La copia principal estaba dañada y se leyó la de respaldo
+}
+
+// isGPTDisk - Verificar si el MBR es el protector de un disco GPT
+func isGPTDisk(mbr *structs.MBR) bool {
+	protective := mbr.Mbr_partitions[0]
+	return protective.Part_status != '0' && protective.Part_type == gptProtectiveType
+}
+
+// newProtectiveMBR - MBR de un disco GPT, con una sola partición que lo cubre entero
+func newProtectiveMBR(size int64, fit byte, signature int64) structs.MBR {
+	mbr := structs.NewMBR(size, fit, signature)
+	mbr.Mbr_partitions[0] = structs.NewPartition('1', gptProtectiveType, fit, gptSectorSize, size-gptSectorSize, [16]byte{})
+	return mbr
+}
+
+// newGUID - GUID aleatorio (versión 4) en el orden de bytes de GPT
+func newGUID() [16]byte {
+	var guid [16]byte
+	rand.Read(guid[:])
+	guid[7] = guid[7]&0x0f | 0x40
+	guid[8] = guid[8]&0x3f | 0x80
+	return guid
+}
+
+// formatGUID - Representación textual de un GUID guardado en el orden de bytes de GPT
+func formatGUID(guid [16]byte) string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+		binary.LittleEndian.Uint32(guid[0:4]),
+		binary.LittleEndian.Uint16(guid[4:6]),
+		binary.LittleEndian.Uint16(guid[6:8]),
+		guid[8:10], guid[10:16])
+}
+
+// gptEntriesSize - Bytes que ocupa el arreglo de entradas, redondeado a sectores
+func gptEntriesSize(count int) int64 {
+	size := int64(count) * int64(binary.Size(structs.GPTEntry{}))
+	return (size + gptSectorSize - 1) / gptSectorSize * gptSectorSize
+}
+
+// newGPT - Tabla GPT vacía para un disco del tamaño indicado
+func newGPT(diskSize int64, count int) (*gptTable, error) {
+	if count < 1 || count > gptMaxEntries {
+		return nil, fmt.Errorf("la cantidad de entradas GPT debe estar entre 1 y %d. Actual: %d", gptMaxEntries, count)
+	}
+
+	entriesSize := gptEntriesSize(count)
+	backupHeader := diskSize - gptSectorSize
+	backupEntries := backupHeader - entriesSize
+	firstUsable := 2*gptSectorSize + entriesSize
+	if backupEntries <= firstUsable {
+		return nil, fmt.Errorf("el disco (%d bytes) es muy pequeño para una tabla GPT de %d entradas", diskSize, count)
+	}
+
+	header := structs.GPTHeader{
+		Gpt_revision:      gptRevision,
+		Gpt_header_size:   uint32(binary.Size(structs.GPTHeader{})),
+		Gpt_current:       gptSectorSize,
+		Gpt_backup:        backupHeader,
+		Gpt_first_usable:  firstUsable,
+		Gpt_last_usable:   backupEntries - 1,
+		Gpt_disk_guid:     newGUID(),
+		Gpt_entries_start: 2 * gptSectorSize,
+		Gpt_entries_count: uint32(count),
+		Gpt_entry_size:    uint32(binary.Size(structs.GPTEntry{})),
+	}
+	copy(header.Gpt_signature[:], gptSignature)
+
+	table := &gptTable{Header: header, Entries: make([]structs.GPTEntry, count)}
+	for i := range table.Entries {
+		table.clearEntry(i)
+	}
+	return table, nil
+}
+
+// gptHeaderCRC - CRC32 de la cabecera calculado con el campo del CRC en 0
+func gptHeaderCRC(header structs.GPTHeader) uint32 {
+	header.Gpt_header_crc = 0
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, &header)
+	return crc32.ChecksumIEEE(buffer.Bytes())
+}
+
+// encodeGPTEntries - Bytes del arreglo de entradas tal como va en el disco
+func encodeGPTEntries(entries []structs.GPTEntry) []byte {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, entries)
+	return buffer.Bytes()
+}
+
+// backupHeader - Cabecera de la copia de respaldo que corresponde a la principal
+func (t *gptTable) backupHeader() structs.GPTHeader {
+	backup := t.Header
+	backup.Gpt_current, backup.Gpt_backup = t.Header.Gpt_backup, t.Header.Gpt_current
+	backup.Gpt_entries_start = t.Header.Gpt_last_usable + 1
+	backup.Gpt_header_crc = gptHeaderCRC(backup)
+	return backup
+}
+
+// write - Guardar las dos copias de la tabla con los CRC recalculados
+func (t *gptTable) write(file *diskFile) error {
+	entries := encodeGPTEntries(t.Entries)
+	t.Header.Gpt_entries_crc = crc32.ChecksumIEEE(entries)
+	t.Header.Gpt_header_crc = gptHeaderCRC(t.Header)
+
+	for _, header := range []structs.GPTHeader{t.Header, t.backupHeader()} {
+		if _, err := file.WriteAt(entries, header.Gpt_entries_start); err != nil {
+			return fmt.Errorf("error al escribir las entradas GPT: %v", err)
+		}
+		file.Seek(header.Gpt_current, 0)
+		if err := binary.Write(file, binary.LittleEndian, &header); err != nil {
+			return fmt.Errorf("error al escribir la cabecera GPT: %v", err)
+		}
+	}
+
+	t.FromBackup = false
+	return nil
+}
+
+// readGPTCopy - Leer y validar una copia de la tabla (cabecera y entradas)
+func readGPTCopy(file *diskFile, position int64) (structs.GPTHeader, []structs.GPTEntry, error) {
+	var header structs.GPTHeader
+	file.Seek(position, 0)
+	if err := binary.Read(file, binary.LittleEndian, &header); err != nil {
+		return header, nil, fmt.Errorf("error al leer la cabecera: %v", err)
+	}
+	if string(header.Gpt_signature[:]) != gptSignature {
+		return header, nil, fmt.Errorf("firma inválida")
+	}
+	if header.Gpt_header_crc != gptHeaderCRC(header) {
+		return header, nil, fmt.Errorf("CRC de la cabecera inválido")
+	}
+	if header.Gpt_current != position || header.Gpt_entry_size != uint32(binary.Size(structs.GPTEntry{})) ||
+		header.Gpt_entries_count < 1 || header.Gpt_entries_count > gptMaxEntries {
+		return header, nil, fmt.Errorf("cabecera inconsistente")
+	}
+
+	raw := make([]byte, int(header.Gpt_entries_count)*int(header.Gpt_entry_size))
+	if _, err := file.ReadAt(raw, header.Gpt_entries_start); err != nil {
+		return header, nil, fmt.Errorf("error al leer las entradas: %v", err)
+	}
+	if crc32.ChecksumIEEE(raw) != header.Gpt_entries_crc {
+		return header, nil, fmt.Errorf("CRC de las entradas inválido")
+	}
+
+	entries := make([]structs.GPTEntry, header.Gpt_entries_count)
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, entries); err != nil {
+		return header, nil, fmt.Errorf("error al leer las entradas: %v", err)
+	}
+	return header, entries, nil
+}
+
+// readGPT - Leer la tabla GPT de un disco con MBR protector. Si la copia
+// principal está dañada se reconstruye a partir de la de respaldo.
+func readGPT(file *diskFile, mbr *structs.MBR) (*gptTable, error) {
+	header, entries, err := readGPTCopy(file, gptSectorSize)
+	if err == nil {
+		return &gptTable{Header: header, Entries: entries}, nil
+	}
+
+	backup, backupEntries, backupErr := readGPTCopy(file, mbr.Mbr_tamano-gptSectorSize)
+	if backupErr != nil {
+		return nil, fmt.Errorf("tabla GPT dañada (principal: %v; respaldo: %v)", err, backupErr)
+	}
+
+	backup.Gpt_current, backup.Gpt_backup = backup.Gpt_backup, backup.Gpt_current
+	backup.Gpt_entries_start = 2 * gptSectorSize
+	return &gptTable{Header: backup, Entries: backupEntries, FromBackup: true}, nil
+}
+
+// gptEntryUsed - Verificar si una entrada tiene una partición
+func gptEntryUsed(entry *structs.GPTEntry) bool {
+	return entry.Gpt_type_guid != [16]byte{} && entry.Gpt_partition.Part_status != '0' && entry.Gpt_partition.Part_s > 0
+}
+
+// clearEntry - Dejar libre una entrada de la tabla
+func (t *gptTable) clearEntry(index int) {
+	t.Entries[index] = structs.GPTEntry{}
+	t.Entries[index].Gpt_partition.Part_status = '0'
+}
+
+// freeEntry - Índice de la primera entrada libre, -1 si la tabla está llena
+func (t *gptTable) freeEntry() int {
+	for i := range t.Entries {
+		if !gptEntryUsed(&t.Entries[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// findPartition - Índice de la entrada con ese nombre, -1 si no existe
+func (t *gptTable) findPartition(name string) int {
+	for i := range t.Entries {
+		if gptEntryUsed(&t.Entries[i]) && strings.EqualFold(partitionName(t.Entries[i].Gpt_partition.Part_name[:]), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// partitions - Particiones de las entradas ocupadas
+func (t *gptTable) partitions() []structs.Partition {
+	var partitions []structs.Partition
+	for i := range t.Entries {
+		if gptEntryUsed(&t.Entries[i]) {
+			partitions = append(partitions, t.Entries[i].Gpt_partition)
+		}
+	}
+	return partitions
+}
+
+// diskEnd - Fin del disco (después de la cabecera de respaldo)
+func (t *gptTable) diskEnd() int64 {
+	return t.Header.Gpt_backup + gptSectorSize
+}
+
+// tableRanges - Zonas del disco ocupadas por la tabla: MBR protector y copia
+// principal al inicio, copia de respaldo al final
+func (t *gptTable) tableRanges() []FreeSpace {
+	backupStart := t.Header.Gpt_last_usable + 1
+	return []FreeSpace{
+		{Start: 0, Size: t.Header.Gpt_first_usable},
+		{Start: backupStart, Size: t.diskEnd() - backupStart},
+	}
+}
+
+// freeSpaces - Espacios libres entre las particiones, dentro de la zona utilizable
+func (t *gptTable) freeSpaces() []FreeSpace {
+	used := t.tableRanges()
+	for _, partition := range t.partitions() {
+		used = append(used, FreeSpace{Start: partition.Part_start, Size: partition.Part_s})
+	}
+	return freeSpacesIn(used, t.diskEnd())
+}
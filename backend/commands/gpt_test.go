@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Pruebas de la tabla GPT: una copia con la cabecera o las entradas dañadas
+// no pasa la verificación de CRC, se lee la de respaldo y la siguiente
+// escritura vuelve a guardar las dos.
+
+// newGPTDisk - Disco GPT con dos particiones
+func newGPTDisk(t *testing.T) string {
+	t.Helper()
+	diskPath := filepath.Join(t.TempDir(), "gpt.mia")
+	if _, err := ExecuteMkdisk(2, "m", "ff", diskPath, "gpt", 0); err != nil {
+		t.Fatalf("mkdisk: %v", err)
+	}
+	t.Cleanup(func() { RemoveDiskFromRegistry(diskPath) })
+	for _, name := range []string{"part1", "part2"} {
+		if _, err := ExecuteFdisk(300, "k", diskPath, "p", "ff", name, "", 0); err != nil {
+			t.Fatalf("fdisk %s: %v", name, err)
+		}
+	}
+	return diskPath
+}
+
+// corruptByte - Invertir un byte del disco
+func corruptByte(t *testing.T, diskPath string, position int64) {
+	t.Helper()
+	file, err := os.OpenFile(diskPath, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	b := make([]byte, 1)
+	if _, err := file.ReadAt(b, position); err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 0xFF
+	if _, err := file.WriteAt(b, position); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readGPTCopyAt - Validar la copia de la tabla que empieza en position
+func readGPTCopyAt(t *testing.T, diskPath string, position int64) error {
+	t.Helper()
+	file, err := openDisk(diskPath, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	_, _, err = readGPTCopy(file, position)
+	return err
+}
+
+// checkGPTPartitions - Verificar las particiones que se leen de la tabla
+func checkGPTPartitions(t *testing.T, diskPath string, fromBackup bool) {
+	t.Helper()
+	disk, err := OpenDisk(diskPath, os.O_RDONLY)
+	if err != nil {
+		t.Fatalf("abrir disco: %v", err)
+	}
+	defer disk.Close()
+	if disk.GPT == nil {
+		t.Fatal("el disco no se leyó como GPT")
+	}
+	if disk.GPT.FromBackup != fromBackup {
+		t.Fatalf("FromBackup = %v, se esperaba %v", disk.GPT.FromBackup, fromBackup)
+	}
+	for _, name := range []string{"part1", "part2"} {
+		if disk.GPT.findPartition(name) == -1 {
+			t.Fatalf("no se encontró la partición '%s'", name)
+		}
+	}
+}
+
+func TestGPTFallsBackToBackup(t *testing.T) {
+	cases := []struct {
+		name     string
+		position func(table *gptTable) int64
+		err      string
+	}{
+		{"cabecera", func(table *gptTable) int64 {
+			return table.Header.Gpt_current + 52 // dentro de Gpt_disk_guid
+		}, "CRC de la cabecera"},
+		{"entradas", func(table *gptTable) int64 {
+			return table.Header.Gpt_entries_start + 20
+		}, "CRC de las entradas"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			diskPath := newGPTDisk(t)
+			disk, err := OpenDisk(diskPath, os.O_RDONLY)
+			if err != nil {
+				t.Fatal(err)
+			}
+			table := disk.GPT
+			disk.Close()
+			backup := table.Header.Gpt_backup
+
+			corruptByte(t, diskPath, tc.position(table))
+			if err := readGPTCopyAt(t, diskPath, gptSectorSize); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("copia principal dañada: error %v, se esperaba '%s'", err, tc.err)
+			}
+			if err := readGPTCopyAt(t, diskPath, backup); err != nil {
+				t.Fatalf("copia de respaldo: %v", err)
+			}
+			checkGPTPartitions(t, diskPath, true)
+
+			// La siguiente escritura guarda de nuevo las dos copias
+			res, err := ExecuteMount(diskPath, "part1")
+			if err != nil {
+				t.Fatalf("mount: %v", err)
+			}
+			id, _ := res.Data["id"].(string)
+			defer ExecuteUnmount(id)
+			if err := readGPTCopyAt(t, diskPath, gptSectorSize); err != nil {
+				t.Fatalf("copia principal tras escribir: %v", err)
+			}
+			checkGPTPartitions(t, diskPath, false)
+		})
+	}
+}
+
+func TestGPTBothCopiesDamaged(t *testing.T) {
+	diskPath := newGPTDisk(t)
+	disk, err := OpenDisk(diskPath, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := disk.GPT.Header
+	disk.Close()
+
+	corruptByte(t, diskPath, header.Gpt_current+52)
+	corruptByte(t, diskPath, header.Gpt_last_usable+1+20) // entradas de respaldo
+
+	if _, err := OpenDisk(diskPath, os.O_RDONLY); err == nil || !strings.Contains(err.Error(), "tabla GPT dañada") {
+		t.Fatalf("con las dos copias dañadas: error %v", err)
+	}
+	if _, err := ExecuteFdisk(100, "k", diskPath, "p", "ff", "part3", "", 0); err == nil {
+		t.Fatal("fdisk escribió sobre una tabla GPT ilegible")
+	}
+}
@@ -0,0 +1,348 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IMPORTAR UNA CARPETA DEL HOST
+// Copia el árbol de una carpeta del sistema anfitrión dentro de la partición
+// de la sesión. Cada carpeta y cada archivo se crea en su propia transacción
+// (como mkdir y mkfile), así que lo que no se puede importar se omite y se
+// reporta sin deshacer lo que ya se copió.
+
+// importer - Estado de una importación en curso
+type importer struct {
+	res       *CommandResult
+	mounted   *MountedPartition
+	session   *Session
+	recursive bool
+	maxName   int   // Bytes que admite un nombre en la partición
+	maxSize   int64 // Bytes que admite un archivo en la partición
+
+	folders int
+	files   int
+	bytes   int64
+	skipped []map[string]string
+}
+
+func ExecuteImport(session *Session, src string, dest string, recursive bool) (*CommandResult, error) {
+	res := NewCommandResult("import")
+
+	// Verificar sesión activa
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
+	}
+
+	// Validar parámetros obligatorios
+	if src == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -src es obligatorio para import")
+	}
+	if dest == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -dest es obligatorio para import")
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return res, newCommandError(ErrCodeNotFound, "la carpeta '%s' no existe en el host", src)
+	}
+	if !info.IsDir() {
+		return res, newCommandError(ErrCodeInvalidArgument, "'%s' no es una carpeta", src)
+	}
+
+	parsedPath := parsePath(dest)
+	if parsedPath == nil || !parsedPath.IsAbsolute {
+		return res, newCommandError(ErrCodeInvalidArgument, "la ruta destino debe ser absoluta: %s", dest)
+	}
+
+	// Buscar la partición montada de la sesión
+	mounted := GetMountedPartition(session.PartitionID)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
+	}
+
+	imp := &importer{
+		res:       res,
+		mounted:   mounted,
+		session:   session,
+		recursive: recursive,
+		skipped:   []map[string]string{},
+	}
+
+	destInode, err := imp.destination(dest)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al preparar la carpeta destino '%s'", dest)
+	}
+
+	imp.importDir(src, parsedPath.FullPath, destInode)
+
+	// Una sola entrada en el journal para toda la importación
+	summary := fmt.Sprintf("%s: %d carpetas, %d archivos (%d bytes), %d omitidos",
+		src, imp.folders, imp.files, imp.bytes, len(imp.skipped))
+	if err := imp.inTransaction(func() error {
+		return WriteJournal(res, mounted, "import", dest, summary)
+	}); err != nil {
+		res.Printf("⚠️ No se pudo escribir al journal: %v\n", err)
+	}
+
+	res.Printf("✅ Importación en '%s' completada: %d carpetas y %d archivos (%d bytes).\n",
+		dest, imp.folders, imp.files, imp.bytes)
+	if len(imp.skipped) > 0 {
+		res.Printf("⚠️ Se omitieron %d elementos.\n", len(imp.skipped))
+	}
+
+	res.AddPath(dest)
+	res.Set("folders", imp.folders)
+	res.Set("files", imp.files)
+	res.Set("bytes", imp.bytes)
+	res.Set("skipped", imp.skipped)
+
+	return res, nil
+}
+
+// inTransaction - Ejecutar fn dentro de su propia transacción del journal
+func (imp *importer) inTransaction(fn func() error) error {
+	tx, err := beginTransaction(imp.mounted)
+	if err != nil {
+		return fmt.Errorf("error al iniciar la transacción: %v", err)
+	}
+	defer tx.rollback()
+
+	if err := fn(); err != nil {
+		return err
+	}
+	return tx.commit()
+}
+
+// skip - Registrar un elemento del host que no se importó
+func (imp *importer) skip(hostPath string, reason string) {
+	imp.res.Printf("⚠️ Omitido '%s': %s\n", hostPath, reason)
+	imp.skipped = append(imp.skipped, map[string]string{
+		"path":   hostPath,
+		"reason": reason,
+	})
+}
+
+// destination - Obtener (o crear, como mkdir) la carpeta destino
+func (imp *importer) destination(dest string) (int64, error) {
+	var inodeIndex int64
+	err := imp.inTransaction(func() error {
+		fs, err := openFileSystem(imp.mounted, os.O_RDONLY)
+		if err != nil {
+			return err
+		}
+		imp.maxName = fs.maxNameLength()
+		imp.maxSize = fs.maxFileSize()
+		existing, inode, lookupErr := fs.Lookup(dest)
+		if lookupErr == nil {
+			fs.Close()
+			if inode.I_type != '0' {
+				return newCommandError(ErrCodeInvalidArgument, "'%s' no es una carpeta", dest)
+			}
+			inodeIndex = existing
+			return nil
+		}
+
+		// Carpetas de la ruta que se van a crear (la destino y sus padres)
+		missing := 0
+		current := ""
+		for _, dir := range strings.Split(strings.Trim(dest, "/"), "/") {
+			current += "/" + dir
+			if _, _, err := fs.Lookup(current); err != nil {
+				missing++
+			}
+		}
+		fs.Close()
+
+		// Con -r se crean también las carpetas padre que falten
+		newInode, _, err := createDirectoryPath(imp.res, imp.mounted, dest, imp.recursive, imp.session)
+		if err != nil {
+			return err
+		}
+		imp.folders += missing
+		inodeIndex = newInode
+		return nil
+	})
+	return inodeIndex, err
+}
+
+// importDir - Copiar el contenido de una carpeta del host en la carpeta destino
+func (imp *importer) importDir(hostDir string, destPath string, destInode int64) {
+	entries, err := os.ReadDir(hostDir)
+	if err != nil {
+		imp.skip(hostDir, fmt.Sprintf("no se pudo leer la carpeta: %v", err))
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		hostPath := filepath.Join(hostDir, name)
+		partitionPath := path.Join(destPath, name)
+
+		// Sin nombres largos BContent.BName guarda como máximo 12 bytes
+		if len(name) > imp.maxName {
+			imp.skip(hostPath, fmt.Sprintf("el nombre tiene %d bytes (máximo %d)", len(name), imp.maxName))
+			continue
+		}
+
+		switch {
+		case entry.IsDir():
+			if !imp.recursive {
+				imp.skip(hostPath, "es una subcarpeta (use -r para importarla)")
+				continue
+			}
+			inodeIndex, err := imp.createFolder(destInode, name)
+			if err != nil {
+				imp.skip(hostPath, err.Error())
+				continue
+			}
+			imp.importDir(hostPath, partitionPath, inodeIndex)
+
+		case entry.Type().IsRegular():
+			size, err := imp.createFile(destInode, name, hostPath)
+			if err != nil {
+				imp.skip(hostPath, err.Error())
+				continue
+			}
+			imp.res.Printf("📄 %s (%d bytes)\n", partitionPath, size)
+
+		default:
+			imp.skip(hostPath, "no es un archivo regular ni una carpeta")
+		}
+	}
+}
+
+// createFolder - Crear una subcarpeta (o reutilizar la existente)
+func (imp *importer) createFolder(parentInode int64, name string) (int64, error) {
+	var inodeIndex int64
+	err := imp.inTransaction(func() error {
+		fs, err := openFileSystem(imp.mounted, os.O_RDWR)
+		if err != nil {
+			return err
+		}
+		defer fs.Close()
+
+		if existing, err := fs.LookupIn(parentInode, name); err == nil {
+			inode, err := fs.ReadInode(existing)
+			if err != nil {
+				return err
+			}
+			if inode.I_type != '0' {
+				return newCommandError(ErrCodeAlreadyExists, "ya existe un archivo con ese nombre")
+			}
+			inodeIndex = existing
+			return nil
+		}
+
+		// Verificar permisos de escritura en el directorio padre
+		hasPermission, err := fs.CanWrite(parentInode, imp.session)
+		if err != nil {
+			return fmt.Errorf("error al verificar permisos: %v", err)
+		}
+		if !hasPermission {
+			return newCommandError(ErrCodePermissionDenied, "sin permisos de escritura en el directorio padre")
+		}
+
+		// Un inodo y un bloque para la carpeta, más uno por si la entrada no cabe
+		if fs.SB.S_free_inodes_count < 1 || fs.SB.S_free_blocks_count < 2 {
+			return newCommandError(ErrCodeNoSpace, "no hay espacio libre en la partición")
+		}
+		if err := fs.enforceSessionQuota(imp.session); err != nil {
+			return fmt.Errorf("error al leer las cuotas: %v", err)
+		}
+		if err := fs.quota.check(1, 1); err != nil {
+			return err
+		}
+
+		newInode, err := fs.CreateDirectory(parentInode, name, imp.session)
+		if err != nil {
+			return err
+		}
+		if err := fs.Flush(); err != nil {
+			return err
+		}
+		imp.folders++
+		inodeIndex = newInode
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+	return inodeIndex, nil
+}
+
+// createFile - Copiar un archivo del host (sobrescribe uno existente, como mkfile)
+func (imp *importer) createFile(parentInode int64, name string, hostPath string) (int64, error) {
+	info, err := os.Stat(hostPath)
+	if err != nil {
+		return 0, fmt.Errorf("no se pudo leer el archivo: %v", err)
+	}
+
+	if info.Size() > imp.maxSize {
+		return 0, newCommandError(ErrCodeNoSpace, "%d bytes excede el máximo de %d bytes por archivo", info.Size(), imp.maxSize)
+	}
+
+	content, err := os.ReadFile(hostPath)
+	if err != nil {
+		return 0, fmt.Errorf("no se pudo leer el archivo: %v", err)
+	}
+
+	err = imp.inTransaction(func() error {
+		fs, err := openFileSystem(imp.mounted, os.O_RDWR)
+		if err != nil {
+			return err
+		}
+		defer fs.Close()
+
+		// Verificar permisos de escritura en el directorio padre
+		hasPermission, err := fs.CanWrite(parentInode, imp.session)
+		if err != nil {
+			return fmt.Errorf("error al verificar permisos: %v", err)
+		}
+		if !hasPermission {
+			return newCommandError(ErrCodePermissionDenied, "sin permisos de escritura en el directorio padre")
+		}
+
+		// Se verifica antes de tocar nada: en EXT2 no hay transacción que deshacer.
+		// Se reserva un bloque más por si la entrada no cabe en la carpeta.
+		if fs.SB.S_free_inodes_count < 1 || fs.SB.S_free_blocks_count < fs.blocksForSize(int64(len(content)))+1 {
+			return newCommandError(ErrCodeNoSpace, "no hay espacio libre suficiente para %d bytes", len(content))
+		}
+		if err := fs.enforceSessionQuota(imp.session); err != nil {
+			return fmt.Errorf("error al leer las cuotas: %v", err)
+		}
+
+		if existing, err := fs.LookupIn(parentInode, name); err == nil {
+			inode, err := fs.ReadInode(existing)
+			if err != nil {
+				return err
+			}
+			if inode.I_type != '1' {
+				return newCommandError(ErrCodeAlreadyExists, "ya existe una carpeta con ese nombre")
+			}
+
+			// Eliminar la entrada existente (y el archivo si era su último enlace)
+			if _, err := fs.Unlink(parentInode, name); err != nil {
+				return fmt.Errorf("error al eliminar archivo existente: %v", err)
+			}
+		}
+		if err := fs.quota.check(fs.blocksForSize(int64(len(content))), 1); err != nil {
+			return err
+		}
+
+		if _, err := fs.CreateFile(parentInode, name, content, imp.session); err != nil {
+			return err
+		}
+		return fs.Flush()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	imp.files++
+	imp.bytes += int64(len(content))
+	return int64(len(content)), nil
+}
@@ -0,0 +1,358 @@
+package commands
+
+import (
+	"backend/structs"
+	"fmt"
+)
+
+// Distribución de los apuntadores dentro de I_block:
+//   - 0..11: bloques directos
+//   - 12: apuntador indirecto simple
+//   - 13: apuntador indirecto doble
+//   - 14: apuntador indirecto triple
+const (
+	directBlocksCount   = 12
+	singleIndirectIndex = 12
+	doubleIndirectIndex = 13
+	tripleIndirectIndex = 14
+)
+
+// maxBlocksPerInode - Máximo de bloques de datos direccionables por un inodo
+func (fs *FileSystem) maxBlocksPerInode() int64 {
+	p := int64(fs.geometry().Pointers)
+	return directBlocksCount + p + p*p + p*p*p
+}
+
+// maxFileSize - Bytes que puede guardar un archivo en la partición
+func (fs *FileSystem) maxFileSize() int64 {
+	return fs.maxBlocksPerInode() * int64(fs.geometry().Data)
+}
+
+// blocksForSize - Bloques que ocupa un archivo de size bytes, contando los de
+// apuntadores que necesitan sus niveles indirectos
+func (fs *FileSystem) blocksForSize(size int64) int64 {
+	geometry := fs.geometry()
+	blockSize := int64(geometry.Data)
+	dataBlocks := (size + blockSize - 1) / blockSize
+	p := int64(geometry.Pointers)
+
+	total := dataBlocks
+	remaining := dataBlocks - directBlocksCount
+	if remaining <= 0 {
+		return total
+	}
+
+	// Indirecto simple: un bloque de apuntadores
+	total++
+	remaining -= p
+	if remaining <= 0 {
+		return total
+	}
+
+	// Indirecto doble: el bloque raíz y uno por cada p bloques de datos
+	inDouble := remaining
+	if inDouble > p*p {
+		inDouble = p * p
+	}
+	total += 1 + (inDouble+p-1)/p
+	remaining -= p * p
+	if remaining <= 0 {
+		return total
+	}
+
+	// Indirecto triple: raíz, segundo nivel y tercer nivel
+	return total + 1 + (remaining+p*p-1)/(p*p) + (remaining+p-1)/p
+}
+
+// newPointerBlock - Crear un bloque de apuntadores vacío (todos en -1)
+func (fs *FileSystem) newPointerBlock() structs.BloqueApuntador {
+	return fs.geometry().newPointerBlock()
+}
+
+// ReadPointerBlock - Leer un bloque de apuntadores
+func (fs *FileSystem) ReadPointerBlock(blockIndex int64) (structs.BloqueApuntador, error) {
+	var pointerBlock structs.BloqueApuntador
+	if err := fs.ReadBlock(blockIndex, &pointerBlock); err != nil {
+		return pointerBlock, fmt.Errorf("error al leer bloque de apuntadores %d: %v", blockIndex, err)
+	}
+	return pointerBlock, nil
+}
+
+// WritePointerBlock - Escribir un bloque de apuntadores
+func (fs *FileSystem) WritePointerBlock(blockIndex int64, pointerBlock *structs.BloqueApuntador) error {
+	if err := fs.WriteBlock(blockIndex, pointerBlock); err != nil {
+		return fmt.Errorf("error al escribir bloque de apuntadores %d: %v", blockIndex, err)
+	}
+	return nil
+}
+
+// InodeBlocks - Obtener los bloques de datos (en orden lógico) y los bloques de apuntadores de un inodo
+func (fs *FileSystem) InodeBlocks(inode *structs.Inodos) ([]int64, []int64, error) {
+	var dataBlocks []int64
+	var pointerBlocks []int64
+
+	// Bloques directos
+	for i := 0; i < directBlocksCount; i++ {
+		if inode.I_block[i] != -1 {
+			dataBlocks = append(dataBlocks, inode.I_block[i])
+		}
+	}
+
+	// Bloques indirectos (simple, doble y triple)
+	levels := []struct {
+		slot  int
+		level int
+	}{
+		{singleIndirectIndex, 1},
+		{doubleIndirectIndex, 2},
+		{tripleIndirectIndex, 3},
+	}
+
+	for _, l := range levels {
+		if inode.I_block[l.slot] == -1 {
+			continue
+		}
+		if err := fs.collectIndirectBlocks(inode.I_block[l.slot], l.level, &dataBlocks, &pointerBlocks); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return dataBlocks, pointerBlocks, nil
+}
+
+// collectIndirectBlocks - Recorrer recursivamente un bloque de apuntadores del nivel indicado
+func (fs *FileSystem) collectIndirectBlocks(blockIndex int64, level int, dataBlocks *[]int64, pointerBlocks *[]int64) error {
+	if blockIndex < 0 || blockIndex >= fs.SB.S_blocks_count {
+		return fmt.Errorf("apuntador inválido al bloque %d", blockIndex)
+	}
+
+	*pointerBlocks = append(*pointerBlocks, blockIndex)
+
+	pointerBlock, err := fs.ReadPointerBlock(blockIndex)
+	if err != nil {
+		return err
+	}
+
+	for _, pointer := range pointerBlock.BPointers {
+		if pointer == -1 {
+			continue
+		}
+
+		if level == 1 {
+			*dataBlocks = append(*dataBlocks, pointer)
+			continue
+		}
+
+		if err := fs.collectIndirectBlocks(pointer, level-1, dataBlocks, pointerBlocks); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DataBlocks - Obtener solo los bloques de datos de un inodo en orden lógico
+func (fs *FileSystem) DataBlocks(inode *structs.Inodos) ([]int64, error) {
+	dataBlocks, _, err := fs.InodeBlocks(inode)
+	return dataBlocks, err
+}
+
+// BlockFor - Obtener el bloque de datos número logicalIndex del inodo,
+// asignándolo (junto con los bloques de apuntadores intermedios) si aún no existe.
+// El inodo se modifica en memoria; el llamador es responsable de escribirlo.
+func (fs *FileSystem) BlockFor(inode *structs.Inodos, logicalIndex int64) (int64, error) {
+	if logicalIndex < 0 || logicalIndex >= fs.maxBlocksPerInode() {
+		return -1, fmt.Errorf("se excede el máximo de %d bloques por inodo", fs.maxBlocksPerInode())
+	}
+
+	// Bloque directo
+	if logicalIndex < directBlocksCount {
+		if inode.I_block[logicalIndex] == -1 {
+			newBlock, err := fs.AllocBlock()
+			if err != nil {
+				return -1, err
+			}
+			inode.I_block[logicalIndex] = newBlock
+		}
+		return inode.I_block[logicalIndex], nil
+	}
+
+	// Determinar el nivel de indirección
+	p := int64(fs.geometry().Pointers)
+	remaining := logicalIndex - directBlocksCount
+	slot, level := singleIndirectIndex, 1
+	if remaining >= p {
+		remaining -= p
+		slot, level = doubleIndirectIndex, 2
+		if remaining >= p*p {
+			remaining -= p * p
+			slot, level = tripleIndirectIndex, 3
+		}
+	}
+
+	// Asegurar el bloque de apuntadores raíz del nivel
+	if inode.I_block[slot] == -1 {
+		newPointer, err := fs.AllocBlock()
+		if err != nil {
+			return -1, err
+		}
+		emptyBlock := fs.newPointerBlock()
+		if err := fs.WritePointerBlock(newPointer, &emptyBlock); err != nil {
+			return -1, err
+		}
+		inode.I_block[slot] = newPointer
+	}
+
+	// Descender por los niveles creando los bloques que falten
+	current := inode.I_block[slot]
+	for l := level; l >= 1; l-- {
+		span := int64(1)
+		for k := 1; k < l; k++ {
+			span *= p
+		}
+		position := remaining / span
+		remaining %= span
+
+		pointerBlock, err := fs.ReadPointerBlock(current)
+		if err != nil {
+			return -1, err
+		}
+
+		if pointerBlock.BPointers[position] == -1 {
+			newBlock, err := fs.AllocBlock()
+			if err != nil {
+				return -1, err
+			}
+			if l > 1 {
+				emptyBlock := fs.newPointerBlock()
+				if err := fs.WritePointerBlock(newBlock, &emptyBlock); err != nil {
+					return -1, err
+				}
+			}
+			pointerBlock.BPointers[position] = newBlock
+			if err := fs.WritePointerBlock(current, &pointerBlock); err != nil {
+				return -1, err
+			}
+		}
+
+		current = pointerBlock.BPointers[position]
+	}
+
+	return current, nil
+}
+
+// FreeInodeBlocks - Liberar todos los bloques de un inodo, incluidos los de apuntadores
+func (fs *FileSystem) FreeInodeBlocks(inode *structs.Inodos) error {
+	dataBlocks, pointerBlocks, err := fs.InodeBlocks(inode)
+	if err != nil {
+		return err
+	}
+
+	for _, blockIndex := range dataBlocks {
+		if err := fs.FreeBlock(blockIndex); err != nil {
+			return fmt.Errorf("error al liberar bloque %d: %v", blockIndex, err)
+		}
+	}
+
+	for _, blockIndex := range pointerBlocks {
+		if err := fs.FreeBlock(blockIndex); err != nil {
+			return fmt.Errorf("error al liberar bloque de apuntadores %d: %v", blockIndex, err)
+		}
+	}
+
+	for i := range inode.I_block {
+		inode.I_block[i] = -1
+	}
+
+	return nil
+}
+
+// AddEntry - Agregar una entrada en el primer espacio libre del directorio.
+// Si todos los bloques de carpeta están llenos se asigna uno nuevo, directo o indirecto.
+func (fs *FileSystem) AddEntry(dirInodeIndex int64, itemName string, itemInodeIndex int64) error {
+	dirInode, err := fs.ReadInode(dirInodeIndex)
+	if err != nil {
+		return fmt.Errorf("error al leer inodo del directorio: %v", err)
+	}
+
+	if err := fs.checkName(itemName); err != nil {
+		return err
+	}
+
+	dirBlocks, err := fs.DataBlocks(dirInode)
+	if err != nil {
+		return err
+	}
+
+	// Buscar un espacio libre en los bloques existentes
+	for _, blockNum := range dirBlocks {
+		dirBlock, err := fs.ReadFolderBlock(blockNum)
+		if err != nil {
+			return fmt.Errorf("error al leer bloque del directorio: %v", err)
+		}
+
+		for j := range dirBlock.BContent {
+			if dirBlock.BContent[j].BInodo != -1 {
+				continue
+			}
+
+			// Una entrada libre no conserva ninguna cadena de nombre
+			dirBlock.BContent[j].BLong = 0
+			if err := fs.setName(&dirBlock.BContent[j], itemName); err != nil {
+				return err
+			}
+			dirBlock.BContent[j].BInodo = itemInodeIndex
+
+			if err := fs.WriteBlock(blockNum, dirBlock); err != nil {
+				return fmt.Errorf("error al escribir bloque del directorio: %v", err)
+			}
+
+			// Las entradas son el contenido de la carpeta
+			touchModify(dirInode)
+			if err := fs.WriteInode(dirInodeIndex, dirInode); err != nil {
+				return fmt.Errorf("error al actualizar inodo del directorio: %v", err)
+			}
+			return nil
+		}
+	}
+
+	// Todos los bloques están llenos: asignar el siguiente bloque lógico.
+	// len(dirBlocks) es ese bloque porque una carpeta no tiene huecos:
+	// RemoveEntry deja la entrada vacía pero nunca libera el bloque de
+	// carpeta, así que los bloques asignados son siempre 0..len-1. Si fsck
+	// quitó un apuntador inválido sí puede quedar un hueco; entonces BlockFor
+	// devuelve un bloque que ya es de la carpeta y se pasa al siguiente para
+	// no sobrescribir sus entradas.
+	used := make(map[int64]bool, len(dirBlocks))
+	for _, blockNum := range dirBlocks {
+		used[blockNum] = true
+	}
+	var newBlockIndex int64
+	for logicalIndex := int64(len(dirBlocks)); ; logicalIndex++ {
+		newBlockIndex, err = fs.BlockFor(dirInode, logicalIndex)
+		if err != nil {
+			return fmt.Errorf("no se pudo agregar la entrada al directorio: %w", err)
+		}
+		if !used[newBlockIndex] {
+			break
+		}
+	}
+
+	dirBlock := fs.newFolderBlock()
+	if err := fs.setName(&dirBlock.BContent[0], itemName); err != nil {
+		return err
+	}
+	dirBlock.BContent[0].BInodo = itemInodeIndex
+
+	if err := fs.WriteBlock(newBlockIndex, &dirBlock); err != nil {
+		return fmt.Errorf("error al escribir nuevo bloque del directorio: %v", err)
+	}
+
+	// Actualizar el inodo del directorio
+	touchModify(dirInode)
+	if err := fs.WriteInode(dirInodeIndex, dirInode); err != nil {
+		return fmt.Errorf("error al actualizar inodo del directorio: %v", err)
+	}
+
+	return nil
+}
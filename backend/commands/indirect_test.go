@@ -0,0 +1,254 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// Pruebas de bloques indirectos: archivos que cruzan cada nivel de indirección
+// se leen completos, ocupan los bloques que calcula blocksForSize y los
+// devuelven todos al eliminarse.
+
+// freeBlocksOf - S_free_blocks_count actual de la imagen
+func freeBlocksOf(t *testing.T, img *crashImage) int64 {
+	t.Helper()
+	fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	return fs.SB.S_free_blocks_count
+}
+
+func TestFileCrossesIndirectLevels(t *testing.T) {
+	img := newCrashImage(t)
+	if _, err := ExecuteMkdir(img.session, "/big", false); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	geometry := fs.geometry()
+	fs.Close()
+	p := int64(geometry.Pointers)
+	data := int64(geometry.Data)
+
+	// Cantidades de bloques de datos justo antes y después de cada límite
+	single := int64(directBlocksCount)
+	double := single + p
+	triple := double + p*p
+	counts := []int64{
+		single, single + 1,
+		double, double + 1,
+		triple, triple + 1,
+		triple + p + 1,   // segundo bloque del tercer nivel
+		triple + p*p + 1, // segundo bloque del segundo nivel
+	}
+
+	for _, count := range counts {
+		for _, size := range []int64{count * data, count*data - data/2} {
+			t.Run(fmt.Sprintf("%d bytes", size), func(t *testing.T) {
+				path := fmt.Sprintf("/big/f%d", size)
+				before := freeBlocksOf(t, img)
+
+				if _, err := ExecuteMkfile(img.session, path, false, int(size), ""); err != nil {
+					t.Fatalf("mkfile: %v", err)
+				}
+
+				fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, inode, err := fs.Lookup(path)
+				if err != nil {
+					fs.Close()
+					t.Fatalf("lookup: %v", err)
+				}
+				dataBlocks, pointerBlocks, err := fs.InodeBlocks(inode)
+				expected := fs.blocksForSize(size)
+				fs.Close()
+				if err != nil {
+					t.Fatalf("bloques del inodo: %v", err)
+				}
+
+				if int64(len(dataBlocks)) != count {
+					t.Fatalf("bloques de datos: %d, se esperaban %d", len(dataBlocks), count)
+				}
+				if used := int64(len(dataBlocks) + len(pointerBlocks)); used != expected {
+					t.Fatalf("el inodo usa %d bloques y blocksForSize calcula %d", used, expected)
+				}
+				if used := before - freeBlocksOf(t, img); used != expected {
+					t.Fatalf("se asignaron %d bloques, se esperaban %d", used, expected)
+				}
+
+				res, err := ReadFileByPath(img.mounted, path)
+				if err != nil {
+					t.Fatalf("leer: %v", err)
+				}
+				content, _ := res.Data["content"].([]byte)
+				want := make([]byte, size)
+				for i := range want {
+					want[i] = byte('0' + i%10)
+				}
+				if !bytes.Equal(content, want) {
+					t.Fatalf("el contenido leído (%d bytes) no coincide con el escrito", len(content))
+				}
+
+				if _, err := ExecuteRemove(img.session, path); err != nil {
+					t.Fatalf("remove: %v", err)
+				}
+				if after := freeBlocksOf(t, img); after != before {
+					t.Fatalf("tras eliminar quedan %d bloques libres, había %d", after, before)
+				}
+				img.checkClean(t, path)
+			})
+		}
+	}
+}
+
+// dataBlocksOf - Bloques de datos de un archivo o carpeta
+func dataBlocksOf(t *testing.T, img *crashImage, path string) []int64 {
+	t.Helper()
+	fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	_, inode, err := fs.Lookup(path)
+	if err != nil {
+		t.Fatalf("lookup %s: %v", path, err)
+	}
+	blocks, err := fs.DataBlocks(inode)
+	if err != nil {
+		t.Fatalf("bloques de %s: %v", path, err)
+	}
+	return blocks
+}
+
+// checkEntries - Verificar que cada nombre se encuentre en la carpeta
+func checkEntries(t *testing.T, img *crashImage, dir string, names []string) {
+	t.Helper()
+	fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	for _, name := range names {
+		if _, _, err := fs.Lookup(dir + "/" + name); err != nil {
+			t.Fatalf("no se encontró '%s': %v", name, err)
+		}
+	}
+}
+
+func TestDirectoryGrowsPastDirectBlocks(t *testing.T) {
+	img := newCrashImageFS(t, "2fs")
+	if _, err := ExecuteMkdir(img.session, "/many", false); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	geometry := fs.geometry()
+	fs.Close()
+	entries := geometry.Entries
+	p := geometry.Pointers
+
+	// Llenar los directos y el indirecto simple, y entrar al doble
+	// ('.' y '..' ocupan dos entradas del primer bloque)
+	total := directBlocksCount*entries - 2 + p*entries + entries + 1
+	var names []string
+	for i := 0; i < total; i++ {
+		name := fmt.Sprintf("f%03d", i)
+		if _, err := ExecuteMkfile(img.session, "/many/"+name, false, 0, ""); err != nil {
+			t.Fatalf("mkfile %s: %v", name, err)
+		}
+		names = append(names, name)
+	}
+
+	blocks := dataBlocksOf(t, img, "/many")
+	if want := (total + 2 + entries - 1) / entries; len(blocks) != want {
+		t.Fatalf("la carpeta usa %d bloques, se esperaban %d", len(blocks), want)
+	}
+	checkEntries(t, img, "/many", names)
+	img.checkClean(t, "carpeta llena")
+
+	// Las entradas que se quitan dejan huecos que se reutilizan sin bloques nuevos
+	var kept, added []string
+	for i, name := range names {
+		if i%2 == 1 {
+			kept = append(kept, name)
+			continue
+		}
+		if _, err := ExecuteRemove(img.session, "/many/"+name); err != nil {
+			t.Fatalf("remove %s: %v", name, err)
+		}
+	}
+	for i := 0; i < total/2; i++ {
+		name := fmt.Sprintf("g%03d", i)
+		if _, err := ExecuteMkfile(img.session, "/many/"+name, false, 0, ""); err != nil {
+			t.Fatalf("mkfile %s: %v", name, err)
+		}
+		added = append(added, name)
+	}
+	if again := dataBlocksOf(t, img, "/many"); len(again) != len(blocks) {
+		t.Fatalf("al reutilizar entradas la carpeta pasó de %d a %d bloques", len(blocks), len(again))
+	}
+	checkEntries(t, img, "/many", append(kept, added...))
+	img.checkClean(t, "entradas reutilizadas")
+}
+
+func TestDirectoryWithHoleKeepsEntries(t *testing.T) {
+	img := newCrashImageFS(t, "2fs")
+	if _, err := ExecuteMkdir(img.session, "/many", false); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := fs.geometry().Entries
+	fs.Close()
+
+	// Cuatro bloques de carpeta llenos
+	total := 4*entries - 2
+	var names []string
+	for i := 0; i < total; i++ {
+		name := fmt.Sprintf("f%03d", i)
+		if _, err := ExecuteMkfile(img.session, "/many/"+name, false, 0, ""); err != nil {
+			t.Fatalf("mkfile %s: %v", name, err)
+		}
+		names = append(names, name)
+	}
+
+	// Quitar el apuntador al segundo bloque, como lo haría fsck con uno inválido
+	fs, err = openFileSystem(img.mounted, os.O_RDWR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirIndex, inode, err := fs.Lookup("/many")
+	if err != nil {
+		fs.Close()
+		t.Fatal(err)
+	}
+	inode.I_block[1] = -1
+	err = fs.WriteInode(dirIndex, inode)
+	fs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	survivors := append(append([]string{}, names[:entries-2]...), names[2*entries-2:]...)
+
+	// La entrada nueva no debe caer sobre un bloque que ya es de la carpeta
+	if _, err := ExecuteMkfile(img.session, "/many/nuevo", false, 0, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	checkEntries(t, img, "/many", append(survivors, "nuevo"))
+	img.checkRepaired(t, "carpeta con hueco")
+}
@@ -12,18 +12,19 @@ import (
 	"unicode/utf8"
 )
 
-// WriteJournal escribe una entrada al journaling
-func WriteJournal(mounted *MountedPartition, operation, path, content string) error {
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+// WriteJournal escribe una entrada al journaling; los avisos van a la salida de res
+func WriteJournal(res *CommandResult, mounted *MountedPartition, operation, path, content string) error {
+	fs, err := openFileSystem(mounted, os.O_RDWR)
 	if err != nil {
-		return fmt.Errorf("error al abrir el disco: %v", err)
+		return err
 	}
-	defer file.Close()
+	defer fs.Close()
+	file, superblock := fs.file, fs.SB
 
-	// Obtener partición y superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
-	if err != nil {
-		return err
+	// EXT2 no reserva espacio para el journaling: escribir antes del bitmap de
+	// inodos pisaría el superbloque y los bitmaps.
+	if superblock.S_file_system_type != 3 {
+		return nil
 	}
 
 	// Calcular posición inicial del journaling y cantidad de entradas.
@@ -55,7 +56,7 @@ func WriteJournal(mounted *MountedPartition, operation, path, content string) er
 	if freeIndex == -1 {
 		// Si el journal está lleno, sobrescribir la entrada más antigua (índice 0)
 		freeIndex = 0
-		fmt.Println("⚠️ Journal lleno, sobrescribiendo entrada más antigua")
+		res.Println("⚠️ Journal lleno, sobrescribiendo entrada más antigua")
 	}
 
 	// Crear nueva entrada de journal
@@ -84,7 +85,7 @@ func WriteJournal(mounted *MountedPartition, operation, path, content string) er
 		return fmt.Errorf("error al escribir journal: %v", err)
 	}
 
-	fmt.Printf("✅ Journal escrito: %s en %s (índice %d)\n", operation, path, freeIndex)
+	res.Printf("✅ Journal escrito: %s en %s (índice %d)\n", operation, path, freeIndex)
 	return nil
 }
 
@@ -95,21 +96,19 @@ func RepairJournal(mounted *MountedPartition) (int, error) {
 	if mounted == nil {
 		return 0, errors.New("mounted partition is nil")
 	}
-	f, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+	defer lockDisk(mounted.Path)()
+	fs, err := openFileSystem(mounted, os.O_RDWR)
 	if err != nil {
 		return 0, fmt.Errorf("open partition file: %w", err)
 	}
-	defer f.Close()
+	defer fs.Close()
+	f, sb := fs.file, fs.SB
 
 	// prepare sizes
 	journalSize := int64(binary.Size(structs.Journal{}))
 	preferredCount := int64(50)
 
 	// read superblock from partition start
-	sb, err := readSuperBlockMixed(f, mounted.Start)
-	if err != nil {
-		return 0, fmt.Errorf("leer superbloque: %w", err)
-	}
 
 	// candidate starts: older code used a different start (using S_inode_start)
 	cand1Start := sb.S_inode_start - (64 * journalSize) // older layout (64 entries)
@@ -288,58 +287,23 @@ func isPrintableUTF8(s string) bool {
 	return true
 }
 
-// ClearJournal limpia todas las entradas del journaling (comando loss)
-func ClearJournal(mounted *MountedPartition) error {
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
-	if err != nil {
-		return fmt.Errorf("error al abrir el disco: %v", err)
-	}
-	defer file.Close()
-
-	// Obtener partición y superbloque
-	_, superblock, err := getPartitionAndSuperblock(file, mounted)
-	if err != nil {
-		return err
-	}
-
-	// Calcular posición inicial del journaling
-	journalStart := superblock.S_inode_start - int64(binary.Size(structs.Journal{})*64)
-
-	// Limpiar todas las entradas
-	var emptyJournal structs.Journal
-	for i := 0; i < 64; i++ {
-		journalPosition := journalStart + int64(i*binary.Size(structs.Journal{}))
-		file.Seek(journalPosition, 0)
-
-		if err := binary.Write(file, binary.LittleEndian, &emptyJournal); err != nil {
-			return fmt.Errorf("error al limpiar journal %d: %v", i, err)
-		}
-	}
-
-	fmt.Println("✅ Journaling limpiado completamente")
-	return nil
-}
-
 // DumpJournalRegions devuelve los bytes raw de las regiones candidatas del journal
 // en formato bruto para diagnóstico. Devuelve un mapa con claves "preferred" y "candidate1".
 func DumpJournalRegions(mounted *MountedPartition) (map[string][]byte, error) {
 	if mounted == nil {
 		return nil, fmt.Errorf("mounted partition is nil")
 	}
-	f, err := os.OpenFile(mounted.Path, os.O_RDONLY, 0)
+	defer rlockDisk(mounted.Path)()
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
 	if err != nil {
 		return nil, fmt.Errorf("open partition file: %w", err)
 	}
-	defer f.Close()
+	defer fs.Close()
+	f, sb := fs.file, fs.SB
 
 	journalSize := int64(binary.Size(structs.Journal{}))
 	preferredCount := int64(50)
 
-	sb, err := readSuperBlockMixed(f, mounted.Start)
-	if err != nil {
-		return nil, fmt.Errorf("leer superbloque: %w", err)
-	}
-
 	cand1Start := sb.S_inode_start - (64 * journalSize)
 	cand1Count := int64(64)
 	prefStart := sb.S_bm_inode_start - (preferredCount * journalSize)
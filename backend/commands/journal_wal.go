@@ -0,0 +1,721 @@
+package commands
+
+import (
+	"backend/structs"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// JOURNAL DE ESCRITURA ANTICIPADA (EXT3)
+// Los comandos que modifican una partición EXT3 abren una transacción. Cada
+// escritura que pasa por los FileSystem de la transacción queda registrada
+// (walOverlay):
+//  1. Lo que se escribe en bloques que estaban libres al empezar va directo al
+//     disco: nada de lo confirmado los referencia todavía.
+//  2. El resto (superbloque, bitmaps, inodos, bloques en uso y la bitácora de
+//     operaciones) se guarda en memoria, por páginas, y las lecturas de la
+//     transacción lo ven.
+// Al confirmar, las páginas se comparan con el disco. Los bloques de datos de
+// archivos reescritos en su lugar se escriben directo (modo "ordered" de
+// ext3); los demás cambios se escriben en el journal, se confirman y se
+// aplican al disco (checkpoint), y el journal queda vacío.
+// Si el proceso se interrumpe, al montar (o con recovery) se rehace una
+// transacción confirmada y se descarta una que no alcanzó a confirmarse.
+
+const (
+	walAreaSize  = 64 * 1024  // Bytes que reserva mkfs para el journal
+	walMagic     = 0xEF530001 // Firma de la cabecera del journal
+	walPageSize  = 512        // Granularidad con la que la transacción guarda lo escrito
+	walDiffChunk = 64         // Granularidad con la que se compara con el disco al confirmar
+
+	walStateEmpty     = 0
+	walStateOpen      = 1
+	walStateCommitted = 2
+)
+
+// walLayout - Ubicación del journal dentro de la partición
+type walLayout struct {
+	partitionStart int64
+	start          int64
+	size           int64
+}
+
+// walChange - Rango del disco modificado por una transacción
+type walChange struct {
+	offset int64
+	data   []byte
+}
+
+// walRecovery - Resultado de revisar el journal de una partición
+type walRecovery struct {
+	Present   bool  // La partición tiene un journal válido
+	Replayed  bool  // Se rehízo una transacción confirmada
+	Discarded bool  // Se descartó una transacción sin confirmar
+	Sequence  int64 // Número de la transacción rehecha o descartada
+	Records   int   // Registros aplicados
+}
+
+// walTransaction - Transacción abierta sobre una partición montada
+type walTransaction struct {
+	mounted     *MountedPartition
+	journal     walLayout
+	overlay     *walOverlay // Escrituras pendientes (nil en passthrough)
+	passthrough bool        // EXT2 o partición sin journal: se escribe directo al disco
+	unlock      func()      // Libera el bloqueo exclusivo del disco
+	done        bool
+}
+
+// Transacciones abiertas por ID de partición. Cada una tiene tomado el bloqueo
+// exclusivo de su disco, así que hay a lo sumo una por disco.
+var (
+	transactionsMutex  sync.Mutex
+	activeTransactions = make(map[string]*walTransaction)
+)
+
+// walLayoutFor - Calcular la ubicación del journal. Las particiones EXT2 y las
+// EXT3 formateadas sin espacio para el journal no tienen uno.
+func walLayoutFor(partitionStart int64, superblock *structs.SuperBloque) (walLayout, bool) {
+	const journalingCount = 50
+
+	if superblock.S_file_system_type != 3 {
+		return walLayout{}, false
+	}
+
+	start := partitionStart + int64(binary.Size(structs.SuperBloque{}))
+	end := superblock.S_bm_inode_start - (journalingCount * int64(binary.Size(structs.Journal{})))
+	if end-start < int64(binary.Size(structs.WalHeader{})) {
+		return walLayout{}, false
+	}
+
+	return walLayout{partitionStart: partitionStart, start: start, size: end - start}, true
+}
+
+// openMountedDisk - Abrir el disco de una partición montada. Si hay una
+// transacción abierta sobre la partición, el disco lee y escribe a través de ella.
+func openMountedDisk(mounted *MountedPartition, flag int) (*diskFile, error) {
+	var overlay *walOverlay
+	transactionsMutex.Lock()
+	if tx, open := activeTransactions[strings.ToLower(mounted.ID)]; open {
+		overlay = tx.overlay
+	}
+	transactionsMutex.Unlock()
+
+	file, err := openDisk(mounted.Path, flag)
+	if err != nil {
+		return nil, err
+	}
+	file.overlay = overlay
+	return file, nil
+}
+
+// beginTransaction - Abrir una transacción sobre la partición montada. Hasta
+// que se confirme, lo que se escriba con openMountedDisk queda registrado en ella.
+// La transacción tiene el bloqueo exclusivo del disco hasta confirmarse o
+// descartarse, por lo que no se puede abrir otra dentro del mismo comando.
+func beginTransaction(mounted *MountedPartition) (*walTransaction, error) {
+	unlock := lockDisk(mounted.Path)
+	tx, err := openTransaction(mounted)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	tx.unlock = unlock
+	return tx, nil
+}
+
+// openTransaction - Preparar el registro de escrituras (con el disco ya bloqueado)
+func openTransaction(mounted *MountedPartition) (*walTransaction, error) {
+	disk, err := openDisk(mounted.Path, os.O_RDWR)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir el disco: %v", err)
+	}
+	defer disk.Close()
+
+	// Sin superbloque válido el comando reportará su propio error
+	fs, err := mountedFileSystem(disk, mounted)
+	if err != nil {
+		return &walTransaction{mounted: mounted, passthrough: true}, nil
+	}
+	layout, ok := walLayoutFor(fs.Partition.Start, fs.SB)
+	if !ok {
+		return &walTransaction{mounted: mounted, passthrough: true}, nil
+	}
+
+	// Terminar una transacción confirmada que no se alcanzó a aplicar
+	if _, err := replayJournal(disk, layout); err != nil {
+		return nil, err
+	}
+
+	tx := &walTransaction{
+		mounted: mounted,
+		journal: layout,
+		overlay: newWalOverlay(fs.SB),
+	}
+	transactionsMutex.Lock()
+	activeTransactions[strings.ToLower(mounted.ID)] = tx
+	transactionsMutex.Unlock()
+
+	return tx, nil
+}
+
+// rollback - Descartar la transacción y liberar el disco (sin efecto si ya se
+// confirmó). Lo escrito directo quedó en bloques que siguen libres.
+func (tx *walTransaction) rollback() {
+	if tx == nil || tx.done {
+		return
+	}
+	tx.done = true
+	if !tx.passthrough {
+		transactionsMutex.Lock()
+		delete(activeTransactions, strings.ToLower(tx.mounted.ID))
+		transactionsMutex.Unlock()
+	}
+	if tx.unlock != nil {
+		tx.unlock()
+	}
+}
+
+// commit - Registrar los cambios en el journal, confirmarlos y aplicarlos al disco
+func (tx *walTransaction) commit() error {
+	if tx.passthrough || tx.done {
+		return nil
+	}
+	defer tx.rollback()
+
+	disk, err := openDisk(tx.mounted.Path, os.O_RDWR)
+	if err != nil {
+		return fmt.Errorf("error al abrir el disco: %v", err)
+	}
+	defer disk.Close()
+
+	changes, ordered, err := tx.overlay.changes(disk)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 && len(ordered) == 0 {
+		return nil
+	}
+
+	records, err := encodeWalRecords(changes)
+	if err != nil {
+		return err
+	}
+	if err := checkWalCapacity(tx.journal, records); err != nil {
+		return err
+	}
+
+	// PASO 1: Bloques de datos, antes de confirmar los metadatos que los
+	// referencian (los escritos directo durante la transacción también se
+	// sincronizan aquí)
+	if err := writeWalChanges(disk, ordered); err != nil {
+		return err
+	}
+	if err := disk.Sync(); err != nil {
+		return fmt.Errorf("error al sincronizar el disco: %v", err)
+	}
+
+	// PASO 2: Escribir y confirmar la transacción en el journal
+	header, err := commitWalRecords(disk, tx.journal, records, len(changes))
+	if err != nil {
+		return err
+	}
+
+	// PASO 3: Checkpoint
+	return checkpointJournal(disk, tx.journal, &header, changes)
+}
+
+// checkWalCapacity - Verificar que los registros de una transacción quepan en el journal
+func checkWalCapacity(layout walLayout, records []byte) error {
+	headerSize := int64(binary.Size(structs.WalHeader{}))
+	if headerSize+int64(len(records)) > layout.size {
+		return newCommandError(ErrCodeNoSpace, "la transacción ocupa %d bytes y el journal solo tiene %d",
+			headerSize+int64(len(records)), layout.size)
+	}
+	return nil
+}
+
+// commitWalRecords - Escribir los registros en el journal y marcarlos como
+// confirmados. Desde ahí un replay los aplica aunque el proceso se interrumpa.
+func commitWalRecords(disk *diskFile, layout walLayout, records []byte, count int) (structs.WalHeader, error) {
+	headerSize := int64(binary.Size(structs.WalHeader{}))
+	previous, _ := readWalHeader(disk, layout)
+	header := structs.WalHeader{
+		WMagic:    walMagic,
+		WSequence: previous.WSequence + 1,
+		WState:    walStateOpen,
+		WCount:    int64(count),
+		WLength:   int64(len(records)),
+		WChecksum: crc32.ChecksumIEEE(records),
+	}
+	if err := writeWalHeader(disk, layout, &header); err != nil {
+		return header, err
+	}
+	if _, err := disk.WriteAt(records, layout.start+headerSize); err != nil {
+		return header, fmt.Errorf("error al escribir el journal: %v", err)
+	}
+	if err := disk.Sync(); err != nil {
+		return header, fmt.Errorf("error al sincronizar el journal: %v", err)
+	}
+
+	header.WState = walStateCommitted
+	if err := writeWalHeader(disk, layout, &header); err != nil {
+		return header, err
+	}
+	if err := disk.Sync(); err != nil {
+		return header, fmt.Errorf("error al sincronizar el journal: %v", err)
+	}
+	return header, nil
+}
+
+// walOverlay - Escrituras de una transacción sobre el área de bloques
+// [blockStart, blockStart+blockCount*blockSize) y el resto de la partición
+type walOverlay struct {
+	mu          sync.Mutex
+	bitmapStart int64 // Bitmap de bloques
+	blockStart  int64
+	blockSize   int64
+	blockCount  int64
+	pages       map[int64][]byte // Páginas escritas (número → contenido completo)
+	freed       map[int64]bool   // Bloques en uso al empezar que la transacción liberó
+	fileData    map[int64]bool   // Bloques escritos como datos de un archivo
+}
+
+// newWalOverlay - Registro vacío para el sistema de archivos del superbloque
+func newWalOverlay(superblock *structs.SuperBloque) *walOverlay {
+	return &walOverlay{
+		bitmapStart: superblock.S_bm_block_start,
+		blockStart:  superblock.S_block_start,
+		blockSize:   superblock.S_block_s,
+		blockCount:  superblock.S_blocks_count,
+		pages:       make(map[int64][]byte),
+		freed:       make(map[int64]bool),
+		fileData:    make(map[int64]bool),
+	}
+}
+
+// blockAt - Bloque que contiene la posición (-1 fuera del área de bloques)
+func (o *walOverlay) blockAt(position int64) int64 {
+	if position < o.blockStart {
+		return -1
+	}
+	index := (position - o.blockStart) / o.blockSize
+	if index >= o.blockCount {
+		return -1
+	}
+	return index
+}
+
+// pieceEnd - Fin del tramo que empieza en position y termina antes de end sin
+// cruzar el límite de un bloque (ni el inicio del área de bloques)
+func (o *walOverlay) pieceEnd(position, end int64) int64 {
+	if block := o.blockAt(position); block >= 0 {
+		if blockEnd := o.blockStart + (block+1)*o.blockSize; blockEnd < end {
+			return blockEnd
+		}
+	} else if position < o.blockStart && end > o.blockStart {
+		return o.blockStart
+	}
+	return end
+}
+
+// committedBitmap - Estado de los bloques [first, first+count) en el disco. El
+// bitmap del disco es el confirmado: sus cambios quedan en memoria hasta confirmar.
+func (o *walOverlay) committedBitmap(file *os.File, first, count int64) ([]byte, error) {
+	states := make([]byte, count)
+	if _, err := file.ReadAt(states, o.bitmapStart+first); err != nil {
+		return nil, fmt.Errorf("error al leer el bitmap de bloques: %v", err)
+	}
+	return states, nil
+}
+
+// read - Reemplazar en p (leído del disco desde offset) lo que escribió la transacción
+func (o *walOverlay) read(p []byte, offset int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(p) == 0 || len(o.pages) == 0 {
+		return
+	}
+
+	first, last := offset/walPageSize, (offset+int64(len(p))-1)/walPageSize
+	if last-first+1 > int64(len(o.pages)) {
+		for page, data := range o.pages {
+			if page >= first && page <= last {
+				copyPage(p, offset, page, data, false)
+			}
+		}
+		return
+	}
+	for page := first; page <= last; page++ {
+		if data, ok := o.pages[page]; ok {
+			copyPage(p, offset, page, data, false)
+		}
+	}
+}
+
+// write - Registrar una escritura. Los tramos en bloques libres al empezar la
+// transacción se escriben directo en el disco.
+func (o *walOverlay) write(d *diskFile, p []byte, offset int64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for len(p) > 0 {
+		end := o.pieceEnd(offset, offset+int64(len(p)))
+		piece := p[:end-offset]
+
+		direct := false
+		if block := o.blockAt(offset); block >= 0 {
+			state, err := o.committedBitmap(d.file, block, 1)
+			if err != nil {
+				return err
+			}
+			direct = state[0] == 0
+		}
+
+		if direct {
+			if _, err := d.writeDiskAt(piece, offset); err != nil {
+				return err
+			}
+			o.update(piece, offset)
+		} else {
+			if err := o.store(d.file, piece, offset); err != nil {
+				return err
+			}
+			if err := o.trackFreed(d.file, piece, offset); err != nil {
+				return err
+			}
+		}
+
+		p = p[len(piece):]
+		offset = end
+	}
+	return nil
+}
+
+// store - Copiar una escritura en sus páginas, leyendo del disco las que aún no estén
+func (o *walOverlay) store(file *os.File, p []byte, offset int64) error {
+	for page := offset / walPageSize; page*walPageSize < offset+int64(len(p)); page++ {
+		data, ok := o.pages[page]
+		if !ok {
+			data = make([]byte, walPageSize)
+			if _, err := file.ReadAt(data, page*walPageSize); err != nil && err != io.EOF {
+				return fmt.Errorf("error al leer el disco: %v", err)
+			}
+			o.pages[page] = data
+		}
+		copyPage(p, offset, page, data, true)
+	}
+	return nil
+}
+
+// update - Actualizar las páginas ya registradas con una escritura directa
+func (o *walOverlay) update(p []byte, offset int64) {
+	for page := offset / walPageSize; page*walPageSize < offset+int64(len(p)); page++ {
+		if data, ok := o.pages[page]; ok {
+			copyPage(p, offset, page, data, true)
+		}
+	}
+}
+
+// trackFreed - Anotar los bloques en uso al empezar que una escritura del
+// bitmap de bloques marca como libres
+func (o *walOverlay) trackFreed(file *os.File, p []byte, offset int64) error {
+	first, last := offset-o.bitmapStart, offset+int64(len(p))-o.bitmapStart
+	if first < 0 {
+		first = 0
+	}
+	if last > o.blockCount {
+		last = o.blockCount
+	}
+	if first >= last {
+		return nil
+	}
+
+	states, err := o.committedBitmap(file, first, last-first)
+	if err != nil {
+		return err
+	}
+	for i, state := range states {
+		index := first + int64(i)
+		if state != 0 && p[o.bitmapStart+index-offset] == 0 {
+			o.freed[index] = true
+		}
+	}
+	return nil
+}
+
+// markFileData - Anotar que un bloque se escribió con datos de un archivo
+func (o *walOverlay) markFileData(block int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.fileData[block] = true
+}
+
+// isFreed - Verificar si la transacción liberó un bloque que estaba en uso
+func (o *walOverlay) isFreed(block int64) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.freed[block]
+}
+
+// changes - Comparar las páginas con el disco. Devuelve los cambios que deben
+// pasar por el journal y los de bloques de datos de archivos reescritos en su
+// lugar, que se escriben directo antes de confirmar. Un bloque liberado y
+// vuelto a usar en la misma transacción pasa por el journal: hasta confirmar,
+// su contenido anterior sigue siendo el válido.
+func (o *walOverlay) changes(disk *diskFile) ([]walChange, []walChange, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	pages := make([]int64, 0, len(o.pages))
+	for page := range o.pages {
+		pages = append(pages, page)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i] < pages[j] })
+
+	var changes, ordered []walChange
+	current := make([]byte, walPageSize)
+	for _, page := range pages {
+		start := page * walPageSize
+		clear(current)
+		if _, err := disk.ReadAt(current, start); err != nil && err != io.EOF {
+			return nil, nil, fmt.Errorf("error al leer el disco: %v", err)
+		}
+
+		data := o.pages[page]
+		for unit := int64(0); unit < walPageSize; unit += walDiffChunk {
+			if bytes.Equal(current[unit:unit+walDiffChunk], data[unit:unit+walDiffChunk]) {
+				continue
+			}
+			for position, end := start+unit, start+unit+walDiffChunk; position < end; {
+				pieceEnd := o.pieceEnd(position, end)
+				target := &changes
+				if block := o.blockAt(position); block >= 0 && o.fileData[block] && !o.freed[block] {
+					target = &ordered
+				}
+				appendChange(target, position, data[position-start:pieceEnd-start])
+				position = pieceEnd
+			}
+		}
+	}
+	return changes, ordered, nil
+}
+
+// copyPage - Copiar la parte común entre p (en offset) y una página; toPage
+// indica el sentido
+func copyPage(p []byte, offset int64, page int64, data []byte, toPage bool) {
+	pageStart := page * walPageSize
+	start, end := offset, offset+int64(len(p))
+	if pageStart > start {
+		start = pageStart
+	}
+	if pageStart+walPageSize < end {
+		end = pageStart + walPageSize
+	}
+	if start >= end {
+		return
+	}
+	if toPage {
+		copy(data[start-pageStart:end-pageStart], p[start-offset:end-offset])
+	} else {
+		copy(p[start-offset:end-offset], data[start-pageStart:end-pageStart])
+	}
+}
+
+// appendChange - Agregar un cambio, uniéndolo al anterior si son contiguos
+func appendChange(target *[]walChange, offset int64, data []byte) {
+	if last := len(*target) - 1; last >= 0 && (*target)[last].offset+int64(len((*target)[last].data)) == offset {
+		(*target)[last].data = append((*target)[last].data, data...)
+		return
+	}
+	*target = append(*target, walChange{offset: offset, data: append([]byte(nil), data...)})
+}
+
+// encodeWalRecords - Serializar los cambios como registros del journal
+func encodeWalRecords(changes []walChange) ([]byte, error) {
+	var buffer bytes.Buffer
+	for _, change := range changes {
+		record := structs.WalRecord{WOffset: change.offset, WLength: int64(len(change.data))}
+		if err := binary.Write(&buffer, binary.LittleEndian, &record); err != nil {
+			return nil, fmt.Errorf("error al serializar el journal: %v", err)
+		}
+		buffer.Write(change.data)
+	}
+	return buffer.Bytes(), nil
+}
+
+// decodeWalRecords - Leer los registros de una transacción del journal
+func decodeWalRecords(records []byte, count int64) ([]walChange, error) {
+	reader := bytes.NewReader(records)
+	changes := make([]walChange, 0, count)
+	for i := int64(0); i < count; i++ {
+		var record structs.WalRecord
+		if err := binary.Read(reader, binary.LittleEndian, &record); err != nil {
+			return nil, fmt.Errorf("registro %d del journal incompleto: %v", i, err)
+		}
+		if record.WLength < 0 || record.WLength > int64(reader.Len()) {
+			return nil, fmt.Errorf("registro %d del journal con tamaño inválido: %d", i, record.WLength)
+		}
+		data := make([]byte, record.WLength)
+		reader.Read(data)
+		changes = append(changes, walChange{offset: record.WOffset, data: data})
+	}
+	return changes, nil
+}
+
+// writeWalChanges - Escribir los cambios en sus posiciones del disco
+func writeWalChanges(file *diskFile, changes []walChange) error {
+	for _, change := range changes {
+		if _, err := file.WriteAt(change.data, change.offset); err != nil {
+			return fmt.Errorf("error al escribir en la posición %d: %v", change.offset, err)
+		}
+	}
+	return nil
+}
+
+// readWalHeader - Leer la cabecera del journal
+func readWalHeader(file *diskFile, layout walLayout) (structs.WalHeader, error) {
+	var header structs.WalHeader
+	file.Seek(layout.start, 0)
+	if err := binary.Read(file, binary.LittleEndian, &header); err != nil {
+		return structs.WalHeader{}, fmt.Errorf("error al leer la cabecera del journal: %v", err)
+	}
+	if header.WMagic != walMagic {
+		return structs.WalHeader{}, fmt.Errorf("el área del journal no contiene un journal válido")
+	}
+	return header, nil
+}
+
+// writeWalHeader - Escribir la cabecera del journal
+func writeWalHeader(file *diskFile, layout walLayout, header *structs.WalHeader) error {
+	file.Seek(layout.start, 0)
+	if err := binary.Write(file, binary.LittleEndian, header); err != nil {
+		return fmt.Errorf("error al escribir la cabecera del journal: %v", err)
+	}
+	return nil
+}
+
+// checkpointJournal - Aplicar una transacción confirmada y vaciar el journal
+func checkpointJournal(file *diskFile, layout walLayout, header *structs.WalHeader, changes []walChange) error {
+	if err := writeWalChanges(file, changes); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("error al sincronizar el disco: %v", err)
+	}
+
+	header.WState = walStateEmpty
+	if err := writeWalHeader(file, layout, header); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// replayJournal - Rehacer la transacción confirmada pendiente o descartar la
+// que quedó abierta. Como cada transacción se aplica al confirmarse, a lo sumo
+// hay una pendiente.
+func replayJournal(file *diskFile, layout walLayout) (walRecovery, error) {
+	header, err := readWalHeader(file, layout)
+	if err != nil {
+		return walRecovery{}, nil
+	}
+
+	recovery := walRecovery{Present: true, Sequence: header.WSequence}
+	if header.WState == walStateEmpty {
+		return recovery, nil
+	}
+
+	headerSize := int64(binary.Size(structs.WalHeader{}))
+	var changes []walChange
+	committed := header.WState == walStateCommitted &&
+		header.WLength >= 0 && headerSize+header.WLength <= layout.size
+	if committed {
+		records := make([]byte, header.WLength)
+		if _, err := file.ReadAt(records, layout.start+headerSize); err != nil {
+			return recovery, fmt.Errorf("error al leer el journal: %v", err)
+		}
+		// Una transacción con registros corruptos se trata como no confirmada
+		if crc32.ChecksumIEEE(records) == header.WChecksum {
+			changes, err = decodeWalRecords(records, header.WCount)
+		}
+		committed = err == nil && changes != nil
+	}
+
+	if !committed {
+		header.WState = walStateEmpty
+		if err := writeWalHeader(file, layout, &header); err != nil {
+			return recovery, err
+		}
+		recovery.Discarded = true
+		return recovery, file.Sync()
+	}
+
+	if err := checkpointJournal(file, layout, &header, changes); err != nil {
+		return recovery, err
+	}
+	recovery.Replayed = true
+	recovery.Records = len(changes)
+	return recovery, nil
+}
+
+// recoverMountedJournal - Revisar el journal de una partición montada
+func recoverMountedJournal(mounted *MountedPartition) (walRecovery, error) {
+	file, err := openDisk(mounted.Path, os.O_RDWR)
+	if err != nil {
+		return walRecovery{}, fmt.Errorf("error al abrir el disco: %v", err)
+	}
+	defer file.Close()
+
+	fs, err := mountedFileSystem(file, mounted)
+	if err != nil {
+		return walRecovery{}, nil // Partición sin formatear
+	}
+	layout, ok := walLayoutFor(fs.Partition.Start, fs.SB)
+	if !ok {
+		return walRecovery{}, nil
+	}
+
+	return replayJournal(file, layout)
+}
+
+// journalPending - Verificar, sin escribir en el disco, si el journal de una
+// partición montada tiene una transacción sin terminar
+func journalPending(mounted *MountedPartition) (bool, error) {
+	file, err := openDisk(mounted.Path, os.O_RDONLY)
+	if err != nil {
+		return false, fmt.Errorf("error al abrir el disco: %v", err)
+	}
+	defer file.Close()
+
+	fs, err := mountedFileSystem(file, mounted)
+	if err != nil {
+		return false, nil // Partición sin formatear
+	}
+	layout, ok := walLayoutFor(fs.Partition.Start, fs.SB)
+	if !ok {
+		return false, nil
+	}
+	header, err := readWalHeader(file, layout)
+	if err != nil {
+		return false, nil
+	}
+	return header.WState != walStateEmpty, nil
+}
+
+// describeJournalRecovery - Mensaje para el resultado de revisar el journal
+func describeJournalRecovery(recovery walRecovery) string {
+	switch {
+	case recovery.Replayed:
+		return fmt.Sprintf("🔁 Journal: se rehízo la transacción %d (%d registros)", recovery.Sequence, recovery.Records)
+	case recovery.Discarded:
+		return fmt.Sprintf("🧹 Journal: se descartó la transacción %d sin confirmar", recovery.Sequence)
+	default:
+		return ""
+	}
+}
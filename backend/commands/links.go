@@ -0,0 +1,216 @@
+package commands
+
+import (
+	"backend/structs"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ENLACES DUROS Y SIMBÓLICOS
+// Un enlace duro es otra entrada de carpeta hacia el mismo inodo; I_links
+// cuenta cuántas hay y los bloques se liberan al quitar la última. Los inodos
+// de particiones formateadas antes de I_links leen 0, que vale como 1.
+// Un enlace simbólico es un inodo de tipo '2' cuyo contenido es la ruta
+// destino (absoluta, o relativa a la carpeta del enlace). Lookup lo sigue al
+// resolver rutas, con un máximo de saltos para detectar ciclos.
+
+// maxSymlinkDepth - Enlaces simbólicos que se siguen al resolver una ruta
+const maxSymlinkDepth = 8
+
+// linkCount - Enlaces duros de un inodo
+func linkCount(inode *structs.Inodos) int64 {
+	if inode.I_links < 1 {
+		return 1
+	}
+	return inode.I_links
+}
+
+// supportsHardLinks - Verificar si los inodos de la partición guardan I_links
+func (fs *FileSystem) supportsHardLinks() bool {
+	return fs.SB.S_inode_s >= inodeStructSize
+}
+
+// CreateSymlink - Crear un enlace simbólico hacia target dentro de una carpeta
+func (fs *FileSystem) CreateSymlink(parentInodeIndex int64, linkName string, target string, session *Session) (int64, error) {
+	if err := fs.checkName(linkName); err != nil {
+		return -1, err
+	}
+
+	newInodeIndex, err := fs.AllocInode()
+	if err != nil {
+		return -1, fmt.Errorf("no se pudo asignar un inodo: %w", err)
+	}
+
+	linkInode := fs.newInode('2', session)
+	linkInode.I_perm = [3]byte{7, 7, 7} // Los permisos que cuentan son los del destino
+	if err := fs.WriteFile(newInodeIndex, &linkInode, []byte(target)); err != nil {
+		return -1, fmt.Errorf("error al escribir el destino del enlace: %w", err)
+	}
+
+	if err := fs.AddEntry(parentInodeIndex, linkName, newInodeIndex); err != nil {
+		return -1, fmt.Errorf("error al agregar entrada al directorio padre: %w", err)
+	}
+
+	return newInodeIndex, nil
+}
+
+// ReadLink - Ruta destino de un enlace simbólico
+func (fs *FileSystem) ReadLink(inode *structs.Inodos) (string, error) {
+	if inode.I_type != '2' {
+		return "", fmt.Errorf("no es un enlace simbólico")
+	}
+	target, err := fs.ReadFile(inode)
+	if err != nil {
+		return "", err
+	}
+	return string(target), nil
+}
+
+// lookupFrom - Resolver components desde la carpeta dirInodeIndex siguiendo
+// los enlaces simbólicos. followLast indica si también se sigue el último.
+func (fs *FileSystem) lookupFrom(dirInodeIndex int64, components []string, followLast bool, depth int) (int64, error) {
+	current := dirInodeIndex
+	for i, name := range components {
+		next, err := fs.LookupIn(current, name)
+		if err != nil {
+			return -1, fmt.Errorf("no se encontró '%s': %v", name, err)
+		}
+
+		if i < len(components)-1 || followLast {
+			next, err = fs.followLink(current, next, depth)
+			if err != nil {
+				return -1, err
+			}
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// followLink - Si inodeIndex es un enlace simbólico, devolver el inodo al que
+// apunta. Las rutas relativas se resuelven desde dirInodeIndex, la carpeta
+// que contiene el enlace.
+func (fs *FileSystem) followLink(dirInodeIndex int64, inodeIndex int64, depth int) (int64, error) {
+	inode, err := fs.ReadInode(inodeIndex)
+	if err != nil {
+		return -1, err
+	}
+	if inode.I_type != '2' {
+		return inodeIndex, nil
+	}
+
+	if depth >= maxSymlinkDepth {
+		return -1, newCommandError(ErrCodeInvalidArgument, "demasiados niveles de enlaces simbólicos (¿ciclo?)")
+	}
+
+	target, err := fs.ReadLink(inode)
+	if err != nil {
+		return -1, err
+	}
+
+	start := dirInodeIndex
+	if strings.HasPrefix(target, "/") {
+		start = 0
+	}
+	var components []string
+	for _, component := range strings.Split(target, "/") {
+		if component != "" {
+			components = append(components, component)
+		}
+	}
+
+	resolved, err := fs.lookupFrom(start, components, true, depth+1)
+	var loopErr *CommandError
+	if errors.As(err, &loopErr) {
+		return -1, err // El ciclo se reporta una sola vez, sin cada salto
+	}
+	if err != nil {
+		return -1, fmt.Errorf("enlace roto hacia '%s': %v", target, err)
+	}
+	return resolved, nil
+}
+
+// removeEntryWhere - Quitar de una carpeta la primera entrada que cumpla
+// match (sin contar "." ni "..") y devolver su inodo, o -1 si no hay ninguna
+func (fs *FileSystem) removeEntryWhere(dirInodeIndex int64, match func(entry *structs.BContent) bool) (int64, error) {
+	dirInode, err := fs.ReadInode(dirInodeIndex)
+	if err != nil {
+		return -1, err
+	}
+
+	dirBlocks, err := fs.DataBlocks(dirInode)
+	if err != nil {
+		return -1, err
+	}
+
+	for _, blockIndex := range dirBlocks {
+		folderBlock, err := fs.ReadFolderBlock(blockIndex)
+		if err != nil {
+			return -1, err
+		}
+
+		for j := range folderBlock.BContent {
+			entry := &folderBlock.BContent[j]
+			name := entryName(entry)
+			if entry.BInodo == -1 || name == "." || name == ".." || !match(entry) {
+				continue
+			}
+
+			inodeIndex := entry.BInodo
+			if err := fs.releaseName(entry); err != nil {
+				return -1, err
+			}
+			entry.BName = [12]byte{}
+			entry.BInodo = -1
+			if err := fs.WriteBlock(blockIndex, folderBlock); err != nil {
+				return -1, err
+			}
+
+			// Actualizar los tiempos de modificación y cambio de la carpeta
+			touchModify(dirInode)
+			return inodeIndex, fs.WriteInode(dirInodeIndex, dirInode)
+		}
+	}
+
+	return -1, nil
+}
+
+// RemoveEntryByName - Quitar la entrada con el nombre indicado y devolver su
+// inodo. A diferencia de RemoveEntry distingue enlaces duros de una misma carpeta.
+func (fs *FileSystem) RemoveEntryByName(dirInodeIndex int64, name string) (int64, error) {
+	inodeIndex, err := fs.removeEntryWhere(dirInodeIndex, func(entry *structs.BContent) bool {
+		return fs.nameMatches(entry, name)
+	})
+	if err == nil && inodeIndex == -1 {
+		err = fmt.Errorf("no se encontró '%s' en el directorio", name)
+	}
+	return inodeIndex, err
+}
+
+// Unlink - Quitar la entrada de un archivo o enlace y liberar su inodo si era
+// el último enlace duro. Devuelve el inodo.
+func (fs *FileSystem) Unlink(dirInodeIndex int64, name string) (int64, error) {
+	inodeIndex, err := fs.RemoveEntryByName(dirInodeIndex, name)
+	if err != nil {
+		return -1, err
+	}
+	return inodeIndex, fs.DropLink(inodeIndex)
+}
+
+// DropLink - Descontar un enlace duro del inodo; con el último se liberan sus
+// bloques y el inodo
+func (fs *FileSystem) DropLink(inodeIndex int64) error {
+	inode, err := fs.ReadInode(inodeIndex)
+	if err != nil {
+		return err
+	}
+
+	if linkCount(inode) <= 1 {
+		return fs.ReleaseInode(inodeIndex)
+	}
+
+	inode.I_links = linkCount(inode) - 1
+	touchChange(inode)
+	return fs.WriteInode(inodeIndex, inode)
+}
@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ExecuteLn - Crear un enlace duro (o simbólico con -s) en dest hacia target
+func ExecuteLn(session *Session, target string, dest string, symbolic bool) (*CommandResult, error) {
+	res := NewCommandResult("ln")
+
+	// Verificar sesión activa
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
+	}
+
+	// Validar parámetros obligatorios
+	if target == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio para ln")
+	}
+	if dest == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -dest es obligatorio para ln")
+	}
+
+	parsedDest := parsePath(dest)
+	if parsedDest == nil || !parsedDest.IsAbsolute || parsedDest.FileName == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "la ruta del enlace debe ser absoluta: %s", dest)
+	}
+	if !symbolic && !strings.HasPrefix(target, "/") {
+		return res, newCommandError(ErrCodeInvalidArgument, "la ruta destino de un enlace duro debe ser absoluta: %s", target)
+	}
+
+	// Buscar la partición montada de la sesión
+	mounted := GetMountedPartition(session.PartitionID)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
+	}
+
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
+	}
+	defer tx.rollback()
+
+	fs, err := openFileSystem(mounted, os.O_RDWR)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el sistema de archivos")
+	}
+	defer fs.Close()
+
+	// Carpeta donde se crea el enlace
+	parentInode, linkName, err := fs.LookupParent(dest)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeNotFound, err, "no se encontró la carpeta de '%s'", dest)
+	}
+	if err := fs.checkName(linkName); err != nil {
+		return res, err
+	}
+	if _, err := fs.LookupIn(parentInode, linkName); err == nil {
+		return res, newCommandError(ErrCodeAlreadyExists, "ya existe '%s'", dest)
+	}
+
+	// Verificar permisos de escritura en el directorio padre
+	hasPermission, err := fs.CanWrite(parentInode, session)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al verificar permisos")
+	}
+	if !hasPermission {
+		return res, newCommandError(ErrCodePermissionDenied, "sin permisos de escritura en el directorio padre de '%s'", dest)
+	}
+
+	var inodeIndex, links int64
+	if symbolic {
+		// El destino no tiene que existir: el enlace se resuelve al usarlo
+		if err := fs.enforceSessionQuota(session); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al leer las cuotas")
+		}
+		inodeIndex, err = fs.CreateSymlink(parentInode, linkName, target, session)
+		if err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al crear el enlace simbólico '%s'", dest)
+		}
+		links = 1
+	} else {
+		if !fs.supportsHardLinks() {
+			return res, newCommandError(ErrCodeUnsupported, "la partición se formateó sin contador de enlaces; vuelva a ejecutar mkfs para usar enlaces duros")
+		}
+
+		targetIndex, targetInode, err := fs.Lookup(target)
+		if err != nil {
+			return res, wrapCommandError(ErrCodeNotFound, err, "no se encontró '%s'", target)
+		}
+		if targetInode.I_type == '0' {
+			return res, newCommandError(ErrCodeInvalidArgument, "no se permiten enlaces duros a carpetas: '%s'", target)
+		}
+
+		if err := fs.AddEntry(parentInode, linkName, targetIndex); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al agregar la entrada '%s'", dest)
+		}
+		targetInode.I_links = linkCount(targetInode) + 1
+		touchChange(targetInode)
+		if err := fs.WriteInode(targetIndex, targetInode); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el inodo de '%s'", target)
+		}
+		inodeIndex, links = targetIndex, targetInode.I_links
+	}
+
+	// Actualizar el superbloque
+	if err := fs.Flush(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
+	}
+
+	kind := "duro"
+	if symbolic {
+		kind = "simbólico"
+	}
+	if err := WriteJournal(res, mounted, "ln", dest, fmt.Sprintf("%s -> %s", kind, target)); err != nil {
+		res.Printf("⚠️ No se pudo escribir al journal: %v\n", err)
+	}
+
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	if symbolic {
+		res.Printf("✅ Enlace simbólico '%s' -> '%s' creado.\n", dest, target)
+	} else {
+		res.Printf("✅ Enlace duro '%s' -> '%s' creado (inodo %d, %d enlaces).\n", dest, target, inodeIndex, links)
+	}
+	res.AddPath(dest)
+	res.Set("inode", inodeIndex)
+	res.Set("target", target)
+	res.Set("symbolic", symbolic)
+	res.Set("links", links)
+
+	return res, nil
+}
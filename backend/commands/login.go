@@ -1,183 +1,134 @@
 package commands
 
 import (
-	"backend/structs"
-	"encoding/binary"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 )
 
-func ExecuteLogin(user, pass, id string) {
-	// Verificar que no haya sesión activa **real**.
-	// Si hay una sesión temporal creada por el modo script (AllowCommandsWithoutSession)
-	// y dicha sesión es la automática '__auto__', permitimos que el comando login
-	// sobrescriba la sesión para que el script pueda dejar una sesión persistente.
-	if IsSessionActive() {
-		// Obtener la sesión actual para determinar su origen
-		if sess := GetCurrentSession(); sess != nil {
-			// Si la sesión actual no es la sesión automática de scripting, rechazamos
-			if !(AllowCommandsWithoutSession && sess.User == "__auto__") {
-				fmt.Println("Error: Ya existe una sesión activa. Use 'logout' para cerrar la sesión actual.")
-				return
-			}
-			// Si es la sesión automática, permitimos continuar y que StartSession la reemplace.
-		} else {
-			// Por seguridad, si IsSessionActive() devolvió true pero no podemos obtener la sesión,
-			// rechazamos el login.
-			fmt.Println("Error: Ya existe una sesión activa. Use 'logout' para cerrar la sesión actual.")
-			return
-		}
+// ExecuteLogin - Iniciar sesión. Recibe la sesión actual del cliente (nil si no
+// tiene) y devuelve la nueva sesión, o nil si el login falló.
+func ExecuteLogin(current *Session, user, pass, id string) (*Session, *CommandResult, error) {
+	res := NewCommandResult("login")
+
+	// Verificar que no haya sesión activa **real**. La sesión automática de los
+	// scripts HTTP ('__auto__') sí puede ser reemplazada por un login.
+	if IsSessionActive(current) && !IsAutoSession(current) {
+		return nil, res, newCommandError(ErrCodeSessionActive, "ya existe una sesión activa. Use 'logout' para cerrar la sesión actual")
 	}
 
 	// Validar parámetros obligatorios
 	if user == "" {
-		fmt.Println("Error: el parámetro -user es obligatorio para login.")
-		return
+		return nil, res, newCommandError(ErrCodeInvalidArgument, "el parámetro -user es obligatorio para login")
 	}
 
 	if pass == "" {
-		fmt.Println("Error: el parámetro -pass es obligatorio para login.")
-		return
+		return nil, res, newCommandError(ErrCodeInvalidArgument, "el parámetro -pass es obligatorio para login")
 	}
 
 	if id == "" {
-		fmt.Println("Error: el parámetro -id es obligatorio para login.")
-		return
-	}
-
-	// Buscar la partición montada por ID
-	mounted := GetMountedPartition(id)
-	if mounted == nil {
-		fmt.Printf("Error: No se encontró ninguna partición montada con ID '%s'.\n", id)
-		return
+		return nil, res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio para login")
 	}
 
-	// Leer el archivo users.txt del sistema de archivos
-	usersContent, err := readUsersFile(mounted)
+	session, err := AuthenticateUser(res, user, pass, id)
 	if err != nil {
-		fmt.Printf("Error al leer archivo users.txt: %v\n", err)
-		return
-	}
-
-	// Buscar el usuario en users.txt y obtener toda su información
-	userInfo, found := findUserWithInfo(usersContent, user, pass)
-	if !found {
-		fmt.Printf("Error: Usuario '%s' no encontrado o contraseña incorrecta.\n", user)
-		return
+		return nil, res, commandErrorFrom(ErrCodeAuthFailed, err)
 	}
 
-	// Determinar si es usuario root (UID=1 y nombre="root")
-	isRoot := (userInfo.UID == 1 && user == "root")
-
-	// Iniciar sesión con toda la información
-	StartSession(user, userInfo.GroupName, id, userInfo.UID, userInfo.GID, isRoot)
-
-	fmt.Println("✅ Sesión iniciada exitosamente.")
-	fmt.Printf("   👤 Usuario: %s\n", user)
-	fmt.Printf("   👥 Grupo: %s\n", userInfo.GroupName)
-	fmt.Printf("   🆔 UID: %d | GID: %d\n", userInfo.UID, userInfo.GID)
-	fmt.Printf("   📀 Partición: %s\n", id)
+	res.Println("✅ Sesión iniciada exitosamente.")
+	res.Printf("   👤 Usuario: %s\n", session.User)
+	res.Printf("   👥 Grupo: %s\n", session.Group)
+	res.Printf("   🆔 UID: %d | GID: %d\n", session.UID, session.GID)
+	res.Printf("   📀 Partición: %s\n", session.PartitionID)
 
-	if isRoot {
-		fmt.Println("   🔑 Rol: Administrador (root)")
-		fmt.Println("   ⚡ Acceso completo a todos los discos y particiones")
+	if session.IsRoot {
+		res.Println("   🔑 Rol: Administrador (root)")
+		res.Println("   ⚡ Acceso completo a todos los discos y particiones")
 	} else {
-		fmt.Println("   🔑 Rol: Usuario estándar")
-		fmt.Printf("   📂 Acceso limitado a la partición: %s\n", id)
-	}
-}
-
-// Leer el archivo users.txt del sistema de archivos
-func readUsersFile(mounted *MountedPartition) (string, error) {
-	// Abrir el archivo del disco
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
-	if err != nil {
-		return "", fmt.Errorf("error al abrir el disco: %v", err)
+		res.Println("   🔑 Rol: Usuario estándar")
+		res.Printf("   📂 Acceso limitado a la partición: %s\n", session.PartitionID)
 	}
-	defer file.Close()
 
-	// Leer el MBR para obtener información de la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		return "", fmt.Errorf("error al leer el MBR: %v", err)
-	}
+	res.Set("user", session.User)
+	res.Set("group", session.Group)
+	res.Set("partitionId", session.PartitionID)
+	res.Set("isRoot", session.IsRoot)
 
-	// Encontrar la partición específica
-	var partition *structs.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_status != '0' {
-			partitionNameBytes := p.Part_name[:]
-			nullIndex := len(partitionNameBytes)
-			for j, b := range partitionNameBytes {
-				if b == 0 {
-					nullIndex = j
-					break
-				}
-			}
-			partitionName := strings.TrimSpace(string(partitionNameBytes[:nullIndex]))
+	return session, res, nil
+}
 
-			if strings.EqualFold(partitionName, mounted.Name) || partitionName == mounted.Name {
-				partitionCopy := p
-				partition = &partitionCopy
-				break
-			}
-		}
+// AuthenticateUser - Validar credenciales contra users.txt y registrar una
+// sesión nueva. Las advertencias se agregan a la salida de res.
+func AuthenticateUser(res *CommandResult, user, pass, id string) (*Session, error) {
+	// Buscar la partición montada por ID
+	mounted := GetMountedPartition(id)
+	if mounted == nil {
+		return nil, newCommandError(ErrCodeNotMounted, "no se encontró ninguna partición montada con ID '%s'", id)
 	}
 
-	if partition == nil {
-		return "", fmt.Errorf("no se pudo encontrar la partición '%s'", mounted.Name)
+	userInfo, isRoot, err := checkCredentials(res, mounted, user, pass)
+	if err != nil {
+		return nil, err
 	}
 
-	// Leer el superbloque
-	file.Seek(partition.Part_start, 0)
-	var superblock structs.SuperBloque
-	if err := binary.Read(file, binary.LittleEndian, &superblock); err != nil {
-		return "", fmt.Errorf("error al leer el superbloque: %v", err)
-	}
+	// Iniciar sesión con toda la información
+	return StartSession(user, userInfo.GroupName, mounted.ID, userInfo.UID, userInfo.GID, isRoot)
+}
 
-	// Leer el inodo de users.txt (inodo 1)
-	inodePosition := superblock.S_inode_start + (1 * superblock.S_inode_s)
+// checkCredentials - Buscar el usuario en users.txt de la partición y validar
+// su contraseña. Devuelve su información y si es root.
+func checkCredentials(res *CommandResult, mounted *MountedPartition, user, pass string) (UserInfo, bool, error) {
+	return checkCredentialsWith(res, mounted, user, pass, checkPassword)
+}
 
-	file.Seek(inodePosition, 0)
-	var usersInode structs.Inodos
-	if err := binary.Read(file, binary.LittleEndian, &usersInode); err != nil {
-		return "", fmt.Errorf("error al leer el inodo de users.txt: %v", err)
+// checkCredentialsWith - checkCredentials comparando la contraseña con verify
+// (checkPassword o una que recuerde las ya verificadas)
+func checkCredentialsWith(res *CommandResult, mounted *MountedPartition, user, pass string, verify func(stored, password string) bool) (UserInfo, bool, error) {
+	// Leer el archivo users.txt del sistema de archivos
+	unlock := rlockDisk(mounted.Path)
+	usersContent, err := readUsersFile(mounted)
+	unlock()
+	if err != nil {
+		return UserInfo{}, false, fmt.Errorf("error al leer archivo users.txt: %v", err)
 	}
 
-	// Leer múltiples bloques si es necesario
-	var allContent []byte
+	// Buscar el usuario en users.txt y obtener toda su información
+	userInfo, found := findUserWithInfo(usersContent, user, pass, verify)
+	if !found {
+		return UserInfo{}, false, fmt.Errorf("usuario '%s' no encontrado o contraseña incorrecta", user)
+	}
 
-	for i := 0; i < 15; i++ { // Máximo 15 bloques directos
-		if usersInode.I_block[i] == -1 {
-			break // No hay más bloques
+	// Migrar la contraseña en texto plano de una entrada antigua a su hash
+	if stored, _ := findUserPassword(usersContent, user); !isPasswordHash(stored) {
+		if err := upgradePassword(mounted, user, pass); err != nil {
+			res.Printf("⚠️ No se pudo guardar el hash de la contraseña de '%s': %v\n", user, err)
 		}
+	}
 
-		blockPosition := superblock.S_block_start + (usersInode.I_block[i] * superblock.S_block_s)
-		file.Seek(blockPosition, 0)
-
-		var block structs.BloqueArchivo
-		if err := binary.Read(file, binary.LittleEndian, &block); err != nil {
-			return "", fmt.Errorf("error al leer bloque %d: %v", i, err)
-		}
+	// Determinar si es usuario root (UID=1 y nombre="root")
+	return userInfo, userInfo.UID == 1 && user == "root", nil
+}
 
-		allContent = append(allContent, block.BContent[:]...)
+// Leer el archivo users.txt del sistema de archivos
+func readUsersFile(mounted *MountedPartition) (string, error) {
+	fs, err := openFileSystem(mounted, os.O_RDONLY)
+	if err != nil {
+		return "", err
 	}
+	defer fs.Close()
 
-	// Usar el tamaño del inodo para obtener el contenido exacto
-	var content string
-	if usersInode.I_s > 0 && usersInode.I_s <= int64(len(allContent)) {
-		content = string(allContent[:usersInode.I_s])
-	} else {
-		content = string(allContent)
+	// users.txt ocupa el inodo 1
+	content, err := fs.ReadUsers()
+	if err != nil {
+		return "", fmt.Errorf("error al leer el contenido de users.txt: %v", err)
 	}
 
 	return content, nil
 }
 
 // findUserWithInfo - Buscar usuario y retornar toda su información
-func findUserWithInfo(usersContent, user, pass string) (UserInfo, bool) {
+func findUserWithInfo(usersContent, user, pass string, verify func(stored, password string) bool) (UserInfo, bool) {
 	lines := strings.Split(usersContent, "\n")
 
 	// Primero, encontrar todos los grupos para hacer el mapeo
@@ -225,7 +176,7 @@ func findUserWithInfo(usersContent, user, pass string) (UserInfo, bool) {
 			password := strings.TrimSpace(parts[4])
 
 			if tipo == "U" && uidStr != "0" { // Es un usuario y no está eliminado
-				if username == user && password == pass {
+				if username == user && verify(password, pass) {
 					// Convertir UID a numérico
 					uid, err := strconv.ParseInt(uidStr, 10, 64)
 					if err != nil {
@@ -276,14 +227,17 @@ func findUserWithInfo(usersContent, user, pass string) (UserInfo, bool) {
 }
 
 // Comando LOGOUT
-func ExecuteLogout() {
-	if !IsSessionActive() {
-		fmt.Println("Error: No hay sesión activa para cerrar.")
-		return
+func ExecuteLogout(session *Session) (*CommandResult, error) {
+	res := NewCommandResult("logout")
+
+	if !IsSessionActive(session) || IsAutoSession(session) {
+		return res, newCommandError(ErrCodeNoSession, "no hay sesión activa para cerrar")
 	}
 
-	session := GetCurrentSession()
-	fmt.Printf("Cerrando sesión del usuario '%s'.\n", session.User)
-	EndSession()
-	fmt.Println("✅ Sesión cerrada exitosamente.")
+	res.Printf("Cerrando sesión del usuario '%s'.\n", session.User)
+	EndSession(session)
+	res.Println("✅ Sesión cerrada exitosamente.")
+	res.Set("user", session.User)
+
+	return res, nil
 }
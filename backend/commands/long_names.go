@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"backend/structs"
+	"fmt"
+	"strings"
+)
+
+// NOMBRES LARGOS
+// Una partición formateada con mkfs -longnames acepta nombres de hasta 255
+// bytes. BName guarda siempre los primeros 12 bytes (lo que ve un lector
+// antiguo) y, si el nombre no cabe, BLong apunta al primer bloque de una
+// cadena de BloqueNombre con el nombre completo. BLong = 0 indica que no hay
+// cadena: el bloque 0 siempre es el primer bloque de la carpeta raíz.
+// La opción se marca en los bits altos de S_magic, así que en las particiones
+// sin ella (donde BLong siempre vale 0) todo se lee igual que antes.
+
+const (
+	ext2Magic         = 0xEF53         // Firma EXT2/EXT3 (16 bits bajos de S_magic)
+	featureLongNames  = int64(1) << 16 // Bit de S_magic: nombres largos activados
+	shortNameLength   = 12             // Bytes de BContent.BName
+	maxLongNameLength = 255
+)
+
+// maxNameBlocks - Bloques que puede ocupar un nombre largo en la partición
+func (fs *FileSystem) maxNameBlocks() int {
+	chunk := fs.geometry().NameChunk
+	return (maxLongNameLength + chunk - 1) / chunk
+}
+
+// validMagic - Verificar la firma del superbloque sin mirar los bits de opciones
+func validMagic(superblock *structs.SuperBloque) bool {
+	return superblock.S_magic&0xFFFF == ext2Magic
+}
+
+// hasLongNames - Verificar si la partición se formateó con nombres largos
+func hasLongNames(superblock *structs.SuperBloque) bool {
+	return superblock.S_magic&featureLongNames != 0
+}
+
+// maxNameLength - Bytes que puede tener el nombre de una entrada en la partición
+func (fs *FileSystem) maxNameLength() int {
+	if hasLongNames(fs.SB) {
+		return maxLongNameLength
+	}
+	return shortNameLength
+}
+
+// checkName - Validar que un nombre cabe en una entrada de la partición
+func (fs *FileSystem) checkName(name string) error {
+	if len(name) <= fs.maxNameLength() {
+		return nil
+	}
+	if hasLongNames(fs.SB) {
+		return newCommandError(ErrCodeInvalidArgument, "el nombre '%s' tiene %d bytes (máximo %d)", name, len(name), maxLongNameLength)
+	}
+	return newCommandError(ErrCodeInvalidArgument,
+		"el nombre '%s' tiene %d bytes (máximo %d; formatee con mkfs -longnames para usar nombres de hasta %d)",
+		name, len(name), shortNameLength, maxLongNameLength)
+}
+
+// EntryName - Nombre completo de una entrada de carpeta. Si la cadena del
+// nombre largo está dañada se devuelven los 12 bytes de BName.
+func (fs *FileSystem) EntryName(entry *structs.BContent) string {
+	if entry.BLong == 0 || !hasLongNames(fs.SB) {
+		return entryName(entry)
+	}
+	_, name, err := fs.nameChain(entry)
+	if err != nil {
+		return entryName(entry)
+	}
+	return name
+}
+
+// nameMatches - Comparar una entrada con un nombre buscado. En particiones sin
+// nombres largos también se acepta la versión truncada a 12 bytes.
+func (fs *FileSystem) nameMatches(entry *structs.BContent, name string) bool {
+	current := strings.Trim(string(entry.BName[:]), "\x00")
+	if entry.BLong != 0 && hasLongNames(fs.SB) {
+		current = fs.EntryName(entry)
+	}
+	if current == name {
+		return true
+	}
+	if hasLongNames(fs.SB) {
+		return false
+	}
+
+	if len(current) > shortNameLength {
+		current = current[:shortNameLength]
+	}
+	if len(name) > shortNameLength {
+		name = name[:shortNameLength]
+	}
+	return current == name
+}
+
+// nameChain - Leer la cadena de bloques del nombre largo de una entrada.
+// Si la cadena está dañada devuelve los bloques válidos leídos hasta ahí.
+func (fs *FileSystem) nameChain(entry *structs.BContent) ([]int64, string, error) {
+	var blocks []int64
+	var name []byte
+
+	for next := int64(entry.BLong); next != -1; {
+		if next <= 0 || next >= fs.SB.S_blocks_count {
+			return blocks, "", fmt.Errorf("bloque de nombre %d fuera de rango", next)
+		}
+		if len(blocks) >= fs.maxNameBlocks() {
+			return blocks, "", fmt.Errorf("el nombre ocupa más de %d bloques", fs.maxNameBlocks())
+		}
+		for _, seen := range blocks {
+			if seen == next {
+				return blocks, "", fmt.Errorf("la cadena del nombre vuelve al bloque %d", next)
+			}
+		}
+
+		var nameBlock structs.BloqueNombre
+		if err := fs.ReadBlock(next, &nameBlock); err != nil {
+			return blocks, "", err
+		}
+		blocks = append(blocks, next)
+		name = append(name, nameBlock.BName...)
+		next = nameBlock.BNext
+	}
+
+	return blocks, strings.TrimRight(string(name), "\x00"), nil
+}
+
+// setName - Guardar el nombre de una entrada. Libera la cadena anterior y, si
+// el nombre no cabe en BName, lo escribe completo en bloques nuevos.
+func (fs *FileSystem) setName(entry *structs.BContent, name string) error {
+	if err := fs.releaseName(entry); err != nil {
+		return err
+	}
+	setEntryName(entry, name)
+	if len(name) <= shortNameLength || !hasLongNames(fs.SB) {
+		return nil
+	}
+
+	// Se escribe desde el último tramo para conocer el siguiente de cada bloque
+	chunk := fs.geometry().NameChunk
+	next := int64(-1)
+	for start := (len(name) - 1) / chunk * chunk; start >= 0; start -= chunk {
+		blockIndex, err := fs.AllocBlock()
+		if err != nil {
+			return fmt.Errorf("no se pudo asignar un bloque para el nombre '%s': %w", name, err)
+		}
+
+		nameBlock := fs.geometry().newNameBlock()
+		copy(nameBlock.BName, name[start:])
+		nameBlock.BNext = next
+		if err := fs.WriteBlock(blockIndex, &nameBlock); err != nil {
+			return fmt.Errorf("error al escribir el bloque de nombre %d: %v", blockIndex, err)
+		}
+		next = blockIndex
+	}
+
+	entry.BLong = int32(next)
+	return nil
+}
+
+// releaseName - Liberar la cadena del nombre largo de una entrada. Una cadena
+// dañada se libera hasta donde se pudo leer; fsck reporta el resto.
+func (fs *FileSystem) releaseName(entry *structs.BContent) error {
+	if entry.BLong == 0 || !hasLongNames(fs.SB) {
+		entry.BLong = 0
+		return nil
+	}
+
+	blocks, _, _ := fs.nameChain(entry)
+	for _, blockIndex := range blocks {
+		if err := fs.FreeBlock(blockIndex); err != nil {
+			return fmt.Errorf("error al liberar el bloque de nombre %d: %v", blockIndex, err)
+		}
+	}
+	entry.BLong = 0
+	return nil
+}
+
+// releaseEntryNames - Liberar los nombres largos de todas las entradas de una
+// carpeta que se va a eliminar (sus bloques de carpeta se liberan aparte)
+func (fs *FileSystem) releaseEntryNames(dirInode *structs.Inodos) error {
+	if !hasLongNames(fs.SB) {
+		return nil
+	}
+
+	dirBlocks, err := fs.DataBlocks(dirInode)
+	if err != nil {
+		return err
+	}
+	for _, blockIndex := range dirBlocks {
+		folderBlock, err := fs.ReadFolderBlock(blockIndex)
+		if err != nil {
+			return err
+		}
+		for j := range folderBlock.BContent {
+			if folderBlock.BContent[j].BInodo == -1 {
+				continue
+			}
+			if err := fs.releaseName(&folderBlock.BContent[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// Pruebas de nombres largos: rename, copy, move y remove con nombres que
+// ocupan varios bloques de nombre devuelven todos sus bloques, y una partición
+// sin -longnames sigue leyendo y escribiendo nombres de 12 bytes como antes.
+
+// longName - Nombre de size bytes que empieza con prefix
+func longName(prefix string, size int) string {
+	return prefix + strings.Repeat("x", size-len(prefix))
+}
+
+func TestLongNamesAcrossCommands(t *testing.T) {
+	for _, fsType := range []string{"2fs", "3fs"} {
+		t.Run(fsType, func(t *testing.T) {
+			img := newCrashImageFS(t, fsType)
+			if _, err := ExecuteMkfs(img.id, "full", fsType, true, 0, 0, 0); err != nil {
+				t.Fatalf("mkfs -longnames: %v", err)
+			}
+			session, _, err := ExecuteLogin(nil, "root", "123", img.id)
+			if err != nil {
+				t.Fatalf("login: %v", err)
+			}
+			img.session = session
+			if _, err := ExecuteMkdir(session, "/a", false); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			if _, err := ExecuteMkdir(session, "/b", false); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			before := freeBlocksOf(t, img)
+
+			// Cada nombre pasa de un bloque de nombre (88 bytes con bloques de 64)
+			fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunk := fs.geometry().NameChunk
+			fs.Close()
+			dir := longName("carpeta_", chunk+40)
+			first := longName("primero_", chunk+10)
+			second := longName("segundo_", 2*chunk+20)
+			last := longName("ultimo_", maxLongNameLength)
+			content := mkfileContent(300)
+
+			if _, err := ExecuteMkdir(session, "/a/"+dir, false); err != nil {
+				t.Fatalf("mkdir con nombre largo: %v", err)
+			}
+			if _, err := ExecuteMkfile(session, "/a/"+dir+"/"+first, false, len(content), ""); err != nil {
+				t.Fatalf("mkfile con nombre largo: %v", err)
+			}
+
+			if _, err := ExecuteRename(session, "/a/"+dir+"/"+first, second); err != nil {
+				t.Fatalf("rename: %v", err)
+			}
+			if _, err := statPath(img.mounted, "/a/"+dir+"/"+first, nil); err == nil {
+				t.Fatal("el nombre anterior sigue existiendo tras rename")
+			}
+			checkContent(t, img, "/a/"+dir+"/"+second, content)
+			img.checkClean(t, "rename")
+
+			if _, err := ExecuteCopy(session, "/a/"+dir+"/"+second, "/b"); err != nil {
+				t.Fatalf("copy: %v", err)
+			}
+			checkContent(t, img, "/b/"+second, content)
+			img.checkClean(t, "copy")
+
+			if _, err := ExecuteMove(session, "/b/"+second, "/a"); err != nil {
+				t.Fatalf("move: %v", err)
+			}
+			if _, err := ExecuteRename(session, "/a/"+second, last); err != nil {
+				t.Fatalf("rename a %d bytes: %v", len(last), err)
+			}
+			if _, err := ExecuteRename(session, "/a/"+last, last+"y"); ErrorCode(err) != ErrCodeInvalidArgument {
+				t.Fatalf("rename a %d bytes: se esperaba %s: %v", len(last)+1, ErrCodeInvalidArgument, err)
+			}
+			checkEntries(t, img, "/a", []string{dir, last, dir + "/" + second})
+			checkContent(t, img, "/a/"+last, content)
+			img.checkClean(t, "move")
+
+			// Al eliminar se liberan también las cadenas de los nombres
+			if _, err := ExecuteRemove(session, "/a/"+dir); err != nil {
+				t.Fatalf("remove de la carpeta: %v", err)
+			}
+			if _, err := ExecuteRemove(session, "/a/"+last); err != nil {
+				t.Fatalf("remove del archivo: %v", err)
+			}
+			if after := freeBlocksOf(t, img); after != before {
+				t.Fatalf("tras eliminar quedan %d bloques libres, había %d", after, before)
+			}
+			img.checkClean(t, "remove")
+		})
+	}
+}
+
+func TestShortNamePartitionUnchanged(t *testing.T) {
+	img := newCrashImageFS(t, "2fs")
+	if sb := superblockOf(t, img); hasLongNames(&sb) {
+		t.Fatal("la partición se formateó con nombres largos")
+	}
+
+	// Los 4 bytes de BLong eran relleno: en una partición sin nombres largos
+	// se ignoran aunque tengan basura que parezca un bloque
+	blocks := dataBlocksOf(t, img, "/docs/sub/b.txt")
+	fs, err := openFileSystem(img.mounted, os.O_RDWR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, docsInode, err := fs.Lookup("/docs")
+	if err != nil {
+		fs.Close()
+		t.Fatal(err)
+	}
+	folderBlock, err := fs.ReadFolderBlock(docsInode.I_block[0])
+	if err != nil {
+		fs.Close()
+		t.Fatal(err)
+	}
+	for i := range folderBlock.BContent {
+		if entryName(&folderBlock.BContent[i]) == "a.txt" {
+			folderBlock.BContent[i].BLong = int32(blocks[0])
+		}
+	}
+	err = fs.WriteBlock(docsInode.I_block[0], folderBlock)
+	fs.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContent(t, img, "/docs/a.txt", mkfileContent(1500))
+	img.checkClean(t, "relleno con basura")
+
+	// Nombres de hasta 12 bytes; uno más largo se busca por sus 12 primeros
+	name := "doce_bytes.x"
+	if _, err := ExecuteRename(img.session, "/docs/a.txt", name); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	if _, err := ExecuteRename(img.session, "/docs/"+name, name+"y"); ErrorCode(err) != ErrCodeInvalidArgument {
+		t.Fatalf("rename a 13 bytes: se esperaba %s: %v", ErrCodeInvalidArgument, err)
+	}
+	checkContent(t, img, "/docs/"+name+"yz", mkfileContent(1500))
+	if _, err := ExecuteCopy(img.session, "/docs/"+name, "/otro"); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if _, err := ExecuteRemove(img.session, "/docs/"+name); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	checkContent(t, img, "/otro/"+name, mkfileContent(1500))
+	checkContent(t, img, "/docs/sub/b.txt", mkfileContent(200))
+	img.checkClean(t, "nombres de 12 bytes")
+}
@@ -2,7 +2,6 @@ package commands
 
 import (
 	"encoding/binary"
-	"fmt"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -12,7 +11,9 @@ import (
 )
 
 
-func ExecuteMkdisk(size int, unit string, fit string, path string) {
+func ExecuteMkdisk(size int, unit string, fit string, path string, table string, entries int) (*CommandResult, error) {
+	res := NewCommandResult("mkdisk")
+
 	var diskSize int64
 
 	unit = strings.ToUpper(unit)
@@ -23,13 +24,11 @@ func ExecuteMkdisk(size int, unit string, fit string, path string) {
 		case "M", "":
 			diskSize = int64(size) * 1024 * 1024
 		default:
-			fmt.Printf("Error: Unidad invalida '%s'. Use 'K' o 'M'.\n", unit)
-			return
+			return res, newCommandError(ErrCodeInvalidArgument, "unidad invalida '%s'. Use 'K' o 'M'", unit)
 	}
 
 	if diskSize <= 0 {
-		fmt.Println("Error: El parámetro -size debe ser mayor que 0.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -size debe ser mayor que 0")
 	}
 
 	var fitByte byte
@@ -44,8 +43,31 @@ func ExecuteMkdisk(size int, unit string, fit string, path string) {
 		case "FF", "":
 			fitByte = 'f'
 		default:
-			fmt.Printf("Error: Ajuste '%s' no válido. Use 'BF', 'WF' o 'FF'.\n", fit)
-			return
+			return res, newCommandError(ErrCodeInvalidArgument, "ajuste '%s' no válido. Use 'BF', 'WF' o 'FF'", fit)
+	}
+
+	// Tabla de particiones: MBR (4 entradas) o GPT (ver gpt.go)
+	table = strings.ToUpper(table)
+	switch table {
+		case "MBR", "":
+			table = "MBR"
+			if entries != 0 {
+				return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -entries solo aplica a discos con -table=gpt")
+			}
+		case "GPT":
+			if entries == 0 {
+				entries = gptDefaultEntries
+			}
+		default:
+			return res, newCommandError(ErrCodeInvalidArgument, "tabla de particiones '%s' no válida. Use 'MBR' o 'GPT'", table)
+	}
+
+	var gpt *gptTable
+	if table == "GPT" {
+		var err error
+		if gpt, err = newGPT(diskSize, entries); err != nil {
+			return res, commandErrorFrom(ErrCodeInvalidArgument, err)
+		}
 	}
 
 	if !strings.HasSuffix(strings.ToLower(path), ".mia"){
@@ -55,14 +77,12 @@ func ExecuteMkdisk(size int, unit string, fit string, path string) {
 	dir := filepath.Dir(path)
 
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		fmt.Printf("Error al crear el archivo: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al crear el archivo")
 	}
 
-	file, err := os.Create(path)
+	file, err := openDisk(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
 	if err != nil {
-		fmt.Printf("Error al crear el archivo: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al crear el archivo")
 	}
 
 	defer file.Close()
@@ -71,14 +91,12 @@ func ExecuteMkdisk(size int, unit string, fit string, path string) {
 
 	for i := int64(0); i < diskSize/1024; i++ {
 		if _, err := file.Write(chunk); err != nil {
-			fmt.Printf("Error al escribir en el archivo: %v\n", err)
-			return
+			return res, wrapCommandError(ErrCodeIO, err, "error al escribir en el archivo")
 		}
 	}
 
 	if err := file.Truncate(diskSize); err != nil {
-		fmt.Printf("Error al truncar el archivo: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al truncar el archivo")
 	}
 
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -86,17 +104,36 @@ func ExecuteMkdisk(size int, unit string, fit string, path string) {
 	diskSignature := r.Int63()
 
 	mbr := structs.NewMBR(diskSize, fitByte, diskSignature)
+	if gpt != nil {
+		mbr = newProtectiveMBR(diskSize, fitByte, diskSignature)
+	}
 
 	file.Seek(0, 0)
 	if err := binary.Write(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("Error al escribir el MBR: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al escribir el MBR")
+	}
+
+	if gpt != nil {
+		if err := gpt.write(file); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al escribir la tabla GPT")
+		}
 	}
 	
 	if err := AddDiskToRegistry(path); err != nil {
-        fmt.Printf("⚠️ Advertencia: No se pudo registrar el disco: %v\n", err)
+        res.Printf("⚠️ Advertencia: No se pudo registrar el disco: %v\n", err)
     }
 
-	fmt.Printf("Disco creado exitosamente en '%s' con tamaño %d bytes, ajuste '%s' y firma %d.\n", path, diskSize, fit, diskSignature)
-
-}
\ No newline at end of file
+	res.Printf("Disco creado exitosamente en '%s' con tamaño %d bytes, ajuste '%s' y firma %d.\n", path, diskSize, fit, diskSignature)
+	if gpt != nil {
+		res.Printf("Tabla de particiones GPT con %d entradas (GUID %s), zona utilizable: %d - %d bytes.\n",
+			entries, formatGUID(gpt.Header.Gpt_disk_guid), gpt.Header.Gpt_first_usable, gpt.Header.Gpt_last_usable)
+		res.Set("entries", entries)
+	}
+	res.AddPath(path)
+	res.Set("size", diskSize)
+	res.Set("fit", string(fitByte))
+	res.Set("signature", diskSignature)
+	res.Set("table", table)
+
+	return res, nil
+}
@@ -0,0 +1,82 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Pruebas de mkfile: solo se sobrescriben archivos, y un mkfile rechazado o
+// sin espacio no deja cambios en la partición (en EXT2 no hay transacción).
+
+func TestMkfileKeepsExistingOnReject(t *testing.T) {
+	img := newCrashImageFS(t, "2fs")
+	if _, err := ExecuteMkgrp(img.session, "devs"); err != nil {
+		t.Fatalf("mkgrp: %v", err)
+	}
+	if _, err := ExecuteMkusr(img.session, "ana", "clave", "devs"); err != nil {
+		t.Fatalf("mkusr: %v", err)
+	}
+	ana, _, err := ExecuteLogin(nil, "ana", "clave", img.id)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// Una carpeta con contenido no se reemplaza por un archivo
+	if _, err := ExecuteMkfile(img.session, "/docs", false, 10, ""); ErrorCode(err) != ErrCodeInvalidArgument {
+		t.Fatalf("mkfile sobre una carpeta: se esperaba %s: %v", ErrCodeInvalidArgument, err)
+	}
+	checkContent(t, img, "/docs/a.txt", mkfileContent(1500))
+	checkContent(t, img, "/docs/sub/b.txt", mkfileContent(200))
+	img.checkClean(t, "mkfile sobre una carpeta")
+
+	// Sin permiso de escritura en /docs no se elimina el archivo anterior
+	if _, err := ExecuteMkfile(ana, "/docs/a.txt", false, 10, ""); ErrorCode(err) != ErrCodePermissionDenied {
+		t.Fatalf("mkfile sin permisos: se esperaba %s: %v", ErrCodePermissionDenied, err)
+	}
+	checkContent(t, img, "/docs/a.txt", mkfileContent(1500))
+	img.checkClean(t, "mkfile sin permisos")
+
+	// Un archivo sí se sobrescribe
+	if _, err := ExecuteMkfile(img.session, "/docs/a.txt", false, 10, ""); err != nil {
+		t.Fatalf("mkfile sobre un archivo: %v", err)
+	}
+	checkContent(t, img, "/docs/a.txt", mkfileContent(10))
+	img.checkClean(t, "archivo sobrescrito")
+}
+
+func TestMkfileFailureReleasesInode(t *testing.T) {
+	img := newCrashImageFS(t, "2fs")
+	before := superblockOf(t, img)
+
+	// Más grande que el máximo por inodo: se rechaza antes de asignar
+	if _, err := ExecuteMkfile(img.session, "/docs/a.txt", false, 100000, ""); ErrorCode(err) != ErrCodeNoSpace {
+		t.Fatalf("mkfile demasiado grande: se esperaba %s: %v", ErrCodeNoSpace, err)
+	}
+	if after := superblockOf(t, img); after != before {
+		t.Fatal("la partición cambió aunque el archivo no cabía en un inodo")
+	}
+	checkContent(t, img, "/docs/a.txt", mkfileContent(1500))
+	img.checkClean(t, "demasiado grande")
+
+	// Sin bloques libres a mitad de la escritura: se devuelven el inodo y los bloques
+	for i := 0; ; i++ {
+		before := superblockOf(t, img)
+		_, err := ExecuteMkfile(img.session, fmt.Sprintf("/r%02d", i), false, 38000, "")
+		if err == nil {
+			continue
+		}
+		if ErrorCode(err) != ErrCodeNoSpace {
+			t.Fatalf("mkfile sin espacio: se esperaba %s: %v", ErrCodeNoSpace, err)
+		}
+		after := superblockOf(t, img)
+		if after.S_free_blocks_count != before.S_free_blocks_count || after.S_free_inodes_count != before.S_free_inodes_count {
+			t.Fatalf("libres %d→%d bloques, %d→%d inodos", before.S_free_blocks_count, after.S_free_blocks_count,
+				before.S_free_inodes_count, after.S_free_inodes_count)
+		}
+		if _, err := statPath(img.mounted, fmt.Sprintf("/r%02d", i), nil); err == nil {
+			t.Fatal("quedó la entrada del archivo que no cupo")
+		}
+		break
+	}
+	img.checkClean(t, "sin espacio")
+}
@@ -4,13 +4,28 @@ import (
 	"backend/structs"
 	"encoding/binary"
 	"fmt"
-	"math"
 	"os"
 	"strings"
 	"time"
 )
 
-func ExecuteMkfs(id string, formatType string, fs string) {
+// GEOMETRÍA DE MKFS
+// Sin opciones, mkfs conserva la distribución original: bloques de 96 bytes,
+// 3 bloques por inodo y, en EXT3, 64 KB de journal de escritura anticipada.
+// -blocksize elige el tamaño de bloque (ver block_geometry.go), -inoderatio
+// reserva un inodo por cada tantos bytes de la partición (el resto son
+// bloques) y -journalsize fija en KB el journal de EXT3. Todo queda en el
+// superbloque: S_block_s y las posiciones de cada área. -type=fast escribe
+// solo los metadatos y deja el área de bloques sin llenar con ceros.
+
+const (
+	mkfsJournalingCount = 50       // Entradas de la bitácora de operaciones (EXT3)
+	minWalSize          = 4 * 1024 // Bytes mínimos del journal de escritura anticipada
+)
+
+func ExecuteMkfs(id string, formatType string, fs string, longNames bool, blockSize int64, inodeRatio int64, journalSize int64) (*CommandResult, error) {
+	res := NewCommandResult("mkfs")
+
 	// Normalizar parámetros
 	formatType = strings.ToLower(formatType)
 	fs = strings.ToLower(fs)
@@ -23,317 +38,252 @@ func ExecuteMkfs(id string, formatType string, fs string) {
 		fs = "2fs" // Por defecto EXT2
 	}
 
-	if formatType != "full" {
-		fmt.Printf("Error: Tipo de formateo '%s' no soportado. Use 'full'.\n", formatType)
-		return
+	if formatType != "full" && formatType != "fast" {
+		return res, newCommandError(ErrCodeUnsupported, "tipo de formateo '%s' no soportado. Use 'full' o 'fast'", formatType)
 	}
 
 	if fs != "2fs" && fs != "3fs" {
-		fmt.Printf("Error: Sistema de archivos '%s' no soportado. Use '2fs' o '3fs'.\n", fs)
-		return
+		return res, newCommandError(ErrCodeUnsupported, "sistema de archivos '%s' no soportado. Use '2fs' o '3fs'", fs)
+	}
+
+	// Sin -blocksize se conservan los bloques de 96 bytes
+	if blockSize == 0 {
+		blockSize = legacyBlockSize
+	}
+	if !validBlockSize(blockSize) {
+		return res, newCommandError(ErrCodeInvalidArgument, "tamaño de bloque %d no soportado. Use 64, 128, 256, 512 o 1024", blockSize)
+	}
+
+	if inodeRatio < 0 {
+		return res, newCommandError(ErrCodeInvalidArgument, "-inoderatio debe ser positivo. Actual: %d", inodeRatio)
+	}
+	if inodeRatio > 0 && inodeRatio < blockSize {
+		return res, newCommandError(ErrCodeInvalidArgument, "-inoderatio (%d bytes) no puede ser menor que el tamaño de bloque (%d bytes)", inodeRatio, blockSize)
+	}
+
+	walSize := int64(walAreaSize)
+	if journalSize != 0 {
+		if fs != "3fs" {
+			return res, newCommandError(ErrCodeInvalidArgument, "-journalsize solo aplica a sistemas 3fs")
+		}
+		walSize = journalSize * 1024
+		if walSize < minWalSize {
+			return res, newCommandError(ErrCodeInvalidArgument, "el journal debe tener al menos %d KB. Actual: %d KB", minWalSize/1024, journalSize)
+		}
 	}
 
-	fmt.Printf("Iniciando formateo %s con sistema %s de la partición...\n", strings.ToUpper(formatType), strings.ToUpper(fs))
+	res.Printf("Iniciando formateo %s con sistema %s de la partición...\n", strings.ToUpper(formatType), strings.ToUpper(fs))
 
 	// Buscar la partición montada por ID
 	mounted := GetMountedPartition(id)
 	if mounted == nil {
-		fmt.Printf("Error: No se encontró ninguna partición montada con ID '%s'.\n", id)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró ninguna partición montada con ID '%s'", id)
 	}
 
 	// Debug información de la partición montada
-	fmt.Printf("Partición montada encontrada:\n")
-	fmt.Printf("   ID: %s\n", mounted.ID)
-	fmt.Printf("   Nombre: '%s'\n", mounted.Name)
-	fmt.Printf("   Ruta: %s\n", mounted.Path)
-	fmt.Printf("   Tamaño: %d bytes\n", mounted.Size)
+	res.Printf("Partición montada encontrada:\n")
+	res.Printf("   ID: %s\n", mounted.ID)
+	res.Printf("   Nombre: '%s'\n", mounted.Name)
+	res.Printf("   Ruta: %s\n", mounted.Path)
+	res.Printf("   Tamaño: %d bytes\n", mounted.Size)
+
+	// Formatear requiere el disco en exclusiva
+	defer lockDisk(mounted.Path)()
 
 	// Abrir el archivo del disco
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+	disk, err := OpenDisk(mounted.Path, os.O_RDWR)
 	if err != nil {
-		fmt.Printf("Error al abrir el disco: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el disco")
 	}
-	defer file.Close()
+	defer disk.Close()
 
-	// Leer el MBR para obtener información de la partición
 	// Preferir usar el offset de inicio almacenado en 'mounted' si está disponible
-	var partition *structs.Partition
-	if mounted.Start > 0 {
-		// Construir una partición basada en mounted
-		var p structs.Partition
-		p.Part_start = mounted.Start
-		p.Part_s = mounted.Size
-		var nameBytes [16]byte
-		copy(nameBytes[:], []byte(mounted.Name))
-		p.Part_name = nameBytes
-		partition = &p
-	} else {
-		// Leer el MBR para obtener información de la partición
-		var mbr structs.MBR
-		if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-			fmt.Printf("Error al leer el MBR: %v\n", err)
-			return
-		}
-
-		// Encontrar la partición específica
-		for _, p := range mbr.Mbr_partitions {
-			if p.Part_status != '0' {
-				partitionNameBytes := p.Part_name[:]
-				nullIndex := len(partitionNameBytes)
-				for j, b := range partitionNameBytes {
-					if b == 0 {
-						nullIndex = j
-						break
-					}
-				}
-				partitionName := strings.TrimSpace(string(partitionNameBytes[:nullIndex]))
-
-				if strings.EqualFold(partitionName, mounted.Name) || partitionName == mounted.Name {
-					partitionCopy := p
-					partition = &partitionCopy
-					break
-				}
-			}
-		}
-
-		if partition == nil {
-			fmt.Printf("Error: No se pudo encontrar la partición '%s'.\n", mounted.Name)
-			fmt.Printf("🔍 Particiones disponibles en el MBR:\n")
-			for i, p := range mbr.Mbr_partitions {
+	partition := &Partition{Disk: disk, Name: mounted.Name, Type: 'P', Start: mounted.Start, Size: mounted.Size, Index: -1}
+	if mounted.Start <= 0 {
+		// Buscar la partición en el MBR (y en las lógicas)
+		partition, err = disk.FindPartition(mounted.Name)
+		if err != nil {
+			res.Printf("🔍 Particiones disponibles en la tabla del disco:\n")
+			for i, p := range disk.PrimaryPartitions() {
 				if p.Part_status != '0' {
-					partitionNameBytes := p.Part_name[:]
-					nullIndex := len(partitionNameBytes)
-					for j, b := range partitionNameBytes {
-						if b == 0 {
-							nullIndex = j
-							break
-						}
-					}
-					partitionName := strings.TrimSpace(string(partitionNameBytes[:nullIndex]))
-					fmt.Printf("   [%d] '%s' (status: %c, type: %c)\n", i, partitionName, p.Part_status, p.Part_type)
+					res.Printf("   [%d] '%s' (status: %c, type: %c)\n", i, partitionName(p.Part_name[:]), p.Part_status, p.Part_type)
 				}
 			}
-			return
+			return res, newCommandError(ErrCodeNotFound, "no se pudo encontrar la partición '%s'", mounted.Name)
 		}
 	}
 
 	// Calcular el número de estructuras según el sistema de archivos
-	var n int64
-	var superblock structs.SuperBloque
+	var inodes, blocks int64
+	if fs == "2fs" {
+		inodes, blocks = calculateEXT2Structures(partition.Size, blockSize, inodeRatio)
+	} else {
+		inodes, blocks = calculateEXT3Structures(partition.Size, blockSize, inodeRatio, walSize)
+	}
+	if inodes < 2 || blocks < 2 {
+		return res, newCommandError(ErrCodeNoSpace,
+			"con estos parámetros la partición de %d bytes tendría %d inodos y %d bloques; se necesitan al menos 2 de cada uno (raíz y users.txt)",
+			partition.Size, inodes, blocks)
+	}
+	fast := formatType == "fast"
 
 	switch fs {
 	case "2fs":
 		// EXT2
-		n = calculateEXT2Structures(partition.Part_s)
-		fmt.Printf("Calculando estructuras EXT2 para partición de %d bytes...\n", partition.Part_s)
-		fmt.Printf("   - Número de inodos: %d\n", n)
-		fmt.Printf("   - Número de bloques: %d\n", n*3)
+		res.Printf("Calculando estructuras EXT2 para partición de %d bytes...\n", partition.Size)
+		res.Printf("   - Número de inodos: %d\n", inodes)
+		res.Printf("   - Número de bloques: %d\n", blocks)
+		res.Printf("   - Tamaño de bloque: %d bytes\n", blockSize)
 
-		superblock = createSuperblock(n, partition.Part_s, partition.Part_start)
+		superblock := createSuperblock(inodes, blocks, partition.Start, blockSize)
 
 		// Escribir las estructuras EXT2 en la partición
-		if err := writeEXT2Structures(file, partition, superblock, n); err != nil {
-			fmt.Printf("Error al escribir estructuras EXT2: %v\n", err)
-			return
+		if err := writeEXT2Structures(disk.file, partition, superblock, fast); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al escribir estructuras EXT2")
 		}
 
 	case "3fs":
 		// EXT3
-		n = calculateEXT3Structures(partition.Part_s)
-		fmt.Printf("Calculando estructuras EXT3 para partición de %d bytes...\n", partition.Part_s)
-		fmt.Printf("   - Número de inodos: %d\n", n)
-		fmt.Printf("   - Número de bloques: %d\n", n*3)
-		fmt.Printf("   - Entradas de Journaling: %d\n", 50)
+		res.Printf("Calculando estructuras EXT3 para partición de %d bytes...\n", partition.Size)
+		res.Printf("   - Número de inodos: %d\n", inodes)
+		res.Printf("   - Número de bloques: %d\n", blocks)
+		res.Printf("   - Tamaño de bloque: %d bytes\n", blockSize)
+		res.Printf("   - Entradas de Journaling: %d\n", mkfsJournalingCount)
+		res.Printf("   - Journal de escritura anticipada: %d bytes\n", walSize)
 
-		superblock = createSuperblockEXT3(n, partition.Part_s, partition.Part_start)
+		superblock := createSuperblockEXT3(inodes, blocks, partition.Start, blockSize, walSize)
 
 		// Escribir las estructuras EXT3 en la partición
-		if err := writeEXT3Structures(file, partition, superblock, n); err != nil {
-			fmt.Printf("Error al escribir estructuras EXT3: %v\n", err)
-			return
+		if err := writeEXT3Structures(disk.file, partition, superblock, walSize, fast); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al escribir estructuras EXT3")
 		}
 	}
 
 	// Crear archivo users.txt en la raíz
-	if err := createUsersFile(file, &superblock); err != nil {
-		fmt.Printf("Error al crear archivo users.txt: %v\n", err)
-		return
+	fileSystem, err := partition.FileSystem()
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al leer el sistema de archivos creado")
+	}
+	// Los nombres largos se marcan en el superbloque (ver long_names.go)
+	if longNames {
+		fileSystem.SB.S_magic |= featureLongNames
+	}
+	if err := createUsersFile(res, fileSystem); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al crear archivo users.txt")
 	}
 
-	fmt.Printf("✅ Sistema de archivos %s creado exitosamente en partición '%s'.\n", strings.ToUpper(fs), mounted.Name)
-	fmt.Printf("   ID: %s\n", id)
-	fmt.Printf("   Tipo: %s\n", strings.ToUpper(formatType))
-	fmt.Printf("   Sistema: %s\n", strings.ToUpper(fs))
-	fmt.Printf("   Inodos: %d\n", n)
-	fmt.Printf("   Bloques: %d\n", n*3)
+	res.Printf("✅ Sistema de archivos %s creado exitosamente en partición '%s'.\n", strings.ToUpper(fs), mounted.Name)
+	res.Printf("   ID: %s\n", id)
+	res.Printf("   Tipo: %s\n", strings.ToUpper(formatType))
+	res.Printf("   Sistema: %s\n", strings.ToUpper(fs))
+	res.Printf("   Inodos: %d\n", inodes)
+	res.Printf("   Bloques: %d de %d bytes\n", blocks, blockSize)
+	if fs == "3fs" {
+		res.Printf("   Journaling: %d entradas\n", mkfsJournalingCount)
+		res.Printf("   Journal de escritura anticipada: %d KB\n", walSize/1024)
+	}
+	if longNames {
+		res.Printf("   Nombres largos: hasta %d bytes\n", maxLongNameLength)
+	}
+	res.Printf("   Archivo users.txt creado en la raíz\n")
+	res.Set("id", id)
+	res.Set("fs", fs)
+	res.Set("type", formatType)
+	res.Set("inodes", inodes)
+	res.Set("blocks", blocks)
+	res.Set("blockSize", blockSize)
 	if fs == "3fs" {
-		fmt.Printf("   Journaling: 50 entradas\n")
+		res.Set("journalSize", walSize)
 	}
-	fmt.Printf("   Archivo users.txt creado en la raíz\n")
-}
+	res.Set("longNames", longNames)
 
-// Calcular número de estructuras según la fórmula EXT3
-func calculateEXT3Structures(partitionSize int64) int64 {
-	const journalingCount = 50 // Constante fija
+	return res, nil
+}
 
-	superblockSize := int64(binary.Size(structs.SuperBloque{}))
-	journalSize := int64(binary.Size(structs.Journal{}))
+// calculateStructures - Inodos y bloques que caben en los available bytes que
+// quedan después del superbloque (y del journal en EXT3)
+func calculateStructures(available int64, partitionSize int64, blockSize int64, inodeRatio int64) (int64, int64) {
 	inodeSize := int64(binary.Size(structs.Inodos{}))
 
-	carpetaSize := int64(binary.Size(structs.BloqueCarpeta{}))
-	archivoSize := int64(binary.Size(structs.BloqueArchivo{}))
-
-	var blockSize int64
-	if carpetaSize > archivoSize {
-		blockSize = carpetaSize
-	} else {
-		blockSize = archivoSize
+	if inodeRatio == 0 {
+		// Proporción original, 3 bloques por inodo:
+		// available = n + 3n + n*inode + 3n*block
+		// n = available / (1 + 3 + inode + 3*block)
+		n := available / (1 + 3 + inodeSize + 3*blockSize)
+		if n <= 0 {
+			n = 1
+		}
+		return n, 3 * n
 	}
 
-	// Fórmula EXT3: tamaño_particion = superblock + 50*journal + n + 3n + n*inode + 3n*block
-	// n = (tamaño_particion - superblock - 50*journal) / (1 + 3 + inode + 3*block)
-
-	numerator := float64(partitionSize - superblockSize - (journalingCount * journalSize))
-	denominator := float64(1 + 3 + inodeSize + 3*blockSize)
-
-	n := math.Floor(numerator / denominator)
+	// Un inodo por cada inodeRatio bytes de la partición; el resto son bloques:
+	// available = n + n*inode + m + m*block
+	n := partitionSize / inodeRatio
+	m := (available - n*(1+inodeSize)) / (1 + blockSize)
+	return n, m
+}
 
-	if n <= 0 {
-		n = 1
-	}
+// Calcular número de estructuras según la fórmula EXT3
+func calculateEXT3Structures(partitionSize int64, blockSize int64, inodeRatio int64, walSize int64) (int64, int64) {
+	superblockSize := int64(binary.Size(structs.SuperBloque{}))
+	journalSize := int64(binary.Size(structs.Journal{}))
 
-	return int64(n)
+	// EXT3: superblock + wal + 50*journal antes de los bitmaps
+	available := partitionSize - superblockSize - walSize - (mkfsJournalingCount * journalSize)
+	return calculateStructures(available, partitionSize, blockSize, inodeRatio)
 }
 
 // Crear superbloque para EXT3
-func createSuperblockEXT3(inodeCount int64, _ int64, partitionStart int64) structs.SuperBloque {
-	const journalingCount = 50
-	blockCount := inodeCount * 3
-	now := time.Now().Unix()
-
+func createSuperblockEXT3(inodeCount int64, blockCount int64, partitionStart int64, blockSize int64, walSize int64) structs.SuperBloque {
 	superblockSize := int64(binary.Size(structs.SuperBloque{}))
 	journalSize := int64(binary.Size(structs.Journal{}))
 
-	// Estructura EXT3: Superbloque → Journaling → Bitmap Inodos → Bitmap Bloques → Inodos → Bloques
-	journalingStart := partitionStart + superblockSize
-	bitmapInodesStart := journalingStart + (journalingCount * journalSize)
-	bitmapBlocksStart := bitmapInodesStart + inodeCount
-	inodesStart := bitmapBlocksStart + blockCount
-
-	carpetaSize := int64(binary.Size(structs.BloqueCarpeta{}))
-	archivoSize := int64(binary.Size(structs.BloqueArchivo{}))
-
-	var blockRealSize int64
-	if carpetaSize > archivoSize {
-		blockRealSize = carpetaSize
-	} else {
-		blockRealSize = archivoSize
-	}
-
-	blocksStart := inodesStart + inodeCount*int64(binary.Size(structs.Inodos{}))
-
-	return structs.SuperBloque{
-		S_file_system_type:  3, // EXT3
-		S_inodes_count:      inodeCount,
-		S_blocks_count:      blockCount,
-		S_free_blocks_count: blockCount - 2,
-		S_free_inodes_count: inodeCount - 2,
-		S_mtime:             now,
-		S_umtime:            now,
-		S_mnt_count:         1,
-		S_magic:             0xEF53,
-		S_inode_s:           int64(binary.Size(structs.Inodos{})),
-		S_block_s:           blockRealSize,
-		S_first_ino:         2,
-		S_first_blo:         2,
-		S_bm_inode_start:    bitmapInodesStart,
-		S_bm_block_start:    bitmapBlocksStart,
-		S_inode_start:       inodesStart,
-		S_block_start:       blocksStart,
-	}
+	// Estructura EXT3: Superbloque → Journal (WAL) → Journaling → Bitmap Inodos → Bitmap Bloques → Inodos → Bloques
+	journalingStart := partitionStart + superblockSize + walSize
+	bitmapInodesStart := journalingStart + (mkfsJournalingCount * journalSize)
+	return newSuperblock(3, inodeCount, blockCount, bitmapInodesStart, blockSize)
 }
 
 // Escribir todas las estructuras EXT3 en la partición
-func writeEXT3Structures(file *os.File, partition *structs.Partition, superblock structs.SuperBloque, n int64) error {
-	const journalingCount = 50
-
+func writeEXT3Structures(file *diskFile, partition *Partition, superblock structs.SuperBloque, walSize int64, fast bool) error {
 	// Posicionarse al inicio de la partición
-	file.Seek(partition.Part_start, 0)
+	file.Seek(partition.Start, 0)
 
 	// 1. Escribir Superbloque
 	if err := binary.Write(file, binary.LittleEndian, &superblock); err != nil {
 		return fmt.Errorf("error escribiendo superbloque: %v", err)
 	}
 
-	// 2. Escribir Journaling (50 entradas vacías)
+	// 2. Escribir el journal de escritura anticipada (vacío; en formateo
+	// rápido basta con la cabecera)
+	walHeader := structs.WalHeader{WMagic: walMagic, WState: walStateEmpty}
+	if err := binary.Write(file, binary.LittleEndian, &walHeader); err != nil {
+		return fmt.Errorf("error escribiendo journal: %v", err)
+	}
+	if fast {
+		file.Seek(walSize-int64(binary.Size(walHeader)), 1)
+	} else if _, err := file.Write(make([]byte, walSize-int64(binary.Size(walHeader)))); err != nil {
+		return fmt.Errorf("error escribiendo journal: %v", err)
+	}
+
+	// 3. Escribir Journaling (50 entradas vacías)
 	emptyJournal := structs.Journal{
 		JCount:   -1,
 		JContent: structs.Information{},
 	}
 
-	for i := 0; i < journalingCount; i++ {
+	for i := 0; i < mkfsJournalingCount; i++ {
 		if err := binary.Write(file, binary.LittleEndian, &emptyJournal); err != nil {
 			return fmt.Errorf("error escribiendo journaling: %v", err)
 		}
 	}
 
-	// 3. Escribir Bitmap de Inodos (inicializado en 0, excepto el primer inodo)
-	bitmapInodes := make([]byte, n)
-	bitmapInodes[0] = 1
-	if _, err := file.Write(bitmapInodes); err != nil {
-		return fmt.Errorf("error escribiendo bitmap de inodos: %v", err)
-	}
-
-	// 4. Escribir Bitmap de Bloques (inicializado en 0, excepto el primer bloque)
-	bitmapBlocks := make([]byte, n*3)
-	bitmapBlocks[0] = 1
-	if _, err := file.Write(bitmapBlocks); err != nil {
-		return fmt.Errorf("error escribiendo bitmap de bloques: %v", err)
-	}
-
-	// 5. Escribir Inodos (inicializar el inodo raíz)
-	rootInode := createRootInode()
-	if err := binary.Write(file, binary.LittleEndian, &rootInode); err != nil {
-		return fmt.Errorf("error escribiendo inodo raíz: %v", err)
-	}
-
-	// Inicializar todos los inodos restantes
-	emptyInode := structs.Inodos{}
-	for i := 0; i < 15; i++ {
-		emptyInode.I_block[i] = -1
-	}
-
-	for i := int64(1); i < n; i++ {
-		if err := binary.Write(file, binary.LittleEndian, &emptyInode); err != nil {
-			return fmt.Errorf("error escribiendo inodos vacíos: %v", err)
-		}
-	}
-
-	// 6. Escribir Bloques (inicializar el bloque raíz)
-	rootBlock := createRootBlock()
-	if err := binary.Write(file, binary.LittleEndian, &rootBlock); err != nil {
-		return fmt.Errorf("error escribiendo bloque raíz: %v", err)
-	}
-
-	// Inicializar todos los bloques restantes
-	emptyBlock := structs.BloqueArchivo{}
-	for i := range emptyBlock.BContent {
-		emptyBlock.BContent[i] = 0
-	}
-
-	for i := int64(1); i < n*3; i++ {
-		if err := binary.Write(file, binary.LittleEndian, &emptyBlock); err != nil {
-			return fmt.Errorf("error escribiendo bloques vacíos: %v", err)
-		}
-	}
-
-	return nil
+	// 4-7. Bitmaps, inodos y bloques
+	return writeTables(file, superblock, fast)
 }
 
 // Registrar operación en el journal (solo para EXT3)
-func logToJournal(file *os.File, superblock *structs.SuperBloque, operation string, path string, content string) error {
+func logToJournal(file *diskFile, superblock *structs.SuperBloque, operation string, path string, content string) error {
 	// Solo registrar si es EXT3
 	if superblock.S_file_system_type != 3 {
 		return nil
@@ -378,204 +328,96 @@ func logToJournal(file *os.File, superblock *structs.SuperBloque, operation stri
 }
 
 // Crear archivo users.txt en la raíz
-func createUsersFile(file *os.File, superblock *structs.SuperBloque) error {
-	// Contenido inicial del archivo users.txt
-	usersContent := "1,G,root\n1,U,root,root,123\n"
+func createUsersFile(res *CommandResult, fs *FileSystem) error {
+	// Contenido inicial del archivo users.txt (la contraseña 123 de root se
+	// guarda como hash, ver passwords.go)
+	rootPassword, err := hashPassword("123")
+	if err != nil {
+		return err
+	}
+	usersContent := fmt.Sprintf("1,G,root\n1,U,root,root,%s\n", rootPassword)
 
-	// Buscar inodo libre (índice 1, ya que 0 es el directorio raíz)
+	// Inodo 1 (el 0 es el directorio raíz); sus bloques empiezan en el 1
 	inodeIndex := int64(1)
 
 	// Crear inodo para el archivo users.txt
 	now := time.Now().Unix()
 	fileInode := structs.Inodos{
-		I_uid:   0,                        // Usuario root
-		I_gid:   0,                        // Grupo root
-		I_s:     int64(len(usersContent)), // Tamaño del contenido
+		I_uid:   rootAccountID, // Usuario root
+		I_gid:   rootAccountID, // Grupo root
 		I_atime: now,
 		I_ctime: now,
 		I_mtime: now,
 		I_type:  '1',                    // Archivo (no directorio)
 		I_perm:  [3]byte{'6', '4', '4'}, // Permisos 644
+		I_links: 1,
 	}
-
-	// El primer bloque del archivo apunta al bloque 1 (bloque 0 es directorio raíz)
-	fileInode.I_block[0] = 1
-
-	// Inicializar el resto de bloques en -1
-	for i := 1; i < 15; i++ {
+	for i := range fileInode.I_block {
 		fileInode.I_block[i] = -1
 	}
 
-	// Debug posiciones
-	inodePosition := superblock.S_inode_start + (inodeIndex * superblock.S_inode_s)
-	blockPosition := superblock.S_block_start + (1 * superblock.S_block_s)
-
-	// Escribir el inodo en la posición correcta
-	file.Seek(inodePosition, 0)
-	if err := binary.Write(file, binary.LittleEndian, &fileInode); err != nil {
-		return fmt.Errorf("error escribiendo inodo users.txt: %v", err)
-	}
-
-	// Crear bloque de archivo con el contenido
-	fileBlock := structs.BloqueArchivo{}
-
-	// Limpiar explícitamente todo el bloque primero
-	for i := range fileBlock.BContent {
-		fileBlock.BContent[i] = 0
+	// Marcar el inodo (y ajustar el contador del superbloque)
+	if err := fs.MarkInode(inodeIndex, true); err != nil {
+		return fmt.Errorf("error actualizando bitmaps: %v", err)
 	}
 
-	// Ahora copiar el contenido
-	copy(fileBlock.BContent[:], []byte(usersContent))
-
-	// Escribir el bloque en la posición correcta (bloque 1)
-	file.Seek(blockPosition, 0)
-	if err := binary.Write(file, binary.LittleEndian, &fileBlock); err != nil {
-		return fmt.Errorf("error escribiendo bloque users.txt: %v", err)
+	// Escribir el contenido; WriteFile reserva los bloques y guarda el inodo
+	if err := fs.WriteFile(inodeIndex, &fileInode, []byte(usersContent)); err != nil {
+		return fmt.Errorf("error escribiendo users.txt: %v", err)
 	}
 
 	// Actualizar el directorio raíz para incluir la entrada de users.txt
-	if err := addFileToRootDirectory(file, superblock, "users.txt", inodeIndex); err != nil {
+	if err := fs.AddEntry(0, "users.txt", inodeIndex); err != nil {
 		return fmt.Errorf("error agregando users.txt al directorio raíz: %v", err)
 	}
 
-	// Actualizar bitmaps
-	if err := updateBitmaps(file, superblock, inodeIndex, 1); err != nil {
-		return fmt.Errorf("error actualizando bitmaps: %v", err)
-	}
-
 	// Registrar en el journal si es EXT3
-	if superblock.S_file_system_type == 3 {
-		if err := logToJournal(file, superblock, "mkfile", "/users.txt", usersContent); err != nil {
-			fmt.Printf("⚠️  Advertencia: error registrando en journal: %v\n", err)
+	if fs.SB.S_file_system_type == 3 {
+		if err := logToJournal(fs.file, fs.SB, "mkfile", "/users.txt", usersContent); err != nil {
+			res.Printf("⚠️  Advertencia: error registrando en journal: %v\n", err)
 		}
 	}
 
-	// Actualizar contadores en superbloque
-	superblock.S_free_inodes_count--
-	superblock.S_free_blocks_count--
-	superblock.S_first_ino = 2 // Siguiente inodo libre
-	superblock.S_first_blo = 2 // Siguiente bloque libre
-
-	return nil
-}
-
-// Agregar archivo al directorio raíz
-func addFileToRootDirectory(file *os.File, superblock *structs.SuperBloque, fileName string, inodeIndex int64) error {
-	// Leer el bloque del directorio raíz (bloque 0)
-	file.Seek(superblock.S_block_start, 0)
-
-	var rootBlock structs.BloqueCarpeta
-	if err := binary.Read(file, binary.LittleEndian, &rootBlock); err != nil {
-		return err
-	}
-
-	// Validar longitud del nombre
-	if len(fileName) > 12 {
-		return fmt.Errorf("nombre de archivo demasiado largo: máximo 12 caracteres")
-	}
-
-	// Limpiar la entrada en posición 2
-	for j := range rootBlock.BContent[2].BName {
-		rootBlock.BContent[2].BName[j] = 0
-	}
-
-	// Copiar el nombre del archivo
-	copy(rootBlock.BContent[2].BName[:], []byte(fileName))
-	rootBlock.BContent[2].BInodo = inodeIndex
-
-	fmt.Printf("✅ Archivo '%s' agregado en posición 2\n", fileName)
-
-	// Escribir el bloque actualizado
-	file.Seek(superblock.S_block_start, 0)
-	return binary.Write(file, binary.LittleEndian, &rootBlock)
-}
-
-// Actualizar bitmaps
-func updateBitmaps(file *os.File, superblock *structs.SuperBloque, inodeIndex int64, blockIndex int64) error {
-	// Actualizar bitmap de inodos
-	file.Seek(superblock.S_bm_inode_start+inodeIndex, 0)
-	if _, err := file.Write([]byte{1}); err != nil {
-		return err
-	}
-
-	// Actualizar bitmap de bloques
-	file.Seek(superblock.S_bm_block_start+blockIndex, 0)
-	if _, err := file.Write([]byte{1}); err != nil {
-		return err
-	}
-
-	return nil
+	return fs.Flush()
 }
 
 // Calcular número de estructuras según la fórmula EXT2
-func calculateEXT2Structures(partitionSize int64) int64 {
-	// Tamaños de las estructuras
+func calculateEXT2Structures(partitionSize int64, blockSize int64, inodeRatio int64) (int64, int64) {
 	superblockSize := int64(binary.Size(structs.SuperBloque{}))
-	inodeSize := int64(binary.Size(structs.Inodos{}))
-
-	// Usar el tamaño del bloque más grande
-	carpetaSize := int64(binary.Size(structs.BloqueCarpeta{}))
-	archivoSize := int64(binary.Size(structs.BloqueArchivo{}))
-
-	var blockSize int64
-	if carpetaSize > archivoSize {
-		blockSize = carpetaSize
-	} else {
-		blockSize = archivoSize
-	}
 
-	// Fórmula: tamaño_particion = sizeOf(superblock) + n + 3*n + n*sizeOf(inodos) + 3*n*sizeOf(block)
-	// Despejando n: n = (tamaño_particion - sizeOf(superblock)) / (1 + 3 + sizeOf(inodos) + 3*sizeOf(block))
-
-	numerator := float64(partitionSize - superblockSize)
-	denominator := float64(1 + 3 + inodeSize + 3*blockSize)
-
-	n := math.Floor(numerator / denominator)
-
-	// Asegurar que n sea positivo y razonable
-	if n <= 0 {
-		n = 1
-	}
+	// EXT2: solo el superbloque antes de los bitmaps
+	return calculateStructures(partitionSize-superblockSize, partitionSize, blockSize, inodeRatio)
+}
 
-	return int64(n)
+// Crear superbloque para EXT2
+func createSuperblock(inodeCount int64, blockCount int64, partitionStart int64, blockSize int64) structs.SuperBloque {
+	// Estructura EXT2: Superbloque → Bitmap Inodos → Bitmap Bloques → Inodos → Bloques
+	bitmapInodesStart := partitionStart + int64(binary.Size(structs.SuperBloque{}))
+	return newSuperblock(2, inodeCount, blockCount, bitmapInodesStart, blockSize)
 }
 
-// Crear superbloque con valores iniciales
-func createSuperblock(inodeCount int64, _ int64, partitionStart int64) structs.SuperBloque {
-	blockCount := inodeCount * 3
+// newSuperblock - Superbloque con valores iniciales; las áreas se ubican una
+// tras otra a partir del bitmap de inodos
+func newSuperblock(fsType int64, inodeCount int64, blockCount int64, bitmapInodesStart int64, blockSize int64) structs.SuperBloque {
 	now := time.Now().Unix()
 
 	// Calcular posiciones de las estructuras
-	superblockSize := int64(binary.Size(structs.SuperBloque{}))
-	bitmapInodesStart := partitionStart + superblockSize
 	bitmapBlocksStart := bitmapInodesStart + inodeCount
 	inodesStart := bitmapBlocksStart + blockCount
-
-	// Usar el tamaño real del bloque más grande
-	carpetaSize := int64(binary.Size(structs.BloqueCarpeta{}))
-	archivoSize := int64(binary.Size(structs.BloqueArchivo{}))
-
-	var blockRealSize int64
-	if carpetaSize > archivoSize {
-		blockRealSize = carpetaSize
-	} else {
-		blockRealSize = archivoSize
-	}
-
 	blocksStart := inodesStart + inodeCount*int64(binary.Size(structs.Inodos{}))
 
 	return structs.SuperBloque{
-		S_file_system_type:  2, // EXT2
+		S_file_system_type:  fsType,
 		S_inodes_count:      inodeCount,
 		S_blocks_count:      blockCount,
-		S_free_blocks_count: blockCount - 2,
-		S_free_inodes_count: inodeCount - 2,
+		S_free_blocks_count: blockCount - 1, // El bloque raíz; users.txt se descuenta al crearlo
+		S_free_inodes_count: inodeCount - 1,
 		S_mtime:             now,
 		S_umtime:            now,
 		S_mnt_count:         1,
 		S_magic:             0xEF53,
 		S_inode_s:           int64(binary.Size(structs.Inodos{})),
-		S_block_s:           blockRealSize,
+		S_block_s:           blockSize,
 		S_first_ino:         2,
 		S_first_blo:         2,
 		S_bm_inode_start:    bitmapInodesStart,
@@ -586,30 +428,40 @@ func createSuperblock(inodeCount int64, _ int64, partitionStart int64) structs.S
 }
 
 // Escribir todas las estructuras EXT2 en la partición
-func writeEXT2Structures(file *os.File, partition *structs.Partition, superblock structs.SuperBloque, n int64) error {
+func writeEXT2Structures(file *diskFile, partition *Partition, superblock structs.SuperBloque, fast bool) error {
 	// Posicionarse al inicio de la partición
-	file.Seek(partition.Part_start, 0)
+	file.Seek(partition.Start, 0)
 
 	// 1. Escribir Superbloque
 	if err := binary.Write(file, binary.LittleEndian, &superblock); err != nil {
 		return fmt.Errorf("error escribiendo superbloque: %v", err)
 	}
 
-	// 2. Escribir Bitmap de Inodos (inicializado en 0, excepto el primer inodo)
-	bitmapInodes := make([]byte, n)
+	// 2-5. Bitmaps, inodos y bloques
+	return writeTables(file, superblock, fast)
+}
+
+// writeTables - Escribir bitmaps, inodos y bloques en las posiciones del
+// superbloque. En formateo rápido solo se escribe el bloque raíz: los demás
+// bloques están libres en el bitmap y se sobrescriben al asignarlos.
+func writeTables(file *diskFile, superblock structs.SuperBloque, fast bool) error {
+	file.Seek(superblock.S_bm_inode_start, 0)
+
+	// Bitmap de Inodos (inicializado en 0, excepto el primer inodo)
+	bitmapInodes := make([]byte, superblock.S_inodes_count)
 	bitmapInodes[0] = 1
 	if _, err := file.Write(bitmapInodes); err != nil {
 		return fmt.Errorf("error escribiendo bitmap de inodos: %v", err)
 	}
 
-	// 3. Escribir Bitmap de Bloques (inicializado en 0, excepto el primer bloque)
-	bitmapBlocks := make([]byte, n*3)
+	// Bitmap de Bloques (inicializado en 0, excepto el primer bloque)
+	bitmapBlocks := make([]byte, superblock.S_blocks_count)
 	bitmapBlocks[0] = 1
 	if _, err := file.Write(bitmapBlocks); err != nil {
 		return fmt.Errorf("error escribiendo bitmap de bloques: %v", err)
 	}
 
-	// 4. Escribir Inodos (inicializar el inodo raíz)
+	// Inodos (inicializar el inodo raíz)
 	rootInode := createRootInode()
 	if err := binary.Write(file, binary.LittleEndian, &rootInode); err != nil {
 		return fmt.Errorf("error escribiendo inodo raíz: %v", err)
@@ -621,28 +473,41 @@ func writeEXT2Structures(file *os.File, partition *structs.Partition, superblock
 		emptyInode.I_block[i] = -1
 	}
 
-	for i := int64(1); i < n; i++ {
+	for i := int64(1); i < superblock.S_inodes_count; i++ {
 		if err := binary.Write(file, binary.LittleEndian, &emptyInode); err != nil {
 			return fmt.Errorf("error escribiendo inodos vacíos: %v", err)
 		}
 	}
 
-	// 5. Escribir Bloques (inicializar el bloque raíz)
-	rootBlock := createRootBlock()
-	if err := binary.Write(file, binary.LittleEndian, &rootBlock); err != nil {
+	// Bloques (inicializar el bloque raíz con la geometría de la partición)
+	geometry := geometryFor(superblock.S_block_s)
+	rootBlock := createRootBlock(geometry)
+	encoded, err := geometry.encode(&rootBlock)
+	if err != nil {
 		return fmt.Errorf("error escribiendo bloque raíz: %v", err)
 	}
-
-	// Inicializar todos los bloques restantes (incluyendo posición 1)
-	emptyBlock := structs.BloqueArchivo{}
-	for i := range emptyBlock.BContent {
-		emptyBlock.BContent[i] = 0
+	rootRaw := make([]byte, superblock.S_block_s)
+	copy(rootRaw, encoded)
+	if _, err := file.WriteAt(rootRaw, superblock.S_block_start); err != nil {
+		return fmt.Errorf("error escribiendo bloque raíz: %v", err)
+	}
+	if fast {
+		return nil
 	}
 
-	for i := int64(1); i < n*3; i++ {
-		if err := binary.Write(file, binary.LittleEndian, &emptyBlock); err != nil {
+	// Llenar con ceros todos los bloques restantes (incluyendo posición 1)
+	zeros := make([]byte, 64*1024)
+	position := superblock.S_block_start + superblock.S_block_s
+	end := superblock.S_block_start + superblock.S_blocks_count*superblock.S_block_s
+	for position < end {
+		size := end - position
+		if size > int64(len(zeros)) {
+			size = int64(len(zeros))
+		}
+		if _, err := file.WriteAt(zeros[:size], position); err != nil {
 			return fmt.Errorf("error escribiendo bloques vacíos: %v", err)
 		}
+		position += size
 	}
 
 	return nil
@@ -653,14 +518,15 @@ func createRootInode() structs.Inodos {
 	now := time.Now().Unix() // Unix timestamp
 
 	inode := structs.Inodos{
-		I_uid:   0,                      // Usuario root
-		I_gid:   0,                      // Grupo root
+		I_uid:   rootAccountID,          // Usuario root
+		I_gid:   rootAccountID,          // Grupo root
 		I_s:     0,                      // Tamaño inicial
 		I_atime: now,                    // Unix timestamp
 		I_ctime: now,                    // Unix timestamp
 		I_mtime: now,                    // Unix timestamp
 		I_type:  '0',                    // Directorio
 		I_perm:  [3]byte{'7', '5', '5'}, // Permisos 755
+		I_links: 1,
 	}
 
 	// El primer bloque apunta al bloque de directorio raíz
@@ -675,25 +541,16 @@ func createRootInode() structs.Inodos {
 }
 
 // Crear el bloque raíz (directorio raíz con . y ..)
-func createRootBlock() structs.BloqueCarpeta {
-	var block structs.BloqueCarpeta
-
-	// Inicializar todas las entradas explícitamente
-	for i := 0; i < 4; i++ {
-		// Limpiar completamente el nombre
-		for j := range block.BContent[i].BName {
-			block.BContent[i].BName[j] = 0
-		}
-		// Marcar como libre
-		block.BContent[i].BInodo = -1
-	}
+func createRootBlock(geometry blockGeometry) structs.BloqueCarpeta {
+	// Todas las entradas libres
+	block := geometry.newFolderBlock()
 
 	// Entrada para "." (directorio actual)
-	copy(block.BContent[0].BName[:], []byte("."))
+	setEntryName(&block.BContent[0], ".")
 	block.BContent[0].BInodo = 0
 
 	// Entrada para ".." (directorio padre)
-	copy(block.BContent[1].BName[:], []byte(".."))
+	setEntryName(&block.BContent[1], "..")
 	block.BContent[1].BInodo = 0
 
 	return block
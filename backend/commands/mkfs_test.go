@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Pruebas de la geometría de mkfs: con tamaños de bloque, proporciones de
+// inodos y journals distintos a los de por defecto, el superbloque guarda los
+// parámetros elegidos y los comandos trabajan sobre ellos hasta un fsck limpio.
+
+func TestMkfsGeometry(t *testing.T) {
+	cases := []struct {
+		fs          string
+		formatType  string
+		longNames   bool
+		blockSize   int64
+		inodeRatio  int64
+		journalSize int64
+	}{
+		{"2fs", "full", false, 64, 1024, 0},
+		{"2fs", "fast", true, 256, 4096, 0},
+		{"3fs", "full", false, 128, 2048, 8},
+		{"3fs", "fast", true, 512, 8192, 16},
+		{"3fs", "full", true, 1024, 16384, 4},
+	}
+
+	for _, tc := range cases {
+		name := fmt.Sprintf("%s-%s-%d-%d-%d", tc.fs, tc.formatType, tc.blockSize, tc.inodeRatio, tc.journalSize)
+		t.Run(name, func(t *testing.T) {
+			// Se formatea encima del árbol de la imagen: fast no limpia los bloques
+			img := newCrashImage(t)
+			if _, err := ExecuteMkfs(img.id, tc.formatType, tc.fs, tc.longNames, tc.blockSize, tc.inodeRatio, tc.journalSize); err != nil {
+				t.Fatalf("mkfs: %v", err)
+			}
+			session, _, err := ExecuteLogin(nil, "root", "123", img.id)
+			if err != nil {
+				t.Fatalf("login: %v", err)
+			}
+			img.checkClean(t, "recién formateada")
+
+			sb := superblockOf(t, img)
+			if sb.S_block_s != tc.blockSize {
+				t.Fatalf("S_block_s = %d, se esperaba %d", sb.S_block_s, tc.blockSize)
+			}
+			if want := img.mounted.Size / tc.inodeRatio; sb.S_inodes_count != want {
+				t.Fatalf("S_inodes_count = %d, se esperaba %d", sb.S_inodes_count, want)
+			}
+			if hasLongNames(&sb) != tc.longNames {
+				t.Fatalf("nombres largos = %v, se esperaba %v", hasLongNames(&sb), tc.longNames)
+			}
+			layout, journaled := walLayoutFor(img.mounted.Start, &sb)
+			if journaled != (tc.fs == "3fs") {
+				t.Fatalf("journal presente = %v en %s", journaled, tc.fs)
+			}
+			if journaled && layout.size != tc.journalSize*1024 {
+				t.Fatalf("journal de %d bytes, se esperaban %d", layout.size, tc.journalSize*1024)
+			}
+
+			// Solo quedan la raíz y users.txt
+			if _, err := statPath(img.mounted, "/docs", nil); err == nil {
+				t.Fatal("/docs sigue existiendo después de formatear")
+			}
+
+			// Un archivo que pasa de los bloques directos y otro que se elimina
+			data := int(geometryFor(sb.S_block_s).Data)
+			size := (directBlocksCount + 3) * data
+			if _, err := ExecuteMkdir(session, "/a/b", true); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			if _, err := ExecuteMkfile(session, "/a/b/grande.txt", false, size, ""); err != nil {
+				t.Fatalf("mkfile: %v", err)
+			}
+			if _, err := ExecuteMkfile(session, "/a/borrar.txt", false, 3*data, ""); err != nil {
+				t.Fatalf("mkfile: %v", err)
+			}
+			if _, err := ExecuteRemove(session, "/a/borrar.txt"); err != nil {
+				t.Fatalf("remove: %v", err)
+			}
+			if tc.longNames {
+				if _, err := ExecuteMkfile(session, "/a/configuracion_produccion.txt", false, 10, ""); err != nil {
+					t.Fatalf("mkfile con nombre largo: %v", err)
+				}
+			}
+			checkContent(t, img, "/a/b/grande.txt", mkfileContent(size))
+			img.checkClean(t, "tras los comandos")
+		})
+	}
+}
+
+func TestMkfsRejectsBadGeometry(t *testing.T) {
+	img := newCrashImage(t)
+	cases := []struct {
+		name        string
+		fs          string
+		blockSize   int64
+		inodeRatio  int64
+		journalSize int64
+	}{
+		{"bloque no soportado", "2fs", 100, 0, 0},
+		{"proporción menor que el bloque", "2fs", 512, 256, 0},
+		{"journal en 2fs", "2fs", 0, 0, 8},
+		{"journal muy chico", "3fs", 0, 0, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ExecuteMkfs(img.id, "full", tc.fs, false, tc.blockSize, tc.inodeRatio, tc.journalSize); ErrorCode(err) != ErrCodeInvalidArgument {
+				t.Fatalf("se esperaba %s: %v", ErrCodeInvalidArgument, err)
+			}
+		})
+	}
+	// La partición no se tocó
+	checkContent(t, img, "/docs/a.txt", mkfileContent(1500))
+	img.checkClean(t, "parámetros rechazados")
+}
@@ -6,59 +6,72 @@ import (
 	"strings"
 )
 
-func ExecuteMkgrp(groupName string) {
+func ExecuteMkgrp(session *Session, groupName string) (*CommandResult, error) {
+	res := NewCommandResult("mkgrp")
+
 	// Verificar sesión activa
-	if !RequireActiveSession() {
-		return
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
 	}
 
 	// Validar parámetros
 	if groupName == "" {
-		fmt.Println("Error: el parámetro -name es obligatorio para mkgrp.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -name es obligatorio para mkgrp")
 	}
 
-	// Obtener sesión actual
-	session := GetCurrentSession()
+	// Validar la sesión recibida
 	if session == nil {
-		fmt.Println("Error: No se pudo obtener la sesión actual.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no se pudo obtener la sesión actual")
 	}
 
 	// Verificar que solo root puede crear grupos
 	if !session.IsRoot {
-		fmt.Printf("Error: Solo el usuario 'root' puede crear grupos. Usuario actual: '%s'\n", session.User)
-		return
+		return res, newCommandError(ErrCodePermissionDenied, "solo el usuario 'root' puede crear grupos. Usuario actual: '%s'", session.User)
 	}
 
 	// Buscar la partición montada de la sesión
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: No se encontró la partición montada con ID '%s'.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
+	}
+
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
 	}
+	defer tx.rollback()
 
 	// Crear el grupo en el archivo users.txt
-	err := createGroupInUsersFile(mounted, groupName)
+	gid, err := createGroupInUsersFile(mounted, groupName)
 	if err != nil {
-		fmt.Printf("Error al crear el grupo '%s': %v\n", groupName, err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al crear el grupo '%s'", groupName)
 	}
 
-	fmt.Printf("Grupo '%s' creado exitosamente.\n", groupName)
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	res.Printf("Grupo '%s' creado exitosamente (GID %d).\n", groupName, gid)
+	res.AddPath("/users.txt")
+	res.Set("group", groupName)
+	res.Set("gid", gid)
+
+	return res, nil
 }
 
-// Crear grupo en el archivo users.txt
-func createGroupInUsersFile(mounted *MountedPartition, groupName string) error {
+// Crear grupo en el archivo users.txt. Devuelve el GID asignado.
+func createGroupInUsersFile(mounted *MountedPartition, groupName string) (int, error) {
 	currentContent, err := ReadUsersFileContent(mounted)
 	if err != nil {
-		return fmt.Errorf("error al leer users.txt: %v", err)
+		return 0, fmt.Errorf("error al leer users.txt: %v", err)
 	}
 
 	// Verificar que el grupo no existe y obtener el siguiente GID
 	nextGID, err := validateAndGetNextGID(currentContent, groupName)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// Crear la nueva línea del grupo
@@ -67,11 +80,10 @@ func createGroupInUsersFile(mounted *MountedPartition, groupName string) error {
 
 	err = WriteUsersFileContent(mounted, newContent)
 	if err != nil {
-		return fmt.Errorf("error al escribir users.txt: %v", err)
+		return 0, fmt.Errorf("error al escribir users.txt: %v", err)
 	}
 
-	fmt.Printf("Línea agregada al users.txt: %d,G,%s\n", nextGID, groupName)
-	return nil
+	return nextGID, nil
 }
 
 // Validar que el grupo no existe y obtener el siguiente GID
@@ -99,7 +111,7 @@ func validateAndGetNextGID(content, groupName string) (int, error) {
 
 			// Verificar si el grupo ya existe
 			if tipo == "G" && gid != 0 && name == groupName {
-				return 0, fmt.Errorf("el grupo '%s' ya existe", groupName)
+				return 0, newCommandError(ErrCodeAlreadyExists, "el grupo '%s' ya existe", groupName)
 			}
 
 			// Actualizar el GID máximo
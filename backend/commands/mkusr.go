@@ -6,103 +6,118 @@ import (
     "strings"
 )
 
-func ExecuteMkusr(username, password, groupName string) {
+func ExecuteMkusr(session *Session, username, password, groupName string) (*CommandResult, error) {
+    res := NewCommandResult("mkusr")
+
     // Verificar sesión activa
-    if !RequireActiveSession() {
-        return
+    if err := RequireActiveSession(session); err != nil {
+        return res, err
     }
 
     // Validar parámetros obligatorios
     if username == "" {
-        fmt.Println("Error: el parámetro -user es obligatorio para mkusr.")
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -user es obligatorio para mkusr")
     }
     if password == "" {
-        fmt.Println("Error: el parámetro -pass es obligatorio para mkusr.")
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -pass es obligatorio para mkusr")
     }
     if groupName == "" {
-        fmt.Println("Error: el parámetro -grp es obligatorio para mkusr.")
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -grp es obligatorio para mkusr")
     }
 
     // Validar longitud de parámetros
     if len(username) > 10 {
-        fmt.Printf("Error: el nombre de usuario no puede exceder 10 caracteres. Actual: %d\n", len(username))
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el nombre de usuario no puede exceder 10 caracteres. Actual: %d", len(username))
     }
     if len(password) > 10 {
-        fmt.Printf("Error: la contraseña no puede exceder 10 caracteres. Actual: %d\n", len(password))
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "la contraseña no puede exceder 10 caracteres. Actual: %d", len(password))
     }
     if len(groupName) > 10 {
-        fmt.Printf("Error: el nombre del grupo no puede exceder 10 caracteres. Actual: %d\n", len(groupName))
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el nombre del grupo no puede exceder 10 caracteres. Actual: %d", len(groupName))
     }
 
-    // Obtener sesión actual
-    session := GetCurrentSession()
+    // Validar la sesión recibida
     if session == nil {
-        fmt.Println("Error: No se pudo obtener la sesión actual.")
-        return
+        return res, newCommandError(ErrCodeNoSession, "no se pudo obtener la sesión actual")
     }
 
     // Verificar que solo root puede crear usuarios
     if session.User != "root" {
-        fmt.Printf("Error: Solo el usuario 'root' puede crear usuarios. Usuario actual: '%s'\n", session.User)
-        return
+        return res, newCommandError(ErrCodePermissionDenied, "solo el usuario 'root' puede crear usuarios. Usuario actual: '%s'", session.User)
     }
 
     // Buscar la partición montada de la sesión
     mounted := GetMountedPartition(session.PartitionID)
     if mounted == nil {
-        fmt.Printf("Error: No se encontró la partición montada con ID '%s'.\n", session.PartitionID)
-        return
+        return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
+    }
+
+    // Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+    tx, err := beginTransaction(mounted)
+    if err != nil {
+        return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
     }
+    defer tx.rollback()
 
     // Crear el usuario en el archivo users.txt
-    err := createUserInUsersFile(mounted, username, password, groupName)
+    uid, err := createUserInUsersFile(mounted, username, password, groupName)
     if err != nil {
-        fmt.Printf("Error al crear el usuario '%s': %v\n", username, err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al crear el usuario '%s'", username)
+    }
+
+    // Confirmar los cambios en el journal
+    if err := tx.commit(); err != nil {
+        return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
     }
 
-    fmt.Printf("Usuario '%s' creado exitosamente.\n", username)
-    fmt.Printf("   Usuario: %s\n", username)
-    fmt.Printf("   Grupo: %s\n", groupName)
+    res.Printf("Usuario '%s' creado exitosamente.\n", username)
+    res.Printf("   Usuario: %s\n", username)
+    res.Printf("   Grupo: %s\n", groupName)
+    res.Printf("   UID: %d\n", uid)
+    res.AddPath("/users.txt")
+    res.Set("user", username)
+    res.Set("uid", uid)
+    res.Set("group", groupName)
+
+    return res, nil
 }
 
-// Crear usuario en el archivo users.txt
-func createUserInUsersFile(mounted *MountedPartition, username, password, groupName string) error {
+// Crear usuario en el archivo users.txt. Devuelve el UID asignado.
+func createUserInUsersFile(mounted *MountedPartition, username, password, groupName string) (int, error) {
     currentContent, err := ReadUsersFileContent(mounted)
     if err != nil {
-        return fmt.Errorf("error al leer users.txt: %v", err)
+        return 0, fmt.Errorf("error al leer users.txt: %v", err)
     }
 
     // Validar y obtener información necesaria
-    groupGID, err := validateAndGetUserInfo(currentContent, username, groupName)
+    nextUID, err := validateAndGetUserInfo(currentContent, username, groupName)
     if err != nil {
-        return err
+        return 0, err
     }
 
-    // Crear la nueva línea del usuario
-    newUserLine := fmt.Sprintf("%s,U,%s,%s,%s\n", groupGID, groupName, username, password)
+    // Crear la nueva línea del usuario (la contraseña se guarda como hash)
+    storedPassword, err := hashPassword(password)
+    if err != nil {
+        return 0, err
+    }
+    newUserLine := fmt.Sprintf("%d,U,%s,%s,%s\n", nextUID, groupName, username, storedPassword)
     newContent := currentContent + newUserLine
 
     err = WriteUsersFileContent(mounted, newContent)
     if err != nil {
-        return fmt.Errorf("error al escribir users.txt: %v", err)
+        return 0, fmt.Errorf("error al escribir users.txt: %v", err)
     }
 
-    fmt.Printf("Línea agregada al users.txt: %s,U,%s,%s,%s\n", groupGID, groupName, username, password)
-    return nil
+    return nextUID, nil
 }
 
-// Validar que el usuario no existe, el grupo existe y obtener IDs
-func validateAndGetUserInfo(content, username, groupName string) (string, error) {
+// Validar que el usuario no existe, el grupo existe y obtener el siguiente UID.
+// Cada usuario tiene su propio UID para que los inodos (y las cuotas) distingan
+// a los usuarios de un mismo grupo.
+func validateAndGetUserInfo(content, username, groupName string) (int, error) {
     lines := strings.Split(content, "\n")
-    groupGID := ""
     groupExists := false
+    maxUID := 0
     
     for _, line := range lines {
         line = strings.TrimSpace(line)
@@ -125,25 +140,25 @@ func validateAndGetUserInfo(content, username, groupName string) (string, error)
                 name := strings.TrimSpace(parts[2])
                 if name == groupName {
                     groupExists = true
-                    groupGID = idStr
-                    fmt.Printf("Grupo '%s' encontrado con GID=%s\n", groupName, groupGID)
                 }
             } else if tipo == "U" && id != 0 { // Es un usuario activo
                 if len(parts) >= 4 {
                     existingUsername := strings.TrimSpace(parts[3])
                     if existingUsername == username {
-                        return "", fmt.Errorf("el usuario '%s' ya existe", username)
+                        return 0, newCommandError(ErrCodeAlreadyExists, "el usuario '%s' ya existe", username)
                     }
                 }
+                if id > maxUID {
+                    maxUID = id
+                }
             }
         }
     }
     
     // Verificar que el grupo existe
     if !groupExists {
-        return "", fmt.Errorf("el grupo '%s' no existe", groupName)
+        return 0, newCommandError(ErrCodeNotFound, "el grupo '%s' no existe", groupName)
     }
     
-    // Solo retornar el GID del grupo
-    return groupGID, nil
-}
\ No newline at end of file
+    return maxUID + 1, nil
+}
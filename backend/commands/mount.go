@@ -2,10 +2,10 @@ package commands
 
 import (
 	"backend/structs"
-	"encoding/binary"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 )
 
 // Agregar Start a MountedPartition (solo para uso en memoria)
@@ -17,15 +17,21 @@ type MountedPartition struct {
 	Start int64 // Posición de inicio de la partición
 }
 
-var mountedPartitions []MountedPartition
-var diskCounters = make(map[string]int)
+// Tabla de montajes en memoria. mountsMutex protege ambas variables; si además
+// se necesita el bloqueo de un disco, se toma antes que este.
+var (
+	mountsMutex       sync.RWMutex
+	mountedPartitions []MountedPartition
+	diskCounters      = make(map[string]int)
+)
+
+// La tabla de montajes se persiste en mount_table.go y se restaura al iniciar
 
-// NOTE: mount state is kept only in memory (no on-disk persistence)
+func ExecuteMount(path string, name string) (*CommandResult, error) {
+	res := NewCommandResult("mount")
 
-func ExecuteMount(path string, name string) {
 	if name == "" {
-		fmt.Println("Error: el parámetro -name es obligatorio para mount.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -name es obligatorio para mount")
 	}
 
 	// Asegurar que el archivo tiene extensión .mia
@@ -35,152 +41,62 @@ func ExecuteMount(path string, name string) {
 
 	// Verificar que el archivo existe
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		fmt.Printf("Error: El archivo '%s' no existe.\n", path)
-		return
+		return res, newCommandError(ErrCodeNotFound, "el archivo '%s' no existe", path)
 	}
 
+	// Nadie más puede usar el disco mientras se actualiza el MBR
+	defer lockDisk(path)()
+
 	// Abrir el archivo del disco EN MODO LECTURA/ESCRITURA
-	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	disk, err := OpenDisk(path, os.O_RDWR)
 	if err != nil {
-		fmt.Printf("Error al abrir el archivo: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el archivo")
 	}
-	defer file.Close()
+	defer disk.Close()
 
-	// Leer el MBR
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("Error al leer el MBR: %v\n", err)
-		return
+	// PASO 1 y 2: Buscar en particiones primarias y luego en las lógicas
+	partition, err := disk.FindPartition(name)
+	if err != nil {
+		return res, newCommandError(ErrCodeNotFound, "no se encontró la partición '%s' en el disco '%s'", name, path)
 	}
-
-	// Variables para la partición encontrada
-	var foundPartition *structs.Partition
-	var partitionIndex int = -1
-	var partitionStart int64
-	var partitionSize int64
-	isLogical := false
-
-	// PASO 1: Buscar en particiones primarias
-	for i, partition := range mbr.Mbr_partitions {
-		if partition.Part_status != '0' && partition.Part_s > 0 {
-			partitionNameBytes := partition.Part_name[:]
-			nullIndex := len(partitionNameBytes)
-			for j, b := range partitionNameBytes {
-				if b == 0 {
-					nullIndex = j
-					break
-				}
-			}
-			partitionName := strings.TrimSpace(string(partitionNameBytes[:nullIndex]))
-
-			if strings.EqualFold(partitionName, name) || partitionName == name {
-				// Solo montar particiones primarias (P o p)
-				if partition.Part_type == 'P' || partition.Part_type == 'p' {
-					partitionCopy := partition
-					foundPartition = &partitionCopy
-					partitionIndex = i
-					partitionStart = partition.Part_start
-					partitionSize = partition.Part_s
-					break
-				} else if partition.Part_type == 'E' || partition.Part_type == 'e' {
-					fmt.Printf("Error: No se pueden montar particiones extendidas. La partición '%s' es de tipo extendida.\n", name)
-					return
-				}
-			}
-		}
+	if partition.Type == 'E' {
+		return res, newCommandError(ErrCodeUnsupported, "no se pueden montar particiones extendidas. La partición '%s' es de tipo extendida", name)
 	}
-
-	// PASO 2: Si no se encontró en primarias, buscar en particiones lógicas
-	if foundPartition == nil {
-		fmt.Printf("🔍 Buscando '%s' en particiones lógicas...\n", name)
-
-		// Encontrar la partición extendida
-		var extendedPartition *structs.Partition
-		for i := 0; i < 4; i++ {
-			if mbr.Mbr_partitions[i].Part_s > 0 &&
-				(mbr.Mbr_partitions[i].Part_type == 'E' || mbr.Mbr_partitions[i].Part_type == 'e') {
-				extendedPartition = &mbr.Mbr_partitions[i]
-				break
-			}
-		}
-
-		if extendedPartition != nil {
-			// Recorrer la cadena de EBRs
-			currentEBRPos := extendedPartition.Part_start
-
-			for currentEBRPos != -1 {
-				file.Seek(currentEBRPos, 0)
-				var ebr structs.EBR
-				if err := binary.Read(file, binary.LittleEndian, &ebr); err != nil {
-					break
-				}
-
-				// Si el EBR está vacío, terminar
-				if ebr.PartS == 0 {
-					break
-				}
-
-				// Extraer el nombre de la partición lógica
-				ebrNameBytes := ebr.PartName[:]
-				nullIndex := len(ebrNameBytes)
-				for j, b := range ebrNameBytes {
-					if b == 0 {
-						nullIndex = j
-						break
-					}
-				}
-				ebrName := strings.TrimSpace(string(ebrNameBytes[:nullIndex]))
-
-				// Comparar nombres
-				if strings.EqualFold(ebrName, name) || ebrName == name {
-					fmt.Printf("✅ Partición lógica '%s' encontrada en EBR\n", name)
-					partitionStart = ebr.PartStart
-					partitionSize = ebr.PartS
-					isLogical = true
-					break
-				}
-
-				// Siguiente EBR en la cadena
-				currentEBRPos = ebr.PartNext
-			}
-		}
-
-		// Si aún no se encontró, mostrar error
-		if !isLogical {
-			fmt.Printf("Error: No se encontró la partición '%s' en el disco '%s'.\n", name, path)
-			return
-		}
+	isLogical := partition.Type == 'L'
+	if isLogical {
+		res.Printf("✅ Partición lógica '%s' encontrada en EBR\n", name)
 	}
+	partitionStart := partition.Start
+	partitionSize := partition.Size
 
 	// PASO 3: Verificar si la partición ya está montada
+	mountsMutex.Lock()
+	defer mountsMutex.Unlock()
 	for _, mounted := range mountedPartitions {
 		if mounted.Path == path && mounted.Name == name {
-			fmt.Printf("Error: La partición '%s' del disco '%s' ya está montada con ID '%s'.\n", name, path, mounted.ID)
-			return
+			return res, newCommandError(ErrCodeAlreadyExists, "la partición '%s' del disco '%s' ya está montada con ID '%s'", name, path, mounted.ID)
 		}
 	}
 
-	// PASO 4: Generar el ID y correlativo (SOLO EN MEMORIA)
+	// PASO 4: Generar el ID y correlativo
 	id := generatePartitionID(path)
 	correlativo := generateCorrelativo()
 
-	// PASO 5: Actualizar SOLO el MBR si es partición primaria
-	// (NO tocamos los EBRs, solo guardamos la info en memoria)
-	if !isLogical && partitionIndex != -1 {
-		// Actualizar la partición primaria en el MBR
-		mbr.Mbr_partitions[partitionIndex].Part_correlative = int64(correlativo)
-		copy(mbr.Mbr_partitions[partitionIndex].Part_id[:], []byte(id)[:4])
-
-		// Escribir el MBR actualizado
-		file.Seek(0, 0)
-		if err := binary.Write(file, binary.LittleEndian, &mbr); err != nil {
-			fmt.Printf("Error al actualizar el MBR: %v\n", err)
-			return
+	// PASO 5: Actualizar SOLO la tabla (MBR o GPT) si es partición primaria
+	// (NO tocamos los EBRs, las lógicas se recuerdan en la tabla de montajes)
+	if !isLogical && partition.Index != -1 {
+		// Actualizar la partición primaria en la tabla
+		entry := disk.PrimaryPartitions()[partition.Index]
+		entry.Part_correlative = int64(correlativo)
+		copy(entry.Part_id[:], []byte(id)[:4])
+
+		// Escribir la tabla actualizada
+		if err := disk.WriteTable(); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al actualizar la tabla de particiones")
 		}
 	}
 
-	// PASO 6: Crear la entrada en memoria (con o sin actualizar disco)
+	// PASO 6: Crear la entrada de montaje
 	mountedPartition := MountedPartition{
 		ID:    id,
 		Path:  path,
@@ -189,8 +105,11 @@ func ExecuteMount(path string, name string) {
 		Start: partitionStart, // ← Guardar posición de inicio
 	}
 
-	// Agregar a la lista de particiones montadas (memoria solamente)
+	// Agregar a la lista de particiones montadas y persistir la tabla
 	mountedPartitions = append(mountedPartitions, mountedPartition)
+	if err := saveMountTable(); err != nil {
+		res.Printf("⚠️ No se pudo guardar la tabla de montajes: %v\n", err)
+	}
 
 	// PASO 7: Mostrar mensaje de éxito
 	partitionType := "Primaria"
@@ -198,11 +117,26 @@ func ExecuteMount(path string, name string) {
 		partitionType = "Lógica"
 	}
 
-	fmt.Printf("Partición '%s' (%s) montada exitosamente.\n", name, partitionType)
-	fmt.Printf("   Disco: %s\n", path)
-	fmt.Printf("   ID asignado: %s\n", id)
-	fmt.Printf("   Correlativo: %d\n", correlativo)
-	fmt.Printf("   Tamaño: %d bytes\n", partitionSize)
+	res.Printf("Partición '%s' (%s) montada exitosamente.\n", name, partitionType)
+	res.Printf("   Disco: %s\n", path)
+	res.Printf("   ID asignado: %s\n", id)
+	res.Printf("   Correlativo: %d\n", correlativo)
+	res.Printf("   Tamaño: %d bytes\n", partitionSize)
+
+	// Rehacer o descartar una transacción del journal interrumpida
+	if recovery, err := recoverMountedJournal(&mountedPartition); err != nil {
+		res.Printf("⚠️ No se pudo revisar el journal: %v\n", err)
+	} else if message := describeJournalRecovery(recovery); message != "" {
+		res.Println(message)
+	}
+
+	res.AddPath(path)
+	res.Set("id", id)
+	res.Set("name", name)
+	res.Set("type", partitionType)
+	res.Set("size", partitionSize)
+
+	return res, nil
 }
 
 // Generar correlativo secuencial
@@ -227,123 +161,186 @@ func generatePartitionID(diskPath string) string {
 		diskCounters[diskPath] = partitionNumber
 	}
 
-	// Determinar la letra según el número de discos diferentes montados
-	currentLetter := getLetter()
+	// La letra identifica al disco: todas sus particiones comparten la misma
+	currentLetter := diskLetter(diskPath)
 
 	// Formato: últimos 2 dígitos + número de partición + letra
 	return fmt.Sprintf("%s%d%c", carnetSuffix, partitionNumber, currentLetter)
 }
 
-func getLetter() byte {
-	// Si es el primer disco de esta "serie", usar A
-	// Si ya hay discos montados, verificar si necesitamos nueva letra
-	uniqueDisks := len(diskCounters)
-
-	if uniqueDisks == 1 {
-		return 'A'
+// diskLetter - Letra del disco: la de sus particiones ya montadas o la primera
+// que no use otro disco (contar los discos distintos repetía IDs al alternarlos)
+func diskLetter(diskPath string) byte {
+	used := make(map[byte]bool)
+	for _, mounted := range mountedPartitions {
+		letter := mounted.ID[len(mounted.ID)-1]
+		if mounted.Path == diskPath {
+			return letter
+		}
+		used[letter] = true
 	}
 
-	// Para múltiples discos, usar letras consecutivas
 	letters := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	if uniqueDisks-1 < len(letters) {
-		return letters[uniqueDisks-1]
+	for i := 0; i < len(letters); i++ {
+		if !used[letters[i]] {
+			return letters[i]
+		}
 	}
 
 	return 'Z' // Fallback si se excede el alfabeto
 }
 
-func ExecuteMounted() {
+func ExecuteMounted() (*CommandResult, error) {
+	res := NewCommandResult("mounted")
+
+	mountsMutex.RLock()
+	defer mountsMutex.RUnlock()
+
 	if len(mountedPartitions) == 0 {
-		fmt.Println("No hay particiones montadas.")
-		return
+		res.Println("No hay particiones montadas.")
+		return res, nil
 	}
 
-	fmt.Println("Particiones montadas:")
+	res.Println("Particiones montadas:")
+	var ids []string
 	for _, mounted := range mountedPartitions {
-		fmt.Printf("ID: %s\n", mounted.ID)
-		fmt.Printf("Nombre: %s\n", mounted.Name)
-		fmt.Printf("Ruta: %s\n", mounted.Path)
-		fmt.Println("-------------------------")
+		ids = append(ids, mounted.ID)
+		res.Printf("ID: %s\n", mounted.ID)
+		res.Printf("Nombre: %s\n", mounted.Name)
+		res.Printf("Ruta: %s\n", mounted.Path)
+		res.Println("-------------------------")
 	}
+	res.Set("ids", ids)
+
+	return res, nil
 }
 
-func ExecuteUnmount(id string) {
+func ExecuteUnmount(id string) (*CommandResult, error) {
+	res := NewCommandResult("unmount")
+
 	if id == "" {
-		fmt.Println("Error: el parámetro -id es obligatorio para unmount.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio para unmount")
+	}
+
+	target := GetMountedPartition(id)
+	if target == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no hay ninguna partición montada con ID '%s'", id)
 	}
+	defer lockDisk(target.Path)()
 
+	mountsMutex.Lock()
+	defer mountsMutex.Unlock()
 	for i, mounted := range mountedPartitions {
 		if strings.EqualFold(mounted.ID, id) {
-			// Abrir el archivo del disco en modo lectura/escritura
-			file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+			// Abrir el disco en modo lectura/escritura (MBR y, si tiene, tabla GPT)
+			disk, err := OpenDisk(mounted.Path, os.O_RDWR)
 			if err != nil {
-				fmt.Printf("Error al abrir el archivo del disco: %v\n", err)
-				return
-			}
-			defer file.Close()
-
-			// Leer el MBR
-			var mbr structs.MBR
-			if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-				fmt.Printf("Error al leer el MBR: %v\n", err)
-				return
+				return res, wrapCommandError(ErrCodeIO, err, "error al abrir el archivo del disco")
 			}
+			defer disk.Close()
 
 			// Buscar la partición por ID y limpiar su ID y correlativo
-			for j, partition := range mbr.Mbr_partitions {
+			partitions := disk.PrimaryPartitions()
+			for _, partition := range partitions {
 				partID := strings.TrimSpace(string(partition.Part_id[:]))
 				// Comparación insensible a mayúsculas/minúsculas para tolerar IDs como '531A' vs '531a'
 				if strings.EqualFold(partID, id) {
 					// Limpiar ID y correlativo
-					for k := range mbr.Mbr_partitions[j].Part_id {
-						mbr.Mbr_partitions[j].Part_id[k] = 0
+					for k := range partition.Part_id {
+						partition.Part_id[k] = 0
 					}
-					mbr.Mbr_partitions[j].Part_correlative = 0
+					partition.Part_correlative = 0
 
-					// Escribir el MBR actualizado de vuelta al disco
-					file.Seek(0, 0)
-					if err := binary.Write(file, binary.LittleEndian, &mbr); err != nil {
-						fmt.Printf("Error al actualizar el MBR: %v\n", err)
-						return
+					// Escribir la tabla actualizada de vuelta al disco
+					if err := disk.WriteTable(); err != nil {
+						return res, wrapCommandError(ErrCodeIO, err, "error al actualizar la tabla de particiones")
 					}
 
-					// Remover de la lista de particiones montadas (memoria solamente)
+					// Remover de la lista de particiones montadas y persistir la tabla
 					mountedPartitions = append(mountedPartitions[:i], mountedPartitions[i+1:]...)
-					fmt.Printf("Partición con ID '%s' desmontada exitosamente.\n", id)
-					return
+					if err := saveMountTable(); err != nil {
+						res.Printf("⚠️ No se pudo guardar la tabla de montajes: %v\n", err)
+					}
+					res.Printf("Partición con ID '%s' desmontada exitosamente.\n", id)
+					unmountWebDAV(res, id)
+					res.Set("id", id)
+					return res, nil
 				}
 			}
 
-			fmt.Printf("Error: No se encontró la partición con ID '%s' en el disco.\n", id)
-			return
+			// Las particiones lógicas no guardan su ID en el MBR: solo se quitan de la tabla
+			if !hasPrimaryPartitionName(partitions, mounted.Name) {
+				mountedPartitions = append(mountedPartitions[:i], mountedPartitions[i+1:]...)
+				if err := saveMountTable(); err != nil {
+					res.Printf("⚠️ No se pudo guardar la tabla de montajes: %v\n", err)
+				}
+				res.Printf("Partición con ID '%s' desmontada exitosamente.\n", id)
+				unmountWebDAV(res, id)
+				res.Set("id", id)
+				return res, nil
+			}
+
+			return res, newCommandError(ErrCodeNotFound, "no se encontró la partición con ID '%s' en el disco", id)
 		}
 	}
 
-	fmt.Printf("Error: No hay ninguna partición montada con ID '%s'.\n", id)
+	return res, newCommandError(ErrCodeNotMounted, "no hay ninguna partición montada con ID '%s'", id)
+}
+
+// unmountWebDAV - Detener el servidor WebDAV de la partición desmontada
+func unmountWebDAV(res *CommandResult, id string) {
+	if stopWebDAV(id) {
+		res.Printf("🛑 Servidor WebDAV de '%s' detenido.\n", id)
+	}
 }
 
-// Función para obtener una partición montada por ID (ahora exportada)
+// Función para obtener una partición montada por ID (ahora exportada).
+// Devuelve una copia: la tabla puede cambiar mientras el comando la usa.
 func GetMountedPartition(id string) *MountedPartition {
-	// Montajes se mantienen en memoria. Si no hay ninguno, devolvemos nil.
+	mountsMutex.RLock()
+	defer mountsMutex.RUnlock()
+
+	// Si no hay ninguno montado, devolvemos nil.
 	// Comparación insensible a mayúsculas/minúsculas para mayor tolerancia
 	idLower := strings.ToLower(id)
-	for i, mounted := range mountedPartitions {
+	for _, mounted := range mountedPartitions {
 		if strings.ToLower(mounted.ID) == idLower {
-			return &mountedPartitions[i]
+			found := mounted
+			return &found
 		}
 	}
 	return nil
 }
 
+// updateMountedPartition - Guardar la nueva posición y tamaño de una partición
+// montada después de redimensionarla (fdisk -add, resizefs)
+func updateMountedPartition(path string, name string, start int64, size int64) {
+	mountsMutex.Lock()
+	defer mountsMutex.Unlock()
+
+	changed := false
+	for i := range mountedPartitions {
+		mounted := &mountedPartitions[i]
+		if mounted.Path == path && strings.EqualFold(mounted.Name, name) && (mounted.Start != start || mounted.Size != size) {
+			mounted.Start, mounted.Size = start, size
+			changed = true
+		}
+	}
+	if changed {
+		saveMountTable()
+	}
+}
+
 // GetMountedPartitionsOnly devuelve las particiones montadas en formato compatible
 func GetMountedPartitionsOnly() []map[string]interface{} {
-	// Montajes se mantienen en memoria. Si no hay ninguno, devolver lista vacía.
+	// Si no hay ninguno montado, devolver lista vacía.
 	var result []map[string]interface{}
 
 	// Agrupar por disco
 	diskMap := make(map[string][]map[string]interface{})
 
+	mountsMutex.RLock()
+	defer mountsMutex.RUnlock()
 	for _, mp := range mountedPartitions {
 		partition := map[string]interface{}{
 			"id":        mp.ID,
@@ -372,4 +369,13 @@ func GetMountedPartitionsOnly() []map[string]interface{} {
 	return result
 }
 
-// Mount state is intentionally kept in-memory only. No persistence functions.
+// hasPrimaryPartitionName - Verificar si el nombre corresponde a una partición
+// de la tabla del disco (MBR o GPT)
+func hasPrimaryPartitionName(partitions []*structs.Partition, name string) bool {
+	for _, partition := range partitions {
+		if partition.Part_s > 0 && strings.EqualFold(strings.TrimSpace(structs.BytesToString(partition.Part_name[:])), name) {
+			return true
+		}
+	}
+	return false
+}
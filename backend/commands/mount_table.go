@@ -0,0 +1,280 @@
+package commands
+
+import (
+	"backend/structs"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// TABLA DE MONTAJES PERSISTENTE
+// Se guarda junto al registro de discos para que los montajes sobrevivan
+// a un reinicio del backend.
+type MountTable struct {
+	Partitions   []MountTableEntry `json:"partitions"`
+	DiskCounters map[string]int    `json:"diskCounters"`
+}
+
+// MountTableEntry - Entrada persistida de una partición montada
+type MountTableEntry struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	Start     int64  `json:"start"`
+	Signature int64  `json:"signature"` // Firma del disco al momento de montar
+}
+
+var mountTableFilePath = filepath.Join(os.TempDir(), "extreamfs_mount_table.json")
+
+// readDiskMBR - Leer el MBR de un disco por su ruta
+func readDiskMBR(diskPath string) (*structs.MBR, error) {
+	file, err := openDisk(diskPath, os.O_RDONLY)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir el disco: %v", err)
+	}
+	defer file.Close()
+
+	var mbr structs.MBR
+	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
+		return nil, fmt.Errorf("error al leer el MBR: %v", err)
+	}
+
+	return &mbr, nil
+}
+
+// saveMountTable - Guardar la tabla de montajes actual a archivo (con mountsMutex tomado)
+func saveMountTable() error {
+	table := MountTable{
+		Partitions:   []MountTableEntry{},
+		DiskCounters: diskCounters,
+	}
+
+	signatures := make(map[string]int64)
+	for _, mounted := range mountedPartitions {
+		signature, ok := signatures[mounted.Path]
+		if !ok {
+			if mbr, err := readDiskMBR(mounted.Path); err == nil {
+				signature = mbr.Mbr_dsk_signature
+			}
+			signatures[mounted.Path] = signature
+		}
+
+		table.Partitions = append(table.Partitions, MountTableEntry{
+			ID:        mounted.ID,
+			Path:      mounted.Path,
+			Name:      mounted.Name,
+			Size:      mounted.Size,
+			Start:     mounted.Start,
+			Signature: signature,
+		})
+	}
+
+	data, err := json.MarshalIndent(table, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(mountTableFilePath, data, 0644)
+}
+
+// loadMountTable - Leer la tabla de montajes persistida (vacía si no existe)
+func loadMountTable() MountTable {
+	table := MountTable{DiskCounters: make(map[string]int)}
+
+	data, err := os.ReadFile(mountTableFilePath)
+	if err != nil {
+		return table
+	}
+
+	if err := json.Unmarshal(data, &table); err != nil {
+		return MountTable{DiskCounters: make(map[string]int)}
+	}
+	if table.DiskCounters == nil {
+		table.DiskCounters = make(map[string]int)
+	}
+
+	return table
+}
+
+// RestoreMountedPartitions - Reconstruir la tabla de montajes al iniciar.
+// Las particiones primarias se toman del MBR (o de la tabla GPT) de cada disco registrado (Part_id),
+// las lógicas de la tabla persistida si su EBR sigue existiendo. Las entradas de
+// discos eliminados o modificados se descartan.
+func RestoreMountedPartitions() {
+	table := loadMountTable()
+
+	// Discos a revisar: registrados + los que aparecen en la tabla persistida
+	var diskPaths []string
+	seenPaths := make(map[string]bool)
+	for _, diskPath := range GetRegisteredDiskPaths() {
+		if !seenPaths[diskPath] {
+			seenPaths[diskPath] = true
+			diskPaths = append(diskPaths, diskPath)
+		}
+	}
+	for _, entry := range table.Partitions {
+		if !seenPaths[entry.Path] {
+			seenPaths[entry.Path] = true
+			diskPaths = append(diskPaths, entry.Path)
+		}
+	}
+
+	var restored []MountedPartition
+	usedIDs := make(map[string]bool)
+	dropped := 0
+
+	for _, diskPath := range diskPaths {
+		disk, err := OpenDisk(diskPath, os.O_RDONLY)
+		if err != nil {
+			continue // El disco ya no existe o no se puede leer
+		}
+		mbr := &disk.MBR
+		partitions := disk.PrimaryPartitions()
+		disk.Close()
+
+		// PASO 1: Particiones primarias con Part_id asignado en el MBR (o en la tabla GPT)
+		for _, partition := range partitions {
+			if partition.Part_status == '0' || partition.Part_s <= 0 {
+				continue
+			}
+			if partition.Part_type != 'P' && partition.Part_type != 'p' {
+				continue
+			}
+
+			id := strings.TrimSpace(strings.TrimRight(string(partition.Part_id[:]), "\x00"))
+			if id == "" || usedIDs[strings.ToLower(id)] {
+				continue
+			}
+
+			usedIDs[strings.ToLower(id)] = true
+			restored = append(restored, MountedPartition{
+				ID:    id,
+				Path:  diskPath,
+				Name:  strings.TrimSpace(structs.BytesToString(partition.Part_name[:])),
+				Size:  partition.Part_s,
+				Start: partition.Part_start,
+			})
+		}
+
+		// PASO 2: Particiones lógicas de la tabla persistida (el EBR no guarda el ID)
+		for _, entry := range table.Partitions {
+			if entry.Path != diskPath || usedIDs[strings.ToLower(entry.ID)] {
+				continue
+			}
+
+			if entry.Signature != mbr.Mbr_dsk_signature || !logicalPartitionExists(diskPath, mbr, entry) {
+				dropped++
+				continue
+			}
+
+			usedIDs[strings.ToLower(entry.ID)] = true
+			restored = append(restored, MountedPartition{
+				ID:    entry.ID,
+				Path:  entry.Path,
+				Name:  entry.Name,
+				Size:  entry.Size,
+				Start: entry.Start,
+			})
+		}
+	}
+
+	// Entradas persistidas cuyo disco ya no existe
+	for _, entry := range table.Partitions {
+		if !usedIDs[strings.ToLower(entry.ID)] {
+			if _, err := os.Stat(entry.Path); err != nil {
+				dropped++
+			}
+		}
+	}
+
+	// Reconstruir los contadores por disco para no repetir IDs
+	mountsMutex.Lock()
+	diskCounters = table.DiskCounters
+	for _, mounted := range restored {
+		number := partitionNumberFromID(mounted.ID)
+		if diskCounters[mounted.Path] < number {
+			diskCounters[mounted.Path] = number
+		}
+	}
+
+	mountedPartitions = restored
+
+	if err := saveMountTable(); err != nil {
+		fmt.Printf("⚠️ No se pudo guardar la tabla de montajes: %v\n", err)
+	}
+	mountsMutex.Unlock()
+
+	if len(restored) > 0 {
+		fmt.Printf("🔄 Particiones montadas restauradas: %d\n", len(restored))
+	}
+	if dropped > 0 {
+		fmt.Printf("🧹 Montajes obsoletos descartados: %d\n", dropped)
+	}
+
+	// Rehacer o descartar transacciones interrumpidas por un cierre inesperado
+	for _, mounted := range restored {
+		unlock := lockDisk(mounted.Path)
+		recovery, err := recoverMountedJournal(&mounted)
+		unlock()
+		if err != nil {
+			fmt.Printf("⚠️ No se pudo revisar el journal de '%s': %v\n", mounted.ID, err)
+		} else if message := describeJournalRecovery(recovery); message != "" {
+			fmt.Printf("%s en '%s'\n", message, mounted.ID)
+		}
+	}
+}
+
+// logicalPartitionExists - Verificar que una partición lógica siga en la cadena de EBRs
+func logicalPartitionExists(diskPath string, mbr *structs.MBR, entry MountTableEntry) bool {
+	var extendedPartition *structs.Partition
+	for i := 0; i < 4; i++ {
+		if mbr.Mbr_partitions[i].Part_s > 0 &&
+			(mbr.Mbr_partitions[i].Part_type == 'E' || mbr.Mbr_partitions[i].Part_type == 'e') {
+			extendedPartition = &mbr.Mbr_partitions[i]
+			break
+		}
+	}
+	if extendedPartition == nil {
+		return false
+	}
+
+	file, err := openDisk(diskPath, os.O_RDONLY)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	currentEBRPos := extendedPartition.Part_start
+	for visited := 0; currentEBRPos != -1 && visited < 1024; visited++ {
+		file.Seek(currentEBRPos, 0)
+		var ebr structs.EBR
+		if err := binary.Read(file, binary.LittleEndian, &ebr); err != nil || ebr.PartS == 0 {
+			return false
+		}
+
+		ebrName := strings.TrimSpace(structs.BytesToString(ebr.PartName[:]))
+		if strings.EqualFold(ebrName, entry.Name) {
+			return ebr.PartStart == entry.Start && ebr.PartS == entry.Size
+		}
+
+		currentEBRPos = ebr.PartNext
+	}
+
+	return false
+}
+
+// partitionNumberFromID - Extraer el número de partición de un ID con formato 53<n><letra>
+func partitionNumberFromID(id string) int {
+	if len(id) < 4 {
+		return 0
+	}
+	number, err := strconv.Atoi(id[2 : len(id)-1])
+	if err != nil {
+		return 0
+	}
+	return number
+}
@@ -0,0 +1,76 @@
+package commands
+
+// ExecutePasswd - Cambiar la contraseña de un usuario. Cada usuario puede
+// cambiar la suya; root, la de cualquiera.
+func ExecutePasswd(session *Session, username string, password string) (*CommandResult, error) {
+	res := NewCommandResult("passwd")
+
+	// Verificar sesión activa
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
+	}
+
+	// Validar parámetros
+	if password == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -pass es obligatorio para passwd")
+	}
+	if len(password) > 10 {
+		return res, newCommandError(ErrCodeInvalidArgument, "la contraseña no puede exceder 10 caracteres. Actual: %d", len(password))
+	}
+	if username == "" {
+		username = session.User
+	}
+
+	// Solo root puede cambiar la contraseña de otro usuario
+	if username != session.User && !session.IsRoot {
+		return res, newCommandError(ErrCodePermissionDenied, "solo el usuario 'root' puede cambiar la contraseña de otro usuario. Usuario actual: '%s'", session.User)
+	}
+
+	// Buscar la partición montada de la sesión
+	mounted := GetMountedPartition(session.PartitionID)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
+	}
+
+	// El hash se calcula antes de tomar el bloqueo del disco
+	storedPassword, err := hashPassword(password)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeInternal, err, "error al calcular el hash de la contraseña")
+	}
+
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
+	}
+	defer tx.rollback()
+
+	usersContent, err := ReadUsersFileContent(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al leer users.txt")
+	}
+
+	newContent, found := setUserPassword(usersContent, username, storedPassword)
+	if !found {
+		return res, newCommandError(ErrCodeNotFound, "el usuario '%s' no existe", username)
+	}
+	if err := WriteUsersFileContent(mounted, newContent); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al escribir users.txt")
+	}
+
+	// La bitácora no guarda la contraseña ni su hash
+	if err := WriteJournal(res, mounted, "passwd", "/users.txt", username); err != nil {
+		res.Printf("⚠️ No se pudo escribir al journal: %v\n", err)
+	}
+
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	res.Printf("✅ Contraseña del usuario '%s' actualizada.\n", username)
+	res.AddPath("/users.txt")
+	res.Set("user", username)
+
+	return res, nil
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CONTRASEÑAS DE users.txt
+// La contraseña de cada usuario se guarda como un hash PBKDF2-SHA256 con sal:
+//   pbkdf2-sha256$<iteraciones>$<sal>$<hash>
+// (sal y hash en base64 sin relleno, que no usa comas). Las entradas antiguas
+// en texto plano se siguen aceptando y se reemplazan por el hash en el
+// siguiente login correcto.
+
+const (
+	passwordScheme     = "pbkdf2-sha256"
+	passwordIterations = 100000
+	passwordSaltSize   = 16
+	passwordKeySize    = 32
+)
+
+// hashPassword - Hash con sal nueva de una contraseña, listo para users.txt
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("error al generar la sal: %v", err)
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, passwordKeySize)
+	if err != nil {
+		return "", fmt.Errorf("error al calcular el hash: %v", err)
+	}
+
+	encoding := base64.RawStdEncoding
+	return fmt.Sprintf("%s$%d$%s$%s", passwordScheme, passwordIterations,
+		encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+// isPasswordHash - Verificar si el campo de users.txt ya es un hash
+func isPasswordHash(stored string) bool {
+	return strings.HasPrefix(stored, passwordScheme+"$")
+}
+
+// checkPassword - Comparar una contraseña con el campo guardado en users.txt
+// (hash o, en entradas antiguas, texto plano)
+func checkPassword(stored, password string) bool {
+	if !isPasswordHash(stored) {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+	}
+
+	parts := strings.Split(stored, "$")
+	if len(parts) != 4 {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(expected))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+// setUserPassword - Reemplazar el campo de contraseña de un usuario activo en
+// el contenido de users.txt. Devuelve false si no se encontró el usuario.
+func setUserPassword(usersContent, username, stored string) (string, bool) {
+	lines := strings.Split(usersContent, "\n")
+	for i, line := range lines {
+		parts := strings.Split(strings.TrimSpace(line), ",")
+		if len(parts) < 5 {
+			continue
+		}
+
+		uidStr := strings.TrimSpace(parts[0])
+		tipo := strings.TrimSpace(parts[1])
+		user := strings.TrimSpace(parts[3])
+		if tipo == "U" && uidStr != "0" && user == username {
+			lines[i] = fmt.Sprintf("%s,U,%s,%s,%s", uidStr, strings.TrimSpace(parts[2]), user, stored)
+			return strings.Join(lines, "\n"), true
+		}
+	}
+	return usersContent, false
+}
+
+// upgradePassword - Reemplazar por su hash la contraseña en texto plano de un
+// usuario que acaba de iniciar sesión. Se hace en una transacción propia,
+// después de leer users.txt con el bloqueo compartido.
+func upgradePassword(mounted *MountedPartition, username, password string) error {
+	stored, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return err
+	}
+	defer tx.rollback()
+
+	usersContent, err := ReadUsersFileContent(mounted)
+	if err != nil {
+		return err
+	}
+
+	// Otro login pudo migrarla mientras tanto
+	if current, found := findUserPassword(usersContent, username); !found || isPasswordHash(current) {
+		return nil
+	}
+
+	newContent, found := setUserPassword(usersContent, username, stored)
+	if !found {
+		return nil
+	}
+	if err := WriteUsersFileContent(mounted, newContent); err != nil {
+		return err
+	}
+
+	return tx.commit()
+}
+
+// findUserPassword - Campo de contraseña de un usuario activo de users.txt
+func findUserPassword(usersContent, username string) (string, bool) {
+	for _, line := range strings.Split(usersContent, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ",")
+		if len(parts) < 5 {
+			continue
+		}
+		if strings.TrimSpace(parts[1]) == "U" && strings.TrimSpace(parts[0]) != "0" && strings.TrimSpace(parts[3]) == username {
+			return strings.TrimSpace(parts[4]), true
+		}
+	}
+	return "", false
+}
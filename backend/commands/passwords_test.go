@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+// Pruebas de las contraseñas de users.txt: hash, verificación, migración de
+// las entradas en texto plano, reglas de passwd y la caché de WebDAV.
+
+func TestPasswordHashAndCheck(t *testing.T) {
+	first, err := hashPassword("clave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := hashPassword("clave")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isPasswordHash(first) || strings.Contains(first, ",") {
+		t.Fatalf("formato inválido para users.txt: %q", first)
+	}
+	if first == second {
+		t.Fatal("dos hashes de la misma contraseña usan la misma sal")
+	}
+	if !checkPassword(first, "clave") || !checkPassword(second, "clave") {
+		t.Fatal("no se acepta la contraseña correcta")
+	}
+	if checkPassword(first, "Clave") || checkPassword(first, "") {
+		t.Fatal("se acepta una contraseña incorrecta")
+	}
+
+	// Texto plano de entradas antiguas
+	if !checkPassword("123", "123") || checkPassword("123", "1234") {
+		t.Fatal("comparación en texto plano incorrecta")
+	}
+
+	// Campos dañados nunca validan
+	for _, stored := range []string{
+		"pbkdf2-sha256$",
+		"pbkdf2-sha256$0$c2Fs$aGFzaA",
+		"pbkdf2-sha256$abc$c2Fs$aGFzaA",
+		"pbkdf2-sha256$1000$!!$aGFzaA",
+		"pbkdf2-sha256$1000$c2Fs$",
+		"pbkdf2-sha256$1000$c2Fs$aGFzaA$extra",
+	} {
+		if checkPassword(stored, "") || checkPassword(stored, "clave") {
+			t.Fatalf("se aceptó el campo dañado %q", stored)
+		}
+	}
+}
+
+func TestLegacyPasswordMigratesOnLogin(t *testing.T) {
+	img := newCrashImage(t)
+
+	content, err := ReadUsersFileContent(img.mounted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, found := setUserPassword(content, "root", "123")
+	if !found {
+		t.Fatal("no se encontró root en users.txt")
+	}
+	if err := WriteUsersFileContent(img.mounted, content); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := ExecuteLogin(nil, "root", "12", img.id); err == nil {
+		t.Fatal("login con contraseña incorrecta")
+	}
+	if stored := rootPassword(t, img); stored != "123" {
+		t.Fatalf("un login fallido cambió la contraseña guardada: %q", stored)
+	}
+
+	if _, _, err := ExecuteLogin(nil, "root", "123", img.id); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	stored := rootPassword(t, img)
+	if !isPasswordHash(stored) || !checkPassword(stored, "123") {
+		t.Fatalf("la contraseña no se migró a hash: %q", stored)
+	}
+	if _, _, err := ExecuteLogin(nil, "root", "123", img.id); err != nil {
+		t.Fatalf("login después de migrar: %v", err)
+	}
+	img.checkClean(t, "migración")
+}
+
+// rootPassword - Campo de contraseña de root en users.txt
+func rootPassword(t *testing.T, img *crashImage) string {
+	t.Helper()
+	content, err := ReadUsersFileContent(img.mounted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := findUserPassword(content, "root")
+	return stored
+}
+
+func TestPasswdPermissions(t *testing.T) {
+	img := newCrashImage(t)
+	if _, err := ExecuteMkgrp(img.session, "devs"); err != nil {
+		t.Fatalf("mkgrp: %v", err)
+	}
+	for _, user := range []string{"ana", "luis"} {
+		if _, err := ExecuteMkusr(img.session, user, "clave", "devs"); err != nil {
+			t.Fatalf("mkusr: %v", err)
+		}
+	}
+	ana, _, err := ExecuteLogin(nil, "ana", "clave", img.id)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// Un usuario no cambia la contraseña de otro
+	for _, user := range []string{"luis", "root"} {
+		if _, err := ExecutePasswd(ana, user, "nueva"); ErrorCode(err) != ErrCodePermissionDenied {
+			t.Fatalf("ana cambió la contraseña de %s: %v", user, err)
+		}
+	}
+	if _, _, err := ExecuteLogin(nil, "luis", "clave", img.id); err != nil {
+		t.Fatalf("la contraseña de luis cambió: %v", err)
+	}
+
+	// La suya sí, y root la de cualquiera
+	if _, err := ExecutePasswd(ana, "", "propia"); err != nil {
+		t.Fatalf("passwd propio: %v", err)
+	}
+	if _, err := ExecutePasswd(img.session, "luis", "deroot"); err != nil {
+		t.Fatalf("passwd de root: %v", err)
+	}
+	if _, _, err := ExecuteLogin(nil, "ana", "propia", img.id); err != nil {
+		t.Fatalf("login con la contraseña nueva: %v", err)
+	}
+	if _, _, err := ExecuteLogin(nil, "luis", "clave", img.id); err == nil {
+		t.Fatal("la contraseña anterior de luis sigue valiendo")
+	}
+	if _, _, err := ExecuteLogin(nil, "luis", "deroot", img.id); err != nil {
+		t.Fatalf("login de luis: %v", err)
+	}
+
+	if _, err := ExecutePasswd(img.session, "nadie", "x"); ErrorCode(err) != ErrCodeNotFound {
+		t.Fatalf("passwd de un usuario inexistente: %v", err)
+	}
+	if _, err := ExecutePasswd(ana, "", "demasiadolarga"); ErrorCode(err) != ErrCodeInvalidArgument {
+		t.Fatalf("contraseña de más de 10 caracteres: %v", err)
+	}
+	img.checkClean(t, "passwd")
+}
+
+func TestWebDAVCredentialsCache(t *testing.T) {
+	stored, err := hashPassword("clave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := hashPassword("otra")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentials := &davCredentials{}
+	if credentials.verify(stored, "mala") {
+		t.Fatal("se aceptó una contraseña incorrecta")
+	}
+	if !credentials.verify(stored, "clave") {
+		t.Fatal("no se aceptó la contraseña correcta")
+	}
+
+	// La segunda verificación sale de la caché: ya no depende del hash
+	credentials.stored = "pbkdf2-sha256$1$AA$AA"
+	if !credentials.verify("pbkdf2-sha256$1$AA$AA", "clave") {
+		t.Fatal("la contraseña verificada no quedó en la caché")
+	}
+
+	// Otra contraseña o un campo distinto en users.txt se vuelven a verificar
+	if credentials.verify("pbkdf2-sha256$1$AA$AA", "mala") {
+		t.Fatal("la caché aceptó otra contraseña")
+	}
+	if credentials.verify(other, "clave") {
+		t.Fatal("la caché sobrevivió a un cambio de contraseña")
+	}
+}
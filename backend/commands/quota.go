@@ -0,0 +1,483 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CUOTAS DE DISCO
+// /quotas.txt guarda los límites por usuario o grupo, con el mismo formato de
+// texto que users.txt:
+//   U,<usuario>,<bloques>,<inodos>
+//   G,<grupo>,<bloques>,<inodos>
+// Un límite 0 significa sin límite. El uso se calcula recorriendo los inodos:
+// cada uno cuenta sus bloques de datos y de apuntadores para su dueño (I_uid)
+// y para su grupo (I_gid). Los bloques de nombres largos no cuentan. Un
+// I_uid/I_gid 0 (la raíz y users.txt de particiones formateadas antes de que
+// mkfs usara el ID de root) cuenta para root, igual al medir y al cobrar.
+// Los comandos que crean contenido activan la cuota del dueño con
+// enforceQuota; desde ahí AllocBlock y AllocInode la descuentan y fallan al
+// superar el límite. root no tiene cuota.
+
+// quotaFileName - Archivo de cuotas en la raíz de la partición
+const quotaFileName = "quotas.txt"
+
+// rootAccountID - UID y GID de root en users.txt
+const rootAccountID = 1
+
+// quotaOwner - ID de usuario o grupo al que se cuenta un I_uid/I_gid
+func quotaOwner(id int64) int64 {
+	if id == 0 {
+		return rootAccountID
+	}
+	return id
+}
+
+// quotaLimit - Límite de bloques e inodos de un usuario ('U') o grupo ('G')
+type quotaLimit struct {
+	Kind   byte
+	Name   string
+	Blocks int64
+	Inodes int64
+}
+
+// quotaUsage - Bloques e inodos usados por un usuario o grupo
+type quotaUsage struct {
+	Blocks int64
+	Inodes int64
+}
+
+// quotaAccount - Usuario o grupo activo de users.txt
+type quotaAccount struct {
+	Kind  byte
+	ID    int64
+	Name  string
+	Group string // Grupo del usuario
+}
+
+// quotaKindName - Nombre del tipo de cuota para los mensajes
+func quotaKindName(kind byte) string {
+	if kind == 'G' {
+		return "grupo"
+	}
+	return "usuario"
+}
+
+// parseQuotas - Interpretar el contenido de quotas.txt (se ignoran líneas inválidas)
+func parseQuotas(content string) []quotaLimit {
+	var limits []quotaLimit
+	for _, line := range strings.Split(content, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ",")
+		if len(parts) < 4 {
+			continue
+		}
+
+		kind := strings.TrimSpace(parts[0])
+		if kind != "U" && kind != "G" {
+			continue
+		}
+		blocks, err := strconv.ParseInt(strings.TrimSpace(parts[2]), 10, 64)
+		if err != nil {
+			continue
+		}
+		inodes, err := strconv.ParseInt(strings.TrimSpace(parts[3]), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		limits = append(limits, quotaLimit{
+			Kind:   kind[0],
+			Name:   strings.TrimSpace(parts[1]),
+			Blocks: blocks,
+			Inodes: inodes,
+		})
+	}
+	return limits
+}
+
+// formatQuotas - Contenido de quotas.txt para una lista de límites
+func formatQuotas(limits []quotaLimit) string {
+	var content strings.Builder
+	for _, limit := range limits {
+		content.WriteString(fmt.Sprintf("%c,%s,%d,%d\n", limit.Kind, limit.Name, limit.Blocks, limit.Inodes))
+	}
+	return content.String()
+}
+
+// findQuota - Límite de un usuario o grupo, o nil si no tiene
+func findQuota(limits []quotaLimit, kind byte, name string) *quotaLimit {
+	for i := range limits {
+		if limits[i].Kind == kind && limits[i].Name == name {
+			return &limits[i]
+		}
+	}
+	return nil
+}
+
+// parseAccounts - Usuarios y grupos activos de users.txt (los eliminados tienen ID 0)
+func parseAccounts(usersContent string) []quotaAccount {
+	var accounts []quotaAccount
+	for _, line := range strings.Split(usersContent, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ",")
+		if len(parts) < 3 {
+			continue
+		}
+
+		id, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+
+		switch strings.TrimSpace(parts[1]) {
+		case "G":
+			accounts = append(accounts, quotaAccount{Kind: 'G', ID: id, Name: strings.TrimSpace(parts[2])})
+		case "U":
+			if len(parts) >= 5 {
+				accounts = append(accounts, quotaAccount{Kind: 'U', ID: id, Name: strings.TrimSpace(parts[3]), Group: strings.TrimSpace(parts[2])})
+			}
+		}
+	}
+	return accounts
+}
+
+// findAccount - Buscar un usuario o grupo activo por nombre
+func findAccount(accounts []quotaAccount, kind byte, name string) *quotaAccount {
+	for i := range accounts {
+		if accounts[i].Kind == kind && accounts[i].Name == name {
+			return &accounts[i]
+		}
+	}
+	return nil
+}
+
+// ReadQuotas - Leer los límites de /quotas.txt (vacío si el archivo no existe)
+func (fs *FileSystem) ReadQuotas() ([]quotaLimit, error) {
+	inodeIndex, err := fs.LookupIn(0, quotaFileName)
+	if err != nil {
+		return nil, nil
+	}
+
+	inode, err := fs.ReadInode(inodeIndex)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el inodo de %s: %v", quotaFileName, err)
+	}
+	content, err := fs.ReadFile(inode)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el contenido de %s: %v", quotaFileName, err)
+	}
+	return parseQuotas(string(content)), nil
+}
+
+// QuotaUsage - Recorrer los inodos en uso y sumar bloques e inodos por UID y por GID
+func (fs *FileSystem) QuotaUsage() (map[int64]*quotaUsage, map[int64]*quotaUsage, error) {
+	bitmap := make([]byte, fs.SB.S_inodes_count)
+	if _, err := fs.file.ReadAt(bitmap, fs.SB.S_bm_inode_start); err != nil {
+		return nil, nil, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+
+	users := make(map[int64]*quotaUsage)
+	groups := make(map[int64]*quotaUsage)
+	add := func(usage map[int64]*quotaUsage, id int64, blocks int64) {
+		if usage[id] == nil {
+			usage[id] = &quotaUsage{}
+		}
+		usage[id].Blocks += blocks
+		usage[id].Inodes++
+	}
+
+	for i := int64(0); i < fs.SB.S_inodes_count; i++ {
+		if bitmap[i] == 0 {
+			continue
+		}
+		inode, err := fs.ReadInode(i)
+		if err != nil {
+			return nil, nil, err
+		}
+		dataBlocks, pointerBlocks, err := fs.InodeBlocks(inode)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error al leer los bloques del inodo %d: %v", i, err)
+		}
+
+		blocks := int64(len(dataBlocks) + len(pointerBlocks))
+		add(users, quotaOwner(inode.I_uid), blocks)
+		add(groups, quotaOwner(inode.I_gid), blocks)
+	}
+
+	return users, groups, nil
+}
+
+// quotaCharge - Cuota activa de un usuario o de un grupo durante un comando
+type quotaCharge struct {
+	limit *quotaLimit
+	usage quotaUsage
+}
+
+// check - Verificar que la cuota admite blocks e inodes más
+func (c *quotaCharge) check(blocks, inodes int64) error {
+	if c == nil {
+		return nil
+	}
+	kind := quotaKindName(c.limit.Kind)
+	if blocks > 0 && c.limit.Blocks > 0 && c.usage.Blocks+blocks > c.limit.Blocks {
+		return newCommandError(ErrCodeQuotaExceeded, "cuota de bloques excedida para el %s '%s' (límite %d)",
+			kind, c.limit.Name, c.limit.Blocks)
+	}
+	if inodes > 0 && c.limit.Inodes > 0 && c.usage.Inodes+inodes > c.limit.Inodes {
+		return newCommandError(ErrCodeQuotaExceeded, "cuota de inodos excedida para el %s '%s' (límite %d)",
+			kind, c.limit.Name, c.limit.Inodes)
+	}
+	return nil
+}
+
+// add - Sumar (o restar, con valores negativos) uso a la cuota
+func (c *quotaCharge) add(blocks, inodes int64) {
+	if c == nil {
+		return
+	}
+	c.usage.Blocks += blocks
+	c.usage.Inodes += inodes
+}
+
+// quotaTracker - Cuotas del usuario y grupo a los que se cobra lo que asigna
+// el comando en curso
+type quotaTracker struct {
+	user  *quotaCharge
+	group *quotaCharge
+}
+
+// check - Verificar sin descontar que las cuotas activas admiten blocks e
+// inodes más. En EXT2 no hay transacción que deshacer, así que los comandos
+// que conocen el tamaño lo verifican antes de asignar.
+func (q *quotaTracker) check(blocks, inodes int64) error {
+	if q == nil {
+		return nil
+	}
+	if err := q.user.check(blocks, inodes); err != nil {
+		return err
+	}
+	return q.group.check(blocks, inodes)
+}
+
+// charge - Descontar bloques e inodos de las cuotas activas
+func (q *quotaTracker) charge(blocks, inodes int64) error {
+	if err := q.check(blocks, inodes); err != nil {
+		return err
+	}
+	if q == nil {
+		return nil
+	}
+	q.user.add(blocks, inodes)
+	q.group.add(blocks, inodes)
+	return nil
+}
+
+// release - Devolver a las cuotas activas los bloques e inodos liberados
+func (q *quotaTracker) release(blocks, inodes int64) {
+	if q == nil {
+		return
+	}
+	q.user.add(-blocks, -inodes)
+	q.group.add(-blocks, -inodes)
+}
+
+// enforceQuota - Activar las cuotas del dueño uid/gid para lo que asigne el
+// comando. No hace nada para root o si el dueño no tiene cuota.
+func (fs *FileSystem) enforceQuota(session *Session, uid, gid int64) error {
+	fs.quota = nil
+	if session == nil || session.IsRoot {
+		return nil
+	}
+	uid, gid = quotaOwner(uid), quotaOwner(gid)
+
+	limits, err := fs.ReadQuotas()
+	if err != nil || len(limits) == 0 {
+		return err
+	}
+	usersContent, err := fs.ReadUsers()
+	if err != nil {
+		return err
+	}
+
+	var userLimit, groupLimit *quotaLimit
+	for _, account := range parseAccounts(usersContent) {
+		if account.Kind == 'U' && account.ID == uid {
+			userLimit = findQuota(limits, 'U', account.Name)
+		}
+		if account.Kind == 'G' && account.ID == gid {
+			groupLimit = findQuota(limits, 'G', account.Name)
+		}
+	}
+	if userLimit == nil && groupLimit == nil {
+		return nil
+	}
+
+	users, groups, err := fs.QuotaUsage()
+	if err != nil {
+		return err
+	}
+
+	tracker := &quotaTracker{}
+	if userLimit != nil {
+		tracker.user = &quotaCharge{limit: userLimit}
+		if usage := users[uid]; usage != nil {
+			tracker.user.usage = *usage
+		}
+	}
+	if groupLimit != nil {
+		tracker.group = &quotaCharge{limit: groupLimit}
+		if usage := groups[gid]; usage != nil {
+			tracker.group.usage = *usage
+		}
+	}
+	fs.quota = tracker
+	return nil
+}
+
+// enforceSessionQuota - Activar las cuotas del usuario de la sesión, dueño de
+// lo que el comando crea
+func (fs *FileSystem) enforceSessionQuota(session *Session) error {
+	if session == nil || session.IsRoot {
+		return nil
+	}
+	userInfo, err := fs.UserInfo(session.User)
+	if err != nil {
+		return nil // Sin usuario en users.txt no hay a quién cobrar
+	}
+	return fs.enforceQuota(session, userInfo.UID, userInfo.GID)
+}
+
+// ExecuteQuota - Fijar los límites de bloques e inodos de un usuario o grupo.
+// Un valor negativo conserva el límite actual; 0 quita el límite.
+func ExecuteQuota(session *Session, user string, group string, blocks int64, inodes int64) (*CommandResult, error) {
+	res := NewCommandResult("quota")
+
+	// Verificar sesión activa
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
+	}
+
+	// Validar parámetros
+	if (user == "") == (group == "") {
+		return res, newCommandError(ErrCodeInvalidArgument, "indique -usr o -grp (solo uno) para quota")
+	}
+	if blocks < 0 && inodes < 0 {
+		return res, newCommandError(ErrCodeInvalidArgument, "indique -blocks y/o -inodes para quota")
+	}
+
+	// Solo root administra las cuotas
+	if !session.IsRoot {
+		return res, newCommandError(ErrCodePermissionDenied, "solo el usuario 'root' puede asignar cuotas. Usuario actual: '%s'", session.User)
+	}
+
+	kind, name := byte('U'), user
+	if group != "" {
+		kind, name = 'G', group
+	}
+	if name == "root" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el %s 'root' no tiene cuota", quotaKindName(kind))
+	}
+
+	// Buscar la partición montada de la sesión
+	mounted := GetMountedPartition(session.PartitionID)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
+	}
+
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
+	}
+	defer tx.rollback()
+
+	fs, err := openFileSystem(mounted, os.O_RDWR)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el sistema de archivos")
+	}
+	defer fs.Close()
+
+	// El usuario o grupo debe existir en users.txt
+	usersContent, err := fs.ReadUsers()
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al leer users.txt")
+	}
+	if findAccount(parseAccounts(usersContent), kind, name) == nil {
+		return res, newCommandError(ErrCodeNotFound, "el %s '%s' no existe", quotaKindName(kind), name)
+	}
+
+	limits, err := fs.ReadQuotas()
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al leer %s", quotaFileName)
+	}
+	limit := findQuota(limits, kind, name)
+	if limit == nil {
+		limits = append(limits, quotaLimit{Kind: kind, Name: name})
+		limit = &limits[len(limits)-1]
+	}
+	if blocks >= 0 {
+		limit.Blocks = blocks
+	}
+	if inodes >= 0 {
+		limit.Inodes = inodes
+	}
+	newLimit := *limit
+
+	// Un usuario o grupo sin límites sale del archivo
+	var kept []quotaLimit
+	for _, l := range limits {
+		if l.Blocks > 0 || l.Inodes > 0 {
+			kept = append(kept, l)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Kind > kept[j].Kind })
+	content := []byte(formatQuotas(kept))
+
+	// Crear /quotas.txt la primera vez; después se reescribe como users.txt
+	if inodeIndex, err := fs.LookupIn(0, quotaFileName); err == nil {
+		inode, err := fs.ReadInode(inodeIndex)
+		if err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al leer el inodo de %s", quotaFileName)
+		}
+		if err := fs.WriteFile(inodeIndex, inode, content); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al escribir %s", quotaFileName)
+		}
+	} else if _, err := fs.CreateFile(0, quotaFileName, content, session); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al crear %s", quotaFileName)
+	}
+
+	// Actualizar el superbloque
+	if err := fs.Flush(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
+	}
+
+	line := strings.TrimSpace(formatQuotas([]quotaLimit{newLimit}))
+	if err := WriteJournal(res, mounted, "quota", "/"+quotaFileName, line); err != nil {
+		res.Printf("⚠️ No se pudo escribir al journal: %v\n", err)
+	}
+
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	res.Printf("✅ Cuota del %s '%s': %s bloques, %s inodos.\n", quotaKindName(kind), name,
+		formatQuotaLimit(newLimit.Blocks), formatQuotaLimit(newLimit.Inodes))
+	res.AddPath("/" + quotaFileName)
+	res.Set("kind", quotaKindName(kind))
+	res.Set("name", name)
+	res.Set("blocks", newLimit.Blocks)
+	res.Set("inodes", newLimit.Inodes)
+
+	return res, nil
+}
+
+// formatQuotaLimit - Texto de un límite (0 es sin límite)
+func formatQuotaLimit(limit int64) string {
+	if limit <= 0 {
+		return "sin límite"
+	}
+	return strconv.FormatInt(limit, 10)
+}
@@ -0,0 +1,154 @@
+package commands
+
+import (
+	"os"
+	"testing"
+)
+
+// Pruebas de cuotas: el límite de inodos y el de bloques se cumplen al crear
+// contenido y el uso que mide rep quota es el mismo que se cobra.
+
+// newQuotaImage - Imagen con el usuario ana (grupo devs) y la carpeta /pub
+// donde puede escribir
+func newQuotaImage(t *testing.T) (*crashImage, *Session) {
+	t.Helper()
+	img := newCrashImage(t)
+	if _, err := ExecuteMkgrp(img.session, "devs"); err != nil {
+		t.Fatalf("mkgrp: %v", err)
+	}
+	if _, err := ExecuteMkusr(img.session, "ana", "clave", "devs"); err != nil {
+		t.Fatalf("mkusr: %v", err)
+	}
+	if _, err := ExecuteMkdir(img.session, "/pub", false); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, err := ExecuteChmod(img.session, "/pub", false, "777"); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+	session, _, err := ExecuteLogin(nil, "ana", "clave", img.id)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return img, session
+}
+
+// quotaUsageOf - Uso medido (como en rep quota) de un UID
+func quotaUsageOf(t *testing.T, img *crashImage, uid int64) quotaUsage {
+	t.Helper()
+	fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	users, _, err := fs.QuotaUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users[uid] == nil {
+		return quotaUsage{}
+	}
+	return *users[uid]
+}
+
+// quotaBlockData - Bytes de contenido de un bloque de archivo
+func quotaBlockData(t *testing.T, img *crashImage) int64 {
+	t.Helper()
+	fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	return int64(fs.geometry().Data)
+}
+
+func TestQuotaInodeLimit(t *testing.T) {
+	img, ana := newQuotaImage(t)
+	if _, err := ExecuteQuota(img.session, "ana", "", -1, 3); err != nil {
+		t.Fatalf("quota: %v", err)
+	}
+
+	for _, name := range []string{"/pub/a", "/pub/b", "/pub/c"} {
+		if _, err := ExecuteMkfile(ana, name, false, 0, ""); err != nil {
+			t.Fatalf("mkfile %s: %v", name, err)
+		}
+	}
+	if _, err := ExecuteMkfile(ana, "/pub/d", false, 0, ""); ErrorCode(err) != ErrCodeQuotaExceeded {
+		t.Fatalf("cuarto inodo con límite 3: %v", err)
+	}
+	if _, err := ExecuteMkdir(ana, "/pub/sub", false); ErrorCode(err) != ErrCodeQuotaExceeded {
+		t.Fatalf("carpeta con la cuota llena: %v", err)
+	}
+	if usage := quotaUsageOf(t, img, ana.UID); usage.Inodes != 3 {
+		t.Fatalf("uso de inodos %d, se esperaban 3", usage.Inodes)
+	}
+
+	// Al borrar se libera la cuota
+	if _, err := ExecuteRemove(img.session, "/pub/a"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := ExecuteMkfile(ana, "/pub/d", false, 0, ""); err != nil {
+		t.Fatalf("mkfile después de liberar: %v", err)
+	}
+	img.checkClean(t, "cuota de inodos")
+}
+
+func TestQuotaBlockLimit(t *testing.T) {
+	img, ana := newQuotaImage(t)
+	blockSize := quotaBlockData(t, img)
+	if _, err := ExecuteQuota(img.session, "ana", "", 3, -1); err != nil {
+		t.Fatalf("quota: %v", err)
+	}
+
+	// Dos bloques de datos caben; dos más no, y el intento no deja nada
+	if _, err := ExecuteMkfile(ana, "/pub/a", false, int(2*blockSize), ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	if _, err := ExecuteMkfile(ana, "/pub/b", false, int(2*blockSize), ""); ErrorCode(err) != ErrCodeQuotaExceeded {
+		t.Fatalf("archivo que supera el límite de bloques: %v", err)
+	}
+	if _, err := statPath(img.mounted, "/pub/b", nil); err == nil {
+		t.Fatal("quedó el archivo que superó la cuota")
+	}
+	if usage := quotaUsageOf(t, img, ana.UID); usage.Blocks != 2 || usage.Inodes != 1 {
+		t.Fatalf("uso %+v, se esperaban 2 bloques y 1 inodo", usage)
+	}
+
+	// Las cuotas de grupo se cobran con el mismo dueño
+	if _, err := ExecuteQuota(img.session, "ana", "", 0, -1); err != nil {
+		t.Fatalf("quota: %v", err)
+	}
+	if _, err := ExecuteQuota(img.session, "", "devs", 2, -1); err != nil {
+		t.Fatalf("quota: %v", err)
+	}
+	if _, err := ExecuteMkfile(ana, "/pub/c", false, 1, ""); ErrorCode(err) != ErrCodeQuotaExceeded {
+		t.Fatalf("archivo que supera la cuota del grupo: %v", err)
+	}
+	img.checkClean(t, "cuota de bloques")
+}
+
+func TestQuotaUsageCountsRootOwnedStructures(t *testing.T) {
+	img := newCrashImage(t)
+
+	// Una partición formateada antes dejaba la raíz y users.txt con I_uid 0
+	fs, err := openFileSystem(img.mounted, os.O_RDWR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := fs.ReadInode(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.I_uid, root.I_gid = 0, 0
+	if err := fs.WriteInode(0, root); err != nil {
+		t.Fatal(err)
+	}
+	used := fs.SB.S_inodes_count - fs.SB.S_free_inodes_count
+	fs.Close()
+
+	if usage := quotaUsageOf(t, img, rootAccountID); usage.Inodes != used {
+		t.Fatalf("root tiene %d inodos, se esperaban los %d en uso", usage.Inodes, used)
+	}
+	if usage := quotaUsageOf(t, img, 0); usage != (quotaUsage{}) {
+		t.Fatalf("se contó uso para el UID 0: %+v", usage)
+	}
+}
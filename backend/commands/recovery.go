@@ -2,319 +2,290 @@ package commands
 
 import (
     "backend/structs"
+    "bytes"
     "encoding/binary"
-    "fmt"
+    "io"
     "os"
-    "time"
 )
 
-// ExecuteRecovery - Recuperar el sistema de archivos EXT3 desde el journaling
-func ExecuteRecovery(id string) {
+// PÉRDIDA Y RECUPERACIÓN (EXT3)
+// loss simula un corte justo después de confirmar una transacción: guarda en
+// el journal de escritura anticipada, como transacción confirmada, todo lo que
+// está por borrar (bitácora de operaciones, bitmaps, inodos y bloques) y recién
+// después lo limpia. recovery rehace esa transacción desde el journal, igual
+// que se hace al montar o al abrir la siguiente transacción sobre la partición.
+
+// lossChunkSize - Bytes que loss lee de una vez al guardar el estado
+const lossChunkSize = 64 * 1024
+
+// ExecuteRecovery - Recuperar el sistema de archivos EXT3 desde el journal
+func ExecuteRecovery(id string) (*CommandResult, error) {
+    res := NewCommandResult("recovery")
+
     // Validar parámetros obligatorios
     if id == "" {
-        fmt.Println("Error: el parámetro -id es obligatorio.")
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio")
     }
 
     // Buscar la partición montada
     mounted := GetMountedPartition(id)
     if mounted == nil {
-        fmt.Printf("Error: la partición '%s' no está montada.\n", id)
-        return
+        return res, newCommandError(ErrCodeNotMounted, "la partición '%s' no está montada", id)
     }
+    defer lockDisk(mounted.Path)()
 
     // Abrir el disco
-    file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+    file, err := openDisk(mounted.Path, os.O_RDWR)
     if err != nil {
-        fmt.Printf("Error al abrir el disco: %v\n", err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al abrir el disco")
     }
     defer file.Close()
 
     // Obtener la partición y superbloque
-    partition, superblock, err := getPartitionAndSuperblock(file, mounted)
+    fs, err := mountedFileSystem(file, mounted)
     if err != nil {
-        fmt.Printf("Error al obtener superbloque: %v\n", err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al obtener superbloque")
     }
+    partition, superblock := fs.Partition, fs.SB
 
-    // Verificar que sea sistema EXT3 (con journaling)
-    if superblock.S_file_system_type != 3 {
-        fmt.Println("Error: la partición no tiene un sistema de archivos EXT3 con journaling.")
-        return
+    // Verificar que sea sistema EXT3 con journal
+    layout, hasJournal := walLayoutFor(partition.Start, superblock)
+    if !hasJournal {
+        return res, newCommandError(ErrCodeUnsupported, "la partición no tiene un sistema de archivos EXT3 con journal")
     }
 
-    fmt.Printf("🔄 Iniciando recuperación del sistema de archivos EXT3 en '%s'...\n", id)
-    fmt.Println()
-
-    // Leer el journal de recuperación
-	journalPos := partition.Part_start + int64(binary.Size(structs.SuperBloque{}))
-	file.Seek(journalPos, 0)
-
-	var journal structs.JournalRecovery
-	if err := binary.Read(file, binary.LittleEndian, &journal); err != nil {
-		fmt.Printf("Error al leer el journal: %v\n", err)
-		return
-	}
-
-	// Verificar si hay información en el journal
-	if journal.Journal_ultimo_montaje == 0 {
-		fmt.Println("⚠️  No hay información de journaling disponible para recuperar.")
-		return
-	}
-
-	fmt.Println("📋 Información del último estado consistente:")
-	fmt.Printf("   Fecha: %s\n", time.Unix(journal.Journal_ultimo_montaje, 0).Format("2006-01-02 15:04:05"))
-	fmt.Printf("   Inodos libres: %d\n", journal.Journal_inodos_libres)
-	fmt.Printf("   Bloques libres: %d\n", journal.Journal_bloques_libres)
-	fmt.Println()
-
-    // Restaurar el superbloque desde el journal
-    superblock.S_free_inodes_count = journal.Journal_inodos_libres
-    superblock.S_free_blocks_count = journal.Journal_bloques_libres
-    superblock.S_mtime = time.Now().Unix()
-    superblock.S_mnt_count++
-
-    // Restaurar bitmaps desde el journal
-    fmt.Println("🔧 Restaurando bitmaps desde el journal...")
-
-    // Restaurar bitmap de inodos
-    file.Seek(superblock.S_bm_inode_start, 0)
-    if err := binary.Write(file, binary.LittleEndian, &journal.Journal_bm_inodos); err != nil {
-        fmt.Printf("Error al restaurar bitmap de inodos: %v\n", err)
-        return
-    }
+    res.Printf("🔄 Iniciando recuperación del sistema de archivos EXT3 en '%s'...\n", id)
+    res.Println()
 
-    // Restaurar bitmap de bloques
-    file.Seek(superblock.S_bm_block_start, 0)
-    if err := binary.Write(file, binary.LittleEndian, &journal.Journal_bm_bloques); err != nil {
-        fmt.Printf("Error al restaurar bitmap de bloques: %v\n", err)
-        return
+    // Rehacer o descartar la transacción pendiente del journal
+    recovery, err := replayJournal(file, layout)
+    if err != nil {
+        return res, wrapCommandError(ErrCodeIO, err, "error al rehacer el journal")
     }
-
-    // Restaurar el área de inodos desde el journal
-    fmt.Println("🔧 Restaurando área de inodos...")
-    file.Seek(superblock.S_inode_start, 0)
-    if err := binary.Write(file, binary.LittleEndian, &journal.Journal_inodos); err != nil {
-        fmt.Printf("Error al restaurar área de inodos: %v\n", err)
-        return
+    if !recovery.Present {
+        return res, newCommandError(ErrCodeIO, "el área del journal de la partición '%s' no contiene un journal válido", id)
     }
 
-    // Restaurar el área de bloques desde el journal
-    fmt.Println("🔧 Restaurando área de bloques...")
-    file.Seek(superblock.S_block_start, 0)
-    if err := binary.Write(file, binary.LittleEndian, &journal.Journal_bloques); err != nil {
-        fmt.Printf("Error al restaurar área de bloques: %v\n", err)
-        return
+    if message := describeJournalRecovery(recovery); message != "" {
+        res.Println(message)
+    } else {
+        res.Println("✅ El journal no tiene transacciones pendientes.")
     }
 
-    // Escribir el superbloque actualizado
-    file.Seek(partition.Part_start, 0)
-    if err := binary.Write(file, binary.LittleEndian, superblock); err != nil {
-        fmt.Printf("Error al actualizar el superbloque: %v\n", err)
-        return
+    if superblock, err = partition.ReadSuperblock(); err != nil {
+        return res, wrapCommandError(ErrCodeIO, err, "error al obtener superbloque")
     }
-
-    fmt.Println()
-    fmt.Println("✅ Sistema de archivos recuperado exitosamente.")
-    fmt.Printf("   📊 Inodos libres: %d\n", superblock.S_free_inodes_count)
-    fmt.Printf("   📊 Bloques libres: %d\n", superblock.S_free_blocks_count)
-    fmt.Println()
-    fmt.Println("🎉 El sistema ha sido restaurado al último estado consistente.")
+    res.Printf("   📊 Inodos libres: %d\n", superblock.S_free_inodes_count)
+    res.Printf("   📊 Bloques libres: %d\n", superblock.S_free_blocks_count)
+    res.Set("id", id)
+    res.Set("replayed", recovery.Replayed)
+    res.Set("discarded", recovery.Discarded)
+    res.Set("sequence", recovery.Sequence)
+    res.Set("freeInodes", superblock.S_free_inodes_count)
+    res.Set("freeBlocks", superblock.S_free_blocks_count)
+
+    return res, nil
 }
 
 // ExecuteLoss - Simular pérdida del sistema de archivos
-func ExecuteLoss(id string) {
+func ExecuteLoss(id string) (*CommandResult, error) {
+    res := NewCommandResult("loss")
+
     // Validar parámetros obligatorios
     if id == "" {
-        fmt.Println("Error: el parámetro -id es obligatorio.")
-        return
+        return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio")
     }
 
     // Buscar la partición montada
     mounted := GetMountedPartition(id)
     if mounted == nil {
-        fmt.Printf("Error: la partición '%s' no está montada.\n", id)
-        return
+        return res, newCommandError(ErrCodeNotMounted, "la partición '%s' no está montada", id)
     }
+    defer lockDisk(mounted.Path)()
 
     // Abrir el disco
-    file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+    file, err := openDisk(mounted.Path, os.O_RDWR)
     if err != nil {
-        fmt.Printf("Error al abrir el disco: %v\n", err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al abrir el disco")
     }
     defer file.Close()
 
     // Obtener la partición y superbloque
-    partition, superblock, err := getPartitionAndSuperblock(file, mounted)
+    fs, err := mountedFileSystem(file, mounted)
     if err != nil {
-        fmt.Printf("Error al obtener superbloque: %v\n", err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al obtener superbloque")
     }
+    partition, superblock := fs.Partition, fs.SB
 
-    fmt.Printf("⚠️  ADVERTENCIA: Esta operación simulará una pérdida del sistema de archivos.\n")
-    fmt.Printf("   Se limpiarán los siguientes bloques en '%s':\n", id)
-    fmt.Println("   - Bitmap de Inodos")
-    fmt.Println("   - Bitmap de Bloques")
-    fmt.Println("   - Área de Inodos")
-    fmt.Println("   - Área de Bloques")
-    fmt.Println()
-
-    // ✅ PASO CRÍTICO: Guardar el estado actual en el journal ANTES de limpiar
-    fmt.Println("💾 Guardando estado actual en el journal de recuperación...")
-    
-    var journal structs.JournalRecovery
-    journal.Journal_ultimo_montaje = time.Now().Unix()
-    journal.Journal_inodos_libres = superblock.S_free_inodes_count
-    journal.Journal_bloques_libres = superblock.S_free_blocks_count
-
-    // Calcular tamaños de bitmaps
-    bitmapInodosSize := superblock.S_inodes_count / 8
-    if superblock.S_inodes_count%8 != 0 {
-        bitmapInodosSize++
+    // Sin journal, recovery no tendría de dónde restaurar
+    layout, hasJournal := walLayoutFor(partition.Start, superblock)
+    if !hasJournal {
+        return res, newCommandError(ErrCodeUnsupported, "la partición no tiene un sistema de archivos EXT3 con journal")
     }
 
-    bitmapBloquesSize := superblock.S_blocks_count / 8
-    if superblock.S_blocks_count%8 != 0 {
-        bitmapBloquesSize++
+    // Aplicar antes una transacción pendiente: loss ocupa el journal
+    if _, err := replayJournal(file, layout); err != nil {
+        return res, wrapCommandError(ErrCodeIO, err, "error al revisar el journal")
+    }
+    if superblock, err = partition.ReadSuperblock(); err != nil {
+        return res, wrapCommandError(ErrCodeIO, err, "error al obtener superbloque")
     }
 
-    // Leer bitmap de inodos actual
-    file.Seek(superblock.S_bm_inode_start, 0)
-    bitmapInodos := make([]byte, bitmapInodosSize)
-    if _, err := file.Read(bitmapInodos); err != nil {
-        fmt.Printf("Error al leer bitmap de inodos: %v\n", err)
-        return
+    areas, err := lostAreas(layout, superblock)
+    if err != nil {
+        return res, wrapCommandError(ErrCodeInternal, err, "error al preparar la limpieza")
     }
-    copy(journal.Journal_bm_inodos[:], bitmapInodos)
 
-    // Leer bitmap de bloques actual
-    file.Seek(superblock.S_bm_block_start, 0)
-    bitmapBloques := make([]byte, bitmapBloquesSize)
-    if _, err := file.Read(bitmapBloques); err != nil {
-        fmt.Printf("Error al leer bitmap de bloques: %v\n", err)
-        return
+    res.Printf("⚠️  ADVERTENCIA: Esta operación simulará una pérdida del sistema de archivos.\n")
+    res.Printf("   Se limpiarán los siguientes bloques en '%s':\n", id)
+    res.Println("   - Bitácora de operaciones (journaling)")
+    res.Println("   - Bitmap de Inodos")
+    res.Println("   - Bitmap de Bloques")
+    res.Println("   - Área de Inodos")
+    res.Println("   - Área de Bloques")
+    res.Println()
+
+    // PASO 1: Guardar en el journal lo que se va a borrar, ANTES de limpiar
+    res.Println("💾 Guardando el estado actual en el journal...")
+    changes, err := collectLostState(file, areas, layout.size)
+    if err != nil {
+        return res, err
     }
-    copy(journal.Journal_bm_bloques[:], bitmapBloques)
-
-    // Leer área de inodos actual
-    fmt.Println("   📦 Guardando área de inodos...")
-    inodosAreaSize := superblock.S_inodes_count * superblock.S_inode_s
-    file.Seek(superblock.S_inode_start, 0)
-    inodosData := make([]byte, inodosAreaSize)
-    if _, err := file.Read(inodosData); err != nil {
-        fmt.Printf("Error al leer área de inodos: %v\n", err)
-        return
+    records, err := encodeWalRecords(changes)
+    if err != nil {
+        return res, wrapCommandError(ErrCodeInternal, err, "error al preparar el journal")
     }
-    copy(journal.Journal_inodos[:], inodosData)
-
-    // Leer área de bloques actual
-    fmt.Println("   📦 Guardando área de bloques...")
-    bloquesAreaSize := superblock.S_blocks_count * superblock.S_block_s
-    file.Seek(superblock.S_block_start, 0)
-    bloquesData := make([]byte, bloquesAreaSize)
-    if _, err := file.Read(bloquesData); err != nil {
-        fmt.Printf("Error al leer área de bloques: %v\n", err)
-        return
+    if err := checkWalCapacity(layout, records); err != nil {
+        return res, wrapCommandError(ErrCodeNoSpace, err, "el estado en uso no cabe en el journal y recovery no podría restaurarlo")
     }
-    copy(journal.Journal_bloques[:], bloquesData)
-
-    // ✅ ESCRIBIR EL JOURNAL AL DISCO (CRÍTICO!)
-    journalPos := partition.Part_start + int64(binary.Size(structs.SuperBloque{}))
-    file.Seek(journalPos, 0)
-    if err := binary.Write(file, binary.LittleEndian, &journal); err != nil {
-        fmt.Printf("Error al guardar el journal: %v\n", err)
-        return
+    header, err := commitWalRecords(file, layout, records, len(changes))
+    if err != nil {
+        return res, wrapCommandError(ErrCodeIO, err, "error al guardar el estado en el journal")
     }
+    res.Printf("   ✅ Transacción %d confirmada: %d registros (%d bytes)\n", header.WSequence, len(changes), len(records))
+    res.Println()
 
-    fmt.Println("   ✅ Estado guardado en el journal de recuperación")
-    fmt.Printf("   📊 Inodos guardados: %d\n", superblock.S_inodes_count)
-    fmt.Printf("   📊 Bloques guardados: %d\n", superblock.S_blocks_count)
-    fmt.Println()
-
-    // Crear buffer de ceros para limpieza
-    zeroBuffer := make([]byte, 1024) // Buffer de 1KB
-
-    // 1. Limpiar bitmap de inodos
-    fmt.Println("🗑️  Limpiando bitmap de inodos...")
-    
-    file.Seek(superblock.S_bm_inode_start, 0)
-    bytesWritten := int64(0)
-    for bytesWritten < bitmapInodosSize {
-        toWrite := int64(len(zeroBuffer))
-        if bytesWritten+toWrite > bitmapInodosSize {
-            toWrite = bitmapInodosSize - bytesWritten
-        }
-        if _, err := file.Write(zeroBuffer[:toWrite]); err != nil {
-            fmt.Printf("Error al limpiar bitmap de inodos: %v\n", err)
-            return
-        }
-        bytesWritten += toWrite
+    // PASO 2: Limpiar (la transacción confirmada queda sin aplicar)
+    res.Println("🗑️  Limpiando bitácora, bitmaps, inodos y bloques...")
+    if err := wipeLostState(file, areas); err != nil {
+        return res, wrapCommandError(ErrCodeIO, err, "error al limpiar la partición")
     }
 
-    // 2. Limpiar bitmap de bloques
-    fmt.Println("🗑️  Limpiando bitmap de bloques...")
-    
-    file.Seek(superblock.S_bm_block_start, 0)
-    bytesWritten = 0
-    for bytesWritten < bitmapBloquesSize {
-        toWrite := int64(len(zeroBuffer))
-        if bytesWritten+toWrite > bitmapBloquesSize {
-            toWrite = bitmapBloquesSize - bytesWritten
-        }
-        if _, err := file.Write(zeroBuffer[:toWrite]); err != nil {
-            fmt.Printf("Error al limpiar bitmap de bloques: %v\n", err)
-            return
-        }
-        bytesWritten += toWrite
+    res.Println()
+    res.Println("✅ Pérdida del sistema de archivos simulada exitosamente.")
+    res.Println()
+    res.Println("💡 Para recuperar el sistema de archivos, use:")
+    res.Printf("   recovery -id=%s\n", id)
+    res.Println("   (también se recupera al montar o con el próximo comando que modifique la partición)")
+    res.Println()
+    res.Println("📊 Recargue el explorador de archivos y el journaling para ver los cambios")
+    res.Set("id", id)
+    res.Set("sequence", header.WSequence)
+    res.Set("records", len(changes))
+    res.Set("bytes", len(records))
+
+    return res, nil
+}
+
+// lostArea - Zona que limpia loss y el patrón con el que queda (vacío = ceros)
+type lostArea struct {
+    start   int64
+    end     int64
+    pattern []byte
+}
+
+// lostAreas - Bitácora de operaciones (entradas libres), bitmaps (en cero),
+// inodos (libres) y bloques (en cero), como los deja mkfs
+func lostAreas(layout walLayout, superblock *structs.SuperBloque) ([]lostArea, error) {
+    var entry bytes.Buffer
+    if err := binary.Write(&entry, binary.LittleEndian, &structs.Journal{JCount: -1}); err != nil {
+        return nil, err
     }
+    emptyInode, err := encodeEmptyInode(superblock.S_inode_s)
+    if err != nil {
+        return nil, err
+    }
+    // Los inodos antiguos (más cortos) se completan con ceros hasta S_inode_s
+    inodePattern := make([]byte, superblock.S_inode_s)
+    copy(inodePattern, emptyInode)
+
+    return []lostArea{
+        {layout.start + layout.size, superblock.S_bm_inode_start, entry.Bytes()},
+        {superblock.S_bm_inode_start, superblock.S_inode_start, nil},
+        {superblock.S_inode_start, superblock.S_block_start, inodePattern},
+        {superblock.S_block_start, superblock.S_block_start + superblock.S_blocks_count*superblock.S_block_s, nil},
+    }, nil
+}
 
-    // 3. Limpiar área de inodos
-    fmt.Println("🗑️  Limpiando área de inodos...")
-    
-    file.Seek(superblock.S_inode_start, 0)
-    bytesWritten = 0
-    for bytesWritten < inodosAreaSize {
-        toWrite := int64(len(zeroBuffer))
-        if bytesWritten+toWrite > inodosAreaSize {
-            toWrite = inodosAreaSize - bytesWritten
-        }
-        if _, err := file.Write(zeroBuffer[:toWrite]); err != nil {
-            fmt.Printf("Error al limpiar área de inodos: %v\n", err)
-            return
-        }
-        bytesWritten += toWrite
+// fill - Contenido de la zona después de limpiarla, desde position
+func (a lostArea) fill(buffer []byte, position int64) {
+    if a.pattern == nil {
+        clear(buffer)
+        return
+    }
+    for i := range buffer {
+        buffer[i] = a.pattern[(position-a.start+int64(i))%int64(len(a.pattern))]
     }
+}
 
-    // 4. Limpiar área de bloques
-    fmt.Println("🗑️  Limpiando área de bloques...")
-    
-    file.Seek(superblock.S_block_start, 0)
-    bytesWritten = 0
-    for bytesWritten < bloquesAreaSize {
-        toWrite := int64(len(zeroBuffer))
-        if bytesWritten+toWrite > bloquesAreaSize {
-            toWrite = bloquesAreaSize - bytesWritten
-        }
-        if _, err := file.Write(zeroBuffer[:toWrite]); err != nil {
-            fmt.Printf("Error al limpiar área de bloques: %v\n", err)
-            return
+// collectLostState - Rangos de las zonas que cambian al limpiarlas, en tramos
+// de walDiffChunk. Deja de leer en cuanto superan lo que cabe en el journal.
+func collectLostState(file *diskFile, areas []lostArea, limit int64) ([]walChange, error) {
+    var changes []walChange
+    recordSize := int64(binary.Size(structs.WalRecord{}))
+    total := int64(0)
+    current := make([]byte, lossChunkSize)
+    wiped := make([]byte, lossChunkSize)
+
+    for _, area := range areas {
+        for position := area.start; position < area.end; position += lossChunkSize {
+            length := area.end - position
+            if length > lossChunkSize {
+                length = lossChunkSize
+            }
+            if _, err := file.ReadAt(current[:length], position); err != nil && err != io.EOF {
+                return nil, wrapCommandError(ErrCodeIO, err, "error al leer la partición")
+            }
+            area.fill(wiped[:length], position)
+
+            for unit := int64(0); unit < length; unit += walDiffChunk {
+                end := unit + walDiffChunk
+                if end > length {
+                    end = length
+                }
+                if bytes.Equal(current[unit:end], wiped[unit:end]) {
+                    continue
+                }
+                count := len(changes)
+                appendChange(&changes, position+unit, current[unit:end])
+                total += end - unit
+                if len(changes) > count {
+                    total += recordSize
+                }
+            }
+            if total > limit {
+                return nil, newCommandError(ErrCodeNoSpace,
+                    "el estado en uso ocupa más de %d bytes y el journal solo tiene %d: recovery no podría restaurarlo", total, limit)
+            }
         }
-        bytesWritten += toWrite
     }
+    return changes, nil
+}
 
-    // ✅ NUEVO: Limpiar el journaling de operaciones
-    fmt.Println("🧹 Limpiando entradas de journaling...")
-    if err := ClearJournal(mounted); err != nil {
-        fmt.Printf("⚠️  Advertencia al limpiar journaling: %v\n", err)
+// wipeLostState - Limpiar las zonas
+func wipeLostState(file *diskFile, areas []lostArea) error {
+    buffer := make([]byte, lossChunkSize)
+    for _, area := range areas {
+        for position := area.start; position < area.end; position += lossChunkSize {
+            length := area.end - position
+            if length > lossChunkSize {
+                length = lossChunkSize
+            }
+            area.fill(buffer[:length], position)
+            if _, err := file.WriteAt(buffer[:length], position); err != nil {
+                return err
+            }
+        }
     }
-
-    fmt.Println()
-    fmt.Println("✅ Pérdida del sistema de archivos simulada exitosamente.")
-    fmt.Println()
-    fmt.Println("💡 Para recuperar el sistema de archivos, use:")
-    fmt.Printf("   recovery -id=%s\n", id)
-    fmt.Println()
-    fmt.Println("📊 Recargue el explorador de archivos y el journaling para ver los cambios")
-}
\ No newline at end of file
+    return file.Sync()
+}
@@ -52,7 +52,8 @@ func formatBytes(bytes int64) string {
 }
 
 // calculateDiskStructure calcula la estructura del disco para visualización
-func calculateDiskStructure(mbr structs.MBR, totalSize int64) []DiskSegment {
+// (en discos GPT, gpt trae la tabla; nil en discos MBR)
+func calculateDiskStructure(mbr structs.MBR, gpt *gptTable, totalSize int64) []DiskSegment {
 	var segments []DiskSegment
 	currentPos := int64(0)
 
@@ -77,9 +78,21 @@ func calculateDiskStructure(mbr structs.MBR, totalSize int64) []DiskSegment {
 
 	// Obtener particiones ordenadas por posición de inicio
 	var partitions []structs.Partition
-	for _, part := range mbr.Mbr_partitions {
-		if part.Part_status != 0 && part.Part_start > 0 && part.Part_s > 0 {
-			partitions = append(partitions, part)
+	usableEnd := totalSize
+	if gpt != nil {
+		// MBR protector seguido de la cabecera y las entradas GPT
+		segments[0].Type = "MBR protector"
+		segments = append(segments, gptTableSegment("GPT", "Cabecera y entradas GPT",
+			gptSectorSize, gpt.Header.Gpt_first_usable-gptSectorSize, totalSize))
+		currentPos = gpt.Header.Gpt_first_usable
+
+		partitions = gpt.partitions()
+		usableEnd = gpt.Header.Gpt_last_usable + 1
+	} else {
+		for _, part := range mbr.Mbr_partitions {
+			if part.Part_status != 0 && part.Part_start > 0 && part.Part_s > 0 {
+				partitions = append(partitions, part)
+			}
 		}
 	}
 
@@ -164,8 +177,8 @@ func calculateDiskStructure(mbr structs.MBR, totalSize int64) []DiskSegment {
 	}
 
 	// Espacio libre al final
-	if currentPos < totalSize {
-		freeSize := totalSize - currentPos
+	if currentPos < usableEnd {
+		freeSize := usableEnd - currentPos
 		segments = append(segments, DiskSegment{
 			Type:        "Libre",
 			Name:        "Espacio Libre",
@@ -183,27 +196,52 @@ func calculateDiskStructure(mbr structs.MBR, totalSize int64) []DiskSegment {
 		})
 	}
 
+	// Copia de respaldo de la tabla GPT al final del disco
+	if gpt != nil {
+		backupStart := gpt.Header.Gpt_last_usable + 1
+		segments = append(segments, gptTableSegment("GPT respaldo", "Copia de respaldo de la tabla GPT",
+			backupStart, gpt.diskEnd()-backupStart, totalSize))
+	}
+
 	return segments
 }
 
+// gptTableSegment - Segmento del reporte disk para una copia de la tabla GPT
+func gptTableSegment(label string, name string, start int64, size int64, totalSize int64) DiskSegment {
+	return DiskSegment{
+		Type:        label,
+		Name:        name,
+		Label:       label,
+		Details:     formatBytes(size),
+		Tooltip:     fmt.Sprintf("%s: %s", name, formatBytes(size)),
+		StartBytes:  start,
+		SizeBytes:   size,
+		StartStr:    fmt.Sprintf("%d", start),
+		SizeStr:     formatBytes(size),
+		Percentage:  float64(size) / float64(totalSize) * 100,
+		CSSClass:    "segment-mbr",
+		LegendClass: "legend-mbr",
+		Status:      "Sistema",
+	}
+}
+
 // ExecuteRep genera reportes con Graphviz
-func ExecuteRep(name string, path string, id string, pathFileLs string, diskPath string) {
+func ExecuteRep(name string, path string, id string, pathFileLs string, diskPath string) (*CommandResult, error) {
+	res := NewCommandResult("rep")
+
 	// Validar parámetros obligatorios
 	if name == "" {
-		fmt.Println("❌ Error: El parámetro -name es obligatorio")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -name es obligatorio")
 	}
 	if path == "" {
-		fmt.Println("❌ Error: El parámetro -path es obligatorio")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio")
 	}
 	if id == "" {
-		fmt.Println("❌ Error: El parámetro -id es obligatorio")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio")
 	}
 
 	// Validar tipos de reporte válidos
-	validReports := []string{"mbr", "disk", "inode", "block", "bm_inode", "bm_block", "tree", "sb", "file", "ls"}
+	validReports := []string{"mbr", "disk", "inode", "block", "bm_inode", "bm_block", "tree", "sb", "file", "ls", "quota"}
 	name = strings.ToLower(name)
 	isValid := false
 	for _, valid := range validReports {
@@ -214,8 +252,7 @@ func ExecuteRep(name string, path string, id string, pathFileLs string, diskPath
 	}
 
 	if !isValid {
-		fmt.Printf("❌ Error: Tipo de reporte '%s' no válido. Tipos válidos: %s\n", name, strings.Join(validReports, ", "))
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "tipo de reporte '%s' no válido. Tipos válidos: %s", name, strings.Join(validReports, ", "))
 	}
 
 	// Obtener información de la partición montada
@@ -225,396 +262,174 @@ func ExecuteRep(name string, path string, id string, pathFileLs string, diskPath
 		mounts := GetMountedPartitions()
 		if len(mounts) == 0 && diskPath != "" {
 			// Only certain reports can be generated directly from a disk file without mounting
+			defer rlockDisk(diskPath)()
+			var err error
 			if name == "mbr" {
-				generateMBRReport(diskPath, path)
-				return
+				err = generateMBRReport(res, diskPath, path)
+			} else if name == "disk" {
+				err = generateDiskReport(res, diskPath, path)
 			}
-			if name == "disk" {
-				generateDiskReport(diskPath, path)
-				return
+			if name == "mbr" || name == "disk" {
+				if err != nil {
+					return res, wrapCommandError(ErrCodeIO, err, "error al generar el reporte '%s'", name)
+				}
+				res.AddPath(path)
+				res.Set("report", name)
+				return res, nil
 			}
 
-			fmt.Printf("⚠️  Aviso: La partición con ID '%s' no está montada, pero se proporcionó -disk='%s'.\n", id, diskPath)
-			fmt.Printf("⚠️  Solo los reportes 'mbr' y 'disk' se pueden generar directamente desde un archivo de disco no montado.\n")
-			return
+			res.Printf("⚠️  Aviso: La partición con ID '%s' no está montada, pero se proporcionó -disk='%s'.\n", id, diskPath)
+			return res, newCommandError(ErrCodeUnsupported, "solo los reportes 'mbr' y 'disk' se pueden generar directamente desde un archivo de disco no montado")
 		}
 
 		// Mostrar mensaje más útil con particiones montadas actuales
 		if len(mounts) == 0 {
-			fmt.Printf("❌ Error: No se encontró una partición montada con ID '%s'. No hay particiones montadas actualmente.\n", id)
-		} else {
-			fmt.Printf("❌ Error: No se encontró una partición montada con ID '%s'. Particiones montadas disponibles:\n", id)
-			for _, m := range mounts {
-				fmt.Printf("   - ID: %s  Nombre: %s  Disco: %s\n", m.ID, m.Name, m.Path)
-			}
+			return res, newCommandError(ErrCodeNotMounted, "no se encontró una partición montada con ID '%s'. No hay particiones montadas actualmente", id)
 		}
-		return
+		res.Printf("Particiones montadas disponibles:\n")
+		for _, m := range mounts {
+			res.Printf("   - ID: %s  Nombre: %s  Disco: %s\n", m.ID, m.Name, m.Path)
+		}
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró una partición montada con ID '%s'", id)
 	}
 
+	// Los reportes solo leen el disco
+	defer rlockDisk(mountedPartition.Path)()
+
 	// Crear directorio si no existe
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		fmt.Printf("❌ Error al crear directorio: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al crear directorio")
 	}
 
 	// Generar el reporte según el tipo
+	var err error
 	switch name {
 	case "mbr":
-		generateMBRReport(mountedPartition.Path, path)
+		err = generateMBRReport(res, mountedPartition.Path, path)
 	case "disk":
-		generateDiskReport(mountedPartition.Path, path)
+		err = generateDiskReport(res, mountedPartition.Path, path)
 	case "inode":
-		generateInodeReport(mountedPartition, path)
+		err = generateInodeReport(res, mountedPartition, path)
 	case "block":
-		generateBlockReport(mountedPartition, path)
+		err = generateBlockReport(res, mountedPartition, path)
 	case "bm_inode":
-		generateBitmapInodeReport(mountedPartition, path)
+		err = generateBitmapInodeReport(res, mountedPartition, path)
 	case "bm_block":
-		generateBitmapBlockReport(mountedPartition, path)
+		err = generateBitmapBlockReport(res, mountedPartition, path)
 	case "tree":
-		generateTreeReport(mountedPartition, path)
+		err = generateTreeReport(res, mountedPartition, path)
 	case "sb":
-		generateSuperBlockReport(mountedPartition, path)
+		err = generateSuperBlockReport(res, mountedPartition, path)
 	case "file":
-		generateFileReport(mountedPartition, path, pathFileLs)
+		err = generateFileReport(res, mountedPartition, path, pathFileLs)
 	case "ls":
-		generateLsReport(mountedPartition, path, pathFileLs)
+		err = generateLsReport(res, mountedPartition, path, pathFileLs)
+	case "quota":
+		err = generateQuotaReport(res, mountedPartition, path)
 	}
-}
 
-// generateMBRReport genera el reporte del MBR
-func generateMBRReport(diskPath string, outputPath string) {
-	file, err := os.Open(diskPath)
 	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al generar el reporte '%s'", name)
 	}
-	defer file.Close()
 
-	// Leer MBR con orden de bytes mixto
-	mbr, err := readMBRMixed(file)
-	if err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
-	}
+	res.AddPath(path)
+	res.Set("report", name)
+	res.Set("id", id)
 
-	htmlContent := generateMBRHTML(mbr, diskPath)
-	generateHTMLReport(htmlContent, outputPath, "MBR")
+	return res, nil
 }
 
-// AGREGAR función nueva para lectura mixta:
-func readMBRMixed(file *os.File) (structs.MBR, error) {
-	var mbr structs.MBR
-
-	file.Seek(0, 0)
-	buffer := make([]byte, 1024)
-	_, err := file.Read(buffer)
+// generateMBRReport genera el reporte del MBR
+func generateMBRReport(res *CommandResult, diskPath string, outputPath string) error {
+	disk, err := OpenDisk(diskPath, os.O_RDONLY)
 	if err != nil {
-		return mbr, err
+		return wrapCommandError(ErrCodeIO, err, "error al abrir el archivo")
 	}
+	defer disk.Close()
 
-	// Leer campos del MBR principal con LittleEndian
-	mbr.Mbr_tamano = int64(binary.LittleEndian.Uint64(buffer[0:8]))
-	mbr.Mbr_fecha_creacion = int64(binary.LittleEndian.Uint64(buffer[8:16]))
-	mbr.Mbr_dsk_signature = int64(binary.LittleEndian.Uint64(buffer[16:24]))
-	mbr.Dsk_fit = buffer[24]
-
-	// Leer particiones con LittleEndian (como estaba funcionando)
-	file.Seek(0, 0)
-	var mbrTemp structs.MBR
-	binary.Read(file, binary.LittleEndian, &mbrTemp)
-
-	// Copiar solo las particiones que funcionaban bien
-	mbr.Mbr_partitions = mbrTemp.Mbr_partitions
-
-	return mbr, nil
+	htmlContent := generateMBRHTML(disk.MBR, disk.GPT, diskPath)
+	return generateHTMLReport(res, htmlContent, outputPath, "MBR")
 }
 
-func readSuperBlockMixed(file *os.File, partitionStart int64) (structs.SuperBloque, error) {
-	var superblock structs.SuperBloque
-
-	// Posicionarse en el inicio de la partición
-	file.Seek(partitionStart, 0)
-
-	// Leer buffer del superbloque (1024 bytes para estar seguros)
-	buffer := make([]byte, 1024)
-	_, err := file.Read(buffer)
+// openReportFileSystem - Abrir en solo lectura el sistema de archivos de la partición a reportar
+func openReportFileSystem(partition *MountedPartition) (*FileSystem, error) {
+	file, err := openDisk(partition.Path, os.O_RDONLY)
 	if err != nil {
-		return superblock, err
-	}
-
-	// Primero intentar con LittleEndian (método original)
-	file.Seek(partitionStart, 0)
-	if err := binary.Read(file, binary.LittleEndian, &superblock); err != nil {
-		return superblock, err
+		return nil, wrapCommandError(ErrCodeIO, err, "error al abrir el archivo")
 	}
 
-	// Validaciones de consistencia
-	if superblock.S_inodes_count <= 0 || superblock.S_inodes_count > 1000000 {
-		return superblock, fmt.Errorf("número de inodos inválido: %d", superblock.S_inodes_count)
-	}
-
-	if superblock.S_inode_s <= 0 || superblock.S_inode_s > 1024 {
-		return superblock, fmt.Errorf("tamaño de inodo inválido: %d", superblock.S_inode_s)
-	}
-
-	if superblock.S_block_s <= 0 || superblock.S_block_s > 8192 {
-		return superblock, fmt.Errorf("tamaño de bloque inválido: %d", superblock.S_block_s)
-	}
-
-	// Verificar que los contadores sean consistentes
-	if superblock.S_free_inodes_count > superblock.S_inodes_count {
-		fmt.Printf("⚠️  Advertencia: inodos libres (%d) > total (%d)\n",
-			superblock.S_free_inodes_count, superblock.S_inodes_count)
+	fs, err := mountedFileSystem(file, partition)
+	if err != nil {
+		file.Close()
+		return nil, wrapCommandError(ErrCodeNotFound, err, "no se encontró el sistema de archivos de la partición '%s'", partition.Name)
 	}
-
-	return superblock, nil
+	return fs, nil
 }
 
 // generateDiskReport genera el reporte del disco
-func generateDiskReport(diskPath string, outputPath string) {
-	file, err := os.Open(diskPath)
+func generateDiskReport(res *CommandResult, diskPath string, outputPath string) error {
+	disk, err := OpenDisk(diskPath, os.O_RDONLY)
 	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
+		return wrapCommandError(ErrCodeIO, err, "error al abrir el archivo")
 	}
-	defer file.Close()
+	defer disk.Close()
 
-	fileInfo, err := file.Stat()
+	fileInfo, err := disk.file.Stat()
 	if err != nil {
-		fmt.Printf("❌ Error al obtener información del archivo: %v\n", err)
-		return
-	}
-
-	// Usar la misma función mixta que en MBR
-	mbr, err := readMBRMixed(file)
-	if err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
+		return wrapCommandError(ErrCodeIO, err, "error al obtener información del archivo")
 	}
 
-	htmlContent := generateDiskHTML(mbr, diskPath, fileInfo)
-	generateHTMLReport(htmlContent, outputPath, "DISK")
+	htmlContent := generateDiskHTML(disk.MBR, disk.GPT, diskPath, fileInfo)
+	return generateHTMLReport(res, htmlContent, outputPath, "DISK")
 }
 
 // generateSuperBlockReport genera el reporte del superbloque
-func generateSuperBlockReport(partition *MountedPartition, outputPath string) {
-	file, err := os.Open(partition.Path)
-	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	// Leer MBR para encontrar la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
-	}
-
-	var partitionStart int64
-	found := false
-
-	// Normalizar el nombre de la partición montada
-	mountedName := strings.TrimSpace(strings.TrimRight(partition.Name, "\x00"))
-
-	for _, part := range mbr.Mbr_partitions {
-		if part.Part_status != '0' && part.Part_status != 0 {
-			partitionName := strings.TrimSpace(strings.TrimRight(string(part.Part_name[:]), "\x00"))
-
-			if partitionName == mountedName {
-				partitionStart = part.Part_start
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
-		fmt.Printf("❌ Error: No se encontró la partición '%s'\n", mountedName)
-		return
-	}
-
-	// Leer superbloque
-	superblock, err := readSuperBlockMixed(file, partitionStart)
+func generateSuperBlockReport(res *CommandResult, partition *MountedPartition, outputPath string) error {
+	fs, err := openReportFileSystem(partition)
 	if err != nil {
-		fmt.Printf("❌ Error al leer el superbloque: %v\n", err)
-		return
+		return err
 	}
+	defer fs.Close()
 
-	htmlContent := generateSuperBlockHTML(superblock, partition.Name, partition.Path)
-	generateHTMLReport(htmlContent, outputPath, "SUPERBLOCK")
+	htmlContent := generateSuperBlockHTML(*fs.SB, partition.Name, partition.Path)
+	return generateHTMLReport(res, htmlContent, outputPath, "SUPERBLOCK")
 }
 
 // generateInodeReport genera el reporte de inodos
-func generateInodeReport(partition *MountedPartition, outputPath string) {
-
-	file, err := os.Open(partition.Path)
-	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	// Leer MBR para encontrar la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
-	}
-
-	var partitionStart int64
-	found := false
-
-	// Normalizar el nombre de la partición montada (quitar espacios y null bytes)
-	mountedName := strings.TrimSpace(strings.TrimRight(partition.Name, "\x00"))
-
-	for _, part := range mbr.Mbr_partitions {
-		if part.Part_status != '0' && part.Part_status != 0 {
-			// Normalizar el nombre de la partición del MBR
-			partitionName := strings.TrimSpace(strings.TrimRight(string(part.Part_name[:]), "\x00"))
-
-			if partitionName == mountedName {
-				partitionStart = part.Part_start
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
-		for i, part := range mbr.Mbr_partitions {
-			if part.Part_status != '0' && part.Part_status != 0 {
-				partitionName := strings.TrimSpace(strings.TrimRight(string(part.Part_name[:]), "\x00"))
-				fmt.Printf("   %d: '%s' (inicio: %d, tamaño: %d)\n",
-					i+1, partitionName, part.Part_start, part.Part_s)
-			}
-		}
-		return
-	}
-
-	// Leer superbloque
-	superblock, err := readSuperBlockMixed(file, partitionStart)
+func generateInodeReport(res *CommandResult, partition *MountedPartition, outputPath string) error {
+	fs, err := openReportFileSystem(partition)
 	if err != nil {
-		fmt.Printf("❌ Error al leer el superbloque: %v\n", err)
-		return
+		return err
 	}
+	defer fs.Close()
 
-	// Validar valores del superbloque
-	if superblock.S_inodes_count <= 0 || superblock.S_inodes_count > 1000000 {
-
-		// Intentar lectura estándar del superbloque
-		file.Seek(partitionStart, 0)
-		if err := binary.Read(file, binary.LittleEndian, &superblock); err != nil {
-			fmt.Printf("❌ Error al leer superbloque con LittleEndian: %v\n", err)
-			return
-		}
-	}
-
-	htmlContent := generateInodeHTML(file, superblock, partition.Name)
-	generateHTMLReport(htmlContent, outputPath, "INODE")
+	htmlContent := generateInodeHTML(fs, partition.Name)
+	return generateHTMLReport(res, htmlContent, outputPath, "INODE")
 }
 
 // generateBlockReport genera el reporte de bloques
-func generateBlockReport(partition *MountedPartition, outputPath string) {
-	file, err := os.Open(partition.Path)
+func generateBlockReport(res *CommandResult, partition *MountedPartition, outputPath string) error {
+	fs, err := openReportFileSystem(partition)
 	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
+		return err
 	}
-	defer file.Close()
-
-	// Leer MBR para encontrar la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
-	}
-
-	var partitionStart int64
-	found := false
-
-	// Normalizar el nombre de la partición montada
-	mountedName := strings.TrimSpace(strings.TrimRight(partition.Name, "\x00"))
-
-	for _, part := range mbr.Mbr_partitions {
-		if part.Part_status != '0' && part.Part_status != 0 {
-			partitionName := strings.TrimSpace(strings.TrimRight(string(part.Part_name[:]), "\x00"))
+	defer fs.Close()
 
-			if partitionName == mountedName {
-				partitionStart = part.Part_start
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
-		fmt.Printf("❌ Error: No se encontró la partición '%s'\n", mountedName)
-		return
-	}
-
-	// Leer superbloque
-	superblock, err := readSuperBlockMixed(file, partitionStart)
-	if err != nil {
-		fmt.Printf("❌ Error al leer el superbloque: %v\n", err)
-		return
-	}
-
-	htmlContent := generateBlockHTML(file, superblock, partition.Name)
-	generateHTMLReport(htmlContent, outputPath, "BLOCK")
+	htmlContent := generateBlockHTML(fs, partition.Name)
+	return generateHTMLReport(res, htmlContent, outputPath, "BLOCK")
 }
 
 // generateBitmapInodeReport genera el reporte del bitmap de inodos
-func generateBitmapInodeReport(partition *MountedPartition, outputPath string) {
-	file, err := os.Open(partition.Path)
-	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	// Leer MBR para encontrar la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
-	}
-
-	var partitionStart int64
-	found := false
-
-	// Normalizar el nombre de la partición montada
-	mountedName := strings.TrimSpace(strings.TrimRight(partition.Name, "\x00"))
-
-	for _, part := range mbr.Mbr_partitions {
-		if part.Part_status != '0' && part.Part_status != 0 {
-			partitionName := strings.TrimSpace(strings.TrimRight(string(part.Part_name[:]), "\x00"))
-
-			if partitionName == mountedName {
-				partitionStart = part.Part_start
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
-		fmt.Printf("❌ Error: No se encontró la partición '%s'\n", mountedName)
-		return
-	}
-
-	// Leer superbloque
-	superblock, err := readSuperBlockMixed(file, partitionStart)
+func generateBitmapInodeReport(res *CommandResult, partition *MountedPartition, outputPath string) error {
+	fs, err := openReportFileSystem(partition)
 	if err != nil {
-		fmt.Printf("❌ Error al leer el superbloque: %v\n", err)
-		return
+		return err
 	}
+	defer fs.Close()
 
 	// Generar contenido del bitmap
-	txtContent := generateBitmapInodeTxt(file, superblock, partition.Name)
+	txtContent := generateBitmapInodeTxt(fs, partition.Name)
 
 	// Asegurar extensión .txt
 	if !strings.HasSuffix(strings.ToLower(outputPath), ".txt") {
@@ -623,61 +438,24 @@ func generateBitmapInodeReport(partition *MountedPartition, outputPath string) {
 
 	// Escribir archivo de texto
 	if err := os.WriteFile(outputPath, []byte(txtContent), 0644); err != nil {
-		fmt.Printf("❌ Error al escribir archivo TXT: %v\n", err)
-		return
+		return wrapCommandError(ErrCodeIO, err, "error al escribir archivo TXT")
 	}
 
-	fmt.Printf("✅ Reporte BITMAP_INODE generado: %s\n", outputPath)
+	res.Printf("✅ Reporte BITMAP_INODE generado: %s\n", outputPath)
+
+	return nil
 }
 
 // generateBitmapBlockReport genera el reporte del bitmap de bloques
-func generateBitmapBlockReport(partition *MountedPartition, outputPath string) {
-	file, err := os.Open(partition.Path)
+func generateBitmapBlockReport(res *CommandResult, partition *MountedPartition, outputPath string) error {
+	fs, err := openReportFileSystem(partition)
 	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	// Leer MBR para encontrar la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
-	}
-
-	var partitionStart int64
-	found := false
-
-	// Normalizar el nombre de la partición montada
-	mountedName := strings.TrimSpace(strings.TrimRight(partition.Name, "\x00"))
-
-	for _, part := range mbr.Mbr_partitions {
-		if part.Part_status != '0' && part.Part_status != 0 {
-			partitionName := strings.TrimSpace(strings.TrimRight(string(part.Part_name[:]), "\x00"))
-
-			if partitionName == mountedName {
-				partitionStart = part.Part_start
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
-		fmt.Printf("❌ Error: No se encontró la partición '%s'\n", mountedName)
-		return
-	}
-
-	// Leer superbloque
-	superblock, err := readSuperBlockMixed(file, partitionStart)
-	if err != nil {
-		fmt.Printf("❌ Error al leer el superbloque: %v\n", err)
-		return
+		return err
 	}
+	defer fs.Close()
 
 	// Generar contenido del bitmap de bloques
-	txtContent := generateBitmapBlockTxt(file, superblock, partition.Name)
+	txtContent := generateBitmapBlockTxt(fs, partition.Name)
 
 	// Asegurar extensión .txt
 	if !strings.HasSuffix(strings.ToLower(outputPath), ".txt") {
@@ -686,116 +464,39 @@ func generateBitmapBlockReport(partition *MountedPartition, outputPath string) {
 
 	// Escribir archivo de texto
 	if err := os.WriteFile(outputPath, []byte(txtContent), 0644); err != nil {
-		fmt.Printf("❌ Error al escribir archivo TXT: %v\n", err)
-		return
+		return wrapCommandError(ErrCodeIO, err, "error al escribir archivo TXT")
 	}
 
-	fmt.Printf("✅ Reporte BITMAP_BLOCK generado: %s\n", outputPath)
+	res.Printf("✅ Reporte BITMAP_BLOCK generado: %s\n", outputPath)
+
+	return nil
 }
 
 // generateTreeReport genera el reporte del árbol del sistema de archivos
-func generateTreeReport(partition *MountedPartition, outputPath string) {
-	file, err := os.Open(partition.Path)
+func generateTreeReport(res *CommandResult, partition *MountedPartition, outputPath string) error {
+	fs, err := openReportFileSystem(partition)
 	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	// Leer MBR para encontrar la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
+		return err
 	}
+	defer fs.Close()
 
-	var partitionStart int64
-	found := false
-
-	// Normalizar el nombre de la partición montada
-	mountedName := strings.TrimSpace(strings.TrimRight(partition.Name, "\x00"))
-
-	for _, part := range mbr.Mbr_partitions {
-		if part.Part_status != '0' && part.Part_status != 0 {
-			partitionName := strings.TrimSpace(strings.TrimRight(string(part.Part_name[:]), "\x00"))
-
-			if partitionName == mountedName {
-				partitionStart = part.Part_start
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
-		fmt.Printf("❌ Error: No se encontró la partición '%s'\n", mountedName)
-		return
-	}
-
-	// Leer superbloque
-	superblock, err := readSuperBlockMixed(file, partitionStart)
-	if err != nil {
-		fmt.Printf("❌ Error al leer el superbloque: %v\n", err)
-		return
-	}
-
-	htmlContent := generateTreeHTML(file, superblock, partition.Name)
-	generateHTMLReport(htmlContent, outputPath, "TREE")
+	htmlContent := generateTreeHTML(fs, partition.Name)
+	return generateHTMLReport(res, htmlContent, outputPath, "TREE")
 }
 
 // generateFileReport genera el reporte de un archivo específico
-func generateFileReport(partition *MountedPartition, outputPath string, filePath string) {
+func generateFileReport(res *CommandResult, partition *MountedPartition, outputPath string, filePath string) error {
 	if filePath == "" {
-		fmt.Println("❌ Error: El parámetro -path_file_ls es obligatorio para el reporte 'file'")
-		return
+		return newCommandError(ErrCodeInvalidArgument, "el parámetro -path_file_ls es obligatorio para el reporte 'file'")
 	}
-
-	file, err := os.Open(partition.Path)
+	fs, err := openReportFileSystem(partition)
 	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	// Leer MBR para encontrar la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
-	}
-
-	var partitionStart int64
-	found := false
-
-	// Normalizar el nombre de la partición montada
-	mountedName := strings.TrimSpace(strings.TrimRight(partition.Name, "\x00"))
-
-	for _, part := range mbr.Mbr_partitions {
-		if part.Part_status != '0' && part.Part_status != 0 {
-			partitionName := strings.TrimSpace(strings.TrimRight(string(part.Part_name[:]), "\x00"))
-
-			if partitionName == mountedName {
-				partitionStart = part.Part_start
-				found = true
-				break
-			}
-		}
-	}
-
-	if !found {
-		fmt.Printf("❌ Error: No se encontró la partición '%s'\n", mountedName)
-		return
-	}
-
-	// Leer superbloque
-	superblock, err := readSuperBlockMixed(file, partitionStart)
-	if err != nil {
-		fmt.Printf("❌ Error al leer el superbloque: %v\n", err)
-		return
+		return err
 	}
+	defer fs.Close()
 
 	// Generar contenido del archivo
-	txtContent := generateFileTxt(file, superblock, filePath, partition.Name)
+	txtContent := generateFileTxt(fs, filePath, partition.Name)
 
 	// Asegurar extensión .txt
 	if !strings.HasSuffix(strings.ToLower(outputPath), ".txt") {
@@ -804,72 +505,62 @@ func generateFileReport(partition *MountedPartition, outputPath string, filePath
 
 	// Escribir archivo de texto
 	if err := os.WriteFile(outputPath, []byte(txtContent), 0644); err != nil {
-		fmt.Printf("❌ Error al escribir archivo TXT: %v\n", err)
-		return
+		return wrapCommandError(ErrCodeIO, err, "error al escribir archivo TXT")
 	}
 
-	fmt.Printf("✅ Reporte FILE generado: %s\n", outputPath)
+	res.Printf("✅ Reporte FILE generado: %s\n", outputPath)
+
+	return nil
 }
 
 // generateLsReport genera el reporte de listado de directorio
-func generateLsReport(partition *MountedPartition, outputPath string, dirPath string) {
+func generateLsReport(res *CommandResult, partition *MountedPartition, outputPath string, dirPath string) error {
 	if dirPath == "" {
 		dirPath = "/" // Directorio raíz por defecto
 	}
-
-	file, err := os.Open(partition.Path)
+	fs, err := openReportFileSystem(partition)
 	if err != nil {
-		fmt.Printf("❌ Error al abrir el archivo: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	// Leer MBR para encontrar la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		fmt.Printf("❌ Error al leer el MBR: %v\n", err)
-		return
+		return err
 	}
+	defer fs.Close()
 
-	var partitionStart int64
-	found := false
-
-	// Normalizar el nombre de la partición montada
-	mountedName := strings.TrimSpace(strings.TrimRight(partition.Name, "\x00"))
+	htmlContent := generateLsHTML(fs, dirPath, partition.Name)
+	return generateHTMLReport(res, htmlContent, outputPath, "LS")
+}
 
-	for _, part := range mbr.Mbr_partitions {
-		if part.Part_status != '0' && part.Part_status != 0 {
-			partitionName := strings.TrimSpace(strings.TrimRight(string(part.Part_name[:]), "\x00"))
+// generateQuotaReport genera el reporte de uso y cuotas por usuario y grupo
+func generateQuotaReport(res *CommandResult, partition *MountedPartition, outputPath string) error {
+	fs, err := openReportFileSystem(partition)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
 
-			if partitionName == mountedName {
-				partitionStart = part.Part_start
-				found = true
-				break
-			}
-		}
+	txtContent, err := generateQuotaTxt(fs, partition.Name)
+	if err != nil {
+		return err
 	}
 
-	if !found {
-		fmt.Printf("❌ Error: No se encontró la partición '%s'\n", mountedName)
-		return
+	// Asegurar extensión .txt
+	if !strings.HasSuffix(strings.ToLower(outputPath), ".txt") {
+		outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".txt"
 	}
 
-	// Leer superbloque
-	superblock, err := readSuperBlockMixed(file, partitionStart)
-	if err != nil {
-		fmt.Printf("❌ Error al leer el superbloque: %v\n", err)
-		return
+	// Escribir archivo de texto
+	if err := os.WriteFile(outputPath, []byte(txtContent), 0644); err != nil {
+		return wrapCommandError(ErrCodeIO, err, "error al escribir archivo TXT")
 	}
 
-	htmlContent := generateLsHTML(file, superblock, dirPath, partition.Name)
-	generateHTMLReport(htmlContent, outputPath, "LS")
+	res.Printf("✅ Reporte QUOTA generado: %s\n", outputPath)
+
+	return nil
 }
 
 // readEBRs lee todos los EBRs de una partición extendida
 func readEBRs(diskPath string, extendedPartition structs.Partition) []structs.EBR {
 	var ebrs []structs.EBR
 
-	file, err := os.Open(diskPath)
+	file, err := openDisk(diskPath, os.O_RDONLY)
 	if err != nil {
 		fmt.Printf("❌ Error al abrir disco para leer EBRs: %v\n", err)
 		return ebrs
@@ -907,7 +598,7 @@ func readEBRs(diskPath string, extendedPartition structs.Partition) []structs.EB
 }
 
 // generateHTMLReport genera el archivo HTML con estilos modernos
-func generateHTMLReport(htmlContent string, outputPath string, reportType string) {
+func generateHTMLReport(res *CommandResult, htmlContent string, outputPath string, reportType string) error {
 	// Asegurar extensión .html
 	if !strings.HasSuffix(strings.ToLower(outputPath), ".html") {
 		outputPath = strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".html"
@@ -915,16 +606,17 @@ func generateHTMLReport(htmlContent string, outputPath string, reportType string
 
 	// Escribir contenido HTML
 	if err := os.WriteFile(outputPath, []byte(htmlContent), 0644); err != nil {
-		fmt.Printf("❌ Error al escribir archivo HTML: %v\n", err)
-		return
+		return wrapCommandError(ErrCodeIO, err, "error al escribir archivo HTML")
 	}
 
-	fmt.Printf("✅ Reporte %s generado: %s\n", reportType, outputPath)
-	fmt.Printf("🌐 Abre en tu navegador: file://%s\n", outputPath)
+	res.Printf("✅ Reporte %s generado: %s\n", reportType, outputPath)
+	res.Printf("🌐 Abre en tu navegador: file://%s\n", outputPath)
+
+	return nil
 }
 
-// generateMBRHTML genera el reporte MBR en HTML moderno
-func generateMBRHTML(mbr structs.MBR, diskPath string) string {
+// generateMBRHTML genera el reporte MBR en HTML moderno (con la tabla GPT si el disco la usa)
+func generateMBRHTML(mbr structs.MBR, gpt *gptTable, diskPath string) string {
 	var html strings.Builder
 
 	html.WriteString(`<!DOCTYPE html>
@@ -1205,11 +897,18 @@ func generateMBRHTML(mbr structs.MBR, diskPath string) string {
             </div>
         </div>`)
 
+	// En discos GPT: cabecera GPT y particiones de sus entradas, no las del MBR protector
+	partitions := mbr.Mbr_partitions[:]
+	if gpt != nil {
+		html.WriteString(generateGPTHeaderHTML(gpt))
+		partitions = gpt.partitions()
+	}
+
 	// Particiones Primarias y Extendidas
 	extendedPartition := -1
 	partitionCount := 0
 
-	for i, partition := range mbr.Mbr_partitions {
+	for i, partition := range partitions {
 		if partition.Part_status != 0 && partition.Part_start > 0 && partition.Part_s > 0 {
 			partitionCount++
 			name := strings.TrimSpace(string(partition.Part_name[:]))
@@ -1281,7 +980,7 @@ func generateMBRHTML(mbr structs.MBR, diskPath string) string {
 
 	// EBRs si hay partición extendida
 	if extendedPartition >= 0 {
-		ebrs := readEBRs(diskPath, mbr.Mbr_partitions[extendedPartition])
+		ebrs := readEBRs(diskPath, partitions[extendedPartition])
 
 		if len(ebrs) > 0 {
 			html.WriteString(`
@@ -1388,7 +1087,50 @@ func generateMBRHTML(mbr structs.MBR, diskPath string) string {
 	return html.String()
 }
 
-func generateDiskHTML(mbr structs.MBR, diskPath string, fileInfo os.FileInfo) string {
+// generateGPTHeaderHTML genera la tarjeta con la cabecera GPT del reporte MBR
+func generateGPTHeaderHTML(gpt *gptTable) string {
+	header := gpt.Header
+	state := "Íntegra (principal y respaldo)"
+	if gpt.FromBackup {
+		state = "Principal dañada, leída del respaldo"
+	}
+
+	items := [][2]string{
+		{"Firma:", string(header.Gpt_signature[:])},
+		{"Revisión:", fmt.Sprintf("%d.%d", header.Gpt_revision>>16, header.Gpt_revision&0xffff)},
+		{"GUID del Disco:", formatGUID(header.Gpt_disk_guid)},
+		{"Cabecera Principal:", fmt.Sprintf("%d bytes", header.Gpt_current)},
+		{"Cabecera de Respaldo:", fmt.Sprintf("%d bytes", header.Gpt_backup)},
+		{"Entradas:", fmt.Sprintf("%d usadas de %d (%d bytes c/u)", len(gpt.partitions()), header.Gpt_entries_count, header.Gpt_entry_size)},
+		{"Zona Utilizable:", fmt.Sprintf("%d - %d bytes", header.Gpt_first_usable, header.Gpt_last_usable)},
+		{"CRC32 Cabecera:", fmt.Sprintf("%08X", header.Gpt_header_crc)},
+		{"CRC32 Entradas:", fmt.Sprintf("%08X", header.Gpt_entries_crc)},
+		{"Estado:", state},
+	}
+
+	var html strings.Builder
+	html.WriteString(`
+        <div class="card animation-fade">
+            <h2 class="card-title">
+                <span class="icon">🧭</span>
+                Tabla de Particiones GPT
+            </h2>
+            <div class="info-grid">`)
+	for _, item := range items {
+		html.WriteString(fmt.Sprintf(`
+                <div class="info-item">
+                    <span class="info-label">%s</span>
+                    <span class="info-value">%s</span>
+                </div>`, item[0], item[1]))
+	}
+	html.WriteString(`
+            </div>
+        </div>`)
+
+	return html.String()
+}
+
+func generateDiskHTML(mbr structs.MBR, gpt *gptTable, diskPath string, fileInfo os.FileInfo) string {
 	var html strings.Builder
 
 	html.WriteString(`<!DOCTYPE html>
@@ -1669,7 +1411,7 @@ func generateDiskHTML(mbr structs.MBR, diskPath string, fileInfo os.FileInfo) st
         </div>`)
 
 	// Calcular estructura del disco
-	diskStructure := calculateDiskStructure(mbr, fileInfo.Size())
+	diskStructure := calculateDiskStructure(mbr, gpt, fileInfo.Size())
 
 	// Mostrar visualización del disco
 	html.WriteString(`
@@ -1788,7 +1530,8 @@ func generateDiskHTML(mbr structs.MBR, diskPath string, fileInfo os.FileInfo) st
 	return html.String()
 }
 
-func generateInodeHTML(file *os.File, superblock structs.SuperBloque, partitionName string) string {
+func generateInodeHTML(fs *FileSystem, partitionName string) string {
+	file, superblock := fs.file, *fs.SB
 	var html strings.Builder
 
 	html.WriteString(`<!DOCTYPE html>
@@ -2245,16 +1988,23 @@ func formatPermissions(perm [3]byte) string {
 }
 
 // readInodesFromPartition lee todos los inodos de una partición
-func readInodesFromPartition(file *os.File, superblock structs.SuperBloque) []structs.Inodos {
+func readInodesFromPartition(file *diskFile, superblock structs.SuperBloque) []structs.Inodos {
 	var inodes []structs.Inodos
 
-	// Posicionarse en el inicio de los inodos
-	file.Seek(superblock.S_inode_start, 0)
+	// Cada inodo ocupa S_inode_s bytes (menos que la estructura en particiones antiguas)
+	inodeSize := superblock.S_inode_s
+	if inodeSize > inodeStructSize {
+		inodeSize = inodeStructSize
+	}
 
 	// Leer todos los inodos
+	raw := make([]byte, inodeSize)
 	for i := int64(0); i < superblock.S_inodes_count; i++ {
-		var inode structs.Inodos
-		if err := binary.Read(file, binary.LittleEndian, &inode); err != nil {
+		if _, err := file.ReadAt(raw, superblock.S_inode_start+i*superblock.S_inode_s); err != nil {
+			break
+		}
+		inode, err := decodeInode(raw)
+		if err != nil {
 			break
 		}
 		inodes = append(inodes, inode)
@@ -2306,7 +2056,7 @@ func isInodeValid(inode structs.Inodos) bool {
 	return hasValidBlock && (inode.I_s > 0 || inode.I_uid >= 0)
 }
 
-func generateBlockHTML(file *os.File, superblock structs.SuperBloque, partitionName string) string {
+func generateBlockHTML(fs *FileSystem, partitionName string) string {
 	var html strings.Builder
 
 	html.WriteString(`<!DOCTYPE html>
@@ -2536,7 +2286,7 @@ func generateBlockHTML(file *os.File, superblock structs.SuperBloque, partitionN
         </div>`)
 
 	// Leer bloques utilizados
-	usedBlocks := readUsedBlocksFromPartition(file, superblock)
+	usedBlocks := readUsedBlocksFromPartition(fs)
 
 	// Mostrar solo la lista enlazada de bloques
 	if len(usedBlocks) > 0 {
@@ -2563,6 +2313,10 @@ func generateBlockHTML(file *os.File, superblock structs.SuperBloque, partitionN
 				blockClass = "block-pointer"
 				iconType = "🔗"
 				blockType = "Apuntadores"
+			case "name":
+				blockClass = "block-folder"
+				iconType = "🏷️"
+				blockType = "Nombre"
 			}
 
 			html.WriteString(fmt.Sprintf(`
@@ -2643,7 +2397,8 @@ type BlockData struct {
 }
 
 // readUsedBlocksFromPartition lee todos los bloques utilizados de una partición
-func readUsedBlocksFromPartition(file *os.File, superblock structs.SuperBloque) []BlockData {
+func readUsedBlocksFromPartition(fs *FileSystem) []BlockData {
+	file, superblock := fs.file, *fs.SB
 	var usedBlocks []BlockData
 
 	// Primero obtener los inodos utilizados para saber qué bloques están en uso
@@ -2653,37 +2408,62 @@ func readUsedBlocksFromPartition(file *os.File, superblock structs.SuperBloque)
 	// Set para evitar bloques duplicados
 	seenBlocks := make(map[int64]bool)
 
-	// Para cada inodo utilizado, leer sus bloques
+	// Para cada inodo utilizado, leer sus bloques (directos, indirectos y de apuntadores)
 	for _, inode := range usedInodes {
-		for _, blockIndex := range inode.I_block {
-			if blockIndex != -1 && blockIndex >= 0 && !seenBlocks[blockIndex] {
-				seenBlocks[blockIndex] = true
+		// Determinar el tipo de bloque según el tipo de inodo
+		var blockType string
+		var realType int64
+		if inode.I_type >= 48 && inode.I_type <= 57 {
+			realType = int64(inode.I_type - 48)
+		} else {
+			realType = int64(inode.I_type)
+		}
 
-				// Leer el contenido del bloque
-				blockPosition := superblock.S_block_start + (blockIndex * superblock.S_block_s)
-				file.Seek(blockPosition, 0)
+		if realType == 0 || inode.I_s == 96 {
+			blockType = "folder"
+		} else {
+			blockType = "file"
+		}
 
-				// Determinar el tipo de bloque según el tipo de inodo
-				var blockType string
-				var realType int64
-				if inode.I_type >= 48 && inode.I_type <= 57 {
-					realType = int64(inode.I_type - 48)
-				} else {
-					realType = int64(inode.I_type)
-				}
+		dataBlocks, pointerBlocks, err := fs.InodeBlocks(&inode)
+		if err != nil {
+			continue
+		}
 
-				if realType == 0 || inode.I_s == 96 {
-					blockType = "folder"
-				} else {
-					blockType = "file"
+		for _, blockIndex := range dataBlocks {
+			if blockIndex >= 0 && !seenBlocks[blockIndex] {
+				seenBlocks[blockIndex] = true
+
+				usedBlocks = append(usedBlocks, BlockData{
+					Index:   blockIndex,
+					Type:    blockType,
+					Content: readBlockContent(fs, blockIndex, blockType),
+				})
+
+				// Bloques con los nombres largos de las entradas de la carpeta
+				if blockType == "folder" {
+					for _, nameBlock := range readNameBlocks(fs, blockIndex) {
+						if !seenBlocks[nameBlock] {
+							seenBlocks[nameBlock] = true
+							usedBlocks = append(usedBlocks, BlockData{
+								Index:   nameBlock,
+								Type:    "name",
+								Content: readBlockContent(fs, nameBlock, "name"),
+							})
+						}
+					}
 				}
+			}
+		}
 
-				content := readBlockContent(file, superblock, blockIndex, blockType)
+		for _, blockIndex := range pointerBlocks {
+			if !seenBlocks[blockIndex] {
+				seenBlocks[blockIndex] = true
 
 				usedBlocks = append(usedBlocks, BlockData{
 					Index:   blockIndex,
-					Type:    blockType,
-					Content: content,
+					Type:    "pointer",
+					Content: readBlockContent(fs, blockIndex, "pointer"),
 				})
 			}
 		}
@@ -2692,16 +2472,35 @@ func readUsedBlocksFromPartition(file *os.File, superblock structs.SuperBloque)
 	return usedBlocks
 }
 
-// readBlockContent lee y formatea el contenido de un bloque
-func readBlockContent(file *os.File, superblock structs.SuperBloque, blockIndex int64, blockType string) string {
-	blockPosition := superblock.S_block_start + (blockIndex * superblock.S_block_s)
-	file.Seek(blockPosition, 0)
+// readNameBlocks devuelve los bloques de nombres largos de un bloque de carpeta
+func readNameBlocks(fs *FileSystem, blockIndex int64) []int64 {
+	if !hasLongNames(fs.SB) {
+		return nil
+	}
+	folderBlock, err := fs.ReadFolderBlock(blockIndex)
+	if err != nil {
+		return nil
+	}
 
+	var nameBlocks []int64
+	for i := range folderBlock.BContent {
+		entry := &folderBlock.BContent[i]
+		if entry.BInodo == -1 || entry.BLong == 0 {
+			continue
+		}
+		blocks, _, _ := fs.nameChain(entry)
+		nameBlocks = append(nameBlocks, blocks...)
+	}
+	return nameBlocks
+}
+
+// readBlockContent lee y formatea el contenido de un bloque
+func readBlockContent(fs *FileSystem, blockIndex int64, blockType string) string {
 	switch blockType {
 	case "folder":
 		// Leer como bloque de carpeta
 		var folderBlock structs.BloqueCarpeta
-		if err := binary.Read(file, binary.LittleEndian, &folderBlock); err != nil {
+		if err := fs.ReadBlock(blockIndex, &folderBlock); err != nil {
 			return fmt.Sprintf("Error al leer bloque de carpeta: %v", err)
 		}
 
@@ -2709,7 +2508,7 @@ func readBlockContent(file *os.File, superblock structs.SuperBloque, blockIndex
 
 		// Formatear el contenido como en el ejemplo
 		for i, content_entry := range folderBlock.BContent {
-			name := strings.TrimRight(string(content_entry.BName[:]), "\x00")
+			name := fs.EntryName(&content_entry)
 			if name != "" {
 				content.WriteString(fmt.Sprintf("%-12s %d\n", name, content_entry.BInodo))
 			} else if i < 4 { // Mostrar entradas vacías solo para las primeras 4
@@ -2719,15 +2518,24 @@ func readBlockContent(file *os.File, superblock structs.SuperBloque, blockIndex
 
 		return content.String()
 
+	case "name":
+		// Leer como tramo de un nombre largo
+		var nameBlock structs.BloqueNombre
+		if err := fs.ReadBlock(blockIndex, &nameBlock); err != nil {
+			return fmt.Sprintf("Error al leer bloque de nombre: %v", err)
+		}
+
+		return fmt.Sprintf("%s\nsiguiente: %d", strings.TrimRight(string(nameBlock.BName), "\x00"), nameBlock.BNext)
+
 	case "file":
 		// Leer como bloque de archivo
 		var fileBlock structs.BloqueArchivo
-		if err := binary.Read(file, binary.LittleEndian, &fileBlock); err != nil {
+		if err := fs.ReadBlock(blockIndex, &fileBlock); err != nil {
 			return fmt.Sprintf("Error al leer bloque de archivo: %v", err)
 		}
 
 		// Convertir contenido del archivo a string
-		content := strings.TrimRight(string(fileBlock.BContent[:]), "\x00")
+		content := strings.TrimRight(string(fileBlock.BContent), "\x00")
 		if content == "" {
 			return "(archivo vacío)"
 		}
@@ -2737,7 +2545,7 @@ func readBlockContent(file *os.File, superblock structs.SuperBloque, blockIndex
 	case "pointer":
 		// Leer como bloque de apuntadores
 		var pointerBlock structs.BloqueApuntador
-		if err := binary.Read(file, binary.LittleEndian, &pointerBlock); err != nil {
+		if err := fs.ReadBlock(blockIndex, &pointerBlock); err != nil {
 			return fmt.Sprintf("Error al leer bloque de apuntadores: %v", err)
 		}
 
@@ -2755,8 +2563,8 @@ func readBlockContent(file *os.File, superblock structs.SuperBloque, blockIndex
 	}
 
 	// Leer contenido raw si no se puede determinar el tipo
-	buffer := make([]byte, superblock.S_block_s)
-	file.Read(buffer)
+	buffer := make([]byte, fs.SB.S_block_s)
+	fs.file.ReadAt(buffer, fs.blockPosition(blockIndex))
 
 	// Convertir a string, mostrando solo caracteres imprimibles
 	var content strings.Builder
@@ -2783,7 +2591,8 @@ func readBlockContent(file *os.File, superblock structs.SuperBloque, blockIndex
 	return content.String()
 }
 
-func generateBitmapInodeTxt(file *os.File, superblock structs.SuperBloque, partitionName string) string {
+func generateBitmapInodeTxt(fs *FileSystem, partitionName string) string {
+	file, superblock := fs.file, *fs.SB
 	var content strings.Builder
 
 	// Encabezado del reporte
@@ -2874,7 +2683,7 @@ func generateBitmapInodeTxt(file *os.File, superblock structs.SuperBloque, parti
 }
 
 // readInodeBitmap lee el bitmap de inodos desde el disco
-func readInodeBitmap(file *os.File, superblock structs.SuperBloque) []byte {
+func readInodeBitmap(file *diskFile, superblock structs.SuperBloque) []byte {
 	// Calcular el tamaño del bitmap en bytes
 	bitmapSizeBytes := (superblock.S_inodes_count + 7) / 8 // Redondear hacia arriba
 
@@ -2915,7 +2724,8 @@ func convertBitmapToBits(bitmapData []byte, totalInodes int) []int {
 	return bits
 }
 
-func generateBitmapBlockTxt(file *os.File, superblock structs.SuperBloque, partitionName string) string {
+func generateBitmapBlockTxt(fs *FileSystem, partitionName string) string {
+	file, superblock := fs.file, *fs.SB
 	var content strings.Builder
 
 	// Encabezado del reporte
@@ -2999,7 +2809,7 @@ func generateBitmapBlockTxt(file *os.File, superblock structs.SuperBloque, parti
 }
 
 // readBlockBitmap lee el bitmap de bloques desde el disco
-func readBlockBitmap(file *os.File, superblock structs.SuperBloque) []byte {
+func readBlockBitmap(file *diskFile, superblock structs.SuperBloque) []byte {
 	// Calcular el tamaño del bitmap en bytes
 	bitmapSizeBytes := (superblock.S_blocks_count + 7) / 8 // Redondear hacia arriba
 
@@ -3031,7 +2841,7 @@ type TreeNode struct {
 }
 
 // generateTreeHTML genera el reporte del árbol en HTML
-func generateTreeHTML(file *os.File, superblock structs.SuperBloque, partitionName string) string {
+func generateTreeHTML(fs *FileSystem, partitionName string) string {
 	var html strings.Builder
 
 	html.WriteString(`<!DOCTYPE html>
@@ -3372,7 +3182,7 @@ func generateTreeHTML(file *os.File, superblock structs.SuperBloque, partitionNa
         </div>`)
 
 	// Construir el árbol del sistema de archivos
-	tree := buildFileSystemTree(file, superblock)
+	tree := buildFileSystemTree(fs)
 
 	if tree != nil {
 		html.WriteString(`
@@ -3549,7 +3359,8 @@ func layoutTreeImproved(node *TreeNode, x, y int) {
 }
 
 // buildFileSystemTree construye el árbol del sistema de archivos
-func buildFileSystemTree(file *os.File, superblock structs.SuperBloque) *TreeNode {
+func buildFileSystemTree(fs *FileSystem) *TreeNode {
+	file, superblock := fs.file, *fs.SB
 	// Leer todos los inodos
 	inodes := readInodesFromPartition(file, superblock)
 	usedInodes := filterUsedInodes(inodes)
@@ -3597,19 +3408,32 @@ func buildFileSystemTree(file *os.File, superblock structs.SuperBloque) *TreeNod
 	rootNode.Content = formatInodeContent(*rootInode, rootIndex)
 
 	// Construir recursivamente el árbol
-	buildInodeTree(file, superblock, rootNode, usedInodes, 1)
+	buildInodeTree(fs, rootNode, usedInodes, 1)
 
 	return rootNode
 }
 
 // buildInodeTree construye recursivamente el árbol desde un inodo
-func buildInodeTree(file *os.File, superblock structs.SuperBloque, parentNode *TreeNode, allInodes []structs.Inodos, level int) {
+func buildInodeTree(fs *FileSystem, parentNode *TreeNode, allInodes []structs.Inodos, level int) {
 	if parentNode.InodeData == nil || level > 5 { // Límite de profundidad
 		return
 	}
 
 	inode := *parentNode.InodeData
 
+	// Determinar el tipo de los bloques de datos del inodo
+	var realType int64
+	if inode.I_type >= 48 && inode.I_type <= 57 {
+		realType = int64(inode.I_type - 48)
+	} else {
+		realType = int64(inode.I_type)
+	}
+
+	dataBlockType := "file_block"
+	if realType == 0 || (realType != 2 && inode.I_s == 96) {
+		dataBlockType = "folder_block"
+	}
+
 	// Procesar bloques del inodo
 	for i := 0; i < 15; i++ {
 		blockIndex := inode.I_block[i]
@@ -3617,85 +3441,116 @@ func buildInodeTree(file *os.File, superblock structs.SuperBloque, parentNode *T
 			continue
 		}
 
-		// Determinar tipo de bloque
-		var blockType string
-		var realType int64
-		if inode.I_type >= 48 && inode.I_type <= 57 {
-			realType = int64(inode.I_type - 48)
-		} else {
-			realType = int64(inode.I_type)
+		var blockNode *TreeNode
+		if i < directBlocksCount { // Bloques directos
+			blockNode = buildDataBlockTreeNode(fs, blockIndex, dataBlockType, allInodes, level)
+		} else { // Bloques de apuntadores (simple, doble y triple)
+			indirectLevel := i - directBlocksCount + 1
+			blockNode = buildPointerBlockTreeNode(fs, blockIndex, indirectLevel, dataBlockType, allInodes, level)
 		}
 
-		if i < 12 { // Bloques directos
-			if realType == 0 || inode.I_s == 96 {
-				blockType = "folder_block"
-			} else {
-				blockType = "file_block"
-			}
-		} else { // Bloques de apuntadores
-			blockType = "pointer_block"
+		parentNode.Children = append(parentNode.Children, blockNode)
+	}
+}
+
+// buildPointerBlockTreeNode construye el nodo de un bloque de apuntadores y sus hijos
+func buildPointerBlockTreeNode(fs *FileSystem, blockIndex int64, indirectLevel int, dataBlockType string, allInodes []structs.Inodos, level int) *TreeNode {
+	blockNode := &TreeNode{
+		Type:     "pointer_block",
+		Index:    blockIndex,
+		Name:     fmt.Sprintf("Block_%d", blockIndex),
+		Level:    level,
+		Children: []*TreeNode{},
+	}
+	blockNode.Content = readPointerBlockForTree(fs, blockIndex)
+
+	if blockIndex >= fs.SB.S_blocks_count {
+		return blockNode
+	}
+
+	pointerBlock, err := fs.ReadPointerBlock(blockIndex)
+	if err != nil {
+		return blockNode
+	}
+
+	for _, pointer := range pointerBlock.BPointers {
+		if pointer == -1 || pointer < 0 {
+			continue
 		}
 
-		// Crear nodo para el bloque
-		blockNode := &TreeNode{
-			Type:     blockType,
-			Index:    blockIndex,
-			Name:     fmt.Sprintf("Block_%d", blockIndex),
-			Level:    level,
-			Children: []*TreeNode{},
+		if indirectLevel == 1 {
+			blockNode.Children = append(blockNode.Children, buildDataBlockTreeNode(fs, pointer, dataBlockType, allInodes, level))
+		} else {
+			blockNode.Children = append(blockNode.Children, buildPointerBlockTreeNode(fs, pointer, indirectLevel-1, dataBlockType, allInodes, level))
 		}
+	}
 
-		// Leer contenido del bloque
-		switch blockType {
-		case "folder_block":
-			blockNode.Content, blockNode.BlockData = readFolderBlockForTree(file, superblock, blockIndex)
+	return blockNode
+}
 
-			// Si es bloque de carpeta, buscar inodos referenciados
-			if folderBlock, ok := blockNode.BlockData.(structs.BloqueCarpeta); ok {
-				for _, entry := range folderBlock.BContent {
-					entryName := strings.TrimRight(string(entry.BName[:]), "\x00")
-					if entryName != "" && entryName != "." && entryName != ".." && entry.BInodo >= 0 {
-						// Buscar el inodo referenciado
-						if int(entry.BInodo) < len(allInodes) {
-							referencedInode := allInodes[entry.BInodo]
-
-							childInodeNode := &TreeNode{
-								Type:      "inode",
-								Index:     int64(entry.BInodo),
-								Name:      entryName,
-								InodeData: &referencedInode,
-								Level:     level + 1,
-								Children:  []*TreeNode{},
+// buildDataBlockTreeNode construye el nodo de un bloque de datos (carpeta o archivo)
+func buildDataBlockTreeNode(fs *FileSystem, blockIndex int64, blockType string, allInodes []structs.Inodos, level int) *TreeNode {
+	// Crear nodo para el bloque
+	blockNode := &TreeNode{
+		Type:     blockType,
+		Index:    blockIndex,
+		Name:     fmt.Sprintf("Block_%d", blockIndex),
+		Level:    level,
+		Children: []*TreeNode{},
+	}
+
+	// Leer contenido del bloque
+	switch blockType {
+	case "folder_block":
+		blockNode.Content, blockNode.BlockData = readFolderBlockForTree(fs, blockIndex)
+
+		// Si es bloque de carpeta, buscar inodos referenciados
+		if folderBlock, ok := blockNode.BlockData.(structs.BloqueCarpeta); ok {
+			for _, entry := range folderBlock.BContent {
+				entryName := fs.EntryName(&entry)
+				if entryName != "" && entryName != "." && entryName != ".." && entry.BInodo >= 0 {
+					// Buscar el inodo referenciado
+					if int(entry.BInodo) < len(allInodes) {
+						referencedInode := allInodes[entry.BInodo]
+
+						// Los enlaces simbólicos muestran su destino junto al nombre
+						if referencedInode.I_type == '2' {
+							if target, err := fs.ReadLink(&referencedInode); err == nil {
+								entryName = fmt.Sprintf("%s → %s", entryName, target)
 							}
+						}
 
-							childInodeNode.Content = formatInodeContent(referencedInode, int(entry.BInodo))
+						childInodeNode := &TreeNode{
+							Type:      "inode",
+							Index:     int64(entry.BInodo),
+							Name:      entryName,
+							InodeData: &referencedInode,
+							Level:     level + 1,
+							Children:  []*TreeNode{},
+						}
 
-							// Recursión para construir subárbol
-							buildInodeTree(file, superblock, childInodeNode, allInodes, level+2)
+						childInodeNode.Content = formatInodeContent(referencedInode, int(entry.BInodo))
 
-							blockNode.Children = append(blockNode.Children, childInodeNode)
-						}
+						// Recursión para construir subárbol
+						buildInodeTree(fs, childInodeNode, allInodes, level+2)
+
+						blockNode.Children = append(blockNode.Children, childInodeNode)
 					}
 				}
 			}
-
-		case "file_block":
-			blockNode.Content = readFileBlockForTree(file, superblock, blockIndex)
-		case "pointer_block":
-			blockNode.Content = readPointerBlockForTree(file, superblock, blockIndex)
 		}
 
-		parentNode.Children = append(parentNode.Children, blockNode)
+	case "file_block":
+		blockNode.Content = readFileBlockForTree(fs, blockIndex)
 	}
+
+	return blockNode
 }
 
 // Funciones para leer contenido de bloques específicos para el árbol
-func readFolderBlockForTree(file *os.File, superblock structs.SuperBloque, blockIndex int64) (string, interface{}) {
-	blockPosition := superblock.S_block_start + (blockIndex * superblock.S_block_s)
-	file.Seek(blockPosition, 0)
-
+func readFolderBlockForTree(fs *FileSystem, blockIndex int64) (string, interface{}) {
 	var folderBlock structs.BloqueCarpeta
-	if err := binary.Read(file, binary.LittleEndian, &folderBlock); err != nil {
+	if err := fs.ReadBlock(blockIndex, &folderBlock); err != nil {
 		return fmt.Sprintf("Error: %v", err), nil
 	}
 
@@ -3704,7 +3559,7 @@ func readFolderBlockForTree(file *os.File, superblock structs.SuperBloque, block
 	content.WriteString("-------|--------\n")
 
 	for _, entry := range folderBlock.BContent {
-		name := strings.TrimRight(string(entry.BName[:]), "\x00")
+		name := fs.EntryName(&entry)
 		if name != "" || entry.BInodo != -1 {
 			content.WriteString(fmt.Sprintf("%-6s | %d\n", name, entry.BInodo))
 		}
@@ -3713,16 +3568,13 @@ func readFolderBlockForTree(file *os.File, superblock structs.SuperBloque, block
 	return content.String(), folderBlock
 }
 
-func readFileBlockForTree(file *os.File, superblock structs.SuperBloque, blockIndex int64) string {
-	blockPosition := superblock.S_block_start + (blockIndex * superblock.S_block_s)
-	file.Seek(blockPosition, 0)
-
+func readFileBlockForTree(fs *FileSystem, blockIndex int64) string {
 	var fileBlock structs.BloqueArchivo
-	if err := binary.Read(file, binary.LittleEndian, &fileBlock); err != nil {
+	if err := fs.ReadBlock(blockIndex, &fileBlock); err != nil {
 		return fmt.Sprintf("Error: %v", err)
 	}
 
-	content := strings.TrimRight(string(fileBlock.BContent[:]), "\x00")
+	content := strings.TrimRight(string(fileBlock.BContent), "\x00")
 	if content == "" {
 		return "(vacío)"
 	}
@@ -3735,12 +3587,9 @@ func readFileBlockForTree(file *os.File, superblock structs.SuperBloque, blockIn
 	return content
 }
 
-func readPointerBlockForTree(file *os.File, superblock structs.SuperBloque, blockIndex int64) string {
-	blockPosition := superblock.S_block_start + (blockIndex * superblock.S_block_s)
-	file.Seek(blockPosition, 0)
-
+func readPointerBlockForTree(fs *FileSystem, blockIndex int64) string {
 	var pointerBlock structs.BloqueApuntador
-	if err := binary.Read(file, binary.LittleEndian, &pointerBlock); err != nil {
+	if err := fs.ReadBlock(blockIndex, &pointerBlock); err != nil {
 		return fmt.Sprintf("Error: %v", err)
 	}
 
@@ -3776,11 +3625,14 @@ func formatInodeContent(inode structs.Inodos, inodeIndex int) string {
 		realType = int64(inode.I_type)
 	}
 
-	if realType == 0 || inode.I_s == 96 {
+	if realType == 2 {
+		content.WriteString("Tipo: Link")
+	} else if realType == 0 || inode.I_s == 96 {
 		content.WriteString("Tipo: Dir")
 	} else {
 		content.WriteString("Tipo: File")
 	}
+	content.WriteString(fmt.Sprintf("\nEnlaces: %d", linkCount(&inode)))
 
 	return content.String()
 }
@@ -4251,7 +4103,7 @@ func formatSuperBlockTimestamp(timestamp int64) string {
 }
 
 // generateFileTxt genera el contenido del reporte de archivo en formato texto
-func generateFileTxt(file *os.File, superblock structs.SuperBloque, filePath, partitionName string) string {
+func generateFileTxt(fs *FileSystem, filePath, partitionName string) string {
 	var content strings.Builder
 
 	// Encabezado del reporte
@@ -4265,7 +4117,7 @@ func generateFileTxt(file *os.File, superblock structs.SuperBloque, filePath, pa
 	content.WriteString("==================================================\n\n")
 
 	// Buscar el archivo en el sistema de archivos
-	fileContent, fileInfo, err := findAndReadFile(file, superblock, filePath)
+	fileContent, fileInfo, err := findAndReadFile(fs, filePath)
 
 	if err != nil {
 		content.WriteString(fmt.Sprintf("❌ ERROR: %v\n\n", err))
@@ -4275,7 +4127,7 @@ func generateFileTxt(file *os.File, superblock structs.SuperBloque, filePath, pa
 		content.WriteString("Intentando listar contenido del directorio raíz...\n\n")
 
 		// Mostrar contenido del directorio raíz para debug
-		debugContent := listRootDirectory(file, superblock)
+		debugContent := listRootDirectory(fs)
 		content.WriteString(debugContent)
 
 		content.WriteString("\n==================================================\n")
@@ -4294,7 +4146,7 @@ func generateFileTxt(file *os.File, superblock structs.SuperBloque, filePath, pa
 	content.WriteString(fmt.Sprintf("UID: %d\n", fileInfo.UID))
 	content.WriteString(fmt.Sprintf("Permisos: %s\n", fileInfo.Permissions))
 	content.WriteString(fmt.Sprintf("Fecha acceso: %s\n", fileInfo.AccessTime))
-	content.WriteString(fmt.Sprintf("Fecha creación: %s\n", fileInfo.CreationTime))
+	content.WriteString(fmt.Sprintf("Fecha cambio (ctime): %s\n", fileInfo.ChangeTime))
 	content.WriteString(fmt.Sprintf("Fecha modificación: %s\n", fileInfo.ModificationTime))
 	content.WriteString("==================================================\n\n")
 
@@ -4306,8 +4158,8 @@ func generateFileTxt(file *os.File, superblock structs.SuperBloque, filePath, pa
 		content.WriteString("(El archivo está vacío)\n")
 	} else {
 		// Verificar si el contenido es texto o binario
-		if isTextContent(fileContent) {
-			content.WriteString(fileContent)
+		if IsTextContent(fileContent) {
+			content.Write(fileContent)
 		} else {
 			content.WriteString("⚠️  El archivo contiene datos binarios.\n")
 			content.WriteString("Mostrando representación hexadecimal:\n\n")
@@ -4337,18 +4189,19 @@ type FileInfo struct {
 	UID              int64
 	Permissions      string
 	AccessTime       string
-	CreationTime     string
+	ChangeTime       string
 	ModificationTime string
 }
 
 // findAndReadFile busca y lee un archivo específico en el sistema de archivos
-func findAndReadFile(file *os.File, superblock structs.SuperBloque, targetPath string) (string, *FileInfo, error) {
+func findAndReadFile(fs *FileSystem, targetPath string) ([]byte, *FileInfo, error) {
+	file, superblock := fs.file, *fs.SB
 	// Normalizar la ruta (remover / inicial si existe)
 	targetPath = strings.TrimPrefix(targetPath, "/")
 
 	// Si la ruta está vacía, es un error
 	if targetPath == "" {
-		return "", nil, fmt.Errorf("ruta de archivo vacía")
+		return nil, nil, fmt.Errorf("ruta de archivo vacía")
 	}
 
 	// Dividir la ruta en componentes
@@ -4360,7 +4213,7 @@ func findAndReadFile(file *os.File, superblock structs.SuperBloque, targetPath s
 	// Leer todos los inodos
 	inodes := readInodesFromPartition(file, superblock)
 	if len(inodes) == 0 {
-		return "", nil, fmt.Errorf("no se encontraron inodos en la partición")
+		return nil, nil, fmt.Errorf("no se encontraron inodos en la partición")
 	}
 
 	// Navegar por cada componente de la ruta
@@ -4371,7 +4224,7 @@ func findAndReadFile(file *os.File, superblock structs.SuperBloque, targetPath s
 
 		// Verificar que el inodo actual sea válido
 		if currentInodeIndex >= int64(len(inodes)) {
-			return "", nil, fmt.Errorf("inodo índice %d fuera de rango", currentInodeIndex)
+			return nil, nil, fmt.Errorf("inodo índice %d fuera de rango", currentInodeIndex)
 		}
 
 		currentInode := inodes[currentInodeIndex]
@@ -4387,18 +4240,21 @@ func findAndReadFile(file *os.File, superblock structs.SuperBloque, targetPath s
 			}
 
 			if realType != 0 && currentInode.I_s != 96 {
-				return "", nil, fmt.Errorf("componente '%s' no es un directorio", component)
+				return nil, nil, fmt.Errorf("componente '%s' no es un directorio", component)
 			}
 		}
 
 		// Usar la función de file_operations.go - CAMBIO AQUÍ
-		foundInodeIndex, err := findInodeInDirectory(file, &superblock, currentInodeIndex, component)
+		foundInodeIndex, err := fs.LookupIn(currentInodeIndex, component)
 		if err != nil {
-			return "", nil, fmt.Errorf("error buscando '%s': %v", component, err)
+			return nil, nil, fmt.Errorf("error buscando '%s': %v", component, err)
+		}
+		if foundInodeIndex, err = fs.followLink(currentInodeIndex, foundInodeIndex, 0); err != nil {
+			return nil, nil, fmt.Errorf("error buscando '%s': %v", component, err)
 		}
 
 		if foundInodeIndex == -1 {
-			return "", nil, fmt.Errorf("archivo/directorio '%s' no encontrado en la ruta '%s'", component, targetPath)
+			return nil, nil, fmt.Errorf("archivo/directorio '%s' no encontrado en la ruta '%s'", component, targetPath)
 		}
 
 		// Para el último componente, verificar que sea un archivo
@@ -4414,13 +4270,13 @@ func findAndReadFile(file *os.File, superblock structs.SuperBloque, targetPath s
 
 			isDirectory := (realType == 0 || foundInode.I_s == 96)
 			if isDirectory {
-				return "", nil, fmt.Errorf("'%s' es un directorio, no un archivo", targetPath)
+				return nil, nil, fmt.Errorf("'%s' es un directorio, no un archivo", targetPath)
 			}
 
 			// Leer el contenido del archivo usando la función de file_operations.go
-			fileContent, err := readFileContentMultiBlock(file, &superblock, &foundInode)
+			fileContent, err := fs.ReadFile(&foundInode)
 			if err != nil {
-				return "", nil, fmt.Errorf("error leyendo contenido del archivo: %v", err)
+				return nil, nil, fmt.Errorf("error leyendo contenido del archivo: %v", err)
 			}
 
 			// Crear información del archivo
@@ -4432,7 +4288,7 @@ func findAndReadFile(file *os.File, superblock structs.SuperBloque, targetPath s
 				UID:              foundInode.I_uid,
 				Permissions:      formatPermissions(foundInode.I_perm),
 				AccessTime:       formatTimestamp(foundInode.I_atime),
-				CreationTime:     formatTimestamp(foundInode.I_ctime),
+				ChangeTime:       formatTimestamp(foundInode.I_ctime),
 				ModificationTime: formatTimestamp(foundInode.I_mtime),
 			}
 
@@ -4443,33 +4299,13 @@ func findAndReadFile(file *os.File, superblock structs.SuperBloque, targetPath s
 		currentInodeIndex = foundInodeIndex
 	}
 
-	return "", nil, fmt.Errorf("ruta de archivo inválida")
-}
-
-// isTextContent verifica si el contenido es texto legible
-func isTextContent(content string) bool {
-	if len(content) == 0 {
-		return true
-	}
-
-	// Contar caracteres no imprimibles
-	nonPrintable := 0
-	for _, char := range content {
-		if char < 32 && char != 9 && char != 10 && char != 13 { // Excluir tab, LF, CR
-			nonPrintable++
-		}
-	}
-
-	// Si más del 20% son caracteres no imprimibles, considerarlo binario
-	threshold := len(content) / 5
-	return nonPrintable <= threshold
+	return nil, nil, fmt.Errorf("ruta de archivo inválida")
 }
 
 // formatAsHex formatea contenido binario como hexadecimal
-func formatAsHex(content string) string {
+func formatAsHex(bytes []byte) string {
 	var hex strings.Builder
 
-	bytes := []byte(content)
 	for i := 0; i < len(bytes); i += 16 {
 		// Dirección
 		hex.WriteString(fmt.Sprintf("%08X: ", i))
@@ -4509,7 +4345,8 @@ func formatAsHex(content string) string {
 }
 
 // listRootDirectory lista el contenido del directorio raíz para debug
-func listRootDirectory(file *os.File, superblock structs.SuperBloque) string {
+func listRootDirectory(fs *FileSystem) string {
+	file, superblock := fs.file, *fs.SB
 	var content strings.Builder
 
 	inodes := readInodesFromPartition(file, superblock)
@@ -4536,21 +4373,23 @@ func listRootDirectory(file *os.File, superblock structs.SuperBloque) string {
 			content.WriteString(fmt.Sprintf("\nDirectorio en inodo %d:\n", i))
 
 			// Listar contenido del directorio
-			for _, blockIndex := range inode.I_block {
-				if blockIndex == -1 || blockIndex < 0 {
+			dirBlocks, err := fs.DataBlocks(&inode)
+			if err != nil {
+				continue
+			}
+
+			for _, blockIndex := range dirBlocks {
+				if blockIndex < 0 {
 					continue
 				}
 
-				blockPosition := superblock.S_block_start + (blockIndex * superblock.S_block_s)
-				file.Seek(blockPosition, 0)
-
 				var folderBlock structs.BloqueCarpeta
-				if err := binary.Read(file, binary.LittleEndian, &folderBlock); err != nil {
+				if err := fs.ReadBlock(blockIndex, &folderBlock); err != nil {
 					continue
 				}
 
 				for _, entry := range folderBlock.BContent {
-					entryName := strings.TrimRight(string(entry.BName[:]), "\x00")
+					entryName := fs.EntryName(&entry)
 					if entryName != "" {
 						content.WriteString(fmt.Sprintf("  - %s (inodo %d)\n", entryName, entry.BInodo))
 					}
@@ -4568,17 +4407,20 @@ type LsEntry struct {
 	Owner            string
 	Group            string
 	Size             int64
-	CreationDate     string
-	CreationTime     string
+	AccessDate       string
+	AccessTime       string
+	ChangeDate       string
+	ChangeTime       string
 	ModificationDate string
 	ModificationTime string
 	Type             string
 	Name             string
 	InodeIndex       int64
+	Links            int64
 }
 
 // generateLsHTML genera el reporte de listado en HTML
-func generateLsHTML(file *os.File, superblock structs.SuperBloque, dirPath, partitionName string) string {
+func generateLsHTML(fs *FileSystem, dirPath, partitionName string) string {
 	var html strings.Builder
 
 	html.WriteString(`<!DOCTYPE html>
@@ -4759,6 +4601,10 @@ func generateLsHTML(file *os.File, superblock structs.SuperBloque, dirPath, part
             background: linear-gradient(135deg, #3498db, #2980b9);
         }
         
+        .type-enlace {
+            background: linear-gradient(135deg, #16a085, #138d75);
+        }
+        
         .name-cell {
             font-weight: 600;
             color: #2c3e50;
@@ -4774,6 +4620,11 @@ func generateLsHTML(file *os.File, superblock structs.SuperBloque, dirPath, part
             color: #3498db;
         }
         
+        .name-link {
+            color: #16a085;
+            font-style: italic;
+        }
+        
         .empty-state {
             text-align: center;
             padding: 60px 20px;
@@ -4876,7 +4727,7 @@ func generateLsHTML(file *os.File, superblock structs.SuperBloque, dirPath, part
         </div>`, dirPath))
 
 	// Obtener entradas del directorio
-	entries, err := listDirectoryContents(file, superblock, dirPath)
+	entries, err := listDirectoryContents(fs, dirPath)
 
 	if err != nil {
 		html.WriteString(fmt.Sprintf(`
@@ -4899,11 +4750,11 @@ func generateLsHTML(file *os.File, superblock structs.SuperBloque, dirPath, part
 		totalSize := int64(0)
 
 		for _, entry := range entries {
-			if entry.Type == "Archivo" {
+			if entry.Type == "Carpeta" {
+				dirCount++
+			} else {
 				fileCount++
 				totalSize += entry.Size
-			} else {
-				dirCount++
 			}
 		}
 
@@ -4934,11 +4785,14 @@ func generateLsHTML(file *os.File, superblock structs.SuperBloque, dirPath, part
                 <thead>
                     <tr>
                         <th>Permisos</th>
+                        <th>Enlaces</th>
                         <th>Owner</th>
                         <th>Grupo</th>
                         <th>Size (en bytes)</th>
-                        <th>Fecha</th>
+                        <th>Modificación</th>
                         <th>Hora</th>
+                        <th>Acceso</th>
+                        <th>Cambio</th>
                         <th>Tipo</th>
                         <th>Name</th>
                     </tr>
@@ -4949,24 +4803,32 @@ func generateLsHTML(file *os.File, superblock structs.SuperBloque, dirPath, part
 		for _, entry := range entries {
 			typeClass := "type-archivo"
 			nameClass := "name-file"
-			if entry.Type == "Carpeta" {
+			switch entry.Type {
+			case "Carpeta":
 				typeClass = "type-carpeta"
 				nameClass = "name-folder"
+			case "Enlace":
+				typeClass = "type-enlace"
+				nameClass = "name-link"
 			}
 
 			html.WriteString(fmt.Sprintf(`
                     <tr>
                         <td><span class="permissions-cell">%s</span></td>
+                        <td class="size-cell">%d</td>
                         <td class="owner-cell">%s</td>
                         <td class="group-cell">%s</td>
                         <td class="size-cell">%d</td>
                         <td class="date-cell">%s</td>
                         <td class="time-cell">%s</td>
+                        <td class="date-cell">%s %s</td>
+                        <td class="date-cell">%s %s</td>
                         <td><span class="type-cell %s">%s</span></td>
                         <td class="name-cell %s">%s</td>
                     </tr>`,
-				entry.Permissions, entry.Owner, entry.Group, entry.Size,
+				entry.Permissions, entry.Links, entry.Owner, entry.Group, entry.Size,
 				entry.ModificationDate, entry.ModificationTime,
+				entry.AccessDate, entry.AccessTime, entry.ChangeDate, entry.ChangeTime,
 				typeClass, entry.Type, nameClass, entry.Name))
 		}
 
@@ -5013,7 +4875,8 @@ func generateLsHTML(file *os.File, superblock structs.SuperBloque, dirPath, part
 }
 
 // listDirectoryContents lista el contenido de un directorio específico
-func listDirectoryContents(file *os.File, superblock structs.SuperBloque, dirPath string) ([]LsEntry, error) {
+func listDirectoryContents(fs *FileSystem, dirPath string) ([]LsEntry, error) {
+	file, superblock := fs.file, *fs.SB
 	var entries []LsEntry
 
 	// Normalizar la ruta
@@ -5024,7 +4887,7 @@ func listDirectoryContents(file *os.File, superblock structs.SuperBloque, dirPat
 	}
 
 	// Buscar el directorio objetivo
-	targetInodeIndex, err := findDirectoryInode(file, superblock, dirPath)
+	targetInodeIndex, err := findDirectoryInode(fs, dirPath)
 	if err != nil {
 		return entries, fmt.Errorf("directorio no encontrado: %v", err)
 	}
@@ -5049,24 +4912,26 @@ func listDirectoryContents(file *os.File, superblock structs.SuperBloque, dirPat
 		return entries, fmt.Errorf("la ruta especificada no es un directorio")
 	}
 
-	// Leer el contenido del directorio
-	for _, blockIndex := range dirInode.I_block {
-		if blockIndex == -1 || blockIndex < 0 {
+	// Leer el contenido del directorio (bloques directos e indirectos)
+	dirBlocks, err := fs.DataBlocks(&dirInode)
+	if err != nil {
+		return entries, fmt.Errorf("error al leer bloques del directorio: %v", err)
+	}
+
+	for _, blockIndex := range dirBlocks {
+		if blockIndex < 0 {
 			continue
 		}
 
 		// Leer bloque de directorio
-		blockPosition := superblock.S_block_start + (blockIndex * superblock.S_block_s)
-		file.Seek(blockPosition, 0)
-
 		var folderBlock structs.BloqueCarpeta
-		if err := binary.Read(file, binary.LittleEndian, &folderBlock); err != nil {
+		if err := fs.ReadBlock(blockIndex, &folderBlock); err != nil {
 			continue
 		}
 
 		// Procesar cada entrada del directorio
 		for _, entry := range folderBlock.BContent {
-			entryName := strings.TrimRight(string(entry.BName[:]), "\x00")
+			entryName := fs.EntryName(&entry)
 
 			// Saltar entradas vacías y referencias a directorio actual/padre
 			if entryName == "" || entryName == "." || entryName == ".." {
@@ -5076,8 +4941,13 @@ func listDirectoryContents(file *os.File, superblock structs.SuperBloque, dirPat
 			if entry.BInodo >= 0 && int(entry.BInodo) < len(inodes) {
 				entryInode := inodes[entry.BInodo]
 
-				// Crear entrada LS
+				// Crear entrada LS (los enlaces simbólicos muestran su destino)
 				lsEntry := createLsEntry(entryInode, entryName, int64(entry.BInodo))
+				if entryInode.I_type == '2' {
+					if target, err := fs.ReadLink(&entryInode); err == nil {
+						lsEntry.Name = fmt.Sprintf("%s -> %s", entryName, target)
+					}
+				}
 				entries = append(entries, lsEntry)
 			}
 		}
@@ -5087,7 +4957,8 @@ func listDirectoryContents(file *os.File, superblock structs.SuperBloque, dirPat
 }
 
 // findDirectoryInode encuentra el inodo de un directorio específico
-func findDirectoryInode(file *os.File, superblock structs.SuperBloque, dirPath string) (int64, error) {
+func findDirectoryInode(fs *FileSystem, dirPath string) (int64, error) {
+	file, superblock := fs.file, *fs.SB
 	if dirPath == "/" {
 		// Para el directorio raíz, buscar el primer directorio válido
 		inodes := readInodesFromPartition(file, superblock)
@@ -5115,7 +4986,7 @@ func findDirectoryInode(file *os.File, superblock structs.SuperBloque, dirPath s
 	currentInodeIndex := int64(0)
 
 	// Buscar directorio raíz primero
-	rootIndex, err := findDirectoryInode(file, superblock, "/")
+	rootIndex, err := findDirectoryInode(fs, "/")
 	if err != nil {
 		return -1, err
 	}
@@ -5127,10 +4998,13 @@ func findDirectoryInode(file *os.File, superblock structs.SuperBloque, dirPath s
 			continue
 		}
 
-		foundIndex, err := findInodeInDirectory(file, &superblock, currentInodeIndex, component)
+		foundIndex, err := fs.LookupIn(currentInodeIndex, component)
 		if err != nil {
 			return -1, fmt.Errorf("no se encontró directorio '%s': %v", component, err)
 		}
+		if foundIndex, err = fs.followLink(currentInodeIndex, foundIndex, 0); err != nil {
+			return -1, fmt.Errorf("no se encontró directorio '%s': %v", component, err)
+		}
 
 		if foundIndex == -1 {
 			return -1, fmt.Errorf("directorio '%s' no encontrado", component)
@@ -5153,13 +5027,18 @@ func createLsEntry(inode structs.Inodos, name string, inodeIndex int64) LsEntry
 	}
 
 	entryType := "Archivo"
-	if realType == 0 || inode.I_s == 96 {
+	permissions := formatLsPermissions(inode.I_perm)
+	if realType == 2 {
+		entryType = "Enlace"
+		permissions = "l" + permissions[1:]
+	} else if realType == 0 || inode.I_s == 96 {
 		entryType = "Carpeta"
 	}
 
 	// Formatear fechas
 	modTime := time.Unix(inode.I_mtime, 0)
-	creTime := time.Unix(inode.I_ctime, 0)
+	accTime := time.Unix(inode.I_atime, 0)
+	chgTime := time.Unix(inode.I_ctime, 0)
 
 	// Generar nombres de usuario y grupo (simulados)
 	ownerName := fmt.Sprintf("User%d", inode.I_uid)
@@ -5169,17 +5048,20 @@ func createLsEntry(inode structs.Inodos, name string, inodeIndex int64) LsEntry
 	}
 
 	return LsEntry{
-		Permissions:      formatLsPermissions(inode.I_perm),
+		Permissions:      permissions,
 		Owner:            ownerName,
 		Group:            groupName,
 		Size:             inode.I_s,
-		CreationDate:     creTime.Format("02/01/2006"),
-		CreationTime:     creTime.Format("15:04"),
+		AccessDate:       accTime.Format("02/01/2006"),
+		AccessTime:       accTime.Format("15:04"),
+		ChangeDate:       chgTime.Format("02/01/2006"),
+		ChangeTime:       chgTime.Format("15:04"),
 		ModificationDate: modTime.Format("02/01/2006"),
 		ModificationTime: modTime.Format("15:04"),
 		Type:             entryType,
 		Name:             name,
 		InodeIndex:       inodeIndex,
+		Links:            linkCount(&inode),
 	}
 }
 
@@ -5259,3 +5141,93 @@ func formatLsPermissions(perm [3]byte) string {
 
 	return permissions
 }
+
+// generateQuotaTxt - Uso de bloques e inodos de cada usuario y grupo frente a
+// su cuota de /quotas.txt
+func generateQuotaTxt(fs *FileSystem, partitionName string) (string, error) {
+	usersContent, err := fs.ReadUsers()
+	if err != nil {
+		return "", err
+	}
+	limits, err := fs.ReadQuotas()
+	if err != nil {
+		return "", err
+	}
+	users, groups, err := fs.QuotaUsage()
+	if err != nil {
+		return "", err
+	}
+
+	var content strings.Builder
+
+	// Encabezado del reporte
+	content.WriteString("==================================================\n")
+	content.WriteString("              REPORTE DE CUOTAS\n")
+	content.WriteString("              ExtreamFS \n")
+	content.WriteString("==================================================\n")
+	content.WriteString(fmt.Sprintf("Partición: %s\n", partitionName))
+	content.WriteString(fmt.Sprintf("Fecha: %s\n", time.Now().Format("2006-01-02 15:04:05")))
+	content.WriteString(fmt.Sprintf("Bloques usados: %d de %d\n", fs.SB.S_blocks_count-fs.SB.S_free_blocks_count, fs.SB.S_blocks_count))
+	content.WriteString(fmt.Sprintf("Inodos usados: %d de %d\n", fs.SB.S_inodes_count-fs.SB.S_free_inodes_count, fs.SB.S_inodes_count))
+	content.WriteString(fmt.Sprintf("Cuotas definidas en /%s: %d\n", quotaFileName, len(limits)))
+	content.WriteString("==================================================\n")
+
+	// usageCell - "uso / límite (porcentaje)" de una columna
+	usageCell := func(used, limit int64) string {
+		if limit <= 0 {
+			return fmt.Sprintf("%d / -", used)
+		}
+		return fmt.Sprintf("%d / %d (%d%%)", used, limit, used*100/limit)
+	}
+
+	accounts := parseAccounts(usersContent)
+	for _, kind := range []byte{'U', 'G'} {
+		usage := users
+		title := "USUARIOS"
+		if kind == 'G' {
+			usage, title = groups, "GRUPOS"
+		}
+
+		content.WriteString(fmt.Sprintf("\n%s\n", title))
+		content.WriteString(fmt.Sprintf("%-12s %-12s %-22s %-22s %s\n", "Nombre", "Grupo", "Bloques (uso/límite)", "Inodos (uso/límite)", "Estado"))
+		content.WriteString(strings.Repeat("-", 80) + "\n")
+
+		for _, account := range accounts {
+			if account.Kind != kind {
+				continue
+			}
+
+			var used quotaUsage
+			if u := usage[account.ID]; u != nil {
+				used = *u
+			}
+			var limit quotaLimit
+			if l := findQuota(limits, kind, account.Name); l != nil {
+				limit = *l
+			}
+
+			status := "Sin cuota"
+			if limit.Blocks > 0 || limit.Inodes > 0 {
+				status = "OK"
+				if (limit.Blocks > 0 && used.Blocks > limit.Blocks) || (limit.Inodes > 0 && used.Inodes > limit.Inodes) {
+					status = "EXCEDIDA"
+				} else if (limit.Blocks > 0 && used.Blocks == limit.Blocks) || (limit.Inodes > 0 && used.Inodes == limit.Inodes) {
+					status = "LLENA"
+				}
+			}
+
+			group := account.Group
+			if kind == 'G' {
+				group = "-"
+			}
+			content.WriteString(fmt.Sprintf("%-12s %-12s %-22s %-22s %s\n", account.Name, group,
+				usageCell(used.Blocks, limit.Blocks), usageCell(used.Inodes, limit.Inodes), status))
+		}
+	}
+
+	content.WriteString("\n==================================================\n")
+	content.WriteString("Los límites se asignan con quota -usr/-grp; root no tiene cuota.\n")
+	content.WriteString("Los bloques incluyen los de apuntadores; los de nombres largos no cuentan.\n")
+
+	return content.String(), nil
+}
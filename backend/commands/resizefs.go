@@ -0,0 +1,469 @@
+package commands
+
+import (
+	"backend/structs"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// REDIMENSIONAR EL SISTEMA DE ARCHIVOS
+// fdisk -add solo mueve el límite de la partición. resizefs (y fdisk -add en
+// una partición formateada) ajusta el sistema de archivos al nuevo tamaño: el
+// superbloque y la zona del journal de EXT3 se quedan donde están, y a partir
+// del bitmap de inodos se vuelven a distribuir bitmaps, inodos y bloques con la
+// misma proporción de bloques por inodo que eligió mkfs. Al reducir, los inodos y
+// bloques en uso que quedarían fuera se mueven antes a posiciones libres dentro
+// del nuevo tamaño; si no caben, no se modifica nada.
+
+// resizeSummary - Resultado de redimensionar un sistema de archivos
+type resizeSummary struct {
+	FileSystem  int64
+	OldInodes   int64
+	NewInodes   int64
+	OldBlocks   int64
+	NewBlocks   int64
+	MovedInodes int
+	MovedBlocks int
+}
+
+// ExecuteResizefs - Ajustar el sistema de archivos de una partición montada al
+// tamaño que tiene ahora en la tabla del disco
+func ExecuteResizefs(id string) (*CommandResult, error) {
+	res := NewCommandResult("resizefs")
+
+	if id == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio para resizefs")
+	}
+
+	mounted := GetMountedPartition(id)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró ninguna partición montada con ID '%s'", id)
+	}
+
+	// Se reescriben las estructuras de la partición: disco en exclusiva
+	defer lockDisk(mounted.Path)()
+
+	disk, err := OpenDisk(mounted.Path, os.O_RDWR)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el disco")
+	}
+	defer disk.Close()
+
+	// El tamaño guardado al montar puede ser anterior a un fdisk -add
+	partition, err := disk.FindPartition(mounted.Name)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeNotFound, err, "error al buscar la partición en el disco")
+	}
+
+	fs, err := partition.FileSystem()
+	if err != nil || !validMagic(fs.SB) {
+		return res, newCommandError(ErrCodeUnsupported, "la partición '%s' no tiene un sistema de archivos EXT2/EXT3 válido", id)
+	}
+
+	summary, err := resizeFileSystem(fs, partition.Size)
+	if err != nil {
+		return res, err
+	}
+	summary.print(res)
+	updateMountedPartition(mounted.Path, mounted.Name, partition.Start, partition.Size)
+
+	res.AddPath(mounted.Path)
+	res.Set("id", id)
+	res.Set("size", partition.Size)
+	res.Set("inodes", summary.NewInodes)
+	res.Set("blocks", summary.NewBlocks)
+	res.Set("previousInodes", summary.OldInodes)
+	res.Set("previousBlocks", summary.OldBlocks)
+	res.Set("movedInodes", summary.MovedInodes)
+	res.Set("movedBlocks", summary.MovedBlocks)
+
+	return res, nil
+}
+
+// resizePartitionFileSystem - Ajustar el sistema de archivos de una partición
+// que fdisk -add está redimensionando (con el disco ya bloqueado). Si la
+// partición no está formateada no hace nada.
+func resizePartitionFileSystem(res *CommandResult, file *diskFile, path, name string, start, newSize int64) error {
+	disk, err := newDisk(file, path)
+	if err != nil {
+		return wrapCommandError(ErrCodeIO, err, "error al leer la tabla de particiones")
+	}
+
+	partition := &Partition{Disk: disk, Name: name, Type: 'P', Start: start, Size: newSize, Index: -1}
+	fs, err := partition.FileSystem()
+	if err != nil || !validMagic(fs.SB) {
+		return nil
+	}
+
+	summary, err := resizeFileSystem(fs, newSize)
+	if err != nil {
+		return err
+	}
+	summary.print(res)
+	return nil
+}
+
+// resizeFileSystem - Redistribuir las estructuras del sistema de archivos para
+// una partición de newSize bytes
+func resizeFileSystem(fs *FileSystem, newSize int64) (*resizeSummary, error) {
+	// Terminar una transacción confirmada que no se alcanzó a aplicar
+	if layout, ok := walLayoutFor(fs.Partition.Start, fs.SB); ok {
+		if _, err := replayJournal(fs.file, layout); err != nil {
+			return nil, wrapCommandError(ErrCodeIO, err, "error al revisar el journal")
+		}
+		superblock, err := fs.Partition.ReadSuperblock()
+		if err != nil {
+			return nil, wrapCommandError(ErrCodeIO, err, "error al leer el superbloque")
+		}
+		fs.SB = superblock
+	}
+
+	if !resizableHeader(fs.Partition.Start, fs.SB) {
+		return nil, newCommandError(ErrCodeUnsupported,
+			"las estructuras del sistema de archivos no corresponden al inicio de la partición (%d)", fs.Partition.Start)
+	}
+
+	return relayoutFileSystem(fs, fs.SB.S_bm_inode_start, fs.Partition.Start+newSize, fs.SB.S_file_system_type)
+}
+
+// relayoutFileSystem - Distribuir bitmaps, inodos y bloques entre tablesStart
+// y end, moviendo antes los inodos y bloques en uso que quedarían fuera. El
+// superbloque resultante se guarda con el tipo fsType (tunefs cambia el tipo
+// junto con el espacio del journal).
+func relayoutFileSystem(fs *FileSystem, tablesStart int64, end int64, fsType int64) (*resizeSummary, error) {
+	sb := fs.SB
+	summary := &resizeSummary{
+		FileSystem: fsType,
+		OldInodes:  sb.S_inodes_count,
+		OldBlocks:  sb.S_blocks_count,
+	}
+
+	// Fórmula de mkfs para el espacio que queda después del journal, con la
+	// proporción actual de bloques por inodo (3 si no se usó -inoderatio):
+	// available = n + n*inode + m + m*block, con m = n*blocks/inodes
+	available := end - tablesStart
+	n := available * sb.S_inodes_count / (sb.S_inodes_count*(1+sb.S_inode_s) + sb.S_blocks_count*(1+sb.S_block_s))
+	m := n * sb.S_blocks_count / sb.S_inodes_count
+	if n < 2 || m < 2 {
+		return nil, newCommandError(ErrCodeNoSpace,
+			"con %d bytes la partición no tiene espacio para el inodo raíz y users.txt", end-fs.Partition.Start)
+	}
+	summary.NewInodes, summary.NewBlocks = n, m
+	if n == sb.S_inodes_count && tablesStart == sb.S_bm_inode_start && fsType == sb.S_file_system_type {
+		return summary, nil
+	}
+
+	inodeBitmap := make([]byte, sb.S_inodes_count)
+	if _, err := fs.file.ReadAt(inodeBitmap, sb.S_bm_inode_start); err != nil {
+		return nil, wrapCommandError(ErrCodeIO, err, "error al leer el bitmap de inodos")
+	}
+	blockBitmap := make([]byte, sb.S_blocks_count)
+	if _, err := fs.file.ReadAt(blockBitmap, sb.S_bm_block_start); err != nil {
+		return nil, wrapCommandError(ErrCodeIO, err, "error al leer el bitmap de bloques")
+	}
+
+	usedInodes, usedBlocks := countUsed(inodeBitmap), countUsed(blockBitmap)
+	if usedInodes > n || usedBlocks > m {
+		return nil, newCommandError(ErrCodeNoSpace,
+			"con %d bytes la partición tendría %d inodos y %d bloques, pero hay %d inodos y %d bloques en uso",
+			end-fs.Partition.Start, n, m, usedInodes, usedBlocks)
+	}
+
+	// PASO 1: Mover lo que quedaría fuera (solo al reducir)
+	used := append([]byte(nil), inodeBitmap...)
+	relocator := &fsRelocator{
+		fs:     fs,
+		inodes: relocationMap(inodeBitmap, n),
+		blocks: relocationMap(blockBitmap, m),
+	}
+	if len(relocator.inodes) > 0 || len(relocator.blocks) > 0 {
+		for index, state := range used {
+			if state == 0 {
+				continue
+			}
+			if err := relocator.moveInode(int64(index)); err != nil {
+				return nil, wrapCommandError(ErrCodeIO, err, "error al reubicar el inodo %d", index)
+			}
+		}
+	}
+	summary.MovedInodes, summary.MovedBlocks = len(relocator.inodes), len(relocator.blocks)
+
+	// PASO 2: Leer las tablas que se conservan antes de escribir en las nuevas posiciones
+	keptInodes, keptBlocks := n, m
+	if n > sb.S_inodes_count {
+		keptInodes, keptBlocks = sb.S_inodes_count, sb.S_blocks_count
+	}
+
+	inodeTable := make([]byte, n*sb.S_inode_s)
+	if _, err := fs.file.ReadAt(inodeTable[:keptInodes*sb.S_inode_s], sb.S_inode_start); err != nil {
+		return nil, wrapCommandError(ErrCodeIO, err, "error al leer la tabla de inodos")
+	}
+	emptyInode, err := encodeEmptyInode(sb.S_inode_s)
+	if err != nil {
+		return nil, wrapCommandError(ErrCodeInternal, err, "error al preparar los inodos nuevos")
+	}
+	for i := keptInodes; i < n; i++ {
+		copy(inodeTable[i*sb.S_inode_s:], emptyInode)
+	}
+
+	blockArea := make([]byte, m*sb.S_block_s)
+	if _, err := fs.file.ReadAt(blockArea[:keptBlocks*sb.S_block_s], sb.S_block_start); err != nil {
+		return nil, wrapCommandError(ErrCodeIO, err, "error al leer los bloques")
+	}
+
+	newInodeBitmap := make([]byte, n)
+	copy(newInodeBitmap, inodeBitmap)
+	newBlockBitmap := make([]byte, m)
+	copy(newBlockBitmap, blockBitmap)
+
+	// PASO 3: Nueva distribución a partir del bitmap de inodos
+	resized := *sb
+	resized.S_file_system_type = fsType
+	resized.S_bm_inode_start = tablesStart
+	resized.S_inodes_count = n
+	resized.S_blocks_count = m
+	resized.S_free_inodes_count = n - usedInodes
+	resized.S_free_blocks_count = m - usedBlocks
+	resized.S_bm_block_start = resized.S_bm_inode_start + n
+	resized.S_inode_start = resized.S_bm_block_start + m
+	resized.S_block_start = resized.S_inode_start + n*sb.S_inode_s
+
+	areas := []struct {
+		data     []byte
+		position int64
+		name     string
+	}{
+		{newInodeBitmap, resized.S_bm_inode_start, "el bitmap de inodos"},
+		{newBlockBitmap, resized.S_bm_block_start, "el bitmap de bloques"},
+		{inodeTable, resized.S_inode_start, "la tabla de inodos"},
+		{blockArea, resized.S_block_start, "los bloques"},
+	}
+	for _, area := range areas {
+		if _, err := fs.file.WriteAt(area.data, area.position); err != nil {
+			return nil, wrapCommandError(ErrCodeIO, err, "error al escribir %s", area.name)
+		}
+	}
+
+	fs.SB = &resized
+	if err := fs.Flush(); err != nil {
+		return nil, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
+	}
+	return summary, nil
+}
+
+// resizableHeader - Verificar que lo anterior al bitmap de inodos sea un
+// superbloque de mkfs (con el journal, en EXT3) en el inicio de la partición.
+// Si la partición se movió sin su sistema de archivos, las posiciones no coinciden.
+func resizableHeader(partitionStart int64, superblock *structs.SuperBloque) bool {
+	superblockSize := int64(binary.Size(structs.SuperBloque{}))
+	journalingSize := 50 * int64(binary.Size(structs.Journal{}))
+
+	header := superblock.S_bm_inode_start - partitionStart
+	if superblock.S_file_system_type == 3 {
+		// El journal de escritura anticipada ocupa KB enteros (mkfs -journalsize)
+		wal := header - superblockSize - journalingSize
+		return wal >= 0 && wal%1024 == 0
+	}
+	return header == superblockSize
+}
+
+// countUsed - Entradas marcadas como usadas en un bitmap
+func countUsed(bitmap []byte) int64 {
+	used := int64(0)
+	for _, state := range bitmap {
+		if state != 0 {
+			used++
+		}
+	}
+	return used
+}
+
+// relocationMap - Asignar a cada índice en uso desde limit uno libre menor que
+// limit. El bitmap queda con el estado posterior a la reubicación.
+func relocationMap(bitmap []byte, limit int64) map[int64]int64 {
+	moves := make(map[int64]int64)
+	free := int64(0)
+	for index := limit; index < int64(len(bitmap)); index++ {
+		if bitmap[index] == 0 {
+			continue
+		}
+		for bitmap[free] != 0 {
+			free++
+		}
+		moves[index] = free
+		bitmap[free], bitmap[index] = bitmap[index], 0
+	}
+	return moves
+}
+
+// encodeEmptyInode - Bytes de un inodo libre (sin bloques) con el tamaño de la partición
+func encodeEmptyInode(inodeSize int64) ([]byte, error) {
+	var inode structs.Inodos
+	for i := range inode.I_block {
+		inode.I_block[i] = -1
+	}
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.LittleEndian, &inode); err != nil {
+		return nil, err
+	}
+	if inodeSize > int64(buffer.Len()) {
+		inodeSize = int64(buffer.Len())
+	}
+	return buffer.Bytes()[:inodeSize], nil
+}
+
+// fsRelocator - Reubicación de los inodos y bloques que quedan fuera al reducir.
+// Cada uno se escribe en su nueva posición con sus referencias ya traducidas;
+// la copia anterior queda fuera del nuevo tamaño.
+type fsRelocator struct {
+	fs     *FileSystem
+	inodes map[int64]int64
+	blocks map[int64]int64
+}
+
+// inode - Nueva posición de un inodo
+func (r *fsRelocator) inode(index int64) int64 {
+	if target, moved := r.inodes[index]; moved {
+		return target
+	}
+	return index
+}
+
+// block - Nueva posición de un bloque
+func (r *fsRelocator) block(index int64) int64 {
+	if target, moved := r.blocks[index]; moved {
+		return target
+	}
+	return index
+}
+
+// validBlock - Verificar que un apuntador esté dentro del sistema de archivos
+func (r *fsRelocator) validBlock(index int64) bool {
+	return index >= 0 && index < r.fs.SB.S_blocks_count
+}
+
+// moveInode - Mover los bloques de un inodo y escribirlo en su nueva posición
+func (r *fsRelocator) moveInode(index int64) error {
+	inode, err := r.fs.ReadInode(index)
+	if err != nil {
+		return err
+	}
+
+	folder := inode.I_type == '0'
+	for slot, blockIndex := range inode.I_block {
+		if !r.validBlock(blockIndex) {
+			continue
+		}
+		level := 0
+		if slot >= directBlocksCount {
+			level = slot - directBlocksCount + 1
+		}
+		if err := r.moveBlock(blockIndex, level, folder); err != nil {
+			return err
+		}
+		inode.I_block[slot] = r.block(blockIndex)
+	}
+
+	return r.fs.WriteInode(r.inode(index), inode)
+}
+
+// moveBlock - Escribir un bloque en su nueva posición. Los de apuntadores
+// (level > 0) y los de carpeta se reescriben con sus referencias traducidas.
+func (r *fsRelocator) moveBlock(index int64, level int, folder bool) error {
+	if level > 0 {
+		pointerBlock, err := r.fs.ReadPointerBlock(index)
+		if err != nil {
+			return err
+		}
+		for position, pointer := range pointerBlock.BPointers {
+			if !r.validBlock(pointer) {
+				continue
+			}
+			if err := r.moveBlock(pointer, level-1, folder); err != nil {
+				return err
+			}
+			pointerBlock.BPointers[position] = r.block(pointer)
+		}
+		return r.fs.WritePointerBlock(r.block(index), &pointerBlock)
+	}
+
+	if folder {
+		return r.moveFolderBlock(index)
+	}
+
+	target := r.block(index)
+	if target == index {
+		return nil
+	}
+	buffer := make([]byte, r.fs.SB.S_block_s)
+	if _, err := r.fs.file.ReadAt(buffer, r.fs.blockPosition(index)); err != nil {
+		return fmt.Errorf("error al leer el bloque %d: %v", index, err)
+	}
+	if _, err := r.fs.file.WriteAt(buffer, r.fs.blockPosition(target)); err != nil {
+		return fmt.Errorf("error al escribir el bloque %d: %v", target, err)
+	}
+	return nil
+}
+
+// moveFolderBlock - Traducir los inodos y nombres largos de las entradas de una carpeta
+func (r *fsRelocator) moveFolderBlock(index int64) error {
+	folderBlock, err := r.fs.ReadFolderBlock(index)
+	if err != nil {
+		return err
+	}
+
+	for i := range folderBlock.BContent {
+		entry := &folderBlock.BContent[i]
+		if entry.BInodo == -1 {
+			continue
+		}
+		entry.BInodo = r.inode(entry.BInodo)
+
+		if entry.BLong != 0 && hasLongNames(r.fs.SB) {
+			if err := r.moveNameChain(int64(entry.BLong)); err != nil {
+				return err
+			}
+			entry.BLong = int32(r.block(int64(entry.BLong)))
+		}
+	}
+
+	return r.fs.WriteBlock(r.block(index), folderBlock)
+}
+
+// moveNameChain - Mover los bloques de un nombre largo traduciendo sus enlaces
+func (r *fsRelocator) moveNameChain(first int64) error {
+	next := first
+	for count := 0; next > 0 && r.validBlock(next) && count < r.fs.maxNameBlocks(); count++ {
+		var nameBlock structs.BloqueNombre
+		if err := r.fs.ReadBlock(next, &nameBlock); err != nil {
+			return err
+		}
+
+		current := next
+		next = nameBlock.BNext
+		if r.validBlock(nameBlock.BNext) {
+			nameBlock.BNext = r.block(nameBlock.BNext)
+		}
+		if err := r.fs.WriteBlock(r.block(current), &nameBlock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// print - Mostrar el resultado del redimensionamiento
+func (s *resizeSummary) print(res *CommandResult) {
+	if s.NewInodes == s.OldInodes {
+		res.Printf("ℹ️  El sistema de archivos ya ocupa toda la partición (%d inodos, %d bloques).\n", s.NewInodes, s.NewBlocks)
+		return
+	}
+
+	res.Printf("📐 Sistema de archivos EXT%d redimensionado:\n", s.FileSystem)
+	res.Printf("   - Inodos: %d → %d\n", s.OldInodes, s.NewInodes)
+	res.Printf("   - Bloques: %d → %d\n", s.OldBlocks, s.NewBlocks)
+	if s.MovedInodes > 0 || s.MovedBlocks > 0 {
+		res.Printf("   🔀 Reubicados antes de reducir: %d inodos y %d bloques\n", s.MovedInodes, s.MovedBlocks)
+	}
+}
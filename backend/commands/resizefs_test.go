@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"backend/structs"
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// Pruebas de resizefs: fdisk -add agranda y reduce el sistema de archivos con
+// la partición, los archivos se conservan y fsck la deja limpia.
+
+// superblockOf - Superbloque actual de la partición de la imagen
+func superblockOf(t *testing.T, img *crashImage) structs.SuperBloque {
+	t.Helper()
+	img.mounted = GetMountedPartition(img.id)
+	fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	return *fs.SB
+}
+
+// mkfileContent - Contenido que mkfile genera para un archivo de size bytes
+func mkfileContent(size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte('0' + i%10)
+	}
+	return content
+}
+
+// checkContent - Verificar el contenido de un archivo de la partición
+func checkContent(t *testing.T, img *crashImage, path string, want []byte) {
+	t.Helper()
+	res, err := ReadFileByPath(img.mounted, path)
+	if err != nil {
+		t.Fatalf("leer %s: %v", path, err)
+	}
+	if content, _ := res.Data["content"].([]byte); !bytes.Equal(content, want) {
+		t.Fatalf("el contenido de %s (%d bytes) no coincide", path, len(content))
+	}
+}
+
+func TestResizeGrowAndShrink(t *testing.T) {
+	for _, fsType := range []string{"2fs", "3fs"} {
+		t.Run(fsType, func(t *testing.T) {
+			img := newCrashImageFS(t, fsType)
+			before := superblockOf(t, img)
+
+			// Agrandar: la partición es la primera del disco y tiene espacio libre detrás
+			if _, err := ExecuteFdisk(0, "k", img.diskPath, "p", "wf", "part1", "", 512); err != nil {
+				t.Fatalf("fdisk -add: %v", err)
+			}
+			grown := superblockOf(t, img)
+			if grown.S_blocks_count <= before.S_blocks_count || grown.S_inodes_count <= before.S_inodes_count {
+				t.Fatalf("no creció: %d→%d bloques, %d→%d inodos", before.S_blocks_count, grown.S_blocks_count,
+					before.S_inodes_count, grown.S_inodes_count)
+			}
+			if grown.S_file_system_type != before.S_file_system_type {
+				t.Fatalf("cambió el tipo de sistema de archivos: %d→%d", before.S_file_system_type, grown.S_file_system_type)
+			}
+			checkContent(t, img, "/docs/a.txt", mkfileContent(1500))
+			img.checkClean(t, "tras agrandar")
+
+			// resizefs sin cambios en la partición no mueve nada
+			res, err := ExecuteResizefs(img.id)
+			if err != nil {
+				t.Fatalf("resizefs: %v", err)
+			}
+			if res.Data["blocks"] != grown.S_blocks_count || res.Data["movedBlocks"] != 0 {
+				t.Fatalf("resizefs sin cambios: %d bloques, %v movidos", res.Data["blocks"], res.Data["movedBlocks"])
+			}
+
+			// Dejar un archivo en la zona nueva: se llena la vieja y se libera
+			var fillers []string
+			for {
+				name := fmt.Sprintf("/r%02d", len(fillers))
+				if _, err := ExecuteMkfile(img.session, name, false, 38000, ""); err != nil {
+					t.Fatalf("mkfile %s: %v", name, err)
+				}
+				fillers = append(fillers, name)
+				blocks := dataBlocksOf(t, img, name)
+				if blocks[len(blocks)-1] >= before.S_blocks_count {
+					break
+				}
+			}
+			if _, err := ExecuteMkfile(img.session, "/docs/c.txt", false, 20000, ""); err != nil {
+				t.Fatalf("mkfile: %v", err)
+			}
+			for _, name := range fillers {
+				if _, err := ExecuteRemove(img.session, name); err != nil {
+					t.Fatalf("remove %s: %v", name, err)
+				}
+			}
+
+			// Reducir al tamaño original: hay que mover los bloques de c.txt
+			blocks := dataBlocksOf(t, img, "/docs/c.txt")
+			if last := blocks[len(blocks)-1]; last < before.S_blocks_count {
+				t.Fatalf("c.txt quedó en el bloque %d, dentro del tamaño original (%d)", last, before.S_blocks_count)
+			}
+			if _, err := ExecuteFdisk(0, "k", img.diskPath, "p", "wf", "part1", "", -512); err != nil {
+				t.Fatalf("fdisk -add negativo: %v", err)
+			}
+			shrunk := superblockOf(t, img)
+			if shrunk.S_blocks_count != before.S_blocks_count || shrunk.S_inodes_count != before.S_inodes_count {
+				t.Fatalf("al reducir quedaron %d bloques y %d inodos, se esperaban %d y %d", shrunk.S_blocks_count,
+					shrunk.S_inodes_count, before.S_blocks_count, before.S_inodes_count)
+			}
+			checkContent(t, img, "/docs/a.txt", mkfileContent(1500))
+			checkContent(t, img, "/docs/c.txt", mkfileContent(20000))
+			img.checkClean(t, "tras reducir")
+		})
+	}
+}
+
+func TestResizeRefusesDataLoss(t *testing.T) {
+	img := newCrashImage(t)
+	for _, name := range []string{"/uno", "/dos"} {
+		if _, err := ExecuteMkfile(img.session, name, false, 30000, ""); err != nil {
+			t.Fatalf("mkfile %s: %v", name, err)
+		}
+	}
+	before := superblockOf(t, img)
+	partition := img.mounted.Size
+
+	// Los archivos no caben en 64 KB
+	if _, err := ExecuteFdisk(0, "b", img.diskPath, "p", "wf", "part1", "", -(partition - 64*1024)); ErrorCode(err) != ErrCodeNoSpace {
+		t.Fatalf("reducir sin espacio: %v", err)
+	}
+	after := superblockOf(t, img)
+	if after != before || img.mounted.Size != partition {
+		t.Fatalf("la partición cambió aunque se rechazó la reducción")
+	}
+	checkContent(t, img, "/uno", mkfileContent(30000))
+	img.checkClean(t, "reducción rechazada")
+}
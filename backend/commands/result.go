@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Códigos de error estables que los clientes pueden interpretar sin leer el texto
+const (
+	ErrCodeInvalidArgument  = "INVALID_ARGUMENT"
+	ErrCodeUnknownCommand   = "UNKNOWN_COMMAND"
+	ErrCodeNoSession        = "NO_SESSION"
+	ErrCodeSessionActive    = "SESSION_ACTIVE"
+	ErrCodeAuthFailed       = "AUTH_FAILED"
+	ErrCodePermissionDenied = "PERMISSION_DENIED"
+	ErrCodeNotFound         = "NOT_FOUND"
+	ErrCodeAlreadyExists    = "ALREADY_EXISTS"
+	ErrCodeNotMounted       = "NOT_MOUNTED"
+	ErrCodeNoSpace          = "NO_SPACE"
+	ErrCodeQuotaExceeded    = "QUOTA_EXCEEDED"
+	ErrCodeUnsupported      = "UNSUPPORTED"
+	ErrCodeIO               = "IO_ERROR"
+	ErrCodeInternal         = "INTERNAL_ERROR"
+)
+
+// CommandResult - Resultado estructurado de un comando. Además del texto para
+// humanos guarda las rutas afectadas y los datos relevantes (inodo creado,
+// ID de montaje asignado, etc.).
+type CommandResult struct {
+	Command string                 `json:"command"`
+	Paths   []string               `json:"paths,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	output  strings.Builder
+}
+
+// outputEcho - Destino opcional donde se replica el texto de cada comando
+// mientras se genera (la CLI lo usa para mostrarlo en pantalla).
+var outputEcho io.Writer
+
+// SetOutputEcho - Replicar la salida de los comandos en w (nil para desactivar)
+func SetOutputEcho(w io.Writer) {
+	outputEcho = w
+}
+
+// NewCommandResult - Crear un resultado vacío para el comando indicado
+func NewCommandResult(command string) *CommandResult {
+	return &CommandResult{
+		Command: command,
+		Data:    make(map[string]interface{}),
+	}
+}
+
+// Printf - Agregar texto formateado a la salida del comando
+func (r *CommandResult) Printf(format string, a ...interface{}) {
+	text := fmt.Sprintf(format, a...)
+	r.output.WriteString(text)
+	if outputEcho != nil {
+		io.WriteString(outputEcho, text)
+	}
+}
+
+// Println - Agregar una línea a la salida del comando
+func (r *CommandResult) Println(a ...interface{}) {
+	r.Printf("%s", fmt.Sprintln(a...))
+}
+
+// Output - Texto para humanos generado por el comando
+func (r *CommandResult) Output() string {
+	if r == nil {
+		return ""
+	}
+	return r.output.String()
+}
+
+// AddPath - Registrar una ruta afectada por el comando
+func (r *CommandResult) AddPath(path string) {
+	r.Paths = append(r.Paths, path)
+}
+
+// Set - Registrar un dato estructurado del resultado
+func (r *CommandResult) Set(key string, value interface{}) {
+	r.Data[key] = value
+}
+
+// CommandError - Error de un comando con un código estable
+type CommandError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// newCommandError - Crear un error con código
+func newCommandError(code string, format string, a ...interface{}) *CommandError {
+	return &CommandError{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// NewCommandError - Versión exportada para los errores generados fuera del paquete
+func NewCommandError(code string, format string, a ...interface{}) *CommandError {
+	return newCommandError(code, format, a...)
+}
+
+// wrapCommandError - Envolver el error de una operación. Si la causa ya trae un
+// código (p. ej. sin permisos o sin espacio) se conserva; si no, se usa code.
+func wrapCommandError(code string, err error, format string, a ...interface{}) *CommandError {
+	var inner *CommandError
+	if errors.As(err, &inner) {
+		code = inner.Code
+	}
+	return &CommandError{Code: code, Message: fmt.Sprintf(format, a...), Err: err}
+}
+
+// commandErrorFrom - Convertir un error en CommandError conservando su código
+// si ya lo tiene; si no, se usa code.
+func commandErrorFrom(code string, err error) *CommandError {
+	var inner *CommandError
+	if errors.As(err, &inner) {
+		code = inner.Code
+	}
+	return &CommandError{Code: code, Message: err.Error()}
+}
+
+// ErrorCode - Obtener el código de un error (INTERNAL_ERROR si no tiene)
+func ErrorCode(err error) string {
+	var commandErr *CommandError
+	if errors.As(err, &commandErr) {
+		return commandErr.Code
+	}
+	return ErrCodeInternal
+}
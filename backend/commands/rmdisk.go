@@ -1,30 +1,34 @@
 package commands
 
 import (
-	"fmt"
 	"os"
 	"strings"
 )
 
-func ExecuteRmdisk(path string) {
+func ExecuteRmdisk(path string) (*CommandResult, error) {
+    res := NewCommandResult("rmdisk")
+
     if !strings.HasSuffix(strings.ToLower(path), ".mia") {
         path += ".mia"
     }
 
     // Verificar que el archivo existe antes de eliminarlo
     if _, err := os.Stat(path); os.IsNotExist(err) {
-        fmt.Printf("Error: El archivo '%s' no existe.\n", path)
-        return
+        return res, newCommandError(ErrCodeNotFound, "el archivo '%s' no existe", path)
     }
 
+    // Esperar a que terminen los comandos que estén usando el disco
+    defer lockDisk(path)()
+
     if err := os.Remove(path); err != nil {
-        fmt.Printf("Error al eliminar el archivo: %v\n", err)
-        return
+        return res, wrapCommandError(ErrCodeIO, err, "error al eliminar el archivo")
     }
 
     if err := RemoveDiskFromRegistry(path); err != nil {
-        fmt.Printf("⚠️ Advertencia: No se pudo actualizar el registro: %v\n", err)
+        res.Printf("⚠️ Advertencia: No se pudo actualizar el registro: %v\n", err)
     }
 
-    fmt.Printf("Disco eliminado exitosamente en '%s'.\n", path)
-}
\ No newline at end of file
+    res.Printf("Disco eliminado exitosamente en '%s'.\n", path)
+
+    return res, nil
+}
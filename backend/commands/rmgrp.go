@@ -1,108 +1,83 @@
 package commands
 
 import (
-	"backend/structs"
-	"encoding/binary"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 )
 
-func ExecuteRmgrp(groupName string) {
+func ExecuteRmgrp(session *Session, groupName string) (*CommandResult, error) {
+	res := NewCommandResult("rmgrp")
+
 	// Verificar sesión activa
-	if !RequireActiveSession() {
-		return
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
 	}
 
 	// Validar parámetros
 	if groupName == "" {
-		fmt.Println("Error: el parámetro -name es obligatorio para rmgrp.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -name es obligatorio para rmgrp")
 	}
 
-	// Obtener sesión actual
-	session := GetCurrentSession()
+	// Validar la sesión recibida
 	if session == nil {
-		fmt.Println("Error: No se pudo obtener la sesión actual.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no se pudo obtener la sesión actual")
 	}
 
 	// Verificar que solo root puede eliminar grupos
 	if !session.IsRoot {
-		fmt.Printf("Error: Solo el usuario 'root' puede eliminar grupos. Usuario actual: '%s'\n", session.User)
-		return
+		return res, newCommandError(ErrCodePermissionDenied, "solo el usuario 'root' puede eliminar grupos. Usuario actual: '%s'", session.User)
 	}
 
 	// Verificar que no se está intentando eliminar el grupo root
 	if groupName == "root" {
-		fmt.Println("Error: No se puede eliminar el grupo 'root'.")
-		return
+		return res, newCommandError(ErrCodePermissionDenied, "no se puede eliminar el grupo 'root'")
 	}
 
 	// Buscar la partición montada de la sesión
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: No se encontró la partición montada con ID '%s'.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
 	}
 
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
+	}
+	defer tx.rollback()
+
 	// Eliminar el grupo del archivo users.txt
-	err := removeGroupFromUsersFile(mounted, groupName)
+	err = removeGroupFromUsersFile(mounted, groupName)
 	if err != nil {
-		fmt.Printf("Error al eliminar el grupo '%s': %v\n", groupName, err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al eliminar el grupo '%s'", groupName)
 	}
 
-	fmt.Printf("Grupo '%s' eliminado exitosamente.\n", groupName)
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	res.Printf("Grupo '%s' eliminado exitosamente (marcado con GID 0 en users.txt).\n", groupName)
+	res.AddPath("/users.txt")
+	res.Set("group", groupName)
+
+	return res, nil
 }
 
 // Eliminar grupo del archivo users.txt
 func removeGroupFromUsersFile(mounted *MountedPartition, groupName string) error {
 	// Abrir el archivo del disco
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+	file, err := openMountedDisk(mounted, os.O_RDWR)
 	if err != nil {
 		return fmt.Errorf("error al abrir el disco: %v", err)
 	}
 	defer file.Close()
 
-	// Leer el MBR para obtener información de la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		return fmt.Errorf("error al leer el MBR: %v", err)
-	}
-
-	// Encontrar la partición específica
-	var partition *structs.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_status != '0' {
-			partitionNameBytes := p.Part_name[:]
-			nullIndex := len(partitionNameBytes)
-			for j, b := range partitionNameBytes {
-				if b == 0 {
-					nullIndex = j
-					break
-				}
-			}
-			partitionName := strings.TrimSpace(string(partitionNameBytes[:nullIndex]))
-
-			if strings.EqualFold(partitionName, mounted.Name) || partitionName == mounted.Name {
-				partitionCopy := p
-				partition = &partitionCopy
-				break
-			}
-		}
-	}
-
-	if partition == nil {
-		return fmt.Errorf("no se pudo encontrar la partición '%s'", mounted.Name)
-	}
-
-	// Leer el superbloque
-	file.Seek(partition.Part_start, 0)
-	var superblock structs.SuperBloque
-	if err := binary.Read(file, binary.LittleEndian, &superblock); err != nil {
-		return fmt.Errorf("error al leer el superbloque: %v", err)
+	// Verificar que la partición (del MBR, lógica o GPT) tenga un sistema de archivos
+	if _, err := mountedFileSystem(file, mounted); err != nil {
+		return err
 	}
 
 	// Leer el contenido actual del archivo users.txt
@@ -118,7 +93,7 @@ func removeGroupFromUsersFile(mounted *MountedPartition, groupName string) error
 	}
 
 	if !found {
-		return fmt.Errorf("el grupo '%s' no existe", groupName)
+		return newCommandError(ErrCodeNotFound, "el grupo '%s' no existe", groupName)
 	}
 
 	// Escribir el contenido actualizado
@@ -127,7 +102,6 @@ func removeGroupFromUsersFile(mounted *MountedPartition, groupName string) error
 		return fmt.Errorf("error al escribir users.txt: %v", err)
 	}
 
-	fmt.Printf("Grupo '%s' marcado como eliminado (GID=0).\n", groupName)
 	return nil
 }
 
@@ -162,15 +136,13 @@ func markGroupAsDeleted(content, groupName string) (string, bool, error) {
 			// Si es el grupo que buscamos y no está ya eliminado
 			if tipo == "G" && name == groupName {
 				if gid == 0 {
-					return "", false, fmt.Errorf("el grupo '%s' ya está eliminado", groupName)
+					return "", false, newCommandError(ErrCodeNotFound, "el grupo '%s' ya está eliminado", groupName)
 				}
 
 				// Marcar como eliminado (GID = 0)
 				newLine := fmt.Sprintf("0,G,%s", name)
 				newLines = append(newLines, newLine)
 				found = true
-
-				fmt.Printf("Grupo encontrado y marcado para eliminación: %s -> %s\n", originalLine, newLine)
 			} else {
 				newLines = append(newLines, originalLine)
 			}
@@ -1,100 +1,78 @@
 package commands
 
 import (
-	"backend/structs"
-	"encoding/binary"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 )
 
-func ExecuteRmusr(username string) {
+func ExecuteRmusr(session *Session, username string) (*CommandResult, error) {
+	res := NewCommandResult("rmusr")
+
 	// Verificar sesión activa
-	if !RequireActiveSession() {
-		return
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
 	}
 
 	// Validar parámetro obligatorio
 	if username == "" {
-		fmt.Println("Error: el parámetro -user es obligatorio para rmusr.")
-		return
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -user es obligatorio para rmusr")
 	}
 
-	// Obtener sesión actual
-	session := GetCurrentSession()
+	// Validar la sesión recibida
 	if session == nil {
-		fmt.Println("Error: No se pudo obtener la sesión actual.")
-		return
+		return res, newCommandError(ErrCodeNoSession, "no se pudo obtener la sesión actual")
 	}
 
 	// Verificar que solo root puede eliminar usuarios
 	if !session.IsRoot {
-		fmt.Printf("Error: Solo el usuario 'root' puede eliminar usuarios. Usuario actual: '%s'\n", session.User)
-		return
+		return res, newCommandError(ErrCodePermissionDenied, "solo el usuario 'root' puede eliminar usuarios. Usuario actual: '%s'", session.User)
 	}
 
 	// Buscar la partición montada de la sesión
 	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
-		fmt.Printf("Error: No se encontró la partición montada con ID '%s'.\n", session.PartitionID)
-		return
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
 	}
 
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
+	}
+	defer tx.rollback()
+
 	// Eliminar el usuario del archivo users.txt
-	err := removeUserFromUsersFile(mounted, username)
+	err = removeUserFromUsersFile(mounted, username)
 	if err != nil {
-		fmt.Printf("Error al eliminar el usuario '%s': %v\n", username, err)
-		return
+		return res, wrapCommandError(ErrCodeIO, err, "error al eliminar el usuario '%s'", username)
 	}
 
-	fmt.Printf("Usuario '%s' eliminado exitosamente.\n", username)
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	res.Printf("Usuario '%s' eliminado exitosamente (marcado con UID 0 en users.txt).\n", username)
+	res.AddPath("/users.txt")
+	res.Set("user", username)
+
+	return res, nil
 }
 
 // Eliminar usuario del archivo users.txt (marcar UID como 0)
 func removeUserFromUsersFile(mounted *MountedPartition, username string) error {
 	// Abrir el archivo del disco
-	file, err := os.OpenFile(mounted.Path, os.O_RDWR, 0644)
+	file, err := openMountedDisk(mounted, os.O_RDWR)
 	if err != nil {
 		return fmt.Errorf("error al abrir el disco: %v", err)
 	}
 	defer file.Close()
 
-	// Leer el MBR para obtener información de la partición
-	var mbr structs.MBR
-	if err := binary.Read(file, binary.LittleEndian, &mbr); err != nil {
-		return fmt.Errorf("error al leer el MBR: %v", err)
-	}
-
-	// Encontrar la partición específica
-	var partition *structs.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_status != '0' {
-			partitionNameBytes := p.Part_name[:]
-			nullIndex := len(partitionNameBytes)
-			for j, b := range partitionNameBytes {
-				if b == 0 {
-					nullIndex = j
-					break
-				}
-			}
-			partitionName := strings.TrimSpace(string(partitionNameBytes[:nullIndex]))
-			if strings.EqualFold(partitionName, mounted.Name) || partitionName == mounted.Name {
-				partitionCopy := p
-				partition = &partitionCopy
-				break
-			}
-		}
-	}
-	if partition == nil {
-		return fmt.Errorf("no se pudo encontrar la partición '%s'", mounted.Name)
-	}
-
-	// Leer el superbloque
-	file.Seek(partition.Part_start, 0)
-	var superblock structs.SuperBloque
-	if err := binary.Read(file, binary.LittleEndian, &superblock); err != nil {
-		return fmt.Errorf("error al leer el superbloque: %v", err)
+	// Verificar que la partición (del MBR, lógica o GPT) tenga un sistema de archivos
+	if _, err := mountedFileSystem(file, mounted); err != nil {
+		return err
 	}
 
 	// Leer el contenido actual del archivo users.txt
@@ -109,7 +87,7 @@ func removeUserFromUsersFile(mounted *MountedPartition, username string) error {
 		return err
 	}
 	if !found {
-		return fmt.Errorf("el usuario '%s' no existe", username)
+		return newCommandError(ErrCodeNotFound, "el usuario '%s' no existe", username)
 	}
 
 	// Escribir el contenido actualizado
@@ -118,7 +96,6 @@ func removeUserFromUsersFile(mounted *MountedPartition, username string) error {
 		return fmt.Errorf("error al escribir users.txt: %v", err)
 	}
 
-	fmt.Printf("Usuario '%s' marcado como eliminado (UID=0).\n", username)
 	return nil
 }
 
@@ -148,7 +125,7 @@ func markUserAsDeleted(content, username string) (string, bool, error) {
 			}
 			if tipo == "U" && user == username {
 				if uid == 0 {
-					return "", false, fmt.Errorf("el usuario '%s' ya está eliminado", username)
+					return "", false, newCommandError(ErrCodeNotFound, "el usuario '%s' ya está eliminado", username)
 				}
 				if len(parts) >= 5 {
 					password := strings.TrimSpace(parts[4])
@@ -159,7 +136,6 @@ func markUserAsDeleted(content, username string) (string, bool, error) {
 					newLines = append(newLines, newLine)
 				}
 				found = true
-				fmt.Printf("Usuario encontrado y marcado para eliminación: %s -> %s\n", originalLine, newLines[len(newLines)-1])
 			} else {
 				newLines = append(newLines, originalLine)
 			}
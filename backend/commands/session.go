@@ -1,13 +1,15 @@
 package commands
 
 import (
-	"encoding/json"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"os"
+	"sync"
 )
 
-// Estructura para manejar la sesión activa
+// Estructura para manejar una sesión de usuario
 type Session struct {
+	Token       string // Identificador de la sesión (se envía en cada petición HTTP)
 	User        string
 	Password    string // Agregado para mantener consistencia
 	Group       string
@@ -18,20 +20,34 @@ type Session struct {
 	IsRoot      bool // Nuevo campo para identificar si es root
 }
 
-// Variable global para la sesión activa
-var currentSession *Session
+// Usuario de la sesión automática usada por los scripts del endpoint HTTP
+const autoSessionUser = "__auto__"
 
-// Cuando es true, los checks de sesión se omiten (útil para ejecutar scripts desde el endpoint HTTP)
-var AllowCommandsWithoutSession bool = false
+// Sesiones activas indexadas por token. Cada cliente (pestaña del frontend,
+// CLI) tiene su propia sesión; ya no existe una sesión global.
+var (
+	sessions      = make(map[string]*Session)
+	sessionsMutex sync.RWMutex
+)
 
-// Setter para controlar el comportamiento desde fuera del paquete
-func SetAllowCommandsWithoutSession(v bool) {
-	AllowCommandsWithoutSession = v
+// newSessionToken - Generar un token aleatorio para identificar la sesión
+func newSessionToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error al generar el token de sesión: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
 }
 
-// Iniciar sesión
-func StartSession(user, group, partitionID string, uid, gid int64, isRoot bool) {
-	currentSession = &Session{
+// Iniciar sesión: registra una sesión nueva y la devuelve con su token
+func StartSession(user, group, partitionID string, uid, gid int64, isRoot bool) (*Session, error) {
+	token, err := newSessionToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		Token:       token,
 		User:        user,
 		Group:       group,
 		PartitionID: partitionID,
@@ -40,118 +56,121 @@ func StartSession(user, group, partitionID string, uid, gid int64, isRoot bool)
 		IsActive:    true,
 		IsRoot:      isRoot,
 	}
-	// Persistir sesión a disco para que otras instancias/procesos la lean
-	saveSessionToFile()
+
+	sessionsMutex.Lock()
+	sessions[token] = session
+	sessionsMutex.Unlock()
+
+	return session, nil
 }
 
 // Cerrar sesión
-func EndSession() {
-	currentSession = nil
-	// Eliminar archivo de sesión
-	_ = os.Remove(sessionFilePath())
-}
+func EndSession(session *Session) {
+	if session == nil {
+		return
+	}
 
-// Verificar si hay sesión activa
-func IsSessionActive() bool {
-	return currentSession != nil && currentSession.IsActive
+	sessionsMutex.Lock()
+	delete(sessions, session.Token)
+	sessionsMutex.Unlock()
+
+	session.IsActive = false
 }
 
-// Obtener sesión actual
-func GetCurrentSession() *Session {
-	if currentSession != nil && currentSession.IsActive {
-		return currentSession
+// Obtener una sesión activa por su token (nil si no existe)
+func GetSessionByToken(token string) *Session {
+	if token == "" {
+		return nil
 	}
 
-	// Intentar cargar sesión desde archivo si no está en memoria
-	loadSessionFromFile()
-	if currentSession != nil && currentSession.IsActive {
-		return currentSession
-	}
+	sessionsMutex.RLock()
+	defer sessionsMutex.RUnlock()
 
-	// Si se permite ejecutar comandos sin sesión (modo script desde GUI),
-	// devolver una sesión temporal que tenga privilegios root para permitir
-	// que los comandos del script se ejecuten sin bloqueo.
-	if AllowCommandsWithoutSession {
-		// Intentar determinar una partición por defecto (la primera montada)
-		defaultPartition := ""
-		if parts := GetMountedPartitions(); len(parts) > 0 {
-			defaultPartition = parts[0].ID
-		}
+	session, ok := sessions[token]
+	if !ok || !session.IsActive {
+		return nil
+	}
+	return session
+}
 
-		tmp := &Session{
-			User:        "__auto__",
-			Group:       "",
-			PartitionID: defaultPartition,
-			UID:         0,
-			GID:         0,
-			IsActive:    true,
-			IsRoot:      true,
-		}
-		currentSession = tmp
-		return currentSession
+// NewAutoSession - Sesión temporal con privilegios root para ejecutar scripts
+// desde el endpoint HTTP sin haber iniciado sesión. No se registra ni tiene token;
+// el servidor solo la usa si se inició con -auto-session.
+func NewAutoSession() *Session {
+	// Intentar determinar una partición por defecto (la primera montada)
+	defaultPartition := ""
+	if parts := GetMountedPartitions(); len(parts) > 0 {
+		defaultPartition = parts[0].ID
 	}
 
-	return nil
+	return &Session{
+		User:        autoSessionUser,
+		PartitionID: defaultPartition,
+		UID:         0,
+		GID:         0,
+		IsActive:    true,
+		IsRoot:      true,
+	}
 }
 
-// Establecer sesión actual (para compatibilidad con login.go)
-func SetCurrentSession(session *Session) {
-	currentSession = session
+// IsAutoSession - Verificar si la sesión es la automática de scripts
+func IsAutoSession(session *Session) bool {
+	return session != nil && session.User == autoSessionUser
 }
 
-// Limpiar sesión actual
-func ClearCurrentSession() {
-	currentSession = nil
+// Verificar si la sesión está activa
+func IsSessionActive(session *Session) bool {
+	return session != nil && session.IsActive
 }
 
-// Verificar si el usuario actual es root
-func IsRootUser() bool {
-	if currentSession == nil || !currentSession.IsActive {
+// Verificar si el usuario de la sesión es root
+func IsRootUser(session *Session) bool {
+	if !IsSessionActive(session) {
 		return false
 	}
-	return currentSession.IsRoot
+	return session.IsRoot
 }
 
-// Obtener el ID de partición del usuario actual
-func GetUserPartitionID() string {
-	if currentSession == nil || !currentSession.IsActive {
+// Obtener el ID de partición del usuario de la sesión
+func GetUserPartitionID(session *Session) string {
+	if !IsSessionActive(session) {
 		return ""
 	}
-	return currentSession.PartitionID
+	return session.PartitionID
 }
 
 // Validar si el usuario tiene acceso a una partición específica
-func ValidatePartitionAccess(partitionID string) error {
-	if currentSession == nil || !currentSession.IsActive {
+func ValidatePartitionAccess(session *Session, partitionID string) error {
+	if !IsSessionActive(session) {
 		return fmt.Errorf("no hay sesión activa")
 	}
 
 	// Root tiene acceso a todas las particiones
-	if currentSession.IsRoot {
+	if session.IsRoot {
 		return nil
 	}
 
 	// Usuarios normales solo pueden acceder a su partición asignada
-	if currentSession.PartitionID != partitionID {
-		return fmt.Errorf("acceso denegado: solo puede acceder a la partición '%s'", currentSession.PartitionID)
+	if session.PartitionID != partitionID {
+		return fmt.Errorf("acceso denegado: solo puede acceder a la partición '%s'", session.PartitionID)
 	}
 
 	return nil
 }
 
 // Validar acceso a un disco específico
-func ValidateDiskAccess(diskPath string) error {
-	if currentSession == nil || !currentSession.IsActive {
+func ValidateDiskAccess(session *Session, diskPath string) error {
+	if !IsSessionActive(session) {
 		return fmt.Errorf("no hay sesión activa")
 	}
 
 	// Root tiene acceso a todos los discos
-	if currentSession.IsRoot {
+	if session.IsRoot {
 		return nil
 	}
 
 	// Verificar si el usuario tiene una partición montada en este disco
-	mounted := GetMountedPartition(currentSession.PartitionID)
+	mounted := GetMountedPartition(session.PartitionID)
 	if mounted == nil {
 		return fmt.Errorf("no tiene particiones montadas")
 	}
@@ -163,41 +182,30 @@ func ValidateDiskAccess(diskPath string) error {
 	return nil
 }
 
-// Verificar sesión y mostrar error si no existe
-func RequireActiveSession() bool {
-	if AllowCommandsWithoutSession {
-		return true
-	}
-
-	if !IsSessionActive() {
-		fmt.Println("Error: No existe una sesión activa. Use el comando 'login' para iniciar sesión.")
-		return false
+// Verificar que exista una sesión activa
+func RequireActiveSession(session *Session) error {
+	if !IsSessionActive(session) {
+		return newCommandError(ErrCodeNoSession, "no existe una sesión activa. Use el comando 'login' para iniciar sesión")
 	}
-	return true
+	return nil
 }
 
 // Verificar si se requieren permisos de root
-func RequireRootPermission() bool {
-	if AllowCommandsWithoutSession {
-		return true
+func RequireRootPermission(session *Session) error {
+	if err := RequireActiveSession(session); err != nil {
+		return err
 	}
 
-	if !IsSessionActive() {
-		fmt.Println("Error: No existe una sesión activa. Use el comando 'login' para iniciar sesión.")
-		return false
+	if !IsRootUser(session) {
+		return newCommandError(ErrCodePermissionDenied, "este comando requiere permisos de administrador (root)")
 	}
 
-	if !IsRootUser() {
-		fmt.Println("Error: Este comando requiere permisos de administrador (root).")
-		return false
-	}
-
-	return true
+	return nil
 }
 
-// Mostrar información de la sesión actual
-func ShowCurrentSession() {
-	if session := GetCurrentSession(); session != nil {
+// Mostrar información de la sesión
+func ShowCurrentSession(session *Session) {
+	if IsSessionActive(session) {
 		fmt.Printf("Sesión activa:\n")
 		fmt.Printf("   👤 Usuario: %s\n", session.User)
 		fmt.Printf("   👥 Grupo: %s\n", session.Group)
@@ -214,103 +222,20 @@ func ShowCurrentSession() {
 }
 
 // Obtener información de sesión para API
-func GetSessionInfo() map[string]interface{} {
-	if currentSession == nil || !currentSession.IsActive {
-		// Intentar cargar sesión desde archivo si existe
-		loadSessionFromFile()
-		if currentSession == nil || !currentSession.IsActive {
-			return map[string]interface{}{
-				"isLoggedIn": false,
-			}
+func GetSessionInfo(session *Session) map[string]interface{} {
+	if !IsSessionActive(session) || IsAutoSession(session) {
+		return map[string]interface{}{
+			"isLoggedIn": false,
 		}
 	}
 
 	return map[string]interface{}{
 		"isLoggedIn":  true,
-		"username":    currentSession.User,
-		"group":       currentSession.Group,
-		"partitionId": currentSession.PartitionID,
-		"uid":         currentSession.UID,
-		"gid":         currentSession.GID,
-		"isRoot":      currentSession.IsRoot,
-	}
-}
-
-// --- Persistencia de sesión ---
-func sessionFilePath() string {
-	return "/tmp/extreamfs_session.json"
-}
-
-func saveSessionToFile() {
-	if currentSession == nil {
-		return
-	}
-
-	// Preparar estructura exportable
-	data := map[string]interface{}{
-		"user":        currentSession.User,
-		"group":       currentSession.Group,
-		"partitionId": currentSession.PartitionID,
-		"uid":         currentSession.UID,
-		"gid":         currentSession.GID,
-		"isActive":    currentSession.IsActive,
-		"isRoot":      currentSession.IsRoot,
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return
-	}
-
-	_ = os.WriteFile(sessionFilePath(), b, 0644)
-}
-
-func loadSessionFromFile() {
-	path := sessionFilePath()
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return
-	}
-
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return
-	}
-
-	var data map[string]interface{}
-	if err := json.Unmarshal(b, &data); err != nil {
-		return
-	}
-
-	// Reconstruir currentSession
-	user, _ := data["user"].(string)
-	group, _ := data["group"].(string)
-	partitionId, _ := data["partitionId"].(string)
-	uid := int64(0)
-	gid := int64(0)
-	if v, ok := data["uid"].(float64); ok {
-		uid = int64(v)
-	}
-	if v, ok := data["gid"].(float64); ok {
-		gid = int64(v)
-	}
-	isActive := false
-	if v, ok := data["isActive"].(bool); ok {
-		isActive = v
-	}
-	isRoot := false
-	if v, ok := data["isRoot"].(bool); ok {
-		isRoot = v
-	}
-
-	if isActive {
-		currentSession = &Session{
-			User:        user,
-			Group:       group,
-			PartitionID: partitionId,
-			UID:         uid,
-			GID:         gid,
-			IsActive:    isActive,
-			IsRoot:      isRoot,
-		}
+		"username":    session.User,
+		"group":       session.Group,
+		"partitionId": session.PartitionID,
+		"uid":         session.UID,
+		"gid":         session.GID,
+		"isRoot":      session.IsRoot,
 	}
 }
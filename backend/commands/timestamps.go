@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"backend/structs"
+	"fmt"
+	"os"
+	"time"
+)
+
+// MARCAS DE TIEMPO DE LOS INODOS
+// Semántica tipo POSIX:
+//   - I_atime: último acceso al contenido (cat, descarga, copia del origen).
+//   - I_mtime: último cambio del contenido. En una carpeta, de sus entradas
+//     (crear, eliminar, renombrar o mover algo dentro de ella).
+//   - I_ctime: último cambio del inodo, ya sea del contenido o de los
+//     metadatos (permisos, dueño, enlaces, nombre). No es la fecha de creación.
+// Cada lectura actualiza I_atime (como strictatime en Linux). export y los
+// reportes no lo actualizan, igual que una copia de respaldo.
+
+// touchModify - Registrar un cambio del contenido (I_mtime e I_ctime)
+func touchModify(inode *structs.Inodos) {
+	now := time.Now().Unix()
+	inode.I_mtime = now
+	inode.I_ctime = now
+}
+
+// touchChange - Registrar un cambio de metadatos (I_ctime)
+func touchChange(inode *structs.Inodos) {
+	inode.I_ctime = time.Now().Unix()
+}
+
+// touchAccess - Registrar una lectura del contenido (I_atime)
+func touchAccess(inode *structs.Inodos) {
+	inode.I_atime = time.Now().Unix()
+}
+
+// markChanged - Registrar un cambio de metadatos en un inodo del disco
+func (fs *FileSystem) markChanged(inodeIndex int64) error {
+	inode, err := fs.ReadInode(inodeIndex)
+	if err != nil {
+		return err
+	}
+	touchChange(inode)
+	return fs.WriteInode(inodeIndex, inode)
+}
+
+// recordAccess - Actualizar I_atime de los inodos leídos por un comando de
+// solo lectura. Se hace después de leer, en una transacción propia.
+func recordAccess(mounted *MountedPartition, inodes []int64) error {
+	if len(inodes) == 0 {
+		return nil
+	}
+
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return err
+	}
+	defer tx.rollback()
+
+	fs, err := openFileSystem(mounted, os.O_RDWR)
+	if err != nil {
+		return err
+	}
+	defer fs.Close()
+
+	for _, inodeIndex := range inodes {
+		inode, err := fs.ReadInode(inodeIndex)
+		if err != nil {
+			return err
+		}
+		touchAccess(inode)
+		if err := fs.WriteInode(inodeIndex, inode); err != nil {
+			return fmt.Errorf("error al actualizar el inodo %d: %v", inodeIndex, err)
+		}
+	}
+
+	return tx.commit()
+}
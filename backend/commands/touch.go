@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// touchDateLayouts - Formatos aceptados por touch -date (hora local). Los dos
+// últimos son los del reporte ls.
+var touchDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02/01/2006 15:04",
+	"02/01/2006",
+}
+
+// parseTouchDate - Interpretar el parámetro -date de touch
+func parseTouchDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(strings.Trim(value, "\"'"))
+	for _, layout := range touchDateLayouts {
+		if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, newCommandError(ErrCodeInvalidArgument,
+		"fecha inválida '%s' (use AAAA-MM-DD [HH:MM[:SS]] o DD/MM/AAAA [HH:MM])", value)
+}
+
+// ExecuteTouch - Fijar I_atime e I_mtime de un archivo o carpeta (ahora o la
+// fecha de -date) y crear un archivo vacío si no existe. I_ctime siempre
+// queda con la hora actual porque registra el cambio del inodo.
+func ExecuteTouch(session *Session, path string, date string) (*CommandResult, error) {
+	res := NewCommandResult("touch")
+
+	// Verificar sesión activa
+	if err := RequireActiveSession(session); err != nil {
+		return res, err
+	}
+
+	// Validar parámetros obligatorios
+	if path == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -path es obligatorio para touch")
+	}
+	parsedPath := parsePath(path)
+	if parsedPath == nil || !parsedPath.IsAbsolute {
+		return res, newCommandError(ErrCodeInvalidArgument, "la ruta debe ser absoluta: %s", path)
+	}
+
+	stamp := time.Now()
+	if date != "" {
+		parsed, err := parseTouchDate(date)
+		if err != nil {
+			return res, err
+		}
+		stamp = parsed
+	}
+
+	// Buscar la partición montada de la sesión
+	mounted := GetMountedPartition(session.PartitionID)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró la partición montada con ID '%s'", session.PartitionID)
+	}
+
+	// Los cambios se aplican al disco al confirmar la transacción (journal EXT3)
+	tx, err := beginTransaction(mounted)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al iniciar la transacción")
+	}
+	defer tx.rollback()
+
+	fs, err := openFileSystem(mounted, os.O_RDWR)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el sistema de archivos")
+	}
+	defer fs.Close()
+
+	created := false
+	inodeIndex, inode, err := fs.Lookup(path)
+	if err != nil {
+		// No existe: crear un archivo vacío en la carpeta padre
+		parentInode, name, parentErr := fs.LookupParent(path)
+		if parentErr != nil {
+			return res, wrapCommandError(ErrCodeNotFound, parentErr, "no se encontró la carpeta de '%s'", path)
+		}
+		if _, lookupErr := fs.LookupIn(parentInode, name); lookupErr == nil {
+			// La entrada existe pero no se pudo resolver (p. ej. un enlace roto)
+			return res, wrapCommandError(ErrCodeNotFound, err, "no se pudo resolver '%s'", path)
+		}
+
+		canWrite, err := fs.CanWrite(parentInode, session)
+		if err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al verificar permisos")
+		}
+		if !canWrite {
+			return res, newCommandError(ErrCodePermissionDenied, "sin permisos de escritura en el directorio padre de '%s'", path)
+		}
+
+		if err := fs.enforceSessionQuota(session); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al leer las cuotas")
+		}
+		if inodeIndex, err = fs.CreateFile(parentInode, name, nil, session); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al crear '%s'", path)
+		}
+		if inode, err = fs.ReadInode(inodeIndex); err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al leer el inodo de '%s'", path)
+		}
+		created = true
+	} else {
+		canWrite, err := fs.CanWrite(inodeIndex, session)
+		if err != nil {
+			return res, wrapCommandError(ErrCodeIO, err, "error al verificar permisos")
+		}
+		if !canWrite {
+			return res, newCommandError(ErrCodePermissionDenied, "sin permisos de escritura sobre '%s'", path)
+		}
+	}
+
+	inode.I_atime = stamp.Unix()
+	inode.I_mtime = stamp.Unix()
+	touchChange(inode)
+	if err := fs.WriteInode(inodeIndex, inode); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el inodo de '%s'", path)
+	}
+
+	// Actualizar el superbloque
+	if err := fs.Flush(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
+	}
+
+	if err := WriteJournal(res, mounted, "touch", path, stamp.Format("2006-01-02 15:04:05")); err != nil {
+		res.Printf("⚠️ No se pudo escribir al journal: %v\n", err)
+	}
+
+	// Confirmar los cambios en el journal
+	if err := tx.commit(); err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al confirmar los cambios")
+	}
+
+	if created {
+		res.Printf("✅ Archivo '%s' creado.\n", path)
+	}
+	res.Printf("✅ Fechas de '%s' actualizadas: acceso y modificación %s.\n", path, formatTimestamp(inode.I_mtime))
+	res.AddPath(path)
+	res.Set("inode", inodeIndex)
+	res.Set("created", created)
+	res.Set("atime", inode.I_atime)
+	res.Set("mtime", inode.I_mtime)
+	res.Set("ctime", inode.I_ctime)
+
+	return res, nil
+}
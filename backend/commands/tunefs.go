@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"backend/structs"
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strings"
+)
+
+// CONVERTIR ENTRE EXT2 Y EXT3
+// tunefs -journal=on convierte una partición EXT2 con datos en EXT3 sin
+// formatearla: después del superbloque se abre el espacio del journal de
+// escritura anticipada (el tamaño por defecto de mkfs) y de las 50 entradas del
+// journaling, y bitmaps, inodos y bloques se redistribuyen en lo que queda con
+// relayoutFileSystem (la misma de resizefs, que mueve antes lo que quedaría
+// fuera). -journal=off rehace una transacción confirmada pendiente, quita el
+// journal y devuelve su espacio a las tablas.
+
+// ExecuteTunefs - Activar o quitar el journal de una partición montada
+func ExecuteTunefs(id string, journal string) (*CommandResult, error) {
+	res := NewCommandResult("tunefs")
+
+	if id == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio para tunefs")
+	}
+	journal = strings.ToLower(journal)
+	if journal != "on" && journal != "off" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -journal debe ser 'on' u 'off'")
+	}
+
+	mounted := GetMountedPartition(id)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró ninguna partición montada con ID '%s'", id)
+	}
+
+	// Se reescriben las estructuras de la partición: disco en exclusiva
+	defer lockDisk(mounted.Path)()
+
+	disk, err := OpenDisk(mounted.Path, os.O_RDWR)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "error al abrir el disco")
+	}
+	defer disk.Close()
+
+	partition, err := disk.FindPartition(mounted.Name)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeNotFound, err, "error al buscar la partición en el disco")
+	}
+
+	fs, err := partition.FileSystem()
+	if err != nil || !validMagic(fs.SB) {
+		return res, newCommandError(ErrCodeUnsupported, "la partición '%s' no tiene un sistema de archivos EXT2/EXT3 válido", id)
+	}
+
+	oldType, wantType := fs.SB.S_file_system_type, int64(2)
+	if journal == "on" {
+		wantType = 3
+	}
+	if oldType == wantType {
+		res.Printf("ℹ️  La partición '%s' ya es EXT%d.\n", id, wantType)
+		res.Set("id", id)
+		res.Set("fs", wantType)
+		res.Set("changed", false)
+		return res, nil
+	}
+
+	var summary *resizeSummary
+	if journal == "on" {
+		summary, err = enableJournal(fs)
+	} else {
+		summary, err = disableJournal(fs)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	res.Printf("✅ Partición '%s' convertida de EXT%d a EXT%d.\n", id, oldType, wantType)
+	if journal == "on" {
+		res.Printf("   Journaling: %d entradas\n", mkfsJournalingCount)
+		res.Printf("   Journal de escritura anticipada: %d KB\n", walAreaSize/1024)
+	} else {
+		res.Printf("   Se liberaron el journal y el journaling\n")
+	}
+	res.Printf("   - Inodos: %d → %d\n", summary.OldInodes, summary.NewInodes)
+	res.Printf("   - Bloques: %d → %d\n", summary.OldBlocks, summary.NewBlocks)
+	if summary.MovedInodes > 0 || summary.MovedBlocks > 0 {
+		res.Printf("   🔀 Reubicados para abrir el journal: %d inodos y %d bloques\n", summary.MovedInodes, summary.MovedBlocks)
+	}
+
+	res.AddPath(mounted.Path)
+	res.Set("id", id)
+	res.Set("fs", wantType)
+	res.Set("changed", true)
+	res.Set("inodes", summary.NewInodes)
+	res.Set("blocks", summary.NewBlocks)
+	res.Set("previousInodes", summary.OldInodes)
+	res.Set("previousBlocks", summary.OldBlocks)
+	res.Set("movedInodes", summary.MovedInodes)
+	res.Set("movedBlocks", summary.MovedBlocks)
+
+	return res, nil
+}
+
+// enableJournal - Convertir una partición EXT2 en EXT3. Las tablas se mueven
+// primero sin cambiar el tipo y el superbloque pasa a EXT3 solo cuando el
+// journal ya está inicializado, para que un corte intermedio no deje un EXT3
+// con un journal inválido.
+func enableJournal(fs *FileSystem) (*resizeSummary, error) {
+	if !resizableHeader(fs.Partition.Start, fs.SB) {
+		return nil, newCommandError(ErrCodeUnsupported,
+			"las estructuras del sistema de archivos no corresponden al inicio de la partición (%d)", fs.Partition.Start)
+	}
+
+	superblockSize := int64(binary.Size(structs.SuperBloque{}))
+	journalingSize := mkfsJournalingCount * int64(binary.Size(structs.Journal{}))
+	walStart := fs.Partition.Start + superblockSize
+	tablesStart := walStart + walAreaSize + journalingSize
+
+	summary, err := relayoutFileSystem(fs, tablesStart, fs.Partition.Start+fs.Partition.Size, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeEmptyJournal(fs.file, walStart, walAreaSize); err != nil {
+		return nil, wrapCommandError(ErrCodeIO, err, "error al inicializar el journal")
+	}
+
+	fs.SB.S_file_system_type = 3
+	if err := fs.Flush(); err != nil {
+		return nil, wrapCommandError(ErrCodeIO, err, "error al actualizar el superbloque")
+	}
+	summary.FileSystem = 3
+	return summary, nil
+}
+
+// disableJournal - Convertir una partición EXT3 en EXT2 quitando el journal
+func disableJournal(fs *FileSystem) (*resizeSummary, error) {
+	// Una transacción confirmada que no se alcanzó a aplicar se pierde al
+	// quitar el journal: se rehace antes
+	if layout, ok := walLayoutFor(fs.Partition.Start, fs.SB); ok {
+		if _, err := replayJournal(fs.file, layout); err != nil {
+			return nil, wrapCommandError(ErrCodeIO, err, "error al revisar el journal")
+		}
+		superblock, err := fs.Partition.ReadSuperblock()
+		if err != nil {
+			return nil, wrapCommandError(ErrCodeIO, err, "error al leer el superbloque")
+		}
+		fs.SB = superblock
+	}
+
+	if !resizableHeader(fs.Partition.Start, fs.SB) {
+		return nil, newCommandError(ErrCodeUnsupported,
+			"las estructuras del sistema de archivos no corresponden al inicio de la partición (%d)", fs.Partition.Start)
+	}
+
+	tablesStart := fs.Partition.Start + int64(binary.Size(structs.SuperBloque{}))
+	return relayoutFileSystem(fs, tablesStart, fs.Partition.Start+fs.Partition.Size, 2)
+}
+
+// writeEmptyJournal - Escribir en start un journal de escritura anticipada
+// vacío de walSize bytes seguido de las entradas libres del journaling
+func writeEmptyJournal(file *diskFile, start int64, walSize int64) error {
+	var buffer bytes.Buffer
+	walHeader := structs.WalHeader{WMagic: walMagic, WState: walStateEmpty}
+	if err := binary.Write(&buffer, binary.LittleEndian, &walHeader); err != nil {
+		return err
+	}
+	buffer.Write(make([]byte, walSize-int64(buffer.Len())))
+
+	emptyJournal := structs.Journal{JCount: -1}
+	for i := 0; i < mkfsJournalingCount; i++ {
+		if err := binary.Write(&buffer, binary.LittleEndian, &emptyJournal); err != nil {
+			return err
+		}
+	}
+
+	_, err := file.WriteAt(buffer.Bytes(), start)
+	return err
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// Pruebas de tunefs: una partición con datos pasa de EXT2 a EXT3 y de vuelta
+// sin perder archivos, y cada paso termina con un fsck limpio.
+
+// tunefsTo - Convertir la partición de la imagen y verificar el tipo resultante
+func tunefsTo(t *testing.T, img *crashImage, journal string, fsType int64) {
+	t.Helper()
+	res, err := ExecuteTunefs(img.id, journal)
+	if err != nil {
+		t.Fatalf("tunefs -journal=%s: %v", journal, err)
+	}
+	if changed, _ := res.Data["changed"].(bool); !changed {
+		t.Fatalf("tunefs -journal=%s no cambió la partición", journal)
+	}
+	sb := superblockOf(t, img)
+	if sb.S_file_system_type != fsType {
+		t.Fatalf("tras tunefs -journal=%s el tipo es EXT%d", journal, sb.S_file_system_type)
+	}
+	layout, journaled := walLayoutFor(img.mounted.Start, &sb)
+	if journaled != (fsType == 3) || (journaled && layout.size != walAreaSize) {
+		t.Fatalf("journal tras tunefs -journal=%s: presente %v, %d bytes", journal, journaled, layout.size)
+	}
+	img.checkClean(t, "tunefs -journal="+journal)
+}
+
+func TestTunefsRoundTrip(t *testing.T) {
+	img := newCrashImageFS(t, "2fs")
+	if _, err := ExecuteMkfile(img.session, "/docs/grande.txt", false, 20000, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+	if _, err := ExecuteLn(img.session, "/docs/a.txt", "/enlace", false); err != nil {
+		t.Fatalf("ln: %v", err)
+	}
+	files := map[string]int{"/docs/a.txt": 1500, "/docs/sub/b.txt": 200, "/docs/grande.txt": 20000, "/enlace": 1500}
+
+	for round := 1; round <= 2; round++ {
+		tunefsTo(t, img, "on", 3)
+
+		// Ya es EXT3: no hay nada que hacer
+		res, err := ExecuteTunefs(img.id, "on")
+		if err != nil {
+			t.Fatalf("tunefs repetido: %v", err)
+		}
+		if changed, _ := res.Data["changed"].(bool); changed {
+			t.Fatal("tunefs -journal=on cambió una partición EXT3")
+		}
+
+		// Un comando con el journal activo
+		name := fmt.Sprintf("/ext3_%d.txt", round)
+		if _, err := ExecuteMkfile(img.session, name, false, 3000, ""); err != nil {
+			t.Fatalf("mkfile en EXT3: %v", err)
+		}
+		files[name] = 3000
+		for path, size := range files {
+			checkContent(t, img, path, mkfileContent(size))
+		}
+		img.checkClean(t, "comando en EXT3")
+
+		tunefsTo(t, img, "off", 2)
+		for path, size := range files {
+			checkContent(t, img, path, mkfileContent(size))
+		}
+	}
+}
+
+func TestTunefsRefusesFullPartition(t *testing.T) {
+	img := newCrashImageFS(t, "2fs")
+
+	// Llenar la partición con archivos que quepan completos, de mayor a menor
+	// (con un bloque de sobra por si la raíz necesita otro bloque de carpeta)
+	count := 0
+	for _, size := range []int64{38000, 4000, 640, 64} {
+		for {
+			sb := superblockOf(t, img)
+			fs, err := openFileSystem(img.mounted, os.O_RDONLY)
+			if err != nil {
+				t.Fatal(err)
+			}
+			need := fs.blocksForSize(size)
+			fs.Close()
+			if sb.S_free_blocks_count < need+1 || sb.S_free_inodes_count == 0 {
+				break
+			}
+			if _, err := ExecuteMkfile(img.session, fmt.Sprintf("/r%03d", count), false, int(size), ""); err != nil {
+				t.Fatalf("mkfile: %v", err)
+			}
+			count++
+		}
+	}
+	before := superblockOf(t, img)
+
+	if _, err := ExecuteTunefs(img.id, "on"); ErrorCode(err) != ErrCodeNoSpace {
+		t.Fatalf("tunefs en una partición llena: %v", err)
+	}
+	if after := superblockOf(t, img); after != before {
+		t.Fatal("la partición cambió aunque se rechazó la conversión")
+	}
+	checkContent(t, img, "/docs/a.txt", mkfileContent(1500))
+	img.checkClean(t, "conversión rechazada")
+}
@@ -0,0 +1,582 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SERVIDOR WEBDAV
+// Expone una partición montada por WebDAV (clase 1) para explorarla con
+// cualquier administrador de archivos, sin FUSE. Cada método se traduce a la
+// lógica existente: PROPFIND → GetFilesList, GET → DownloadFile, PUT →
+// UploadFile (mkfile), MKCOL → mkdir, DELETE → remove, MOVE → move/rename y
+// COPY → copy. Cada petición se autentica con HTTP basic contra users.txt de
+// la partición y se ejecuta con una sesión propia (sin token ni registro).
+// users.txt se lee en cada petición, pero la contraseña ya verificada en la
+// conexión no vuelve a pasar por PBKDF2 mientras su hash guardado no cambie.
+// El servidor escucha por defecto solo en 127.0.0.1 y se detiene con
+// stop-webdav o al desmontar la partición.
+
+// webdavHandler - Manejador HTTP de una partición
+type webdavHandler struct {
+	partitionID string
+}
+
+// Servidores WebDAV en marcha por ID de partición (en minúsculas)
+var (
+	webdavMutex   sync.Mutex
+	webdavServers = make(map[string]*http.Server)
+)
+
+func ExecuteServeWebDAV(id string, port string, bind string) (*CommandResult, error) {
+	res := NewCommandResult("serve-webdav")
+
+	// Validar parámetros obligatorios
+	if id == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio para serve-webdav")
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return res, newCommandError(ErrCodeInvalidArgument, "puerto inválido '%s'", port)
+	}
+
+	// Por defecto solo se atienden conexiones locales
+	if bind == "" {
+		bind = "127.0.0.1"
+	}
+
+	mounted := GetMountedPartition(id)
+	if mounted == nil {
+		return res, newCommandError(ErrCodeNotMounted, "no se encontró ninguna partición montada con ID '%s'", id)
+	}
+
+	webdavMutex.Lock()
+	defer webdavMutex.Unlock()
+	key := strings.ToLower(mounted.ID)
+	if _, running := webdavServers[key]; running {
+		return res, newCommandError(ErrCodeAlreadyExists, "la partición '%s' ya tiene un servidor WebDAV (use stop-webdav)", mounted.ID)
+	}
+
+	// Se escucha antes de devolver para reportar un puerto ocupado
+	address := net.JoinHostPort(bind, port)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return res, wrapCommandError(ErrCodeIO, err, "no se pudo escuchar en %s", address)
+	}
+
+	server := &http.Server{
+		Handler: &webdavHandler{partitionID: mounted.ID},
+		ConnContext: func(ctx context.Context, _ net.Conn) context.Context {
+			return context.WithValue(ctx, davCredentialsKey{}, &davCredentials{})
+		},
+	}
+	webdavServers[key] = server
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("⚠️ Servidor WebDAV de '%s' detenido: %v\n", mounted.ID, err)
+		}
+	}()
+
+	res.Printf("🌐 Partición '%s' disponible por WebDAV en http://%s/\n", mounted.ID, address)
+	res.Printf("   Use las credenciales de users.txt de la partición (autenticación básica).\n")
+	res.Printf("   Para detenerlo: stop-webdav -id=%s (o unmount)\n", mounted.ID)
+	res.Set("id", mounted.ID)
+	res.Set("port", port)
+	res.Set("bind", bind)
+
+	return res, nil
+}
+
+// ExecuteStopWebDAV - Detener el servidor WebDAV de una partición
+func ExecuteStopWebDAV(id string) (*CommandResult, error) {
+	res := NewCommandResult("stop-webdav")
+
+	if id == "" {
+		return res, newCommandError(ErrCodeInvalidArgument, "el parámetro -id es obligatorio para stop-webdav")
+	}
+	if !stopWebDAV(id) {
+		return res, newCommandError(ErrCodeNotFound, "la partición '%s' no tiene un servidor WebDAV en marcha", id)
+	}
+
+	res.Printf("🛑 Servidor WebDAV de '%s' detenido.\n", id)
+	res.Set("id", id)
+	return res, nil
+}
+
+// stopWebDAV - Cerrar el servidor de la partición y sus conexiones; false si
+// no había uno
+func stopWebDAV(id string) bool {
+	webdavMutex.Lock()
+	defer webdavMutex.Unlock()
+
+	key := strings.ToLower(id)
+	server, running := webdavServers[key]
+	if !running {
+		return false
+	}
+	delete(webdavServers, key)
+	server.Close()
+	return true
+}
+
+func (h *webdavHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	session, err := h.authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=\"MIA %s\"", h.partitionID))
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	filePath := davPath(r.URL.Path)
+
+	switch r.Method {
+	case "OPTIONS":
+		w.Header().Set("DAV", "1")
+		w.Header().Set("Allow", "OPTIONS, PROPFIND, GET, HEAD, PUT, MKCOL, DELETE, MOVE, COPY")
+		w.WriteHeader(http.StatusOK)
+	case "PROPFIND":
+		h.propfind(w, r, session, filePath)
+	case "GET", "HEAD":
+		h.get(w, r, session, filePath)
+	case "PUT":
+		h.put(w, r, session, filePath)
+	case "MKCOL":
+		h.mkcol(w, r, session, filePath)
+	case "DELETE":
+		h.delete(w, session, filePath)
+	case "MOVE", "COPY":
+		h.moveOrCopy(w, r, session, filePath)
+	default:
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+	}
+}
+
+// authenticate - Sesión para la petición a partir de las credenciales básicas
+func (h *webdavHandler) authenticate(r *http.Request) (*Session, error) {
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return nil, fmt.Errorf("se requiere usuario y contraseña")
+	}
+
+	mounted := GetMountedPartition(h.partitionID)
+	if mounted == nil {
+		return nil, fmt.Errorf("la partición '%s' ya no está montada", h.partitionID)
+	}
+
+	verify := checkPassword
+	if credentials, ok := r.Context().Value(davCredentialsKey{}).(*davCredentials); ok {
+		verify = credentials.verify
+	}
+	// WebDAV no muestra la salida de texto: si no se pudo guardar el hash de la
+	// contraseña se vuelve a intentar en el siguiente inicio de sesión
+	userInfo, isRoot, err := checkCredentialsWith(NewCommandResult("webdav"), mounted, user, pass, verify)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		User:        user,
+		Group:       userInfo.GroupName,
+		PartitionID: mounted.ID,
+		UID:         userInfo.UID,
+		GID:         userInfo.GID,
+		IsActive:    true,
+		IsRoot:      isRoot,
+	}, nil
+}
+
+// davCredentialsKey - Clave de las credenciales de la conexión en el contexto
+type davCredentialsKey struct{}
+
+// davCredentials - Última contraseña verificada en una conexión. Solo se
+// guarda un HMAC de la contraseña junto con el campo de users.txt contra el
+// que se verificó: si el campo cambia (passwd, rmusr) se vuelve a verificar.
+type davCredentials struct {
+	mutex  sync.Mutex
+	stored string
+	digest []byte
+}
+
+// davCredentialsSecret - Clave del HMAC, nueva en cada ejecución del backend
+var davCredentialsSecret = func() []byte {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}()
+
+// verify - checkPassword, salvo que ya se haya verificado en la conexión
+func (c *davCredentials) verify(stored, password string) bool {
+	mac := hmac.New(sha256.New, davCredentialsSecret)
+	mac.Write([]byte(password))
+	digest := mac.Sum(nil)
+
+	c.mutex.Lock()
+	cached := c.stored == stored && hmac.Equal(c.digest, digest)
+	c.mutex.Unlock()
+	if cached {
+		return true
+	}
+
+	if !checkPassword(stored, password) {
+		return false
+	}
+	c.mutex.Lock()
+	c.stored, c.digest = stored, digest
+	c.mutex.Unlock()
+	return true
+}
+
+// davPath - Ruta de la partición a partir de la ruta de la URL
+func davPath(urlPath string) string {
+	return path.Clean("/" + urlPath)
+}
+
+// davReply - Responder el resultado de un comando: el estado indicado si no
+// hubo error o el estado HTTP que corresponde a su código
+func davReply(w http.ResponseWriter, err error, status int) {
+	if err != nil {
+		http.Error(w, err.Error(), davErrorStatus(err))
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// davStatError - Responder el error de buscar un recurso: 404 si no existe
+func davStatError(w http.ResponseWriter, err error) {
+	status := davErrorStatus(err)
+	if ErrorCode(err) == ErrCodeNotFound {
+		status = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// davErrorStatus - Código HTTP (RFC 4918) según el código de error del comando
+func davErrorStatus(err error) int {
+	switch ErrorCode(err) {
+	case ErrCodeNoSession:
+		return http.StatusUnauthorized
+	case ErrCodePermissionDenied:
+		return http.StatusForbidden
+	case ErrCodeNotFound:
+		// La carpeta padre no existe
+		return http.StatusConflict
+	case ErrCodeNotMounted:
+		return http.StatusServiceUnavailable
+	case ErrCodeAlreadyExists:
+		return http.StatusPreconditionFailed
+	case ErrCodeInvalidArgument:
+		return http.StatusBadRequest
+	case ErrCodeUnsupported:
+		return http.StatusNotImplemented
+	case ErrCodeNoSpace, ErrCodeQuotaExceeded:
+		return http.StatusInsufficientStorage
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Respuesta multistatus de PROPFIND
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"D:multistatus"`
+	Namespace string        `xml:"xmlns:D,attr"`
+	Responses []davResponse `xml:"D:response"`
+}
+
+type davResponse struct {
+	Href   string  `xml:"D:href"`
+	Prop   davProp `xml:"D:propstat>D:prop"`
+	Status string  `xml:"D:propstat>D:status"`
+}
+
+type davProp struct {
+	DisplayName   string          `xml:"D:displayname"`
+	ResourceType  davResourceType `xml:"D:resourcetype"`
+	ContentLength *int64          `xml:"D:getcontentlength,omitempty"`
+	ContentType   string          `xml:"D:getcontenttype,omitempty"`
+	LastModified  string          `xml:"D:getlastmodified,omitempty"`
+}
+
+type davResourceType struct {
+	Collection *struct{} `xml:"D:collection,omitempty"`
+}
+
+// davEntry - Respuesta de PROPFIND para un archivo o carpeta
+func davEntry(filePath string, node FileNode) davResponse {
+	href := (&url.URL{Path: filePath}).EscapedPath()
+	entry := davResponse{
+		Prop: davProp{
+			DisplayName: node.Name,
+		},
+		Status: "HTTP/1.1 200 OK",
+	}
+	if node.Modified > 0 {
+		entry.Prop.LastModified = time.Unix(node.Modified, 0).UTC().Format(http.TimeFormat)
+	}
+
+	if node.Type == "folder" {
+		if !strings.HasSuffix(href, "/") {
+			href += "/"
+		}
+		entry.Prop.ResourceType.Collection = &struct{}{}
+	} else {
+		size := node.Size
+		entry.Prop.ContentLength = &size
+		entry.Prop.ContentType = "application/octet-stream"
+	}
+	entry.Href = href
+	return entry
+}
+
+// propfind - Propiedades del recurso y, con Depth 1, de su contenido
+func (h *webdavHandler) propfind(w http.ResponseWriter, r *http.Request, session *Session, filePath string) {
+	mounted := GetMountedPartition(h.partitionID)
+	if mounted == nil {
+		http.Error(w, "partición no montada", http.StatusServiceUnavailable)
+		return
+	}
+
+	node, err := StatPath(session, mounted, filePath)
+	if err != nil {
+		davStatError(w, err)
+		return
+	}
+
+	status := davMultistatus{Namespace: "DAV:"}
+	status.Responses = append(status.Responses, davEntry(filePath, node))
+
+	// Depth "infinity" se atiende como 1: basta para explorar carpeta por carpeta
+	if node.Type == "folder" && r.Header.Get("Depth") != "0" {
+		children, err := GetFilesList(session, mounted, filePath)
+		if err != nil {
+			davStatError(w, err)
+			return
+		}
+		for _, child := range children {
+			status.Responses = append(status.Responses, davEntry(path.Join(filePath, child.Name), child))
+		}
+	}
+
+	var body bytes.Buffer
+	body.WriteString(xml.Header)
+	if err := xml.NewEncoder(&body).Encode(status); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	w.Write(body.Bytes())
+}
+
+// get - Contenido de un archivo (HEAD solo envía las cabeceras)
+func (h *webdavHandler) get(w http.ResponseWriter, r *http.Request, session *Session, filePath string) {
+	mounted := GetMountedPartition(h.partitionID)
+	if mounted == nil {
+		http.Error(w, "partición no montada", http.StatusServiceUnavailable)
+		return
+	}
+
+	node, err := StatPath(session, mounted, filePath)
+	if err != nil {
+		davStatError(w, err)
+		return
+	}
+	if node.Type == "folder" {
+		http.Error(w, "no se puede descargar una carpeta", http.StatusMethodNotAllowed)
+		return
+	}
+	if node.Modified > 0 {
+		w.Header().Set("Last-Modified", time.Unix(node.Modified, 0).UTC().Format(http.TimeFormat))
+	}
+
+	started := false
+	_, err = DownloadFile(session, h.partitionID, filePath, func(size int64) io.Writer {
+		started = true
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == "HEAD" {
+			return io.Discard
+		}
+		return w
+	})
+	if err != nil && !started {
+		status := davErrorStatus(err)
+		if ErrorCode(err) == ErrCodeNotFound {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+	}
+}
+
+// put - Crear o reemplazar un archivo; la carpeta padre debe existir
+func (h *webdavHandler) put(w http.ResponseWriter, r *http.Request, session *Session, filePath string) {
+	status := http.StatusCreated
+	if mounted := GetMountedPartition(h.partitionID); mounted != nil {
+		if _, err := statPath(mounted, filePath, nil); err == nil {
+			status = http.StatusNoContent
+		}
+	}
+
+	_, err := UploadFile(session, h.partitionID, filePath, false, r.ContentLength, r.Body)
+	davReply(w, err, status)
+}
+
+// delete - Eliminar un archivo o una carpeta con su contenido
+func (h *webdavHandler) delete(w http.ResponseWriter, session *Session, filePath string) {
+	mounted := GetMountedPartition(h.partitionID)
+	if mounted == nil {
+		http.Error(w, "partición no montada", http.StatusServiceUnavailable)
+		return
+	}
+	if _, err := statPath(mounted, filePath, nil); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	_, err := ExecuteRemove(session, filePath)
+	davReply(w, err, http.StatusNoContent)
+}
+
+// mkcol - Crear una carpeta; la carpeta padre debe existir
+func (h *webdavHandler) mkcol(w http.ResponseWriter, r *http.Request, session *Session, filePath string) {
+	if r.ContentLength > 0 {
+		http.Error(w, "MKCOL no admite cuerpo", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	res, err := ExecuteMkdir(session, filePath, false)
+	if err == nil {
+		if created, _ := res.Data["created"].(bool); !created {
+			http.Error(w, "el recurso ya existe", http.StatusMethodNotAllowed)
+			return
+		}
+	}
+	davReply(w, err, http.StatusCreated)
+}
+
+// moveOrCopy - MOVE y COPY hacia la ruta de la cabecera Destination. move y
+// copy llevan el elemento a otra carpeta con el mismo nombre, así que el
+// cambio de nombre se hace con rename.
+func (h *webdavHandler) moveOrCopy(w http.ResponseWriter, r *http.Request, session *Session, srcPath string) {
+	destination, err := url.Parse(r.Header.Get("Destination"))
+	if err != nil || destination.Path == "" {
+		http.Error(w, "cabecera Destination inválida", http.StatusBadRequest)
+		return
+	}
+	destPath := davPath(destination.Path)
+	if srcPath == "/" || destPath == "/" || srcPath == destPath {
+		http.Error(w, "origen o destino inválido", http.StatusForbidden)
+		return
+	}
+
+	mounted := GetMountedPartition(h.partitionID)
+	if mounted == nil {
+		http.Error(w, "partición no montada", http.StatusServiceUnavailable)
+		return
+	}
+	source, err := statPath(mounted, srcPath, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	srcDir, srcName := path.Split(srcPath)
+	destDir, destName := path.Split(destPath)
+	srcDir, destDir = path.Clean(srcDir), path.Clean(destDir)
+
+	// Overwrite: T (por defecto) reemplaza el destino existente. Se mueve o
+	// copia primero con un nombre temporal y el destino se quita solo si eso
+	// funcionó; si no se puede quitar, se deshace.
+	status := http.StatusCreated
+	finalName := destName
+	if _, err := statPath(mounted, destPath, nil); err == nil {
+		if r.Header.Get("Overwrite") == "F" {
+			http.Error(w, "el destino ya existe", http.StatusPreconditionFailed)
+			return
+		}
+		status = http.StatusNoContent
+		destName = fmt.Sprintf("~dav%08x", uint32(time.Now().UnixNano()))
+	}
+
+	if r.Method == "MOVE" {
+		err = davMove(session, srcPath, destDir, destName)
+	} else {
+		err = h.davCopy(session, srcPath, source, destDir, destName)
+	}
+	if err == nil && destName != finalName {
+		tempPath := path.Join(destDir, destName)
+		if _, err = ExecuteRemove(session, destPath); err != nil {
+			if r.Method == "MOVE" {
+				davMove(session, tempPath, srcDir, srcName)
+			} else {
+				ExecuteRemove(session, tempPath)
+			}
+		} else {
+			_, err = ExecuteRename(session, tempPath, finalName)
+		}
+	}
+	davReply(w, err, status)
+}
+
+// davMove - Llevar srcPath a destDir con el nombre destName. move conserva el
+// nombre, así que para cambiar de carpeta y de nombre se renombra y luego se
+// mueve; si el movimiento falla se devuelve el nombre original.
+func davMove(session *Session, srcPath string, destDir string, destName string) error {
+	srcDir, srcName := path.Split(srcPath)
+	srcDir = path.Clean(srcDir)
+
+	var err error
+	switch {
+	case srcDir == destDir:
+		_, err = ExecuteRename(session, srcPath, destName)
+	case srcName == destName:
+		_, err = ExecuteMove(session, srcPath, destDir)
+	default:
+		if _, err = ExecuteRename(session, srcPath, destName); err != nil {
+			return err
+		}
+		renamed := path.Join(srcDir, destName)
+		if _, err = ExecuteMove(session, renamed, destDir); err != nil {
+			if _, undoErr := ExecuteRename(session, renamed, srcName); undoErr != nil {
+				return wrapCommandError(ErrorCode(err), err, "no se pudo devolver '%s' a su nombre original (%v)", renamed, undoErr)
+			}
+		}
+	}
+	return err
+}
+
+// davCopy - Copiar srcPath a destDir con el nombre destName
+func (h *webdavHandler) davCopy(session *Session, srcPath string, source FileNode, destDir string, destName string) error {
+	srcDir, srcName := path.Split(srcPath)
+	srcDir = path.Clean(srcDir)
+
+	var err error
+	switch {
+	case srcDir != destDir && srcName == destName:
+		_, err = ExecuteCopy(session, srcPath, destDir)
+	case source.Type == "file":
+		// Copia con otro nombre: se lee el archivo y se escribe como con mkfile
+		var content bytes.Buffer
+		_, err = DownloadFile(session, h.partitionID, srcPath, func(int64) io.Writer { return &content })
+		if err == nil {
+			_, err = UploadFile(session, h.partitionID, path.Join(destDir, destName), false, int64(content.Len()), &content)
+		}
+	default:
+		err = newCommandError(ErrCodeUnsupported, "copiar una carpeta con otro nombre no está soportado")
+	}
+	return err
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Pruebas del servidor WebDAV contra el manejador, sin abrir un puerto.
+
+// davRequest - Ejecutar una petición autenticada y devolver el código HTTP
+func davRequest(t *testing.T, img *crashImage, user, pass, method, target string, headers map[string]string) int {
+	t.Helper()
+	handler := &webdavHandler{partitionID: img.id}
+	r := httptest.NewRequest(method, target, nil)
+	r.SetBasicAuth(user, pass)
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestWebDAVMoveOverwrite(t *testing.T) {
+	img := newCrashImage(t)
+	if _, err := ExecuteMkfile(img.session, "/otro/x.txt", false, 10, ""); err != nil {
+		t.Fatalf("mkfile: %v", err)
+	}
+
+	// Reemplazar un archivo existente con otro de otra carpeta y otro nombre
+	code := davRequest(t, img, "root", "123", "MOVE", "/docs/a.txt", map[string]string{"Destination": "/otro/x.txt"})
+	if code != http.StatusNoContent {
+		t.Fatalf("MOVE: código %d", code)
+	}
+	node, err := statPath(img.mounted, "/otro/x.txt", nil)
+	if err != nil || node.Size != 1500 {
+		t.Fatalf("el destino no tiene el archivo movido: %+v %v", node, err)
+	}
+	if _, err := statPath(img.mounted, "/docs/a.txt", nil); err == nil {
+		t.Fatal("el origen sigue existiendo")
+	}
+
+	// Un movimiento imposible (una carpeta dentro de sí misma) no debe
+	// borrar el destino ni dejar el origen con el nombre temporal
+	code = davRequest(t, img, "root", "123", "MOVE", "/docs", map[string]string{"Destination": "/docs/sub"})
+	if code < 400 {
+		t.Fatalf("MOVE de una carpeta dentro de sí misma: código %d", code)
+	}
+	for _, p := range []string{"/docs", "/docs/sub", "/docs/sub/b.txt"} {
+		if _, err := statPath(img.mounted, p, nil); err != nil {
+			t.Fatalf("'%s' se perdió: %v", p, err)
+		}
+	}
+	root, err := GetFilesList(img.session, img.mounted, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range root {
+		if strings.HasPrefix(node.Name, "~dav") {
+			t.Fatalf("quedó el nombre temporal '%s'", node.Name)
+		}
+	}
+	img.checkClean(t, "después de MOVE")
+}
+
+func TestWebDAVPropfindChecksReadPermission(t *testing.T) {
+	img := newCrashImage(t)
+	if _, err := ExecuteMkgrp(img.session, "devs"); err != nil {
+		t.Fatalf("mkgrp: %v", err)
+	}
+	if _, err := ExecuteMkusr(img.session, "ana", "clave", "devs"); err != nil {
+		t.Fatalf("mkusr: %v", err)
+	}
+	if _, err := ExecuteChmod(img.session, "/docs", false, "000"); err != nil {
+		t.Fatalf("chmod: %v", err)
+	}
+
+	depth := map[string]string{"Depth": "1"}
+	if code := davRequest(t, img, "ana", "clave", "PROPFIND", "/docs", depth); code != http.StatusForbidden {
+		t.Fatalf("PROPFIND de una carpeta sin lectura: código %d", code)
+	}
+	if code := davRequest(t, img, "ana", "clave", "PROPFIND", "/docs/sub", map[string]string{"Depth": "0"}); code != http.StatusForbidden {
+		t.Fatalf("PROPFIND dentro de una carpeta sin lectura: código %d", code)
+	}
+	if code := davRequest(t, img, "root", "123", "PROPFIND", "/docs", depth); code != http.StatusMultiStatus {
+		t.Fatalf("PROPFIND de root: código %d", code)
+	}
+}
+
+func TestWebDAVStopsOnUnmount(t *testing.T) {
+	img := newCrashImage(t)
+
+	res, err := ExecuteServeWebDAV(img.id, "0", "")
+	if err != nil {
+		t.Fatalf("serve-webdav: %v", err)
+	}
+	if bind, _ := res.Data["bind"].(string); bind != "127.0.0.1" {
+		t.Fatalf("escucha en '%s' en vez de 127.0.0.1", bind)
+	}
+	if _, err := ExecuteServeWebDAV(img.id, "0", ""); ErrorCode(err) != ErrCodeAlreadyExists {
+		t.Fatalf("segundo servidor para la misma partición: %v", err)
+	}
+
+	if _, err := ExecuteUnmount(img.id); err != nil {
+		t.Fatalf("unmount: %v", err)
+	}
+	if _, err := ExecuteStopWebDAV(img.id); ErrorCode(err) != ErrCodeNotFound {
+		t.Fatalf("el servidor sigue en marcha después de unmount: %v", err)
+	}
+}
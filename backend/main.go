@@ -7,10 +7,14 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"path"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Estructuras para la API HTTP
@@ -18,18 +22,35 @@ type CommandRequest struct {
 	Command string `json:"command"`
 }
 
+// CommandResponse - Respuesta de /execute. Code es un código de error estable
+// (ver commands.ErrCode*); Paths y Data describen el efecto del comando y
+// Output conserva el texto para humanos.
 type CommandResponse struct {
-	Success bool   `json:"success"`
-	Output  string `json:"output"`
-	Error   string `json:"error,omitempty"`
+	Success bool                   `json:"success"`
+	Command string                 `json:"command,omitempty"`
+	Code    string                 `json:"code,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+	Paths   []string               `json:"paths,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Output  string                 `json:"output,omitempty"`
+	Token   string                 `json:"token,omitempty"` // Token de la sesión creada por login
 }
 
+// autoSessionEnabled - Con -auto-session los comandos de /execute y
+// /execute/script sin token usan la sesión automática con permisos root; si
+// no, los que requieren sesión fallan con NO_SESSION.
+var autoSessionEnabled bool
+
 func main() {
 	// Flag para determinar si ejecutar en modo servidor HTTP o CLI
 	serverMode := flag.Bool("server", false, "Ejecutar en modo servidor HTTP")
 	port := flag.String("port", "8080", "Puerto para el servidor HTTP")
+	flag.BoolVar(&autoSessionEnabled, "auto-session", false, "Ejecutar como root los comandos HTTP sin token (solo para pruebas locales)")
 	flag.Parse()
 
+	// Restaurar las particiones montadas antes del último reinicio
+	commands.RestoreMountedPartitions()
+
 	if *serverMode {
 		startHTTPServer(*port)
 	} else {
@@ -41,7 +62,10 @@ func main() {
 func startHTTPServer(port string) {
 	// Configurar CORS
 	http.HandleFunc("/execute", corsMiddleware(executeCommandHandler))
+	http.HandleFunc("/execute/script", corsMiddleware(executeScriptHandler))
 	http.HandleFunc("/health", corsMiddleware(healthHandler))
+	http.HandleFunc("/login", corsMiddleware(loginHandler))
+	http.HandleFunc("/logout", corsMiddleware(logoutHandler))
 
 	// Ruta para obtener la lista de discos
 	http.HandleFunc("/disks", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
@@ -76,7 +100,7 @@ func startHTTPServer(port string) {
 	// Ruta para obtener información de la sesión actual (para que el frontend pueda sincronizar)
 	http.HandleFunc("/session", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		info := commands.GetSessionInfo()
+		info := commands.GetSessionInfo(sessionFromRequest(r))
 		response := map[string]interface{}{
 			"success": true,
 			"session": info,
@@ -89,6 +113,8 @@ func startHTTPServer(port string) {
 	http.HandleFunc("/journaling/repair", corsMiddleware(journalingRepairHandler))
 	http.HandleFunc("/journaling/dump", corsMiddleware(journalingDumpHandler))
 	http.HandleFunc("/file/read", corsMiddleware(readFileHandler))
+	http.HandleFunc("/file", corsMiddleware(fileHandler))
+	http.HandleFunc("/export", corsMiddleware(exportHandler))
 
 	// ***
 	// *** CAMBIO REALIZADO AQUÍ ***
@@ -111,8 +137,8 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Configurar headers CORS
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, PUT, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Session-Token")
 
 		// Responder a OPTIONS request (preflight)
 		if r.Method == "OPTIONS" {
@@ -146,7 +172,7 @@ func readFileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validar sesión
-	session := commands.GetCurrentSession()
+	session := sessionFromRequest(r)
 	if session == nil || session.User == "" {
 		response := map[string]interface{}{
 			"success": false,
@@ -168,8 +194,18 @@ func readFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Los usuarios que no son root solo acceden a la partición de su sesión
+	if err := commands.ValidatePartitionAccess(session, mountedPartition.ID); err != nil {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+		sendJSONResponse(w, response, http.StatusForbidden)
+		return
+	}
+
 	// Leer el contenido del archivo
-	content, err := commands.ReadFileByPath(mountedPartition, req.Path)
+	res, err := commands.ReadFileByPath(mountedPartition, req.Path)
 	if err != nil {
 		response := map[string]interface{}{
 			"success": false,
@@ -179,14 +215,163 @@ func readFileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Los archivos binarios (NUL, UTF-8 inválido) se envían en base64
+	content, _ := res.Data["content"].([]byte)
 	response := map[string]interface{}{
-		"success": true,
-		"content": content,
-		"path":    req.Path,
+		"success":  true,
+		"content":  string(content),
+		"encoding": "utf-8",
+		"size":     len(content),
+		"path":     req.Path,
+	}
+	if atimeError, ok := res.Data["atimeError"]; ok {
+		response["atimeError"] = atimeError
+	}
+	if !commands.IsTextContent(content) {
+		response["content"] = base64.StdEncoding.EncodeToString(content)
+		response["encoding"] = "base64"
 	}
 	sendJSONResponse(w, response, http.StatusOK)
 }
 
+// Handler para subir (PUT) o descargar (GET) un archivo como bytes, sin JSON.
+// Parámetros: ?path=/ruta/archivo, ?id= (opcional, partición; por defecto la
+// de la sesión) y en PUT ?r=true para crear las carpetas padre.
+func fileHandler(w http.ResponseWriter, r *http.Request) {
+	session := sessionFromRequest(r)
+	if session == nil || session.User == "" {
+		response := CommandResponse{
+			Success: false,
+			Code:    commands.ErrCodeNoSession,
+			Error:   "Debe iniciar sesión primero",
+		}
+		sendJSONResponse(w, response, http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	filePath := query.Get("path")
+	if filePath == "" {
+		response := CommandResponse{
+			Success: false,
+			Code:    commands.ErrCodeInvalidArgument,
+			Error:   "el parámetro path es obligatorio",
+		}
+		sendJSONResponse(w, response, http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		started := false
+		_, err := commands.DownloadFile(session, query.Get("id"), filePath, func(size int64) io.Writer {
+			started = true
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", path.Base(filePath)))
+			return w
+		})
+		if err != nil && started {
+			// Las cabeceras ya se enviaron: solo se puede cortar la respuesta
+			fmt.Printf("⚠️ Descarga de '%s' interrumpida: %v\n", filePath, err)
+			return
+		}
+		if err != nil {
+			response := CommandResponse{
+				Success: false,
+				Code:    commands.ErrorCode(err),
+				Error:   err.Error(),
+			}
+			sendJSONResponse(w, response, fileErrorStatus(err))
+		}
+
+	case "PUT":
+		recursive := query.Get("r") == "true"
+		res, err := commands.UploadFile(session, query.Get("id"), filePath, recursive, r.ContentLength, r.Body)
+		response := CommandResponse{
+			Success: err == nil,
+			Command: res.Command,
+			Paths:   res.Paths,
+			Data:    res.Data,
+			Output:  res.Output(),
+		}
+		status := http.StatusOK
+		if err != nil {
+			response.Error = err.Error()
+			response.Code = commands.ErrorCode(err)
+			status = fileErrorStatus(err)
+		}
+		sendJSONResponse(w, response, status)
+
+	default:
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+	}
+}
+
+// exportHandler - GET /export?path=...&id=...: subárbol de la partición como archivo tar
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session := sessionFromRequest(r)
+	if session == nil || session.User == "" {
+		response := CommandResponse{
+			Success: false,
+			Code:    commands.ErrCodeNoSession,
+			Error:   "Debe iniciar sesión primero",
+		}
+		sendJSONResponse(w, response, http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	exportPath := query.Get("path")
+	if exportPath == "" {
+		exportPath = "/"
+	}
+
+	started := false
+	_, err := commands.ExportTar(session, query.Get("id"), exportPath, func(name string) io.Writer {
+		started = true
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		return w
+	})
+	if err != nil && started {
+		// Las cabeceras ya se enviaron: solo se puede cortar la respuesta
+		fmt.Printf("⚠️ Exportación de '%s' interrumpida: %v\n", exportPath, err)
+		return
+	}
+	if err != nil {
+		response := CommandResponse{
+			Success: false,
+			Code:    commands.ErrorCode(err),
+			Error:   err.Error(),
+		}
+		sendJSONResponse(w, response, fileErrorStatus(err))
+	}
+}
+
+// fileErrorStatus - Código HTTP según el código de error del comando
+func fileErrorStatus(err error) int {
+	switch commands.ErrorCode(err) {
+	case commands.ErrCodeNoSession:
+		return http.StatusUnauthorized
+	case commands.ErrCodePermissionDenied:
+		return http.StatusForbidden
+	case commands.ErrCodeNotFound, commands.ErrCodeNotMounted:
+		return http.StatusNotFound
+	case commands.ErrCodeInvalidArgument, commands.ErrCodeUnsupported:
+		return http.StatusBadRequest
+	case commands.ErrCodeNoSpace, commands.ErrCodeQuotaExceeded:
+		return http.StatusInsufficientStorage
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // Handler para obtener el journaling
 func journalingHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
@@ -208,7 +393,7 @@ func journalingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validar sesión
-	session := commands.GetCurrentSession()
+	session := sessionFromRequest(r)
 	if session == nil || session.User == "" {
 		response := map[string]interface{}{
 			"success": false,
@@ -230,6 +415,16 @@ func journalingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Los usuarios que no son root solo acceden a la partición de su sesión
+	if err := commands.ValidatePartitionAccess(session, mountedPartition.ID); err != nil {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+		sendJSONResponse(w, response, http.StatusForbidden)
+		return
+	}
+
 	// Obtener el journaling
 	entries, err := commands.GetJournaling(mountedPartition)
 	if err != nil {
@@ -271,7 +466,7 @@ func journalingRepairHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validar sesión
-	session := commands.GetCurrentSession()
+	session := sessionFromRequest(r)
 	if session == nil || session.User == "" {
 		response := map[string]interface{}{
 			"success": false,
@@ -291,6 +486,16 @@ func journalingRepairHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Los usuarios que no son root solo acceden a la partición de su sesión
+	if err := commands.ValidatePartitionAccess(session, mountedPartition.ID); err != nil {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+		sendJSONResponse(w, response, http.StatusForbidden)
+		return
+	}
+
 	count, err := commands.RepairJournal(mountedPartition)
 	if err != nil {
 		response := map[string]interface{}{
@@ -330,7 +535,7 @@ func journalingDumpHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validar sesión (mantener requisito para seguridad)
-	session := commands.GetCurrentSession()
+	session := sessionFromRequest(r)
 	if session == nil || session.User == "" {
 		response := map[string]interface{}{
 			"success": false,
@@ -350,6 +555,16 @@ func journalingDumpHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Los usuarios que no son root solo acceden a la partición de su sesión
+	if err := commands.ValidatePartitionAccess(session, mountedPartition.ID); err != nil {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+		sendJSONResponse(w, response, http.StatusForbidden)
+		return
+	}
+
 	rawMap, err := commands.DumpJournalRegions(mountedPartition)
 	if err != nil {
 		response := map[string]interface{}{
@@ -395,23 +610,203 @@ func executeCommandHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Capturar la salida del comando
-	output, err := executeCommandFromHTTP(req.Command)
+	// Sin token el comando corre sin sesión (o con la automática si el
+	// servidor se inició con -auto-session); un login la reemplaza.
+	session := sessionFromRequest(r)
+	ctx := &commandContext{Session: session}
+	if ctx.Session == nil && autoSessionEnabled {
+		ctx.Session = commands.NewAutoSession()
+	}
 
+	res, output, err := executeCommandFromHTTP(ctx, req.Command)
+
+	// Devolver el token si el comando inició una sesión nueva
+	token := ""
+	if ctx.Session != nil && ctx.Session != session && !commands.IsAutoSession(ctx.Session) {
+		token = ctx.Session.Token
+	}
+
+	response := CommandResponse{
+		Success: err == nil,
+		Output:  output,
+		Token:   token,
+	}
+	if res != nil {
+		response.Command = res.Command
+		response.Paths = res.Paths
+		response.Data = res.Data
+	}
 	if err != nil {
-		response := CommandResponse{
-			Success: false,
-			Output:  output,
-			Error:   err.Error(),
+		response.Error = err.Error()
+		response.Code = commands.ErrorCode(err)
+	}
+	sendJSONResponse(w, response, http.StatusOK)
+}
+
+// Handler para ejecutar un script .smia completo en una sola petición
+func executeScriptHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Script      string `json:"script"` // Contenido del script
+		Path        string `json:"path"`   // o ruta del script en el servidor
+		StopOnError bool   `json:"stopOnError"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := map[string]interface{}{
+			"success": false,
+			"code":    commands.ErrCodeInvalidArgument,
+			"error":   "Error al decodificar la petición: " + err.Error(),
 		}
-		sendJSONResponse(w, response, http.StatusOK)
-	} else {
-		response := CommandResponse{
-			Success: true,
-			Output:  output,
+		sendJSONResponse(w, response, http.StatusBadRequest)
+		return
+	}
+
+	content := req.Script
+	if content == "" && req.Path != "" {
+		data, err := os.ReadFile(req.Path)
+		if err != nil {
+			response := map[string]interface{}{
+				"success": false,
+				"code":    commands.ErrCodeNotFound,
+				"error":   fmt.Sprintf("no se pudo leer el script '%s': %v", req.Path, err),
+			}
+			sendJSONResponse(w, response, http.StatusOK)
+			return
 		}
-		sendJSONResponse(w, response, http.StatusOK)
+		content = string(data)
 	}
+	if strings.TrimSpace(content) == "" {
+		response := map[string]interface{}{
+			"success": false,
+			"code":    commands.ErrCodeInvalidArgument,
+			"error":   "Debe enviar el contenido del script (script) o su ruta (path)",
+		}
+		sendJSONResponse(w, response, http.StatusBadRequest)
+		return
+	}
+
+	// Igual que /execute: sin token solo se usa la sesión automática con -auto-session
+	session := sessionFromRequest(r)
+	ctx := &commandContext{Session: session}
+	if ctx.Session == nil && autoSessionEnabled {
+		ctx.Session = commands.NewAutoSession()
+	}
+
+	report := runScript(ctx, content, req.StopOnError, func(number int, line string) (*commands.CommandResult, string, error) {
+		return executeCommandFromHTTP(ctx, line)
+	})
+
+	token := ""
+	if ctx.Session != nil && ctx.Session != session && !commands.IsAutoSession(ctx.Session) {
+		token = ctx.Session.Token
+	}
+
+	response := map[string]interface{}{
+		"success":    report.Failed == 0,
+		"lines":      report.Lines,
+		"total":      report.Total,
+		"succeeded":  report.Succeeded,
+		"failed":     report.Failed,
+		"stopped":    report.Stopped,
+		"durationMs": report.DurationMs,
+	}
+	if token != "" {
+		response["token"] = token
+	}
+	sendJSONResponse(w, response, http.StatusOK)
+}
+
+// Handler para iniciar sesión y obtener un token
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		User        string `json:"user"`
+		Pass        string `json:"pass"`
+		PartitionID string `json:"partitionId"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   "Error al decodificar la petición: " + err.Error(),
+		}
+		sendJSONResponse(w, response, http.StatusBadRequest)
+		return
+	}
+
+	if req.User == "" || req.Pass == "" || req.PartitionID == "" {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   "Los campos user, pass y partitionId son obligatorios",
+		}
+		sendJSONResponse(w, response, http.StatusBadRequest)
+		return
+	}
+
+	res := commands.NewCommandResult("login")
+	session, err := commands.AuthenticateUser(res, req.User, req.Pass, req.PartitionID)
+	if err != nil {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+		sendJSONResponse(w, response, http.StatusUnauthorized)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"token":   session.Token,
+		"session": commands.GetSessionInfo(session),
+	}
+	if output := res.Output(); output != "" {
+		response["output"] = output
+	}
+	sendJSONResponse(w, response, http.StatusOK)
+}
+
+// Handler para cerrar la sesión asociada al token de la petición
+func logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session := sessionFromRequest(r)
+	if session == nil {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   "No hay sesión activa para cerrar",
+		}
+		sendJSONResponse(w, response, http.StatusUnauthorized)
+		return
+	}
+
+	commands.EndSession(session)
+
+	response := map[string]interface{}{
+		"success": true,
+	}
+	sendJSONResponse(w, response, http.StatusOK)
+}
+
+// sessionFromRequest - Resolver la sesión del cliente a partir del token enviado
+// en "Authorization: Bearer <token>" o en "X-Session-Token"
+func sessionFromRequest(r *http.Request) *commands.Session {
+	token := strings.TrimSpace(r.Header.Get("X-Session-Token"))
+	if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return commands.GetSessionByToken(token)
 }
 
 // Handler para health check
@@ -453,7 +848,7 @@ func filesHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("Partición ID: %s, Path: %s\n", req.PartitionID, req.Path)
 
 	// Validar que el usuario esté logueado
-	session := commands.GetCurrentSession()
+	session := sessionFromRequest(r)
 	if session == nil {
 		fmt.Println("Usuario actual: <ninguno>")
 	} else {
@@ -485,8 +880,18 @@ func filesHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Los usuarios que no son root solo acceden a la partición de su sesión
+	if err := commands.ValidatePartitionAccess(session, mountedPartition.ID); err != nil {
+		response := map[string]interface{}{
+			"success": false,
+			"error":   err.Error(),
+		}
+		sendJSONResponse(w, response, http.StatusForbidden)
+		return
+	}
+
 	fmt.Println("Llamando a GetFilesList...")
-	files, err := commands.GetFilesList(mountedPartition, req.Path)
+	files, err := commands.GetFilesList(session, mountedPartition, req.Path)
 
 	if err != nil {
 		fmt.Printf("ERROR en GetFilesList: %v\n", err)
@@ -519,8 +924,8 @@ func filesHandler(w http.ResponseWriter, r *http.Request) {
 func sendJSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
 	// Ensure CORS headers are present for all JSON responses (extra safety)
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, PUT, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Session-Token")
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(data)
@@ -561,26 +966,9 @@ func removeInlineComment(line string) string {
 	return line
 }
 
-// Ejecutar comando desde HTTP y capturar salida
-func executeCommandFromHTTP(commandLine string) (string, error) {
-	// Redirigir stdout para capturar la salida
-	originalStdout := os.Stdout
-	r, w, _ := os.Pipe()
-	os.Stdout = w
-
-	// Buffer para capturar la salida
-	outputChan := make(chan string)
-	go func() {
-		scanner := bufio.NewScanner(r)
-		var output strings.Builder
-		for scanner.Scan() {
-			output.WriteString(scanner.Text() + "\n")
-		}
-		outputChan <- output.String()
-	}()
-
-	var err error
-
+// Ejecutar comando desde HTTP. Devuelve el resultado estructurado del comando
+// y el texto para humanos (incluidas las notas de sustitución de IDs).
+func executeCommandFromHTTP(ctx *commandContext, commandLine string) (*commands.CommandResult, string, error) {
 	parts := parseArguments(commandLine)
 
 	// Ignorar líneas que contengan sólo guiones bajos o guiones (artefactos de scripts)
@@ -595,21 +983,20 @@ func executeCommandFromHTTP(commandLine string) (string, error) {
 			}
 		}
 		if onlyUnderscores {
-			w.Close()
-			os.Stdout = originalStdout
-			return "", nil
+			return nil, "", nil
 		}
 	}
 
 	if len(parts) == 0 {
-		w.Close()
-		os.Stdout = originalStdout
-		return "", fmt.Errorf("comando vacío")
+		return nil, "", invalidArgs("comando vacío")
 	}
 
 	command := strings.ToLower(parts[0])
 	args := parts[1:]
 
+	// Notas generadas antes de ejecutar el comando
+	var notes strings.Builder
+
 	// Autoreplace de IDs desconocidos como 'A118' con la primera partición montada disponible.
 	// Esto ayuda a ejecutar scripts de prueba que usan IDs antiguos o placeholders.
 	for i, p := range parts {
@@ -634,7 +1021,7 @@ func executeCommandFromHTTP(commandLine string) (string, error) {
 							if i-1 >= 0 && i-1 < len(args) {
 								args[i-1] = newToken
 							}
-							fmt.Printf("Nota: ID '%s' no encontrado. Usando '%s' en su lugar.\n", val, idv)
+							fmt.Fprintf(&notes, "Nota: ID '%s' no encontrado. Usando '%s' en su lugar.\n", val, idv)
 						}
 					}
 				}
@@ -642,23 +1029,108 @@ func executeCommandFromHTTP(commandLine string) (string, error) {
 		}
 	}
 
-	// Permitir que comandos ejecutados desde el endpoint HTTP se ejecuten
-	// sin requerir sesión activa (la GUI pedirá sesión sólo para visualizador).
-	commands.SetAllowCommandsWithoutSession(true)
-	defer commands.SetAllowCommandsWithoutSession(false)
+	res, err := executeCommand(ctx, command, args, commandLine)
+
+	return res, notes.String() + res.Output(), err
+}
+
+// ScriptLineResult - Resultado de una línea de un script
+type ScriptLineResult struct {
+	Line       int                    `json:"line"`
+	Command    string                 `json:"command"`
+	Success    bool                   `json:"success"`
+	Code       string                 `json:"code,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Output     string                 `json:"output,omitempty"`
+	Paths      []string               `json:"paths,omitempty"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+	DurationMs float64                `json:"durationMs"`
+}
+
+// ScriptReport - Reporte por línea de la ejecución de un script
+type ScriptReport struct {
+	Lines      []ScriptLineResult `json:"lines"`
+	Total      int                `json:"total"`
+	Succeeded  int                `json:"succeeded"`
+	Failed     int                `json:"failed"`
+	Stopped    bool               `json:"stopped"` // Se detuvo por un error
+	DurationMs float64            `json:"durationMs"`
+}
+
+// FirstFailure - Primera línea que falló (nil si todas fueron exitosas)
+func (r *ScriptReport) FirstFailure() *ScriptLineResult {
+	for i := range r.Lines {
+		if !r.Lines[i].Success {
+			return &r.Lines[i]
+		}
+	}
+	return nil
+}
+
+// runScript - Ejecutar un script .smia línea por línea con el mismo análisis que
+// la CLI (comentarios, comentarios inline y argumentos entre comillas). run
+// ejecuta una línea ya limpia y devuelve su resultado y su texto.
+func runScript(ctx *commandContext, content string, stopOnError bool, run func(number int, line string) (*commands.CommandResult, string, error)) *ScriptReport {
+	report := &ScriptReport{Lines: []ScriptLineResult{}}
+	start := time.Now()
+
+	ctx.InScript = true
+	defer func() { ctx.InScript = false }()
 
-	err = executeCommand(command, args, commandLine)
+	for i, rawLine := range strings.Split(content, "\n") {
+		line := strings.TrimRight(rawLine, "\r")
+		if isComment(line) {
+			continue
+		}
 
-	// Restaurar stdout y obtener salida
-	w.Close()
-	os.Stdout = originalStdout
-	output := <-outputChan
+		line = strings.TrimSpace(removeInlineComment(line))
+		if line == "" || len(parseArguments(line)) == 0 {
+			continue
+		}
+
+		lineStart := time.Now()
+		res, output, err := run(i+1, line)
+
+		result := ScriptLineResult{
+			Line:       i + 1,
+			Command:    line,
+			Success:    err == nil,
+			Output:     output,
+			DurationMs: float64(time.Since(lineStart).Microseconds()) / 1000,
+		}
+		if res != nil {
+			result.Paths = res.Paths
+			result.Data = res.Data
+		}
+
+		report.Total++
+		if err != nil {
+			result.Code = commands.ErrorCode(err)
+			result.Error = err.Error()
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+		report.Lines = append(report.Lines, result)
+
+		if err != nil && stopOnError {
+			report.Stopped = true
+			break
+		}
+	}
+
+	report.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	return report
+}
 
-	return output, err
+// commandContext - Estado del cliente que ejecuta los comandos (CLI o petición HTTP)
+type commandContext struct {
+	Session  *commands.Session
+	InScript bool // Evita ejecutar 'execute' dentro de un script
 }
 
 // Función para ejecutar comandos
-func executeCommand(command string, args []string, fullLine string) error {
+func executeCommand(ctx *commandContext, command string, args []string, fullLine string) (*commands.CommandResult, error) {
 	switch command {
 	case "mkdisk":
 		mkdiskCmd := flag.NewFlagSet("mkdisk", flag.ContinueOnError)
@@ -666,32 +1138,34 @@ func executeCommand(command string, args []string, fullLine string) error {
 		unit := mkdiskCmd.String("unit", "m", "Unidad del tamaño (K o M).")
 		fit := mkdiskCmd.String("fit", "ff", "Tipo de ajuste (BF, FF, WF).")
 		path := mkdiskCmd.String("path", "", "Ruta del disco a crear.")
+		table := mkdiskCmd.String("table", "mbr", "Tabla de particiones (MBR o GPT). En GPT las posiciones de la tabla se guardan en bytes y no en LBA, así que fdisk/gdisk del sistema no la pueden leer.")
+		entries := mkdiskCmd.Int("entries", 0, "Cantidad de entradas de la tabla GPT (128 por defecto).")
 
 		if err := mkdiskCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para mkdisk")
+			return nil, invalidArgs("el parámetro -path es obligatorio para mkdisk")
 		}
 		if *size <= 0 {
-			return fmt.Errorf("el parámetro -size es obligatorio y debe ser positivo")
+			return nil, invalidArgs("el parámetro -size es obligatorio y debe ser positivo")
 		}
 
-		commands.ExecuteMkdisk(*size, *unit, *fit, *path)
+		return commands.ExecuteMkdisk(*size, *unit, *fit, *path, *table, *entries)
 
 	case "rmdisk":
 		rmdiskCmd := flag.NewFlagSet("rmdisk", flag.ContinueOnError)
 		path := rmdiskCmd.String("path", "", "Ruta del disco a eliminar.")
 
 		if err := rmdiskCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para rmdisk")
+			return nil, invalidArgs("el parámetro -path es obligatorio para rmdisk")
 		}
 
-		commands.ExecuteRmdisk(*path)
+		return commands.ExecuteRmdisk(*path)
 
 	case "fdisk":
 		fdiskCmd := flag.NewFlagSet("fdisk", flag.ContinueOnError)
@@ -705,20 +1179,20 @@ func executeCommand(command string, args []string, fullLine string) error {
 		add := fdiskCmd.Int64("add", 0, "Cantidad de espacio a agregar o quitar.")
 
 		if err := fdiskCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para fdisk")
+			return nil, invalidArgs("el parámetro -path es obligatorio para fdisk")
 		}
 
 		// Solo validar -size si NO es delete ni add
 		if *delete == "" && *add == 0 {
 			if *size <= 0 {
-				return fmt.Errorf("el parámetro -size es obligatorio y debe ser positivo para crear particiones")
+				return nil, invalidArgs("el parámetro -size es obligatorio y debe ser positivo para crear particiones")
 			}
 		}
 
-		commands.ExecuteFdisk(*size, *unit, *path, *tipo, *fit, *name, *delete, *add)
+		return commands.ExecuteFdisk(*size, *unit, *path, *tipo, *fit, *name, *delete, *add)
 
 	case "mount":
 		mountCmd := flag.NewFlagSet("mount", flag.ContinueOnError)
@@ -726,47 +1200,51 @@ func executeCommand(command string, args []string, fullLine string) error {
 		name := mountCmd.String("name", "", "Nombre de la partición")
 
 		if err := mountCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para mount")
+			return nil, invalidArgs("el parámetro -path es obligatorio para mount")
 		}
 		if *name == "" {
-			return fmt.Errorf("el parámetro -name es obligatorio para mount")
+			return nil, invalidArgs("el parámetro -name es obligatorio para mount")
 		}
 
-		commands.ExecuteMount(*path, *name)
+		return commands.ExecuteMount(*path, *name)
 
 	case "mounted":
-		commands.ExecuteMounted()
+		return commands.ExecuteMounted()
 
 	case "unmount":
 		unmountCmd := flag.NewFlagSet("unmount", flag.ContinueOnError)
 		id := unmountCmd.String("id", "", "ID de la partición montada")
 
 		if err := unmountCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *id == "" {
-			return fmt.Errorf("el parámetro -id es obligatorio para unmount")
+			return nil, invalidArgs("el parámetro -id es obligatorio para unmount")
 		}
 
-		commands.ExecuteUnmount(*id)
+		return commands.ExecuteUnmount(*id)
 
 	case "mkfs":
 		mkfsCmd := flag.NewFlagSet("mkfs", flag.ContinueOnError)
 		id := mkfsCmd.String("id", "", "ID de la partición montada")
-		formatType := mkfsCmd.String("type", "full", "Tipo de formateo (full)")
+		formatType := mkfsCmd.String("type", "full", "Tipo de formateo (full o fast)")
 		fs := mkfsCmd.String("fs", "2fs", "Tipo de sistema de archivos (2fs o 3fs)")
+		longNames := mkfsCmd.Bool("longnames", false, "Permitir nombres de hasta 255 bytes")
+		blockSize := mkfsCmd.Int64("blocksize", 0, "Tamaño de bloque en bytes (64, 128, 256, 512 o 1024)")
+		inodeRatio := mkfsCmd.Int64("inoderatio", 0, "Bytes de partición por inodo")
+		journalSize := mkfsCmd.Int64("journalsize", 0, "Tamaño del journal en KB (solo 3fs)")
 
 		if err := mkfsCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *id == "" {
-			return fmt.Errorf("el parámetro -id es obligatorio para mkfs")
+			return nil, invalidArgs("el parámetro -id es obligatorio para mkfs")
 		}
 
-		commands.ExecuteMkfs(*id, *formatType, *fs)
+		return commands.ExecuteMkfs(*id, *formatType, *fs, *longNames, *blockSize, *inodeRatio, *journalSize)
 
 	case "login":
 		loginCmd := flag.NewFlagSet("login", flag.ContinueOnError)
@@ -775,19 +1253,27 @@ func executeCommand(command string, args []string, fullLine string) error {
 		id := loginCmd.String("id", "", "ID de la partición montada")
 
 		if err := loginCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *user == "" {
-			return fmt.Errorf("el parámetro -user es obligatorio para login")
+			return nil, invalidArgs("el parámetro -user es obligatorio para login")
 		}
 		if *pass == "" {
-			return fmt.Errorf("el parámetro -pass es obligatorio para login")
+			return nil, invalidArgs("el parámetro -pass es obligatorio para login")
 		}
 
-		commands.ExecuteLogin(*user, *pass, *id)
+		session, res, err := commands.ExecuteLogin(ctx.Session, *user, *pass, *id)
+		if session != nil {
+			ctx.Session = session
+		}
+		return res, err
 
 	case "logout":
-		commands.ExecuteLogout()
+		res, err := commands.ExecuteLogout(ctx.Session)
+		if !commands.IsSessionActive(ctx.Session) {
+			ctx.Session = nil
+		}
+		return res, err
 
 	case "cat":
 		catCmd := flag.NewFlagSet("cat", flag.ContinueOnError)
@@ -798,7 +1284,7 @@ func executeCommand(command string, args []string, fullLine string) error {
 		}
 
 		if err := catCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 
 		fileMap := make(map[string]string)
@@ -809,36 +1295,36 @@ func executeCommand(command string, args []string, fullLine string) error {
 		}
 
 		if len(fileMap) == 0 {
-			return fmt.Errorf("debe especificar al menos un archivo con -file1")
+			return nil, invalidArgs("debe especificar al menos un archivo con -file1")
 		}
 
-		commands.ExecuteCat(fileMap)
+		return commands.ExecuteCat(ctx.Session, fileMap)
 
 	case "mkgrp":
 		mkgrpCmd := flag.NewFlagSet("mkgrp", flag.ContinueOnError)
 		groupName := mkgrpCmd.String("name", "", "Nombre del grupo a crear")
 
 		if err := mkgrpCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *groupName == "" {
-			return fmt.Errorf("el parámetro -name es obligatorio para mkgrp")
+			return nil, invalidArgs("el parámetro -name es obligatorio para mkgrp")
 		}
 
-		commands.ExecuteMkgrp(*groupName)
+		return commands.ExecuteMkgrp(ctx.Session, *groupName)
 
 	case "rmgrp":
 		rmgrpCmd := flag.NewFlagSet("rmgrp", flag.ContinueOnError)
 		groupName := rmgrpCmd.String("name", "", "Nombre del grupo a eliminar")
 
 		if err := rmgrpCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *groupName == "" {
-			return fmt.Errorf("el parámetro -name es obligatorio para rmgrp")
+			return nil, invalidArgs("el parámetro -name es obligatorio para rmgrp")
 		}
 
-		commands.ExecuteRmgrp(*groupName)
+		return commands.ExecuteRmgrp(ctx.Session, *groupName)
 
 	case "mkusr":
 		mkusrCmd := flag.NewFlagSet("mkusr", flag.ContinueOnError)
@@ -847,26 +1333,26 @@ func executeCommand(command string, args []string, fullLine string) error {
 		groupName := mkusrCmd.String("grp", "", "Grupo al que pertenece el usuario")
 
 		if err := mkusrCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *username == "" || *password == "" || *groupName == "" {
-			return fmt.Errorf("los parámetros -user, -pass y -grp son obligatorios para mkusr")
+			return nil, invalidArgs("los parámetros -user, -pass y -grp son obligatorios para mkusr")
 		}
 
-		commands.ExecuteMkusr(*username, *password, *groupName)
+		return commands.ExecuteMkusr(ctx.Session, *username, *password, *groupName)
 
 	case "rmusr":
 		rmusrCmd := flag.NewFlagSet("rmusr", flag.ContinueOnError)
 		username := rmusrCmd.String("user", "", "Nombre del usuario a eliminar")
 
 		if err := rmusrCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *username == "" {
-			return fmt.Errorf("el parámetro -user es obligatorio para rmusr")
+			return nil, invalidArgs("el parámetro -user es obligatorio para rmusr")
 		}
 
-		commands.ExecuteRmusr(*username)
+		return commands.ExecuteRmusr(ctx.Session, *username)
 
 	case "chgrp":
 		chgrpCmd := flag.NewFlagSet("chgrp", flag.ContinueOnError)
@@ -874,13 +1360,46 @@ func executeCommand(command string, args []string, fullLine string) error {
 		groupName := chgrpCmd.String("grp", "", "Nuevo grupo del usuario")
 
 		if err := chgrpCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *username == "" || *groupName == "" {
-			return fmt.Errorf("los parámetros -user y -grp son obligatorios para chgrp")
+			return nil, invalidArgs("los parámetros -user y -grp son obligatorios para chgrp")
 		}
 
-		commands.ExecuteChgrp(*username, *groupName)
+		return commands.ExecuteChgrp(ctx.Session, *username, *groupName)
+
+	case "passwd":
+		passwdCmd := flag.NewFlagSet("passwd", flag.ContinueOnError)
+		username := passwdCmd.String("usr", "", "Usuario (por defecto, el de la sesión)")
+		password := passwdCmd.String("pass", "", "Nueva contraseña")
+
+		if err := passwdCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *password == "" {
+			return nil, invalidArgs("el parámetro -pass es obligatorio para passwd")
+		}
+
+		return commands.ExecutePasswd(ctx.Session, *username, *password)
+
+	case "quota":
+		quotaCmd := flag.NewFlagSet("quota", flag.ContinueOnError)
+		username := quotaCmd.String("usr", "", "Usuario al que se asigna la cuota")
+		groupName := quotaCmd.String("grp", "", "Grupo al que se asigna la cuota")
+		blocks := quotaCmd.Int64("blocks", -1, "Máximo de bloques (0 = sin límite)")
+		inodes := quotaCmd.Int64("inodes", -1, "Máximo de inodos (0 = sin límite)")
+
+		if err := quotaCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if (*username == "") == (*groupName == "") {
+			return nil, invalidArgs("indique -usr o -grp (solo uno) para quota")
+		}
+		if *blocks < 0 && *inodes < 0 {
+			return nil, invalidArgs("indique -blocks y/o -inodes para quota")
+		}
+
+		return commands.ExecuteQuota(ctx.Session, *username, *groupName, *blocks, *inodes)
 
 	case "mkfile":
 		mkfileCmd := flag.NewFlagSet("mkfile", flag.ContinueOnError)
@@ -890,13 +1409,13 @@ func executeCommand(command string, args []string, fullLine string) error {
 		cont := mkfileCmd.String("cont", "", "Archivo del sistema con contenido")
 
 		if err := mkfileCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para mkfile")
+			return nil, invalidArgs("el parámetro -path es obligatorio para mkfile")
 		}
 
-		commands.ExecuteMkfile(*path, *recursive, *size, *cont)
+		return commands.ExecuteMkfile(ctx.Session, *path, *recursive, *size, *cont)
 
 	case "mkdir":
 		mkdirArgs := parseArguments(fullLine)[1:]
@@ -905,46 +1424,104 @@ func executeCommand(command string, args []string, fullLine string) error {
 		parents := mkdirCmd.Bool("p", false, "Crear directorios padre si no existen")
 
 		if err := mkdirCmd.Parse(mkdirArgs); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para mkdir")
+			return nil, invalidArgs("el parámetro -path es obligatorio para mkdir")
+		}
+
+		return commands.ExecuteMkdir(ctx.Session, *path, *parents)
+
+	case "import":
+		importCmd := flag.NewFlagSet("import", flag.ContinueOnError)
+		src := importCmd.String("src", "", "Carpeta del host a importar")
+		dest := importCmd.String("dest", "", "Carpeta destino en la partición")
+		recursive := importCmd.Bool("r", false, "Importar subcarpetas y crear las carpetas destino que falten")
+
+		if err := importCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *src == "" || *dest == "" {
+			return nil, invalidArgs("los parámetros -src y -dest son obligatorios para import")
+		}
+
+		return commands.ExecuteImport(ctx.Session, *src, *dest, *recursive)
+
+	case "export":
+		exportCmd := flag.NewFlagSet("export", flag.ContinueOnError)
+		path := exportCmd.String("path", "", "Ruta de la carpeta o archivo a exportar")
+		dest := exportCmd.String("dest", "", "Carpeta o archivo tar de destino en el host")
+		format := exportCmd.String("format", "dir", "Formato de salida (dir o tar)")
+
+		if err := exportCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *path == "" || *dest == "" {
+			return nil, invalidArgs("los parámetros -path y -dest son obligatorios para export")
 		}
 
-		commands.ExecuteMkdir(*path, *parents)
+		return commands.ExecuteExport(ctx.Session, *path, *dest, *format)
+
+	case "serve-webdav":
+		webdavCmd := flag.NewFlagSet("serve-webdav", flag.ContinueOnError)
+		id := webdavCmd.String("id", "", "ID de la partición montada a exponer")
+		port := webdavCmd.String("port", "8081", "Puerto del servidor WebDAV")
+		bind := webdavCmd.String("bind", "127.0.0.1", "Dirección en la que escucha (0.0.0.0 para todas)")
+
+		if err := webdavCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *id == "" {
+			return nil, invalidArgs("el parámetro -id es obligatorio para serve-webdav")
+		}
+
+		return commands.ExecuteServeWebDAV(*id, *port, *bind)
+
+	case "stop-webdav":
+		stopCmd := flag.NewFlagSet("stop-webdav", flag.ContinueOnError)
+		id := stopCmd.String("id", "", "ID de la partición con el servidor WebDAV")
+
+		if err := stopCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *id == "" {
+			return nil, invalidArgs("el parámetro -id es obligatorio para stop-webdav")
+		}
+
+		return commands.ExecuteStopWebDAV(*id)
 
 	case "rep":
 		repCmd := flag.NewFlagSet("rep", flag.ContinueOnError)
-		name := repCmd.String("name", "", "Nombre del reporte (mbr, disk, inode, block, bm_inode, bm_block, tree, sb, file, ls)")
+		name := repCmd.String("name", "", "Nombre del reporte (mbr, disk, inode, block, bm_inode, bm_block, tree, sb, file, ls, quota)")
 		path := repCmd.String("path", "", "Ruta donde guardar el reporte")
 		id := repCmd.String("id", "", "ID de la partición montada (opcional si se usa -disk)")
 		disk := repCmd.String("disk", "", "Ruta al archivo de disco (.mia) para generar reportes sin montar la partición (opcional)")
 		pathFileLs := repCmd.String("path_file_ls", "", "Ruta del archivo o carpeta (para reportes file y ls)")
 
 		if err := repCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *name == "" || *path == "" {
-			return fmt.Errorf("los parámetros -name y -path son obligatorios para rep")
+			return nil, invalidArgs("los parámetros -name y -path son obligatorios para rep")
 		}
 		// Either -id or -disk can be used. If both are empty, we want to fail.
 		if *id == "" && *disk == "" {
-			return fmt.Errorf("al menos uno de los parámetros -id o -disk es obligatorio para rep cuando no hay particiones montadas")
+			return nil, invalidArgs("al menos uno de los parámetros -id o -disk es obligatorio para rep cuando no hay particiones montadas")
 		}
 
-		commands.ExecuteRep(*name, *path, *id, *pathFileLs, *disk)
+		return commands.ExecuteRep(*name, *path, *id, *pathFileLs, *disk)
 
 	case "remove":
 		removeCmd := flag.NewFlagSet("remove", flag.ContinueOnError)
 		path := removeCmd.String("path", "", "Ruta del archivo o carpeta a eliminar")
 
 		if err := removeCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para remove")
+			return nil, invalidArgs("el parámetro -path es obligatorio para remove")
 		}
-		commands.ExecuteRemove(*path)
+		return commands.ExecuteRemove(ctx.Session, *path)
 
 	case "edit":
 		editCmd := flag.NewFlagSet("edit", flag.ContinueOnError)
@@ -952,16 +1529,16 @@ func executeCommand(command string, args []string, fullLine string) error {
 		contenido := editCmd.String("contenido", "", "Archivo del sistema con el nuevo contenido")
 
 		if err := editCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para edit")
+			return nil, invalidArgs("el parámetro -path es obligatorio para edit")
 		}
 		if *contenido == "" {
-			return fmt.Errorf("el parámetro -contenido es obligatorio para edit")
+			return nil, invalidArgs("el parámetro -contenido es obligatorio para edit")
 		}
 
-		commands.ExecuteEdit(*path, *contenido)
+		return commands.ExecuteEdit(ctx.Session, *path, *contenido)
 
 	case "rename":
 		renameCmd := flag.NewFlagSet("rename", flag.ContinueOnError)
@@ -969,16 +1546,16 @@ func executeCommand(command string, args []string, fullLine string) error {
 		name := renameCmd.String("name", "", "Nuevo nombre del archivo o carpeta")
 
 		if err := renameCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para rename")
+			return nil, invalidArgs("el parámetro -path es obligatorio para rename")
 		}
 		if *name == "" {
-			return fmt.Errorf("el parámetro -name es obligatorio para rename")
+			return nil, invalidArgs("el parámetro -name es obligatorio para rename")
 		}
 
-		commands.ExecuteRename(*path, *name)
+		return commands.ExecuteRename(ctx.Session, *path, *name)
 
 	case "copy":
 		copyCmd := flag.NewFlagSet("copy", flag.ContinueOnError)
@@ -986,16 +1563,16 @@ func executeCommand(command string, args []string, fullLine string) error {
 		destino := copyCmd.String("destino", "", "Ruta del archivo o carpeta destino")
 
 		if err := copyCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para copy")
+			return nil, invalidArgs("el parámetro -path es obligatorio para copy")
 		}
 		if *destino == "" {
-			return fmt.Errorf("el parámetro -destino es obligatorio para copy")
+			return nil, invalidArgs("el parámetro -destino es obligatorio para copy")
 		}
 
-		commands.ExecuteCopy(*path, *destino)
+		return commands.ExecuteCopy(ctx.Session, *path, *destino)
 
 	case "move":
 		moveCmd := flag.NewFlagSet("move", flag.ContinueOnError)
@@ -1003,16 +1580,48 @@ func executeCommand(command string, args []string, fullLine string) error {
 		destino := moveCmd.String("destino", "", "Ruta del archivo o carpeta destino")
 
 		if err := moveCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para move")
+			return nil, invalidArgs("el parámetro -path es obligatorio para move")
 		}
 		if *destino == "" {
-			return fmt.Errorf("el parámetro -destino es obligatorio para move")
+			return nil, invalidArgs("el parámetro -destino es obligatorio para move")
+		}
+
+		return commands.ExecuteMove(ctx.Session, *path, *destino)
+
+	case "ln":
+		lnCmd := flag.NewFlagSet("ln", flag.ContinueOnError)
+		path := lnCmd.String("path", "", "Ruta a la que apunta el enlace")
+		dest := lnCmd.String("dest", "", "Ruta del enlace a crear")
+		symbolic := lnCmd.Bool("s", false, "Crear un enlace simbólico en vez de uno duro")
+
+		if err := lnCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *path == "" {
+			return nil, invalidArgs("el parámetro -path es obligatorio para ln")
+		}
+		if *dest == "" {
+			return nil, invalidArgs("el parámetro -dest es obligatorio para ln")
 		}
 
-		commands.ExecuteMove(*path, *destino)
+		return commands.ExecuteLn(ctx.Session, *path, *dest, *symbolic)
+
+	case "touch":
+		touchCmd := flag.NewFlagSet("touch", flag.ContinueOnError)
+		path := touchCmd.String("path", "", "Ruta del archivo o carpeta")
+		date := touchCmd.String("date", "", "Fecha de acceso y modificación (por defecto, ahora)")
+
+		if err := touchCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *path == "" {
+			return nil, invalidArgs("el parámetro -path es obligatorio para touch")
+		}
+
+		return commands.ExecuteTouch(ctx.Session, *path, *date)
 
 	case "find":
 		findCmd := flag.NewFlagSet("find", flag.ContinueOnError)
@@ -1020,16 +1629,16 @@ func executeCommand(command string, args []string, fullLine string) error {
 		name := findCmd.String("name", "", "Patrón de nombre a buscar (regex)")
 
 		if err := findCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para find")
+			return nil, invalidArgs("el parámetro -path es obligatorio para find")
 		}
 		if *name == "" {
-			return fmt.Errorf("el parámetro -name es obligatorio para find")
+			return nil, invalidArgs("el parámetro -name es obligatorio para find")
 		}
 
-		commands.ExecuteFind(*path, *name)
+		return commands.ExecuteFind(ctx.Session, *path, *name)
 
 	case "chown":
 		chownCmd := flag.NewFlagSet("chown", flag.ContinueOnError)
@@ -1038,16 +1647,16 @@ func executeCommand(command string, args []string, fullLine string) error {
 		usuario := chownCmd.String("usuario", "", "Nuevo propietario")
 
 		if err := chownCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para chown")
+			return nil, invalidArgs("el parámetro -path es obligatorio para chown")
 		}
 		if *usuario == "" {
-			return fmt.Errorf("el parámetro -usuario es obligatorio para chown")
+			return nil, invalidArgs("el parámetro -usuario es obligatorio para chown")
 		}
 
-		commands.ExecuteChown(*path, *recursive, *usuario)
+		return commands.ExecuteChown(ctx.Session, *path, *recursive, *usuario)
 
 	case "chmod":
 		chmodCmd := flag.NewFlagSet("chmod", flag.ContinueOnError)
@@ -1056,83 +1665,186 @@ func executeCommand(command string, args []string, fullLine string) error {
 		ugo := chmodCmd.String("ugo", "", "Nuevos permisos (rwxrwxrwx)")
 
 		if err := chmodCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *path == "" {
-			return fmt.Errorf("el parámetro -path es obligatorio para chmod")
+			return nil, invalidArgs("el parámetro -path es obligatorio para chmod")
 		}
 		if *ugo == "" {
-			return fmt.Errorf("el parámetro -ugo es obligatorio para chmod")
+			return nil, invalidArgs("el parámetro -ugo es obligatorio para chmod")
 		}
 
-		commands.ExecuteChmod(*path, *recursive, *ugo)
+		return commands.ExecuteChmod(ctx.Session, *path, *recursive, *ugo)
 
 	case "recovery":
 		recoveryCmd := flag.NewFlagSet("recovery", flag.ContinueOnError)
 		id := recoveryCmd.String("id", "", "ID de la partición montada")
 
 		if err := recoveryCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *id == "" {
-			return fmt.Errorf("el parámetro -id es obligatorio para recovery")
+			return nil, invalidArgs("el parámetro -id es obligatorio para recovery")
 		}
 
-		commands.ExecuteRecovery(*id)
+		return commands.ExecuteRecovery(*id)
 
 	case "loss":
 		lossCmd := flag.NewFlagSet("loss", flag.ContinueOnError)
 		id := lossCmd.String("id", "", "ID de la partición montada")
 
 		if err := lossCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *id == "" {
-			return fmt.Errorf("el parámetro -id es obligatorio para loss")
+			return nil, invalidArgs("el parámetro -id es obligatorio para loss")
+		}
+
+		return commands.ExecuteLoss(*id)
+
+	case "fsck":
+		fsckCmd := flag.NewFlagSet("fsck", flag.ContinueOnError)
+		id := fsckCmd.String("id", "", "ID de la partición montada")
+		repair := fsckCmd.Bool("repair", false, "Corregir las inconsistencias encontradas")
+
+		if err := fsckCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *id == "" {
+			return nil, invalidArgs("el parámetro -id es obligatorio para fsck")
+		}
+
+		return commands.ExecuteFsck(*id, *repair)
+
+	case "resizefs":
+		resizefsCmd := flag.NewFlagSet("resizefs", flag.ContinueOnError)
+		id := resizefsCmd.String("id", "", "ID de la partición montada")
+
+		if err := resizefsCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *id == "" {
+			return nil, invalidArgs("el parámetro -id es obligatorio para resizefs")
+		}
+
+		return commands.ExecuteResizefs(*id)
+
+	case "tunefs":
+		tunefsCmd := flag.NewFlagSet("tunefs", flag.ContinueOnError)
+		id := tunefsCmd.String("id", "", "ID de la partición montada")
+		journal := tunefsCmd.String("journal", "", "Activar (on) o quitar (off) el journal")
+
+		if err := tunefsCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *id == "" || *journal == "" {
+			return nil, invalidArgs("los parámetros -id y -journal son obligatorios para tunefs")
 		}
 
-		commands.ExecuteLoss(*id)
+		return commands.ExecuteTunefs(*id, *journal)
+
+	case "execute":
+		executeCmd := flag.NewFlagSet("execute", flag.ContinueOnError)
+		path := executeCmd.String("path", "", "Ruta del script .smia")
+		stop := executeCmd.Bool("stop", false, "Detener el script en el primer error")
+
+		if err := executeCmd.Parse(args); err != nil {
+			return nil, invalidArgs("%v", err)
+		}
+		if *path == "" {
+			return nil, invalidArgs("el parámetro -path es obligatorio para execute")
+		}
+		if ctx.InScript {
+			return nil, invalidArgs("no se permite usar 'execute' dentro de un script")
+		}
+
+		data, err := os.ReadFile(*path)
+		if err != nil {
+			return nil, commands.NewCommandError(commands.ErrCodeNotFound, "no se pudo leer el script '%s': %v", *path, err)
+		}
+
+		res := commands.NewCommandResult("execute")
+		report := runScript(ctx, string(data), *stop, func(number int, line string) (*commands.CommandResult, string, error) {
+			parts := parseArguments(line)
+			res.Printf("╰─➤ [%d] %s\n", number, line)
+			lineRes, err := executeCommand(ctx, strings.ToLower(parts[0]), parts[1:], line)
+			if err != nil {
+				res.Printf("Error: %v\n", err)
+			}
+			return lineRes, lineRes.Output(), err
+		})
+
+		res.Printf("📜 Script '%s': %d comandos, %d exitosos, %d con error", *path, report.Total, report.Succeeded, report.Failed)
+		if report.Stopped {
+			res.Printf(" (detenido en la línea %d)", report.Lines[len(report.Lines)-1].Line)
+		}
+		res.Printf("\n")
+
+		res.AddPath(*path)
+		res.Set("lines", report.Lines)
+		res.Set("total", report.Total)
+		res.Set("succeeded", report.Succeeded)
+		res.Set("failed", report.Failed)
+		res.Set("stopped", report.Stopped)
+		res.Set("durationMs", report.DurationMs)
+
+		if failed := report.FirstFailure(); failed != nil {
+			return res, commands.NewCommandError(failed.Code, "el script terminó con %d comandos fallidos (primero en la línea %d)", report.Failed, failed.Line)
+		}
+		return res, nil
 
 	case "journaling":
 		journalCmd := flag.NewFlagSet("journaling", flag.ContinueOnError)
 		id := journalCmd.String("id", "", "ID de la partición montada")
 
 		if err := journalCmd.Parse(args); err != nil {
-			return err
+			return nil, invalidArgs("%v", err)
 		}
 		if *id == "" {
-			return fmt.Errorf("el parámetro -id es obligatorio para journaling")
+			return nil, invalidArgs("el parámetro -id es obligatorio para journaling")
 		}
 
 		// Buscar partición montada por ID (usa la función centralizada que ahora es insensible a mayúsculas)
 		mounted := commands.GetMountedPartition(*id)
 		if mounted == nil {
-			return fmt.Errorf("no se encontró una partición montada con ID '%s'", *id)
+			return nil, commands.NewCommandError(commands.ErrCodeNotMounted, "no se encontró una partición montada con ID '%s'", *id)
 		}
 
 		entries, err := commands.GetJournaling(mounted)
 		if err != nil {
-			return fmt.Errorf("error al obtener journaling: %v", err)
+			return nil, commands.NewCommandError(commands.ErrCodeIO, "error al obtener journaling: %v", err)
 		}
 
-		fmt.Printf("Journaling entries (count=%d):\n", len(entries))
+		res := commands.NewCommandResult("journaling")
+		res.Printf("Journaling entries (count=%d):\n", len(entries))
 		for idx, e := range entries {
-			fmt.Printf("[%d] %s %s %s\n", idx, e.Timestamp, e.Operation, e.Path)
+			res.Printf("[%d] %s %s %s\n", idx, e.Timestamp, e.Operation, e.Path)
 			if e.Content != "" {
-				fmt.Printf("    Content: %s\n", e.Content)
+				res.Printf("    Content: %s\n", e.Content)
 			}
 		}
+		res.Set("id", mounted.ID)
+		res.Set("entries", entries)
+		return res, nil
 
 	default:
-		return fmt.Errorf("comando no reconocido: %s", command)
+		return nil, commands.NewCommandError(commands.ErrCodeUnknownCommand, "comando no reconocido: %s", command)
 	}
+}
 
-	return nil
+// invalidArgs - Error de parámetros inválidos o faltantes
+func invalidArgs(format string, a ...interface{}) error {
+	return commands.NewCommandError(commands.ErrCodeInvalidArgument, format, a...)
 }
 
 func startCLI() {
 	scanner := bufio.NewScanner(os.Stdin)
 
+	// La CLI mantiene su propia sesión local y muestra la salida de cada
+	// comando mientras se genera
+	ctx := &commandContext{}
+	commands.SetOutputEcho(os.Stdout)
+
 	for {
 		fmt.Print("╰─➤ ")
 		if !scanner.Scan() {
@@ -1169,7 +1881,7 @@ func startCLI() {
 		command := strings.ToLower(parts[0])
 		args := parts[1:]
 
-		if err := executeCommand(command, args, line); err != nil {
+		if _, err := executeCommand(ctx, command, args, line); err != nil {
 			fmt.Printf("Error: %v\n", err)
 		}
 	}
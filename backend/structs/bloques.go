@@ -1,23 +1,33 @@
 package structs
 
+// Los bloques ocupan S_block_s bytes en la partición. Cuántas entradas, bytes
+// o apuntadores caben en cada uno depende de ese tamaño (ver mkfs -blocksize),
+// así que los arreglos se crean al leer el bloque.
+
 // BloqueCarpeta representa un bloque que contiene información de directorios
 type BloqueCarpeta struct {
-    BContent [4]BContent // 4 entradas por bloque de carpeta
+    BContent []BContent // 4 entradas por bloque de 96 bytes
 }
 
 // BloqueArchivo representa un bloque que contiene datos de archivos  
 type BloqueArchivo struct {
-    BContent [64]byte // 64 bytes de datos por bloque
+    BContent []byte // 64 bytes de datos por bloque de 96 bytes
 }
 
 // BloqueApuntador representa un bloque que contiene punteros a otros bloques
 type BloqueApuntador struct {
-    BPointers [8]int64 // 8 punteros × 8 bytes = 64 bytes
+    BPointers []int64 // 8 punteros × 8 bytes = 64 bytes en bloques de 96 bytes
 }
 
 // BContent representa una entrada en un directorio
 type BContent struct {
     BName  [12]byte // Nombre del archivo/directorio
-    _      [4]byte  // Padding para alineación
+    BLong  int32    // Primer bloque de nombre largo (0 = sin nombre largo)
     BInodo int64    // Número de inodo (mantener int64 para compatibilidad)
-}
\ No newline at end of file
+}
+
+// BloqueNombre guarda un tramo del nombre largo de una entrada de carpeta
+type BloqueNombre struct {
+    BName []byte // 88 bytes del nombre en bloques de 96 bytes
+    BNext int64  // Siguiente bloque del nombre (-1 = último)
+}
@@ -0,0 +1,30 @@
+package structs
+
+// GPTHeader - Cabecera de la tabla de particiones GPT. Hay dos copias: la
+// principal después del MBR protector y la de respaldo en el último sector
+// del disco. Las posiciones van en bytes, igual que en el MBR.
+type GPTHeader struct {
+    Gpt_signature     [8]byte  // "EFI PART"
+    Gpt_revision      uint32   // Versión del formato
+    Gpt_header_size   uint32   // Bytes de la cabecera
+    Gpt_header_crc    uint32   // CRC32 de la cabecera con este campo en 0
+    Gpt_current       int64    // Posición de esta copia de la cabecera
+    Gpt_backup        int64    // Posición de la otra copia
+    Gpt_first_usable  int64    // Primer byte disponible para particiones
+    Gpt_last_usable   int64    // Último byte disponible para particiones (inclusive)
+    Gpt_disk_guid     [16]byte // Identificador único del disco
+    Gpt_entries_start int64    // Posición del arreglo de entradas de esta copia
+    Gpt_entries_count uint32   // Cantidad de entradas del arreglo
+    Gpt_entry_size    uint32   // Bytes de cada entrada
+    Gpt_entries_crc   uint32   // CRC32 del arreglo de entradas
+}
+
+// GPTEntry - Entrada del arreglo de particiones GPT. La partición se guarda
+// con el mismo formato que en el MBR (nombre, ajuste, ID de montaje...).
+type GPTEntry struct {
+    Gpt_type_guid   [16]byte  // Tipo de partición (ceros = entrada libre)
+    Gpt_unique_guid [16]byte  // Identificador único de la partición
+    Gpt_attributes  int64     // Atributos (sin uso por ahora)
+    Gpt_partition   Partition // Datos de la partición
+    Gpt_reserved    [41]byte  // Relleno hasta 128 bytes por entrada
+}
@@ -10,4 +10,5 @@ type Inodos struct {
     I_block [15]int64
     I_type  byte     
     I_perm  [3]byte  
+    I_links int64    // Enlaces duros (0 en particiones formateadas antes de este campo)
 }
\ No newline at end of file
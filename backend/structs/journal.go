@@ -14,13 +14,20 @@ type Journal struct {
     JContent Information // Contiene toda la información de la acción que se hizo
 }
 
-// JournalRecovery - Estructura extendida para recuperación del sistema de archivos EXT3
-type JournalRecovery struct {
-    Journal_ultimo_montaje   int64      // Timestamp del último montaje
-    Journal_inodos_libres    int64      // Cantidad de inodos libres
-    Journal_bloques_libres   int64      // Cantidad de bloques libres
-    Journal_bm_inodos        [500]byte  // Copia del bitmap de inodos
-    Journal_bm_bloques       [500]byte  // Copia del bitmap de bloques
-    Journal_inodos           [20000]byte // Copia del área de inodos
-    Journal_bloques          [50000]byte // Copia del área de bloques
-}
\ No newline at end of file
+// WalHeader - Cabecera del journal de escritura anticipada (EXT3).
+// Va al inicio del área reservada entre el superbloque y la bitácora de operaciones,
+// seguida por WCount registros WalRecord, cada uno con sus datos a continuación.
+type WalHeader struct {
+    WMagic    int64  // Firma del journal (0xEF53 + versión)
+    WSequence int64  // Número de la última transacción escrita
+    WState    int64  // 0 vacío/aplicado, 1 abierta (sin confirmar), 2 confirmada
+    WCount    int64  // Cantidad de registros de la transacción
+    WLength   int64  // Bytes ocupados por los registros y sus datos
+    WChecksum uint32 // CRC32 de los registros y sus datos
+}
+
+// WalRecord - Rango del disco que la transacción va a sobrescribir
+type WalRecord struct {
+    WOffset int64 // Posición absoluta en el disco
+    WLength int64 // Cantidad de bytes que siguen al registro
+}
@@ -3,7 +3,7 @@ import Login from './components/Login';
 import FileSystemViewer from './components/FileSystemViewer';
 import JournalingViewer from './components/JournalingViewer';
 import './App.css';
-import { BACKEND_URL } from './config'; // Asegúrate que config.ts esté importando bien
+import { BACKEND_URL, authHeaders, setSessionToken } from './config'; // Asegúrate que config.ts esté importando bien
 
 // *** DEBUG ***
 // Esto nos dirá qué URL se compiló en el build de producción
@@ -18,8 +18,13 @@ interface CommandResult {
 
 interface BackendResponse {
   success: boolean;
-  output: string;
+  command?: string;
+  code?: string;
   error?: string;
+  paths?: string[];
+  data?: Record<string, unknown>;
+  output?: string;
+  token?: string;
 }
 
 interface Session {
@@ -53,10 +58,10 @@ const App: React.FC = () => {
 
   // Comandos que requieren autenticación
   const COMMANDS_REQUIRING_AUTH = [
-    'mkgrp', 'rmgrp', 'mkusr', 'rmusr',
+    'mkgrp', 'rmgrp', 'mkusr', 'rmusr', 'passwd', 'quota',
     'mkdir', 'mkfile', 'remove', 'edit',
     'rename', 'copy', 'move', 'find',
-    'chown', 'chmod', 'cat', 'recovery', 'loss'
+    'chown', 'chmod', 'cat', 'recovery', 'loss', 'import', 'ln', 'touch'
   ];
 
   useEffect(() => {
@@ -104,9 +109,13 @@ const App: React.FC = () => {
 
   const fetchSessionInfo = async () => {
     try {
-      const resp = await fetch(`${BACKEND_URL}/session`);
+      const resp = await fetch(`${BACKEND_URL}/session`, { headers: authHeaders() });
       if (!resp.ok) return;
       const data = await resp.json();
+      if (data && data.session && !data.session.isLoggedIn) {
+        // El token guardado ya no es válido (p. ej. el backend se reinició)
+        setSessionToken(null);
+      }
       if (data && data.session && data.session.isLoggedIn) {
         const s = data.session;
         setSession({
@@ -206,9 +215,7 @@ const App: React.FC = () => {
         
         const response = await fetch(`${BACKEND_URL}/execute`, {
           method: 'POST',
-          headers: {
-            'Content-Type': 'application/json',
-          },
+          headers: authHeaders(),
           body: JSON.stringify({ command: command.trim() }),
         });
 
@@ -216,11 +223,21 @@ const App: React.FC = () => {
           const result: BackendResponse = await response.json();
           
           setOutput(prev => prev.slice(0, -1));
+
+          // Un login dentro del script devuelve el token de la nueva sesión
+          if (result.token) {
+            setSessionToken(result.token);
+            fetchSessionInfo();
+          } else if (result.success && command.trim().toLowerCase() === 'logout') {
+            setSessionToken(null);
+            setSession({ partitionId: '', username: '', isLoggedIn: false, isRoot: false });
+          }
           
           if (result.success) {
             addToOutput(command.trim(), result.output || '✅ Comando ejecutado exitosamente', false);
           } else {
-            addToOutput(command.trim(), result.error || 'Error desconocido', true);
+            const errorText = `❌ [${result.code || 'ERROR'}] ${result.error || 'Error desconocido'}`;
+            addToOutput(command.trim(), result.output ? `${result.output}${errorText}` : errorText, true);
           }
         } else {
           setOutput(prev => prev.slice(0, -1));
@@ -251,15 +268,15 @@ const App: React.FC = () => {
     if (!session.isLoggedIn) return;
 
     try {
-      const response = await fetch(`${BACKEND_URL}/execute`, {
+      const response = await fetch(`${BACKEND_URL}/logout`, {
         method: 'POST',
-        headers: {
-          'Content-Type': 'application/json',
-        },
-        body: JSON.stringify({ command: 'logout' }),
+        headers: authHeaders(),
       });
 
-      if (response.ok) {
+      // Aunque el backend ya no reconozca el token, la sesión local se descarta
+      setSessionToken(null);
+
+      if (response.ok || response.status === 401) {
         setSession({
           partitionId: '',
           username: '',
@@ -1,6 +1,6 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import '../App.css';
-import { BACKEND_URL } from '../config';
+import { BACKEND_URL, authHeaders } from '../config';
 
 interface Partition {
   name: string;
@@ -29,10 +29,31 @@ interface FileNode {
   permissions: string;
   owner: string;
   group: string;
+  modified?: number; // I_mtime (segundos Unix)
+  accessed?: number; // I_atime
+  changed?: number;  // I_ctime
+  target?: string;
   content?: string;
   children?: FileNode[];
 }
 
+// Fecha local de un tiempo del inodo (segundos Unix)
+const formatTime = (seconds?: number): string =>
+  seconds ? new Date(seconds * 1000).toLocaleString() : '—';
+
+// Vista hexadecimal (como rep -name=file) del contenido binario recibido en base64
+const toHexView = (base64: string): string => {
+  const bytes = Uint8Array.from(atob(base64), (c) => c.charCodeAt(0));
+  const lines: string[] = [];
+  for (let i = 0; i < bytes.length; i += 16) {
+    const chunk = Array.from(bytes.slice(i, i + 16));
+    const hex = chunk.map((b) => b.toString(16).padStart(2, '0').toUpperCase()).join(' ');
+    const ascii = chunk.map((b) => (b >= 32 && b <= 126 ? String.fromCharCode(b) : '.')).join('');
+    lines.push(`${i.toString(16).padStart(8, '0').toUpperCase()}: ${hex.padEnd(47, ' ')} | ${ascii}`);
+  }
+  return lines.join('\n');
+};
+
 const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
   const [currentPath, setCurrentPath] = useState('/');
   const [files, setFiles] = useState<FileNode[]>([]);
@@ -42,6 +63,10 @@ const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
   const [fileContent, setFileContent] = useState('');
   const [isLoadingContent, setIsLoadingContent] = useState(false);
   const [showModal, setShowModal] = useState(false);
+  const [isUploading, setIsUploading] = useState(false);
+  const [transferError, setTransferError] = useState('');
+
+  const uploadInputRef = useRef<HTMLInputElement>(null);
 
   // BACKEND_URL imported from config
 
@@ -56,7 +81,7 @@ const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
     try {
       const response = await fetch(`${BACKEND_URL}/files`, {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: authHeaders(),
         body: JSON.stringify({
           partitionId: partition.id,
           path: path,
@@ -87,7 +112,7 @@ const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
     try {
       const response = await fetch(`${BACKEND_URL}/file/read`, {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: authHeaders(),
         body: JSON.stringify({
           partitionId: partition.id,
           path: filePath,
@@ -96,7 +121,9 @@ const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
 
       if (response.ok) {
         const data = await response.json();
-        if (data.success) {
+        if (data.success && data.encoding === 'base64') {
+          setFileContent(`⚠️ Archivo binario (${data.size} bytes)\n\n${toHexView(data.content)}`);
+        } else if (data.success) {
           setFileContent(data.content);
         } else {
           setFileContent(`Error: ${data.error}`);
@@ -111,6 +138,98 @@ const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
     }
   };
 
+  // URL de PUT/GET /file para un archivo de la carpeta actual
+  const fileUrl = (fileName: string) => {
+    const filePath = currentPath === '/' 
+      ? `/${fileName}` 
+      : `${currentPath}/${fileName}`;
+    const params = new URLSearchParams({ path: filePath, id: partition.id });
+    return `${BACKEND_URL}/file?${params.toString()}`;
+  };
+
+  // Subir un archivo del equipo a la carpeta actual (el contenido viaja como bytes)
+  const uploadFile = async (event: React.ChangeEvent<HTMLInputElement>) => {
+    const file = event.target.files?.[0];
+    event.target.value = '';
+    if (!file) return;
+
+    setIsUploading(true);
+    setTransferError('');
+
+    try {
+      const headers = authHeaders();
+      headers['Content-Type'] = 'application/octet-stream';
+      const response = await fetch(fileUrl(file.name), {
+        method: 'PUT',
+        headers,
+        body: file,
+      });
+
+      const data = await response.json();
+      if (data.success) {
+        await loadFiles(currentPath);
+      } else {
+        setTransferError(`Error al subir '${file.name}': ${data.error}`);
+      }
+    } catch (err) {
+      setTransferError('Error de conexión con el servidor');
+    } finally {
+      setIsUploading(false);
+    }
+  };
+
+  // Descargar el archivo seleccionado tal como está guardado en la partición
+  const downloadFile = async (fileName: string) => {
+    setTransferError('');
+
+    try {
+      const response = await fetch(fileUrl(fileName), { headers: authHeaders() });
+      if (!response.ok) {
+        const data = await response.json();
+        setTransferError(`Error al descargar '${fileName}': ${data.error}`);
+        return;
+      }
+
+      const blob = await response.blob();
+      const url = URL.createObjectURL(blob);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = fileName;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch (err) {
+      setTransferError('Error de conexión con el servidor');
+    }
+  };
+
+  // Descargar la carpeta actual con todo su contenido como archivo tar
+  const exportFolder = async () => {
+    setTransferError('');
+    const tarName = currentPath === '/'
+      ? `${partition.id}.tar`
+      : `${currentPath.split('/').pop()}.tar`;
+
+    try {
+      const params = new URLSearchParams({ path: currentPath, id: partition.id });
+      const response = await fetch(`${BACKEND_URL}/export?${params.toString()}`, { headers: authHeaders() });
+      if (!response.ok) {
+        const data = await response.json();
+        setTransferError(`Error al exportar '${currentPath}': ${data.error}`);
+        return;
+      }
+
+      const blob = await response.blob();
+      const url = URL.createObjectURL(blob);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = tarName;
+      link.click();
+      URL.revokeObjectURL(url);
+    } catch (err) {
+      setTransferError('Error de conexión con el servidor');
+    }
+  };
+
   const handleFileClick = async (file: FileNode) => {
     if (file.type === 'folder') {
       navigateToFolder(file.name);
@@ -136,6 +255,7 @@ const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
   };
 
   const getFileIcon = (file: FileNode) => {
+    if (file.target) return '🔗';
     if (file.type === 'folder') return '📁';
     
     const ext = file.name.split('.').pop()?.toLowerCase();
@@ -175,9 +295,32 @@ const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
           <button className="btn btn-secondary" onClick={() => loadFiles(currentPath)}>
             🔄 Recargar
           </button>
+          <button
+            className="btn btn-primary"
+            onClick={() => uploadInputRef.current?.click()}
+            disabled={isUploading}
+          >
+            {isUploading ? '⏳ Subiendo...' : '📤 Subir archivo'}
+          </button>
+          <button className="btn btn-secondary" onClick={exportFolder}>
+            📦 Exportar (tar)
+          </button>
+          <input
+            ref={uploadInputRef}
+            type="file"
+            onChange={uploadFile}
+            style={{ display: 'none' }}
+          />
         </div>
       </div>
 
+      {transferError && (
+        <div className="explorer-error">
+          <span className="error-icon">⚠️</span>
+          <p className='m-3'>{transferError}</p>
+        </div>
+      )}
+
       <div className="explorer-body">
         {isLoading ? (
           <div className="explorer-loading">
@@ -208,7 +351,9 @@ const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
               >
                 <span className="file-icon">{getFileIcon(file)}</span>
                 <div className="file-info">
-                  <span className="file-name">{file.name}</span>
+                  <span className="file-name">
+                    {file.name}{file.target && ` → ${file.target}`}
+                  </span>
                   <div className="file-meta">
                     <span className="file-size">
                       {file.size > 1024 
@@ -253,7 +398,13 @@ const FileExplorer: React.FC<FileExplorerProps> = ({ partition }) => {
                 <span>Permisos: {selectedFile.permissions}</span>
                 <span>Propietario: {selectedFile.owner}</span>
                 <span>Grupo: {selectedFile.group}</span>
+                <span>Modificado: {formatTime(selectedFile.modified)}</span>
+                <span>Acceso: {formatTime(selectedFile.accessed)}</span>
+                <span>Cambio: {formatTime(selectedFile.changed)}</span>
               </div>
+              <button className="btn btn-primary" onClick={() => downloadFile(selectedFile.name)}>
+                📥 Descargar
+              </button>
               <button className="btn btn-secondary" onClick={closeModal}>
                 Cerrar
               </button>
@@ -1,5 +1,5 @@
 import React, { useState, useEffect } from 'react';
-import { BACKEND_URL } from '../config';
+import { BACKEND_URL, authHeaders } from '../config';
 
 interface JournalEntry {
   operation: string;
@@ -47,7 +47,7 @@ const JournalingViewer: React.FC<JournalingViewerProps> = ({ partitionId, onClos
     try {
       const response = await fetch(`${BACKEND_URL}/journaling`, {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: authHeaders(),
         body: JSON.stringify({ partitionId }),
       });
 
@@ -1,6 +1,6 @@
 import React, { useState, useEffect } from 'react';
 import '../App.css';
-import { BACKEND_URL } from '../config';
+import { BACKEND_URL, setSessionToken } from '../config';
 
 interface LoginProps {
   onLogin: (partitionId: string, username: string, isRoot: boolean) => void;
@@ -94,24 +94,22 @@ const Login: React.FC<LoginProps> = ({ onLogin, onClose }) => {
     setError('');
 
     try {
-      const command = `login -user=${username} -pass=${password} -id=${selectedPartition}`;
-
-      const response = await fetch(`${BACKEND_URL}/execute`, {
+      const response = await fetch(`${BACKEND_URL}/login`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ command }),
+        body: JSON.stringify({ user: username, pass: password, partitionId: selectedPartition }),
       });
 
-      if (response.ok) {
-        const result = await response.json();
-
-        if (result.success) {
-          const isRoot = username.toLowerCase() === 'root';
-          onLogin(selectedPartition, username, isRoot);  // Pasar isRoot
-          onClose();
-        } else {
-          setError(result.error || 'Credenciales incorrectas');
-        }
+      const result = await response.json().catch(() => null);
+
+      if (result && result.success && result.token) {
+        // Guardar el token: las siguientes peticiones usan esta sesión
+        setSessionToken(result.token);
+        const isRoot = !!result.session?.isRoot;
+        onLogin(result.session?.partitionId || selectedPartition, username, isRoot);  // Pasar isRoot
+        onClose();
+      } else if (result) {
+        setError(result.error || 'Credenciales incorrectas');
       } else {
         setError('Error de conexión con el servidor');
       }
@@ -2,3 +2,28 @@
 // Permite sobrescribir la URL mediante la variable de entorno VITE_BACKEND_URL
 const backendUrl = (import.meta as any)?.env?.VITE_BACKEND_URL ?? 'http://localhost:8080';
 export const BACKEND_URL: string = backendUrl;
+
+// Token de la sesión del backend. Cada pestaña tiene su propia sesión: el token
+// se guarda en sessionStorage (no se comparte entre pestañas) y se envía en
+// cada petición.
+const SESSION_TOKEN_KEY = 'extreamfs_session_token';
+
+export const getSessionToken = (): string | null => sessionStorage.getItem(SESSION_TOKEN_KEY);
+
+export const setSessionToken = (token: string | null): void => {
+  if (token) {
+    sessionStorage.setItem(SESSION_TOKEN_KEY, token);
+  } else {
+    sessionStorage.removeItem(SESSION_TOKEN_KEY);
+  }
+};
+
+// Headers JSON con el token de sesión (si existe)
+export const authHeaders = (): Record<string, string> => {
+  const headers: Record<string, string> = { 'Content-Type': 'application/json' };
+  const token = getSessionToken();
+  if (token) {
+    headers['Authorization'] = `Bearer ${token}`;
+  }
+  return headers;
+};